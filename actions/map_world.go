@@ -3,15 +3,22 @@
 package actions
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/playbymail/ottomap/internal/annotations"
 	"github.com/playbymail/ottomap/internal/coords"
 	"github.com/playbymail/ottomap/internal/direction"
 	"github.com/playbymail/ottomap/internal/edges"
 	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/progress"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
 	"github.com/playbymail/ottomap/internal/tiles"
 	"github.com/playbymail/ottomap/internal/wxx"
 	"log"
-	"strings"
+	"slices"
+	"sync"
 )
 
 type MapConfig struct {
@@ -19,17 +26,31 @@ type MapConfig struct {
 		All          bool
 		BorderCounts bool
 	}
-	Origin coords.Map
-	Render struct {
+	Origin   coords.Map
+	Progress *progress.Reporter_t // if set, report each tile as it is converted to a hex
+	Workers  int                  // number of goroutines converting tiles to hexes, 0 or 1 converts sequentially
+	Render   struct {
 		FordsAsPills bool // if true, draw ford icons as pills
+		GMOnly       bool // if true, mark hexes that were never visited or scouted as GM only
 		ShiftMap     bool // if true, shift the map up and left to make it smaller
 	}
 	Show struct {
-		Origin bool // if set, put a marker in the origin hex
+		Annotations []*annotations.Hex_t         // if set, add the player's notes, labels, and icons
+		Changes     string                       // if set, ring the hexes that this turn added something to
+		Contacts    map[coords.Map][]wxx.Contact // if set, mark the last known positions of foreign units
+		Heatmap     bool                         // if set, shade hexes by the turn they were first reported
+		Inventory   map[parser.UnitId_t]string   // if set, add the inventory to the notes for clan units
+		Origin      bool                         // if set, put a marker in the origin hex
+		Reachable   map[coords.Map]int           // if set, shade the hexes in the reachability overlay
+		Regions     []wxx.Region                 // if set, shade the named territories
+		Teleports   []wxx.Teleport               // if set, draw the "Goes to" jumps
+		UnitHistory []wxx.UnitHistory            // if set, draw the paths the units took
 	}
 }
 
-func MapWorld(allTiles *tiles.Map_t, allSpecialNames map[string]*parser.Special_t, clan parser.UnitId_t, cfg MapConfig, options ...wxx.Option) (*wxx.WXX, error) {
+// MapWorld converts the tiles to Worldographer hexes.
+// It stops with the context's error if the context is cancelled before all the tiles are converted.
+func MapWorld(ctx context.Context, allTiles *tiles.Map_t, allSpecialNames map[string]*parser.Special_t, clan parser.UnitId_t, cfg MapConfig, options ...wxx.Option) (*wxx.WXX, error) {
 	if allTiles.Length() == 0 {
 		log.Fatalf("error: no tiles to map\n")
 	}
@@ -69,88 +90,242 @@ func MapWorld(allTiles *tiles.Map_t, allSpecialNames map[string]*parser.Special_
 		log.Printf("map: shift left  %5d columns\n", renderOffset.Column)
 	}
 
+	// on a world that wraps, the tiles across the seam are drawn next to the others
+	stitch := allTiles.Stitch()
+	shift := func(location coords.Map) coords.Map {
+		location = stitch(location)
+		return coords.Map{Column: location.Column - renderOffset.Column, Row: location.Row - renderOffset.Row}
+	}
+
+	// convert the tiles in a fixed order so that the hexes are merged the same way every time
+	sortedTiles := make([]*tiles.Tile_t, 0, len(allTiles.Tiles))
+	for _, t := range allTiles.Tiles {
+		sortedTiles = append(sortedTiles, t)
+	}
+	slices.SortFunc(sortedTiles, func(a, b *tiles.Tile_t) int {
+		if a.Location.Column != b.Location.Column {
+			return a.Location.Column - b.Location.Column
+		}
+		return a.Location.Row - b.Location.Row
+	})
+	cfg.Progress.Start("map", len(sortedTiles))
+	hexes, err := convertTiles(ctx, sortedTiles, allSpecialNames, clan, &cfg, shift)
+	if err != nil {
+		cfg.Progress.Done()
+		return nil, fmt.Errorf("map: %w", err)
+	}
+
 	// world hex map is indexed by render location, not true location
 	worldHexMap := map[coords.Map]*wxx.Hex{}
-	for _, t := range allTiles.Tiles {
-		hex := &wxx.Hex{
-			Location: t.Location,
-			RenderAt: coords.Map{
-				Column: t.Location.Column - renderOffset.Column,
-				Row:    t.Location.Row - renderOffset.Row,
-			},
-			Terrain: t.Terrain,
-			Features: wxx.Features{
-				IsOrigin: cfg.Show.Origin && t.Location == cfg.Origin,
-				//Resources: report.Resources,
-			},
-			WasVisited: t.Visited != "",
-			WasScouted: t.Scouted != "",
-		}
-
-		// todo: one way fords and one way passes?
-		for _, d := range direction.Directions {
-			for _, edge := range t.Edges[d] {
-				switch edge {
-				case edges.None:
-				case edges.Canal:
-					hex.Features.Edges.Canal = append(hex.Features.Edges.Canal, d)
-				case edges.Ford:
-					hex.Features.Edges.Ford = append(hex.Features.Edges.Ford, d)
-				case edges.Pass:
-					hex.Features.Edges.Pass = append(hex.Features.Edges.Pass, d)
-				case edges.River:
-					hex.Features.Edges.River = append(hex.Features.Edges.River, d)
-				case edges.StoneRoad:
-					hex.Features.Edges.StoneRoad = append(hex.Features.Edges.StoneRoad, d)
-				default:
-					panic(fmt.Sprintf("assert(edge != %d)", edge))
-				}
-			}
+	var errs []error
+	for _, hex := range hexes {
+		for _, warning := range hex.Features.Warnings {
+			log.Printf("warn: map: %s: %s\n", hex.Location.GridString(), warning)
+		}
+		worldHexMap[hex.RenderAt] = hex
+		if err := consolidatedMap.MergeHex(hex); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", hex.Location.GridString(), err))
 		}
+	}
+	cfg.Progress.Done()
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("map: merge hexes: %w", err)
+	}
 
-		for _, encounter := range t.Encounters {
-			if encounter.UnitId.InClan(clan) {
-				encounter.Friendly = true
+	log.Printf("map: collected %8d new     hexes\n", len(worldHexMap))
+
+	for _, t := range cfg.Show.Teleports {
+		consolidatedMap.AddTeleport(wxx.Teleport{
+			UnitId: t.UnitId,
+			From:   shift(t.From),
+			To:     shift(t.To),
+		})
+	}
+
+	for _, h := range cfg.Show.UnitHistory {
+		history := wxx.UnitHistory{UnitId: h.UnitId}
+		for _, turn := range h.Turns {
+			shifted := wxx.UnitTurn{TurnId: turn.TurnId}
+			for _, hex := range turn.Path {
+				shifted.Path = append(shifted.Path, shift(hex))
 			}
-			hex.Features.Encounters = append(hex.Features.Encounters, encounter)
+			history.Turns = append(history.Turns, shifted)
 		}
+		consolidatedMap.AddUnitHistory(history)
+	}
 
-		for _, resource := range t.Resources {
-			hex.Features.Resources = append(hex.Features.Resources, resource)
+	for _, hex := range cfg.Show.Annotations {
+		at := shift(hex.Location)
+		for _, a := range hex.Annotations {
+			consolidatedMap.AddAnnotation(wxx.Annotation{At: at, Label: a.Label, Note: a.Note, Icon: a.Icon, Color: a.Color})
 		}
+	}
 
-		for _, settlement := range t.Settlements {
-			id := strings.ToLower(settlement.Name)
-			if special, ok := allSpecialNames[id]; ok {
-				log.Printf("settlement: %s -> special %q\n", id, special.Name)
-				hex.Features.Special = append(hex.Features.Special, special)
-				continue
+	for _, r := range cfg.Show.Regions {
+		region := wxx.Region{Name: r.Name, Color: r.Color}
+		for _, hex := range r.Hexes {
+			region.Hexes = append(region.Hexes, shift(hex))
+		}
+		consolidatedMap.AddRegion(region)
+	}
+
+	return consolidatedMap, nil
+}
+
+// convertTiles converts the tiles to hexes, in the same order as the tiles.
+// When cfg.Workers is more than 1, the tiles are converted by a pool of that
+// many goroutines; the tiles are independent, so only the order of the log
+// messages changes. It stops with the context's error if the context is
+// cancelled before all the tiles are converted.
+func convertTiles(ctx context.Context, sortedTiles []*tiles.Tile_t, allSpecialNames map[string]*parser.Special_t, clan parser.UnitId_t, cfg *MapConfig, shift func(coords.Map) coords.Map) ([]*wxx.Hex, error) {
+	hexes := make([]*wxx.Hex, len(sortedTiles))
+	if cfg.Workers < 2 || len(sortedTiles) < 2 {
+		for n, t := range sortedTiles {
+			if err := ctx.Err(); err != nil {
+				return nil, err
 			}
-			hex.Features.Settlements = append(hex.Features.Settlements, settlement)
+			hexes[n] = convertTileToHex(t, allSpecialNames, clan, cfg, shift)
+			cfg.Progress.Add(1)
 		}
+		return hexes, nil
+	}
 
-		for _, special := range t.Special {
-			//log.Printf("map world: checking special %q\n", special.Name)
-			foundId := false
-			for _, v := range hex.Features.Special {
-				foundId = v.Id == special.Id
-				if foundId {
-					break // avoid duplicates
+	// converting a tile is quick, so the workers are handed batches of tiles
+	const tilesPerBatch = 256
+	work := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < cfg.Workers && w*tilesPerBatch < len(sortedTiles); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for start := range work {
+				end := min(start+tilesPerBatch, len(sortedTiles))
+				for n := start; n < end; n++ {
+					hexes[n] = convertTileToHex(sortedTiles[n], allSpecialNames, clan, cfg, shift)
 				}
+				cfg.Progress.Add(end - start)
 			}
-			if !foundId {
-				hex.Features.Special = append(hex.Features.Special, special)
+		}()
+	}
+	var err error
+	for start := 0; start < len(sortedTiles); start += tilesPerBatch {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		work <- start
+	}
+	close(work)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return hexes, nil
+}
+
+// convertTileToHex converts a single tile to a hex.
+// It only changes the tile's own encounters, so tiles can be converted concurrently.
+func convertTileToHex(t *tiles.Tile_t, allSpecialNames map[string]*parser.Special_t, clan parser.UnitId_t, cfg *MapConfig, shift func(coords.Map) coords.Map) *wxx.Hex {
+	hex := &wxx.Hex{
+		Location: t.Location,
+		RenderAt: shift(t.Location),
+		Terrain:  t.Terrain,
+		Features: wxx.Features{
+			IsOrigin: cfg.Show.Origin && t.Location == cfg.Origin,
+			//Resources: report.Resources,
+		},
+		WasVisited: t.Visited != "",
+		WasScouted: t.Scouted != "",
+	}
+	// terrain from far horizon sightings and neighbor back-fill hasn't been confirmed by a unit in the hex
+	hex.IsGMOnly = cfg.Render.GMOnly && !(hex.WasVisited || hex.WasScouted)
+	// data that can't be rendered is dropped and recorded as a warning on the hex,
+	// which MapWorld logs when it merges the hex
+	warn := func(format string, args ...any) {
+		hex.Features.Warnings = append(hex.Features.Warnings, fmt.Sprintf(format, args...))
+	}
+	if hex.Terrain < 0 || int(hex.Terrain) >= terrain.NumberOfTerrainTypes {
+		warn("unknown terrain %d", hex.Terrain)
+		hex.Terrain = terrain.UnknownLand
+	}
+
+	if _, ok := cfg.Show.Reachable[t.Location]; ok {
+		hex.Features.IsReachable = true
+	}
+	hex.Features.Contacts = cfg.Show.Contacts[t.Location]
+	for _, lost := range t.Lost {
+		hex.Features.Lost = append(hex.Features.Lost, wxx.NoteLine{Kind: "lost", Message: fmt.Sprintf("%s vanished", lost), TurnId: lost.TurnId})
+	}
+	if cfg.Show.Heatmap {
+		hex.Features.FirstSeen = t.FirstSeen
+	}
+	if cfg.Show.Changes != "" {
+		hex.Features.IsChanged = t.Changed == cfg.Show.Changes
+	}
+
+	// todo: one way fords and one way passes?
+	for _, d := range direction.Directions {
+		for _, edge := range t.Edges[d] {
+			switch edge {
+			case edges.None:
+			case edges.Canal:
+				hex.Features.Edges.Canal = append(hex.Features.Edges.Canal, d)
+			case edges.Ford:
+				hex.Features.Edges.Ford = append(hex.Features.Edges.Ford, d)
+			case edges.Pass:
+				hex.Features.Edges.Pass = append(hex.Features.Edges.Pass, d)
+			case edges.River:
+				hex.Features.Edges.River = append(hex.Features.Edges.River, d)
+			case edges.StoneRoad:
+				hex.Features.Edges.StoneRoad = append(hex.Features.Edges.StoneRoad, d)
+			default:
+				warn("unknown edge %d to the %s", edge, d)
+			}
+		}
+	}
+
+	for _, encounter := range t.Encounters {
+		if encounter.UnitId.InClan(clan) {
+			encounter.Friendly = true
+			if inventory, ok := cfg.Show.Inventory[encounter.UnitId]; ok {
+				if hex.Features.Inventory == nil {
+					hex.Features.Inventory = map[parser.UnitId_t]string{}
+				}
+				hex.Features.Inventory[encounter.UnitId] = inventory
 			}
 		}
+		hex.Features.Encounters = append(hex.Features.Encounters, encounter)
+	}
 
-		worldHexMap[hex.RenderAt] = hex
+	for _, resource := range t.Resources {
+		if _, ok := resources.EnumToString[resource]; !ok {
+			warn("unknown resource %d", resource)
+			continue
+		}
+		hex.Features.Resources = append(hex.Features.Resources, resource)
+	}
 
-		if err := consolidatedMap.MergeHex(hex); err != nil {
-			log.Fatalf("error: wxx: mergeHexes: newHexes: %v\n", err)
+	for _, settlement := range t.Settlements {
+		if special, ok := parser.LookupSpecial(allSpecialNames, settlement.Name, t.Location); ok {
+			log.Printf("settlement: %s -> special %q\n", special.Id, special.Name)
+			hex.Features.Special = append(hex.Features.Special, special)
+			continue
 		}
+		hex.Features.Settlements = append(hex.Features.Settlements, settlement)
 	}
 
-	log.Printf("map: collected %8d new     hexes\n", len(worldHexMap))
+	for _, special := range t.Special {
+		//log.Printf("map world: checking special %q\n", special.Name)
+		foundId := false
+		for _, v := range hex.Features.Special {
+			foundId = v.Id == special.Id
+			if foundId {
+				break // avoid duplicates
+			}
+		}
+		if !foundId {
+			hex.Features.Special = append(hex.Features.Special, special)
+		}
+	}
 
-	return consolidatedMap, nil
+	return hex
 }
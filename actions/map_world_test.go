@@ -0,0 +1,188 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package actions_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+// bigMap returns a map of columns by rows tiles with edges, encounters,
+// resources, and settlements, and one tile with a resource that can't be drawn.
+func bigMap(columns, rows int) *tiles.Map_t {
+	kinds := []terrain.Terrain_e{terrain.Prairie, terrain.GrassyHills, terrain.Lake, terrain.ConiferHills, terrain.Swamp, terrain.Ocean}
+	m := tiles.NewMap()
+	for column := 1; column <= columns; column++ {
+		for row := 1; row <= rows; row++ {
+			t := m.FetchTile("0138", coords.Map{Column: column, Row: row})
+			t.Terrain, t.Visited = kinds[(column+row)%len(kinds)], "0901-07"
+			t.MergeEdge(direction.Directions[(column*row)%len(direction.Directions)], edges.River)
+			t.MergeEncounter(&parser.Encounter_t{TurnId: "0901-07", UnitId: parser.UnitId_t(fmt.Sprintf("0%03de1", (column+row)%500))})
+			if (column+row)%7 == 0 {
+				t.MergeResource(resources.Coal)
+				t.MergeSettlement(&parser.Settlement_t{TurnId: "0901-07", Name: fmt.Sprintf("Town %d-%d", column, row)}, nil, false)
+			}
+		}
+	}
+	m.Tiles[coords.Map{Column: 2, Row: 2}].Resources = append(m.Tiles[coords.Map{Column: 2, Row: 2}].Resources, resources.Resource_e(999))
+	return m
+}
+
+// the map must be the same when the tiles are converted by several workers.
+func TestMapWorldWorkers(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	encode := func(workers int) []byte {
+		m := bigMap(40, 30)
+		cfg := actions.MapConfig{Workers: workers}
+		w, err := actions.MapWorld(context.Background(), m, nil, "0138", cfg)
+		if err != nil {
+			t.Fatalf("workers %d: map: %v", workers, err)
+		}
+		upperLeft, lowerRight := m.Bounds()
+		var buf bytes.Buffer
+		if err := w.Encode(context.Background(), &buf, "0901-07", upperLeft, lowerRight, wxx.RenderConfig{Deterministic: true}); err != nil {
+			t.Fatalf("workers %d: encode: %v", workers, err)
+		}
+		return buf.Bytes()
+	}
+
+	want := encode(0)
+	for _, workers := range []int{2, 4, 16} {
+		if got := encode(workers); !bytes.Equal(got, want) {
+			t.Errorf("workers %d: map differs from the sequential map", workers)
+		}
+	}
+
+	// a cancelled context stops the conversion
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := actions.MapWorld(ctx, bigMap(4, 4), nil, "0138", actions.MapConfig{Workers: 4}); err == nil {
+		t.Errorf("cancelled: want error, got nil")
+	}
+}
+
+func BenchmarkMapWorld(b *testing.B) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	m := bigMap(300, 200)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers-%d", workers), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := actions.MapWorld(context.Background(), m, nil, "0138", actions.MapConfig{Workers: workers}); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(m.Length()*b.N)/b.Elapsed().Seconds(), "tiles/s")
+		})
+	}
+}
+
+// data that can't be drawn is dropped and marked with a red "!" when warnings are shown.
+func TestMapWorldWarnings(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	m := tiles.NewMap()
+	for column := 1; column <= 4; column++ {
+		tile := m.FetchTile("0138", coords.Map{Column: column, Row: 1})
+		tile.Terrain, tile.Visited = terrain.Prairie, "0901-07"
+	}
+	m.Tiles[coords.Map{Column: 1, Row: 1}].Terrain = terrain.Terrain_e(999)
+	m.Tiles[coords.Map{Column: 2, Row: 1}].Resources = []resources.Resource_e{resources.Resource_e(999)}
+	m.Tiles[coords.Map{Column: 3, Row: 1}].MergeEdge(direction.North, edges.Edge_e(999))
+
+	w, err := actions.MapWorld(context.Background(), m, nil, "0138", actions.MapConfig{})
+	if err != nil {
+		t.Fatalf("map: %v", err)
+	}
+	upperLeft, lowerRight := m.Bounds()
+	for _, tc := range []struct {
+		id   int
+		show bool
+		want int
+	}{
+		{1, false, 0},
+		{2, true, 3},
+	} {
+		cfg := wxx.RenderConfig{Deterministic: true}
+		cfg.Show.Warnings = tc.show
+		doc, err := w.Document(context.Background(), "0901-07", upperLeft, lowerRight, cfg)
+		if err != nil {
+			t.Fatalf("%d: document: %v", tc.id, err)
+		}
+		got := 0
+		for _, feature := range doc.Features {
+			if feature.MapLayer != "Tribenet Warnings" {
+				continue
+			}
+			got++
+			if feature.Label == nil || feature.Label.Text != "!" {
+				t.Errorf("%d: label: want %q, got %+v", tc.id, "!", feature.Label)
+			}
+		}
+		if got != tc.want {
+			t.Errorf("%d: warnings: want %d, got %d", tc.id, tc.want, got)
+		}
+	}
+}
+
+// the heatmap only shades tiles when it is asked for, and only tiles that were reported.
+func TestMapWorldHeatmap(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	m := tiles.NewMap()
+	for column := 1; column <= 3; column++ {
+		tile := m.FetchTile("0138", coords.Map{Column: column, Row: 1})
+		tile.Terrain, tile.Visited = terrain.Prairie, "0901-07"
+	}
+	m.Tiles[coords.Map{Column: 1, Row: 1}].FirstSeen = "0901-06"
+	m.Tiles[coords.Map{Column: 2, Row: 1}].FirstSeen = "0901-07"
+
+	upperLeft, lowerRight := m.Bounds()
+	for _, tc := range []struct {
+		id   int
+		show bool
+		want int
+	}{
+		{1, false, 0},
+		{2, true, 2},
+	} {
+		cfg := actions.MapConfig{}
+		cfg.Show.Heatmap = tc.show
+		w, err := actions.MapWorld(context.Background(), m, nil, "0138", cfg)
+		if err != nil {
+			t.Fatalf("%d: map: %v", tc.id, err)
+		}
+		doc, err := w.Document(context.Background(), "0901-07", upperLeft, lowerRight, wxx.RenderConfig{Deterministic: true})
+		if err != nil {
+			t.Fatalf("%d: document: %v", tc.id, err)
+		}
+		got := 0
+		for _, shape := range doc.Shapes {
+			if shape.MapLayer == "Tribenet Heatmap" {
+				got++
+			}
+		}
+		if got != tc.want {
+			t.Errorf("%d: shaded: want %d, got %d", tc.id, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package actions
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"html"
+	"sort"
+	"strings"
+)
+
+// TurnSummary_t is a digest of the things a clan learned in a single turn.
+type TurnSummary_t struct {
+	TurnId  string
+	ClanId  parser.UnitId_t
+	Season  string
+	Weather string
+
+	NewHexes    []*tiles.Tile_t           // tiles first reported this turn
+	Terrain     map[terrain.Terrain_e]int // terrain counts for the new tiles
+	Settlements []SummarySettlement_t
+	Encounters  []SummaryEncounter_t
+	FailedMoves []SummaryFailedMove_t
+	Lost        []SummaryLost_t     // units and scouting parties that did not return
+	Teleports   []SummaryTeleport_t // "Goes to" jumps
+	Winds       []SummaryWind_t     // winds reported by fleets
+	Movement    []SummaryMovement_t // estimated movement points for the land units that advanced
+}
+
+type SummaryMovement_t struct {
+	UnitId    parser.UnitId_t
+	Steps     int      // advances that were costed
+	Spent     int      // movement points spent on the costed advances
+	Remaining int      // movement points left after the last costed advance
+	Warnings  []string // steps where the report disagrees with the estimate
+}
+
+type SummarySettlement_t struct {
+	Name     string
+	Location coords.Map
+	IsNew    bool // true if this is the first turn the settlement was reported
+}
+
+type SummaryEncounter_t struct {
+	UnitId   parser.UnitId_t // unit that was encountered
+	SeenBy   parser.UnitId_t
+	Location coords.Map
+}
+
+type SummaryTeleport_t struct {
+	UnitId parser.UnitId_t
+	From   coords.Map
+	To     coords.Map
+}
+
+type SummaryWind_t struct {
+	UnitId parser.UnitId_t
+	Wind   parser.Wind_t
+}
+
+type SummaryLost_t struct {
+	UnitId   parser.UnitId_t
+	ScoutNo  int        // set only for scouting parties
+	Location coords.Map // last known location
+}
+
+func (l SummaryLost_t) name() string {
+	if l.ScoutNo != 0 {
+		return fmt.Sprintf("%s scout %d", l.UnitId, l.ScoutNo)
+	}
+	return string(l.UnitId)
+}
+
+type SummaryFailedMove_t struct {
+	UnitId    parser.UnitId_t
+	Location  coords.Map // hex the unit was in when the move failed
+	Direction direction.Direction_e
+	Terrain   terrain.Terrain_e // terrain in the hex the unit tried to enter, if known
+	Reason    results.Result_e
+}
+
+// SummarizeTurn builds a digest of the turn from the parsed turns and the merged map.
+// The map must be the result of walking the turns, since it is used to find the
+// tiles that were first reported in this turn.
+func SummarizeTurn(allTurns []*parser.Turn_t, worldMap *tiles.Map_t, clan parser.UnitId_t, turnId string) (*TurnSummary_t, error) {
+	var turn *parser.Turn_t
+	for _, t := range allTurns {
+		if t.Id == turnId {
+			turn = t
+			break
+		}
+	}
+	if turn == nil {
+		return nil, fmt.Errorf("turn %s: not found", turnId)
+	}
+
+	s := &TurnSummary_t{
+		TurnId:  turnId,
+		ClanId:  clan,
+		Season:  turn.Season,
+		Weather: turn.Weather,
+		Terrain: map[terrain.Terrain_e]int{},
+	}
+
+	for _, tile := range worldMap.Tiles {
+		if tile.FirstSeen != turnId {
+			continue
+		}
+		s.NewHexes = append(s.NewHexes, tile)
+		s.Terrain[tile.Terrain]++
+	}
+	sort.Slice(s.NewHexes, func(i, j int) bool {
+		return s.NewHexes[i].Location.GridString() < s.NewHexes[j].Location.GridString()
+	})
+
+	// settlements are new if no earlier turn reported them
+	knownSettlements := map[string]bool{}
+	for _, t := range allTurns {
+		if t.Id >= turnId {
+			continue
+		}
+		for _, moves := range t.SortedMoves {
+			forEachReport(moves, func(_ *parser.Move_t, report *parser.Report_t, _ bool) {
+				for _, settlement := range report.Settlements {
+					knownSettlements[strings.ToLower(settlement.Name)] = true
+				}
+			})
+		}
+	}
+
+	for _, moves := range turn.SortedMoves {
+		if moves.GoesTo != "" {
+			s.Teleports = append(s.Teleports, SummaryTeleport_t{UnitId: moves.UnitId, From: moves.StartLocation, To: moves.Location})
+		}
+		for _, move := range moves.Moves {
+			if move.Wind.String() != "" {
+				s.Winds = append(s.Winds, SummaryWind_t{UnitId: moves.UnitId, Wind: move.Wind})
+				break
+			}
+		}
+	}
+
+	for _, moves := range turn.SortedMoves {
+		for _, move := range moves.Moves {
+			if move.Result == results.Vanished {
+				s.Lost = append(s.Lost, SummaryLost_t{UnitId: moves.UnitId, Location: move.Location})
+			}
+		}
+		for _, scout := range moves.Scouts {
+			for _, move := range scout.Moves {
+				if move.Result == results.Vanished {
+					s.Lost = append(s.Lost, SummaryLost_t{UnitId: moves.UnitId, ScoutNo: scout.No, Location: move.Location})
+				}
+			}
+		}
+	}
+	sort.Slice(s.Lost, func(i, j int) bool {
+		if s.Lost[i].UnitId == s.Lost[j].UnitId {
+			return s.Lost[i].ScoutNo < s.Lost[j].ScoutNo
+		}
+		return s.Lost[i].UnitId < s.Lost[j].UnitId
+	})
+
+	for _, moves := range turn.SortedMoves {
+		var m SummaryMovement_t
+		for _, move := range moves.Moves {
+			if !move.MP.Known {
+				continue
+			}
+			m.Steps++
+			if move.Result == results.Succeeded {
+				m.Spent += move.MP.Cost
+			}
+			m.Remaining = move.MP.Remaining
+			if move.MP.Warning != "" {
+				m.Warnings = append(m.Warnings, move.MP.Warning)
+			}
+		}
+		if m.Steps != 0 {
+			m.UnitId = moves.UnitId
+			s.Movement = append(s.Movement, m)
+		}
+	}
+
+	seenSettlements := map[string]bool{}
+	seenEncounters := map[parser.UnitId_t]bool{}
+	for _, moves := range turn.SortedMoves {
+		forEachReport(moves, func(move *parser.Move_t, report *parser.Report_t, isScout bool) {
+			for _, settlement := range report.Settlements {
+				id := strings.ToLower(settlement.Name)
+				if settlement.Name == "" || seenSettlements[id] {
+					continue
+				}
+				seenSettlements[id] = true
+				s.Settlements = append(s.Settlements, SummarySettlement_t{
+					Name:     settlement.Name,
+					Location: move.Location,
+					IsNew:    !knownSettlements[id],
+				})
+			}
+			for _, encounter := range report.Encounters {
+				if encounter.UnitId.InClan(clan) || seenEncounters[encounter.UnitId] {
+					continue
+				}
+				seenEncounters[encounter.UnitId] = true
+				s.Encounters = append(s.Encounters, SummaryEncounter_t{
+					UnitId:   encounter.UnitId,
+					SeenBy:   moves.UnitId,
+					Location: move.Location,
+				})
+			}
+			if !isScout && move.Result == results.Failed {
+				fm := SummaryFailedMove_t{
+					UnitId:    move.UnitId,
+					Location:  move.Location,
+					Direction: move.Advance,
+					Reason:    move.Reason,
+				}
+				for _, border := range report.Borders {
+					if border.Direction == move.Advance && border.Terrain != terrain.Blank {
+						fm.Terrain = border.Terrain
+					}
+				}
+				s.FailedMoves = append(s.FailedMoves, fm)
+			}
+		})
+	}
+	sort.Slice(s.Settlements, func(i, j int) bool {
+		return s.Settlements[i].Name < s.Settlements[j].Name
+	})
+	sort.Slice(s.Teleports, func(i, j int) bool {
+		return s.Teleports[i].UnitId < s.Teleports[j].UnitId
+	})
+	sort.Slice(s.Winds, func(i, j int) bool {
+		return s.Winds[i].UnitId < s.Winds[j].UnitId
+	})
+	sort.Slice(s.Movement, func(i, j int) bool {
+		return s.Movement[i].UnitId < s.Movement[j].UnitId
+	})
+	sort.Slice(s.Encounters, func(i, j int) bool {
+		return s.Encounters[i].UnitId < s.Encounters[j].UnitId
+	})
+	sort.SliceStable(s.FailedMoves, func(i, j int) bool {
+		return s.FailedMoves[i].UnitId < s.FailedMoves[j].UnitId
+	})
+
+	return s, nil
+}
+
+// forEachReport calls fn for every report made by the unit and its scouts.
+// isScout is set for moves made by the unit's scouts.
+func forEachReport(moves *parser.Moves_t, fn func(move *parser.Move_t, report *parser.Report_t, isScout bool)) {
+	for _, move := range moves.Moves {
+		if move.Report != nil {
+			fn(move, move.Report, false)
+		}
+	}
+	for _, scout := range moves.Scouts {
+		for _, move := range scout.Moves {
+			if move.Report != nil {
+				fn(move, move.Report, true)
+			}
+		}
+	}
+}
+
+// sortedTerrain returns the terrain counts, largest first.
+func (s *TurnSummary_t) sortedTerrain() []terrain.Terrain_e {
+	var list []terrain.Terrain_e
+	for t := range s.Terrain {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if s.Terrain[list[i]] == s.Terrain[list[j]] {
+			return list[i].String() < list[j].String()
+		}
+		return s.Terrain[list[i]] > s.Terrain[list[j]]
+	})
+	return list
+}
+
+func (fm SummaryFailedMove_t) reason() string {
+	switch fm.Reason {
+	case results.Blocked:
+		return "blocked"
+	case results.ExhaustedMovementPoints:
+		return "not enough movement points"
+	case results.Prohibited:
+		return "prohibited from entering"
+	}
+	return "failed"
+}
+
+func (fm SummaryFailedMove_t) target() string {
+	if fm.Terrain == terrain.Blank {
+		return fm.Direction.String()
+	}
+	return fmt.Sprintf("%s into %s", fm.Direction, fm.Terrain)
+}
+
+// Markdown returns the summary as a markdown document.
+func (s *TurnSummary_t) Markdown() []byte {
+	b := &bytes.Buffer{}
+	_, _ = fmt.Fprintf(b, "# Turn %s summary for clan %s\n", s.TurnId, s.ClanId)
+	if s.Season != "" {
+		_, _ = fmt.Fprintf(b, "\nSeason: %s. Weather: %s.\n", s.Season, s.Weather)
+	}
+	for _, wind := range s.Winds {
+		_, _ = fmt.Fprintf(b, "\nFleet %s reported %s winds.\n", wind.UnitId, wind.Wind)
+	}
+
+	_, _ = fmt.Fprintf(b, "\n## New hexes\n\n")
+	if len(s.NewHexes) == 0 {
+		_, _ = fmt.Fprintf(b, "No new hexes were discovered.\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "%d new hexes were discovered.\n\n", len(s.NewHexes))
+		_, _ = fmt.Fprintf(b, "| Terrain | Count |\n|---|---:|\n")
+		for _, t := range s.sortedTerrain() {
+			_, _ = fmt.Fprintf(b, "| %s | %d |\n", t, s.Terrain[t])
+		}
+		_, _ = fmt.Fprintf(b, "\n| Hex | Terrain | Discovered By |\n|---|---|---|\n")
+		for _, tile := range s.NewHexes {
+			_, _ = fmt.Fprintf(b, "| %s | %s | %s |\n", tile.Location.GridString(), tile.Terrain, tile.DiscoveredBy)
+		}
+	}
+
+	_, _ = fmt.Fprintf(b, "\n## Settlements\n\n")
+	if len(s.Settlements) == 0 {
+		_, _ = fmt.Fprintf(b, "No settlements were reported.\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "| Settlement | Hex | New |\n|---|---|---|\n")
+		for _, settlement := range s.Settlements {
+			_, _ = fmt.Fprintf(b, "| %s | %s | %s |\n", settlement.Name, settlement.Location.GridString(), yesNo(settlement.IsNew))
+		}
+	}
+
+	_, _ = fmt.Fprintf(b, "\n## Units encountered\n\n")
+	if len(s.Encounters) == 0 {
+		_, _ = fmt.Fprintf(b, "No other units were encountered.\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "| Unit | Hex | Seen By |\n|---|---|---|\n")
+		for _, encounter := range s.Encounters {
+			_, _ = fmt.Fprintf(b, "| %s | %s | %s |\n", encounter.UnitId, encounter.Location.GridString(), encounter.SeenBy)
+		}
+	}
+
+	_, _ = fmt.Fprintf(b, "\n## Failed movements\n\n")
+	if len(s.FailedMoves) == 0 {
+		_, _ = fmt.Fprintf(b, "No movements failed.\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "| Unit | Hex | Move | Reason |\n|---|---|---|---|\n")
+		for _, fm := range s.FailedMoves {
+			_, _ = fmt.Fprintf(b, "| %s | %s | %s | %s |\n", fm.UnitId, fm.Location.GridString(), fm.target(), fm.reason())
+		}
+	}
+
+	if len(s.Lost) != 0 {
+		_, _ = fmt.Fprintf(b, "\n## Lost units\n\n")
+		_, _ = fmt.Fprintf(b, "| Unit | Last Known Hex |\n|---|---|\n")
+		for _, l := range s.Lost {
+			_, _ = fmt.Fprintf(b, "| %s | %s |\n", l.name(), l.Location.GridString())
+		}
+	}
+
+	if len(s.Movement) != 0 {
+		_, _ = fmt.Fprintf(b, "\n## Movement points\n\n")
+		_, _ = fmt.Fprintf(b, "Estimated from the terrain and edges, assuming %d MP per unit.\n\n", pathfinding.DefaultMovementPoints)
+		_, _ = fmt.Fprintf(b, "| Unit | Steps | Spent | Left | Warnings |\n|---|---:|---:|---:|---|\n")
+		for _, m := range s.Movement {
+			_, _ = fmt.Fprintf(b, "| %s | %d | %d | %d | %s |\n", m.UnitId, m.Steps, m.Spent, m.Remaining, strings.Join(m.Warnings, "; "))
+		}
+	}
+
+	if len(s.Teleports) != 0 {
+		_, _ = fmt.Fprintf(b, "\n## Goes to\n\n")
+		_, _ = fmt.Fprintf(b, "| Unit | From | To |\n|---|---|---|\n")
+		for _, t := range s.Teleports {
+			_, _ = fmt.Fprintf(b, "| %s | %s | %s |\n", t.UnitId, t.From.GridString(), t.To.GridString())
+		}
+	}
+
+	return b.Bytes()
+}
+
+// HTML returns the summary as a stand-alone HTML document.
+func (s *TurnSummary_t) HTML() []byte {
+	e := html.EscapeString
+	b := &bytes.Buffer{}
+	title := e(fmt.Sprintf("Turn %s summary for clan %s", s.TurnId, s.ClanId))
+	_, _ = fmt.Fprintf(b, "<!DOCTYPE html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n", title)
+	_, _ = fmt.Fprintf(b, "<h1>%s</h1>\n", title)
+	if s.Season != "" {
+		_, _ = fmt.Fprintf(b, "<p>Season: %s. Weather: %s.</p>\n", e(s.Season), e(s.Weather))
+	}
+	for _, wind := range s.Winds {
+		_, _ = fmt.Fprintf(b, "<p>Fleet %s reported %s winds.</p>\n", e(string(wind.UnitId)), e(wind.Wind.String()))
+	}
+
+	_, _ = fmt.Fprintf(b, "<h2>New hexes</h2>\n")
+	if len(s.NewHexes) == 0 {
+		_, _ = fmt.Fprintf(b, "<p>No new hexes were discovered.</p>\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "<p>%d new hexes were discovered.</p>\n", len(s.NewHexes))
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Terrain</th><th>Count</th></tr>\n")
+		for _, t := range s.sortedTerrain() {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%d</td></tr>\n", e(t.String()), s.Terrain[t])
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n<table>\n<tr><th>Hex</th><th>Terrain</th><th>Discovered By</th></tr>\n")
+		for _, tile := range s.NewHexes {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>\n", e(tile.Location.GridString()), e(tile.Terrain.String()), e(string(tile.DiscoveredBy)))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	_, _ = fmt.Fprintf(b, "<h2>Settlements</h2>\n")
+	if len(s.Settlements) == 0 {
+		_, _ = fmt.Fprintf(b, "<p>No settlements were reported.</p>\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Settlement</th><th>Hex</th><th>New</th></tr>\n")
+		for _, settlement := range s.Settlements {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>\n", e(settlement.Name), e(settlement.Location.GridString()), yesNo(settlement.IsNew))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	_, _ = fmt.Fprintf(b, "<h2>Units encountered</h2>\n")
+	if len(s.Encounters) == 0 {
+		_, _ = fmt.Fprintf(b, "<p>No other units were encountered.</p>\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Unit</th><th>Hex</th><th>Seen By</th></tr>\n")
+		for _, encounter := range s.Encounters {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>\n", e(string(encounter.UnitId)), e(encounter.Location.GridString()), e(string(encounter.SeenBy)))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	_, _ = fmt.Fprintf(b, "<h2>Failed movements</h2>\n")
+	if len(s.FailedMoves) == 0 {
+		_, _ = fmt.Fprintf(b, "<p>No movements failed.</p>\n")
+	} else {
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Unit</th><th>Hex</th><th>Move</th><th>Reason</th></tr>\n")
+		for _, fm := range s.FailedMoves {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n", e(string(fm.UnitId)), e(fm.Location.GridString()), e(fm.target()), e(fm.reason()))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	if len(s.Lost) != 0 {
+		_, _ = fmt.Fprintf(b, "<h2>Lost units</h2>\n")
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Unit</th><th>Last Known Hex</th></tr>\n")
+		for _, l := range s.Lost {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td></tr>\n", e(l.name()), e(l.Location.GridString()))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	if len(s.Movement) != 0 {
+		_, _ = fmt.Fprintf(b, "<h2>Movement points</h2>\n")
+		_, _ = fmt.Fprintf(b, "<p>Estimated from the terrain and edges, assuming %d MP per unit.</p>\n", pathfinding.DefaultMovementPoints)
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Unit</th><th>Steps</th><th>Spent</th><th>Left</th><th>Warnings</th></tr>\n")
+		for _, m := range s.Movement {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%d</td><td>%d</td><td>%d</td><td>%s</td></tr>\n", e(string(m.UnitId)), m.Steps, m.Spent, m.Remaining, e(strings.Join(m.Warnings, "; ")))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	if len(s.Teleports) != 0 {
+		_, _ = fmt.Fprintf(b, "<h2>Goes to</h2>\n")
+		_, _ = fmt.Fprintf(b, "<table>\n<tr><th>Unit</th><th>From</th><th>To</th></tr>\n")
+		for _, t := range s.Teleports {
+			_, _ = fmt.Fprintf(b, "<tr><td>%s</td><td>%s</td><td>%s</td></tr>\n", e(string(t.UnitId)), e(t.From.GridString()), e(t.To.GridString()))
+		}
+		_, _ = fmt.Fprintf(b, "</table>\n")
+	}
+
+	_, _ = fmt.Fprintf(b, "</body>\n</html>\n")
+	return b.Bytes()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
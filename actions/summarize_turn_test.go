@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package actions_test
+
+import (
+	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+// hexes for the summary turns: where the tribe starts, where it moves to, and where its scout goes
+var summaryStart, summaryMoved, summaryScouted = coords.Map{Column: 10, Row: 10}, coords.Map{Column: 11, Row: 10}, coords.Map{Column: 11, Row: 9}
+
+// summaryTurns returns two walked turns for clan 0138 and the merged map.
+// Bree was reported in the first turn, so only Rivertown is new in the second.
+// In the second turn, the tribe moves NE into a new hex, fails to move N into a
+// lake, and meets 0250 and its own element; its scout fails a move and meets 0250 again.
+// Element 0138e2 "Goes to" the hex the tribe started in.
+func summaryTurns() ([]*parser.Turn_t, *tiles.Map_t) {
+	start, moved, scouted := summaryStart, summaryMoved, summaryScouted
+
+	first := &parser.Turn_t{Id: "0902-01", SortedMoves: []*parser.Moves_t{{
+		UnitId: "0138",
+		Moves: []*parser.Move_t{{UnitId: "0138", Still: true, Result: results.StayedInPlace, Location: start, Report: &parser.Report_t{
+			Settlements: []*parser.Settlement_t{{Name: "Bree"}},
+		}}},
+	}}}
+
+	second := &parser.Turn_t{Id: "0902-02", Season: "Winter", Weather: "FINE", SortedMoves: []*parser.Moves_t{{
+		UnitId: "0138",
+		Moves: []*parser.Move_t{
+			{UnitId: "0138", Advance: direction.NorthEast, Result: results.Succeeded, Location: moved, Report: &parser.Report_t{
+				Settlements: []*parser.Settlement_t{{Name: "Rivertown"}, {Name: "bree"}},
+				Encounters:  []*parser.Encounter_t{{UnitId: "0250"}, {UnitId: "0138e1"}},
+			}},
+			{UnitId: "0138", Advance: direction.North, Result: results.Failed, Reason: results.Blocked, Location: moved, Report: &parser.Report_t{
+				Borders: []*parser.Border_t{{Direction: direction.North, Terrain: terrain.Lake}},
+			}},
+		},
+		Scouts: []*parser.Scout_t{{No: 1, Moves: []*parser.Move_t{
+			{UnitId: "0138", Advance: direction.North, Result: results.Succeeded, Location: scouted, Report: &parser.Report_t{
+				Encounters: []*parser.Encounter_t{{UnitId: "0250"}, {UnitId: "0250e1"}},
+			}},
+			{UnitId: "0138", Advance: direction.North, Result: results.Failed, Reason: results.Prohibited, Location: scouted, Report: &parser.Report_t{}},
+		}}},
+	}, {
+		UnitId: "0138e2", GoesTo: summaryStart.GridString(), StartLocation: summaryScouted, Location: summaryStart,
+	}}}
+
+	worldMap := tiles.NewMap()
+	for _, tt := range []struct {
+		location  coords.Map
+		terrain   terrain.Terrain_e
+		firstSeen string
+	}{
+		{start, terrain.Prairie, "0902-01"},
+		{moved, terrain.GrassyHills, "0902-02"},
+		{scouted, terrain.Prairie, "0902-02"},
+	} {
+		tile := worldMap.FetchTile("0138", tt.location)
+		tile.Terrain, tile.FirstSeen = tt.terrain, tt.firstSeen
+	}
+
+	return []*parser.Turn_t{first, second}, worldMap
+}
+
+func TestSummarizeTurn(t *testing.T) {
+	allTurns, worldMap := summaryTurns()
+	s, err := actions.SummarizeTurn(allTurns, worldMap, "0138", "0902-02")
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+
+	var newHexes []string
+	for _, tile := range s.NewHexes {
+		newHexes = append(newHexes, tile.Location.GridString())
+	}
+	var settlements []string
+	for _, settlement := range s.Settlements {
+		settlements = append(settlements, settlement.Name+" "+yesNo(settlement.IsNew))
+	}
+	var encounters []string
+	for _, encounter := range s.Encounters {
+		encounters = append(encounters, string(encounter.UnitId)+" "+string(encounter.SeenBy)+" "+encounter.Location.GridString())
+	}
+	var teleports []string
+	for _, teleport := range s.Teleports {
+		teleports = append(teleports, string(teleport.UnitId)+" "+teleport.From.GridString()+" "+teleport.To.GridString())
+	}
+	var failed []string
+	for _, fm := range s.FailedMoves {
+		failed = append(failed, string(fm.UnitId)+" "+fm.Direction.String()+" "+fm.Terrain.String()+" "+fm.Reason.String())
+	}
+
+	for _, tc := range []struct {
+		id   int
+		what string
+		got  []string
+		want []string
+	}{
+		{1, "new hexes", newHexes, []string{summaryScouted.GridString(), summaryMoved.GridString()}},
+		{2, "settlements", settlements, []string{"Rivertown yes", "bree no"}},
+		{3, "encounters", encounters, []string{"0250 0138 " + summaryMoved.GridString(), "0250e1 0138 " + summaryScouted.GridString()}},
+		{4, "failed moves", failed, []string{"0138 N " + terrain.Lake.String() + " " + results.Blocked.String()}},
+		{5, "teleports", teleports, []string{"0138e2 " + summaryScouted.GridString() + " " + summaryStart.GridString()}},
+	} {
+		if len(tc.got) != len(tc.want) {
+			t.Errorf("%d: %s: want %q, got %q", tc.id, tc.what, tc.want, tc.got)
+			continue
+		}
+		for i := range tc.want {
+			if tc.got[i] != tc.want[i] {
+				t.Errorf("%d: %s: want %q, got %q", tc.id, tc.what, tc.want, tc.got)
+				break
+			}
+		}
+	}
+	if got := s.Terrain[terrain.Prairie] + s.Terrain[terrain.GrassyHills]; got != 2 || s.Terrain[terrain.Prairie] != 1 {
+		t.Errorf("terrain: want 1 prairie and 1 grassy hills, got %v", s.Terrain)
+	}
+
+	if _, err := actions.SummarizeTurn(allTurns, worldMap, "0138", "0902-03"); err == nil {
+		t.Errorf("missing turn: want error, got nil")
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"github.com/playbymail/ottomap/internal/anonymize"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var argsAnonymize struct {
+	seed   int64  // selects the coordinate shift and clan numbers, random if zero
+	output string // directory to write the anonymized files to
+}
+
+var cmdAnonymize = &cobra.Command{
+	Use:   "anonymize file...",
+	Short: "anonymize reports or tniif documents for sharing",
+	Long: `Rewrite turn reports or tniif documents so that they can be shared publicly, for example in a bug report.
+Coordinates are shifted, clan numbers are remapped, unit names are removed, and settlement and special hex names are replaced.
+The structure is kept, so an anonymized report parses the same way as the original.
+
+All the files given are rewritten with the same mappings. The files are written to the output directory,
+named with the remapped clan. Without --seed a random seed is used, so the mappings can't be reversed.
+Coordinates are never wrapped around the edge of the map. A random seed is replaced until the shift keeps
+every hex on the map; a --seed whose shift doesn't fit is an error.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// a random seed is replaced until the shift keeps every hex on the map
+		tries := 100
+		if argsAnonymize.seed != 0 {
+			tries = 1
+		}
+		for try := 1; ; try++ {
+			seed := argsAnonymize.seed
+			if seed == 0 {
+				var buf [8]byte
+				if _, err := rand.Read(buf[:]); err != nil {
+					log.Fatalf("error: seed: %v\n", err)
+				}
+				seed = int64(binary.LittleEndian.Uint64(buf[:]))
+			}
+			a := anonymize.New(seed)
+			files := anonymizeFiles(a, args)
+			if err := a.Err(); err != nil {
+				if try < tries {
+					continue
+				}
+				log.Fatalf("error: anonymize: %v (use another --seed)\n", err)
+			}
+			for _, file := range files {
+				var err error
+				if file.doc != nil {
+					err = writeDocument(file.name, file.doc)
+				} else {
+					err = os.WriteFile(file.name, file.data, 0o644)
+				}
+				if err != nil {
+					log.Fatalf("error: %v\n", err)
+				}
+				log.Printf("anonymize: %s: created %s\n", filepath.Base(file.path), file.name)
+			}
+			return
+		}
+	},
+}
+
+// anonymizedFile_t is an anonymized report or document waiting to be written.
+type anonymizedFile_t struct {
+	path string // the original file
+	name string // the anonymized file
+	doc  *tniif.Document_t
+	data []byte
+}
+
+// anonymizeFiles rewrites all the files with the same anonymizer.
+func anonymizeFiles(a *anonymize.Anonymizer_t, paths []string) (files []anonymizedFile_t) {
+	for _, path := range paths {
+		if strings.HasSuffix(path, ".json") {
+			doc, err := readDocument(path)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			doc = a.Document(doc)
+			files = append(files, anonymizedFile_t{path: path, name: filepath.Join(argsAnonymize.output, doc.Source+".json"), doc: doc})
+			continue
+		}
+		fid, tid, input, err := readReportFile(path)
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		data := a.Report(fid, tid, input)
+		clan := strings.TrimPrefix(fid, tid+".")
+		files = append(files, anonymizedFile_t{path: path, name: filepath.Join(argsAnonymize.output, tid+"."+a.UnitId(clan)+".report.txt"), data: data})
+	}
+	return files
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/tui"
+	"github.com/spf13/cobra"
+	"log"
+)
+
+var argsBrowse struct {
+	center string // grid coordinates of the hex to start on
+	color  string // auto, always, or never
+}
+
+var cmdBrowse = &cobra.Command{
+	Use:   "browse",
+	Short: "browse the merged map in the terminal",
+	Long: `Load the turn reports and browse the merged map in the terminal.
+Move the cursor with the arrow keys to see every report about a hex,
+search for settlements and units with "/", and jump to grid coordinates with "g".
+Press "?" for the list of keys.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if argsBrowse.center != "" {
+			if _, err := coords.HexToMap(argsBrowse.center); err != nil {
+				return fmt.Errorf("center: %w", err)
+			}
+		}
+		switch argsBrowse.color {
+		case "auto", "always", "never":
+		default:
+			return fmt.Errorf("color must be auto, always, or never")
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		m := tui.New(fmt.Sprintf("Clan %s %s", argsRender.clanId, w.maxTurnId), w.tiles, w.turns)
+		m.Notes = argsRender.mapper.Show.Annotations
+		if argsBrowse.center != "" {
+			m.Cursor, _ = coords.HexToMap(argsBrowse.center)
+		}
+		if err := tui.Run(m, useColor(argsBrowse.color)); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/config"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var argsConfig struct {
+	data    string // path to data folder
+	path    string // path to the configuration file
+	profile string
+	clanId  string
+}
+
+var cmdConfig = &cobra.Command{
+	Use:   "config",
+	Short: "manage the configuration file",
+	Long:  `Commands for the ottomap.json configuration file.`,
+}
+
+var cmdConfigCheck = &cobra.Command{
+	Use:   "check",
+	Short: "check the configuration and print the effective settings",
+	Long: `Load the configuration file with the profile and the clan's section,
+print every setting and where it came from, and check that the other
+profiles and clan sections load, too.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := argsConfig.path
+		if path == "" {
+			path = filepath.Join(argsConfig.data, "ottomap.json")
+		}
+		if _, err := os.Stat(path); err != nil {
+			log.Fatalf("error: config: %v\n", err)
+		}
+
+		cfg, err := config.Load(path, argsConfig.profile, argsConfig.clanId)
+		if err != nil {
+			log.Fatalf("error: config: %s: %v\n", path, err)
+		}
+		settings, err := cfg.Settings()
+		if err != nil {
+			log.Fatalf("error: config: %s: %v\n", path, err)
+		}
+
+		profile := argsConfig.profile
+		if _, ok := cfg.Profiles[config.DefaultProfile]; ok && profile == "" {
+			profile = config.DefaultProfile
+		}
+		fmt.Printf("config:  %s\n", path)
+		fmt.Printf("profile: %s\n", orNone(profile))
+		fmt.Printf("clan:    %s\n\n", orNone(argsConfig.clanId))
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "SETTING\tVALUE\tSOURCE\n")
+		for _, setting := range settings {
+			source := setting.Source
+			if source == "" {
+				source = "default"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", setting.Path, setting.Value, source)
+		}
+		_ = tw.Flush()
+
+		// the other profiles and clan sections are only loaded when they are selected,
+		// so check them now rather than when the player switches to them.
+		var problems []string
+		if err := checkConfigLayers(cfg.Layers); err != nil {
+			problems = append(problems, err.Error())
+		}
+		var profiles, clans []string
+		for name := range cfg.Profiles {
+			profiles = append(profiles, name)
+		}
+		for clanId := range cfg.Clans {
+			clans = append(clans, clanId)
+		}
+		sort.Strings(profiles)
+		sort.Strings(clans)
+		for _, name := range profiles {
+			if other, err := config.Load(path, name, argsConfig.clanId); err != nil {
+				problems = append(problems, inSection("profiles", name, err))
+			} else if err := checkConfigLayers(other.Layers); err != nil {
+				problems = append(problems, inSection("profiles", name, err))
+			}
+		}
+		for _, clanId := range clans {
+			if other, err := config.Load(path, argsConfig.profile, clanId); err != nil {
+				problems = append(problems, inSection("clans", clanId, err))
+			} else if err := checkConfigLayers(other.Layers); err != nil {
+				problems = append(problems, inSection("clans", clanId, err))
+			}
+		}
+		if len(problems) != 0 {
+			fmt.Println()
+			for _, problem := range problems {
+				fmt.Printf("error: %s\n", problem)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("\nok: %d profiles, %d clan sections\n", len(profiles), len(clans))
+	},
+}
+
+// inSection adds the section to errors that don't already name it.
+func inSection(kind, name string, err error) string {
+	prefix := fmt.Sprintf("%s: %q: ", kind, name)
+	if strings.HasPrefix(err.Error(), prefix) {
+		return err.Error()
+	}
+	return prefix + err.Error()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
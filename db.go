@@ -6,13 +6,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/playbymail/ottomap/internal/extract"
 	"github.com/playbymail/ottomap/internal/stdlib"
 	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"github.com/playbymail/ottomap/internal/turns"
 	"github.com/spf13/cobra"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -20,8 +23,18 @@ var (
 		paths struct {
 			store string // path to the database store
 		}
+		alliance struct {
+			name  string // name of the alliance
+			clan  string // clan joining or leaving the alliance
+			share bool   // if true, the clan shares its reports with the alliance
+		}
 		create struct {
 			force bool // if true, overwrite existing database
+			user  struct {
+				handle string // name the user signs in with
+				clan   string // clan the user belongs to
+				secret string // secret the user signs in with
+			}
 		}
 		load struct {
 			clan  string   // clan that owns the reports
@@ -35,6 +48,53 @@ var (
 		Short: "Database management commands",
 	}
 
+	cmdDbAlliance = &cobra.Command{
+		Use:   "alliance",
+		Short: "Manage the clans in an alliance",
+		Long: `Clans in an alliance may opt in to sharing their reports with the other members.
+The server renders combined maps from the reports of the clans that share with the user's clan.`,
+	}
+
+	cmdDbAllianceJoin = &cobra.Command{
+		Use:   "join",
+		Short: "add a clan to an alliance or change whether it shares its reports",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return checkAllianceArgs()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			clan, _ := strconv.Atoi(argsDb.alliance.clan)
+			store, err := sqlite.Open(argsDb.paths.store, context.Background())
+			if err != nil {
+				log.Fatalf("db: %v\n", err)
+			}
+			defer store.Close()
+			if err := store.JoinAlliance(argsDb.alliance.name, clan, argsDb.alliance.share); err != nil {
+				log.Fatalf("db: alliance: %q: clan %04d: %v\n", argsDb.alliance.name, clan, err)
+			}
+			log.Printf("db: alliance: %q: clan %04d: joined (sharing %v)\n", argsDb.alliance.name, clan, argsDb.alliance.share)
+		},
+	}
+
+	cmdDbAllianceLeave = &cobra.Command{
+		Use:   "leave",
+		Short: "remove a clan from an alliance",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return checkAllianceArgs()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			clan, _ := strconv.Atoi(argsDb.alliance.clan)
+			store, err := sqlite.Open(argsDb.paths.store, context.Background())
+			if err != nil {
+				log.Fatalf("db: %v\n", err)
+			}
+			defer store.Close()
+			if err := store.LeaveAlliance(argsDb.alliance.name, clan); err != nil {
+				log.Fatalf("db: alliance: %q: clan %04d: %v\n", argsDb.alliance.name, clan, err)
+			}
+			log.Printf("db: alliance: %q: clan %04d: left\n", argsDb.alliance.name, clan)
+		},
+	}
+
 	cmdDbCreate = &cobra.Command{
 		Use:   "create",
 		Short: "Create new database or database objects",
@@ -178,6 +238,129 @@ var (
 		},
 	}
 
+	cmdDbCreateUser = &cobra.Command{
+		Use:   "user",
+		Short: "create a user that can sign in to the server",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if argsDb.paths.store == "" {
+				return fmt.Errorf("database: path to store is required\n")
+			} else if ok, err := stdlib.IsFileExists(argsDb.paths.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else if !ok {
+				return fmt.Errorf("database: %s: does not exist\n", argsDb.paths.store)
+			}
+			if argsDb.create.user.secret == "" {
+				return fmt.Errorf("secret: is required (use --secret or OTTOMAP_USER_SECRET)")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			clan, err := strconv.Atoi(argsDb.create.user.clan)
+			if err != nil {
+				log.Fatalf("db: %q: invalid clan\n", argsDb.create.user.clan)
+			}
+			store, err := sqlite.Open(argsDb.paths.store, context.Background())
+			if err != nil {
+				log.Fatalf("db: %v\n", err)
+			}
+			defer store.Close()
+			id, err := store.CreateUser(argsDb.create.user.handle, clan, argsDb.create.user.secret)
+			if err != nil {
+				log.Fatalf("db: create: user %q: %v\n", argsDb.create.user.handle, err)
+			}
+			log.Printf("db: create: user %q: clan %04d: created %8d\n", argsDb.create.user.handle, clan, id)
+		},
+	}
+
+	cmdDbCreateAlliance = &cobra.Command{
+		Use:   "alliance",
+		Short: "create an alliance that clans can join",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if argsDb.paths.store == "" {
+				return fmt.Errorf("database: path to store is required\n")
+			} else if ok, err := stdlib.IsFileExists(argsDb.paths.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else if !ok {
+				return fmt.Errorf("database: %s: does not exist\n", argsDb.paths.store)
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			store, err := sqlite.Open(argsDb.paths.store, context.Background())
+			if err != nil {
+				log.Fatalf("db: %v\n", err)
+			}
+			defer store.Close()
+			id, err := store.CreateAlliance(argsDb.alliance.name)
+			if err != nil {
+				log.Fatalf("db: create: alliance %q: %v\n", argsDb.alliance.name, err)
+			}
+			log.Printf("db: create: alliance %q: created %8d\n", argsDb.alliance.name, id)
+		},
+	}
+
+	cmdDbImport = &cobra.Command{
+		Use:     "import",
+		Short:   "import report files for rendering",
+		Long:    `Import the text report files in the path without scrubbing them so that "db render" can parse them. Replaces any reports with the same name or contents.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error { return cmdDbLoadPath.PreRunE(cmd, args) },
+		Run: func(cmd *cobra.Command, args []string) {
+			clan, err := strconv.Atoi(argsDb.load.clan)
+			if err != nil {
+				log.Fatalf("db: %v\n", err)
+			}
+			store, err := sqlite.Open(argsDb.paths.store, context.Background())
+			if err != nil {
+				log.Fatalf("db: %v\n", err)
+			}
+			defer store.Close()
+			reports, err := stdlib.FindAllInputs(argsDb.load.path)
+			if err != nil {
+				log.Fatalf("%s: %v\n", argsDb.load.path, err)
+			} else if len(reports) == 0 {
+				log.Fatalf("%s: no files found\n", argsDb.load.path)
+			}
+			for _, report := range reports {
+				if report.Kind != "text" {
+					log.Printf("%04d: %s: skipped: only text reports can be imported\n", clan, report.Name)
+					continue
+				}
+				if err := removeInputFile(store, clan, report); err != nil {
+					log.Fatalf("removing %q: %v\n", report.Name, err)
+				}
+				id, err := importInputFile(store, clan, report)
+				if err != nil {
+					log.Printf("%04d: %s: error: %v\n", clan, report.Name, err)
+					continue
+				}
+				log.Printf("%04d: %s: imported %8d\n", clan, report.Name, id)
+			}
+		},
+	}
+
+	cmdDbRender = &cobra.Command{
+		Use:   "render",
+		Short: "Create a map from the reports in the database",
+		Long:  `Load and parse the clan's turn reports from the database and create a map. Accepts the same flags as the render command.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if argsDb.paths.store == "" {
+				return fmt.Errorf("database: path to store is required\n")
+			} else if ok, err := stdlib.IsFileExists(argsDb.paths.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else if !ok {
+				return fmt.Errorf("database: %s: does not exist\n", argsDb.paths.store)
+			} else if path, err := filepath.Abs(argsDb.paths.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else {
+				argsRender.paths.store = path
+			}
+			return cmdRender.PreRunE(cmd, args)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			cmdRender.Run(cmd, args)
+		},
+	}
+
 	cmdDbLoadPath = &cobra.Command{
 		Use:   "path",
 		Short: "load new files in path",
@@ -288,6 +471,28 @@ func loadInputFile(store *sqlite.Store, clan int, report *stdlib.File_t) (int, e
 	return id, nil
 }
 
+// importInputFile loads a report file into the database without scrubbing it,
+// so that it can be parsed again by the render pipeline.
+// We assume that the caller has already handled duplicates before calling this function.
+func importInputFile(store *sqlite.Store, clan int, report *stdlib.File_t) (int, error) {
+	if !(0 < clan && clan < 1000) {
+		return 0, fmt.Errorf("%d: invalid clan", clan)
+	}
+	data, err := os.ReadFile(filepath.Join(report.Path, report.Name))
+	if err != nil {
+		return 0, errors.Join(fmt.Errorf("reading %q", report.Name), err)
+	}
+	if clean, fixes := extract.Normalize(data); len(fixes) != 0 {
+		log.Printf("%04d: %s: %s\n", clan, report.Name, strings.Join(fixes, ", "))
+		data = clean
+	}
+	id, err := store.CreateNewReport(clan, report.Year, report.Month, report.Unit, report.Hash, data)
+	if err != nil {
+		return 0, errors.Join(fmt.Errorf("inserting %q", report.Name), err)
+	}
+	return id, nil
+}
+
 // removeInputFile removes a report from the database. It uses both the
 // hash and the name to identify the report. If the file is not in the
 // database, it does nothing.
@@ -306,3 +511,56 @@ func removeInputFile(store *sqlite.Store, clan int, report *stdlib.File_t) error
 	}
 	return nil
 }
+
+// collectStoreInputs fetches the clan's reports from the database, from the
+// first turn through the cutoff turn, and returns them as inputs for the parser.
+// checkAllianceArgs validates the store and clan for the alliance commands.
+func checkAllianceArgs() error {
+	if argsDb.paths.store == "" {
+		return fmt.Errorf("database: path to store is required\n")
+	} else if ok, err := stdlib.IsFileExists(argsDb.paths.store); err != nil {
+		return fmt.Errorf("database: %v\n", err)
+	} else if !ok {
+		return fmt.Errorf("database: %s: does not exist\n", argsDb.paths.store)
+	}
+	if n, err := strconv.Atoi(argsDb.alliance.clan); err != nil || !(0 < n && n < 1000) {
+		return fmt.Errorf("%q: invalid clan", argsDb.alliance.clan)
+	}
+	return nil
+}
+
+func collectStoreInputs(path string, clanId string, fromYear, fromMonth, maxYear, maxMonth int) ([]*turns.TurnReportFile_t, error) {
+	clan, err := strconv.Atoi(clanId)
+	if err != nil {
+		return nil, fmt.Errorf("clan %q: %v", clanId, err)
+	}
+	store, err := sqlite.Open(path, context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer store.Close()
+
+	reports, err := store.GetReportsByTurnRange(clan, fromYear, fromMonth, maxYear, maxMonth)
+	if err != nil {
+		return nil, err
+	}
+	var inputs []*turns.TurnReportFile_t
+	for _, report := range reports {
+		// reports loaded with "db load" are scrubbed and can't be parsed again
+		if strings.HasPrefix(report.Lines, "// ") {
+			log.Printf("warn: db: %04d-%02d.%s: report was scrubbed when loaded: use \"db import\" to render it\n", report.Year, report.Month, report.Unit)
+			continue
+		}
+		rf := &turns.TurnReportFile_t{
+			Id:   fmt.Sprintf("%04d-%02d.%s", report.Year, report.Month, report.Unit),
+			Path: fmt.Sprintf("%s#%d", path, report.ID),
+			Data: []byte(report.Lines),
+		}
+		rf.Turn.Id = fmt.Sprintf("%04d-%02d", report.Year, report.Month)
+		rf.Turn.Year, rf.Turn.Month = report.Year, report.Month
+		rf.Turn.ClanId = report.Unit
+		inputs = append(inputs, rf)
+	}
+	log.Printf("db: %s: found %d reports\n", path, len(inputs))
+	return inputs, nil
+}
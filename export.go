@@ -0,0 +1,176 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/history"
+	"github.com/playbymail/ottomap/internal/render"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+)
+
+var argsExport struct {
+	output string // path to the output file, stdout if empty
+	tsv    bool   // if set, write tab separated values
+	json   bool   // if set, write JSON instead of delimited text (history only)
+	format string // format for the map, one of render.Formats()
+}
+
+var cmdExport = &cobra.Command{
+	Use:   "export",
+	Short: "export merged map data",
+	Long:  `Load and parse turn reports and write the merged data as CSV or TSV files.`,
+}
+
+var cmdExportEncounters = &cobra.Command{
+	Use:     "encounters",
+	Short:   "export the encounter log",
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport(cmd.Context(), export.Encounters)
+	},
+}
+
+var cmdExportHistory = &cobra.Command{
+	Use:   "history",
+	Short: "export the location history of every unit",
+	Long: `Write one entry per unit for every turn it was reported in: the hex it started and ended in, what it did,
+the number of steps it took, the movement points spent when every step can be costed, and the hexes it passed through.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		units := history.Build(w.turns, w.tiles)
+
+		sep := export.CSV
+		if argsExport.tsv {
+			sep = export.TSV
+		}
+		writeExport(func(out io.Writer) error {
+			if argsExport.json {
+				return history.WriteJSON(out, units)
+			}
+			return history.WriteCSV(out, units, sep)
+		})
+	},
+}
+
+var cmdExportMap = &cobra.Command{
+	Use:   "map",
+	Short: "export the map for another mapping tool",
+	Long: `Write the merged map for another mapping tool:
+  azgaar  - Azgaar's Fantasy Map Generator full JSON, with a cell for each hex and a burg for each settlement
+  foundry - FoundryVTT scene with a hex grid, the terrain drawn under the grid, and the settlements as text
+  hexkit  - Hex Kit map whose tiles are named "ottomap/<terrain code>.png"
+  text    - two characters for each hex, the same as render preview without colors`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, err := render.Lookup(argsExport.format); err != nil {
+			return err
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		renderer, err := render.Lookup(argsExport.format)
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		m := render.NewMap(fmt.Sprintf("Clan %s %s", argsRender.clanId, w.maxTurnId), w.tiles)
+		writeExport(func(out io.Writer) error {
+			return renderer.Render(out, m)
+		})
+	},
+}
+
+var cmdExportSettlements = &cobra.Command{
+	Use:     "settlements",
+	Short:   "export the settlement registry",
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport(cmd.Context(), export.Settlements)
+	},
+}
+
+var cmdExportTiles = &cobra.Command{
+	Use:     "tiles",
+	Short:   "export the merged tile state",
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		runExport(cmd.Context(), export.Tiles)
+	},
+}
+
+// addExportFlags registers the flags shared by the export commands.
+func addExportFlags(cmd *cobra.Command) {
+	addReportFlags(cmd)
+	cmd.Flags().StringVarP(&argsExport.output, "output", "o", "", "file to write to (default is stdout)")
+	cmd.Flags().BoolVar(&argsExport.tsv, "tsv", false, "write tab separated values")
+}
+
+// runExport loads the world and writes it with the export function.
+func runExport(ctx context.Context, fn func(io.Writer, *tiles.Map_t, rune) error) {
+	w, err := loadWorld(ctx)
+	if err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+
+	sep := export.CSV
+	if argsExport.tsv {
+		sep = export.TSV
+	}
+
+	writeExport(func(out io.Writer) error {
+		return fn(out, w.tiles, sep)
+	})
+}
+
+// writeExport calls the write function with the output file, or stdout if there isn't one.
+func writeExport(fn func(io.Writer) error) {
+	if argsExport.output == "" {
+		if err := fn(os.Stdout); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		return
+	}
+
+	fd, err := os.Create(argsExport.output)
+	if err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	if err := fn(fd); err != nil {
+		_ = fd.Close()
+		log.Fatalf("error: %v\n", err)
+	} else if err := fd.Close(); err != nil {
+		log.Fatalf("error: %v\n", err)
+	}
+	fmt.Printf("export: created %s\n", argsExport.output)
+}
+
+// dumpMerged writes the merged tiles to the file in the format, which is
+// chosen from the extension of the file when empty.
+func dumpMerged(worldMap *tiles.Map_t, path, format string) error {
+	f, err := export.FormatOf(format, path)
+	if err != nil {
+		return err
+	}
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := export.WriteMerged(fd, export.Merged(worldMap, argsRender.render.Notes), f); err != nil {
+		_ = fd.Close()
+		return err
+	}
+	return fd.Close()
+}
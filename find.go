@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/search"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+var cmdFind = &cobra.Command{
+	Use:   "find query",
+	Short: "search the map for settlements, units, and notes",
+	Long: `Load the turn reports and print the settlements, special hexes, units, and annotations
+whose names or text contain the query, ignoring case, with the hex and the turn they were first seen.`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if argsRender.paths.store != "" {
+			if path, err := filepath.Abs(argsRender.paths.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else {
+				argsRender.paths.store = path
+			}
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		query := strings.Join(args, " ")
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		matches := search.Find(query, w.tiles, w.turns, argsRender.mapper.Show.Annotations)
+		if len(matches) == 0 {
+			log.Printf("find: %q: not found\n", query)
+			os.Exit(1)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Kind\tName\tHex\tFirst Seen\n")
+		for _, m := range matches {
+			hex := "N/A"
+			if !m.Location.IsZero() {
+				hex = m.Location.GridString()
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", m.Kind, m.Name, hex, m.FirstSeen)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/gen"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"github.com/playbymail/ottomap/internal/turns"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+var argsGen struct {
+	seed        int64  // selects the map, random if zero
+	size        string // "COLUMNSxROWS" in hexes
+	origin      string // upper left hex of the map
+	land        float64
+	rivers      int
+	settlements int
+	clanId      string
+	turnId      string
+	format      string // merged or tniif
+	output      string // path to the output file, stdout if empty
+}
+
+var cmdGen = &cobra.Command{
+	Use:   "gen",
+	Short: "generate a synthetic map for testing and demos",
+	Long: `Generate a plausible map with continents, rivers, and settlements, without any turn reports.
+
+The merged format is the same as render's --dump-merged, so the map can be drawn with "render --from-merged".
+The tniif format is a document for a turn in which the clan walks over every hex.
+The same seed and options always give the same map. Without --seed a random seed is used and logged.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if argsGen.seed == 0 {
+			var buf [8]byte
+			if _, err := rand.Read(buf[:]); err != nil {
+				log.Fatalf("error: seed: %v\n", err)
+			}
+			argsGen.seed = int64(binary.LittleEndian.Uint64(buf[:]))
+		}
+		opts := gen.Options_t{
+			Seed:        argsGen.seed,
+			Land:        argsGen.land,
+			Rivers:      argsGen.rivers,
+			Settlements: argsGen.settlements,
+			TurnId:      argsGen.turnId,
+			ClanId:      parser.UnitId_t(argsGen.clanId),
+		}
+		var err error
+		if opts.Columns, opts.Rows, err = parsePageSize(argsGen.size); err != nil {
+			log.Fatalf("error: size: %v\n", err)
+		} else if opts.Origin, err = coords.HexToMap(argsGen.origin); err != nil {
+			log.Fatalf("error: origin: %q: %v\n", argsGen.origin, err)
+		} else if _, _, err = turns.ParseTurnId(argsGen.turnId); err != nil {
+			log.Fatalf("error: turn: %v\n", err)
+		}
+
+		worldMap, err := gen.Generate(opts)
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		log.Printf("gen: seed %d: %d tiles\n", argsGen.seed, worldMap.Length())
+
+		switch argsGen.format {
+		case "merged":
+			format, err := export.FormatOf("", argsGen.output)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			b := &bytes.Buffer{}
+			if err := export.WriteMerged(b, export.Merged(worldMap, wxx.NotePolicy{}), format); err != nil {
+				log.Fatalf("error: %v\n", err)
+			} else if err := writeGenOutput(b.Bytes()); err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+		case "tniif":
+			turn, err := gen.Turn(worldMap, opts)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			doc := tniif.FromTurn(fmt.Sprintf("%s.%s", opts.TurnId, opts.ClanId), turn)
+			if err := writeDocument(argsGen.output, doc); err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+		default:
+			log.Fatalf("error: format must be merged or tniif\n")
+		}
+		if argsGen.output != "" {
+			log.Printf("gen: created %s\n", argsGen.output)
+		}
+	},
+}
+
+var argsGenReport struct {
+	output string // path to the report file, stdout if empty
+	expect string // path to write the expected document to
+}
+
+var cmdGenReport = &cobra.Command{
+	Use:   "report scenario.json",
+	Short: "generate a turn report from a scenario",
+	Long: `Generate the text of a turn report from a scenario, which is a tniif document.
+
+Line and step numbers in the scenario are ignored. The expected document is what "parse file" should
+return for the report, so the two can be compared with "tniif diff" when reproducing a parser bug.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		scenario, err := readDocument(args[0])
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		text, want, err := testkit.Report(scenario)
+		if err != nil {
+			log.Fatalf("error: %s: %v\n", args[0], err)
+		}
+		if argsGenReport.output == "" {
+			_, err = os.Stdout.Write(text)
+		} else {
+			err = os.WriteFile(argsGenReport.output, text, 0o644)
+		}
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		if argsGenReport.expect != "" {
+			if err := writeDocument(argsGenReport.expect, want); err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			log.Printf("gen: created %s\n", argsGenReport.expect)
+		}
+		if argsGenReport.output != "" {
+			log.Printf("gen: created %s\n", argsGenReport.output)
+		}
+	},
+}
+
+// writeGenOutput writes the data to the output file, or stdout if there isn't one.
+func writeGenOutput(data []byte) error {
+	if argsGen.output == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(argsGen.output, data, 0o644)
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/ingest"
+	"github.com/playbymail/ottomap/internal/stdlib"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var argsIngest struct {
+	data  string // path to root of data files
+	force bool   // if true, replace existing reports with different contents
+}
+
+var cmdIngest = &cobra.Command{
+	Use:   "ingest path...",
+	Short: "Stage turn reports from zip archives and email exports",
+	Long: `Extract the docx, odt, rtf, and txt reports from zip archives, .eml files, or folders of .eml and .zip files.
+Documents are converted to text, and each report is saved in the input folder as YYYY-MM.0NNN.report.txt
+using the turn and unit from its name or contents. Reports for elements are saved as the report for the clan.`,
+	Args: cobra.MinimumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if path, err := abspath(filepath.Join(argsIngest.data, "input")); err != nil {
+			return fmt.Errorf("data: %v", err)
+		} else if ok, err := stdlib.IsDirExists(path); err != nil {
+			return fmt.Errorf("data: %v", err)
+		} else if !ok {
+			return fmt.Errorf("data: %s: is not a directory", path)
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		input := filepath.Join(argsIngest.data, "input")
+
+		var attachments []*ingest.Attachment_t
+		for _, arg := range args {
+			list, err := ingestPath(arg)
+			if err != nil {
+				log.Fatalf("error: ingest: %v\n", err)
+			}
+			attachments = append(attachments, list...)
+		}
+
+		staged, skipped := 0, 0
+		for _, attachment := range attachments {
+			name, text, fixes, err := attachment.Report()
+			if err != nil {
+				log.Printf("warn: ingest: %s: %v\n", attachment.Source, err)
+				skipped++
+				continue
+			} else if len(fixes) != 0 {
+				log.Printf("warn: ingest: %s: %s: %s\n", attachment.Source, attachment.Name, strings.Join(fixes, ", "))
+			}
+			path := filepath.Join(input, name)
+			if existing, err := os.ReadFile(path); err == nil {
+				if bytes.Equal(existing, text) {
+					log.Printf("ingest: %s: %s: already staged as %s\n", attachment.Source, attachment.Name, name)
+					continue
+				} else if !argsIngest.force {
+					log.Printf("warn: ingest: %s: %s: %s exists with different contents (use --force to replace)\n", attachment.Source, attachment.Name, name)
+					skipped++
+					continue
+				}
+			}
+			if err := os.WriteFile(path, text, 0644); err != nil {
+				log.Fatalf("error: ingest: %s: %v\n", path, err)
+			}
+			log.Printf("ingest: %s: %s: staged as %s\n", attachment.Source, attachment.Name, name)
+			staged++
+		}
+		log.Printf("ingest: %d reports staged, %d skipped\n", staged, skipped)
+	},
+}
+
+// ingestPath returns the reports in a zip archive, an email, or a folder of archives and emails.
+func ingestPath(path string) ([]*ingest.Attachment_t, error) {
+	sb, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !sb.IsDir() {
+		return ingestFile(path)
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var list []*ingest.Attachment_t
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".eml", ".zip":
+		default:
+			continue
+		}
+		if entry.IsDir() {
+			continue
+		}
+		attachments, err := ingestFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, attachments...)
+	}
+	return list, nil
+}
+
+// ingestFile returns the reports in a zip archive or an email.
+func ingestFile(path string) ([]*ingest.Attachment_t, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".eml":
+		return ingest.FromEmail(path, data)
+	case ".zip":
+		return ingest.FromZip(path, data)
+	}
+	return nil, fmt.Errorf("%s: expected a .zip or .eml file", path)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package annotations loads the optional annotations.json file from the data folder.
+//
+// Annotations are things a player wants on the map that the turn reports don't
+// contain, for example "ambush here 0903-02". They are kept in their own file so
+// that they survive when the map is rendered again.
+//
+// The file is an object keyed by grid coordinates, with a list of annotations for each hex:
+//
+//	{
+//	  "AB 1203": [
+//	    {"label": "Ambush", "note": "ambush here 0903-02", "color": "#ff0000"},
+//	    {"icon": "Settlement Ruins"}
+//	  ]
+//	}
+package annotations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// Annotation_t is something to add to a hex on the map.
+// At least one of the label, note, or icon must be set.
+type Annotation_t struct {
+	Label string `json:"label,omitempty"` // text drawn on the map
+	Note  string `json:"note,omitempty"`  // text shown in the note for the icon
+	Icon  string `json:"icon,omitempty"`  // Worldographer feature type, defaults to DefaultIcon for notes
+	Color string `json:"color,omitempty"` // "#rrggbb" for the icon, optional
+}
+
+// Hex_t is the list of annotations for a single hex.
+type Hex_t struct {
+	Location    coords.Map
+	Annotations []*Annotation_t
+}
+
+// DefaultIcon is used for annotations that have a note but no icon,
+// since Worldographer attaches notes to features.
+const DefaultIcon = "Symbol Point-of-Interest"
+
+var rxColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Load reads the annotations from a file and returns them sorted by location.
+// If the file doesn't exist, no annotations are returned.
+func Load(path string) ([]*Hex_t, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates the annotations.
+func Parse(data []byte) ([]*Hex_t, error) {
+	var raw map[string][]*Annotation_t
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	var hexes []*Hex_t
+	for hex, list := range raw {
+		location, err := coords.HexToMap(hex)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", hex, err)
+		}
+		for n, a := range list {
+			if a == nil || (a.Label == "" && a.Note == "" && a.Icon == "") {
+				return nil, fmt.Errorf("%s: annotation %d: need a label, note, or icon", hex, n+1)
+			} else if a.Color != "" && !rxColor.MatchString(a.Color) {
+				return nil, fmt.Errorf("%s: annotation %d: invalid color %q", hex, n+1, a.Color)
+			}
+			if a.Icon == "" && a.Note != "" {
+				a.Icon = DefaultIcon
+			}
+		}
+		hexes = append(hexes, &Hex_t{Location: location, Annotations: list})
+	}
+	sort.Slice(hexes, func(i, j int) bool {
+		return hexes[i].Location.GridString() < hexes[j].Location.GridString()
+	})
+	return hexes, nil
+}
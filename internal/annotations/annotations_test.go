@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package annotations_test
+
+import (
+	"github.com/playbymail/ottomap/internal/annotations"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	hexes, err := annotations.Parse([]byte(`{
+		"AB 1204": [{"icon": "Settlement Ruins"}],
+		"AB 1203": [{"label": "Ambush", "note": "ambush here 0903-02", "color": "#ff0000"}, {"label": "Camp"}]
+	}`))
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if len(hexes) != 2 {
+		t.Fatalf("hexes: want 2, got %d", len(hexes))
+	}
+	if got := hexes[0].Location.GridString(); got != "AB 1203" {
+		t.Errorf("hex 0: want %q, got %q", "AB 1203", got)
+	}
+	if got := len(hexes[0].Annotations); got != 2 {
+		t.Fatalf("hex 0: annotations: want 2, got %d", got)
+	}
+	if got := hexes[0].Annotations[0].Icon; got != annotations.DefaultIcon {
+		t.Errorf("note without icon: want %q, got %q", annotations.DefaultIcon, got)
+	}
+	if got := hexes[0].Annotations[1].Icon; got != "" {
+		t.Errorf("label without note: want no icon, got %q", got)
+	}
+
+	for _, bad := range []string{
+		`[]`,
+		`{"AB12": [{"label": "Camp"}]}`,
+		`{"AB 1203": [{}]}`,
+		`{"AB 1203": [{"label": "Camp", "color": "red"}]}`,
+	} {
+		if _, err := annotations.Parse([]byte(bad)); err == nil {
+			t.Errorf("%s: want error, got nil", bad)
+		}
+	}
+}
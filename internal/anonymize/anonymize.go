@@ -0,0 +1,411 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package anonymize rewrites turn reports and tniif documents so that they
+// can be shared publicly, for example to reproduce a parser bug.
+//
+// Coordinates are shifted, clan numbers are remapped, and settlement and
+// special hex names are replaced. The structure of the report is kept, so
+// the anonymized report parses the same way as the original. The same
+// Anonymizer_t must be used for all the files that are shared together.
+//
+// A four digit number is only remapped when it is the id of a unit in the
+// files, so item counts and years are kept. Coordinates are never wrapped
+// around the edge of the map; check Err before sharing the output.
+package anonymize
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"log"
+	"math/rand"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// the map is 26 by 26 grids, and each grid is 30 columns by 21 rows
+const (
+	mapColumns = 26 * 30
+	mapRows    = 26 * 21
+)
+
+var (
+	// rxToken matches grid coordinates, obscured coordinates, and numbers that may be unit ids
+	rxToken = regexp.MustCompile(`\b([A-Z]{2}) (\d{4})\b|## (\d{4})\b|\b(\d{4})([cefg]\d)?\b`)
+	// rxUnitHeader matches the unit id in a section header
+	rxUnitHeader = regexp.MustCompile(`^(?:Courier|Element|Fleet|Garrison|Tribe) (\d{4}(?:[cefg]\d)?),`)
+	// rxSectionHeader matches the unit name in a section header
+	rxSectionHeader = regexp.MustCompile(`^((?:Courier|Element|Fleet|Garrison|Tribe) \d{4}(?:[cefg]\d)?, )([^,]*)(, )`)
+)
+
+// Anonymizer_t holds the mappings so that every file is rewritten the same way.
+type Anonymizer_t struct {
+	rng      *rand.Rand
+	columns  int // columns to shift, always even so that hex neighbors don't change
+	rows     int
+	clans    map[string]string // three digit clan number to the replacement
+	used     map[string]bool   // clan numbers already handed out
+	names    map[string]string // settlement and special hex names
+	specials map[string]string // special hex ids
+	units    map[string]bool   // unit ids found in the files
+	err      error             // first coordinate that was shifted off the map
+}
+
+// New returns an anonymizer. The seed selects the coordinate shift and the
+// clan numbers, so the same seed and inputs always give the same output.
+func New(seed int64) *Anonymizer_t {
+	a := &Anonymizer_t{
+		rng:      rand.New(rand.NewSource(seed)),
+		clans:    map[string]string{},
+		used:     map[string]bool{},
+		names:    map[string]string{},
+		specials: map[string]string{},
+		units:    map[string]bool{},
+	}
+	a.columns = 2 * (1 + a.rng.Intn(mapColumns/2-1))
+	a.rows = 1 + a.rng.Intn(mapRows-1)
+	return a
+}
+
+// Err returns an error if a coordinate was shifted off the map.
+// Wrapping it around to the other edge would make hexes that aren't neighbors
+// look like neighbors, so the output must not be used; try another seed.
+func (a *Anonymizer_t) Err() error {
+	return a.err
+}
+
+// Hex returns the shifted grid coordinates. Obscured and invalid coordinates are returned unchanged.
+// If the shift moves the hex off the map, the error is reported by Err.
+func (a *Anonymizer_t) Hex(hex string) string {
+	m, err := coords.HexToMap(hex)
+	if err != nil {
+		return hex
+	}
+	m.Column, m.Row = m.Column+a.columns, m.Row+a.rows
+	if m.Column >= mapColumns || m.Row >= mapRows {
+		if a.err == nil {
+			a.err = fmt.Errorf("%s: shift of %d columns and %d rows moves it off the map", hex, a.columns, a.rows)
+		}
+		m.Column, m.Row = m.Column%mapColumns, m.Row%mapRows
+	}
+	return m.ToHex()
+}
+
+// UnitId returns the unit id with the clan number remapped.
+// The first digit and the suffix of the unit id are kept.
+func (a *Anonymizer_t) UnitId(id string) string {
+	if len(id) < 4 {
+		return id
+	}
+	return id[:1] + a.clan(id[1:4]) + id[4:]
+}
+
+func (a *Anonymizer_t) clan(number string) string {
+	if number == "000" {
+		return number
+	} else if clan, ok := a.clans[number]; ok {
+		return clan
+	}
+	for {
+		clan := fmt.Sprintf("%03d", 1+a.rng.Intn(999))
+		if !a.used[clan] {
+			a.clans[number], a.used[clan] = clan, true
+			return clan
+		}
+	}
+}
+
+// Name returns the replacement for a settlement or special hex name.
+func (a *Anonymizer_t) Name(name string) string {
+	if name == "" {
+		return name
+	} else if alias, ok := a.names[name]; ok {
+		return alias
+	}
+	alias := "Hamlet " + letters(len(a.names))
+	a.names[name] = alias
+	return alias
+}
+
+// SpecialId returns the replacement for a special hex id.
+func (a *Anonymizer_t) SpecialId(id string) string {
+	if alias, ok := a.specials[id]; ok {
+		return alias
+	}
+	alias := "special-" + strings.ToLower(letters(len(a.specials)))
+	a.specials[id] = alias
+	return alias
+}
+
+// letters returns n in base 26 using the letters A to Z.
+func letters(n int) string {
+	s := string(rune('A' + n%26))
+	for n = n / 26; n > 0; n = n/26 - 1 {
+		s = string(rune('A'+(n-1)%26)) + s
+	}
+	return s
+}
+
+// Report returns an anonymized copy of the report text.
+//
+// The report is parsed first to find the settlement names. If the parser
+// fails, the names found before the failure are used and a warning is logged,
+// so a report that triggers a parser bug can still be anonymized.
+func (a *Anonymizer_t) Report(fid, tid string, data []byte) []byte {
+	turn, err := parser.ParseInput(context.Background(), fid, tid, data, true, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		log.Printf("warn: %s: anonymize: %v: settlement names after the error are not replaced\n", fid, err)
+	}
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		if m := rxUnitHeader.FindSubmatch(line); m != nil {
+			a.units[string(m[1])] = true
+		}
+	}
+	if turn != nil {
+		a.turnUnits(turn)
+	}
+
+	// replace the longest names first so that a name that contains another isn't mangled
+	var names []string
+	if turn != nil {
+		for _, moves := range turn.UnitMoves {
+			for _, move := range moves.Moves {
+				names = append(names, settlementNames(move.Report)...)
+			}
+			for _, scout := range moves.Scouts {
+				for _, move := range scout.Moves {
+					names = append(names, settlementNames(move.Report)...)
+				}
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	lines := bytes.Split(data, []byte{'\n'})
+	for n, line := range lines {
+		if bytes.HasPrefix(line, []byte(">>>>")) {
+			special, _ := parser.ParseSpecialHex("", line)
+			lines[n] = []byte(fmt.Sprintf(">>>>%s>%s", a.SpecialId(special.Id), a.Name(special.Name)))
+			if !special.Location.IsZero() {
+				lines[n] = append(lines[n], []byte(">!at "+a.Hex(special.Location.GridString()))...)
+			}
+			// notes are free text, so they are dropped; the other directives don't name anything
+			for _, d := range special.Directives {
+				if d.Kind != parser.NoteDirective {
+					lines[n] = append(lines[n], []byte(">"+d.String())...)
+				}
+			}
+			continue
+		}
+		line = rxSectionHeader.ReplaceAll(line, []byte("${1}${3}"))
+		for _, name := range names {
+			line = replaceWord(line, name, a.Name(name))
+		}
+		lines[n] = a.tokens(line)
+	}
+	return bytes.Join(lines, []byte{'\n'})
+}
+
+// turnUnits adds the ids of the units that moved, were followed, or were encountered.
+func (a *Anonymizer_t) turnUnits(turn *parser.Turn_t) {
+	add := func(moves []*parser.Move_t) {
+		for _, move := range moves {
+			a.addUnit(string(move.Follows))
+			if move.Report == nil {
+				continue
+			}
+			for _, e := range move.Report.Encounters {
+				a.addUnit(string(e.UnitId))
+			}
+			for _, s := range move.Report.Settlements {
+				a.addUnit(string(s.Owner))
+			}
+		}
+	}
+	for id, moves := range turn.UnitMoves {
+		a.addUnit(string(id))
+		a.addUnit(string(moves.Follows))
+		add(moves.Moves)
+		for _, scout := range moves.Scouts {
+			add(scout.Moves)
+		}
+	}
+}
+
+func (a *Anonymizer_t) addUnit(id string) {
+	if id != "" {
+		a.units[id] = true
+	}
+}
+
+func settlementNames(r *parser.Report_t) (names []string) {
+	if r == nil {
+		return nil
+	}
+	for _, s := range r.Settlements {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// tokens replaces the grid coordinates and unit ids in the text.
+// Obscured coordinates and numbers that aren't the id of a unit in the files,
+// like item counts and the year of a turn, are left alone.
+func (a *Anonymizer_t) tokens(line []byte) []byte {
+	var b []byte
+	prev := 0
+	for _, m := range rxToken.FindAllSubmatchIndex(line, -1) {
+		b = append(b, line[prev:m[0]]...)
+		prev = m[1]
+		token := string(line[m[0]:m[1]])
+		if m[2] != -1 { // grid coordinates
+			b = append(b, a.Hex(token)...)
+		} else if m[6] != -1 || (m[10] == -1 && !a.units[token]) {
+			b = append(b, token...)
+		} else {
+			b = append(b, a.UnitId(token)...)
+		}
+	}
+	return append(b, line[prev:]...)
+}
+
+// replaceWord replaces occurrences of old that aren't part of a longer word.
+func replaceWord(line []byte, old, new string) []byte {
+	isWord := func(ch byte) bool {
+		return ch == '_' || ('0' <= ch && ch <= '9') || ('A' <= ch && ch <= 'Z') || ('a' <= ch && ch <= 'z')
+	}
+	var b []byte
+	for {
+		i := bytes.Index(line, []byte(old))
+		if i == -1 {
+			return append(b, line...)
+		}
+		end := i + len(old)
+		if (i > 0 && isWord(line[i-1])) || (end < len(line) && isWord(line[end])) {
+			b, line = append(b, line[:end]...), line[end:]
+			continue
+		}
+		b, line = append(append(b, line[:i]...), new...), line[end:]
+	}
+}
+
+// Document returns an anonymized copy of the document.
+func (a *Anonymizer_t) Document(doc *tniif.Document_t) *tniif.Document_t {
+	out := &tniif.Document_t{Source: a.source(doc.Source), Turn: doc.Turn, Units: []*tniif.Unit_t{}}
+	// map the settlement names and find the unit ids first so that they are replaced in the status lines
+	for _, u := range doc.Units {
+		a.addUnit(u.Id)
+		a.addUnit(u.Follows)
+		moves := slices.Clone(u.Moves)
+		for _, s := range u.Scouts {
+			moves = append(moves, s.Moves...)
+		}
+		for _, m := range moves {
+			a.addUnit(m.Follows)
+			if m.Report != nil {
+				for _, s := range m.Report.Settlements {
+					a.Name(s)
+				}
+				for _, e := range m.Report.Encounters {
+					a.addUnit(e)
+				}
+			}
+		}
+	}
+	for _, u := range doc.Units {
+		unit := &tniif.Unit_t{
+			Id:          a.UnitId(u.Id),
+			PreviousHex: a.Hex(u.PreviousHex),
+			CurrentHex:  a.Hex(u.CurrentHex),
+			Follows:     u.Follows,
+			GoesTo:      a.Hex(u.GoesTo),
+			Status:      string(a.text(u.Status)),
+		}
+		if unit.Follows != "" {
+			unit.Follows = a.UnitId(unit.Follows)
+		}
+		for _, m := range u.Moves {
+			unit.Moves = append(unit.Moves, a.move(m))
+		}
+		for _, s := range u.Scouts {
+			scout := &tniif.Scout_t{No: s.No, Line: s.Line}
+			for _, m := range s.Moves {
+				scout.Moves = append(scout.Moves, a.move(m))
+			}
+			unit.Scouts = append(unit.Scouts, scout)
+		}
+		out.Units = append(out.Units, unit)
+	}
+	sort.Slice(out.Units, func(i, j int) bool {
+		return out.Units[i].Id < out.Units[j].Id
+	})
+	for _, s := range doc.Specials {
+		out.Specials = append(out.Specials, &tniif.Special_t{Id: a.SpecialId(s.Id), Name: a.Name(s.Name)})
+	}
+	sort.Slice(out.Specials, func(i, j int) bool {
+		return out.Specials[i].Id < out.Specials[j].Id
+	})
+	for _, e := range doc.Errors {
+		out.Errors = append(out.Errors, string(a.text(e)))
+	}
+	return out
+}
+
+// source remaps the clan in a report id like "0902-02.0138".
+func (a *Anonymizer_t) source(source string) string {
+	var sources []string
+	for _, s := range strings.Split(source, ",") {
+		if turn, clan, ok := strings.Cut(s, "."); ok {
+			s = turn + "." + a.UnitId(clan)
+		}
+		sources = append(sources, s)
+	}
+	return strings.Join(sources, ",")
+}
+
+// text replaces known names, grid coordinates, and unit ids in free text.
+func (a *Anonymizer_t) text(s string) []byte {
+	line := []byte(s)
+	var names []string
+	for name := range a.names {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return len(names[i]) > len(names[j])
+	})
+	for _, name := range names {
+		line = replaceWord(line, name, a.names[name])
+	}
+	return a.tokens(line)
+}
+
+func (a *Anonymizer_t) move(m *tniif.Move_t) *tniif.Move_t {
+	move := *m
+	move.Follows, move.GoesTo = m.Follows, a.Hex(m.GoesTo)
+	if move.Follows != "" {
+		move.Follows = a.UnitId(move.Follows)
+	}
+	if m.Report != nil {
+		report := *m.Report
+		report.Encounters = nil
+		for _, e := range m.Report.Encounters {
+			report.Encounters = append(report.Encounters, a.UnitId(e))
+		}
+		report.Settlements = nil
+		for _, s := range m.Report.Settlements {
+			report.Settlements = append(report.Settlements, a.Name(s))
+		}
+		move.Report = &report
+	}
+	return &move
+}
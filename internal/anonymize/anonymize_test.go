@@ -0,0 +1,124 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package anonymize_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/anonymize"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestHex(t *testing.T) {
+	a := anonymize.New(42)
+	for _, tc := range []struct {
+		id   int
+		from string
+	}{
+		{id: 1, from: "QB 1008"},
+		{id: 2, from: "AA 0101"},
+		{id: 3, from: "CD 3021"},
+	} {
+		got := a.Hex(tc.from)
+		if got == tc.from {
+			t.Errorf("%d: %q: not shifted", tc.id, tc.from)
+		}
+		if again := a.Hex(tc.from); again != got {
+			t.Errorf("%d: %q: want %q, got %q", tc.id, tc.from, got, again)
+		}
+		// neighbors must stay neighbors
+		for _, d := range []direction.Direction_e{direction.North, direction.NorthEast, direction.SouthEast, direction.South, direction.SouthWest, direction.NorthWest} {
+			if want, got := a.Hex(coords.Move(tc.from, d)), coords.Move(got, d); want != got {
+				t.Errorf("%d: %q: %s: want %q, got %q", tc.id, tc.from, d, want, got)
+			}
+		}
+	}
+	if got := a.Hex("## 1008"); got != "## 1008" {
+		t.Errorf("obscured: want %q, got %q", "## 1008", got)
+	}
+	if err := a.Err(); err != nil {
+		t.Errorf("err: want nil, got %v", err)
+	}
+	// the last hex on the map can't be shifted without wrapping around
+	a.Hex("ZZ 3021")
+	if a.Err() == nil {
+		t.Errorf("edge: want error")
+	}
+}
+
+// TestUnitIds checks that only the ids of units are remapped, not item counts or years.
+func TestUnitIds(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	report := "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n" +
+		"Current Turn 1012-02 (#26), Winter, FINE\tNext Turn 1012-03 (#27), 28/10/2023\n" +
+		"Tribe Movement: Move N-PR\\\n" +
+		"0138 Status: PRAIRIE, 0138, 1590c2\n" +
+		"Humans 1590\n"
+	a := anonymize.New(1)
+	anon := string(a.Report("1012-02.0138", "1012-02", []byte(report)))
+	clan, element := a.UnitId("0138"), a.UnitId("1590c2")
+	for _, want := range []string{
+		"Tribe " + clan + ", , Current Hex",
+		"Current Turn 1012-02 (#26)",
+		"Next Turn 1012-03 (#27), 28/10/2023",
+		clan + " Status: PRAIRIE, " + clan + ", " + element,
+		"Humans 1590",
+	} {
+		if !strings.Contains(anon, want) {
+			t.Errorf("want %q in\n%s", want, anon)
+		}
+	}
+}
+
+// TestReport checks that an anonymized report parses to the anonymized document of the original report.
+func TestReport(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		for name, data := range tc.Reports {
+			fid, tid := strings.TrimSuffix(name, ".report.txt"), name[:7]
+			data, _ = extract.Normalize(data)
+			data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+
+			a := anonymize.New(1)
+			anon := a.Report(fid, tid, data)
+			if bytes.Contains(anon, []byte("0138")) {
+				t.Errorf("%s: %s: anonymized report contains the clan number", tc.Name, fid)
+			}
+
+			turn, err := parser.ParseInput(context.Background(), fid, tid, data, true, false, false, false, false, false, false, false, parser.ParseConfig{})
+			if err != nil {
+				t.Fatalf("%s: %s: original: %v", tc.Name, fid, err)
+			}
+			want, _ := json.MarshalIndent(a.Document(tniif.FromTurn(fid, turn)), "", "  ")
+
+			anonFid := tid + "." + a.UnitId(fid[8:])
+			turn, err = parser.ParseInput(context.Background(), anonFid, tid, anon, true, false, false, false, false, false, false, false, parser.ParseConfig{})
+			if err != nil {
+				t.Fatalf("%s: %s: anonymized: %v", tc.Name, fid, err)
+			}
+			got, _ := json.MarshalIndent(tniif.FromTurn(anonFid, turn), "", "  ")
+
+			for _, line := range testkit.Diff(want, got, 20) {
+				t.Errorf("%s: %s: %s", tc.Name, fid, line)
+			}
+		}
+	}
+}
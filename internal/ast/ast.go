@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package ast
+
+// Report_t is the abstract syntax tree for a turn report.
+// It is built from the concrete syntax tree by the cst package.
+type Report_t struct {
+	Units []*Unit_t
+}
+
+// Unit_t is the section of the report for a single unit.
+type Unit_t struct {
+	Id          string
+	Line        int    // line number of the section header
+	CurrentHex  string // from the section header, for example "QQ 1008" or "## 1008"
+	PreviousHex string // from the section header, for example "QQ 1010" or "N/A"
+	Turn        *Turn_t
+	Lines       []*Line_t // the lines in the section that we know how to parse
+}
+
+// Turn_t is the turn from the "Current Turn" line of a section.
+type Turn_t struct {
+	Line  int
+	Year  int
+	Month int
+}
+
+// Line_t is a line from a section, with its type.
+// Text is the original text of the line, without the end of line.
+type Line_t struct {
+	No   int
+	Type LineType
+	Text []byte
+}
+
+type LineType int
+
+const (
+	UNKNOWN_LINE LineType = iota
+	FLEET_MOVEMENT
+	SCOUT_MOVEMENT
+	SPECIAL_HEX
+	STATUS
+	TRIBE_FOLLOWS
+	TRIBE_GOES_TO
+	TRIBE_MOVEMENT
+	UNIT_EVENT
+	SETTLEMENT_OWNER
+)
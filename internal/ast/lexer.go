@@ -38,23 +38,35 @@ func (l *Lexer) Next() Token {
 
 	// we are going to chunk the input into four categories:
 	// 1. run of invalid utf8 (type will be INVALID_UTF8)
-	// 3. newline (type will be EOL)
-	// 2. run of whitespace (type will be SPACES)
+	// 2. newline (type will be EOL)
+	// 3. run of whitespace (type will be SPACES)
 	// 4. words (type will be WORD)
-	start := l.pos
-	r, w := utf8.DecodeRune(l.input[start:])
-	l.pos, l.col = l.pos+w, l.col+1
-
-	if r == utf8.RuneError {
-		// invalid utf8 sequence means we must advance the input to the the first valid rune
-		for l.pos < len(l.input) {
-			if r, w = utf8.DecodeRune(l.input[l.pos:]); r != utf8.RuneError {
-				break
-			}
-			l.pos, l.col = l.pos+w, l.col+1
-		}
+	input := l.input[l.pos:]
+	var lexeme, rest []byte
+	if lexeme, rest = AcceptInvalidRunes(input); lexeme != nil {
 		tok.Type = INVALID_UTF8
-		return tok
+	} else if lexeme, rest = AcceptEOL(input); lexeme != nil {
+		tok.Type = EOL
+	} else if lexeme, rest = AcceptWhitespace(input); lexeme != nil {
+		tok.Type = SPACES
+	} else if lexeme, rest = AcceptText(input); lexeme != nil {
+		tok.Type = WORD
+	} else {
+		// should never happen, but we must always make progress
+		_, w := utf8.DecodeRune(input)
+		lexeme, rest, tok.Type = input[:w], input[w:], INVALID_UTF8
+	}
+	tok.Literal = append([]byte{}, lexeme...)
+
+	// AcceptEOL converts all line endings to a single newline, so we use the
+	// length of the remaining input to advance past the original bytes.
+	l.pos = len(l.input) - len(rest)
+	if tok.Type == EOL {
+		l.line, l.col = l.line+1, 1
+	} else if tok.Type == INVALID_UTF8 {
+		l.col += len(lexeme)
+	} else {
+		l.col += utf8.RuneCount(lexeme)
 	}
 
 	return tok
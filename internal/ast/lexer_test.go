@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package ast_test
+
+import (
+	"github.com/playbymail/ottomap/internal/ast"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	type token struct {
+		typ       ast.TokenType
+		line, col int
+		literal   string
+	}
+	tests := []struct {
+		id     int
+		input  string
+		tokens []token
+	}{
+		{1, "", []token{{ast.EOF, 1, 1, ""}}},
+		{2, "Tribe 0138,", []token{{ast.WORD, 1, 1, "Tribe"}, {ast.SPACES, 1, 6, " "}, {ast.WORD, 1, 7, "0138,"}, {ast.EOF, 1, 12, ""}}},
+		{3, "a\r\nb\rc\n", []token{{ast.WORD, 1, 1, "a"}, {ast.EOL, 1, 2, "\n"}, {ast.WORD, 2, 1, "b"}, {ast.EOL, 2, 2, "\n"}, {ast.WORD, 3, 1, "c"}, {ast.EOL, 3, 2, "\n"}, {ast.EOF, 4, 1, ""}}},
+		{4, "(## 1108)", []token{{ast.WORD, 1, 1, "("}, {ast.WORD, 1, 2, "#"}, {ast.WORD, 1, 3, "#"}, {ast.SPACES, 1, 4, " "}, {ast.WORD, 1, 5, "1108)"}, {ast.EOF, 1, 10, ""}}},
+		{5, "x\xff\xfey", []token{{ast.WORD, 1, 1, "x"}, {ast.INVALID_UTF8, 1, 2, "\xff\xfe"}, {ast.WORD, 1, 4, "y"}, {ast.EOF, 1, 5, ""}}},
+	}
+
+	for _, tt := range tests {
+		l := ast.NewLexer([]byte(tt.input))
+		for n, want := range tt.tokens {
+			got := l.Next()
+			if got.Type != want.typ || got.Line != want.line || got.Col != want.col || string(got.Literal) != want.literal {
+				t.Errorf("lexer(%d): token %d: want %d %d:%d %q: got %d %d:%d %q", tt.id, n+1, want.typ, want.line, want.col, want.literal, got.Type, got.Line, got.Col, string(got.Literal))
+				break
+			}
+		}
+	}
+}
@@ -26,6 +26,17 @@ const (
 	NorthNorthWest
 )
 
+// FromBearing returns the compass point closest to the bearing.
+// The bearing is in degrees, measured clockwise from north.
+// The points are 30 degrees apart, starting with North at zero.
+func FromBearing(degrees float64) Point_e {
+	for degrees < 0 {
+		degrees += 360
+	}
+	n := int((degrees+15)/30) % 12
+	return Point_e(n + int(North))
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (p Point_e) MarshalJSON() ([]byte, error) {
 	return json.Marshal(EnumToString[p])
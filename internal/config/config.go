@@ -0,0 +1,727 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package config loads the optional ottomap.json file from the data folder.
+// Settings that are missing from the file keep their zero values.
+//
+// The file may have named profiles and per-clan sections. Each is a partial
+// configuration that is applied on top of the settings before it: first the
+// profile, then the section for the clan. Objects in a section are merged
+// with the settings below them; lists and values replace them.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/notify"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/transform"
+	"github.com/playbymail/ottomap/internal/units"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Config_t is the contents of the configuration file.
+type Config_t struct {
+	Colors     Colors_t        `json:"colors"`
+	Elevations Elevations_t    `json:"elevations"`
+	Encounters Encounters_t    `json:"encounters"`
+	Hexes      Hexes_t         `json:"hexes"`
+	Layers     map[string]bool `json:"layers"` // defaults for the render --show-* flags, for example "heatmap"
+	Legend     Legend_t        `json:"legend"`
+	Notes      Notes_t         `json:"notes"`
+	Notify     Notify_t        `json:"notify"`
+	Output     Output_t        `json:"output"`
+	Regions    []Region_t      `json:"regions"`
+	Rivers     []River_t       `json:"rivers"`
+	Seasons    Seasons_t       `json:"seasons"`
+	Symbols    Symbols_t       `json:"symbols"`
+	Transforms []Transform_t   `json:"transforms"`
+	World      World_t         `json:"world"`
+
+	Profiles map[string]json.RawMessage `json:"profiles"` // named partial configurations
+	Clans    map[string]json.RawMessage `json:"clans"`    // partial configurations by clan id, for example "0138"
+
+	// Sources is where each setting came from, keyed by its path in the file,
+	// for example "legend.title". Settings that aren't in the file aren't listed.
+	Sources map[string]string `json:"-"`
+}
+
+// DefaultProfile is applied when no profile is requested and the file has one with this name.
+const DefaultProfile = "default"
+
+// Colors_t overrides the default colors.
+type Colors_t struct {
+	Region string            `json:"region"` // "#rrggbb" for regions that don't set a color
+	Clans  map[string]string `json:"clans"`  // "#rrggbb" for the units of allied clans, by clan id, for example "0249"
+}
+
+var rxClanId = regexp.MustCompile(`^0\d{3}$`)
+
+// ClanColors returns the colors for the units of allied clans, keyed by clan id.
+// Clans that aren't listed are given colors from the renderer's palette.
+func (c Colors_t) ClanColors() (map[string]string, error) {
+	for _, id := range slices.Sorted(maps.Keys(c.Clans)) {
+		if !rxClanId.MatchString(id) {
+			return nil, fmt.Errorf("colors: clans: invalid clan id %q", id)
+		} else if !rxColor.MatchString(c.Clans[id]) {
+			return nil, fmt.Errorf("colors: clans: %q: invalid color %q", id, c.Clans[id])
+		}
+	}
+	return c.Clans, nil
+}
+
+// RegionColor returns the color for regions that don't set one.
+func (c Colors_t) RegionColor() (string, error) {
+	if c.Region == "" {
+		return DefaultRegionColor, nil
+	} else if !rxColor.MatchString(c.Region) {
+		return "", fmt.Errorf("colors: region: invalid color %q", c.Region)
+	}
+	return c.Region, nil
+}
+
+// Symbols_t sets the Worldographer feature type for the unit markers,
+// keyed by type of unit, for example {"fleet": "Military Sailing Ship"}.
+type Symbols_t map[string]string
+
+// UnitSymbols returns the feature types by unit type. Types that aren't set keep the renderer's defaults.
+func (s Symbols_t) UnitSymbols() (map[units.Type_e]string, error) {
+	symbols := map[units.Type_e]string{}
+	for _, key := range slices.Sorted(maps.Keys(s)) {
+		kind := units.Unknown
+		for k, name := range units.EnumToString {
+			if strings.EqualFold(key, name) {
+				kind = k
+			}
+		}
+		if kind == units.Unknown {
+			return nil, fmt.Errorf("symbols: unknown unit type %q", key)
+		} else if strings.TrimSpace(s[key]) == "" {
+			return nil, fmt.Errorf("symbols: %s: missing feature type", key)
+		}
+		symbols[kind] = s[key]
+	}
+	return symbols, nil
+}
+
+// Output_t controls the names of the files that are written to the output folder.
+type Output_t struct {
+	// Map is the name of the map file. "{clan}" is replaced with the clan id
+	// and "{turn}" with the last turn. If empty, the render flags choose the name.
+	Map string `json:"map"`
+}
+
+// MapName returns the name of the map file, or an empty string if the name isn't set.
+func (o Output_t) MapName(clanId, turnId string) (string, error) {
+	if o.Map == "" {
+		return "", nil
+	}
+	name := strings.NewReplacer("{clan}", clanId, "{turn}", turnId).Replace(o.Map)
+	if name != filepath.Base(name) {
+		return "", fmt.Errorf("output: map: %q must not contain a path", o.Map)
+	} else if filepath.Ext(name) != ".wxx" {
+		name += ".wxx"
+	}
+	return name, nil
+}
+
+// Elevations_t sets the elevation of each terrain on the rendered map.
+// When disabled, the renderer uses its built-in elevations.
+type Elevations_t struct {
+	Enabled  bool           `json:"enabled"`
+	Heights  map[string]int `json:"heights"`  // by height category, for example "hills"
+	Terrains map[string]int `json:"terrains"` // by terrain code, for example "LCM"; overrides the height category
+}
+
+// Encounters_t controls which encounters get unit markers.
+// By default, only the encounters from the last turn are drawn.
+type Encounters_t struct {
+	Turns int  `json:"turns"` // draw the encounters from this many turns, ending with the last
+	All   bool `json:"all"`   // draw the encounters from every turn
+	Fade  bool `json:"fade"`  // draw older encounters more transparent
+	Stamp bool `json:"stamp"` // add the turn to the labels of older encounters
+}
+
+// Validate checks the number of turns.
+func (e Encounters_t) Validate() error {
+	if e.Turns < 0 {
+		return fmt.Errorf("encounters: turns: must not be negative")
+	}
+	return nil
+}
+
+// Notes_t controls how the lines of the notes in the map are written.
+// Duplicate lines are always dropped.
+type Notes_t struct {
+	TurnPrefix bool `json:"turnPrefix"` // start lines with their turn instead of ending with it
+	Max        int  `json:"max"`        // keep this many lines in a note and fold the rest into "and N more"; 0 keeps them all
+}
+
+// Validate checks the number of lines.
+func (n Notes_t) Validate() error {
+	if n.Max < 0 {
+		return fmt.Errorf("notes: max: must not be negative")
+	}
+	return nil
+}
+
+// Hexes_t sets the layout of the hexes in the map file, so that the map
+// matches one that the player already keeps in Worldographer.
+// Settings that are empty keep the renderer's defaults.
+type Hexes_t struct {
+	Width     float64 `json:"width"`     // hex width in Worldographer
+	Height    float64 `json:"height"`    // hex height in Worldographer
+	ViewLevel string  `json:"viewLevel"` // any of HexViewLevels
+}
+
+// HexViewLevels are the values that the viewLevel setting accepts.
+var HexViewLevels = []string{"world", "continent", "kingdom", "province"}
+
+// Validate checks the size and view level.
+func (h Hexes_t) Validate() error {
+	if h.ViewLevel != "" && !slices.Contains(HexViewLevels, h.ViewLevel) {
+		return fmt.Errorf("hexes: viewLevel: %q is not one of %s", h.ViewLevel, strings.Join(HexViewLevels, ", "))
+	} else if h.Width < 0 || h.Height < 0 {
+		return fmt.Errorf("hexes: width and height must not be negative")
+	}
+	return nil
+}
+
+// World_t is the size of the big map in grids. A world that has a size wraps
+// around at the edges, so that units moving off one side come back on the other.
+// By default the map doesn't wrap.
+type World_t struct {
+	Columns int `json:"columns"` // grids from west to east, for example 26 for AA to AZ
+	Rows    int `json:"rows"`    // grids from north to south, for example 26 for AA to ZA
+}
+
+// Wrap returns the size of the world for the coordinates package.
+func (w World_t) Wrap() (coords.World, error) {
+	if w.Columns < 0 || w.Rows < 0 {
+		return coords.World{}, fmt.Errorf("world: columns and rows must not be negative")
+	} else if w.Columns > 26 || w.Rows > 26 {
+		return coords.World{}, fmt.Errorf("world: columns and rows must not be more than 26")
+	}
+	return coords.World{Columns: w.Columns, Rows: w.Rows}, nil
+}
+
+// Legend_t adds a legend below the map.
+type Legend_t struct {
+	Enabled  bool     `json:"enabled"`
+	Title    string   `json:"title"`    // defaults to "Legend"
+	Sections []string `json:"sections"` // any of LegendSections, all of them if empty
+}
+
+// LegendSections are the sections that the legend can show, in the order they are drawn.
+var LegendSections = []string{"terrain", "edges", "units", "metadata"}
+
+// Show returns the sections to draw. It returns nil if the legend is disabled.
+func (l Legend_t) Show() (map[string]bool, error) {
+	if !l.Enabled {
+		return nil, nil
+	}
+	sections := l.Sections
+	if len(sections) == 0 {
+		sections = LegendSections
+	}
+	show := map[string]bool{}
+	for _, section := range sections {
+		found := false
+		for _, known := range LegendSections {
+			found = found || section == known
+		}
+		if !found {
+			return nil, fmt.Errorf("legend: unknown section %q", section)
+		}
+		show[section] = true
+	}
+	return show, nil
+}
+
+// Notify_t configures the message posted after a successful render.
+// Notifications are disabled when the webhook is empty.
+type Notify_t struct {
+	Webhook  string `json:"webhook"`  // Discord or Slack incoming webhook URL
+	Template string `json:"template"` // text/template for the message, optional
+	MapURL   string `json:"mapUrl"`   // link to the published map, optional
+}
+
+// Validate checks that the webhook is an http or https URL and that the template parses.
+func (n Notify_t) Validate() error {
+	if n.Webhook != "" {
+		if u, err := url.Parse(n.Webhook); err != nil {
+			return fmt.Errorf("notify: webhook: %w", err)
+		} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notify: webhook: %q is not an http or https URL", n.Webhook)
+		}
+	}
+	if n.Template != "" {
+		if _, err := notify.Parse(n.Template); err != nil {
+			return fmt.Errorf("notify: template: %w", err)
+		}
+	}
+	return nil
+}
+
+// Region_t is a named territory that is shaded on the map, for example claimed
+// lands or a trade zone. The region is the listed hexes plus the hexes inside
+// the polygon, if there is one.
+type Region_t struct {
+	Name    string   `json:"name"`
+	Color   string   `json:"color"`   // "#rrggbb", optional
+	Hexes   []string `json:"hexes"`   // grid coordinates, for example "AB 1203"
+	Polygon []string `json:"polygon"` // grid coordinates of the corners, at least three
+}
+
+// DefaultRegionColor is used for regions that don't set a color.
+const DefaultRegionColor = "#ff8000"
+
+var rxColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// Locations returns the hexes in the region, sorted by row and then column.
+func (r Region_t) Locations() ([]coords.Map, error) {
+	if r.Name == "" {
+		return nil, fmt.Errorf("regions: missing name")
+	} else if r.Color != "" && !rxColor.MatchString(r.Color) {
+		return nil, fmt.Errorf("regions: %q: invalid color %q", r.Name, r.Color)
+	} else if len(r.Polygon) != 0 && len(r.Polygon) < 3 {
+		return nil, fmt.Errorf("regions: %q: polygon needs at least three corners", r.Name)
+	}
+	in := map[coords.Map]bool{}
+	for _, hex := range r.Hexes {
+		location, err := coords.HexToMap(hex)
+		if err != nil {
+			return nil, fmt.Errorf("regions: %q: %w", r.Name, err)
+		}
+		in[location] = true
+	}
+	var corners []coords.Map
+	for _, hex := range r.Polygon {
+		location, err := coords.HexToMap(hex)
+		if err != nil {
+			return nil, fmt.Errorf("regions: %q: polygon: %w", r.Name, err)
+		}
+		corners = append(corners, location)
+	}
+	if len(corners) != 0 {
+		upperLeft, lowerRight := corners[0], corners[0]
+		for _, c := range corners {
+			upperLeft.Column, upperLeft.Row = min(upperLeft.Column, c.Column), min(upperLeft.Row, c.Row)
+			lowerRight.Column, lowerRight.Row = max(lowerRight.Column, c.Column), max(lowerRight.Row, c.Row)
+		}
+		for column := upperLeft.Column; column <= lowerRight.Column; column++ {
+			for row := upperLeft.Row; row <= lowerRight.Row; row++ {
+				if location := (coords.Map{Column: column, Row: row}); location.InPolygon(corners) {
+					in[location] = true
+				}
+			}
+		}
+	}
+	var locations []coords.Map
+	for location := range in {
+		locations = append(locations, location)
+	}
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Row != locations[j].Row {
+			return locations[i].Row < locations[j].Row
+		}
+		return locations[i].Column < locations[j].Column
+	})
+	return locations, nil
+}
+
+// River_t names a waterway. The river is given the name if it borders any of the hexes.
+type River_t struct {
+	Name  string   `json:"name"`
+	Hexes []string `json:"hexes"` // grid coordinates, for example "AB 1203"
+}
+
+// Transform_t is a rule that changes the merged map before it is drawn.
+// If is an expression that selects the hexes, for example
+// "terrain == SW and edge == River". The label, note, icon, and color are
+// added to the selected hexes like an annotation.
+type Transform_t struct {
+	If                      string `json:"if"`
+	Label                   string `json:"label"`
+	Note                    string `json:"note"`
+	Icon                    string `json:"icon"`
+	Color                   string `json:"color"`
+	DropEncountersOlderThan int    `json:"dropEncountersOlderThan"` // turns; 0 keeps them all
+}
+
+// TransformRules compiles the transforms, in order.
+func (c *Config_t) TransformRules() ([]*transform.Rule_t, error) {
+	var rules []*transform.Rule_t
+	for n, t := range c.Transforms {
+		when, err := transform.Compile(t.If)
+		if err != nil {
+			return nil, fmt.Errorf("transforms: %d: if: %w", n+1, err)
+		}
+		rule := &transform.Rule_t{Name: t.If, When: when, DropEncountersOlderThan: t.DropEncountersOlderThan}
+		if t.Label != "" || t.Note != "" || t.Icon != "" {
+			rule.Annotation = &annotations.Annotation_t{Label: t.Label, Note: t.Note, Icon: t.Icon, Color: t.Color}
+			if rule.Annotation.Icon == "" && rule.Annotation.Note != "" {
+				rule.Annotation.Icon = annotations.DefaultIcon
+			}
+		} else if t.Color != "" {
+			return nil, fmt.Errorf("transforms: %d: color needs a label, note, or icon", n+1)
+		}
+		if t.Color != "" && !rxColor.MatchString(t.Color) {
+			return nil, fmt.Errorf("transforms: %d: invalid color %q", n+1, t.Color)
+		} else if t.DropEncountersOlderThan < 0 {
+			return nil, fmt.Errorf("transforms: %d: dropEncountersOlderThan must not be negative", n+1)
+		} else if rule.Annotation == nil && rule.DropEncountersOlderThan == 0 {
+			return nil, fmt.Errorf("transforms: %d: nothing to do", n+1)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// RiverNames returns the name of the waterway for each hex in the river list.
+// A hex that is listed for two rivers is an error.
+func (c *Config_t) RiverNames() (map[coords.Map]string, error) {
+	names := map[coords.Map]string{}
+	for n, river := range c.Rivers {
+		if river.Name == "" {
+			return nil, fmt.Errorf("rivers: river %d: missing name", n+1)
+		}
+		for _, hex := range river.Hexes {
+			location, err := coords.HexToMap(hex)
+			if err != nil {
+				return nil, fmt.Errorf("rivers: %q: %w", river.Name, err)
+			} else if name, ok := names[location]; ok && name != river.Name {
+				return nil, fmt.Errorf("rivers: %q: %s is also on %q", river.Name, hex, name)
+			}
+			names[location] = river.Name
+		}
+	}
+	return names, nil
+}
+
+// Map returns the elevation for every terrain, starting from the defaults
+// for each height category. It returns nil if elevations are disabled.
+func (e Elevations_t) Map() (map[terrain.Terrain_e]int, error) {
+	if !e.Enabled {
+		return nil, nil
+	}
+	heights := map[terrain.Height_e]int{}
+	for k, v := range terrain.DefaultElevations {
+		heights[k] = v
+	}
+	for name, elevation := range e.Heights {
+		height, ok := terrain.StringToHeight[name]
+		if !ok {
+			return nil, fmt.Errorf("elevations: heights: unknown height %q", name)
+		}
+		heights[height] = elevation
+	}
+	elevations := map[terrain.Terrain_e]int{}
+	for n := 0; n < terrain.NumberOfTerrainTypes; n++ {
+		t := terrain.Terrain_e(n)
+		elevations[t] = heights[t.Height()]
+	}
+	for code, elevation := range e.Terrains {
+		t, ok := terrain.StringToTerrain(code)
+		if !ok || code == "" {
+			return nil, fmt.Errorf("elevations: terrains: unknown terrain %q", code)
+		}
+		elevations[t] = elevation
+	}
+	return elevations, nil
+}
+
+// Seasons_t marks tiles as icy in some months so that winter maps look different.
+// When enabled without rules, DefaultIcyRules are used.
+type Seasons_t struct {
+	Enabled bool        `json:"enabled"`
+	Icy     []IcyRule_t `json:"icy"`
+}
+
+// IcyRule_t marks the terrains, or the terrains in the height categories, as icy in the months.
+type IcyRule_t struct {
+	Months   []int    `json:"months"`   // 1 to 12
+	Heights  []string `json:"heights"`  // height categories, for example "high-mountains"
+	Terrains []string `json:"terrains"` // terrain codes, for example "TU"
+}
+
+// DefaultIcyRules ice over the cold terrains from November through February.
+var DefaultIcyRules = []IcyRule_t{
+	{Months: []int{11, 12, 1, 2}, Heights: []string{"high-mountains", "ice"}, Terrains: []string{"AR", "LSM", "SH", "TU"}},
+}
+
+// IcyTerrains returns the terrains that are icy in the month.
+// It returns nil if seasons are disabled.
+func (s Seasons_t) IcyTerrains(month int) (map[terrain.Terrain_e]bool, error) {
+	if !s.Enabled {
+		return nil, nil
+	}
+	rules := s.Icy
+	if len(rules) == 0 {
+		rules = DefaultIcyRules
+	}
+	icy := map[terrain.Terrain_e]bool{}
+	for n, rule := range rules {
+		inMonth := false
+		for _, m := range rule.Months {
+			if m < 1 || m > 12 {
+				return nil, fmt.Errorf("seasons: icy: rule %d: invalid month %d", n+1, m)
+			}
+			inMonth = inMonth || m == month
+		}
+		heights := map[terrain.Height_e]bool{}
+		for _, name := range rule.Heights {
+			height, ok := terrain.StringToHeight[name]
+			if !ok {
+				return nil, fmt.Errorf("seasons: icy: rule %d: unknown height %q", n+1, name)
+			}
+			heights[height] = true
+		}
+		for _, code := range rule.Terrains {
+			t, ok := terrain.StringToTerrain(code)
+			if !ok || code == "" {
+				return nil, fmt.Errorf("seasons: icy: rule %d: unknown terrain %q", n+1, code)
+			}
+			icy[t] = icy[t] || inMonth
+		}
+		for k := 0; k < terrain.NumberOfTerrainTypes; k++ {
+			if t := terrain.Terrain_e(k); heights[t.Height()] {
+				icy[t] = icy[t] || inMonth
+			}
+		}
+	}
+	for t, ok := range icy {
+		if !ok {
+			delete(icy, t)
+		}
+	}
+	return icy, nil
+}
+
+// Load reads the configuration from a file and applies the profile and the
+// section for the clan. If profile is empty, the "default" profile is used
+// when the file has one. It is an error to ask for a profile that isn't in
+// the file, but a clan without a section just gets the shared settings.
+// Keys that aren't settings are errors, and so are invalid values.
+// If the file doesn't exist, an empty configuration is returned.
+func Load(path, profile, clanId string) (*Config_t, error) {
+	cfg := &Config_t{Sources: map[string]string{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if profile != "" {
+			return nil, fmt.Errorf("profiles: %q: no configuration file", profile)
+		}
+		return cfg, nil
+	} else if err != nil {
+		return nil, err
+	} else if err = decodeStrict(data, cfg); err != nil {
+		return nil, err
+	}
+	profiles, clans := cfg.Profiles, cfg.Clans
+	if err = recordSources(cfg.Sources, "", data, SourceFile); err != nil {
+		return nil, err
+	}
+
+	if profile == "" {
+		if _, ok := profiles[DefaultProfile]; ok {
+			profile = DefaultProfile
+		}
+	}
+	if profile != "" {
+		section, ok := profiles[profile]
+		if !ok {
+			var names []string
+			for name := range profiles {
+				names = append(names, fmt.Sprintf("%q", name))
+			}
+			sort.Strings(names)
+			if len(names) == 0 {
+				return nil, fmt.Errorf("profiles: %q: not found: the file has no profiles", profile)
+			}
+			return nil, fmt.Errorf("profiles: %q: not found: want one of %s", profile, strings.Join(names, ", "))
+		} else if err = applySection(cfg, section, fmt.Sprintf("profile %q", profile)); err != nil {
+			return nil, fmt.Errorf("profiles: %q: %w", profile, err)
+		}
+	}
+	if section, ok := clans[clanId]; ok && clanId != "" {
+		if err = applySection(cfg, section, fmt.Sprintf("clan %q", clanId)); err != nil {
+			return nil, fmt.Errorf("clans: %q: %w", clanId, err)
+		}
+	}
+
+	cfg.Profiles, cfg.Clans = profiles, clans
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// SourceFile is the source of settings from the shared part of the file.
+const SourceFile = "file"
+
+// decodeStrict unmarshals the data, rejecting keys that aren't settings.
+func decodeStrict(data []byte, cfg *Config_t) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			line := 1 + bytes.Count(data[:syntaxErr.Offset], []byte{'\n'})
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	} else if dec.More() {
+		return fmt.Errorf("unexpected data after the configuration")
+	}
+	return nil
+}
+
+// recordSources sets the source of every setting in the data.
+// Objects are walked into; any other value is a single setting.
+func recordSources(sources map[string]string, prefix string, data json.RawMessage, source string) error {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		// not an object, so it is a value
+		sources[prefix] = source
+		return nil
+	}
+	for key, value := range object {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		} else if key == "profiles" || key == "clans" {
+			continue
+		}
+		if err := recordSources(sources, path, value, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applySection unmarshals a partial configuration on top of the current one.
+// Sections can't nest, so profiles and clans are rejected.
+func applySection(cfg *Config_t, section json.RawMessage, source string) error {
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(section, &keys); err != nil {
+		return err
+	}
+	for _, key := range []string{"profiles", "clans"} {
+		if _, ok := keys[key]; ok {
+			return fmt.Errorf("%s are not allowed in a section", key)
+		}
+	}
+	if err := decodeStrict(section, cfg); err != nil {
+		return err
+	}
+	return recordSources(cfg.Sources, "", section, source)
+}
+
+// Validate checks every setting, not just the ones that the current command uses.
+// All the problems are returned, not just the first.
+func (c *Config_t) Validate() error {
+	var errs []error
+	if _, err := c.Colors.RegionColor(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Colors.ClanColors(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Elevations.Map(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Encounters.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Hexes.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Legend.Show(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Notes.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := c.Notify.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Output.MapName("0138", "0901-01"); err != nil {
+		errs = append(errs, err)
+	}
+	for _, region := range c.Regions {
+		if _, err := region.Locations(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if _, err := c.RiverNames(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Seasons.IcyTerrains(1); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.Symbols.UnitSymbols(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.TransformRules(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := c.World.Wrap(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Setting_t is a single value in the effective configuration.
+type Setting_t struct {
+	Path   string // for example "legend.title"
+	Value  string // as JSON
+	Source string // SourceFile, the profile, or the clan; empty if it is the default value
+}
+
+// Settings returns every value in the effective configuration, sorted by path.
+// Lists are a single value.
+func (c *Config_t) Settings() ([]Setting_t, error) {
+	effective := *c
+	effective.Profiles, effective.Clans = nil, nil
+	data, err := json.Marshal(effective)
+	if err != nil {
+		return nil, err
+	}
+	var settings []Setting_t
+	var walk func(prefix string, value any)
+	walk = func(prefix string, value any) {
+		if object, ok := value.(map[string]any); ok && len(object) != 0 {
+			for key, v := range object {
+				if prefix == "" && (key == "profiles" || key == "clans") {
+					continue
+				} else if prefix == "" {
+					walk(key, v)
+				} else {
+					walk(prefix+"."+key, v)
+				}
+			}
+			return
+		}
+		text, _ := json.Marshal(value)
+		settings = append(settings, Setting_t{Path: prefix, Value: string(text), Source: c.Sources[prefix]})
+	}
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	walk("", root)
+	sort.Slice(settings, func(i, j int) bool {
+		return settings[i].Path < settings[j].Path
+	})
+	return settings, nil
+}
@@ -0,0 +1,385 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package config_test
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/config"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/spf13/pflag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestElevations(t *testing.T) {
+	// disabled elevations use the renderer's built-in values
+	if got, err := (config.Elevations_t{}).Map(); err != nil || got != nil {
+		t.Errorf("disabled: want nil, nil, got %v, %v", got, err)
+	}
+
+	e := config.Elevations_t{
+		Enabled:  true,
+		Heights:  map[string]int{"hills": 600},
+		Terrains: map[string]int{"RH": 750},
+	}
+	got, err := e.Map()
+	if err != nil {
+		t.Fatalf("enabled: want nil, got %v", err)
+	}
+	for _, tc := range []struct {
+		terrain terrain.Terrain_e
+		want    int
+	}{
+		{terrain.Ocean, terrain.DefaultElevations[terrain.DeepWater]},
+		{terrain.Prairie, terrain.DefaultElevations[terrain.Flat]},
+		{terrain.GrassyHills, 600},
+		{terrain.RockyHills, 750},
+		{terrain.HighSnowyMountains, terrain.DefaultElevations[terrain.HighMountains]},
+	} {
+		if got[tc.terrain] != tc.want {
+			t.Errorf("%s: want %d, got %d", tc.terrain, tc.want, got[tc.terrain])
+		}
+	}
+
+	for _, bad := range []config.Elevations_t{
+		{Enabled: true, Heights: map[string]int{"valleys": 1}},
+		{Enabled: true, Terrains: map[string]int{"XX": 1}},
+	} {
+		if _, err := bad.Map(); err == nil {
+			t.Errorf("%v: want error, got nil", bad)
+		}
+	}
+}
+
+func TestIcyTerrains(t *testing.T) {
+	if got, err := (config.Seasons_t{}).IcyTerrains(1); err != nil || got != nil {
+		t.Errorf("disabled: want nil, nil, got %v, %v", got, err)
+	}
+
+	defaults := config.Seasons_t{Enabled: true}
+	for _, tc := range []struct {
+		id      int
+		month   int
+		terrain terrain.Terrain_e
+		want    bool
+	}{
+		{id: 1, month: 1, terrain: terrain.Tundra, want: true},
+		{id: 2, month: 1, terrain: terrain.HighSnowyMountains, want: true},
+		{id: 3, month: 1, terrain: terrain.Prairie, want: false},
+		{id: 4, month: 7, terrain: terrain.Tundra, want: false},
+		{id: 5, month: 11, terrain: terrain.PolarIce, want: true},
+	} {
+		got, err := defaults.IcyTerrains(tc.month)
+		if err != nil {
+			t.Fatalf("%d: want nil, got %v", tc.id, err)
+		}
+		if got[tc.terrain] != tc.want {
+			t.Errorf("%d: %s in month %d: want %v, got %v", tc.id, tc.terrain, tc.month, tc.want, got[tc.terrain])
+		}
+	}
+
+	custom := config.Seasons_t{Enabled: true, Icy: []config.IcyRule_t{{Months: []int{3}, Terrains: []string{"PR"}}}}
+	if got, err := custom.IcyTerrains(3); err != nil || len(got) != 1 || !got[terrain.Prairie] {
+		t.Errorf("custom: want only prairie, got %v, %v", got, err)
+	}
+
+	for _, bad := range []config.Seasons_t{
+		{Enabled: true, Icy: []config.IcyRule_t{{Months: []int{13}}}},
+		{Enabled: true, Icy: []config.IcyRule_t{{Months: []int{1}, Heights: []string{"valleys"}}}},
+		{Enabled: true, Icy: []config.IcyRule_t{{Months: []int{1}, Terrains: []string{"XX"}}}},
+	} {
+		if _, err := bad.IcyTerrains(1); err == nil {
+			t.Errorf("%v: want error, got nil", bad)
+		}
+	}
+}
+
+func TestRiverNames(t *testing.T) {
+	cfg := &config.Config_t{Rivers: []config.River_t{
+		{Name: "Silver Run", Hexes: []string{"QQ 1008", "QQ 1009"}},
+		{Name: "Black Water", Hexes: []string{"AB 0101"}},
+	}}
+	names, err := cfg.RiverNames()
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	for hex, want := range map[string]string{"QQ 1008": "Silver Run", "QQ 1009": "Silver Run", "AB 0101": "Black Water", "AB 0102": ""} {
+		location, err := coords.HexToMap(hex)
+		if err != nil {
+			t.Fatalf("%s: %v", hex, err)
+		}
+		if got := names[location]; got != want {
+			t.Errorf("%s: want %q, got %q", hex, want, got)
+		}
+	}
+
+	for _, bad := range [][]config.River_t{
+		{{Name: "", Hexes: []string{"QQ 1008"}}},
+		{{Name: "Silver Run", Hexes: []string{"QQ10"}}},
+		{{Name: "Silver Run", Hexes: []string{"QQ 1008"}}, {Name: "Black Water", Hexes: []string{"QQ 1008"}}},
+	} {
+		if _, err := (&config.Config_t{Rivers: bad}).RiverNames(); err == nil {
+			t.Errorf("%v: want error, got nil", bad)
+		}
+	}
+}
+
+func TestRegionLocations(t *testing.T) {
+	hexes := func(locations []coords.Map) (list []string) {
+		for _, location := range locations {
+			list = append(list, location.ToHex())
+		}
+		return list
+	}
+
+	got, err := config.Region_t{Name: "Home", Hexes: []string{"AB 0102", "AB 0101", "AB 0101"}}.Locations()
+	if err != nil {
+		t.Fatalf("hexes: want nil, got %v", err)
+	} else if want := []string{"AB 0101", "AB 0102"}; fmt.Sprint(hexes(got)) != fmt.Sprint(want) {
+		t.Errorf("hexes: want %v, got %v", want, hexes(got))
+	}
+
+	// a triangle with corners on three hexes includes the corners and the hexes between them
+	got, err = config.Region_t{Name: "Zone", Polygon: []string{"AB 0505", "AB 0905", "AB 0709"}}.Locations()
+	if err != nil {
+		t.Fatalf("polygon: want nil, got %v", err)
+	}
+	in := map[string]bool{}
+	for _, hex := range hexes(got) {
+		in[hex] = true
+	}
+	for hex, want := range map[string]bool{"AB 0505": true, "AB 0905": true, "AB 0709": true, "AB 0707": true, "AB 0405": false, "AB 0710": false} {
+		if in[hex] != want {
+			t.Errorf("polygon: %s: want %v, got %v", hex, want, in[hex])
+		}
+	}
+
+	for _, bad := range []config.Region_t{
+		{Hexes: []string{"AB 0101"}},
+		{Name: "Home", Color: "green", Hexes: []string{"AB 0101"}},
+		{Name: "Home", Polygon: []string{"AB 0101", "AB 0202"}},
+		{Name: "Home", Hexes: []string{"AB01"}},
+	} {
+		if _, err := bad.Locations(); err == nil {
+			t.Errorf("%v: want error, got nil", bad)
+		}
+	}
+}
+
+func TestLegendShow(t *testing.T) {
+	if got, err := (config.Legend_t{Sections: []string{"terrain"}}).Show(); got != nil || err != nil {
+		t.Errorf("disabled: want nil, nil, got %v, %v", got, err)
+	}
+	got, err := config.Legend_t{Enabled: true}.Show()
+	if err != nil {
+		t.Fatalf("defaults: want nil, got %v", err)
+	}
+	for _, section := range config.LegendSections {
+		if !got[section] {
+			t.Errorf("defaults: %s: want true, got false", section)
+		}
+	}
+	got, err = config.Legend_t{Enabled: true, Sections: []string{"edges"}}.Show()
+	if err != nil || len(got) != 1 || !got["edges"] {
+		t.Errorf("edges: want only edges, got %v, %v", got, err)
+	}
+	if _, err := (config.Legend_t{Enabled: true, Sections: []string{"weather"}}).Show(); err == nil {
+		t.Errorf("unknown section: want error, got nil")
+	}
+}
+
+func TestHexesValidate(t *testing.T) {
+	for _, tc := range []struct {
+		id    int
+		hexes config.Hexes_t
+		ok    bool
+	}{
+		{id: 1, hexes: config.Hexes_t{}, ok: true},
+		{id: 2, hexes: config.Hexes_t{Width: 40, Height: 46.18, ViewLevel: "continent"}, ok: true},
+		{id: 3, hexes: config.Hexes_t{Height: -1}},
+		{id: 4, hexes: config.Hexes_t{ViewLevel: "WORLD"}},
+		{id: 5, hexes: config.Hexes_t{Width: -1}},
+	} {
+		if err := tc.hexes.Validate(); (err == nil) != tc.ok {
+			t.Errorf("%d: want ok %v, got %v", tc.id, tc.ok, err)
+		}
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ottomap.json")
+	data := `{
+  "layers": {"heatmap": true},
+  "legend": {"enabled": true, "title": "Shared"},
+  "regions": [{"name": "Home", "hexes": ["QQ 1008"]}],
+  "profiles": {
+    "default": {"legend": {"title": "Default"}},
+    "alliance": {"layers": {"contacts": true}, "regions": [{"name": "Alliance", "hexes": ["QQ 1208"]}]},
+    "печать": {"layers": {"heatmap": false}, "legend": {"enabled": false}}
+  },
+  "clans": {
+    "0138": {"colors": {"region": "#00ff00"}, "output": {"map": "{clan}-{turn}"}}
+  }
+}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		id       int
+		profile  string
+		clanId   string
+		title    string
+		enabled  bool
+		layers   string
+		region   string
+		color    string
+		fileName string
+	}{
+		{id: 1, profile: "", clanId: "0249", title: "Default", enabled: true, layers: "map[heatmap:true]", region: "Home", color: config.DefaultRegionColor},
+		{id: 2, profile: "alliance", clanId: "0249", title: "Shared", enabled: true, layers: "map[contacts:true heatmap:true]", region: "Alliance", color: config.DefaultRegionColor},
+		{id: 3, profile: "печать", clanId: "0138", title: "Shared", enabled: false, layers: "map[heatmap:false]", region: "Home", color: "#00ff00", fileName: "0138-0902-03.wxx"},
+	} {
+		cfg, err := config.Load(path, tc.profile, tc.clanId)
+		if err != nil {
+			t.Fatalf("%d: want nil, got %v", tc.id, err)
+		}
+		if cfg.Legend.Title != tc.title || cfg.Legend.Enabled != tc.enabled {
+			t.Errorf("%d: legend: want %q %v, got %q %v", tc.id, tc.title, tc.enabled, cfg.Legend.Title, cfg.Legend.Enabled)
+		}
+		if got := fmt.Sprint(cfg.Layers); got != tc.layers {
+			t.Errorf("%d: layers: want %s, got %s", tc.id, tc.layers, got)
+		}
+		if len(cfg.Regions) != 1 || cfg.Regions[0].Name != tc.region {
+			t.Errorf("%d: regions: want %q, got %v", tc.id, tc.region, cfg.Regions)
+		}
+		if got, err := cfg.Colors.RegionColor(); err != nil || got != tc.color {
+			t.Errorf("%d: color: want %q, got %q, %v", tc.id, tc.color, got, err)
+		}
+		if got, err := cfg.Output.MapName("0138", "0902-03"); err != nil || got != tc.fileName {
+			t.Errorf("%d: map name: want %q, got %q, %v", tc.id, tc.fileName, got, err)
+		}
+	}
+
+	if _, err := config.Load(path, "solo", "0138"); err == nil {
+		t.Errorf("unknown profile: want error, got nil")
+	}
+	if _, err := config.Load(filepath.Join(t.TempDir(), "missing.json"), "alliance", "0138"); err == nil {
+		t.Errorf("missing file with profile: want error, got nil")
+	}
+	if _, err := (config.Output_t{Map: "../{clan}"}).MapName("0138", "0902-03"); err == nil {
+		t.Errorf("map name with path: want error, got nil")
+	}
+}
+
+func TestLoadStrict(t *testing.T) {
+	dir := t.TempDir()
+	load := func(data string) (*config.Config_t, error) {
+		path := filepath.Join(dir, "ottomap.json")
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return config.Load(path, "", "0138")
+	}
+
+	for _, tc := range []struct {
+		id   int
+		data string
+		want string
+	}{
+		{id: 1, data: `{"legnd": {"enabled": true}}`, want: `unknown field "legnd"`},
+		{id: 2, data: `{"clans": {"0138": {"legend": {"titel": "x"}}}}`, want: `clans: "0138": unknown field "titel"`},
+		{id: 3, data: "{\n\"legend\": {,}}", want: "line 2: invalid character ',' looking for beginning of object key string"},
+		{id: 4, data: `{"colors": {"region": "green"}, "notify": {"webhook": "discord.com/api"}}`, want: "colors: region: invalid color \"green\"\nnotify: webhook: \"discord.com/api\" is not an http or https URL"},
+		{id: 5, data: `{"seasons": {"enabled": true, "icy": [{"months": [13]}]}}`, want: "seasons: icy: rule 1: invalid month 13"},
+		{id: 6, data: `{"notify": {"template": "{{join .Units"}}`, want: "notify: template: template: notify:1: unclosed action"},
+		{id: 7, data: `{"colors": {"clans": {"0249": "#00ff00", "249": "#0000ff"}}}`, want: `colors: clans: invalid clan id "249"`},
+		{id: 8, data: `{"colors": {"clans": {"0249": "lime"}}}`, want: `colors: clans: "0249": invalid color "lime"`},
+		{id: 9, data: `{"symbols": {"fleet": "Military Sailing Ship", "ship": "Military Sailing Ship"}}`, want: `symbols: unknown unit type "ship"`},
+		{id: 10, data: `{"transforms": [{"if": "terrain == SW and", "note": "good fishing"}]}`, want: `transforms: 1: if: unexpected end of expression`},
+		{id: 11, data: `{"transforms": [{"if": "terrain == SW"}]}`, want: `transforms: 1: nothing to do`},
+		{id: 12, data: `{"encounters": {"turns": -1}}`, want: `encounters: turns: must not be negative`},
+		{id: 13, data: `{"notes": {"max": -1}}`, want: `notes: max: must not be negative`},
+	} {
+		_, err := load(tc.data)
+		if err == nil {
+			t.Errorf("%d: want error, got nil", tc.id)
+		} else if err.Error() != tc.want {
+			t.Errorf("%d: want %q, got %q", tc.id, tc.want, err.Error())
+		}
+	}
+
+	cfg, err := load(`{"legend": {"enabled": true}, "clans": {"0138": {"legend": {"title": "Mine"}, "regions": []}}}`)
+	if err != nil {
+		t.Fatalf("sources: want nil, got %v", err)
+	}
+	settings, err := cfg.Settings()
+	if err != nil {
+		t.Fatalf("settings: want nil, got %v", err)
+	}
+	got := map[string]config.Setting_t{}
+	for _, setting := range settings {
+		got[setting.Path] = setting
+	}
+	for _, want := range []config.Setting_t{
+		{Path: "legend.enabled", Value: "true", Source: config.SourceFile},
+		{Path: "legend.title", Value: `"Mine"`, Source: `clan "0138"`},
+		{Path: "regions", Value: "[]", Source: `clan "0138"`},
+		{Path: "seasons.enabled", Value: "false", Source: ""},
+	} {
+		if got[want.Path] != want {
+			t.Errorf("settings: %s: want %+v, got %+v", want.Path, want, got[want.Path])
+		}
+	}
+	if _, ok := got["clans"]; ok {
+		t.Errorf("settings: clans: want omitted, got %+v", got["clans"])
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	var data, clanId, secret string
+	var heatmap bool
+	flags := pflag.NewFlagSet("render", pflag.ContinueOnError)
+	flags.StringVar(&data, "data", "data", "")
+	flags.StringVar(&clanId, "clan-id", "", "")
+	flags.StringVar(&secret, "secret", "", "")
+	flags.BoolVar(&heatmap, "show-heatmap", false, "")
+	if err := flags.SetAnnotation("secret", config.EnvAnnotation, []string{"OTTOMAP_USER_SECRET"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := flags.Parse([]string{"--data", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"OTTOMAP_DATA":         "env",
+		"OTTOMAP_CLAN_ID":      "0138",
+		"OTTOMAP_SECRET":       "server",
+		"OTTOMAP_USER_SECRET":  "user",
+		"OTTOMAP_SHOW_HEATMAP": "true",
+	}
+	used, err := config.ApplyEnv(flags, func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	})
+	if err != nil {
+		t.Fatalf("want nil, got %v", err)
+	}
+	if want := "[OTTOMAP_CLAN_ID OTTOMAP_SHOW_HEATMAP OTTOMAP_USER_SECRET]"; fmt.Sprint(used) != want {
+		t.Errorf("used: want %s, got %v", want, used)
+	}
+	if data != "cli" || clanId != "0138" || secret != "user" || !heatmap {
+		t.Errorf("flags: got data %q, clan %q, secret %q, heatmap %v", data, clanId, secret, heatmap)
+	}
+	// the environment counts as the command line for the configuration file's layers
+	if !flags.Lookup("show-heatmap").Changed {
+		t.Errorf("show-heatmap: want changed, got unchanged")
+	}
+
+	flags = pflag.NewFlagSet("render", pflag.ContinueOnError)
+	flags.BoolVar(&heatmap, "show-heatmap", false, "")
+	if _, err := config.ApplyEnv(flags, func(name string) (string, bool) { return "maybe", true }); err == nil {
+		t.Errorf("invalid bool: want error, got nil")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"sort"
+	"strings"
+)
+
+// Settings are layered from lowest to highest: the flag defaults, this file,
+// the OTTOMAP_* environment variables, and the command line.
+
+// EnvPrefix starts the name of every environment variable that sets a flag.
+// The rest of the name is the flag in upper case with dashes replaced by
+// underscores, so --clan-id is OTTOMAP_CLAN_ID.
+const EnvPrefix = "OTTOMAP_"
+
+// EnvAnnotation is the flag annotation that names the environment variable,
+// for flags where the default name would be shared with an unrelated flag.
+const EnvAnnotation = "ottomap-env"
+
+// EnvName returns the environment variable for the flag.
+func EnvName(flag *pflag.Flag) string {
+	if names := flag.Annotations[EnvAnnotation]; len(names) != 0 {
+		return names[0]
+	}
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+}
+
+// ApplyEnv sets each flag that wasn't given on the command line from its
+// environment variable, if the variable is set. The flag is then treated as
+// changed, so the configuration file doesn't override it. It returns the
+// variables that were used, sorted by name.
+func ApplyEnv(flags *pflag.FlagSet, lookup func(string) (string, bool)) ([]string, error) {
+	var used []string
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" {
+			return
+		}
+		name := EnvName(flag)
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if err = flags.Set(flag.Name, value); err != nil {
+			err = fmt.Errorf("env: %s: %w", name, err)
+			return
+		}
+		used = append(used, name)
+	})
+	sort.Strings(used)
+	return used, err
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package consistency flags merged map data that can't be right, like a hex
+// that is ocean on one turn and mountains on another. These are usually
+// typos in a report or a unit that was placed in the wrong hex.
+package consistency
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"sort"
+)
+
+// Rule_e is a consistency rule.
+type Rule_e int
+
+const (
+	UnknownRule      Rule_e = iota
+	OceanMountain           // a hex reported as water on one turn and mountains on another
+	RiverOnWater            // a river between two water hexes
+	FordWithoutRiver        // a ford on a side of the hex without a river
+)
+
+var (
+	// RuleToString is a helper map for marshalling the enum
+	RuleToString = map[Rule_e]string{
+		UnknownRule:      "?",
+		OceanMountain:    "ocean-mountain",
+		RiverOnWater:     "river-on-water",
+		FordWithoutRiver: "ford-without-river",
+	}
+	// StringToRule is a helper map for unmarshalling the enum
+	StringToRule = map[string]Rule_e{
+		"ocean-mountain":     OceanMountain,
+		"river-on-water":     RiverOnWater,
+		"ford-without-river": FordWithoutRiver,
+	}
+)
+
+func (r Rule_e) String() string {
+	if str, ok := RuleToString[r]; ok {
+		return str
+	}
+	return fmt.Sprintf("Rule_e(%d)", int(r))
+}
+
+// DefaultRules are the rules that Check runs unless they are disabled.
+var DefaultRules = []Rule_e{OceanMountain, RiverOnWater, FordWithoutRiver}
+
+// Problem_t is a rule that the merged data breaks.
+// TurnId, UnitId, and Line are set when the problem comes from a single report line.
+type Problem_t struct {
+	Rule     Rule_e
+	Location coords.Map
+	TurnId   string
+	UnitId   parser.UnitId_t
+	Line     int
+	Problem  string
+}
+
+// Check runs the default rules, except the disabled ones, against the turns and the map.
+// The turns must be sorted and walked so that the locations of the moves are set.
+// Problems are returned sorted by hex and then rule.
+func Check(input []*parser.Turn_t, worldMap *tiles.Map_t, disabled map[Rule_e]bool) []Problem_t {
+	var list []Problem_t
+	for _, rule := range DefaultRules {
+		if disabled[rule] {
+			continue
+		}
+		switch rule {
+		case OceanMountain:
+			list = append(list, oceanMountain(input, worldMap)...)
+		case RiverOnWater:
+			list = append(list, riverOnWater(worldMap)...)
+		case FordWithoutRiver:
+			list = append(list, fordWithoutRiver(worldMap)...)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		if a, b := list[i].Location.GridString(), list[j].Location.GridString(); a != b {
+			return a < b
+		}
+		return list[i].Rule < list[j].Rule
+	})
+	return list
+}
+
+// observation_t is the terrain a report line gave for a hex.
+type observation_t struct {
+	turnId  string
+	unitId  parser.UnitId_t
+	line    int
+	terrain terrain.Terrain_e
+}
+
+// oceanMountain compares the terrain that every report line gave for each hex,
+// including the terrain of the neighbors seen from the hex.
+// The first water and first mountain observations of a hex are reported.
+func oceanMountain(input []*parser.Turn_t, worldMap *tiles.Map_t) (list []Problem_t) {
+	water, mountain := map[coords.Map]observation_t{}, map[coords.Map]observation_t{}
+	var order []coords.Map
+	observe := func(location coords.Map, o observation_t) {
+		var seen map[coords.Map]observation_t
+		if o.terrain.IsWater() {
+			seen = water
+		} else if o.terrain.IsAnyMountain() || o.terrain == terrain.UnknownMountain {
+			seen = mountain
+		} else {
+			return
+		}
+		if _, ok := seen[location]; ok {
+			return
+		}
+		seen[location] = o
+		_, inWater := water[location]
+		_, inMountain := mountain[location]
+		if inWater && inMountain {
+			order = append(order, location)
+		}
+	}
+	observeMoves := func(turnId string, unitId parser.UnitId_t, moves []*parser.Move_t) {
+		for _, move := range moves {
+			if move.Report == nil || move.Location.IsZero() {
+				continue
+			}
+			observe(move.Location, observation_t{turnId: turnId, unitId: unitId, line: move.LineNo, terrain: move.Report.Terrain})
+			for _, border := range move.Report.Borders {
+				if border.Terrain != terrain.Blank {
+					neighbor := worldMap.World.Add(move.Location, border.Direction)
+					observe(neighbor, observation_t{turnId: turnId, unitId: unitId, line: move.LineNo, terrain: border.Terrain})
+				}
+			}
+		}
+	}
+	for _, turn := range input {
+		for _, moves := range turn.SortedMoves {
+			observeMoves(turn.Id, moves.UnitId, moves.Moves)
+			for _, scout := range moves.Scouts {
+				observeMoves(turn.Id, moves.UnitId, scout.Moves)
+			}
+		}
+	}
+
+	for _, location := range order {
+		// the problem is charged to the later of the two lines
+		first, second := water[location], mountain[location]
+		if second.turnId < first.turnId {
+			first, second = second, first
+		}
+		list = append(list, Problem_t{
+			Rule:     OceanMountain,
+			Location: location,
+			TurnId:   second.turnId,
+			UnitId:   second.unitId,
+			Line:     second.line,
+			Problem:  fmt.Sprintf("reported as %s by %s in %s, then as %s", first.terrain, first.unitId, first.turnId, second.terrain),
+		})
+	}
+	return list
+}
+
+// riverOnWater reports rivers between two water hexes.
+// Each pair of hexes is reported once, from the hex that sorts first.
+func riverOnWater(worldMap *tiles.Map_t) (list []Problem_t) {
+	seen := map[[2]coords.Map]bool{}
+	for _, tile := range sortedTiles(worldMap) {
+		if !tile.Terrain.IsWater() {
+			continue
+		}
+		for _, d := range direction.Directions {
+			if !tile.HasEdge(d, edges.River) {
+				continue
+			}
+			location := worldMap.World.Add(tile.Location, d)
+			neighbor, ok := worldMap.Tiles[location]
+			if !ok || !neighbor.Terrain.IsWater() || seen[[2]coords.Map{location, tile.Location}] {
+				continue
+			}
+			seen[[2]coords.Map{tile.Location, location}] = true
+			list = append(list, Problem_t{
+				Rule:     RiverOnWater,
+				Location: tile.Location,
+				Problem:  fmt.Sprintf("river to the %s between %s and %s %s", d, tile.Terrain, neighbor.Terrain, location.GridString()),
+			})
+		}
+	}
+	return list
+}
+
+// fordWithoutRiver reports fords on a side that has no river on either hex.
+func fordWithoutRiver(worldMap *tiles.Map_t) (list []Problem_t) {
+	for _, tile := range sortedTiles(worldMap) {
+		for _, d := range direction.Directions {
+			if !tile.HasEdge(d, edges.Ford) || tile.HasEdge(d, edges.River) {
+				continue
+			}
+			location := worldMap.World.Add(tile.Location, d)
+			if neighbor, ok := worldMap.Tiles[location]; ok && neighbor.HasEdge(d.Opposite(), edges.River) {
+				continue
+			}
+			list = append(list, Problem_t{
+				Rule:     FordWithoutRiver,
+				Location: tile.Location,
+				Problem:  fmt.Sprintf("ford to the %s without a river", d),
+			})
+		}
+	}
+	return list
+}
+
+// sortedTiles returns the tiles sorted by grid coordinates, so that problems are found in the same order on every run.
+func sortedTiles(worldMap *tiles.Map_t) []*tiles.Tile_t {
+	list := make([]*tiles.Tile_t, 0, len(worldMap.Tiles))
+	for _, tile := range worldMap.Tiles {
+		list = append(list, tile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Location.GridString() < list[j].Location.GridString()
+	})
+	return list
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package consistency_test
+
+import (
+	"github.com/playbymail/ottomap/internal/consistency"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	at := func(grid string) coords.Map {
+		location, err := coords.HexToMap(grid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location
+	}
+
+	// QQ 1010 is ocean on the first turn and mountains on the second, as seen from QQ 1011.
+	// QQ 1210 and QQ 1211 are lakes with a river between them.
+	// QQ 1410 has a ford to the north without a river, QQ 1412 has a ford with one.
+	var input []*parser.Turn_t
+	for _, tc := range []struct {
+		turnId  string
+		terrain terrain.Terrain_e
+	}{
+		{"0901-01", terrain.Ocean},
+		{"0901-02", terrain.LowConiferMountains},
+	} {
+		moves := &parser.Moves_t{TurnId: tc.turnId, UnitId: "0138", Moves: []*parser.Move_t{{
+			LineNo:   5,
+			Still:    true,
+			Location: at("QQ 1011"),
+			Report:   &parser.Report_t{Terrain: terrain.Prairie, Borders: []*parser.Border_t{{Direction: direction.North, Terrain: tc.terrain}}},
+		}}}
+		input = append(input, &parser.Turn_t{Id: tc.turnId, SortedMoves: []*parser.Moves_t{moves}})
+	}
+	worldMap := tiles.NewMap()
+	for _, tc := range []struct {
+		grid    string
+		terrain terrain.Terrain_e
+		edges   map[direction.Direction_e][]edges.Edge_e
+	}{
+		{"QQ 1010", terrain.LowConiferMountains, nil},
+		{"QQ 1011", terrain.Prairie, nil},
+		{"QQ 1210", terrain.Lake, map[direction.Direction_e][]edges.Edge_e{direction.South: {edges.River}}},
+		{"QQ 1211", terrain.Lake, map[direction.Direction_e][]edges.Edge_e{direction.North: {edges.River}}},
+		{"QQ 1410", terrain.Prairie, map[direction.Direction_e][]edges.Edge_e{direction.North: {edges.Ford}}},
+		{"QQ 1412", terrain.Prairie, map[direction.Direction_e][]edges.Edge_e{direction.North: {edges.Ford, edges.River}}},
+	} {
+		tile := &tiles.Tile_t{Location: at(tc.grid), Terrain: tc.terrain}
+		for d, list := range tc.edges {
+			tile.Edges[d] = list
+		}
+		worldMap.Tiles[tile.Location] = tile
+	}
+
+	for _, tc := range []struct {
+		id       int
+		disabled map[consistency.Rule_e]bool
+		want     []consistency.Problem_t
+	}{
+		{1, nil, []consistency.Problem_t{
+			{Rule: consistency.OceanMountain, Location: at("QQ 1010"), TurnId: "0901-02", UnitId: "0138", Line: 5, Problem: "reported as O by 0138 in 0901-01, then as LCM"},
+			{Rule: consistency.RiverOnWater, Location: at("QQ 1210")},
+			{Rule: consistency.FordWithoutRiver, Location: at("QQ 1410")},
+		}},
+		{2, map[consistency.Rule_e]bool{consistency.OceanMountain: true, consistency.FordWithoutRiver: true}, []consistency.Problem_t{
+			{Rule: consistency.RiverOnWater, Location: at("QQ 1210")},
+		}},
+	} {
+		got := consistency.Check(input, worldMap, tc.disabled)
+		if len(got) != len(tc.want) {
+			t.Errorf("%d: want %d problems, got %+v", tc.id, len(tc.want), got)
+			continue
+		}
+		for n, want := range tc.want {
+			if got[n].Rule != want.Rule || got[n].Location != want.Location {
+				t.Errorf("%d: %d: want %s in %s, got %s in %s", tc.id, n, want.Rule, want.Location.GridString(), got[n].Rule, got[n].Location.GridString())
+			} else if want.Problem != "" && got[n] != want {
+				t.Errorf("%d: %d: want %+v, got %+v", tc.id, n, want, got[n])
+			}
+		}
+	}
+}
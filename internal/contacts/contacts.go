@@ -0,0 +1,201 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package contacts keeps an intelligence log of foreign units seen by the clan.
+// The log is saved between runs so that sightings are not lost when older
+// turn reports are removed from the input folder.
+package contacts
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// Sighting_t is a single observation of a foreign unit.
+type Sighting_t struct {
+	UnitId parser.UnitId_t `json:"unit"`
+	Clan   parser.UnitId_t `json:"clan"`
+	TurnId string          `json:"turn"`
+	Hex    string          `json:"hex"`
+	SeenBy parser.UnitId_t `json:"seenBy"`
+
+	Location coords.Map `json:"-"`
+}
+
+// Log_t is the list of sightings, sorted by unit and turn.
+type Log_t struct {
+	Sightings []*Sighting_t `json:"sightings"`
+
+	// index is the sightings by unit and hex, so that Add doesn't scan the whole log.
+	// It is built by the first call to Add.
+	index map[sightingKey_t][]*Sighting_t
+}
+
+type sightingKey_t struct {
+	unitId parser.UnitId_t
+	hex    string
+}
+
+// History_t is the list of sightings for a single unit, sorted by turn.
+type History_t struct {
+	UnitId    parser.UnitId_t
+	Clan      parser.UnitId_t
+	Sightings []*Sighting_t
+}
+
+// LastSeen returns the most recent sighting of the unit.
+func (h *History_t) LastSeen() *Sighting_t {
+	return h.Sightings[len(h.Sightings)-1]
+}
+
+// Load reads the log from a file.
+// If the file does not exist, an empty log is returned.
+func Load(path string) (*Log_t, error) {
+	l := &Log_t{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	} else if err != nil {
+		return nil, err
+	} else if err = json.Unmarshal(data, l); err != nil {
+		return nil, err
+	}
+	for _, s := range l.Sightings {
+		if s.Location, err = coords.HexToMap(s.Hex); err != nil {
+			return nil, errors.Join(errors.New(s.Hex), err)
+		}
+	}
+	return l, nil
+}
+
+// Save writes the log to a file.
+func (l *Log_t) Save(path string) error {
+	l.sort()
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Add adds a sighting to the log. It returns false if the sighting is already in the log.
+func (l *Log_t) Add(s *Sighting_t) bool {
+	if l.index == nil {
+		l.index = map[sightingKey_t][]*Sighting_t{}
+		for _, ls := range l.Sightings {
+			key := sightingKey_t{unitId: ls.UnitId, hex: ls.Hex}
+			l.index[key] = append(l.index[key], ls)
+		}
+	}
+	key := sightingKey_t{unitId: s.UnitId, hex: s.Hex}
+	for _, ls := range l.index[key] {
+		if ls.TurnId == s.TurnId && ls.SeenBy == s.SeenBy {
+			return false
+		}
+	}
+	l.index[key] = append(l.index[key], s)
+	l.Sightings = append(l.Sightings, s)
+	return true
+}
+
+// Collect adds every sighting of a unit outside the clan to the log.
+// The turns must have been walked so that the move locations are set.
+// It returns the number of new sightings.
+func (l *Log_t) Collect(allTurns []*parser.Turn_t, clan parser.UnitId_t) int {
+	added := 0
+	for _, turn := range allTurns {
+		for _, moves := range turn.SortedMoves {
+			var reports []*parser.Move_t
+			reports = append(reports, moves.Moves...)
+			for _, scout := range moves.Scouts {
+				reports = append(reports, scout.Moves...)
+			}
+			for _, move := range reports {
+				if move.Report == nil || move.Location.IsZero() {
+					continue
+				}
+				for _, encounter := range move.Report.Encounters {
+					if encounter.UnitId.InClan(clan) {
+						continue
+					}
+					if l.Add(&Sighting_t{
+						UnitId:   encounter.UnitId,
+						Clan:     encounter.UnitId.Clan(),
+						TurnId:   turn.Id,
+						Hex:      move.Location.GridString(),
+						SeenBy:   moves.UnitId,
+						Location: move.Location,
+					}) {
+						added++
+					}
+				}
+			}
+		}
+	}
+	l.sort()
+	return added
+}
+
+// Histories returns the sightings grouped by unit, sorted by unit id.
+// Sightings after maxTurnId are left out, so that a map for an earlier turn
+// doesn't show what was seen later. An empty maxTurnId includes every sighting.
+func (l *Log_t) Histories(maxTurnId string) []*History_t {
+	l.sort()
+	var list []*History_t
+	for _, s := range l.Sightings {
+		if maxTurnId != "" && s.TurnId > maxTurnId {
+			continue
+		}
+		if len(list) == 0 || list[len(list)-1].UnitId != s.UnitId {
+			list = append(list, &History_t{UnitId: s.UnitId, Clan: s.Clan})
+		}
+		h := list[len(list)-1]
+		h.Sightings = append(h.Sightings, s)
+	}
+	return list
+}
+
+func (l *Log_t) sort() {
+	sort.SliceStable(l.Sightings, func(i, j int) bool {
+		a, b := l.Sightings[i], l.Sightings[j]
+		if a.UnitId != b.UnitId {
+			return a.UnitId < b.UnitId
+		} else if a.TurnId != b.TurnId {
+			return a.TurnId < b.TurnId
+		} else if a.Hex != b.Hex {
+			return a.Hex < b.Hex
+		}
+		return a.SeenBy < b.SeenBy
+	})
+}
+
+// Age returns the number of turns between the two turns (yyyy-mm format).
+// It returns 0 if the first turn is after the second and -1 if either turn id is not valid.
+func Age(fromTurnId, toTurnId string) int {
+	from, to := turnNumber(fromTurnId), turnNumber(toTurnId)
+	if from < 0 || to < 0 {
+		return -1
+	} else if from > to {
+		return 0
+	}
+	return to - from
+}
+
+func turnNumber(turnId string) int {
+	if len(turnId) != 7 || turnId[4] != '-' {
+		return -1
+	}
+	year, err := strconv.Atoi(turnId[:4])
+	if err != nil {
+		return -1
+	}
+	month, err := strconv.Atoi(turnId[5:])
+	if err != nil || month < 1 || month > 12 {
+		return -1
+	}
+	return year*12 + month - 1
+}
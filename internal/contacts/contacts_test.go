@@ -0,0 +1,147 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package contacts_test
+
+import (
+	"github.com/playbymail/ottomap/internal/contacts"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"path/filepath"
+	"testing"
+)
+
+func TestAge(t *testing.T) {
+	for _, tt := range []struct {
+		from, to string
+		want     int
+	}{
+		{"0902-02", "0902-02", 0},
+		{"0902-02", "0902-05", 3},
+		{"0901-12", "0902-01", 1},
+		{"0902-05", "0902-02", 0},
+		{"0902-13", "0902-01", -1},
+		{"N/A", "0902-01", -1},
+	} {
+		if got := contacts.Age(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s -> %s: want %d, got %d", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contacts.json")
+	l := &contacts.Log_t{}
+	l.Add(&contacts.Sighting_t{UnitId: "1590", Clan: "0590", TurnId: "0902-03", Hex: "QQ 1108", SeenBy: "0138"})
+	l.Add(&contacts.Sighting_t{UnitId: "1590", Clan: "0590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138"})
+	if l.Add(&contacts.Sighting_t{UnitId: "1590", Clan: "0590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138"}) {
+		t.Errorf("add: duplicate sighting was added")
+	}
+	if err := l.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	l, err := contacts.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	histories := l.Histories("")
+	if len(histories) != 1 {
+		t.Fatalf("histories: want 1, got %d", len(histories))
+	}
+	last := histories[0].LastSeen()
+	if last.TurnId != "0902-03" || last.Location.GridString() != "QQ 1108" {
+		t.Errorf("last seen: want 0902-03 QQ 1108, got %s %s", last.TurnId, last.Location.GridString())
+	}
+}
+
+func TestHistoriesMaxTurn(t *testing.T) {
+	// the saved log has sightings from a later run than the --max-turn of this one
+	l := &contacts.Log_t{}
+	l.Add(&contacts.Sighting_t{UnitId: "1590", Clan: "0590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138"})
+	l.Add(&contacts.Sighting_t{UnitId: "1590", Clan: "0590", TurnId: "0902-05", Hex: "QQ 1109", SeenBy: "0138"})
+	l.Add(&contacts.Sighting_t{UnitId: "2590", Clan: "0590", TurnId: "0902-06", Hex: "QQ 1110", SeenBy: "0138"})
+	for _, tt := range []struct {
+		id        int
+		maxTurnId string
+		units     int
+		lastSeen  string
+		age       int
+	}{
+		{1, "", 2, "0902-05", 0},
+		{2, "0902-06", 2, "0902-05", 1},
+		{3, "0902-03", 1, "0902-02", 1},
+		{4, "0902-01", 0, "", 0},
+	} {
+		histories := l.Histories(tt.maxTurnId)
+		if len(histories) != tt.units {
+			t.Errorf("%d: units: want %d, got %d", tt.id, tt.units, len(histories))
+			continue
+		} else if tt.units == 0 {
+			continue
+		}
+		last := histories[0].LastSeen()
+		if last.TurnId != tt.lastSeen {
+			t.Errorf("%d: last seen: want %s, got %s", tt.id, tt.lastSeen, last.TurnId)
+		}
+		if tt.maxTurnId == "" {
+			continue
+		}
+		if age := contacts.Age(last.TurnId, tt.maxTurnId); age != tt.age {
+			t.Errorf("%d: age: want %d, got %d", tt.id, tt.age, age)
+		}
+	}
+}
+
+func TestAddLoaded(t *testing.T) {
+	// the index must include the sightings that were loaded, not just the added ones
+	path := filepath.Join(t.TempDir(), "contacts.json")
+	l := &contacts.Log_t{}
+	l.Add(&contacts.Sighting_t{UnitId: "1590", Clan: "0590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138"})
+	if err := l.Save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	l, err := contacts.Load(path)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for _, tt := range []struct {
+		id   int
+		s    contacts.Sighting_t
+		want bool
+	}{
+		{1, contacts.Sighting_t{UnitId: "1590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138"}, false},
+		{2, contacts.Sighting_t{UnitId: "1590", TurnId: "0902-03", Hex: "QQ 1107", SeenBy: "0138"}, true},
+		{3, contacts.Sighting_t{UnitId: "1590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138e1"}, true},
+		{4, contacts.Sighting_t{UnitId: "2590", TurnId: "0902-02", Hex: "QQ 1107", SeenBy: "0138"}, true},
+		{5, contacts.Sighting_t{UnitId: "1590", TurnId: "0902-03", Hex: "QQ 1107", SeenBy: "0138"}, false},
+	} {
+		s := tt.s
+		if got := l.Add(&s); got != tt.want {
+			t.Errorf("%d: add: want %v, got %v", tt.id, tt.want, got)
+		}
+	}
+	if len(l.Sightings) != 4 {
+		t.Errorf("sightings: want 4, got %d", len(l.Sightings))
+	}
+}
+
+func TestCollect(t *testing.T) {
+	report := &parser.Report_t{Encounters: []*parser.Encounter_t{
+		{UnitId: "0138e1"}, // our own element is not a contact
+		{UnitId: "1590"},
+		{UnitId: "1590e1"},
+		{UnitId: "0590c2"},
+	}}
+	turn := &parser.Turn_t{Id: "0902-02", SortedMoves: []*parser.Moves_t{{
+		UnitId: "0138",
+		Moves:  []*parser.Move_t{{Report: report, Location: coords.Map{Column: 10, Row: 7}}},
+	}}}
+	l := &contacts.Log_t{}
+	if got := l.Collect([]*parser.Turn_t{turn}, "0138"); got != 3 {
+		t.Fatalf("collect: want 3, got %d", got)
+	}
+	for _, s := range l.Sightings {
+		if s.Clan != "0590" {
+			t.Errorf("%s: clan: want %q, got %q", s.UnitId, "0590", s.Clan)
+		}
+	}
+}
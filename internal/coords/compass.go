@@ -0,0 +1,121 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package coords
+
+import (
+	"github.com/playbymail/ottomap/internal/compass"
+	"github.com/playbymail/ottomap/internal/direction"
+	"math"
+)
+
+// CompassSteps are the moves from a hex to the hex two away at each compass point.
+// They match the "N/NE" notation that the reports use for far horizons.
+var CompassSteps = map[compass.Point_e][2]direction.Direction_e{
+	compass.North:          {direction.North, direction.North},
+	compass.NorthNorthEast: {direction.North, direction.NorthEast},
+	compass.NorthEast:      {direction.NorthEast, direction.NorthEast},
+	compass.East:           {direction.NorthEast, direction.SouthEast},
+	compass.SouthEast:      {direction.SouthEast, direction.SouthEast},
+	compass.SouthSouthEast: {direction.South, direction.SouthEast},
+	compass.South:          {direction.South, direction.South},
+	compass.SouthSouthWest: {direction.South, direction.SouthWest},
+	compass.SouthWest:      {direction.SouthWest, direction.SouthWest},
+	compass.West:           {direction.SouthWest, direction.NorthWest},
+	compass.NorthWest:      {direction.NorthWest, direction.NorthWest},
+	compass.NorthNorthWest: {direction.North, direction.NorthWest},
+}
+
+// Project returns the hex two away from this one at the compass point.
+// It returns false if the point is unknown.
+func (m Map) Project(p compass.Point_e) (Map, bool) {
+	steps, ok := CompassSteps[p]
+	if !ok {
+		return m, false
+	}
+	return m.Move(steps[0], steps[1]), true
+}
+
+// FarHorizon returns the hexes at distance two, indexed by compass point.
+// These are the hexes that a crow's nest can report.
+func (m Map) FarHorizon() map[compass.Point_e]Map {
+	ring := map[compass.Point_e]Map{}
+	for p, steps := range CompassSteps {
+		ring[p] = m.Move(steps[0], steps[1])
+	}
+	return ring
+}
+
+// Ring returns the hexes at the given distance, clockwise starting from north.
+// The ring at distance zero is the hex itself.
+func (m Map) Ring(radius int) []Map {
+	if radius < 1 {
+		return []Map{m}
+	}
+	at := m
+	for n := 0; n < radius; n++ {
+		at = at.Add(direction.North)
+	}
+	var ring []Map
+	for _, d := range []direction.Direction_e{direction.SouthEast, direction.South, direction.SouthWest, direction.NorthWest, direction.North, direction.NorthEast} {
+		for n := 0; n < radius; n++ {
+			ring = append(ring, at)
+			at = at.Add(d)
+		}
+	}
+	return ring
+}
+
+// Spiral returns the hex and every ring out to the given distance, innermost first.
+func (m Map) Spiral(radius int) []Map {
+	spiral := []Map{m}
+	for r := 1; r <= radius; r++ {
+		spiral = append(spiral, m.Ring(r)...)
+	}
+	return spiral
+}
+
+// Line returns the hexes on the straight line from this hex to the other,
+// including both ends. Each hex is a neighbor of the one before it.
+func (m Map) Line(to Map) []Map {
+	a, b := m.ToCube(), to.ToCube()
+	n := a.Distance(b)
+	if n == 0 {
+		return []Map{m}
+	}
+	// nudge the end points so that lines along hex edges always round the same way
+	const epsilon = 1e-6
+	aq, ar, as := float64(a.Q)+epsilon, float64(a.R)+epsilon, float64(a.S)-2*epsilon
+	bq, br, bs := float64(b.Q)+epsilon, float64(b.R)+epsilon, float64(b.S)-2*epsilon
+	line := make([]Map, 0, n+1)
+	for i := 0; i <= n; i++ {
+		t := float64(i) / float64(n)
+		line = append(line, cubeRound(aq+(bq-aq)*t, ar+(br-ar)*t, as+(bs-as)*t).ToMap())
+	}
+	return line
+}
+
+// LineOfSight returns true if none of the hexes between this hex and the other
+// are blocked. The end points are never checked.
+func (m Map) LineOfSight(to Map, blocked func(Map) bool) bool {
+	line := m.Line(to)
+	for _, hex := range line[1 : len(line)-1] {
+		if blocked(hex) {
+			return false
+		}
+	}
+	return true
+}
+
+// cubeRound returns the hex that contains the fractional cube coordinates.
+func cubeRound(q, r, s float64) Cube {
+	rq, rr, rs := math.Round(q), math.Round(r), math.Round(s)
+	dq, dr, ds := math.Abs(rq-q), math.Abs(rr-r), math.Abs(rs-s)
+	if dq > dr && dq > ds {
+		rq = -rr - rs
+	} else if dr > ds {
+		rr = -rq - rs
+	} else {
+		rs = -rq - rr
+	}
+	return Cube{Q: int(rq), R: int(rr), S: int(rs)}
+}
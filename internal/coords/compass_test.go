@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package coords_test
+
+import (
+	"github.com/playbymail/ottomap/internal/compass"
+	"github.com/playbymail/ottomap/internal/coords"
+	"testing"
+)
+
+func TestProject(t *testing.T) {
+	// check an even and an odd column, since the neighbors differ
+	for _, from := range []coords.Map{{Column: 11, Row: 5}, {Column: 12, Row: 5}} {
+		horizon := from.FarHorizon()
+		if len(horizon) != 12 {
+			t.Errorf("%s: far horizon: want 12 hexes, got %d", from, len(horizon))
+		}
+		for p := compass.North; p <= compass.NorthNorthWest; p++ {
+			to, ok := from.Project(p)
+			if !ok {
+				t.Errorf("%s: %s: not projected", from, p)
+				continue
+			}
+			if got := from.Distance(to); got != 2 {
+				t.Errorf("%s: %s: distance: want 2, got %d", from, p, got)
+			}
+			if got := compass.FromBearing(from.Bearing(to)); got != p {
+				t.Errorf("%s: %s: bearing: got %s", from, p, got)
+			}
+			if horizon[p] != to {
+				t.Errorf("%s: %s: far horizon: want %s, got %s", from, p, to, horizon[p])
+			}
+		}
+		if _, ok := from.Project(compass.Unknown); ok {
+			t.Errorf("%s: unknown point was projected", from)
+		}
+	}
+}
+
+func TestRingAndSpiral(t *testing.T) {
+	from := coords.Map{Column: 11, Row: 5}
+	for _, tc := range []struct {
+		id     int
+		radius int
+		ring   int
+		spiral int
+	}{
+		{1, 0, 1, 1},
+		{2, 1, 6, 7},
+		{3, 2, 12, 19},
+		{4, 3, 18, 37},
+	} {
+		ring := from.Ring(tc.radius)
+		if len(ring) != tc.ring {
+			t.Errorf("%d: ring: want %d hexes, got %d", tc.id, tc.ring, len(ring))
+		}
+		seen := map[coords.Map]bool{}
+		for _, hex := range ring {
+			if got := from.Distance(hex); got != tc.radius {
+				t.Errorf("%d: ring: %s: want distance %d, got %d", tc.id, hex, tc.radius, got)
+			}
+			seen[hex] = true
+		}
+		if len(seen) != len(ring) {
+			t.Errorf("%d: ring: duplicate hexes", tc.id)
+		}
+		if got := len(from.Spiral(tc.radius)); got != tc.spiral {
+			t.Errorf("%d: spiral: want %d hexes, got %d", tc.id, tc.spiral, got)
+		}
+	}
+	if north, _ := from.Project(compass.North); from.Ring(2)[0] != north {
+		t.Errorf("ring: want to start at %s, got %s", north, from.Ring(2)[0])
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	from := coords.Map{Column: 11, Row: 5}
+	for _, tc := range []struct {
+		id      int
+		to      coords.Map
+		blocked coords.Map
+		visible bool
+	}{
+		{1, coords.Map{Column: 11, Row: 9}, coords.Map{Column: 11, Row: 7}, false},
+		{2, coords.Map{Column: 11, Row: 9}, coords.Map{Column: 12, Row: 7}, true},
+		{3, coords.Map{Column: 11, Row: 6}, coords.Map{Column: 11, Row: 6}, true},
+		{4, coords.Map{Column: 15, Row: 3}, coords.Map{Column: 13, Row: 4}, false},
+	} {
+		line := from.Line(tc.to)
+		if len(line) != from.Distance(tc.to)+1 || line[0] != from || line[len(line)-1] != tc.to {
+			t.Errorf("%d: line: got %v", tc.id, line)
+		}
+		for i := 1; i < len(line); i++ {
+			if line[i-1].Distance(line[i]) != 1 {
+				t.Errorf("%d: line: %s and %s are not neighbors", tc.id, line[i-1], line[i])
+			}
+		}
+		got := from.LineOfSight(tc.to, func(hex coords.Map) bool { return hex == tc.blocked })
+		if got != tc.visible {
+			t.Errorf("%d: line of sight: want %v, got %v", tc.id, tc.visible, got)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package coords
+
+import (
+	"math"
+)
+
+// Cube are the coordinates of a hex in a cube.
+// They have the constraint Q + R + S = 0.
+//
+// See https://www.redblobgames.com/grids/hexagons/ for the math.
+type Cube struct {
+	Q int
+	R int
+	S int
+}
+
+// ToCube converts map coordinates to cube coordinates.
+// The map uses an "odd-q" layout: odd columns are shoved down half a hex.
+func (m Map) ToCube() Cube {
+	q := m.Column
+	r := m.Row - (m.Column-(m.Column&1))/2
+	return Cube{Q: q, R: r, S: -q - r}
+}
+
+// ToMap converts cube coordinates back to map coordinates.
+func (c Cube) ToMap() Map {
+	return Map{
+		Column: c.Q,
+		Row:    c.R + (c.Q-(c.Q&1))/2,
+	}
+}
+
+// Distance returns the number of hexes between the two cubes.
+func (c Cube) Distance(to Cube) int {
+	return (abs(c.Q-to.Q) + abs(c.R-to.R) + abs(c.S-to.S)) / 2
+}
+
+// Distance returns the number of hexes between the two locations.
+func (m Map) Distance(to Map) int {
+	return m.ToCube().Distance(to.ToCube())
+}
+
+// Bearing returns the direction from this hex to the other, in degrees
+// measured clockwise from north. The result is in the range [0, 360).
+// The bearing from a hex to itself is zero.
+func (m Map) Bearing(to Map) float64 {
+	// convert to the centers of flat-topped hexes, with y increasing to the south
+	fx, fy := m.center()
+	tx, ty := to.center()
+	dx, dy := tx-fx, ty-fy
+	if dx == 0 && dy == 0 {
+		return 0
+	}
+	degrees := math.Atan2(dx, -dy) * 180 / math.Pi
+	if degrees < 0 {
+		degrees += 360
+	}
+	return degrees
+}
+
+// InPolygon returns true if the center of the hex is inside the polygon.
+// The vertices are the centers of the hexes at the corners of the polygon.
+// Hexes on the corners are inside, but hexes on the sides may not be.
+func (m Map) InPolygon(vertices []Map) bool {
+	x, y := m.center()
+	inside := false
+	for i, j := 0, len(vertices)-1; i < len(vertices); j, i = i, i+1 {
+		if vertices[i] == m {
+			return true
+		}
+		xi, yi := vertices[i].center()
+		xj, yj := vertices[j].center()
+		if (yi > y) != (yj > y) && x < (xj-xi)*(y-yi)/(yj-yi)+xi {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// center returns the center of the hex on a flat-top layout with unit size hexes.
+func (m Map) center() (float64, float64) {
+	x := 1.5 * float64(m.Column)
+	y := math.Sqrt(3) * (float64(m.Row) + 0.5*float64(m.Column&1))
+	return x, y
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
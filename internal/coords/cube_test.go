@@ -0,0 +1,39 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package coords_test
+
+import (
+	"github.com/playbymail/ottomap/internal/compass"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"testing"
+)
+
+func TestDistanceAndBearing(t *testing.T) {
+	from := coords.Map{Column: 11, Row: 5}
+	tests := []struct {
+		id       int
+		to       coords.Map
+		distance int
+		point    compass.Point_e
+	}{
+		{1, from.Move(direction.North), 1, compass.North},
+		{2, from.Move(direction.North, direction.North), 2, compass.North},
+		{3, from.Move(direction.North, direction.NorthEast), 2, compass.NorthNorthEast},
+		{4, from.Move(direction.NorthEast, direction.SouthEast), 2, compass.East},
+		{5, from.Move(direction.South, direction.SouthWest), 2, compass.SouthSouthWest},
+		{6, from.Move(direction.SouthWest, direction.NorthWest), 2, compass.West},
+		{7, from.Move(direction.NorthWest, direction.NorthWest, direction.NorthWest), 3, compass.NorthWest},
+	}
+	for _, tt := range tests {
+		if got := from.Distance(tt.to); got != tt.distance {
+			t.Errorf("%d: distance: want %d, got %d", tt.id, tt.distance, got)
+		}
+		if got := compass.FromBearing(from.Bearing(tt.to)); got != tt.point {
+			t.Errorf("%d: bearing: want %s, got %s", tt.id, tt.point, got)
+		}
+		if got := tt.to.ToCube().ToMap(); got != tt.to {
+			t.Errorf("%d: cube: want %s, got %s", tt.id, tt.to, got)
+		}
+	}
+}
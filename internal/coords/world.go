@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package coords
+
+import (
+	"github.com/playbymail/ottomap/internal/direction"
+	"sort"
+)
+
+// Each grid on the big map is 30 columns by 21 rows.
+const (
+	GridColumns = 30
+	GridRows    = 21
+)
+
+// World is the size of the big map in grids. The map wraps around on an axis
+// that has a size, so that moving east from the last column lands in the first.
+// The zero value is a flat map that never wraps.
+type World struct {
+	Columns int // grids from west to east, 0 for no wrap
+	Rows    int // grids from north to south, 0 for no wrap
+}
+
+// IsZero returns true if the map doesn't wrap.
+func (w World) IsZero() bool {
+	return w.Columns < 1 && w.Rows < 1
+}
+
+// width and height are the size of the world in hexes, zero if the axis doesn't wrap.
+func (w World) width() int {
+	return max(w.Columns, 0) * GridColumns
+}
+
+func (w World) height() int {
+	return max(w.Rows, 0) * GridRows
+}
+
+// Wrap returns the location moved back onto the map.
+// The width is always an even number of columns, so wrapping keeps the
+// column parity and the neighbors stay the same.
+func (w World) Wrap(m Map) Map {
+	if width := w.width(); width != 0 {
+		m.Column = ((m.Column % width) + width) % width
+	}
+	if height := w.height(); height != 0 {
+		m.Row = ((m.Row % height) + height) % height
+	}
+	return m
+}
+
+// Add returns the neighbor in the given direction, wrapped onto the map.
+func (w World) Add(m Map, d direction.Direction_e) Map {
+	return w.Wrap(m.Add(d))
+}
+
+// Move returns the location after the moves, wrapped onto the map.
+func (w World) Move(m Map, ds ...direction.Direction_e) Map {
+	return w.Wrap(m.Move(ds...))
+}
+
+// Nearest returns the copy of the location that is closest to the origin.
+// The copies are the location shifted by the size of the world, so the result
+// may be off the map; it is used to measure and draw across the seam.
+func (w World) Nearest(from, to Map) Map {
+	to = w.Wrap(to)
+	if width := w.width(); width != 0 {
+		if d := to.Column - from.Column; d > width/2 {
+			to.Column -= width
+		} else if d < -width/2 {
+			to.Column += width
+		}
+	}
+	if height := w.height(); height != 0 {
+		if d := to.Row - from.Row; d > height/2 {
+			to.Row -= height
+		} else if d < -height/2 {
+			to.Row += height
+		}
+	}
+	return to
+}
+
+// Distance returns the number of hexes between the two locations, going
+// across the seam when that is shorter.
+func (w World) Distance(from, to Map) int {
+	from = w.Wrap(from)
+	return from.Distance(w.Nearest(from, to))
+}
+
+// Seam returns where to cut the map so that the hexes are drawn together.
+// The cut is at the end of the widest empty band of columns and rows;
+// hexes before the cut are moved to the far side by Unwrap.
+// On an axis that doesn't wrap, the cut is zero.
+func (w World) Seam(hexes []Map) Map {
+	cut := func(size int, values []int) int {
+		if size == 0 || len(values) == 0 {
+			return 0
+		}
+		sort.Ints(values)
+		// the gap from the last value around to the first is the starting candidate
+		gap, end := values[0]+size-values[len(values)-1], values[0]
+		for i := 1; i < len(values); i++ {
+			if g := values[i] - values[i-1]; g > gap {
+				gap, end = g, values[i]
+			}
+		}
+		return end
+	}
+	var columns, rows []int
+	for _, hex := range hexes {
+		hex = w.Wrap(hex)
+		columns, rows = append(columns, hex.Column), append(rows, hex.Row)
+	}
+	return Map{Column: cut(w.width(), columns), Row: cut(w.height(), rows)}
+}
+
+// Unwrap returns the location that the hex is drawn at, given the seam.
+// Hexes before the seam are moved past the other edge of the map.
+func (w World) Unwrap(seam, m Map) Map {
+	m = w.Wrap(m)
+	if m.Column < seam.Column {
+		m.Column += w.width()
+	}
+	if m.Row < seam.Row {
+		m.Row += w.height()
+	}
+	return m
+}
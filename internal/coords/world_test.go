@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package coords_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"testing"
+)
+
+func TestWorld(t *testing.T) {
+	// 26 grids across is 780 columns, 26 grids down is 546 rows
+	world := coords.World{Columns: 26, Rows: 26}
+	for _, tc := range []struct {
+		id       int
+		from     coords.Map
+		moves    []direction.Direction_e
+		want     coords.Map
+		distance int
+	}{
+		{1, coords.Map{Column: 779, Row: 10}, []direction.Direction_e{direction.NorthEast}, coords.Map{Column: 0, Row: 10}, 1},
+		{2, coords.Map{Column: 0, Row: 10}, []direction.Direction_e{direction.NorthWest, direction.NorthWest}, coords.Map{Column: 778, Row: 9}, 2},
+		{3, coords.Map{Column: 5, Row: 0}, []direction.Direction_e{direction.North}, coords.Map{Column: 5, Row: 545}, 1},
+		{4, coords.Map{Column: 5, Row: 545}, []direction.Direction_e{direction.South, direction.South}, coords.Map{Column: 5, Row: 1}, 2},
+		{5, coords.Map{Column: 100, Row: 100}, []direction.Direction_e{direction.South}, coords.Map{Column: 100, Row: 101}, 1},
+	} {
+		got := world.Move(tc.from, tc.moves...)
+		if got != tc.want {
+			t.Errorf("%d: move: want %s, got %s", tc.id, tc.want, got)
+		}
+		if d := world.Distance(tc.from, got); d != tc.distance {
+			t.Errorf("%d: distance: want %d, got %d", tc.id, tc.distance, d)
+		}
+	}
+
+	// a flat world doesn't wrap
+	flat := coords.World{}
+	if got := flat.Add(coords.Map{Column: 0, Row: 0}, direction.North); got != (coords.Map{Column: 0, Row: -1}) {
+		t.Errorf("flat: want (0, -1), got %s", got)
+	}
+}
+
+func TestWorldSeam(t *testing.T) {
+	// hexes on both sides of the east-west seam are drawn together
+	world := coords.World{Columns: 26}
+	hexes := []coords.Map{{Column: 777, Row: 10}, {Column: 779, Row: 10}, {Column: 0, Row: 11}, {Column: 2, Row: 12}}
+	seam := world.Seam(hexes)
+	var unwrapped []coords.Map
+	for _, hex := range hexes {
+		unwrapped = append(unwrapped, world.Unwrap(seam, hex))
+	}
+	for i := 1; i < len(unwrapped); i++ {
+		if d := unwrapped[i].Column - unwrapped[i-1].Column; d != 2 && d != 1 {
+			t.Errorf("seam %s: %s and %s are not side by side", seam, unwrapped[i-1], unwrapped[i])
+		}
+	}
+
+	// hexes that don't straddle the seam don't move
+	hexes = []coords.Map{{Column: 100, Row: 10}, {Column: 110, Row: 10}}
+	seam = world.Seam(hexes)
+	for _, hex := range hexes {
+		if got := world.Unwrap(seam, hex); got != hex {
+			t.Errorf("seam %s: want %s, got %s", seam, hex, got)
+		}
+	}
+}
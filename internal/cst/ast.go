@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package cst
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/ast"
+	"regexp"
+	"strconv"
+)
+
+var (
+	rxScoutNo = regexp.MustCompile(`^[1-8]:Scout$`)
+	rxTurnId  = regexp.MustCompile(`^(\d{3,4})-(\d{1,2})$`)
+)
+
+// ToAST returns the abstract syntax tree for the report.
+// The section headers and turn lines are parsed here; the other lines are
+// typed and passed through for the step parsers.
+func (r *Report_t) ToAST() (*ast.Report_t, error) {
+	report := &ast.Report_t{}
+	for _, section := range r.Sections {
+		unit, err := section.toAST()
+		if err != nil {
+			return report, err
+		}
+		report.Units = append(report.Units, unit)
+	}
+	return report, nil
+}
+
+func (s *Section_t) toAST() (*ast.Unit_t, error) {
+	unit := &ast.Unit_t{Id: s.UnitId, Line: s.Header.No}
+
+	// Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)
+	if text, ok := s.Header.After("Current", "Hex", "="); !ok {
+		return nil, fmt.Errorf("%d: %s: missing current hex", s.Header.No, s.UnitId)
+	} else if hex, _, ok := bytes.Cut(text, []byte{','}); !ok {
+		return nil, fmt.Errorf("%d: %s: current hex: missing comma", s.Header.No, s.UnitId)
+	} else {
+		unit.CurrentHex = string(bytes.TrimSpace(hex))
+	}
+	if text, ok := s.Header.After("(", "Previous", "Hex", "="); !ok {
+		return nil, fmt.Errorf("%d: %s: missing previous hex", s.Header.No, s.UnitId)
+	} else if hex, _, ok := bytes.Cut(text, []byte{')'}); !ok {
+		return nil, fmt.Errorf("%d: %s: previous hex: missing closing paren", s.Header.No, s.UnitId)
+	} else {
+		unit.PreviousHex = string(bytes.TrimSpace(hex))
+	}
+
+	for _, line := range s.Lines {
+		words := line.Words()
+		lineType := ast.UNKNOWN_LINE
+		switch {
+		case line.HasPrefix("Current", "Turn"):
+			if unit.Turn != nil {
+				continue
+			}
+			turn, err := turnFromWords(words)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %s: %w", line.No, s.UnitId, err)
+			}
+			turn.Line = line.No
+			unit.Turn = turn
+			continue
+		case line.HasPrefix(">", ">", ">", ">"):
+			lineType = ast.SPECIAL_HEX
+		case line.HasPrefix("Tribe", "Movement:"):
+			lineType = ast.TRIBE_MOVEMENT
+		case line.HasPrefix("Tribe", "Follows"):
+			lineType = ast.TRIBE_FOLLOWS
+		case line.HasPrefix("Tribe", "Goes", "to"):
+			lineType = ast.TRIBE_GOES_TO
+		case len(words) > 3 && isWindStrength(words[0]) && words[2] == "Fleet" && words[3] == "Movement:":
+			lineType = ast.FLEET_MOVEMENT
+		case len(words) > 1 && words[0] == "Scout" && rxScoutNo.MatchString(words[1]):
+			lineType = ast.SCOUT_MOVEMENT
+		case line.HasPrefix(s.UnitId, "Status:"):
+			lineType = ast.STATUS
+		case len(words) > 0 && (words[0] == "Created" || words[0] == "Disbanded"):
+			lineType = ast.UNIT_EVENT
+		case len(words) > 0 && words[0] == "Settlement":
+			lineType = ast.SETTLEMENT_OWNER
+		default:
+			continue
+		}
+		unit.Lines = append(unit.Lines, &ast.Line_t{No: line.No, Type: lineType, Text: line.Text()})
+	}
+
+	return unit, nil
+}
+
+// turnFromWords parses "Current Turn 902-02 (#26), ...".
+func turnFromWords(words []string) (*ast.Turn_t, error) {
+	if len(words) < 3 {
+		return nil, fmt.Errorf("current turn: missing turn id")
+	}
+	match := rxTurnId.FindStringSubmatch(words[2])
+	if match == nil {
+		return nil, fmt.Errorf("current turn: invalid turn id %q", words[2])
+	}
+	year, _ := strconv.Atoi(match[1])
+	month, _ := strconv.Atoi(match[2])
+	if !(1 <= month && month <= 12) {
+		return nil, fmt.Errorf("current turn: invalid month %q", words[2])
+	}
+	return &ast.Turn_t{Year: year, Month: month}, nil
+}
+
+func isWindStrength(word string) bool {
+	switch word {
+	case "CALM", "MILD", "STRONG", "GALE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package cst builds the concrete syntax tree for a turn report.
+// The tree keeps every token from the lexer, grouped into lines and
+// then into unit sections, so that the original text can be recovered.
+package cst
+
+import (
+	"bytes"
+	"github.com/playbymail/ottomap/internal/ast"
+	"regexp"
+	"strings"
+)
+
+var (
+	// rxUnitId matches a unit id followed by the comma that ends it in a section header.
+	rxUnitId = regexp.MustCompile(`^\d{4}([cefg]\d)?,$`)
+)
+
+// Report_t is the concrete syntax tree for a turn report.
+// Lines before the first unit section are kept in the preamble.
+type Report_t struct {
+	Preamble []*Line_t
+	Sections []*Section_t
+}
+
+// Section_t is the lines for a single unit, starting with the section header
+// (for example "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)").
+type Section_t struct {
+	Kind   string // Courier, Element, Fleet, Garrison, or Tribe
+	UnitId string
+	Header *Line_t
+	Lines  []*Line_t
+}
+
+// Line_t is the tokens for a single line, without the end of line.
+type Line_t struct {
+	No     int
+	Tokens []ast.Token
+}
+
+// Parse returns the concrete syntax tree for the input.
+// It never fails; lines that don't belong to a section are kept in the preamble.
+func Parse(input []byte) *Report_t {
+	r := &Report_t{}
+	var section *Section_t
+	line := &Line_t{No: 1}
+	lexer := ast.NewLexer(input)
+	for tok := lexer.Next(); ; tok = lexer.Next() {
+		if tok.Type != ast.EOL && tok.Type != ast.EOF {
+			line.Tokens = append(line.Tokens, tok)
+			continue
+		}
+		if len(line.Tokens) != 0 {
+			if kind, unitId, ok := line.sectionHeader(); ok {
+				section = &Section_t{Kind: kind, UnitId: unitId, Header: line}
+				r.Sections = append(r.Sections, section)
+			} else if section != nil {
+				section.Lines = append(section.Lines, line)
+			} else {
+				r.Preamble = append(r.Preamble, line)
+			}
+		}
+		if tok.Type == ast.EOF {
+			break
+		}
+		line = &Line_t{No: tok.Line + 1}
+	}
+	return r
+}
+
+// sectionHeader returns the kind and unit id if the line starts a unit section.
+func (l *Line_t) sectionHeader() (kind, unitId string, ok bool) {
+	words := l.Words()
+	if len(words) < 2 {
+		return "", "", false
+	}
+	switch words[0] {
+	case "Courier", "Element", "Fleet", "Garrison", "Tribe":
+	default:
+		return "", "", false
+	}
+	if !rxUnitId.MatchString(words[1]) {
+		return "", "", false
+	}
+	return words[0], strings.TrimSuffix(words[1], ","), true
+}
+
+// Text returns the original text of the line.
+func (l *Line_t) Text() []byte {
+	var text []byte
+	for _, tok := range l.Tokens {
+		text = append(text, tok.Literal...)
+	}
+	return text
+}
+
+// Words returns the literals for the words on the line, skipping spaces and invalid runes.
+func (l *Line_t) Words() []string {
+	var words []string
+	for _, tok := range l.Tokens {
+		if tok.Type == ast.WORD {
+			words = append(words, string(tok.Literal))
+		}
+	}
+	return words
+}
+
+// HasPrefix returns true if the words on the line start with the given words.
+func (l *Line_t) HasPrefix(prefix ...string) bool {
+	words := l.Words()
+	if len(words) < len(prefix) {
+		return false
+	}
+	for n, word := range prefix {
+		if words[n] != word {
+			return false
+		}
+	}
+	return true
+}
+
+// After returns the text of the line following the given sequence of words,
+// with leading spaces removed. Returns false if the sequence isn't found.
+func (l *Line_t) After(seq ...string) ([]byte, bool) {
+	for start := range l.Tokens {
+		n, pos := 0, start
+		for ; n < len(seq) && pos < len(l.Tokens); pos++ {
+			if tok := l.Tokens[pos]; tok.Type == ast.SPACES {
+				continue
+			} else if tok.Type != ast.WORD || string(tok.Literal) != seq[n] {
+				break
+			}
+			n++
+		}
+		if n == len(seq) {
+			rest := &Line_t{No: l.No, Tokens: l.Tokens[pos:]}
+			return bytes.TrimLeft(rest.Text(), " \t"), true
+		}
+	}
+	return nil, false
+}
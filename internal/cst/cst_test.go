@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package cst_test
+
+import (
+	"github.com/playbymail/ottomap/internal/ast"
+	"github.com/playbymail/ottomap/internal/cst"
+	"testing"
+)
+
+func TestToAST(t *testing.T) {
+	input := []byte("Tribe Movement: Move N\r\n" +
+		"Tribe 0138, , Current Hex = ## 1008, (Previous Hex = N/A)\r\n" +
+		"Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\r\n" +
+		"Tribe Movement: Move N-PR\r\n" +
+		"Tribe Follows 0138e1\r\n" +
+		"Scout 1:Scout Still, 0138e1\r\n" +
+		"0138 Status: PRAIRIE, 0138\r\n" +
+		"Humans 10\r\n" +
+		"\r\n" +
+		"Element 0138e1, Cavalry, Current Hex = QQ 1109, (Previous Hex = QQ 1108)\n" +
+		"CALM NE Fleet Movement: Move N-O\n" +
+		"Tribe Goes to QQ 1010\n" +
+		">>>>Alpha>A\n")
+	r := cst.Parse(input)
+	if len(r.Preamble) != 1 || r.Preamble[0].No != 1 {
+		t.Fatalf("preamble: want line 1, got %d lines", len(r.Preamble))
+	} else if len(r.Sections) != 2 {
+		t.Fatalf("sections: want 2, got %d", len(r.Sections))
+	} else if got := string(r.Sections[0].Header.Text()); got != "Tribe 0138, , Current Hex = ## 1008, (Previous Hex = N/A)" {
+		t.Errorf("header: text: got %q", got)
+	}
+
+	report, err := r.ToAST()
+	if err != nil {
+		t.Fatalf("ast: want nil, got %v", err)
+	}
+	tribe, element := report.Units[0], report.Units[1]
+	if tribe.Id != "0138" || tribe.Line != 2 || tribe.CurrentHex != "## 1008" || tribe.PreviousHex != "N/A" {
+		t.Errorf("tribe: got %q %d %q %q", tribe.Id, tribe.Line, tribe.CurrentHex, tribe.PreviousHex)
+	}
+	if tribe.Turn == nil || tribe.Turn.Year != 902 || tribe.Turn.Month != 2 || tribe.Turn.Line != 3 {
+		t.Errorf("tribe: turn: got %+v", tribe.Turn)
+	}
+	if element.Id != "0138e1" || element.CurrentHex != "QQ 1109" || element.PreviousHex != "QQ 1108" || element.Turn != nil {
+		t.Errorf("element: got %q %q %q %+v", element.Id, element.CurrentHex, element.PreviousHex, element.Turn)
+	}
+
+	for _, tc := range []struct {
+		unit  *ast.Unit_t
+		types []ast.LineType
+	}{
+		{tribe, []ast.LineType{ast.TRIBE_MOVEMENT, ast.TRIBE_FOLLOWS, ast.SCOUT_MOVEMENT, ast.STATUS}},
+		{element, []ast.LineType{ast.FLEET_MOVEMENT, ast.TRIBE_GOES_TO, ast.SPECIAL_HEX}},
+	} {
+		if len(tc.unit.Lines) != len(tc.types) {
+			t.Errorf("%s: lines: want %d, got %d", tc.unit.Id, len(tc.types), len(tc.unit.Lines))
+			continue
+		}
+		for n, line := range tc.unit.Lines {
+			if line.Type != tc.types[n] {
+				t.Errorf("%s: line %d: type: want %d, got %d", tc.unit.Id, line.No, tc.types[n], line.Type)
+			}
+		}
+	}
+	if got := string(tribe.Lines[3].Text); got != "0138 Status: PRAIRIE, 0138" {
+		t.Errorf("status: text: got %q", got)
+	}
+}
@@ -33,6 +33,27 @@ var Directions = []Direction_e{
 	NorthWest,
 }
 
+// Opposite returns the direction that points back to the hex we came from.
+// Edges are shared by two hexes, so we need this to find an edge that was
+// reported from the neighbor's side.
+func (d Direction_e) Opposite() Direction_e {
+	switch d {
+	case North:
+		return South
+	case NorthEast:
+		return SouthWest
+	case SouthEast:
+		return NorthWest
+	case South:
+		return North
+	case SouthWest:
+		return NorthEast
+	case NorthWest:
+		return SouthEast
+	}
+	return Unknown
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (d Direction_e) MarshalJSON() ([]byte, error) {
 	return json.Marshal(EnumToString[d])
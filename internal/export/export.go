@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package export writes the merged map data as delimited text files
+// for analysis in spreadsheets and other tools.
+package export
+
+import (
+	"encoding/csv"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	CSV = ','  // separator for comma separated files
+	TSV = '\t' // separator for tab separated files
+)
+
+// Tiles writes one row per tile with the terrain and the turns the tile was seen.
+func Tiles(w io.Writer, worldMap *tiles.Map_t, sep rune) error {
+	cw := newWriter(w, sep)
+	if err := cw.Write([]string{"hex", "terrain", "first_seen", "last_seen", "discovered_by", "visited", "scouted", "resources", "settlements"}); err != nil {
+		return err
+	}
+	for _, tile := range sortedTiles(worldMap) {
+		var resources, settlements []string
+		for _, r := range tile.Resources {
+			resources = append(resources, r.String())
+		}
+		for _, s := range tile.Settlements {
+			settlements = append(settlements, s.Name)
+		}
+		if err := cw.Write([]string{
+			tile.Location.GridString(),
+			tile.Terrain.String(),
+			tile.FirstSeen,
+			tile.LastSeen,
+			string(tile.DiscoveredBy),
+			tile.Visited,
+			tile.Scouted,
+			strings.Join(resources, " "),
+			strings.Join(settlements, "; "),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Settlements writes one row for every settlement and special hex on the map.
+func Settlements(w io.Writer, worldMap *tiles.Map_t, sep rune) error {
+	cw := newWriter(w, sep)
+	if err := cw.Write([]string{"hex", "name", "kind", "turn"}); err != nil {
+		return err
+	}
+	for _, tile := range sortedTiles(worldMap) {
+		for _, s := range tile.Settlements {
+			if err := cw.Write([]string{tile.Location.GridString(), s.Name, "settlement", s.TurnId}); err != nil {
+				return err
+			}
+		}
+		for _, s := range tile.Special {
+			if err := cw.Write([]string{tile.Location.GridString(), s.Name, "special", s.TurnId}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Encounters writes one row for every unit encountered on the map.
+func Encounters(w io.Writer, worldMap *tiles.Map_t, sep rune) error {
+	cw := newWriter(w, sep)
+	if err := cw.Write([]string{"hex", "turn", "unit", "clan", "friendly"}); err != nil {
+		return err
+	}
+	for _, tile := range sortedTiles(worldMap) {
+		encounters := append([]*parser.Encounter_t{}, tile.Encounters...)
+		sort.SliceStable(encounters, func(i, j int) bool {
+			if encounters[i].TurnId == encounters[j].TurnId {
+				return encounters[i].UnitId < encounters[j].UnitId
+			}
+			return encounters[i].TurnId < encounters[j].TurnId
+		})
+		for _, e := range encounters {
+			friendly := "no"
+			if e.Friendly {
+				friendly = "yes"
+			}
+			if err := cw.Write([]string{tile.Location.GridString(), e.TurnId, string(e.UnitId), string(e.UnitId.Clan()), friendly}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func newWriter(w io.Writer, sep rune) *csv.Writer {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	return cw
+}
+
+// sortedTiles returns the tiles sorted by grid coordinates.
+func sortedTiles(worldMap *tiles.Map_t) []*tiles.Tile_t {
+	var list []*tiles.Tile_t
+	for _, tile := range worldMap.Tiles {
+		list = append(list, tile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Location.GridString() < list[j].Location.GridString()
+	})
+	return list
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package export_test
+
+import (
+	"bytes"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	worldMap := tiles.NewMap()
+	tile := worldMap.FetchTile("0138", coords.Map{Column: 10, Row: 10})
+	tile.Terrain = terrain.Prairie
+	tile.MarkSeen("0902-03", "0138e1")
+	tile.MarkSeen("0902-02", "0138")
+	tile.Settlements = append(tile.Settlements, &parser.Settlement_t{TurnId: "0902-02", Name: "Alpha, Beta"})
+	tile.Encounters = append(tile.Encounters, &parser.Encounter_t{TurnId: "0902-03", UnitId: "1590e1"})
+
+	tests := []struct {
+		id   int
+		fn   func(*bytes.Buffer) error
+		want string
+	}{
+		{1, func(b *bytes.Buffer) error { return export.Tiles(b, worldMap, export.CSV) },
+			"hex,terrain,first_seen,last_seen,discovered_by,visited,scouted,resources,settlements\n" +
+				"AA 1111,PR,0902-02,0902-03,0138,,,,\"Alpha, Beta\"\n"},
+		{2, func(b *bytes.Buffer) error { return export.Settlements(b, worldMap, export.TSV) },
+			"hex\tname\tkind\tturn\n" +
+				"AA 1111\tAlpha, Beta\tsettlement\t0902-02\n"},
+		{3, func(b *bytes.Buffer) error { return export.Encounters(b, worldMap, export.CSV) },
+			"hex,turn,unit,clan,friendly\n" +
+				"AA 1111,0902-03,1590e1,0590,no\n"},
+	}
+	for _, tt := range tests {
+		b := &bytes.Buffer{}
+		if err := tt.fn(b); err != nil {
+			t.Errorf("%d: error: %v", tt.id, err)
+		} else if got := b.String(); got != tt.want {
+			t.Errorf("%d: want %q, got %q", tt.id, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Format_e is the file format of a merged tile dump.
+type Format_e int
+
+const (
+	JSON      Format_e = iota // an indented array of tiles
+	NDJSON                    // one tile per line, for streaming into other tools
+	Delimited                 // one row per tile, with the lists flattened into columns
+)
+
+var (
+	// FormatToString is a helper map for marshalling the enum
+	FormatToString = map[Format_e]string{
+		JSON:      "json",
+		NDJSON:    "ndjson",
+		Delimited: "csv",
+	}
+	// StringToFormat is a helper map for unmarshalling the enum
+	StringToFormat = map[string]Format_e{
+		"json":   JSON,
+		"ndjson": NDJSON,
+		"csv":    Delimited,
+	}
+)
+
+func (e Format_e) String() string {
+	if str, ok := FormatToString[e]; ok {
+		return str
+	}
+	return fmt.Sprintf("Format_e(%d)", int(e))
+}
+
+// FormatOf returns the format named by s. When s is empty, the format is
+// chosen from the extension of the path: ".ndjson" and ".jsonl" are NDJSON,
+// ".csv" is CSV, and everything else is JSON.
+func FormatOf(s, path string) (Format_e, error) {
+	if s != "" {
+		if format, ok := StringToFormat[strings.ToLower(s)]; ok {
+			return format, nil
+		}
+		return JSON, fmt.Errorf("unknown format %q: want json, ndjson, or csv", s)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return NDJSON, nil
+	case ".csv":
+		return Delimited, nil
+	}
+	return JSON, nil
+}
+
+// Confidence levels for a merged tile, from most to least certain.
+const (
+	Visited  = "visited"  // a unit moved through or stayed in the hex
+	Scouted  = "scouted"  // a scouting party or a unit's status line reported the hex
+	Observed = "observed" // the terrain was reported from a neighboring hex
+	Inferred = "inferred" // only the kind of terrain is known, for example from a fleet's sighting
+)
+
+// MergedTile_t is a tile from the merged map, with where the data came from.
+type MergedTile_t struct {
+	Hex          string                    `json:"hex"` // "AB 0102"
+	Terrain      terrain.Terrain_e         `json:"terrain"`
+	Confidence   string                    `json:"confidence"`
+	FirstSeen    string                    `json:"firstSeen,omitempty"`
+	LastSeen     string                    `json:"lastSeen,omitempty"`
+	Changed      string                    `json:"changed,omitempty"`
+	DiscoveredBy string                    `json:"discoveredBy,omitempty"`
+	Visited      string                    `json:"visited,omitempty"`
+	Scouted      string                    `json:"scouted,omitempty"`
+	Sources      []string                  `json:"sources,omitempty"` // units that reported the tile
+	Edges        map[string][]edges.Edge_e `json:"edges,omitempty"`   // by direction, for example "NE"
+	Resources    []resources.Resource_e    `json:"resources,omitempty"`
+	Settlements  []*MergedSettlement_t     `json:"settlements,omitempty"`
+	Special      []*MergedSpecial_t        `json:"special,omitempty"`
+	Encounters   []*MergedEncounter_t      `json:"encounters,omitempty"`
+	Lost         []*MergedLost_t           `json:"lost,omitempty"`
+	Notes        []string                  `json:"notes,omitempty"` // the lost units, written with the note policy
+}
+
+type MergedSettlement_t struct {
+	Name   string `json:"name"`
+	TurnId string `json:"turn,omitempty"`
+}
+
+type MergedSpecial_t struct {
+	Id         string   `json:"id"`
+	Name       string   `json:"name"`
+	TurnId     string   `json:"turn,omitempty"`
+	Directives []string `json:"directives,omitempty"` // "!icon Settlement City", "!capital"
+}
+
+type MergedEncounter_t struct {
+	TurnId   string `json:"turn"`
+	UnitId   string `json:"unit"`
+	Friendly bool   `json:"friendly,omitempty"`
+}
+
+type MergedLost_t struct {
+	TurnId  string `json:"turn"`
+	UnitId  string `json:"unit"`
+	ScoutNo int    `json:"scout,omitempty"`
+}
+
+// Merged returns the tiles sorted by grid coordinates.
+func Merged(worldMap *tiles.Map_t, notes wxx.NotePolicy) []*MergedTile_t {
+	var list []*MergedTile_t
+	for _, tile := range sortedTiles(worldMap) {
+		mt := &MergedTile_t{
+			Hex:          tile.Location.GridString(),
+			Terrain:      tile.Terrain,
+			Confidence:   confidence(tile),
+			FirstSeen:    tile.FirstSeen,
+			LastSeen:     tile.LastSeen,
+			Changed:      tile.Changed,
+			DiscoveredBy: string(tile.DiscoveredBy),
+			Visited:      tile.Visited,
+			Scouted:      tile.Scouted,
+			Resources:    tile.Resources,
+		}
+		for source, ok := range tile.SourcedBy {
+			if ok {
+				mt.Sources = append(mt.Sources, source)
+			}
+		}
+		sort.Strings(mt.Sources)
+		for _, d := range direction.Directions {
+			if len(tile.Edges[d]) == 0 {
+				continue
+			} else if mt.Edges == nil {
+				mt.Edges = map[string][]edges.Edge_e{}
+			}
+			mt.Edges[d.String()] = tile.Edges[d]
+		}
+		for _, s := range tile.Settlements {
+			mt.Settlements = append(mt.Settlements, &MergedSettlement_t{Name: s.Name, TurnId: s.TurnId})
+		}
+		for _, s := range tile.Special {
+			ms := &MergedSpecial_t{Id: s.Id, Name: s.Name, TurnId: s.TurnId}
+			for _, d := range s.Directives {
+				ms.Directives = append(ms.Directives, d.String())
+			}
+			mt.Special = append(mt.Special, ms)
+		}
+		for _, e := range tile.Encounters {
+			mt.Encounters = append(mt.Encounters, &MergedEncounter_t{TurnId: e.TurnId, UnitId: string(e.UnitId), Friendly: e.Friendly})
+		}
+		var lost []wxx.NoteLine
+		for _, l := range tile.Lost {
+			mt.Lost = append(mt.Lost, &MergedLost_t{TurnId: l.TurnId, UnitId: string(l.UnitId), ScoutNo: l.ScoutNo})
+			lost = append(lost, wxx.NoteLine{Kind: "lost", Message: fmt.Sprintf("%s vanished", l), TurnId: l.TurnId})
+		}
+		mt.Notes = notes.Text(lost)
+		list = append(list, mt)
+	}
+	return list
+}
+
+// confidence returns how certain the terrain of the tile is.
+func confidence(tile *tiles.Tile_t) string {
+	switch {
+	case tile.Visited != "":
+		return Visited
+	case tile.Scouted != "":
+		return Scouted
+	}
+	switch tile.Terrain {
+	case terrain.Blank, terrain.UnknownJungleSwamp, terrain.UnknownLand, terrain.UnknownMountain, terrain.UnknownWater:
+		return Inferred
+	}
+	return Observed
+}
+
+// WriteMerged writes the merged tiles in the format.
+func WriteMerged(w io.Writer, list []*MergedTile_t, format Format_e) error {
+	switch format {
+	case JSON:
+		if list == nil {
+			list = []*MergedTile_t{}
+		}
+		data, err := json.MarshalIndent(list, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		for _, mt := range list {
+			if err := enc.Encode(mt); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Delimited:
+		return writeMergedCSV(w, list)
+	}
+	return fmt.Errorf("unknown format %d", format)
+}
+
+// writeMergedCSV writes one row per tile. Lists are joined with spaces, or
+// with semicolons for names that may have spaces in them.
+func writeMergedCSV(w io.Writer, list []*MergedTile_t) error {
+	cw := newWriter(w, CSV)
+	if err := cw.Write([]string{"hex", "terrain", "confidence", "first_seen", "last_seen", "changed", "discovered_by", "visited", "scouted", "sources", "edges", "resources", "settlements", "special", "encounters", "lost"}); err != nil {
+		return err
+	}
+	for _, mt := range list {
+		var edgeList, resourceList, settlements, special, encounters, lost []string
+		for _, d := range direction.Directions {
+			for _, e := range mt.Edges[d.String()] {
+				edgeList = append(edgeList, fmt.Sprintf("%s:%s", d, e))
+			}
+		}
+		for _, r := range mt.Resources {
+			resourceList = append(resourceList, r.String())
+		}
+		for _, s := range mt.Settlements {
+			settlements = append(settlements, s.Name)
+		}
+		for _, s := range mt.Special {
+			special = append(special, s.Name)
+		}
+		for _, e := range mt.Encounters {
+			encounters = append(encounters, fmt.Sprintf("%s:%s", e.TurnId, e.UnitId))
+		}
+		for _, l := range mt.Lost {
+			lost = append(lost, fmt.Sprintf("%s:%s", l.TurnId, l.UnitId))
+		}
+		if err := cw.Write([]string{
+			mt.Hex,
+			mt.Terrain.String(),
+			mt.Confidence,
+			mt.FirstSeen,
+			mt.LastSeen,
+			mt.Changed,
+			mt.DiscoveredBy,
+			mt.Visited,
+			mt.Scouted,
+			strings.Join(mt.Sources, " "),
+			strings.Join(edgeList, " "),
+			strings.Join(resourceList, " "),
+			strings.Join(settlements, "; "),
+			strings.Join(special, "; "),
+			strings.Join(encounters, " "),
+			strings.Join(lost, " "),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadMerged reads the tiles written by WriteMerged as JSON or NDJSON.
+func ReadMerged(r io.Reader) ([]*MergedTile_t, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) != 0 && data[0] == '[' {
+		var list []*MergedTile_t
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	var list []*MergedTile_t
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for line := 1; dec.More(); line++ {
+		mt := &MergedTile_t{}
+		if err := dec.Decode(mt); err != nil {
+			return nil, fmt.Errorf("tile %d: %w", line, err)
+		}
+		list = append(list, mt)
+	}
+	return list, nil
+}
+
+// MergedMap rebuilds the map from the merged tiles, so that it can be
+// rendered without loading the turn reports again.
+func MergedMap(list []*MergedTile_t) (*tiles.Map_t, error) {
+	worldMap := tiles.NewMap()
+	for _, mt := range list {
+		location, err := coords.HexToMap(mt.Hex)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", mt.Hex, err)
+		} else if _, ok := worldMap.Tiles[location]; ok {
+			return nil, fmt.Errorf("%q: duplicate tile", mt.Hex)
+		}
+		tile := worldMap.FetchTile("", location)
+		tile.Terrain = mt.Terrain
+		tile.FirstSeen, tile.LastSeen, tile.Changed = mt.FirstSeen, mt.LastSeen, mt.Changed
+		tile.DiscoveredBy = parser.UnitId_t(mt.DiscoveredBy)
+		tile.Visited, tile.Scouted = mt.Visited, mt.Scouted
+		tile.Source(mt.Sources...)
+		for key, list := range mt.Edges {
+			d, ok := direction.StringToEnum[key]
+			if !ok || d == direction.Unknown {
+				return nil, fmt.Errorf("%q: edges: invalid direction %q", mt.Hex, key)
+			}
+			for _, e := range list {
+				tile.MergeEdge(d, e)
+			}
+		}
+		tile.Resources = append(tile.Resources, mt.Resources...)
+		for _, s := range mt.Settlements {
+			tile.Settlements = append(tile.Settlements, &parser.Settlement_t{TurnId: s.TurnId, Name: s.Name})
+		}
+		for _, s := range mt.Special {
+			special := &parser.Special_t{TurnId: s.TurnId, Id: s.Id, Name: s.Name}
+			for _, text := range s.Directives {
+				keyword, value, _ := strings.Cut(strings.TrimPrefix(text, "!"), " ")
+				kind, ok := parser.StringToDirective[strings.ToLower(keyword)]
+				if !ok {
+					return nil, fmt.Errorf("%q: special %q: unknown directive %q", mt.Hex, s.Id, text)
+				}
+				special.Directives = append(special.Directives, &parser.Directive_t{Kind: kind, Value: strings.TrimSpace(value)})
+			}
+			tile.Special = append(tile.Special, special)
+		}
+		for _, e := range mt.Encounters {
+			tile.Encounters = append(tile.Encounters, &parser.Encounter_t{TurnId: e.TurnId, UnitId: parser.UnitId_t(e.UnitId), Friendly: e.Friendly})
+		}
+		for _, l := range mt.Lost {
+			tile.Lost = append(tile.Lost, &tiles.Lost_t{TurnId: l.TurnId, UnitId: parser.UnitId_t(l.UnitId), ScoutNo: l.ScoutNo})
+		}
+	}
+	return worldMap, nil
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package export_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"strings"
+	"testing"
+)
+
+func TestFormatOf(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		format string
+		path   string
+		want   export.Format_e
+		err    bool
+	}{
+		{1, "", "0138.json", export.JSON, false},
+		{2, "", "0138.jsonl", export.NDJSON, false},
+		{3, "", "0138.CSV", export.Delimited, false},
+		{4, "", "0138", export.JSON, false},
+		{5, "ndjson", "0138.csv", export.NDJSON, false},
+		{6, "xml", "0138.xml", export.JSON, true},
+	} {
+		got, err := export.FormatOf(tc.format, tc.path)
+		if tc.err != (err != nil) {
+			t.Errorf("%d: want error %v, got %v", tc.id, tc.err, err)
+		} else if got != tc.want {
+			t.Errorf("%d: want %s, got %s", tc.id, tc.want, got)
+		}
+	}
+}
+
+// mergedTestMap returns a visited, a scouted, an observed, and an inferred tile.
+func mergedTestMap() *tiles.Map_t {
+	worldMap := tiles.NewMap()
+	visited := worldMap.FetchTile("0138", coords.Map{Column: 10, Row: 10})
+	visited.Terrain, visited.Visited = terrain.Prairie, "0902-02"
+	visited.MarkSeen("0902-02", "0138")
+	visited.MarkSeen("0902-03", "0138e1")
+	visited.Source("0138e1")
+	visited.MergeEdge(direction.NorthEast, edges.River)
+	visited.Settlements = append(visited.Settlements, &parser.Settlement_t{TurnId: "0902-02", Name: "Alpha, Beta"})
+	visited.Encounters = append(visited.Encounters, &parser.Encounter_t{TurnId: "0902-03", UnitId: "1590e1"})
+	visited.Lost = append(visited.Lost, &tiles.Lost_t{TurnId: "0902-03", UnitId: "0138e1", ScoutNo: 1}, &tiles.Lost_t{TurnId: "0902-03", UnitId: "0138e1", ScoutNo: 1})
+	worldMap.FetchTile("0138", coords.Map{Column: 10, Row: 11}).Scouted = "0902-02"
+	worldMap.FetchTile("0138", coords.Map{Column: 11, Row: 10}).Terrain = terrain.Ocean
+	worldMap.FetchTile("0138", coords.Map{Column: 11, Row: 11}).Terrain = terrain.UnknownLand
+	return worldMap
+}
+
+func TestMerged(t *testing.T) {
+	list := export.Merged(mergedTestMap(), wxx.NotePolicy{})
+	var got []string
+	for _, mt := range list {
+		got = append(got, mt.Hex+" "+mt.Confidence)
+	}
+	want := []string{"AA 1111 visited", "AA 1112 scouted", "AA 1211 observed", "AA 1212 inferred"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("confidence: want %q, got %q", want, got)
+	}
+	if mt := list[0]; strings.Join(mt.Sources, " ") != "0138 0138e1" || len(mt.Edges["NE"]) != 1 || len(mt.Notes) != 1 {
+		t.Errorf("tile: want sources, edge, and one note, got %+v", mt)
+	}
+
+	for _, tc := range []struct {
+		id     int
+		format export.Format_e
+		lines  int
+	}{
+		{1, export.NDJSON, 4},
+		{2, export.Delimited, 5},
+	} {
+		b := &bytes.Buffer{}
+		if err := export.WriteMerged(b, list, tc.format); err != nil {
+			t.Errorf("%d: error: %v", tc.id, err)
+		} else if got := strings.Count(b.String(), "\n"); got != tc.lines {
+			t.Errorf("%d: want %d lines, got %d", tc.id, tc.lines, got)
+		}
+	}
+
+	b := &bytes.Buffer{}
+	if err := export.WriteMerged(b, list, export.JSON); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	} else if len(decoded) != 4 || decoded[0]["terrain"] != "PR" || decoded[0]["confidence"] != "visited" {
+		t.Errorf("json: want 4 tiles starting with a visited prairie, got %v", decoded)
+	}
+}
+
+// the tiles read back from a dump must dump the same way.
+func TestReadMerged(t *testing.T) {
+	list := export.Merged(mergedTestMap(), wxx.NotePolicy{})
+	list[0].Special = []*export.MergedSpecial_t{{Id: "bree", Name: "Bree", TurnId: "0902-02", Directives: []string{"!icon Settlement City", "!capital"}}}
+	want := &bytes.Buffer{}
+	if err := export.WriteMerged(want, list, export.NDJSON); err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []export.Format_e{export.JSON, export.NDJSON} {
+		b := &bytes.Buffer{}
+		if err := export.WriteMerged(b, list, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		read, err := export.ReadMerged(b)
+		if err != nil {
+			t.Fatalf("%s: read: %v", format, err)
+		}
+		worldMap, err := export.MergedMap(read)
+		if err != nil {
+			t.Fatalf("%s: map: %v", format, err)
+		}
+		got := &bytes.Buffer{}
+		if err := export.WriteMerged(got, export.Merged(worldMap, wxx.NotePolicy{}), export.NDJSON); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		} else if got.String() != want.String() {
+			t.Errorf("%s: want %s, got %s", format, want, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		id    int
+		input string
+	}{
+		{1, `{"hex":"N/A","terrain":"PR"}`},
+		{2, `{"hex":"AA 0101","terrain":"PR"}` + "\n" + `{"hex":"AA 0101","terrain":"PR"}`},
+		{3, `{"hex":"AA 0101","terrain":"PR","edges":{"UP":["River"]}}`},
+		{4, `{"hex":"AA 0101","terrain":"PR","special":[{"id":"x","name":"x","directives":["!fly"]}]}`},
+	} {
+		read, err := export.ReadMerged(strings.NewReader(tc.input))
+		if err == nil {
+			_, err = export.MergedMap(read)
+		}
+		if err == nil {
+			t.Errorf("%d: want error, got nil", tc.id)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package extract
+
+// cp1252 maps the bytes 0x80 to 0x9f of Windows-1252 to runes.
+// The other bytes are the same as ISO-8859-1. Unassigned bytes map to the replacement character.
+var cp1252 = [32]rune{
+	'€', '�', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '�', 'Ž', '�',
+	'�', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '�', 'ž', 'Ÿ',
+}
+
+// decodeCP1252 returns the rune for a Windows-1252 byte.
+func decodeCP1252(b byte) rune {
+	if 0x80 <= b && b <= 0x9f {
+		return cp1252[b-0x80]
+	}
+	return rune(b)
+}
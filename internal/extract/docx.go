@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// docxMain is the namespace for the elements of a Word document.
+const docxMain = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// DOCX returns the text of a Word document.
+// Paragraphs become lines; tabs, line breaks, and the case of the text are kept.
+// tndocx lower-cases the text and drops tabs, which the report parser needs.
+func DOCX(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("docx: %w", err)
+	}
+	var document *zip.File
+	for _, file := range zr.File {
+		if file.Name == "word/document.xml" {
+			document = file
+			break
+		}
+	}
+	if document == nil {
+		return nil, fmt.Errorf("docx: missing word/document.xml")
+	}
+	rc, err := document.Open()
+	if err != nil {
+		return nil, fmt.Errorf("docx: %w", err)
+	}
+	defer rc.Close()
+
+	var out bytes.Buffer
+	text, skip := 0, 0 // only the character data in text elements is part of the report
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("docx: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Space != docxMain {
+				continue
+			} else if skip > 0 || t.Name.Local == "pPr" || t.Name.Local == "rPr" {
+				// properties hold tab stops, which are not tabs in the text
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "t":
+				text++
+			case "tab":
+				out.WriteByte('\t')
+			case "br", "cr":
+				out.WriteByte('\n')
+			}
+		case xml.EndElement:
+			if t.Name.Space != docxMain {
+				continue
+			} else if skip > 0 {
+				skip--
+			} else if t.Name.Local == "t" {
+				text--
+			} else if t.Name.Local == "p" {
+				out.WriteByte('\n')
+			}
+		case xml.CharData:
+			if text > 0 && skip == 0 {
+				out.Write(t)
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// punctuation maps the typographic characters that word processors insert
+// to the ASCII characters that the report parser expects.
+var punctuation = map[rune]string{
+	'\u00a0': " ",   // no-break space
+	'\u2010': "-",   // hyphen
+	'\u2011': "-",   // non-breaking hyphen
+	'‒':      "-",   // figure dash
+	'–':      "-",   // en dash
+	'—':      "-",   // em dash
+	'‘':      "'",   // left single quote
+	'’':      "'",   // right single quote
+	'‚':      "'",   // single low quote
+	'‛':      "'",   // single high reversed quote
+	'“':      `"`,   // left double quote
+	'”':      `"`,   // right double quote
+	'„':      `"`,   // double low quote
+	'…':      "...", // ellipsis
+	'\u200b': "",    // zero width space
+	'\ufeff': "",    // zero width no-break space, a byte order mark in the middle of the text
+}
+
+// Normalize converts the text of a report to clean UTF-8.
+// It removes byte order marks, decodes UTF-16 and Windows-1252 text, and replaces
+// smart punctuation with ASCII. It returns the text and a description of each fix;
+// the list is empty when the text was already clean.
+func Normalize(data []byte) ([]byte, []string) {
+	var fixes []string
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		data = data[3:]
+		fixes = append(fixes, "removed UTF-8 byte order mark")
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		data = decodeUTF16(data[2:], false)
+		fixes = append(fixes, "decoded UTF-16LE")
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		data = decodeUTF16(data[2:], true)
+		fixes = append(fixes, "decoded UTF-16BE")
+	default:
+		if bigEndian, ok := looksLikeUTF16(data); ok {
+			data = decodeUTF16(data, bigEndian)
+			if bigEndian {
+				fixes = append(fixes, "decoded UTF-16BE without byte order mark")
+			} else {
+				fixes = append(fixes, "decoded UTF-16LE without byte order mark")
+			}
+		}
+	}
+
+	if !utf8.Valid(data) {
+		// decode only the bytes that aren't valid UTF-8 so that a report that mixes
+		// UTF-8 and Windows-1252 (from a copy and paste) keeps its UTF-8 characters.
+		var sb strings.Builder
+		invalid := 0
+		for len(data) != 0 {
+			r, size := utf8.DecodeRune(data)
+			if r == utf8.RuneError && size == 1 {
+				r = decodeCP1252(data[0])
+				invalid++
+			}
+			sb.WriteRune(r)
+			data = data[size:]
+		}
+		data = []byte(sb.String())
+		fixes = append(fixes, fmt.Sprintf("decoded Windows-1252 (%d characters)", invalid))
+	}
+
+	replaced := 0
+	if bytes.IndexFunc(data, func(r rune) bool { _, ok := punctuation[r]; return ok }) != -1 {
+		var sb strings.Builder
+		for _, r := range string(data) {
+			if ascii, ok := punctuation[r]; ok {
+				sb.WriteString(ascii)
+				replaced++
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+		data = []byte(sb.String())
+	}
+	if replaced != 0 {
+		fixes = append(fixes, fmt.Sprintf("replaced %d typographic characters", replaced))
+	}
+
+	return data, fixes
+}
+
+// looksLikeUTF16 returns true if the text has no byte order mark but most of the
+// bytes in the odd (little endian) or even (big endian) positions are zero.
+func looksLikeUTF16(data []byte) (bigEndian, ok bool) {
+	if len(data) < 4 || len(data)%2 != 0 {
+		return false, false
+	}
+	evenZeros, oddZeros := 0, 0
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0 {
+			evenZeros++
+		}
+		if data[i+1] == 0 {
+			oddZeros++
+		}
+	}
+	pairs := len(data) / 2
+	if oddZeros*10 >= pairs*9 && evenZeros == 0 {
+		return false, true
+	} else if evenZeros*10 >= pairs*9 && oddZeros == 0 {
+		return true, true
+	}
+	return false, false
+}
+
+// decodeUTF16 returns the UTF-8 encoding of UTF-16 text. A trailing odd byte is dropped.
+func decodeUTF16(data []byte, bigEndian bool) []byte {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		} else {
+			units = append(units, uint16(data[i+1])<<8|uint16(data[i]))
+		}
+	}
+	return []byte(string(utf16.Decode(units)))
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package extract converts word processor documents into the plain text
+// that the report parser expects: one line per paragraph, with tabs kept.
+// It reads Word, RTF, and ODT documents.
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// IsDocument returns true if the file name has an extension that this package converts.
+func IsDocument(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".docx", ".odt", ".rtf":
+		return true
+	}
+	return false
+}
+
+// ToText converts the document to plain text based on the extension of the file name.
+func ToText(name string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".docx":
+		return DOCX(data)
+	case ".odt":
+		return ODT(data)
+	case ".rtf":
+		return RTF(data)
+	}
+	return nil, fmt.Errorf("%s: unsupported document type", name)
+}
+
+// Report returns the text of a report file that the parser can read.
+// Documents are converted to text, the text is normalized, and line endings are
+// converted to newlines. It returns the fixes that Normalize made.
+func Report(name string, data []byte) ([]byte, []string, error) {
+	if IsDocument(name) {
+		var err error
+		if data, err = ToText(name, data); err != nil {
+			return nil, nil, err
+		}
+	}
+	data, fixes := Normalize(data)
+	data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+	data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
+	return data, fixes, nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package extract_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/playbymail/ottomap/internal/extract"
+	"testing"
+)
+
+func TestRTF(t *testing.T) {
+	for _, tc := range []struct {
+		id    int
+		input string
+		want  string
+	}{
+		{1, `{\rtf1\ansi{\fonttbl{\f0 Courier;}}\f0 Tribe 0138, , Current Hex = QQ 1008\par Current Turn 902-02 (#26)\tab Next Turn\par}`,
+			"Tribe 0138, , Current Hex = QQ 1008\nCurrent Turn 902-02 (#26)\tNext Turn\n"},
+		{2, `{\rtf1{\info{\title Turn}}{\*\generator Word;}Village \'c9cole\par}`,
+			"Village École\n"},
+		{3, `{\rtf1\uc1 Caf\u233?\rquote s \{x\}\line}`,
+			"Café's {x}\n"},
+	} {
+		got, err := extract.RTF([]byte(tc.input))
+		if err != nil {
+			t.Errorf("%d: error %v", tc.id, err)
+		} else if string(got) != tc.want {
+			t.Errorf("%d: got %q, want %q", tc.id, got, tc.want)
+		}
+	}
+	if _, err := extract.RTF([]byte("Tribe 0138")); err == nil {
+		t.Errorf("rtf: missing header: want error")
+	}
+}
+
+func TestODT(t *testing.T) {
+	content := `<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:automatic-styles><style:style/></office:automatic-styles>
+<office:body><office:text>
+<text:p>Tribe 0138, , Current Hex = QQ 1008</text:p>
+<text:p>Current Turn 902-02 (#26), Winter, FINE<text:tab/>Next Turn 902-03<office:annotation><text:p>ignore me</text:p></office:annotation></text:p>
+<text:p>Move N-PR,<text:s text:c="2"/>\N-GH</text:p>
+</office:text></office:body>
+</office:document-content>`
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if w, err := zw.Create("content.xml"); err != nil {
+		t.Fatal(err)
+	} else if _, err = w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	} else if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Tribe 0138, , Current Hex = QQ 1008\nCurrent Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03\nMove N-PR,  \\N-GH\n"
+	got, err := extract.ODT(buf.Bytes())
+	if err != nil {
+		t.Fatalf("odt: %v", err)
+	} else if string(got) != want {
+		t.Errorf("odt: got %q, want %q", got, want)
+	}
+}
+
+func TestDOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:rPr><w:b/></w:rPr><w:t>Tribe 0138, , Current Hex = QQ 1008</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Current Turn 902-02 (#26), Winter, FINE</w:t></w:r><w:r><w:tab/><w:t>Next Turn 902-03</w:t></w:r></w:p>
+<w:p><w:r><w:t>Move N-PR,</w:t><w:br/><w:t>\N-GH</w:t></w:r><w:r><w:instrText>PAGE</w:instrText></w:r></w:p>
+</w:body></w:document>`
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if w, err := zw.Create("word/document.xml"); err != nil {
+		t.Fatal(err)
+	} else if _, err = w.Write([]byte(document)); err != nil {
+		t.Fatal(err)
+	} else if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Tribe 0138, , Current Hex = QQ 1008\nCurrent Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03\nMove N-PR,\n\\N-GH\n"
+	got, err := extract.DOCX(buf.Bytes())
+	if err != nil {
+		t.Fatalf("docx: %v", err)
+	} else if string(got) != want {
+		t.Errorf("docx: got %q, want %q", got, want)
+	}
+	if _, err := extract.DOCX([]byte("Tribe 0138")); err == nil {
+		t.Errorf("docx: not a zip: want error")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	utf16le := []byte{0xff, 0xfe}
+	for _, r := range "Tribe 0138\r\n" {
+		utf16le = append(utf16le, byte(r), 0)
+	}
+	for _, tc := range []struct {
+		id    int
+		input []byte
+		want  string
+		fixes int
+	}{
+		{1, []byte("Tribe 0138\n"), "Tribe 0138\n", 0},
+		{2, []byte("\xef\xbb\xbfTribe 0138\n"), "Tribe 0138\n", 1},
+		{3, utf16le, "Tribe 0138\r\n", 1},
+		{4, []byte("Village \x93Alpha\x94 \x96 1590\n"), `Village "Alpha" - 1590` + "\n", 2},
+		{5, []byte("Village “Alpha” — École\n"), `Village "Alpha" - ` + "École\n", 1},
+		{6, []byte("\xc9cole \x93Alpha\x94\n"), `École "Alpha"` + "\n", 2},
+		{7, []byte("École \x93Alpha\x94\n"), `École "Alpha"` + "\n", 2},
+	} {
+		got, fixes := extract.Normalize(tc.input)
+		if string(got) != tc.want {
+			t.Errorf("%d: got %q, want %q", tc.id, got, tc.want)
+		}
+		if len(fixes) != tc.fixes {
+			t.Errorf("%d: fixes: got %d %q, want %d", tc.id, len(fixes), fixes, tc.fixes)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// odtText is the namespace for the text elements of an OpenDocument file.
+const odtText = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+
+// ODT returns the text of an OpenDocument Text file.
+// Paragraphs and headings become lines; tabs, line breaks, and runs of spaces are kept.
+func ODT(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("odt: %w", err)
+	}
+	var content *zip.File
+	for _, file := range zr.File {
+		if file.Name == "content.xml" {
+			content = file
+			break
+		}
+	}
+	if content == nil {
+		return nil, fmt.Errorf("odt: missing content.xml")
+	}
+	rc, err := content.Open()
+	if err != nil {
+		return nil, fmt.Errorf("odt: %w", err)
+	}
+	defer rc.Close()
+
+	var out bytes.Buffer
+	paragraphs, skip := 0, 0 // text outside of paragraphs is only formatting whitespace
+	decoder := xml.NewDecoder(rc)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("odt: %w", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skip > 0 || t.Name.Local == "annotation" || t.Name.Local == "note" {
+				// comments and footnotes are not part of the report
+				skip++
+			} else if t.Name.Space == odtText {
+				switch t.Name.Local {
+				case "p", "h":
+					paragraphs++
+				case "tab":
+					out.WriteByte('\t')
+				case "line-break":
+					out.WriteByte('\n')
+				case "s":
+					count := 1
+					for _, attr := range t.Attr {
+						if attr.Name.Local == "c" {
+							if n, err := strconv.Atoi(attr.Value); err == nil && n > 0 {
+								count = n
+							}
+						}
+					}
+					out.Write(bytes.Repeat([]byte{' '}, count))
+				}
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+			} else if t.Name.Space == odtText && (t.Name.Local == "p" || t.Name.Local == "h") {
+				paragraphs--
+				out.WriteByte('\n')
+			}
+		case xml.CharData:
+			if paragraphs > 0 && skip == 0 {
+				out.Write(t)
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+// rtfSkipped are destinations whose contents are not part of the document text.
+var rtfSkipped = map[string]bool{
+	"author": true, "buptim": true, "colortbl": true, "comment": true, "creatim": true,
+	"doccomm": true, "fonttbl": true, "footer": true, "footerf": true, "footerl": true,
+	"footerr": true, "footnote": true, "header": true, "headerf": true, "headerl": true,
+	"headerr": true, "info": true, "keywords": true, "listtable": true, "listoverridetable": true,
+	"operator": true, "pict": true, "printim": true, "revtim": true, "rsidtbl": true,
+	"stylesheet": true, "subject": true, "title": true, "xmlnstbl": true,
+}
+
+// rtfSymbols are control words that stand for characters.
+var rtfSymbols = map[string]string{
+	"bullet": "*", "cell": "\t", "emdash": "-", "emspace": " ", "endash": "-", "enspace": " ",
+	"ldblquote": `"`, "line": "\n", "lquote": "'", "par": "\n", "rdblquote": `"`, "row": "\n",
+	"rquote": "'", "sect": "\n", "tab": "\t",
+}
+
+// RTF returns the text of a Rich Text Format document.
+// Formatting is dropped; paragraphs become lines and tabs are kept.
+func RTF(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte(`{\rtf`)) {
+		return nil, fmt.Errorf("rtf: missing header")
+	}
+	type state_t struct {
+		skip     bool // true if the group is a destination we ignore
+		ucSkip   int  // number of fallback characters after a \u control word
+		sawGroup bool // true once the first control word in the group has been seen
+	}
+	var out bytes.Buffer
+	stack := []state_t{{ucSkip: 1}}
+	pendingSkip := 0 // fallback characters still to skip after \u
+	for pos := 0; pos < len(data); {
+		state := &stack[len(stack)-1]
+		ch := data[pos]
+		switch ch {
+		case '{':
+			stack = append(stack, state_t{skip: state.skip, ucSkip: state.ucSkip})
+			pos++
+			continue
+		case '}':
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("rtf: unbalanced braces at %d", pos)
+			}
+			stack = stack[:len(stack)-1]
+			pos++
+			continue
+		case '\r', '\n':
+			pos++
+			continue
+		}
+		if ch != '\\' {
+			if pendingSkip > 0 {
+				pendingSkip--
+			} else if !state.skip {
+				out.WriteByte(ch)
+			}
+			pos++
+			continue
+		}
+
+		// control symbol or control word
+		pos++
+		if pos >= len(data) {
+			break
+		}
+		ch = data[pos]
+		switch {
+		case ch == '\\' || ch == '{' || ch == '}':
+			if pendingSkip > 0 {
+				pendingSkip--
+			} else if !state.skip {
+				out.WriteByte(ch)
+			}
+			pos++
+		case ch == '*':
+			// optional destination; we don't know any, so ignore the group
+			state.skip = true
+			pos++
+		case ch == '~':
+			if !state.skip {
+				out.WriteByte(' ')
+			}
+			pos++
+		case ch == '_':
+			if !state.skip {
+				out.WriteByte('-')
+			}
+			pos++
+		case ch == '\'':
+			if pos+2 >= len(data) {
+				return nil, fmt.Errorf("rtf: truncated hex escape at %d", pos)
+			}
+			n, err := strconv.ParseUint(string(data[pos+1:pos+3]), 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("rtf: invalid hex escape at %d", pos)
+			}
+			pos += 3
+			if pendingSkip > 0 {
+				pendingSkip--
+			} else if !state.skip {
+				out.WriteRune(decodeCP1252(byte(n)))
+			}
+		case ch == '\r' || ch == '\n':
+			// an escaped newline is a paragraph break
+			if !state.skip {
+				out.WriteByte('\n')
+			}
+			pos++
+		case isLetter(ch):
+			start := pos
+			for pos < len(data) && isLetter(data[pos]) {
+				pos++
+			}
+			word := string(data[start:pos])
+			param, hasParam := 0, false
+			if pos < len(data) && (data[pos] == '-' || isDigit(data[pos])) {
+				numStart := pos
+				pos++
+				for pos < len(data) && isDigit(data[pos]) {
+					pos++
+				}
+				param, _ = strconv.Atoi(string(data[numStart:pos]))
+				hasParam = true
+			}
+			if pos < len(data) && data[pos] == ' ' {
+				// the delimiting space is part of the control word
+				pos++
+			}
+			first := !state.sawGroup
+			state.sawGroup = true
+			switch {
+			case first && rtfSkipped[word]:
+				state.skip = true
+			case word == "uc" && hasParam:
+				state.ucSkip = param
+			case word == "u" && hasParam:
+				if param < 0 {
+					param += 65536
+				}
+				if !state.skip {
+					r := rune(param)
+					if !utf8.ValidRune(r) {
+						r = utf8.RuneError
+					}
+					out.WriteRune(r)
+				}
+				pendingSkip = state.ucSkip
+			default:
+				if text, ok := rtfSymbols[word]; ok && !state.skip {
+					out.WriteString(text)
+				}
+			}
+		default:
+			// unknown control symbol
+			pos++
+		}
+	}
+	return out.Bytes(), nil
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isLetter(ch byte) bool {
+	return ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
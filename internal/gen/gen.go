@@ -0,0 +1,383 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package gen generates plausible tile maps, with continents, rivers, and
+// settlements, for demos and for testing the renderer without real reports.
+//
+// The same options always give the same map, so a seed can be shared to
+// reproduce a problem.
+package gen
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Options_t controls the generated map.
+type Options_t struct {
+	Seed        int64
+	Origin      coords.Map      // upper left hex of the map
+	Columns     int             // width of the map in hexes
+	Rows        int             // height of the map in hexes
+	Land        float64         // fraction of the hexes that are land, more than 0 and less than 1
+	Rivers      int             // rivers to start; a river that can't flow downhill is dropped
+	Settlements int             // settlements to place, fewer if there isn't room for them
+	TurnId      string          // turn the tiles are reported in
+	ClanId      parser.UnitId_t // unit that reports the tiles
+}
+
+// Validate returns an error if the map can't be generated with the options.
+func (o Options_t) Validate() error {
+	if o.Columns < 2 || o.Rows < 2 {
+		return fmt.Errorf("size: must be at least 2 by 2 hexes")
+	} else if !(0 < o.Land && o.Land < 1) {
+		return fmt.Errorf("land: must be more than 0 and less than 1")
+	} else if o.Rivers < 0 {
+		return fmt.Errorf("rivers: must not be negative")
+	} else if o.Settlements < 0 {
+		return fmt.Errorf("settlements: must not be negative")
+	} else if len(o.ClanId) != 4 {
+		return fmt.Errorf("clan: must be a 4 digit number")
+	}
+	return nil
+}
+
+// generator_t holds the state for one map.
+type generator_t struct {
+	opts      Options_t
+	rng       *rand.Rand
+	hexes     []coords.Map // sorted by column then row
+	inBounds  map[coords.Map]bool
+	elevation map[coords.Map]float64
+	moisture  map[coords.Map]float64
+	worldMap  *tiles.Map_t
+}
+
+// Generate returns a map with the tiles reported by the clan in the turn.
+func Generate(opts Options_t) (*tiles.Map_t, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	g := &generator_t{
+		opts:      opts,
+		rng:       rand.New(rand.NewSource(opts.Seed)),
+		inBounds:  map[coords.Map]bool{},
+		elevation: map[coords.Map]float64{},
+		moisture:  map[coords.Map]float64{},
+		worldMap:  tiles.NewMap(),
+	}
+	for column := 0; column < opts.Columns; column++ {
+		for row := 0; row < opts.Rows; row++ {
+			location := coords.Map{Column: opts.Origin.Column + column, Row: opts.Origin.Row + row}
+			g.hexes = append(g.hexes, location)
+			g.inBounds[location] = true
+		}
+	}
+	g.shape()
+	g.terrain()
+	g.rivers()
+	g.resources()
+	g.settlements()
+	return g.worldMap, nil
+}
+
+// shape sets the elevation and moisture of each hex. The elevation drops
+// towards the edges of the map, so the land forms continents in the middle.
+func (g *generator_t) shape() {
+	height := newField(g.rng, g.opts.Columns, g.opts.Rows, 12)
+	wet := newField(g.rng, g.opts.Columns, g.opts.Rows, 8)
+	for _, location := range g.hexes {
+		x, y := g.position(location)
+		dx, dy := 2*x/float64(g.opts.Columns)-1, 2*y/float64(g.opts.Rows)-1
+		g.elevation[location] = height.at(x, y) - 0.35*(dx*dx+dy*dy)
+		g.moisture[location] = wet.at(x, y)
+	}
+}
+
+// position returns the center of the hex relative to the origin.
+// Odd columns are shifted down by half a hex.
+func (g *generator_t) position(location coords.Map) (x, y float64) {
+	x, y = float64(location.Column-g.opts.Origin.Column), float64(location.Row-g.opts.Origin.Row)
+	if location.Column%2 != 0 {
+		y += 0.5
+	}
+	return x, y
+}
+
+// terrain creates the tiles. The lowest hexes are water, lakes if they can't
+// reach the edge of the map; the rest are flat, hills, or mountains by height,
+// and the moisture picks the kind.
+func (g *generator_t) terrain() {
+	ranked := append([]coords.Map{}, g.hexes...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return g.elevation[ranked[i]] < g.elevation[ranked[j]]
+	})
+	water := int(math.Round(float64(len(ranked)) * (1 - g.opts.Land)))
+	isWater := map[coords.Map]bool{}
+	for _, location := range ranked[:water] {
+		isWater[location] = true
+	}
+	ocean := g.flood(isWater)
+
+	for n, location := range ranked {
+		tile := g.worldMap.FetchTile(g.opts.ClanId, location)
+		tile.MarkSeen(g.opts.TurnId, g.opts.ClanId)
+		tile.Visited = g.opts.TurnId
+		if n < water {
+			if ocean[location] {
+				tile.Terrain = terrain.Ocean
+			} else {
+				tile.Terrain = terrain.Lake
+			}
+			continue
+		}
+		// rank is the height among the land hexes, from 0 to 1
+		rank := float64(n-water) / float64(len(ranked)-water)
+		tile.Terrain = landTerrain(rank, g.moisture[location])
+	}
+}
+
+// flood returns the water hexes that are connected to the edge of the map.
+func (g *generator_t) flood(isWater map[coords.Map]bool) map[coords.Map]bool {
+	reached := map[coords.Map]bool{}
+	var queue []coords.Map
+	for _, location := range g.hexes {
+		x, y := location.Column-g.opts.Origin.Column, location.Row-g.opts.Origin.Row
+		onEdge := x == 0 || y == 0 || x == g.opts.Columns-1 || y == g.opts.Rows-1
+		if onEdge && isWater[location] {
+			reached[location] = true
+			queue = append(queue, location)
+		}
+	}
+	for len(queue) != 0 {
+		location := queue[0]
+		queue = queue[1:]
+		for _, d := range direction.Directions {
+			neighbor := location.Add(d)
+			if isWater[neighbor] && !reached[neighbor] {
+				reached[neighbor] = true
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	return reached
+}
+
+// landTerrain returns the terrain for a land hex from its height among the
+// land hexes and its moisture, both from 0 to 1.
+func landTerrain(rank, moisture float64) terrain.Terrain_e {
+	pick := func(list ...terrain.Terrain_e) terrain.Terrain_e {
+		n := int(moisture * float64(len(list)))
+		return list[min(max(n, 0), len(list)-1)]
+	}
+	switch {
+	case rank > 0.97:
+		return pick(terrain.LowAridMountains, terrain.HighSnowyMountains, terrain.Alps)
+	case rank > 0.9:
+		return pick(terrain.LowAridMountains, terrain.LowConiferMountains, terrain.LowConiferMountains, terrain.LowSnowyMountains)
+	case rank > 0.7:
+		return pick(terrain.AridHills, terrain.RockyHills, terrain.GrassyHills, terrain.ConiferHills, terrain.DeciduousHills)
+	case rank > 0.6:
+		return pick(terrain.BrushHills, terrain.PrairiePlateau, terrain.GrassyHillsPlateau, terrain.DeciduousHills)
+	}
+	return pick(terrain.Desert, terrain.BrushFlat, terrain.Prairie, terrain.Prairie, terrain.Deciduous, terrain.Deciduous, terrain.Swamp)
+}
+
+// rivers starts rivers in the hills and mountains and lets them flow to the
+// lowest neighbor until they reach water, join another river, or can't go
+// any lower. A river runs along the side between two land hexes and is
+// recorded on both of them. Some river sides get a ford.
+func (g *generator_t) rivers() {
+	var sources []coords.Map
+	for _, location := range g.hexes {
+		switch g.worldMap.Tiles[location].Terrain.Height() {
+		case terrain.Hills, terrain.Plateau, terrain.Mountains:
+			sources = append(sources, location)
+		}
+	}
+	if len(sources) == 0 {
+		return
+	}
+	for n := 0; n < g.opts.Rivers; n++ {
+		from := sources[g.rng.Intn(len(sources))]
+		for steps := 0; steps < g.opts.Columns+g.opts.Rows; steps++ {
+			d, to, ok := g.downhill(from)
+			if !ok {
+				break
+			}
+			here, there := g.worldMap.Tiles[from], g.worldMap.Tiles[to]
+			if there.Terrain.IsWater() || here.HasEdge(d, edges.River) {
+				break
+			}
+			joined := g.onRiver(to)
+			here.MergeEdge(d, edges.River)
+			there.MergeEdge(d.Opposite(), edges.River)
+			if g.rng.Float64() < 0.1 {
+				here.MergeEdge(d, edges.Ford)
+				there.MergeEdge(d.Opposite(), edges.Ford)
+			}
+			if joined {
+				break
+			}
+			from = to
+		}
+	}
+}
+
+// downhill returns the direction to the lowest neighbor on the map,
+// if it isn't higher than the hex.
+func (g *generator_t) downhill(from coords.Map) (direction.Direction_e, coords.Map, bool) {
+	best, found := direction.Unknown, false
+	var to coords.Map
+	for _, d := range direction.Directions {
+		neighbor := from.Add(d)
+		if !g.inBounds[neighbor] || g.elevation[neighbor] > g.elevation[from] {
+			continue
+		} else if !found || g.elevation[neighbor] < g.elevation[to] {
+			best, to, found = d, neighbor, true
+		}
+	}
+	return best, to, found
+}
+
+// onRiver returns true if any side of the hex has a river.
+func (g *generator_t) onRiver(location coords.Map) bool {
+	for _, d := range direction.Directions {
+		if g.worldMap.Tiles[location].HasEdge(d, edges.River) {
+			return true
+		}
+	}
+	return false
+}
+
+// resources puts ore and stone in some of the hills and mountains.
+func (g *generator_t) resources() {
+	kinds := []resources.Resource_e{resources.Coal, resources.CopperOre, resources.IronOre, resources.Limestone, resources.Salt, resources.Silver, resources.Gold, resources.ZincOre}
+	for _, location := range g.hexes {
+		tile := g.worldMap.Tiles[location]
+		switch tile.Terrain.Height() {
+		case terrain.Hills, terrain.Mountains, terrain.HighMountains:
+			if g.rng.Float64() < 0.08 {
+				tile.MergeResource(kinds[g.rng.Intn(len(kinds))])
+			}
+		}
+	}
+}
+
+// settlements places settlements on dry, low land, at least three hexes
+// apart, preferring hexes beside rivers and water.
+func (g *generator_t) settlements() {
+	var good, fair []coords.Map
+	for _, location := range g.hexes {
+		tile := g.worldMap.Tiles[location]
+		switch tile.Terrain.Height() {
+		case terrain.Flat, terrain.Hills, terrain.Plateau:
+		default:
+			continue
+		}
+		if g.onRiver(location) || g.nearWater(location) {
+			good = append(good, location)
+		} else {
+			fair = append(fair, location)
+		}
+	}
+	g.rng.Shuffle(len(good), func(i, j int) { good[i], good[j] = good[j], good[i] })
+	g.rng.Shuffle(len(fair), func(i, j int) { fair[i], fair[j] = fair[j], fair[i] })
+
+	var placed []coords.Map
+	for _, location := range append(good, fair...) {
+		if len(placed) >= g.opts.Settlements {
+			break
+		}
+		crowded := false
+		for _, other := range placed {
+			if crowded = location.Distance(other) < 3; crowded {
+				break
+			}
+		}
+		if crowded {
+			continue
+		}
+		placed = append(placed, location)
+		g.worldMap.Tiles[location].Settlements = append(g.worldMap.Tiles[location].Settlements, &parser.Settlement_t{TurnId: g.opts.TurnId, Name: g.name()})
+	}
+}
+
+// nearWater returns true if a neighbor on the map is water.
+func (g *generator_t) nearWater(location coords.Map) bool {
+	for _, d := range direction.Directions {
+		if tile, ok := g.worldMap.Tiles[location.Add(d)]; ok && tile.Terrain.IsWater() {
+			return true
+		}
+	}
+	return false
+}
+
+// name returns a name for a settlement made from two or three syllables.
+func (g *generator_t) name() string {
+	syllables := []string{"al", "bar", "bree", "cor", "dun", "el", "fen", "gar", "hol", "ith", "kel", "lor", "mar", "nor", "os", "pel", "quen", "ros", "sil", "tor", "ul", "vel", "wyn", "yar"}
+	n := 2 + g.rng.Intn(2)
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		sb.WriteString(syllables[g.rng.Intn(len(syllables))])
+	}
+	name := sb.String()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// field_t is smooth noise with values from 0 to 1.
+// It adds three layers of interpolated random values, each one
+// half the size and half the weight of the one before it.
+type field_t struct {
+	layers []*lattice_t
+}
+
+type lattice_t struct {
+	scale  float64 // hexes between the random values
+	weight float64
+	values [][]float64
+}
+
+func newField(rng *rand.Rand, columns, rows int, scale float64) *field_t {
+	f := &field_t{}
+	for weight := 1.0; len(f.layers) < 3; scale, weight = scale/2, weight/2 {
+		l := &lattice_t{scale: scale, weight: weight}
+		for x := 0; x <= int(float64(columns)/scale)+2; x++ {
+			var column []float64
+			for y := 0; y <= int(float64(rows)/scale)+2; y++ {
+				column = append(column, rng.Float64())
+			}
+			l.values = append(l.values, column)
+		}
+		f.layers = append(f.layers, l)
+	}
+	return f
+}
+
+// at returns the value of the field at the position, in hexes.
+func (f *field_t) at(x, y float64) float64 {
+	var sum, weights float64
+	for _, l := range f.layers {
+		fx, fy := x/l.scale, y/l.scale
+		ix, iy := int(fx), int(fy)
+		tx, ty := smooth(fx-float64(ix)), smooth(fy-float64(iy))
+		top := l.values[ix][iy]*(1-tx) + l.values[ix+1][iy]*tx
+		bottom := l.values[ix][iy+1]*(1-tx) + l.values[ix+1][iy+1]*tx
+		sum += l.weight * (top*(1-ty) + bottom*ty)
+		weights += l.weight
+	}
+	return sum / weights
+}
+
+func smooth(t float64) float64 {
+	return t * t * (3 - 2*t)
+}
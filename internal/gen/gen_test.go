@@ -0,0 +1,149 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package gen_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/gen"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/turns"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func options(seed int64) gen.Options_t {
+	return gen.Options_t{
+		Seed:        seed,
+		Origin:      coords.Map{Column: 30, Row: 21},
+		Columns:     40,
+		Rows:        30,
+		Land:        0.5,
+		Rivers:      12,
+		Settlements: 10,
+		TurnId:      "0901-01",
+		ClanId:      "0138",
+	}
+}
+
+// dump returns the merged tiles of the map, so that maps can be compared.
+func dump(t *testing.T, worldMap *tiles.Map_t) string {
+	b := &bytes.Buffer{}
+	if err := export.WriteMerged(b, export.Merged(worldMap, wxx.NotePolicy{}), export.NDJSON); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestGenerate(t *testing.T) {
+	m1, err := gen.Generate(options(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m2, err := gen.Generate(options(1)); err != nil {
+		t.Fatal(err)
+	} else if dump(t, m1) != dump(t, m2) {
+		t.Errorf("seed 1: want the same map twice")
+	}
+	if m3, err := gen.Generate(options(2)); err != nil {
+		t.Fatal(err)
+	} else if dump(t, m1) == dump(t, m3) {
+		t.Errorf("seed 2: want a different map than seed 1")
+	}
+
+	var land, rivers, settlements int
+	for _, tile := range m1.Tiles {
+		if !tile.Terrain.IsWater() {
+			land++
+		}
+		settlements += len(tile.Settlements)
+		for _, d := range direction.Directions {
+			for _, e := range tile.Edges[d] {
+				if e != edges.River && e != edges.Ford {
+					continue
+				} else if e == edges.River {
+					rivers++
+				}
+				// rivers and fords run between two land hexes and are on both sides
+				neighbor, ok := m1.Tiles[tile.Location.Add(d)]
+				if !ok || tile.Terrain.IsWater() || neighbor.Terrain.IsWater() {
+					t.Errorf("%s: %s %s: want land on both sides", tile.Location.GridString(), d, e)
+				} else if !neighbor.HasEdge(d.Opposite(), e) {
+					t.Errorf("%s: %s %s: missing on %s", tile.Location.GridString(), d, e, neighbor.Location.GridString())
+				}
+				if e == edges.Ford && !tile.HasEdge(d, edges.River) {
+					t.Errorf("%s: %s: ford without a river", tile.Location.GridString(), d)
+				}
+			}
+		}
+	}
+	if m1.Length() != 40*30 {
+		t.Errorf("tiles: want %d, got %d", 40*30, m1.Length())
+	}
+	if land != 600 {
+		t.Errorf("land: want 600, got %d", land)
+	}
+	if rivers == 0 || settlements == 0 {
+		t.Errorf("want rivers and settlements, got %d river sides and %d settlements", rivers, settlements)
+	}
+
+	for _, tc := range []struct {
+		id   int
+		opts func(*gen.Options_t)
+	}{
+		{1, func(o *gen.Options_t) { o.Columns = 1 }},
+		{2, func(o *gen.Options_t) { o.Land = 1 }},
+		{3, func(o *gen.Options_t) { o.Rivers = -1 }},
+		{4, func(o *gen.Options_t) { o.ClanId = "138" }},
+	} {
+		opts := options(1)
+		tc.opts(&opts)
+		if _, err := gen.Generate(opts); err == nil {
+			t.Errorf("%d: want error, got nil", tc.id)
+		}
+	}
+}
+
+// walking the generated turn must give back the generated map.
+func TestTurn(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	opts := options(3)
+	want, err := gen.Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turn, err := gen.Turn(want, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := turns.Walk(context.Background(), []*parser.Turn_t{turn}, nil, coords.World{}, "", false, false, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for location, w := range want.Tiles {
+		g, ok := got.Tiles[location]
+		if !ok {
+			t.Errorf("%s: missing", location.GridString())
+			continue
+		} else if g.Terrain != w.Terrain {
+			t.Errorf("%s: terrain: want %s, got %s", location.GridString(), w.Terrain, g.Terrain)
+		}
+		for _, d := range direction.Directions {
+			if len(g.Edges[d]) != len(w.Edges[d]) {
+				t.Errorf("%s: %s: edges: want %v, got %v", location.GridString(), d, w.Edges[d], g.Edges[d])
+			}
+		}
+		if len(g.Settlements) != len(w.Settlements) || len(g.Resources) != len(w.Resources) {
+			t.Errorf("%s: want %d settlements and %d resources, got %d and %d", location.GridString(), len(w.Settlements), len(w.Resources), len(g.Settlements), len(g.Resources))
+		}
+	}
+}
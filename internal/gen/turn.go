@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package gen
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/tiles"
+)
+
+// Turn returns a turn in which the clan walks over every hex of the map,
+// down one column and up the next, and reports what it sees. Walking the
+// turn gives back the same tiles, so it can be written as a tniif document
+// and used in place of a report.
+func Turn(worldMap *tiles.Map_t, opts Options_t) (*parser.Turn_t, error) {
+	var year, month int
+	if _, err := fmt.Sscanf(opts.TurnId, "%04d-%02d", &year, &month); err != nil {
+		return nil, fmt.Errorf("turn %q: %w", opts.TurnId, err)
+	}
+
+	var path []coords.Map
+	for column := 0; column < opts.Columns; column++ {
+		for n := 0; n < opts.Rows; n++ {
+			row := n
+			if column%2 != 0 { // up the odd columns
+				row = opts.Rows - 1 - n
+			}
+			path = append(path, coords.Map{Column: opts.Origin.Column + column, Row: opts.Origin.Row + row})
+		}
+	}
+
+	moves := &parser.Moves_t{
+		TurnId:  opts.TurnId,
+		UnitId:  opts.ClanId,
+		FromHex: path[0].GridString(),
+		ToHex:   path[len(path)-1].GridString(),
+	}
+	for n, location := range path {
+		tile, ok := worldMap.Tiles[location]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing tile", location.GridString())
+		}
+		move := &parser.Move_t{UnitId: opts.ClanId, LineNo: 1, StepNo: n + 1, TurnId: opts.TurnId, Result: results.Succeeded, Report: report(worldMap, tile, opts)}
+		if n == 0 {
+			// the unit reports the hex it starts in before it moves
+			move.Still = true
+		} else if move.Advance, ok = toward(path[n-1], location); !ok {
+			return nil, fmt.Errorf("%s: not next to %s", location.GridString(), path[n-1].GridString())
+		}
+		moves.Moves = append(moves.Moves, move)
+	}
+
+	return &parser.Turn_t{
+		Id:           opts.TurnId,
+		Year:         year,
+		Month:        month,
+		UnitMoves:    map[parser.UnitId_t]*parser.Moves_t{opts.ClanId: moves},
+		SortedMoves:  []*parser.Moves_t{moves},
+		SpecialNames: map[string]*parser.Special_t{},
+	}, nil
+}
+
+// toward returns the direction from one hex to its neighbor.
+func toward(from, to coords.Map) (direction.Direction_e, bool) {
+	for _, d := range direction.Directions {
+		if from.Add(d) == to {
+			return d, true
+		}
+	}
+	return direction.Unknown, false
+}
+
+// report returns what a unit in the tile sees: the terrain, the edges,
+// the neighboring water, the resources, and the settlements.
+func report(worldMap *tiles.Map_t, tile *tiles.Tile_t, opts Options_t) *parser.Report_t {
+	r := &parser.Report_t{UnitId: opts.ClanId, TurnId: opts.TurnId, Terrain: tile.Terrain}
+	for _, d := range direction.Directions {
+		for _, e := range tile.Edges[d] {
+			r.Borders = append(r.Borders, &parser.Border_t{Direction: d, Edge: e})
+		}
+		if neighbor, ok := worldMap.Tiles[tile.Location.Add(d)]; ok && neighbor.Terrain.IsWater() {
+			r.Borders = append(r.Borders, &parser.Border_t{Direction: d, Terrain: neighbor.Terrain})
+		}
+	}
+	r.Resources = append(r.Resources, tile.Resources...)
+	for _, s := range tile.Settlements {
+		r.Settlements = append(r.Settlements, &parser.Settlement_t{TurnId: opts.TurnId, Name: s.Name})
+	}
+	return r
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package history reconstructs where each unit was on every turn that was loaded.
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Unit_t is the history of a single unit, one entry per turn that it was reported in.
+type Unit_t struct {
+	Id    parser.UnitId_t `json:"id"`
+	Turns []*Turn_t       `json:"turns"`
+}
+
+// Turn_t is where the unit started and ended a turn and how it got there.
+type Turn_t struct {
+	Turn   string `json:"turn"`
+	From   string `json:"from"` // grid coordinates, empty if the start isn't known
+	To     string `json:"to"`
+	Action string `json:"action"`
+	Result string `json:"result"`
+	Steps  int    `json:"steps"`        // successful advances
+	MP     *int   `json:"mp,omitempty"` // movement points spent, nil if a step can't be costed
+
+	// Path is the start of the turn and the end of each move that changed hexes.
+	// Hexes is the same path in grid coordinates.
+	Path  []coords.Map `json:"-"`
+	Hexes []string     `json:"path"`
+}
+
+// Build returns the history of every unit in the turns, sorted by unit id.
+// The turns must be sorted and walked so that the locations are set.
+// Movement points are only derived for land moves between tiles on the map.
+func Build(turns []*parser.Turn_t, worldMap *tiles.Map_t) []*Unit_t {
+	units := map[parser.UnitId_t]*Unit_t{}
+	for _, turn := range turns {
+		for _, moves := range turn.SortedMoves {
+			u, ok := units[moves.UnitId]
+			if !ok {
+				u = &Unit_t{Id: moves.UnitId}
+				units[moves.UnitId] = u
+			}
+			u.Turns = append(u.Turns, newTurn(turn.Id, moves, worldMap))
+		}
+	}
+
+	var list []*Unit_t
+	for _, u := range units {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Id < list[j].Id
+	})
+	return list
+}
+
+func newTurn(turnId string, moves *parser.Moves_t, worldMap *tiles.Map_t) *Turn_t {
+	t := &Turn_t{
+		Turn:   turnId,
+		Action: Action(moves),
+		Result: LastResult(moves),
+	}
+	if !moves.StartLocation.IsZero() {
+		t.From = moves.StartLocation.GridString()
+		t.Path = append(t.Path, moves.StartLocation)
+	}
+	if !moves.Location.IsZero() {
+		t.To = moves.Location.GridString()
+	}
+
+	mp, costed := 0, !moves.StartLocation.IsZero() && moves.Follows == "" && moves.GoesTo == ""
+	from := moves.StartLocation
+	for _, move := range moves.Moves {
+		if move.Location.IsZero() || move.Location == from {
+			continue
+		}
+		if move.Advance != direction.Unknown && move.Result == results.Succeeded {
+			t.Steps++
+			if cost, ok := pathfinding.StepCost(worldMap.Tiles[from], worldMap.Tiles[move.Location], move.Advance); ok {
+				mp += cost
+			} else {
+				costed = false
+			}
+		}
+		t.Path = append(t.Path, move.Location)
+		from = move.Location
+	}
+	if !moves.Location.IsZero() && (len(t.Path) == 0 || t.Path[len(t.Path)-1] != moves.Location) {
+		// follows and goes to jumps don't have a location on each move
+		t.Path = append(t.Path, moves.Location)
+	}
+	if costed {
+		t.MP = &mp
+	}
+	for _, location := range t.Path {
+		t.Hexes = append(t.Hexes, location.GridString())
+	}
+	return t
+}
+
+// Action returns a short description of what the unit did on the turn.
+func Action(moves *parser.Moves_t) string {
+	if moves.Follows != "" {
+		return fmt.Sprintf("followed %s", moves.Follows)
+	} else if moves.GoesTo != "" {
+		return fmt.Sprintf("went to %s", moves.GoesTo)
+	}
+	moved, failed := false, false
+	for _, move := range moves.Moves {
+		if move.Advance == direction.Unknown {
+			continue
+		} else if move.Result == results.Succeeded {
+			moved = true
+		} else if move.Result == results.Failed {
+			failed = true
+		}
+	}
+	if moved {
+		return "moved"
+	} else if failed {
+		return "failed to move"
+	}
+	return "stayed"
+}
+
+// LastResult returns the result of the unit's last move, ignoring the status line.
+func LastResult(moves *parser.Moves_t) string {
+	for n := len(moves.Moves) - 1; n >= 0; n-- {
+		move := moves.Moves[n]
+		if move.Result == results.StatusLine {
+			continue
+		} else if move.Result == results.Unknown && move.Follows != "" {
+			return results.Followed.String()
+		} else if move.Result == results.Unknown && move.GoesTo != "" {
+			return results.Teleported.String()
+		} else if move.Result == results.Failed && move.Reason != results.Unknown {
+			return fmt.Sprintf("%s (%s)", move.Result, move.Reason)
+		}
+		return move.Result.String()
+	}
+	return ""
+}
+
+// WriteJSON writes the histories as indented JSON.
+func WriteJSON(w io.Writer, units []*Unit_t) error {
+	if units == nil {
+		units = []*Unit_t{}
+	}
+	buf, err := json.MarshalIndent(units, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, '\n'))
+	return err
+}
+
+// WriteCSV writes one row per unit per turn using the separator.
+// The path is the hexes the unit passed through, separated by commas.
+func WriteCSV(w io.Writer, units []*Unit_t, sep rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	if err := cw.Write([]string{"unit", "turn", "from", "to", "action", "result", "steps", "mp", "path"}); err != nil {
+		return err
+	}
+	for _, u := range units {
+		for _, t := range u.Turns {
+			var mp string
+			if t.MP != nil {
+				mp = strconv.Itoa(*t.MP)
+			}
+			if err := cw.Write([]string{string(u.Id), t.Turn, t.From, t.To, t.Action, t.Result, strconv.Itoa(t.Steps), mp, strings.Join(t.Hexes, ", ")}); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package history_test
+
+import (
+	"bytes"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/history"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	start := coords.Map{Column: 10, Row: 10}
+	north := start.Add(direction.North)
+	worldMap := tiles.NewMap()
+	worldMap.FetchTile("", start).Terrain = terrain.Prairie
+	worldMap.FetchTile("", north).Terrain = terrain.Swamp
+
+	turns := []*parser.Turn_t{
+		{Id: "0902-02", SortedMoves: []*parser.Moves_t{
+			{UnitId: "0138", StartLocation: start, Location: north, Moves: []*parser.Move_t{
+				{Advance: direction.North, Result: results.Succeeded, Location: north},
+				{Advance: direction.North, Result: results.Failed, Reason: results.Blocked, Location: north},
+			}},
+		}},
+		{Id: "0902-03", SortedMoves: []*parser.Moves_t{
+			{UnitId: "0138", StartLocation: north, Location: start, GoesTo: start.GridString()},
+		}},
+	}
+
+	b := &bytes.Buffer{}
+	if err := history.WriteCSV(b, history.Build(turns, worldMap), ','); err != nil {
+		t.Fatalf("error: %v", err)
+	}
+	want := "unit,turn,from,to,action,result,steps,mp,path\n" +
+		"0138,0902-02,AA 1111,AA 1110,moved,Failed (Blocked),1,8,\"AA 1111, AA 1110\"\n" +
+		"0138,0902-03,AA 1110,AA 1111,went to AA 1111,,0,,\"AA 1110, AA 1111\"\n"
+	if got := b.String(); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+// the roster uses Action and LastResult to describe each unit's last turn
+func TestActionLastResult(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		moves  *parser.Moves_t
+		action string
+		result string
+	}{
+		{1, &parser.Moves_t{Moves: []*parser.Move_t{
+			{Advance: direction.North, Result: results.Succeeded},
+			{Advance: direction.North, Result: results.Failed, Reason: results.Blocked},
+			{Still: true, Result: results.StatusLine},
+		}}, "moved", results.Failed.String() + " (" + results.Blocked.String() + ")"},
+		{2, &parser.Moves_t{Moves: []*parser.Move_t{
+			{Advance: direction.North, Result: results.Failed},
+		}}, "failed to move", results.Failed.String()},
+		{3, &parser.Moves_t{Moves: []*parser.Move_t{
+			{Still: true, Result: results.StayedInPlace},
+			{Still: true, Result: results.StatusLine},
+		}}, "stayed", results.StayedInPlace.String()},
+		{4, &parser.Moves_t{Follows: "0138", Moves: []*parser.Move_t{{Follows: "0138"}}}, "followed 0138", results.Followed.String()},
+		{5, &parser.Moves_t{GoesTo: "QQ 1410", Moves: []*parser.Move_t{{GoesTo: "QQ 1410"}}}, "went to QQ 1410", results.Teleported.String()},
+		{6, &parser.Moves_t{}, "stayed", ""},
+	} {
+		if got := history.Action(tc.moves); got != tc.action {
+			t.Errorf("%d: action: want %q, got %q", tc.id, tc.action, got)
+		}
+		if got := history.LastResult(tc.moves); got != tc.result {
+			t.Errorf("%d: result: want %q, got %q", tc.id, tc.result, got)
+		}
+	}
+}
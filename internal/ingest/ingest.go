@@ -0,0 +1,159 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package ingest extracts turn report attachments from zip archives and
+// email exports (.eml files).
+package ingest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path"
+	"strings"
+)
+
+// MaxFileSize is the largest attachment that will be extracted.
+// Turn reports are small, so anything larger is probably not a report.
+const MaxFileSize = 16 << 20
+
+// Attachment_t is a report file extracted from an archive or email.
+type Attachment_t struct {
+	Source string // archive or email the file came from
+	Name   string // original name of the file
+	Data   []byte
+}
+
+// IsReport returns true if the name has a docx, odt, rtf, or txt extension.
+func IsReport(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".docx", ".odt", ".rtf", ".txt":
+		return true
+	}
+	return false
+}
+
+// FromZip returns the report files in the archive.
+// Email files in the archive are searched for attachments.
+func FromZip(source string, data []byte) ([]*Attachment_t, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	var list []*Attachment_t
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Base(file.Name)
+		if strings.HasPrefix(name, ".") || strings.HasPrefix(file.Name, "__MACOSX/") {
+			// skip resource forks and hidden files added by some archivers
+			continue
+		}
+		isEmail := strings.EqualFold(path.Ext(name), ".eml")
+		if !isEmail && !IsReport(name) {
+			continue
+		} else if file.UncompressedSize64 > MaxFileSize {
+			return nil, fmt.Errorf("%s: %s: file is too large", source, file.Name)
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", source, file.Name, err)
+		}
+		contents, err := io.ReadAll(io.LimitReader(rc, MaxFileSize))
+		_ = rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", source, file.Name, err)
+		}
+		if isEmail {
+			attachments, err := FromEmail(source+"/"+file.Name, contents)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, attachments...)
+			continue
+		}
+		list = append(list, &Attachment_t{Source: source, Name: name, Data: contents})
+	}
+	return list, nil
+}
+
+// FromEmail returns the report attachments in an email message.
+// Zip attachments are searched for reports.
+func FromEmail(source string, data []byte) ([]*Attachment_t, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	var list []*Attachment_t
+	err = walkPart(source, mail.Header(msg.Header), msg.Body, func(name string, contents []byte) error {
+		if strings.EqualFold(path.Ext(name), ".zip") {
+			attachments, err := FromZip(source+"/"+name, contents)
+			if err != nil {
+				return err
+			}
+			list = append(list, attachments...)
+		} else if IsReport(name) {
+			list = append(list, &Attachment_t{Source: source, Name: name, Data: contents})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// walkPart calls fn for every part of the message that has a file name.
+func walkPart(source string, header mail.Header, body io.Reader, fn func(name string, contents []byte) error) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		// a missing or broken content type is treated as plain text
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("%s: %w", source, err)
+			}
+			if err := walkPart(source, mail.Header(part.Header), part, fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	name := params["name"]
+	if _, dispositionParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && dispositionParams["filename"] != "" {
+		name = dispositionParams["filename"]
+	}
+	if name == "" {
+		return nil
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	contents, err := io.ReadAll(io.LimitReader(body, MaxFileSize+1))
+	if err != nil {
+		return fmt.Errorf("%s: %s: %w", source, name, err)
+	} else if len(contents) > MaxFileSize {
+		return fmt.Errorf("%s: %s: file is too large", source, name)
+	}
+	return fn(name, contents)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package ingest_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"github.com/playbymail/ottomap/internal/ingest"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+const report = "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n"
+
+func TestFromEmail(t *testing.T) {
+	eml := "From: gm@example.com\r\n" +
+		"Subject: Turn 902-02\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: multipart/mixed; boundary=\"xyz\"\r\n" +
+		"\r\n" +
+		"--xyz\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"Your reports are attached.\r\n" +
+		"--xyz\r\n" +
+		"Content-Type: text/plain; name=\"0138.txt\"\r\n" +
+		"Content-Disposition: attachment; filename=\"902-02 0138.txt\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		base64.StdEncoding.EncodeToString([]byte(report)) + "\r\n" +
+		"--xyz\r\n" +
+		"Content-Type: application/pdf; name=\"rules.pdf\"\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"\r\n" +
+		"JVBERi0=\r\n" +
+		"--xyz--\r\n"
+
+	// wrap the email in a zip with a text report to exercise both readers
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct{ name, data string }{
+		{"mail/turn.eml", eml},
+		{"0902-03.0138.report.txt", report},
+		{"__MACOSX/._0902-03.0138.report.txt", "junk"},
+		{"notes.md", "junk"},
+	} {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err = w.Write([]byte(file.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := ingest.FromZip("reports.zip", buf.Bytes())
+	if err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("zip: got %d attachments, want 2", len(list))
+	}
+	for i, want := range []string{"902-02 0138.txt", "0902-03.0138.report.txt"} {
+		if list[i].Name != want {
+			t.Errorf("zip: %d: name: got %q, want %q", i, list[i].Name, want)
+		}
+		if string(list[i].Data) != report {
+			t.Errorf("zip: %d: data: got %q, want %q", i, list[i].Data, report)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	for _, tc := range []struct {
+		id   int
+		name string
+		data string
+		want string
+		text string
+	}{
+		{1, "902-02 0138.txt", report, "0902-02.0138.report.txt", report},
+		{2, "902-02 1138e1.txt", "Element 1138e1, , Current Hex = QQ 1008\r\n", "0902-02.0138.report.txt", "Element 1138e1, , Current Hex = QQ 1008\n"},
+		{3, "turn.rtf", `{\rtf1 Tribe 0590, , Current Hex = QQ 1008\par Current Turn 902-02 (#26)\par}`, "0902-02.0590.report.txt", "Tribe 0590, , Current Hex = QQ 1008\nCurrent Turn 902-02 (#26)\n"},
+		{4, "0902-02.0138.report.txt", "\xef\xbb\xbfTribe 0138\r", "0902-02.0138.report.txt", "Tribe 0138\n"},
+	} {
+		a := &ingest.Attachment_t{Source: "test", Name: tc.name, Data: []byte(tc.data)}
+		name, text, _, err := a.Report()
+		if err != nil {
+			t.Errorf("%d: error %v", tc.id, err)
+			continue
+		}
+		if name != tc.want {
+			t.Errorf("%d: name: got %q, want %q", tc.id, name, tc.want)
+		} else if !turns.IsReportFileName(name) {
+			t.Errorf("%d: name: %q is not read by render", tc.id, name)
+		}
+		if string(text) != tc.text {
+			t.Errorf("%d: text: got %q, want %q", tc.id, text, tc.text)
+		}
+	}
+
+	a := &ingest.Attachment_t{Source: "test", Name: "notes.txt", Data: []byte("no report here\n")}
+	if _, _, _, err := a.Report(); err == nil {
+		t.Errorf("notes: want error")
+	}
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package ingest
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/stdlib"
+	"strings"
+)
+
+// Report returns the text of the attachment and the name of the file that render
+// reads it from, YYYY-MM.0NNN.report.txt. Documents are converted to text, and
+// reports for elements are named for the clan that the element belongs to.
+// It returns the fixes made while normalizing the text.
+func (a *Attachment_t) Report() (name string, text []byte, fixes []string, err error) {
+	text, fixes, err = extract.Report(a.Name, a.Data)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("%s: %w", a.Name, err)
+	}
+	// the name is YYYY-MM.UNIT.report.EXT
+	name, err = stdlib.ReportName(a.Name, text)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	fields := strings.Split(name, ".")
+	clan := parser.UnitId_t(fields[1]).Clan()
+	return fmt.Sprintf("%s.%s.report.txt", fields[0], clan), text, fixes, nil
+}
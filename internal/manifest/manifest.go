@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package manifest records what went into a run and what came out of it,
+// so that players can tell which reports and settings produced a map.
+package manifest
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/pflag"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Manifest_t is written as JSON next to the output of a run.
+type Manifest_t struct {
+	Command   string            `json:"command"` // for example "render"
+	Version   string            `json:"version"` // ottomap version
+	GoVersion string            `json:"goVersion"`
+	Started   time.Time         `json:"started"`
+	Elapsed   string            `json:"elapsed"`
+	Flags     map[string]string `json:"flags"` // flags that were set on the command line or from the environment
+	Inputs    []File_t          `json:"inputs"`
+	Outputs   []File_t          `json:"outputs"`
+	Warnings  int               `json:"warnings"` // number of "warn:" lines logged during the run
+
+	counter *Counter_t
+}
+
+// File_t is an input or output file.
+type File_t struct {
+	Id   string `json:"id,omitempty"` // report id, for inputs
+	Path string `json:"path"`
+	Size int    `json:"size"`
+	SHA1 string `json:"sha1"`
+}
+
+// New starts a manifest for the command. The flags that were changed are
+// recorded, except for secrets. The counter is optional.
+func New(command, version string, flags *pflag.FlagSet, counter *Counter_t) *Manifest_t {
+	m := &Manifest_t{
+		Command:   command,
+		Version:   version,
+		GoVersion: runtime.Version(),
+		Started:   time.Now().UTC(),
+		Flags:     map[string]string{},
+		counter:   counter,
+	}
+	if flags != nil {
+		flags.Visit(func(flag *pflag.Flag) {
+			if strings.Contains(flag.Name, "secret") {
+				m.Flags[flag.Name] = "(redacted)"
+				return
+			}
+			m.Flags[flag.Name] = flag.Value.String()
+		})
+	}
+	if counter != nil {
+		counter.Reset()
+	}
+	return m
+}
+
+// AddInput records an input file. The hash is of the data as it was read, before any clean up.
+func (m *Manifest_t) AddInput(id, path string, data []byte) {
+	m.Inputs = append(m.Inputs, File_t{Id: id, Path: path, Size: len(data), SHA1: hash(data)})
+}
+
+// AddInputFile records an input file, reading it from the path.
+func (m *Manifest_t) AddInputFile(id, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m.AddInput(id, path, data)
+	return nil
+}
+
+// AddOutput records a file that the run created.
+func (m *Manifest_t) AddOutput(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	m.Outputs = append(m.Outputs, File_t{Path: path, Size: len(data), SHA1: hash(data)})
+	return nil
+}
+
+// Write sets the elapsed time and the warning count, then saves the manifest.
+func (m *Manifest_t) Write(path string) error {
+	m.Elapsed = time.Since(m.Started).String()
+	if m.counter != nil {
+		m.Warnings = m.counter.Count()
+	}
+	sort.Slice(m.Inputs, func(i, j int) bool {
+		return m.Inputs[i].Path < m.Inputs[j].Path
+	})
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// Path returns the manifest path for an output file, for example "0138.manifest.json" for "0138.wxx".
+func Path(output string) string {
+	for _, ext := range []string{".wxx", ".json", ".txt"} {
+		if strings.HasSuffix(output, ext) {
+			return strings.TrimSuffix(output, ext) + ".manifest.json"
+		}
+	}
+	return output + ".manifest.json"
+}
+
+func hash(data []byte) string {
+	return fmt.Sprintf("%x", sha1.Sum(data))
+}
+
+// Counter_t counts the warnings written to the log.
+// It is meant to wrap the log output with log.SetOutput.
+type Counter_t struct {
+	sync.Mutex
+	w     io.Writer
+	count int
+}
+
+// NewCounter returns a counter that passes writes through to w.
+func NewCounter(w io.Writer) *Counter_t {
+	return &Counter_t{w: w}
+}
+
+// Write counts the lines that contain a "warn: " message and writes them to the underlying writer.
+func (c *Counter_t) Write(p []byte) (int, error) {
+	c.Lock()
+	for _, line := range bytes.Split(p, []byte{'\n'}) {
+		// the log flags may put a time and a file name before the message
+		if bytes.HasPrefix(line, []byte("warn: ")) || bytes.Contains(line, []byte(" warn: ")) {
+			c.count++
+		}
+	}
+	w := c.w
+	c.Unlock()
+	return w.Write(p)
+}
+
+// SetOutput changes the underlying writer.
+func (c *Counter_t) SetOutput(w io.Writer) {
+	c.Lock()
+	defer c.Unlock()
+	c.w = w
+}
+
+// Count returns the number of warnings since the last reset.
+func (c *Counter_t) Count() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.count
+}
+
+// Reset sets the count to zero.
+func (c *Counter_t) Reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.count = 0
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package manifest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/manifest"
+	"github.com/spf13/pflag"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManifest(t *testing.T) {
+	var out bytes.Buffer
+	counter := manifest.NewCounter(&out)
+	logger := log.New(counter, "", log.Ltime|log.Lshortfile)
+
+	var clanId, secret string
+	flags := pflag.NewFlagSet("render", pflag.ContinueOnError)
+	flags.StringVar(&clanId, "clan-id", "", "")
+	flags.StringVar(&secret, "secret", "", "")
+	flags.String("data", "data", "")
+	if err := flags.Parse([]string{"--clan-id", "0138", "--secret", "hunter2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Printf("warn: before the run\n")
+	m := manifest.New("render", "0.30.0", flags, counter)
+	logger.Printf("warn: will shift map up and left\n")
+	logger.Printf("walk: 0902-02: not a warning\n")
+	logger.Printf("warn: %q: empty file\n", "0902-03.0138")
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "0138.wxx")
+	if err := os.WriteFile(output, []byte("map"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.AddInput("0902-02.0138", "0902-02.0138.report.txt", []byte("report"))
+	if err := m.AddOutput(output); err != nil {
+		t.Fatalf("output: want nil, got %v", err)
+	}
+	path := manifest.Path(output)
+	if want := filepath.Join(dir, "0138.manifest.json"); path != want {
+		t.Errorf("path: want %q, got %q", want, path)
+	}
+	if err := m.Write(path); err != nil {
+		t.Fatalf("write: want nil, got %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got manifest.Manifest_t
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	if got.Warnings != 2 {
+		t.Errorf("warnings: want 2, got %d", got.Warnings)
+	}
+	if len(got.Flags) != 2 || got.Flags["clan-id"] != "0138" || got.Flags["secret"] != "(redacted)" {
+		t.Errorf("flags: want clan-id and redacted secret, got %v", got.Flags)
+	}
+	// sha1 of "report" and "map"
+	if len(got.Inputs) != 1 || got.Inputs[0].SHA1 != "a27297bde9732f2e73fbc06db2611764e3ad9855" {
+		t.Errorf("inputs: got %+v", got.Inputs)
+	}
+	if len(got.Outputs) != 1 || got.Outputs[0].Size != 3 || got.Outputs[0].SHA1 != "37745ed7a0f005fb14522c5cc7c1ba3d9e0df579" {
+		t.Errorf("outputs: got %+v", got.Outputs)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("warn: will shift map up and left")) {
+		t.Errorf("log: want messages passed through, got %q", out.String())
+	}
+}
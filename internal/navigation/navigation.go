@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package navigation models the effect of the wind on fleet movement.
+//
+// The rules are an approximation. We don't have many fleet reports to test
+// against, so the validation only warns and never rejects a report.
+package navigation
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/winds"
+)
+
+// Rules_t are the wind rules used to validate fleet movement.
+type Rules_t struct {
+	// MaxHexes is the most hexes a fleet can advance in a turn for each wind strength.
+	MaxHexes map[winds.Strength_e]int
+	// IntoTheWind is true if fleets may advance directly into the wind for each wind strength.
+	IntoTheWind map[winds.Strength_e]bool
+}
+
+// DefaultRules returns the rules we use when none are given.
+// Fleets can row in a calm, but otherwise can't sail directly into the wind.
+func DefaultRules() Rules_t {
+	return Rules_t{
+		MaxHexes: map[winds.Strength_e]int{
+			winds.Calm:   2,
+			winds.Mild:   4,
+			winds.Strong: 6,
+			winds.Gale:   8,
+		},
+		IntoTheWind: map[winds.Strength_e]bool{
+			winds.Calm: true,
+		},
+	}
+}
+
+// Warning_t is a fleet movement that doesn't agree with the wind rules.
+type Warning_t struct {
+	TurnId  string
+	UnitId  parser.UnitId_t
+	Message string
+}
+
+func (w Warning_t) String() string {
+	return fmt.Sprintf("%s: %-6s: %s", w.TurnId, w.UnitId, w.Message)
+}
+
+// Validate checks the movement of every fleet in the turns.
+func (r Rules_t) Validate(turns []*parser.Turn_t) []Warning_t {
+	var warnings []Warning_t
+	for _, turn := range turns {
+		for _, moves := range turn.SortedMoves {
+			if moves.UnitId.IsFleet() {
+				warnings = append(warnings, r.ValidateFleet(moves)...)
+			}
+		}
+	}
+	return warnings
+}
+
+// ValidateFleet checks the fleet's movement against the wind reported for the turn.
+// Fleets that didn't report a wind are not checked.
+func (r Rules_t) ValidateFleet(moves *parser.Moves_t) []Warning_t {
+	var wind parser.Wind_t
+	var advances []direction.Direction_e
+	for _, move := range moves.Moves {
+		if move.Wind.Strength != winds.Unknown {
+			wind = move.Wind
+		}
+		if move.Advance != direction.Unknown && move.Result == results.Succeeded {
+			advances = append(advances, move.Advance)
+		}
+	}
+	if wind.Strength == winds.Unknown {
+		return nil
+	}
+
+	var warnings []Warning_t
+	warn := func(format string, args ...any) {
+		warnings = append(warnings, Warning_t{TurnId: moves.TurnId, UnitId: moves.UnitId, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if limit, ok := r.MaxHexes[wind.Strength]; ok && len(advances) > limit {
+		warn("moved %d hexes: %s winds allow %d", len(advances), wind.Strength, limit)
+	}
+	if !r.IntoTheWind[wind.Strength] {
+		for _, d := range advances {
+			if d == wind.From {
+				warn("advanced %s into %s winds from the %s", d, wind.Strength, wind.From)
+				break
+			}
+		}
+	}
+
+	// the drift is the hex we end up in by following the advances from the starting hex
+	from, err := coords.HexToMap(moves.FromHex)
+	if err != nil {
+		return warnings // obscured or missing starting hex
+	}
+	to, err := coords.HexToMap(moves.ToHex)
+	if err != nil {
+		return warnings
+	}
+	if drift := from.Move(advances...); drift != to {
+		warn("reported end hex %s: computed drift ends in %s", to.GridString(), drift.GridString())
+	}
+
+	return warnings
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package navigation_test
+
+import (
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/navigation"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/winds"
+	"testing"
+)
+
+func TestValidateFleet(t *testing.T) {
+	fleet := func(strength winds.Strength_e, from direction.Direction_e, toHex string, ds ...direction.Direction_e) *parser.Moves_t {
+		moves := &parser.Moves_t{TurnId: "0902-02", UnitId: "0138f1", FromHex: "QQ 1010", ToHex: toHex}
+		for _, d := range ds {
+			moves.Moves = append(moves.Moves, &parser.Move_t{
+				Advance: d,
+				Result:  results.Succeeded,
+				Wind:    parser.Wind_t{Strength: strength, From: from},
+			})
+		}
+		return moves
+	}
+
+	rules := navigation.DefaultRules()
+	tests := []struct {
+		id    int
+		moves *parser.Moves_t
+		want  int
+	}{
+		{1, fleet(winds.Mild, direction.North, "QQ 1012", direction.South, direction.South), 0},
+		{2, fleet(winds.Calm, direction.North, "QQ 1007", direction.North, direction.North, direction.North), 1},
+		{3, fleet(winds.Mild, direction.North, "QQ 1009", direction.North), 1},
+		{4, fleet(winds.Mild, direction.North, "QQ 1013", direction.South, direction.South), 1},
+		{5, fleet(winds.Calm, direction.North, "QQ 1009", direction.North), 0},
+	}
+	for _, tt := range tests {
+		got := rules.ValidateFleet(tt.moves)
+		if len(got) != tt.want {
+			t.Errorf("%d: warnings: want %d, got %d: %v", tt.id, tt.want, len(got), got)
+		}
+	}
+}
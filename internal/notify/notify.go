@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package notify posts a render summary to a Discord or Slack webhook.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate is used when the configuration doesn't provide a template.
+const DefaultTemplate = `**{{.ClanId}}** map updated for turn {{.TurnId}}: {{.TilesAdded}} new tiles
+{{- if .NewSettlements}}, new settlements: {{join .NewSettlements ", "}}{{end}}
+{{- if .MapURL}}
+{{.MapURL}}{{else}}
+{{.MapFile}}{{end}}`
+
+// Summary_t is the data available to the message template.
+type Summary_t struct {
+	ClanId         string
+	TurnId         string
+	TilesAdded     int      // tiles first seen in this turn
+	NewSettlements []string // settlements first seen in this turn
+	MapFile        string   // base name of the map file
+	MapURL         string   // link to the published map, may be empty
+}
+
+// Message executes the template against the summary.
+// If the template is empty, the default template is used.
+func Message(tmpl string, s Summary_t) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultTemplate
+	}
+	t, err := Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, s); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// Parse compiles the message template.
+func Parse(tmpl string) (*template.Template, error) {
+	return template.New("notify").Funcs(template.FuncMap{"join": strings.Join}).Parse(tmpl)
+}
+
+// Post sends the message to the webhook.
+// Slack webhooks expect the message in "text"; everything else is treated as Discord, which expects "content".
+func Post(ctx context.Context, webhook, message string) error {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return err
+	} else if u.Scheme != "https" && u.Scheme != "http" {
+		return fmt.Errorf("webhook: %q: unsupported scheme", u.Scheme)
+	}
+	payload := map[string]string{"content": message}
+	if strings.HasSuffix(u.Hostname(), "slack.com") {
+		payload = map[string]string{"text": message}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook: %s: %s", resp.Status, strings.TrimSpace(string(text)))
+	}
+	return nil
+}
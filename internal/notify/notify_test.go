@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/notify"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMessage(t *testing.T) {
+	for _, tc := range []struct {
+		id      int
+		tmpl    string
+		summary notify.Summary_t
+		want    string
+	}{
+		{1, "",
+			notify.Summary_t{ClanId: "0138", TurnId: "0902-03", TilesAdded: 4, MapFile: "0138.wxx"},
+			"**0138** map updated for turn 0902-03: 4 new tiles\n0138.wxx"},
+		{2, "",
+			notify.Summary_t{ClanId: "0138", TurnId: "0902-03", TilesAdded: 4, NewSettlements: []string{"Alpha", "Bravo"}, MapFile: "0138.wxx", MapURL: "https://example.com/0138.wxx"},
+			"**0138** map updated for turn 0902-03: 4 new tiles, new settlements: Alpha, Bravo\nhttps://example.com/0138.wxx"},
+		{3, "{{.TurnId}} +{{.TilesAdded}}",
+			notify.Summary_t{TurnId: "0902-03", TilesAdded: 2},
+			"0902-03 +2"},
+	} {
+		got, err := notify.Message(tc.tmpl, tc.summary)
+		if err != nil {
+			t.Errorf("%d: error: %v", tc.id, err)
+		} else if got != tc.want {
+			t.Errorf("%d: got %q, want %q", tc.id, got, tc.want)
+		}
+	}
+}
+
+func TestPost(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := notify.Post(context.Background(), srv.URL, "hello"); err != nil {
+		t.Fatalf("post: %v", err)
+	} else if got["content"] != "hello" {
+		t.Errorf("post: content: got %q, want %q", got["content"], "hello")
+	}
+}
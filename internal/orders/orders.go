@@ -0,0 +1,241 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package orders reads the movement orders a player submitted for a turn
+// and checks them against the results in the turn report.
+//
+// An orders file has one order per line. Blank lines and lines starting
+// with "#" are ignored. Keywords and directions are not case sensitive.
+//
+//	0138 move NE N N       advance in each direction, in order
+//	0138 scout 1 N N NE    send scout 1 out in each direction
+//	0138e1 follow 0138     follow another unit
+//	0138e2 goes to QQ 1410 jump to the hex
+//
+// Directions may also be separated with dashes, as in "NE-N-N".
+package orders
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Kind_e is the kind of order.
+type Kind_e int
+
+const (
+	Move Kind_e = iota
+	Scout
+	Follow
+	GoesTo
+)
+
+// Order_t is a single movement order.
+type Order_t struct {
+	Line   int // line number in the orders file
+	UnitId parser.UnitId_t
+	Kind   Kind_e
+	Scout  int                     // scout number, only for Scout orders
+	Steps  []direction.Direction_e // only for Move and Scout orders
+	Target string                  // unit to follow or hex to go to
+}
+
+var (
+	rxUnitId = regexp.MustCompile(`^\d{4}([cefg]\d)?$`)
+	rxHex    = regexp.MustCompile(`^([A-Z]{2}|##) \d{4}$`)
+)
+
+// Parse returns the orders in the file. It stops at the first line that isn't a valid order.
+func Parse(data []byte) ([]*Order_t, error) {
+	var list []*Order_t
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(strings.ReplaceAll(line, "-", " "))
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%d: missing order", lineNo)
+		} else if !rxUnitId.MatchString(fields[0]) {
+			return nil, fmt.Errorf("%d: %q: invalid unit id", lineNo, fields[0])
+		}
+		o := &Order_t{Line: lineNo, UnitId: parser.UnitId_t(fields[0])}
+		switch keyword, args := strings.ToLower(fields[1]), fields[2:]; keyword {
+		case "move":
+			o.Kind = Move
+			steps, err := parseSteps(args)
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", lineNo, err)
+			}
+			o.Steps = steps
+		case "scout":
+			o.Kind = Scout
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%d: missing scout number", lineNo)
+			}
+			no, err := strconv.Atoi(args[0])
+			if err != nil || no < 1 || no > 8 {
+				return nil, fmt.Errorf("%d: %q: invalid scout number", lineNo, args[0])
+			}
+			steps, err := parseSteps(args[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%d: %w", lineNo, err)
+			}
+			o.Scout, o.Steps = no, steps
+		case "follow", "follows":
+			o.Kind = Follow
+			if len(args) != 1 || !rxUnitId.MatchString(args[0]) {
+				return nil, fmt.Errorf("%d: follow: expected a unit id", lineNo)
+			}
+			o.Target = args[0]
+		case "goes", "goto":
+			o.Kind = GoesTo
+			if len(args) != 0 && strings.ToLower(args[0]) == "to" {
+				args = args[1:]
+			}
+			hex := strings.ToUpper(strings.Join(args, " "))
+			if !rxHex.MatchString(hex) {
+				return nil, fmt.Errorf("%d: goes to: %q: invalid hex", lineNo, hex)
+			}
+			o.Target = hex
+		default:
+			return nil, fmt.Errorf("%d: %q: unknown order", lineNo, fields[1])
+		}
+		list = append(list, o)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func parseSteps(args []string) ([]direction.Direction_e, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("missing directions")
+	}
+	var steps []direction.Direction_e
+	for _, arg := range args {
+		d, ok := direction.StringToEnum[strings.ToUpper(arg)]
+		if !ok || d == direction.Unknown {
+			return nil, fmt.Errorf("%q: invalid direction", arg)
+		}
+		steps = append(steps, d)
+	}
+	return steps, nil
+}
+
+// Discrepancy_t is a difference between an order and the turn report.
+type Discrepancy_t struct {
+	Line    int // line number of the order
+	UnitId  parser.UnitId_t
+	Problem string
+}
+
+// Reconcile checks each order against the results in the turn report.
+// The discrepancies are returned in the order of the lines in the orders file.
+func Reconcile(orders []*Order_t, turn *parser.Turn_t) []Discrepancy_t {
+	var list []Discrepancy_t
+	for _, o := range orders {
+		flag := func(format string, args ...any) {
+			list = append(list, Discrepancy_t{Line: o.Line, UnitId: o.UnitId, Problem: fmt.Sprintf(format, args...)})
+		}
+		moves, ok := turn.UnitMoves[o.UnitId]
+		if !ok {
+			flag("unit is not in the turn report")
+			continue
+		}
+		switch o.Kind {
+		case Move:
+			if moves.Follows != "" {
+				flag("ordered to move, but followed %s", moves.Follows)
+			} else if moves.GoesTo != "" {
+				flag("ordered to move, but went to %s", moves.GoesTo)
+			} else {
+				for _, problem := range compareSteps(o.Steps, moves.Moves) {
+					flag("%s", problem)
+				}
+			}
+		case Scout:
+			var scout *parser.Scout_t
+			for _, s := range moves.Scouts {
+				if s.No == o.Scout {
+					scout = s
+				}
+			}
+			if scout == nil {
+				flag("scout %d: order not executed", o.Scout)
+				continue
+			}
+			for _, problem := range compareSteps(o.Steps, scout.Moves) {
+				flag("scout %d: %s", o.Scout, problem)
+			}
+		case Follow:
+			if string(moves.Follows) != o.Target {
+				flag("ordered to follow %s, but %s", o.Target, reported(moves))
+			}
+		case GoesTo:
+			if moves.GoesTo != o.Target && !(strings.HasPrefix(o.Target, "##") && strings.HasSuffix(moves.GoesTo, o.Target[2:])) {
+				flag("ordered to go to %s, but %s", o.Target, reported(moves))
+			}
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Line < list[j].Line
+	})
+	return list
+}
+
+// compareSteps compares the ordered directions with the advances in the report.
+// Checking stops at the first step that failed since the unit doesn't move after that.
+func compareSteps(ordered []direction.Direction_e, moves []*parser.Move_t) (problems []string) {
+	var advances []*parser.Move_t
+	for _, move := range moves {
+		if move.Advance != direction.Unknown {
+			advances = append(advances, move)
+		}
+	}
+	for n, d := range ordered {
+		if n >= len(advances) {
+			problems = append(problems, fmt.Sprintf("order not executed: steps %d to %d were not reported", n+1, len(ordered)))
+			return problems
+		}
+		move := advances[n]
+		if move.Advance != d {
+			problems = append(problems, fmt.Sprintf("step %d: unexpected direction: ordered %s, reported %s", n+1, d, move.Advance))
+			return problems
+		}
+		if move.Result == results.Failed {
+			switch move.Reason {
+			case results.ExhaustedMovementPoints:
+				problems = append(problems, fmt.Sprintf("step %d of %d: exhausted movement points early", n+1, len(ordered)))
+			case results.Unknown:
+				problems = append(problems, fmt.Sprintf("step %d of %d: failed", n+1, len(ordered)))
+			default:
+				problems = append(problems, fmt.Sprintf("step %d of %d: failed (%s)", n+1, len(ordered), move.Reason))
+			}
+			return problems
+		}
+	}
+	if len(advances) > len(ordered) {
+		problems = append(problems, fmt.Sprintf("reported %d steps, but only %d were ordered", len(advances), len(ordered)))
+	}
+	return problems
+}
+
+// reported describes what the report says the unit did instead.
+func reported(moves *parser.Moves_t) string {
+	if moves.Follows != "" {
+		return fmt.Sprintf("followed %s", moves.Follows)
+	} else if moves.GoesTo != "" {
+		return fmt.Sprintf("went to %s", moves.GoesTo)
+	}
+	return "the report has no follows or goes to"
+}
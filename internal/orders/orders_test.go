@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package orders_test
+
+import (
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/orders"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		id    int
+		input string
+		err   bool
+	}{
+		{1, "# orders\n\n0138 move NE-N n\n0138 scout 1 N N\n0138e1 follow 0138\n0138e2 goes to qq 1410\n", false},
+		{2, "0138 move\n", true},
+		{3, "0138 move NN\n", true},
+		{4, "0138 scout 9 N\n", true},
+		{5, "0138 sail N\n", true},
+		{6, "138 move N\n", true},
+		{7, "0138e2 goes to QQ10\n", true},
+	} {
+		_, err := orders.Parse([]byte(tt.input))
+		if tt.err != (err != nil) {
+			t.Errorf("%d: want error %v, got %v", tt.id, tt.err, err)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	list, err := orders.Parse([]byte("0138 move NE N N\n0138 scout 1 N N\n0138e1 follow 0138\n0138e2 goes to QQ 1410\n0138e3 move S\n0138c1 move SW SW\n"))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	turn := &parser.Turn_t{UnitMoves: map[parser.UnitId_t]*parser.Moves_t{
+		"0138": {Moves: []*parser.Move_t{
+			{Advance: direction.NorthEast, Result: results.Succeeded},
+			{Advance: direction.North, Result: results.Failed, Reason: results.ExhaustedMovementPoints},
+		}, Scouts: []*parser.Scout_t{
+			{No: 1, Moves: []*parser.Move_t{{Advance: direction.North, Result: results.Succeeded}, {Advance: direction.NorthWest, Result: results.Succeeded}}},
+		}},
+		"0138e1": {Follows: "0138"},
+		"0138e2": {GoesTo: "QQ 1411"},
+		"0138c1": {Moves: []*parser.Move_t{{Advance: direction.SouthWest, Result: results.Succeeded}, {Advance: direction.SouthWest, Result: results.Succeeded}}},
+	}}
+	want := []orders.Discrepancy_t{
+		{Line: 1, UnitId: "0138", Problem: "step 2 of 3: exhausted movement points early"},
+		{Line: 2, UnitId: "0138", Problem: "scout 1: step 2: unexpected direction: ordered N, reported NW"},
+		{Line: 4, UnitId: "0138e2", Problem: "ordered to go to QQ 1410, but went to QQ 1411"},
+		{Line: 5, UnitId: "0138e3", Problem: "unit is not in the turn report"},
+	}
+	if got := orders.Reconcile(list, turn); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v\ngot  %+v", want, got)
+	}
+}
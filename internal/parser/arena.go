@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+// arenaBlockSize is the number of values allocated at a time.
+// Large enough to cut the allocation count, small enough that a short
+// report doesn't waste much memory.
+const arenaBlockSize = 128
+
+// arena_t hands out the small structs created for every step of a movement
+// line from slices that are allocated in blocks. A large report costs a few
+// hundred allocations for these structs instead of hundreds of thousands.
+//
+// The values escape into the Turn_t, so they can't be recycled with a
+// sync.Pool. A block is freed by the garbage collector once nothing points
+// into it. A nil arena is valid and allocates each value individually.
+//
+// An arena is not safe for concurrent use; ParseInput creates one per report.
+type arena_t struct {
+	moves   []Move_t
+	reports []Report_t
+	borders []Border_t
+}
+
+// newMove returns a pointer to a copy of the move.
+func (a *arena_t) newMove(m Move_t) *Move_t {
+	if a == nil {
+		return &m
+	}
+	if len(a.moves) == cap(a.moves) {
+		a.moves = make([]Move_t, 0, arenaBlockSize)
+	}
+	a.moves = append(a.moves, m)
+	return &a.moves[len(a.moves)-1]
+}
+
+// newReport returns a pointer to an empty report for the unit.
+func (a *arena_t) newReport(tid string, unitId UnitId_t) *Report_t {
+	if a == nil {
+		return &Report_t{TurnId: tid, UnitId: unitId}
+	}
+	if len(a.reports) == cap(a.reports) {
+		a.reports = make([]Report_t, 0, arenaBlockSize)
+	}
+	a.reports = append(a.reports, Report_t{TurnId: tid, UnitId: unitId})
+	return &a.reports[len(a.reports)-1]
+}
+
+// newBorder returns a pointer to a copy of the border.
+func (a *arena_t) newBorder(b Border_t) *Border_t {
+	if a == nil {
+		return &b
+	}
+	if len(a.borders) == cap(a.borders) {
+		a.borders = make([]Border_t, 0, arenaBlockSize)
+	}
+	a.borders = append(a.borders, b)
+	return &a.borders[len(a.borders)-1]
+}
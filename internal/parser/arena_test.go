@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"reflect"
+	"testing"
+)
+
+// TestArena checks that the moves that ParseInput takes from its arena match the moves
+// from the exported line parser, which allocates each one, and that no two moves or
+// reports share memory. The report has thousands of steps, so the arena fills many blocks.
+func TestArena(t *testing.T) {
+	report := testkit.LargeReport("0138")
+	turn, err := parser.ParseInput(context.Background(), "test", "0902-02", report, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	moves, reports := map[*parser.Move_t]bool{}, map[*parser.Report_t]bool{}
+	for unitId, unitMoves := range turn.UnitMoves {
+		var all []*parser.Move_t
+		all = append(all, unitMoves.Moves...)
+		for _, scout := range unitMoves.Scouts {
+			all = append(all, scout.Moves...)
+		}
+		for _, move := range all {
+			if moves[move] {
+				t.Errorf("%s: %d: %d: move is shared", unitId, move.LineNo, move.StepNo)
+			} else if move.Report != nil && reports[move.Report] {
+				t.Errorf("%s: %d: %d: report is shared", unitId, move.LineNo, move.StepNo)
+			}
+			moves[move], reports[move.Report] = true, true
+		}
+	}
+	if len(moves) < 1000 {
+		t.Errorf("moves: want at least 1000, got %d", len(moves))
+	}
+
+	var unitId parser.UnitId_t
+	checked := 0
+	for n, line := range bytes.Split(report, []byte("\n")) {
+		lineNo := n + 1
+		if bytes.HasPrefix(line, []byte("Tribe 0138")) || bytes.HasPrefix(line, []byte("Element ")) || bytes.HasPrefix(line, []byte("Courier ")) || bytes.HasPrefix(line, []byte("Garrison ")) {
+			unitId = parser.UnitId_t(bytes.TrimSuffix(bytes.Fields(line)[1], []byte(",")))
+			continue
+		} else if !bytes.HasPrefix(line, []byte("Tribe Movement: ")) {
+			continue
+		}
+		want, err := parser.ParseTribeMovementLine("test", "0902-02", unitId, lineNo, line, false, false, false, false)
+		if err != nil {
+			t.Fatalf("%s: %d: parse: %v", unitId, lineNo, err)
+		}
+		var got []*parser.Move_t
+		for _, move := range turn.UnitMoves[unitId].Moves {
+			if move.LineNo == lineNo {
+				got = append(got, move)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: %d: moves: want %d, got %d", unitId, lineNo, len(want), len(got))
+			continue
+		}
+		for i := range want {
+			g, w := *got[i], *want[i]
+			g.TurnId = w.TurnId
+			if !reflect.DeepEqual(g, w) {
+				t.Errorf("%s: %d: %d: want %+v, got %+v", unitId, lineNo, w.StepNo, w, g)
+			}
+		}
+		checked++
+	}
+	if checked == 0 {
+		t.Errorf("tribe movement lines: want some, got none")
+	}
+}
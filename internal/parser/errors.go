@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+import (
+	"fmt"
+)
+
+// InternalError_t is returned when the grammar returns something the parser
+// doesn't know how to handle. These are bugs in the parser, not in the report,
+// so they carry enough context for the user to report them.
+type InternalError_t struct {
+	File string   // id of the report file
+	Line int      // line number in the report, indexed from 1
+	Unit UnitId_t // unit whose section contains the line
+	Step int      // step number within the line, or 0 if not known
+	Text string   // the start of the line
+	Want string   // the type that the parser expected
+	Got  string   // the type that the grammar returned
+}
+
+func (e *InternalError_t) Error() string {
+	if e.Step != 0 {
+		return fmt.Sprintf("%s: %s: %d: step %d: internal error: want %s, got %s: %s", e.File, e.Unit, e.Line, e.Step, e.Want, e.Got, e.Text)
+	}
+	return fmt.Sprintf("%s: %s: %d: internal error: want %s, got %s: %s", e.File, e.Unit, e.Line, e.Want, e.Got, e.Text)
+}
+
+// unexpectedType returns an internal error for a grammar result of the wrong type.
+func unexpectedType(fid string, unitId UnitId_t, lineNo, stepNo int, line []byte, want string, got any) *InternalError_t {
+	return &InternalError_t{
+		File: fid,
+		Line: lineNo,
+		Unit: unitId,
+		Step: stepNo,
+		Text: errslug(line, 58),
+		Want: want,
+		Got:  fmt.Sprintf("%T", got),
+	}
+}
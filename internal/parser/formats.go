@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Format_t describes the behavior of the reports from one era of the game.
+// The parser selects the format from the turn id unless the caller forces one.
+type Format_t struct {
+	Name      string // name used on the command line
+	FirstTurn string // first turn, in yyyy-mm format, that uses this format
+	// ObscuredCurrentHex is true if the report may hide the grid of the
+	// unit's current hex (for example "## 1108").
+	ObscuredCurrentHex bool
+}
+
+// Formats is the list of known report formats, sorted by first turn.
+var Formats = []*Format_t{
+	{Name: "obscured", FirstTurn: "0899-12", ObscuredCurrentHex: true},
+	{Name: "current", FirstTurn: "0902-02"},
+}
+
+func (f *Format_t) String() string {
+	return f.Name
+}
+
+// FormatForTurn returns the format used by reports for the turn.
+// Turns before the first known format use the oldest format.
+func FormatForTurn(turnId string) *Format_t {
+	format := Formats[0]
+	for _, f := range Formats[1:] {
+		if turnId < f.FirstTurn {
+			break
+		}
+		format = f
+	}
+	return format
+}
+
+// LookupFormat returns the format with the given name.
+func LookupFormat(name string) (*Format_t, error) {
+	var names []string
+	for _, f := range Formats {
+		if f.Name == name {
+			return f, nil
+		}
+		names = append(names, f.Name)
+	}
+	return nil, fmt.Errorf("unknown report format %q: want one of %s", name, strings.Join(names, ", "))
+}
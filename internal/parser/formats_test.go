@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/parser"
+	"testing"
+)
+
+func TestFormatForTurn(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		turnId string
+		want   string
+	}{
+		{1, "0800-01", "obscured"},
+		{2, "0899-12", "obscured"},
+		{3, "0902-01", "obscured"},
+		{4, "0902-02", "current"},
+		{5, "0910-06", "current"},
+	} {
+		if got := parser.FormatForTurn(tc.turnId); got.Name != tc.want {
+			t.Errorf("%d: %s: want %q, got %q", tc.id, tc.turnId, tc.want, got.Name)
+		}
+	}
+}
+
+func TestLookupFormat(t *testing.T) {
+	for _, tc := range []struct {
+		id   int
+		name string
+		err  bool
+	}{
+		{1, "obscured", false},
+		{2, "current", false},
+		{3, "auto", true},
+		{4, "", true},
+	} {
+		got, err := parser.LookupFormat(tc.name)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d: %q: want error, got %q", tc.id, tc.name, got.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %q: error %v", tc.id, tc.name, err)
+		} else if got.Name != tc.name {
+			t.Errorf("%d: %q: got %q", tc.id, tc.name, got.Name)
+		}
+	}
+}
+
+func TestReportFormat(t *testing.T) {
+	obscured, err := parser.LookupFormat("obscured")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		id          int
+		year, month int
+		format      *parser.Format_t
+		want        string
+		err         bool
+	}{
+		{1, 902, 1, nil, "obscured", false},
+		{2, 902, 2, nil, "", true},
+		{3, 902, 2, obscured, "obscured", false},
+	} {
+		turnId := fmt.Sprintf("%04d-%02d", tc.year, tc.month)
+		// the turn id is read from the first section and only courier sections are checked
+		currentTurn := fmt.Sprintf("Current Turn %d-%02d (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n", tc.year, tc.month)
+		report := "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n" + currentTurn +
+			"Tribe Movement: Move N-PR\\\n" +
+			"Courier 0138c1, , Current Hex = ## 1008, (Previous Hex = ## 1009)\n" + currentTurn +
+			"Tribe Movement: Move N-PR\\\n"
+		turn, err := parser.ParseInput(context.Background(), "test", turnId, []byte(report), false, false, false, false, false, false, false, false, parser.ParseConfig{Format: tc.format})
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d: %s: want error, got nil", tc.id, turnId)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %s: error %v", tc.id, turnId, err)
+		} else if got := turn.ReportFormat().Name; got != tc.want {
+			t.Errorf("%d: %s: format: want %q, got %q", tc.id, turnId, tc.want, got)
+		} else if !turn.ToMayBeObscured() {
+			t.Errorf("%d: %s: to may be obscured: want true, got false", tc.id, turnId)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/parser"
+	"testing"
+)
+
+// The fuzz targets feed malformed report lines to the parser and fail if it panics
+// instead of returning an error. The regression inputs that they have found are in
+// testdata/fuzz. Run a target with, for example,
+//
+//	go test ./internal/parser -run='^$' -fuzz=FuzzTribeMovementLine -fuzztime=30s
+
+// seeds are lines from real reports, including the quirks that we have had to handle.
+var seeds = []string{
+	`Tribe Movement: Move N-PR,  \N-GH, River S\`,
+	`Tribe Movement: Move NE-PR, Find Iron Ore\NE-GH, Ford N, River S\`,
+	`Tribe Movement: Move \`,
+	`Tribe Movement: Move N-PR, 1 Tribe Movement: Move S-PR`,
+	`Tribe Movement: Move Not enough M.P's to move to N into GRASSY HILLS`,
+	`Tribe Movement: Move Can't Move on Ocean to NE of HEX`,
+	`Scout 1:Scout NE-PR, Find Iron Ore, 1590\NE-SW, Village Alpha\`,
+	`Scout 2:Scout Still, 0138e1\`,
+	`Scout 8:Scout N-LCM, Nothing of interest found\Can't Move on Lake to N of HEX,  Patrolled and found 0987`,
+	`CALM NE Fleet Movement: Move NE-O,-(NE O,  SE O, N O)\NE-O,-(N O)\`,
+	`0138 Status: GRASSY HILLS, Village Bravo, O NW, 0138, 0138e1`,
+	`0138e1 Status: PRAIRIE, River S,,Ford N 0138, 0138e1`,
+	`0138c1 Status: SWAMP, Lcm N, NE, 0138c1; People 12, Horses 3`,
+	`Tribe Follows 0138`,
+	`Tribe Goes to QQ 1410`,
+	`Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)`,
+	`Current Turn 902-02 (#26), Winter, FINE	Next Turn 902-03 (#27), 28/10/2023`,
+	`Courier 0138c1, , Current Hex = ## 1008, (Previous Hex = N/A)`,
+	``,
+	`\\\\,,,--`,
+}
+
+func addSeeds(f *testing.F) {
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+}
+
+func FuzzTribeMovementLine(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, line []byte) {
+		_, _ = parser.ParseTribeMovementLine("fuzz", "0902-02", "0138", 1, line, false, false, false, false)
+		_, _ = parser.ParseTribeMovementLine("fuzz", "0902-02", "0138", 1, line, true, false, false, true)
+	})
+}
+
+func FuzzScoutMovementLine(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, line []byte) {
+		_, _ = parser.ParseScoutMovementLine("fuzz", "0902-02", "0138", 1, line, false, false, false, false, false)
+		_, _ = parser.ParseScoutMovementLine("fuzz", "0902-02", "0138", 1, line, true, false, false, true, true)
+	})
+}
+
+func FuzzFleetMovementLine(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, line []byte) {
+		_, _ = parser.ParseFleetMovementLine("fuzz", "0902-02", "0138f1", 1, line, false, false, false, false, false)
+	})
+}
+
+func FuzzStatusLine(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, line []byte) {
+		_, _ = parser.ParseStatusLine("fuzz", "0902-02", "0138", 1, line, false, false, false, false)
+		_, _ = parser.ParseStatusLine("fuzz", "0902-02", "0138", 1, line, true, false, false, true)
+	})
+}
+
+func FuzzLocationLine(f *testing.F) {
+	addSeeds(f)
+	f.Fuzz(func(t *testing.T, line []byte) {
+		_, _ = parser.ParseLocationLine("fuzz", "0902-02", "0138", 1, line, false)
+	})
+}
+
+// FuzzGrammar runs the generated lexer and grammar directly from each entry point.
+func FuzzGrammar(f *testing.F) {
+	addSeeds(f)
+	entrypoints := []string{"FleetMovement", "Location", "ScoutMovement", "StatusLine", "TribeFollows", "TribeGoesTo", "TribeMovement", "TurnInfo"}
+	f.Fuzz(func(t *testing.T, line []byte) {
+		for _, entrypoint := range entrypoints {
+			_, _ = parser.Parse("fuzz", line, parser.Entrypoint(entrypoint))
+		}
+	})
+}
+
+func FuzzParseInput(f *testing.F) {
+	f.Add([]byte("Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\nCurrent Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\nTribe Movement: Move N-PR,  \\N-GH, River S\\\n0138 Status: GRASSY HILLS, Village Bravo, O NW, 0138, 0138e1\n"))
+	f.Fuzz(func(t *testing.T, input []byte) {
+		_, _ = parser.ParseInput(context.Background(), "fuzz", "0902-02", input, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	})
+}
+
+// TestParseInputInternalError checks that an internal error skips the rest of
+// the unit's section and that the parser continues with the next unit.
+func TestParseInputInternalError(t *testing.T) {
+	input := []byte(`Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)
+Current Turn 902-02 (#26), Winter, FINE	Next Turn 902-03 (#27), 28/10/2023
+Tribe Movement: Move N
+0138 Status: PRAIRIE, 0138
+
+Element 0138e1, , Current Hex = QQ 1108, (Previous Hex = QQ 1108)
+Current Turn 902-02 (#26), Winter, FINE
+Tribe Movement: Move \
+0138e1 Status: PRAIRIE, 0138e1
+`)
+	turn, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", input, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		t.Fatalf("parse: want nil, got %v", err)
+	}
+	if len(turn.Errors) != 1 {
+		t.Fatalf("errors: want 1, got %d", len(turn.Errors))
+	}
+	if ie := turn.Errors[0]; ie.File != "0902-02.0138" || ie.Unit != "0138" || ie.Line != 3 {
+		t.Errorf("error: want 0902-02.0138/0138/3, got %s/%s/%d", ie.File, ie.Unit, ie.Line)
+	}
+	if moves, ok := turn.UnitMoves["0138"]; !ok {
+		t.Errorf("0138: want moves, got none")
+	} else if moves.Status != "" {
+		t.Errorf("0138: status: want skipped, got %q", moves.Status)
+	}
+	if moves, ok := turn.UnitMoves["0138e1"]; !ok {
+		t.Errorf("0138e1: want moves, got none")
+	} else if len(moves.Moves) == 0 {
+		t.Errorf("0138e1: want moves, got none")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"github.com/playbymail/ottomap/internal/items"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"testing"
+)
+
+func TestStatusInventory(t *testing.T) {
+	for _, tc := range []struct {
+		id        int
+		status    string
+		inventory string
+		people    int
+		horses    int
+	}{
+		{1, "0138 Status: PRAIRIE, 120 People, 30 Horses, 10 Wagons, 0138, 1590", "120 People, 30 Horses, 10 Wagons", 120, 30},
+		{2, "0138 Status: PRAIRIE, 0138, 1590", "", 0, 0},
+		{3, "0138 Status: PRAIRIE, 5 Horses, 0138", "5 Horses", 0, 5},
+		{4, "0138 Status: PRAIRIE, 3 Cattle, 2 People, 0138", "2 People, 3 Cattle", 2, 0},
+	} {
+		turn := parse(t, "0902-02", "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n"+
+			"Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n"+
+			"Tribe Movement: Move \\\n"+
+			tc.status+"\n")
+		moves, ok := turn.UnitMoves["0138"]
+		if !ok {
+			t.Errorf("%d: want moves, got none", tc.id)
+			continue
+		}
+		inventory := moves.Inventory
+		if got := inventory.String(); got != tc.inventory {
+			t.Errorf("%d: inventory: want %q, got %q", tc.id, tc.inventory, got)
+		}
+		if inventory != nil && (inventory.People != tc.people || inventory.Animals[items.Horses] != tc.horses) {
+			t.Errorf("%d: want %d people and %d horses, got %d and %d", tc.id, tc.people, tc.horses, inventory.People, inventory.Animals[items.Horses])
+		}
+		// the inventory is removed before the status line is parsed, so the terrain is still found
+		var status *parser.Move_t
+		for _, move := range moves.Moves {
+			if move.Report != nil && move.Report.Terrain != terrain.Blank {
+				status = move
+			}
+		}
+		if status == nil || status.Report.Terrain != terrain.Prairie {
+			t.Errorf("%d: want prairie status, got %v", tc.id, status)
+		}
+	}
+}
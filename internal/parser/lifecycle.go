@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// UnitEvent_e is a change in the life of a unit.
+//
+// TribeNet doesn't print these lines. They are an ottomap directive that the
+// player adds by hand to the section of the unit that made the change:
+//
+//	Created Element 0138e2
+//	Disbanded Garrison 0138g1
+type UnitEvent_e int
+
+const (
+	UnknownUnitEvent UnitEvent_e = iota
+	UnitCreated                  // the unit was formed this turn
+	UnitDisbanded                // the unit was disbanded this turn
+)
+
+var (
+	// UnitEventToString is a helper map for marshalling the enum
+	UnitEventToString = map[UnitEvent_e]string{
+		UnknownUnitEvent: "?",
+		UnitCreated:      "created",
+		UnitDisbanded:    "disbanded",
+	}
+	// StringToUnitEvent is a helper map for unmarshalling the enum
+	StringToUnitEvent = map[string]UnitEvent_e{
+		"created":   UnitCreated,
+		"disbanded": UnitDisbanded,
+	}
+)
+
+func (e UnitEvent_e) String() string {
+	if str, ok := UnitEventToString[e]; ok {
+		return str
+	}
+	return fmt.Sprintf("UnitEvent_e(%d)", int(e))
+}
+
+// UnitEvent_t is a unit event directive from a report.
+type UnitEvent_t struct {
+	Kind   UnitEvent_e
+	UnitId UnitId_t // the unit that was created or disbanded
+	LineNo int
+}
+
+var rxUnitEvent = regexp.MustCompile(`^(Created|Disbanded) (Courier|Element|Fleet|Garrison) (\d{4}[cefg][1-9])\s*$`)
+
+// IsUnitEventLine returns true if the line looks like a unit event.
+func IsUnitEventLine(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("Created ")) || bytes.HasPrefix(line, []byte("Disbanded "))
+}
+
+// ParseUnitEventLine returns the event from a "Created" or "Disbanded" directive.
+// The kind of unit must agree with the unit id, so "Created Element 0138c1" is an error.
+func ParseUnitEventLine(fid string, unitId UnitId_t, lineNo int, line []byte) (*UnitEvent_t, error) {
+	match := rxUnitEvent.FindSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("%s: %s: %d: invalid unit event %q", fid, unitId, lineNo, slug(line, 30))
+	}
+	kind, suffix := UnitCreated, map[string]byte{"Courier": 'c', "Element": 'e', "Fleet": 'f', "Garrison": 'g'}[string(match[2])]
+	if string(match[1]) == "Disbanded" {
+		kind = UnitDisbanded
+	}
+	if match[3][4] != suffix {
+		return nil, fmt.Errorf("%s: %s: %d: %s %s: unit is not a %s", fid, unitId, lineNo, match[1], match[3], bytes.ToLower(match[2]))
+	}
+	return &UnitEvent_t{Kind: kind, UnitId: UnitId_t(match[3]), LineNo: lineNo}, nil
+}
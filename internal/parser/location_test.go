@@ -11,6 +11,7 @@ import (
 	"github.com/playbymail/ottomap/internal/resources"
 	"github.com/playbymail/ottomap/internal/results"
 	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/winds"
 	"testing"
 )
 
@@ -85,11 +86,13 @@ func TestFleetMovementParse(t *testing.T) {
 		id     string
 		line   string
 		unitId parser.UnitId_t
+		wind   parser.Wind_t
 		moves  []*parser.Move_t
 		debug  bool
 	}{
 		{id: "900-05.0138f2",
 			line:   `STRONG S Fleet Movement: Move NW-GH,`,
+			wind:   parser.Wind_t{Strength: winds.Strong, From: direction.South},
 			unitId: "0138f2",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("NW-GH"),
@@ -101,6 +104,7 @@ func TestFleetMovementParse(t *testing.T) {
 		},
 		{id: "900-06.0138f4",
 			line:   `MILD NW Fleet Movement: Move NE-LCM,  Lcm NE, SE, S,\NE-LCM,  Lcm NE, SE, SW, S,\NE-LCM,  Lcm NE, SE, SW, S,\`,
+			wind:   parser.Wind_t{Strength: winds.Mild, From: direction.NorthWest},
 			unitId: "0138f4",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("NE-LCM,  Lcm NE, SE, S"),
@@ -139,6 +143,7 @@ func TestFleetMovementParse(t *testing.T) {
 		},
 		{id: "900-06.0138f1",
 			line:   `MILD NW Fleet Movement: Move SE-O,-(NE O,  SE LCM,  N O,  S LCM,  SW O,  NW O,  )(Sight Water - N/N, Sight Land - N/NE)`,
+			wind:   parser.Wind_t{Strength: winds.Mild, From: direction.NorthWest},
 			unitId: "0138f1",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("SE-O,-(NE O,  SE LCM,  N O,  S LCM,  SW O,  NW O,  )(Sight Water - N/N, Sight Land - N/NE)"),
@@ -162,6 +167,7 @@ func TestFleetMovementParse(t *testing.T) {
 		},
 		{id: "900-06.0138f2",
 			line:   `MILD NW Fleet Movement: Move SE-O,-(NE O)(Sight Water - N/N, Sight Land - N/NE)\No River Adjacent to Hex to SW of HEX`,
+			wind:   parser.Wind_t{Strength: winds.Mild, From: direction.NorthWest},
 			unitId: "0138f1",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("SE-O,-(NE O)(Sight Water - N/N, Sight Land - N/NE)"),
@@ -183,6 +189,7 @@ func TestFleetMovementParse(t *testing.T) {
 		},
 		{id: "900-06.1138f7",
 			line:   `MILD N Fleet Movement: Move SW-PR The Dirty Squirrel-(NE GH,  SE O, N GH, S O, SW O, NW O, )(Sight Land - N/N,Sight Land - N/NE,Sight Land - N/NW,Sight Water - NE/NE,Sight Water - NE/SE,Sight Water - SE/SE,Sight Water - S/SE,Sight Water - S/S,Sight Water - S/SW,Sight Water - SW/SW,Sight Water - SW/NW,Sight Water - NW/NW, )\NW-O, -(NE GH, SE PR, N SW, S O, SW O, NW O, )(Sight Water - N/N,Sight Land - N/NE,Sight Water - N/NW,Sight Land - NE/NE,Sight Land - NE/SE,Sight Water - SE/SE,Sight Water - S/SE,Sight Water - S/S,Sight Water - S/SW,Sight Water - SW/SW,Sight Water - SW/NW,Sight Water - NW/NW, )\NW-O, -(NE SW, SE O, N O, S O, SW O, NW O, )(Sight Water - N/N,Sight Water - N/NE,Sight Water - N/NW,Sight Land - NE/NE,Sight Land - NE/SE,Sight Land - SE/SE,Sight Water - S/SE,Sight Water - S/S,Sight Water - S/SW,Sight Water - SW/SW,Sight Water - SW/NW,Sight Water - NW/NW, )\N-O, -(NE O, SE SW, N O, S O, SW O, NW O, )(Sight Land - N/N,Sight Land - N/NE,Sight Water - N/NW,Sight Land - NE/NE,Sight Land - NE/SE,Sight Land - SE/SE,Sight Water - S/SE,Sight Water - S/S,Sight Water - S/SW,Sight Water - SW/SW,Sight Water - SW/NW,Sight Water - NW/NW, )\N-O,  Lcm NE, N,-(NE LCM, SE O, N LCM, S O, SW O, NW O, )(Sight Land - N/N,Sight Land - N/NE,Sight Water - N/NW,Sight Land - NE/NE,Sight Land - NE/SE,Sight Land - SE/SE,Sight Land - S/SE,Sight Water - S/S,Sight Water - S/SW,Sight Water - SW/SW,Sight Water - SW/NW,Sight Water - NW/NW, )\N-LCM,  Lcm NE, SE,  Ensalada sin Tomate\`,
+			wind:   parser.Wind_t{Strength: winds.Mild, From: direction.North},
 			unitId: "0138f7",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("SW-PR The Dirty Squirrel-(NE GH,  SE O, N GH, S O, SW O, NW O, )(Sight Land - N/N,Sight Land - N/NE,Sight Land - N/NW,Sight Water - NE/NE,Sight Water - NE/SE,Sight Water - SE/SE,Sight Water - S/SE,Sight Water - S/S,Sight Water - S/SW,Sight Water - SW/SW,Sight Water - SW/NW,Sight Water - NW/NW, )"),
@@ -334,11 +341,15 @@ func TestFleetMovementParse(t *testing.T) {
 			},
 		},
 	} {
-		fm, err := parser.ParseFleetMovementLine(tc.id, tc.unitId, 1, []byte(tc.line), tc.debug, tc.debug)
+		fm, err := parser.ParseFleetMovementLine(tc.id, "", tc.unitId, 1, []byte(tc.line), false, tc.debug, tc.debug, false, false)
 		if err != nil {
 			t.Errorf("id %q: parse failed: %v\n", tc.id, err)
 			continue
 		}
+		stampUnit(tc.moves, tc.unitId)
+		for _, m := range tc.moves {
+			m.Wind = tc.wind
+		}
 		i1, i2 := 0, 0
 		for i1 < len(tc.moves) && i2 < len(fm) {
 			m1, m2 := tc.moves[i1], fm[i2]
@@ -446,7 +457,7 @@ func TestScoutMovementParse(t *testing.T) {
 							{Direction: direction.NorthWest, Terrain: terrain.Ocean},
 						},
 						Resources:  []resources.Resource_e{resources.IronOre},
-						Encounters: []parser.UnitId_t{"0138c2", "0138c3", "1590"},
+						Encounters: []*parser.Encounter_t{{UnitId: "1590"}, {UnitId: "0138c2"}, {UnitId: "0138c3"}},
 					},
 				},
 				{LineNo: 1, StepNo: 4, Line: []byte("Can't Move on Ocean to N of HEX,  Patrolled and found 1590,  0138c2,  0138c3"),
@@ -454,7 +465,7 @@ func TestScoutMovementParse(t *testing.T) {
 						Borders: []*parser.Border_t{
 							{Direction: direction.North, Terrain: terrain.Ocean},
 						},
-						Encounters: []parser.UnitId_t{"0138c2", "0138c3", "1590"},
+						Encounters: []*parser.Encounter_t{{UnitId: "1590"}, {UnitId: "0138c2"}, {UnitId: "0138c3"}},
 					},
 				},
 			},
@@ -468,7 +479,7 @@ func TestScoutMovementParse(t *testing.T) {
 						Borders: []*parser.Border_t{
 							{Direction: direction.South, Edge: edges.River},
 						},
-						Encounters: []parser.UnitId_t{"0590"},
+						Encounters: []*parser.Encounter_t{{UnitId: "0590"}},
 					},
 				},
 				{LineNo: 1, StepNo: 2, Line: []byte("Not enough M.P's to move to SE into ROCKY HILLS,  Patrolled and found 0590"),
@@ -476,7 +487,7 @@ func TestScoutMovementParse(t *testing.T) {
 						Borders: []*parser.Border_t{
 							{Direction: direction.SouthEast, Terrain: terrain.RockyHills},
 						},
-						Encounters: []parser.UnitId_t{"0590"},
+						Encounters: []*parser.Encounter_t{{UnitId: "0590"}},
 					},
 				},
 			},
@@ -492,7 +503,7 @@ func TestScoutMovementParse(t *testing.T) {
 							{Direction: direction.South, Edge: edges.River},
 							{Direction: direction.NorthWest, Terrain: terrain.Ocean},
 						},
-						Encounters: []parser.UnitId_t{"3138"},
+						Encounters: []*parser.Encounter_t{{UnitId: "3138"}},
 					},
 				},
 				{LineNo: 1, StepNo: 2, Line: []byte("Can't Move on Ocean to N of HEX,  Patrolled and found 3138"),
@@ -500,7 +511,7 @@ func TestScoutMovementParse(t *testing.T) {
 						Borders: []*parser.Border_t{
 							{Direction: direction.North, Terrain: terrain.Ocean},
 						},
-						Encounters: []parser.UnitId_t{"3138"},
+						Encounters: []*parser.Encounter_t{{UnitId: "3138"}},
 					},
 				},
 			},
@@ -538,7 +549,7 @@ func TestScoutMovementParse(t *testing.T) {
 			},
 		},
 	} {
-		sm, err := parser.ParseScoutMovementLine(tc.id, tc.unitId, 1, []byte(tc.line), tc.debug, tc.debug)
+		sm, err := parser.ParseScoutMovementLine(tc.id, "", tc.unitId, 1, []byte(tc.line), false, tc.debug, tc.debug, false, false)
 		if err != nil {
 			t.Errorf("id %q: parse failed: %v\n", tc.id, err)
 			continue
@@ -546,6 +557,7 @@ func TestScoutMovementParse(t *testing.T) {
 		if tc.scoutNo != sm.No {
 			t.Errorf("id %q: scoutNo: want %d, got %d\n", tc.id, tc.scoutNo, sm.No)
 		}
+		stampUnit(tc.moves, tc.unitId)
 		i1, i2 := 0, 0
 		for i1 < len(tc.moves) && i2 < len(sm.Moves) {
 			m1, m2 := tc.moves[i1], sm.Moves[i2]
@@ -583,9 +595,9 @@ func TestStatusLine(t *testing.T) {
 			unitId: "0138",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("PRAIRIE, 0138"),
-					Result: results.Succeeded, Still: true, Report: &parser.Report_t{
+					Result: results.StatusLine, Still: true, Report: &parser.Report_t{
 						Terrain:    terrain.Prairie,
-						Encounters: []parser.UnitId_t{"0138"},
+						Encounters: []*parser.Encounter_t{{UnitId: "0138"}},
 					},
 				},
 			},
@@ -595,12 +607,12 @@ func TestStatusLine(t *testing.T) {
 			unitId: "0138e1",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("PRAIRIE,River S, 0138e1"),
-					Result: results.Succeeded, Still: true, Report: &parser.Report_t{
+					Result: results.StatusLine, Still: true, Report: &parser.Report_t{
 						Terrain: terrain.Prairie,
 						Borders: []*parser.Border_t{
 							{Direction: direction.South, Edge: edges.River},
 						},
-						Encounters: []parser.UnitId_t{"0138e1"},
+						Encounters: []*parser.Encounter_t{{UnitId: "0138e1"}},
 					},
 				},
 			},
@@ -610,13 +622,13 @@ func TestStatusLine(t *testing.T) {
 			unitId: "0138",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("PRAIRIE, O S,Ford SE, 2138, 0138"),
-					Result: results.Succeeded, Still: true, Report: &parser.Report_t{
+					Result: results.StatusLine, Still: true, Report: &parser.Report_t{
 						Terrain: terrain.Prairie,
 						Borders: []*parser.Border_t{
 							{Direction: direction.SouthEast, Edge: edges.Ford},
 							{Direction: direction.South, Terrain: terrain.Ocean},
 						},
-						Encounters: []parser.UnitId_t{"0138", "2138"},
+						Encounters: []*parser.Encounter_t{{UnitId: "2138"}, {UnitId: "0138"}},
 					},
 				},
 			},
@@ -626,12 +638,12 @@ func TestStatusLine(t *testing.T) {
 			unitId: "0138e1",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("PRAIRIE, O NW, 0138e1"),
-					Result: results.Succeeded, Still: true, Report: &parser.Report_t{
+					Result: results.StatusLine, Still: true, Report: &parser.Report_t{
 						Terrain: terrain.Prairie,
 						Borders: []*parser.Border_t{
 							{Direction: direction.NorthWest, Terrain: terrain.Ocean},
 						},
-						Encounters: []parser.UnitId_t{"0138e1"},
+						Encounters: []*parser.Encounter_t{{UnitId: "0138e1"}},
 					},
 				},
 			},
@@ -641,24 +653,25 @@ func TestStatusLine(t *testing.T) {
 			unitId: "0138",
 			moves: []*parser.Move_t{
 				{LineNo: 1, StepNo: 1, Line: []byte("CONIFER HILLS, O SW, NW, S, 2138, 0138c1, 0138, 1138"),
-					Result: results.Succeeded, Still: true, Report: &parser.Report_t{
+					Result: results.StatusLine, Still: true, Report: &parser.Report_t{
 						Terrain: terrain.ConiferHills,
 						Borders: []*parser.Border_t{
 							{Direction: direction.South, Terrain: terrain.Ocean},
 							{Direction: direction.SouthWest, Terrain: terrain.Ocean},
 							{Direction: direction.NorthWest, Terrain: terrain.Ocean},
 						},
-						Encounters: []parser.UnitId_t{"0138", "0138c1", "1138", "2138"},
+						Encounters: []*parser.Encounter_t{{UnitId: "2138"}, {UnitId: "0138c1"}, {UnitId: "0138"}, {UnitId: "1138"}},
 					},
 				},
 			},
 		},
 	} {
-		sl, err := parser.ParseStatusLine(tc.id, tc.unitId, 1, []byte(tc.line), tc.debug, tc.debug)
+		sl, err := parser.ParseStatusLine(tc.id, "", tc.unitId, 1, []byte(tc.line), false, tc.debug, tc.debug, false)
 		if err != nil {
 			t.Errorf("id %q: parse failed: %v\n", tc.id, err)
 			continue
 		}
+		stampUnit(tc.moves, tc.unitId)
 		i1, i2 := 0, 0
 		for i1 < len(tc.moves) && i2 < len(sl) {
 			m1, m2 := tc.moves[i1], sl[i2]
@@ -694,7 +707,7 @@ func TestTribeFollowsParse(t *testing.T) {
 		{id: "1812", line: "Tribe Follows 1812", follows: "1812"},
 		{id: "1812f3", line: "Tribe Follows 1812f3", follows: "1812f3"},
 	} {
-		tf, err := parser.ParseTribeFollowsLine(tc.id, tc.unitId, 1, []byte(tc.line), tc.debug)
+		tf, err := parser.ParseTribeFollowsLine(tc.id, "", tc.unitId, 1, []byte(tc.line), tc.debug)
 		if err != nil {
 			t.Errorf("id %q: parse failed: %v\n", tc.id, err)
 			continue
@@ -717,7 +730,7 @@ func TestTribeGoesParse(t *testing.T) {
 		{id: "2", line: "Tribe Goes to ## 1812", goesTo: "## 1812"},
 		{id: "3", line: "Tribe Goes to N/A", goesTo: "N/A"},
 	} {
-		gt, err := parser.ParseTribeGoesToLine(tc.id, tc.unitId, 1, []byte(tc.line), tc.debug)
+		gt, err := parser.ParseTribeGoesToLine(tc.id, "", tc.unitId, 1, []byte(tc.line), tc.debug)
 		if err != nil {
 			t.Errorf("id %q: parse failed: %v\n", tc.id, err)
 			continue
@@ -776,7 +789,7 @@ func TestTribeMovementParse(t *testing.T) {
 			},
 		},
 	} {
-		tm, err := parser.ParseTribeMovementLine(tc.id, tc.unitId, 1, []byte(tc.line), tc.debug, tc.debug)
+		tm, err := parser.ParseTribeMovementLine(tc.id, "", tc.unitId, 1, []byte(tc.line), false, tc.debug, tc.debug, false)
 		if err != nil {
 			t.Errorf("id %q: parse failed: %v\n", tc.id, err)
 			continue
@@ -913,3 +926,13 @@ func diffUnitSets(set1, set2 []parser.UnitId_t) ([]parser.UnitId_t, []parser.Uni
 
 	return onlyInSet1, onlyInSet2
 }
+
+// stampUnit sets the unit id that the parser adds to every move and report.
+func stampUnit(moves []*parser.Move_t, unitId parser.UnitId_t) {
+	for _, m := range moves {
+		m.UnitId = unitId
+		if m.Report != nil {
+			m.Report.UnitId = unitId
+		}
+	}
+}
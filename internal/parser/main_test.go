@@ -0,0 +1,35 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/parser"
+	"io"
+	"log"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// the parser logs every section it reads and every error it finds;
+	// that is just noise in the tests and when fuzzing
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// parse returns the turn parsed from the report with the default settings.
+func parse(t *testing.T, tid, report string) *parser.Turn_t {
+	t.Helper()
+	return parseWith(t, tid, report, parser.ParseConfig{})
+}
+
+// parseWith returns the turn parsed from the report with the configuration.
+func parseWith(t *testing.T, tid, report string, cfg parser.ParseConfig) *parser.Turn_t {
+	t.Helper()
+	turn, err := parser.ParseInput(context.Background(), "test", tid, []byte(report), false, false, false, false, false, false, false, false, cfg)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return turn
+}
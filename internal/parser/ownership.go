@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// SettlementOwner_t is the clan that controls a settlement in the unit's current hex.
+//
+// TribeNet doesn't print these lines. They are an ottomap directive that the
+// player adds by hand to the section of the unit that is in the hex at the end of the turn:
+//
+//	Settlement Rivertown Held by 0250
+//	Settlement Rivertown Captured by 0138
+type SettlementOwner_t struct {
+	Name     string   // name of the settlement
+	Clan     UnitId_t // clan that controls the settlement
+	Captured bool     // true if the clan took the settlement this turn
+	LineNo   int
+}
+
+// Ownership_t is a change in the owner of a settlement.
+type Ownership_t struct {
+	TurnId   string
+	Clan     UnitId_t
+	Captured bool
+}
+
+func (o *Ownership_t) String() string {
+	if o.Captured {
+		return fmt.Sprintf("captured by %s", o.Clan)
+	}
+	return fmt.Sprintf("held by %s", o.Clan)
+}
+
+var rxSettlementOwner = regexp.MustCompile(`^Settlement (.+?) (Held|Captured) by (0\d{3})\s*$`)
+
+// IsSettlementOwnerLine returns true if the line looks like a settlement owner directive.
+func IsSettlementOwnerLine(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("Settlement "))
+}
+
+// ParseSettlementOwnerLine returns the owner from a "Settlement ... Held by" or "Settlement ... Captured by" directive.
+// The owner must be a clan, so "Held by 1250" is an error, and so is a line that doesn't match either form.
+func ParseSettlementOwnerLine(fid string, unitId UnitId_t, lineNo int, line []byte) (*SettlementOwner_t, error) {
+	match := rxSettlementOwner.FindSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("%s: %s: %d: invalid settlement owner %q", fid, unitId, lineNo, slug(line, 30))
+	}
+	return &SettlementOwner_t{
+		Name:     string(bytes.TrimSpace(match[1])),
+		Clan:     UnitId_t(match[3]),
+		Captured: string(match[2]) == "Captured",
+		LineNo:   lineNo,
+	}, nil
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"testing"
+)
+
+func TestSettlementOwnerLine(t *testing.T) {
+	for _, tc := range []struct {
+		id       int
+		line     string
+		name     string
+		clan     parser.UnitId_t
+		captured bool
+		err      bool
+	}{
+		{id: 1, line: "Settlement Rivertown Held by 0250", name: "Rivertown", clan: "0250"},
+		{id: 2, line: "Settlement Old Mill Captured by 0138", name: "Old Mill", clan: "0138", captured: true},
+		{id: 3, line: "Settlement Rivertown Held by 1250", err: true},
+		{id: 4, line: "Settlement Rivertown", err: true},
+		{id: 5, line: "Settlement Rivertown Taken by 0250", err: true},
+	} {
+		owner, err := parser.ParseSettlementOwnerLine("test", "0138", 1, []byte(tc.line))
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d: want error, got %+v", tc.id, owner)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: error %v", tc.id, err)
+		} else if owner.Name != tc.name || owner.Clan != tc.clan || owner.Captured != tc.captured {
+			t.Errorf("%d: want %q %s %v, got %q %s %v", tc.id, tc.name, tc.clan, tc.captured, owner.Name, owner.Clan, owner.Captured)
+		}
+	}
+}
@@ -4,9 +4,12 @@ package parser
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/playbymail/ottomap/internal/direction"
 	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/items"
 	"github.com/playbymail/ottomap/internal/resources"
 	"github.com/playbymail/ottomap/internal/results"
 	"github.com/playbymail/ottomap/internal/terrain"
@@ -33,49 +36,139 @@ var (
 	rxTribeSection    = regexp.MustCompile(`^Tribe \d{4}, `)
 )
 
-const (
-	LastTurnCurrentLocationObscured = "0902-01"
-)
-
 type ParseConfig struct {
+	// Format forces the report format. When nil, the format is selected from the turn id.
+	Format *Format_t
 	Ignore struct {
 		Scouts bool
 		Logged struct {
 			Scouts bool
 		}
 	}
+	// Workers is the number of goroutines that parse unit sections.
+	// Zero or one parses the report sequentially.
+	Workers int
 }
 
-func ParseInput(fid, tid string, input []byte, acceptLoneDash, debugParser, debugSections, debugSteps, debugNodes, debugFleetMovement bool, experimentalUnitSplit, experimentalScoutStill bool, cfg ParseConfig) (*Turn_t, error) {
-	debugfm := func(format string, args ...any) {
-		if debugFleetMovement {
-			log.Printf(format, args...)
+// ParseInput parses a turn report.
+// It stops with the context's error if the context is cancelled before the report is parsed.
+func ParseInput(ctx context.Context, fid, tid string, input []byte, acceptLoneDash, debugParser, debugSections, debugSteps, debugNodes, debugFleetMovement bool, experimentalUnitSplit, experimentalScoutStill bool, cfg ParseConfig) (*Turn_t, error) {
+	p := &sectionParser_t{
+		ctx:                    ctx,
+		fid:                    fid,
+		tid:                    tid,
+		acceptLoneDash:         acceptLoneDash,
+		debugParser:            debugParser,
+		debugSections:          debugSections,
+		debugSteps:             debugSteps,
+		debugNodes:             debugNodes,
+		debugFleetMovement:     debugFleetMovement,
+		experimentalUnitSplit:  experimentalUnitSplit,
+		experimentalScoutStill: experimentalScoutStill,
+		cfg:                    cfg,
+		t: &Turn_t{
+			Format:    cfg.Format,
+			UnitMoves: map[UnitId_t]*Moves_t{},
+		},
+		a: &arena_t{},
+	}
+	if debugParser {
+		log.Printf("%s: parser: %8d bytes\n", fid, len(input))
+	}
+
+	lines := bytes.Split(input, []byte("\n"))
+	var err error
+	if cfg.Workers > 1 {
+		err = parseSectionsConcurrently(p, lines, cfg.Workers)
+	} else {
+		_, err = p.parseLines(1, lines)
+	}
+	t := p.t
+	if err != nil {
+		return t, err
+	}
+
+	// stuff the turn id into all the moves so that sammy can sort them later
+	turnId := fmt.Sprintf("%04d-%02d", t.Year, t.Month)
+	for _, v := range t.UnitMoves {
+		v.TurnId = turnId
+		for _, move := range v.Moves {
+			move.TurnId = turnId
 		}
 	}
-	debugp := func(format string, args ...any) {
-		if debugParser {
+
+	return t, nil
+}
+
+// linesBetweenCancelChecks is how often the parser checks whether it has been cancelled.
+const linesBetweenCancelChecks = 64
+
+// sectionParser_t holds the options for parsing a report and the turn
+// that the parsed units are added to.
+type sectionParser_t struct {
+	ctx                    context.Context // checked every few lines so that long reports can be cancelled
+	fid, tid               string
+	acceptLoneDash         bool
+	debugParser            bool
+	debugSections          bool
+	debugSteps             bool
+	debugNodes             bool
+	debugFleetMovement     bool
+	experimentalUnitSplit  bool
+	experimentalScoutStill bool
+	cfg                    ParseConfig
+	t                      *Turn_t
+	a                      *arena_t // allocates the moves and reports
+}
+
+// parseLines parses lines from the report and adds the units to the turn.
+// The lines must start at the beginning of the report or at a unit section header.
+// It returns true if parsing stopped early without an error.
+func (p *sectionParser_t) parseLines(firstLineNo int, lines [][]byte) (stopped bool, err error) {
+	fid, tid, t, a, cfg := p.fid, p.tid, p.t, p.a, &p.cfg
+	acceptLoneDash, debugParser, debugSteps, debugNodes := p.acceptLoneDash, p.debugParser, p.debugSteps, p.debugNodes
+	debugFleetMovement, experimentalUnitSplit, experimentalScoutStill := p.debugFleetMovement, p.experimentalUnitSplit, p.experimentalScoutStill
+	debugfm := func(format string, args ...any) {
+		if debugFleetMovement {
 			log.Printf(format, args...)
 		}
 	}
 	debugs := func(format string, args ...any) {
-		if debugSections {
+		if p.debugSections {
 			log.Printf(format, args...)
 		}
 	}
-	debugp("%s: parser: %8d bytes\n", fid, len(input))
 
-	t := &Turn_t{
-		UnitMoves: map[UnitId_t]*Moves_t{},
-	}
 	var unitId UnitId_t // current unit being parsed
 	var moves *Moves_t  // current move being parsed
 
 	var statusLinePrefix []byte
-	for n, line := range bytes.Split(input, []byte("\n")) {
+
+	// skipSection is set when a line fails with an internal error. we can't trust
+	// the rest of the unit's section, so we skip it and continue with the next unit.
+	var skipSection bool
+	skipOnInternalError := func(err error) bool {
+		var ie *InternalError_t
+		if !errors.As(err, &ie) {
+			return false
+		}
+		log.Printf("warn: %v\n", ie)
+		log.Printf("warn: %s: %s: skipping rest of section: please report this error\n", fid, unitId)
+		t.Errors = append(t.Errors, ie)
+		skipSection = true
+		return true
+	}
+
+	for n, line := range lines {
+		if n%linesBetweenCancelChecks == 0 {
+			if err := p.ctx.Err(); err != nil {
+				return false, fmt.Errorf("%s: %d: %w", fid, firstLineNo+n, err)
+			}
+		}
 		if len(line) == 0 {
 			continue
 		}
-		lineNo := n + 1
+		lineNo := firstLineNo + n
 
 		if rxCourierSection.Match(line) {
 			unitId = UnitId_t(line[8:14])
@@ -83,124 +176,127 @@ func ParseInput(fid, tid string, input []byte, acceptLoneDash, debugParser, debu
 			location, err := ParseLocationLine(fid, tid, unitId, lineNo, line, debugParser)
 			if err != nil {
 				log.Printf("%s: %s: %d: location %q: %v\n", fid, unitId, lineNo, slug(line, 14), err)
-				return t, nil
+				return true, nil
 			} else if _, ok := t.UnitMoves[unitId]; ok {
 				log.Printf("%s: %s: %d: location %q\n", fid, unitId, lineNo, slug(line, 14))
-				return t, fmt.Errorf("duplicate unit in turn")
-			} else if t.Id > LastTurnCurrentLocationObscured && strings.HasPrefix(location.CurrentHex, "##") {
-				log.Printf("info: last turn current location is obscured is %s\n", LastTurnCurrentLocationObscured)
+				return false, fmt.Errorf("duplicate unit in turn")
+			} else if format := t.ReportFormat(); !format.ObscuredCurrentHex && strings.HasPrefix(location.CurrentHex, "##") {
+				log.Printf("info: report format %q does not obscure the current location\n", format.Name)
 				log.Printf("%s: %s: %d: location %q\n", fid, unitId, lineNo, location.CurrentHex)
-				return t, fmt.Errorf("current location is obscured")
+				return false, fmt.Errorf("current location is obscured")
 			}
 			moves = &Moves_t{TurnId: t.Id, UnitId: unitId, FromHex: location.PreviousHex, ToHex: location.CurrentHex}
 			t.UnitMoves[moves.UnitId] = moves
 			statusLinePrefix = []byte(fmt.Sprintf("%s Status: ", unitId))
+			skipSection = false
 		} else if rxElementSection.Match(line) {
 			unitId = UnitId_t(line[8:14])
 			debugs("%s: %d: found %q\n", fid, lineNo, unitId)
 			location, err := ParseLocationLine(fid, tid, unitId, lineNo, line, debugParser)
 			if err != nil {
 				log.Printf("%s: %s: %d: location %q: %v\n", fid, unitId, lineNo, slug(line, 14), err)
-				return t, nil
+				return true, nil
 			} else if _, ok := t.UnitMoves[unitId]; ok {
 				log.Printf("%s: %s: %d: location %q\n", fid, unitId, lineNo, slug(line, 14))
-				return t, fmt.Errorf("duplicate unit in turn")
+				return false, fmt.Errorf("duplicate unit in turn")
 			}
 			moves = &Moves_t{TurnId: t.Id, UnitId: unitId, FromHex: location.PreviousHex, ToHex: location.CurrentHex}
 			t.UnitMoves[moves.UnitId] = moves
 			statusLinePrefix = []byte(fmt.Sprintf("%s Status: ", unitId))
+			skipSection = false
 		} else if rxFleetSection.Match(line) {
 			unitId = UnitId_t(line[6:12])
 			debugs("%s: %d: found %q\n", fid, lineNo, unitId)
 			location, err := ParseLocationLine(fid, tid, unitId, lineNo, line, debugParser)
 			if err != nil {
 				log.Printf("%s: %s: %d: location %q: %v\n", fid, unitId, lineNo, slug(line, 12), err)
-				return t, nil
+				return true, nil
 			} else if _, ok := t.UnitMoves[unitId]; ok {
 				log.Printf("%s: %s: %d: location %q\n", fid, unitId, lineNo, slug(line, 12))
-				return t, fmt.Errorf("duplicate unit in turn")
+				return false, fmt.Errorf("duplicate unit in turn")
 			}
 			moves = &Moves_t{TurnId: t.Id, UnitId: unitId, FromHex: location.PreviousHex, ToHex: location.CurrentHex}
 			t.UnitMoves[moves.UnitId] = moves
 			statusLinePrefix = []byte(fmt.Sprintf("%s Status: ", unitId))
+			skipSection = false
 		} else if rxGarrisonSection.Match(line) {
 			unitId = UnitId_t(line[9:15])
 			debugs("%s: %d: found %q\n", fid, lineNo, unitId)
 			location, err := ParseLocationLine(fid, tid, unitId, lineNo, line, debugParser)
 			if err != nil {
 				log.Printf("%s: %s: %d: location %q: %v\n", fid, unitId, lineNo, slug(line, 15), err)
-				return t, nil
+				return true, nil
 			} else if _, ok := t.UnitMoves[unitId]; ok {
 				log.Printf("%s: %s: %d: location %q\n", fid, unitId, lineNo, slug(line, 15))
-				return t, fmt.Errorf("duplicate unit in turn")
+				return false, fmt.Errorf("duplicate unit in turn")
 			}
 			moves = &Moves_t{TurnId: t.Id, UnitId: unitId, FromHex: location.PreviousHex, ToHex: location.CurrentHex}
 			t.UnitMoves[moves.UnitId] = moves
 			statusLinePrefix = []byte(fmt.Sprintf("%s Status: ", unitId))
+			skipSection = false
 		} else if rxTribeSection.Match(line) {
 			unitId = UnitId_t(line[6:10])
 			debugs("%s: %d: found %q\n", fid, lineNo, unitId)
 			location, err := ParseLocationLine(fid, tid, unitId, lineNo, line, debugParser)
 			if err != nil {
 				log.Printf("%s: %s: %d: location %q: %v\n", fid, unitId, lineNo, slug(line, 10), err)
-				return t, nil
+				return true, nil
 			} else if _, ok := t.UnitMoves[unitId]; ok {
 				log.Printf("%s: %s: %d: location %q\n", fid, unitId, lineNo, slug(line, 10))
-				return t, fmt.Errorf("duplicate unit in turn")
+				return false, fmt.Errorf("duplicate unit in turn")
 			}
 			moves = &Moves_t{TurnId: t.Id, UnitId: unitId, FromHex: location.PreviousHex, ToHex: location.CurrentHex}
 			t.UnitMoves[moves.UnitId] = moves
 			statusLinePrefix = []byte(fmt.Sprintf("%s Status: ", unitId))
+			skipSection = false
 		} else if moves == nil {
 			log.Printf("%s: %s: %d: found line outside of section: %q\n", fid, unitId, lineNo, slug(line, 20))
+		} else if skipSection {
+			debugs("%s: %s: %d: skipping %q\n", fid, unitId, lineNo, slug(line, 20))
 		} else if bytes.HasPrefix(line, []byte("Current Turn ")) {
 			debugs("%s: %d: found %q\n", fid, lineNo, slug(line, 19))
 			if va, err := Parse(fid, line, Entrypoint("TurnInfo")); err != nil {
 				log.Printf("%s: %s: %d: error parsing turn info", fid, unitId, lineNo)
-				return t, err
+				return false, err
 			} else if turnInfo, ok := va.(TurnInfo_t); !ok {
-				log.Printf("%s: %s: %d: error parsing turn info", fid, unitId, lineNo)
-				log.Printf("error: parser.TurnInfo_t, got %T\n", va)
-				log.Printf("please report this error\n")
-				panic(fmt.Sprintf("unexpected type %T", va))
+				skipOnInternalError(unexpectedType(fid, unitId, lineNo, 0, line, "TurnInfo_t", va))
 			} else {
 				if t.Id == "" {
 					t.Year, t.Month = turnInfo.CurrentTurn.Year, turnInfo.CurrentTurn.Month
 					t.Id = fmt.Sprintf("%04d-%02d", t.Year, t.Month)
 				}
+				if t.Season == "" {
+					t.Season, t.Weather = parseSeasonWeather(line)
+				}
 				if turnInfo.CurrentTurn.Year != t.Year || turnInfo.CurrentTurn.Month != t.Month {
 					log.Printf("%s: %s: %d: current turn: %04d-%02d", fid, unitId, lineNo, t.Year, t.Month)
 					log.Printf("%s: %s: %d:    unit turn: %04d-%02d", fid, unitId, lineNo, turnInfo.CurrentTurn.Year, turnInfo.CurrentTurn.Month)
-					return t, fmt.Errorf("turn mismatch in report")
+					return false, fmt.Errorf("turn mismatch in report")
 				}
 			}
 		} else if bytes.HasPrefix(line, []byte{'>', '>', '>', '>'}) {
-			input := bytes.TrimPrefix(line, []byte{'>', '>', '>', '>'})
-			id, name, ok := bytes.Cut(input, []byte{'>'})
-			if !ok || len(name) == 0 {
-				name = id
-			}
-			id = bytes.ToLower(bytes.TrimSpace(id))
-			name = bytes.TrimSpace(name)
+			special, err := ParseSpecialHex(t.Id, line)
 			//log.Printf("%s: %s: %d: current turn: %04d-%02d", fid, unitId, lineNo, t.Year, t.Month)
-			log.Printf("%s: %s: %d: special name: %q -> %q", fid, unitId, lineNo, id, name)
+			log.Printf("%s: %s: %d: special name: %q -> %q", fid, unitId, lineNo, special.Id, special.Name)
+			if err != nil {
+				log.Printf("warn: %s: %s: %d: special %q: %v: ignoring the directives\n", fid, unitId, lineNo, special.Id, err)
+			}
 			if t.SpecialNames == nil {
 				t.SpecialNames = make(map[string]*Special_t)
 			}
-			t.SpecialNames[string(id)] = &Special_t{
-				TurnId: t.Id,
-				Id:     string(id),
-				Name:   string(name),
-			}
+			t.SpecialNames[special.Key()] = special
 		} else if rxFleetMovement.Match(line) {
 			pfx, _, ok := bytes.Cut(line, []byte{':'})
 			if !ok {
 				pfx = []byte(slug(line, 23))
 			}
 			debugfm("%s: %s: %d: found %q\n", fid, unitId, lineNo, pfx)
-			unitMoves, err := ParseFleetMovementLine(fid, tid, unitId, lineNo, line, acceptLoneDash, debugFleetMovement || debugSteps, debugFleetMovement || debugNodes, debugFleetMovement, experimentalUnitSplit)
+			unitMoves, err := parseFleetMovementLine(a, fid, tid, unitId, lineNo, line, acceptLoneDash, debugFleetMovement || debugSteps, debugFleetMovement || debugNodes, debugFleetMovement, experimentalUnitSplit)
 			if err != nil {
-				return t, err
+				if skipOnInternalError(err) {
+					continue
+				}
+				return false, err
 			}
 			if len(unitMoves) > 0 {
 				moves.Moves = append(moves.Moves, unitMoves...)
@@ -209,11 +305,14 @@ func ParseInput(fid, tid string, input []byte, acceptLoneDash, debugParser, debu
 			debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, slug(line, 13))
 			if moves.Follows != "" {
 				log.Printf("error: %s: %s: %d: found multiple follows\n", fid, unitId, lineNo)
-				return t, fmt.Errorf("multiple follows")
+				return false, fmt.Errorf("multiple follows")
 			}
 			followMove, err := ParseTribeFollowsLine(fid, tid, unitId, lineNo, line, false)
 			if err != nil {
-				return t, err
+				if skipOnInternalError(err) {
+					continue
+				}
+				return false, err
 			}
 			moves.Follows = followMove.Follows
 			moves.Moves = append(moves.Moves, followMove)
@@ -221,19 +320,40 @@ func ParseInput(fid, tid string, input []byte, acceptLoneDash, debugParser, debu
 			debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, slug(line, 14))
 			if moves.GoesTo != "" {
 				log.Printf("error: %s: %s: %d: found multiple goes to\n", fid, unitId, lineNo)
-				return t, fmt.Errorf("multiple goes to")
+				return false, fmt.Errorf("multiple goes to")
 			}
 			goesToMove, err := ParseTribeGoesToLine(fid, tid, unitId, lineNo, line, false)
 			if err != nil {
-				return t, err
+				if skipOnInternalError(err) {
+					continue
+				}
+				return false, err
 			}
 			moves.GoesTo = goesToMove.GoesTo
 			moves.Moves = append(moves.Moves, goesToMove)
+		} else if IsUnitEventLine(line) {
+			debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, slug(line, 14))
+			event, err := ParseUnitEventLine(fid, unitId, lineNo, line)
+			if err != nil {
+				log.Printf("warn: %v: ignoring the line\n", err)
+				continue
+			}
+			moves.Events = append(moves.Events, event)
+		} else if IsSettlementOwnerLine(line) {
+			debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, slug(line, 14))
+			owner, err := ParseSettlementOwnerLine(fid, unitId, lineNo, line)
+			if err != nil {
+				return false, err
+			}
+			moves.Owners = append(moves.Owners, owner)
 		} else if bytes.HasPrefix(line, []byte("Tribe Movement: ")) {
 			debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, slug(line, 14))
-			unitMoves, err := ParseTribeMovementLine(fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit)
+			unitMoves, err := parseTribeMovementLine(a, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit)
 			if err != nil {
-				return t, err
+				if skipOnInternalError(err) {
+					continue
+				}
+				return false, err
 			}
 			if len(unitMoves) > 0 {
 				moves.Moves = append(moves.Moves, unitMoves...)
@@ -246,35 +366,55 @@ func ParseInput(fid, tid string, input []byte, acceptLoneDash, debugParser, debu
 				}
 			} else {
 				debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, slug(line, 14))
-				scoutMoves, err := ParseScoutMovementLine(fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit, experimentalScoutStill)
+				scoutMoves, err := parseScoutMovementLine(a, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit, experimentalScoutStill)
 				if err != nil {
+					if skipOnInternalError(err) {
+						continue
+					}
 					log.Printf("%s: %s: %d: %s\n", fid, unitId, lineNo, err)
-					return t, err
+					return false, err
 				}
 				moves.Scouts = append(moves.Scouts, scoutMoves)
 			}
 		} else if bytes.HasPrefix(line, statusLinePrefix) {
 			debugs("%s: %s: %d: found %q\n", fid, unitId, lineNo, statusLinePrefix)
-			statusMoves, err := ParseStatusLine(fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit)
+			moves.Status = string(bytes.TrimSpace(line[len(statusLinePrefix):]))
+			// the grammar doesn't know about inventory, so we strip it before parsing the status line
+			status, inventory := SplitStatusInventory(line[len(statusLinePrefix):])
+			moves.Inventory = inventory
+			line = append(append([]byte{}, statusLinePrefix...), status...)
+			statusMoves, err := parseStatusLine(a, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit)
 			if err != nil {
-				return t, err
+				if skipOnInternalError(err) {
+					continue
+				}
+				return false, err
 			}
 			if len(statusMoves) > 0 {
 				moves.Moves = append(moves.Moves, statusMoves...)
 			}
 		}
 	}
+	return false, nil
+}
 
-	// stuff the turn id into all the moves so that sammy can sort them later
-	turnId := fmt.Sprintf("%04d-%02d", t.Year, t.Month)
-	for _, v := range t.UnitMoves {
-		v.TurnId = turnId
-		for _, move := range v.Moves {
-			move.TurnId = turnId
+// ParseSpecialHexLine returns the id and name from a ">>>>id>name" line.
+// The id is forced to lower case. If the name is missing, the id is used.
+// The name ends at the first directive; see ParseSpecialHex.
+func ParseSpecialHexLine(line []byte) (id, name []byte) {
+	fields := specialHexFields(line)
+	id = fields[0]
+	var names [][]byte
+	for _, field := range fields[1:] {
+		if bytes.HasPrefix(field, []byte{'!'}) {
+			break
 		}
+		names = append(names, field)
 	}
-
-	return t, nil
+	if name = bytes.Join(names, []byte{'>'}); len(name) == 0 {
+		name = id
+	}
+	return bytes.ToLower(id), name
 }
 
 func slug(b []byte, n int) string {
@@ -347,15 +487,17 @@ type Step_t struct {
 // ParseFleetMovementLine parses a fleet movement line.
 // It returns the generic struct that covers all the known movement steps and cases.
 func ParseFleetMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves bool, experimentalUnitSplit bool) ([]*Move_t, error) {
+	return parseFleetMovementLine(nil, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves, experimentalUnitSplit)
+}
+
+func parseFleetMovementLine(a *arena_t, fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves bool, experimentalUnitSplit bool) ([]*Move_t, error) {
+	var wind Wind_t
 	if va, err := Parse(fid, line, Entrypoint("FleetMovement")); err != nil {
 		return nil, err
 	} else if mt, ok := va.(Movement_t); !ok {
-		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, line)
-		log.Printf("error: want Movement_t, got %T\n", va)
-		log.Printf("please report this error\n")
-		panic(fmt.Errorf("unexpected type %T\n", va))
+		return nil, unexpectedType(fid, unitId, lineNo, 0, line, "Movement_t", va)
 	} else {
-		line = mt.Text
+		line, wind = mt.Text, Wind_t{Strength: mt.Winds.Strength, From: mt.Winds.From}
 	}
 	if debugSteps {
 		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, slug(line, 44))
@@ -367,7 +509,26 @@ func ParseFleetMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line [
 	}
 	line = bytes.TrimPrefix(line, []byte{'M', 'o', 'v', 'e'})
 
-	return parseMovementLine(fid, tid, unitId, lineNo, line, false, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves, experimentalUnitSplit, false)
+	moves, err := parseMovementLine(a, fid, tid, unitId, lineNo, line, false, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves, experimentalUnitSplit, false)
+	for _, move := range moves {
+		move.Wind = wind
+	}
+	return moves, err
+}
+
+// parseSeasonWeather returns the season and weather from the "Current Turn" line.
+// The grammar validates these fields but doesn't return them.
+//
+//	Current Turn 902-02 (#26), Winter, FINE	Next Turn 902-03 (#27), 28/10/2023
+func parseSeasonWeather(line []byte) (season, weather string) {
+	if n := bytes.Index(line, []byte("Next Turn")); n != -1 {
+		line = line[:n]
+	}
+	fields := strings.Split(string(line), ",")
+	if len(fields) < 3 {
+		return "", ""
+	}
+	return strings.TrimSpace(fields[1]), strings.TrimSpace(fields[2])
 }
 
 func ParseLocationLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, debug bool) (Location_t, error) {
@@ -375,16 +536,17 @@ func ParseLocationLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte
 		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, slug(line, 14))
 		return Location_t{}, err
 	} else if location, ok := va.(Location_t); !ok {
-		log.Printf("%s: %s: %d: location: %q\n", fid, unitId, lineNo, slug(line, 15))
-		log.Printf("error: invalid type\n")
-		log.Printf("please report this error")
-		panic(fmt.Errorf("want Location_t, got %T", va))
+		return Location_t{}, unexpectedType(fid, unitId, lineNo, 0, line, "Location_t", va)
 	} else {
 		return location, nil
 	}
 }
 
 func ParseScoutMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes bool, experimentalUnitSplit, cleanUpScoutStill bool) (*Scout_t, error) {
+	return parseScoutMovementLine(nil, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit, cleanUpScoutStill)
+}
+
+func parseScoutMovementLine(a *arena_t, fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes bool, experimentalUnitSplit, cleanUpScoutStill bool) (*Scout_t, error) {
 	scout := &Scout_t{
 		TurnId: tid,
 		LineNo: lineNo,
@@ -394,10 +556,7 @@ func ParseScoutMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line [
 	if va, err := Parse(fid, line, Entrypoint("ScoutMovement")); err != nil {
 		return nil, err
 	} else if mt, ok := va.(Movement_t); !ok {
-		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, line)
-		log.Printf("error: want Movement_t, got %T\n", va)
-		log.Printf("please report this error\n")
-		panic(fmt.Errorf("unexpected type %T\n", va))
+		return nil, unexpectedType(fid, unitId, lineNo, 0, line, "Movement_t", va)
 	} else {
 		scout.No = mt.ScoutNo
 		line = mt.Text
@@ -422,7 +581,7 @@ func ParseScoutMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line [
 	}
 
 	// parse the moves and then update each with the turn we did the scouting in
-	moves, err := parseMovementLine(fid, tid, unitId, lineNo, line, true, acceptLoneDash, debugSteps, debugNodes, false, experimentalUnitSplit, cleanUpScoutStill)
+	moves, err := parseMovementLine(a, fid, tid, unitId, lineNo, line, true, acceptLoneDash, debugSteps, debugNodes, false, experimentalUnitSplit, cleanUpScoutStill)
 	if err != nil {
 		log.Printf("%s: %s: %d: %q: %v\n", fid, unitId, lineNo, line, err)
 		return nil, err
@@ -437,6 +596,10 @@ func ParseScoutMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line [
 }
 
 func ParseStatusLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes bool, experimentalUnitSplit bool) ([]*Move_t, error) {
+	return parseStatusLine(nil, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit)
+}
+
+func parseStatusLine(a *arena_t, fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes bool, experimentalUnitSplit bool) ([]*Move_t, error) {
 	if va, err := Parse(fid, line, Entrypoint("StatusLine")); err != nil {
 		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, string(line))
 		log.Printf("status %v\n", err)
@@ -464,10 +627,7 @@ func ParseStatusLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte,
 		}
 		return nil, err
 	} else if mt, ok := va.(Movement_t); !ok {
-		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, line)
-		log.Printf("error: want Movement_t, got %T\n", va)
-		log.Printf("please report this error\n")
-		panic(fmt.Errorf("unexpected type %T\n", va))
+		return nil, unexpectedType(fid, unitId, lineNo, 0, line, "Movement_t", va)
 	} else {
 		line = mt.Text
 	}
@@ -476,7 +636,7 @@ func ParseStatusLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte,
 	}
 
 	// status lines have to be tagged since they are reported as scouting lines
-	moves, err := parseMovementLine(fid, tid, unitId, lineNo, line, false, acceptLoneDash, debugSteps, debugNodes, false, experimentalUnitSplit, false)
+	moves, err := parseMovementLine(a, fid, tid, unitId, lineNo, line, false, acceptLoneDash, debugSteps, debugNodes, false, experimentalUnitSplit, false)
 	if len(moves) > 0 && moves[0].Result == results.Succeeded {
 		moves[0].Result = results.StatusLine
 		//log.Printf("status: %s: %s: %s: %d: %d: %q\n", fid, tid, unitId, lineNo, len(moves), string(line))
@@ -484,15 +644,44 @@ func ParseStatusLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte,
 	return moves, err
 }
 
+// SplitStatusInventory removes the "<quantity> <item>" segments from the status text.
+// It returns the remaining text and the inventory, which is nil if there were no items.
+func SplitStatusInventory(text []byte) ([]byte, *Inventory_t) {
+	var inventory *Inventory_t
+	var kept [][]byte
+	for _, segment := range bytes.Split(text, []byte{','}) {
+		qty, name, ok := bytes.Cut(bytes.TrimSpace(segment), []byte{' '})
+		if !ok {
+			kept = append(kept, segment)
+			continue
+		}
+		n, err := strconv.Atoi(string(qty))
+		item, isItem := items.StringToEnum[string(bytes.TrimSpace(name))]
+		if err != nil || n < 0 || !isItem || item == items.None {
+			kept = append(kept, segment)
+			continue
+		}
+		if inventory == nil {
+			inventory = &Inventory_t{Animals: map[items.Item_e]int{}, Goods: map[items.Item_e]int{}}
+		}
+		switch item {
+		case items.People:
+			inventory.People += n
+		case items.Cattle, items.Elephant, items.Goats, items.Horses:
+			inventory.Animals[item] += n
+		default:
+			inventory.Goods[item] += n
+		}
+	}
+	return bytes.Join(kept, []byte{','}), inventory
+}
+
 func ParseTribeFollowsLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, debug bool) (*Move_t, error) {
 	var follows UnitId_t
 	if va, err := Parse(fid, line, Entrypoint("TribeFollows")); err != nil {
 		return nil, err
 	} else if mt, ok := va.(Movement_t); !ok {
-		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, line)
-		log.Printf("error: want Movement_t, got %T\n", va)
-		log.Printf("please report this error\n")
-		panic(fmt.Errorf("unexpected type %T\n", va))
+		return nil, unexpectedType(fid, unitId, lineNo, 0, line, "Movement_t", va)
 	} else {
 		follows = mt.Follows
 	}
@@ -516,10 +705,7 @@ func ParseTribeGoesToLine(fid, tid string, unitId UnitId_t, lineNo int, line []b
 	if va, err := Parse(fid, line, Entrypoint("TribeGoesTo")); err != nil {
 		return nil, err
 	} else if mt, ok := va.(Movement_t); !ok {
-		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, line)
-		log.Printf("error: want Movement_t, got %T\n", va)
-		log.Printf("please report this error\n")
-		panic(fmt.Errorf("unexpected type %T\n", va))
+		return nil, unexpectedType(fid, unitId, lineNo, 0, line, "Movement_t", va)
 	} else {
 		goesTo = mt.GoesTo
 	}
@@ -539,13 +725,14 @@ func ParseTribeGoesToLine(fid, tid string, unitId UnitId_t, lineNo int, line []b
 }
 
 func ParseTribeMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes bool, experimentalUnitSplit bool) ([]*Move_t, error) {
+	return parseTribeMovementLine(nil, fid, tid, unitId, lineNo, line, acceptLoneDash, debugSteps, debugNodes, experimentalUnitSplit)
+}
+
+func parseTribeMovementLine(a *arena_t, fid, tid string, unitId UnitId_t, lineNo int, line []byte, acceptLoneDash, debugSteps, debugNodes bool, experimentalUnitSplit bool) ([]*Move_t, error) {
 	if va, err := Parse(fid, line, Entrypoint("TribeMovement")); err != nil {
 		return nil, err
 	} else if mt, ok := va.(Movement_t); !ok {
-		log.Printf("%s: %s: %d: %q\n", fid, unitId, lineNo, line)
-		log.Printf("error: want Movement_t, got %T\n", va)
-		log.Printf("please report this error\n")
-		panic(fmt.Errorf("unexpected type %T\n", va))
+		return nil, unexpectedType(fid, unitId, lineNo, 0, line, "Movement_t", va)
 	} else {
 		line = mt.Text
 	}
@@ -559,7 +746,7 @@ func ParseTribeMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line [
 	}
 	line = bytes.TrimPrefix(line, []byte{'M', 'o', 'v', 'e'})
 
-	moves, err := parseMovementLine(fid, tid, unitId, lineNo, line, false, acceptLoneDash, debugSteps, debugNodes, false, experimentalUnitSplit, false)
+	moves, err := parseMovementLine(a, fid, tid, unitId, lineNo, line, false, acceptLoneDash, debugSteps, debugNodes, false, experimentalUnitSplit, false)
 	if err != nil {
 		return nil, err
 	}
@@ -572,7 +759,7 @@ func ParseTribeMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line [
 
 // parseMovementLine parses all the moves on a single line.
 // it returns a slice containing the results for each move or an error.
-func parseMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte, isScout bool, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves bool, experimentalUnitSplit, scoutStill bool) ([]*Move_t, error) {
+func parseMovementLine(a *arena_t, fid, tid string, unitId UnitId_t, lineNo int, line []byte, isScout bool, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves bool, experimentalUnitSplit, scoutStill bool) ([]*Move_t, error) {
 	var moves []*Move_t
 
 	line = bytes.TrimSpace(line)
@@ -582,15 +769,15 @@ func parseMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte
 	if bytes.Equal(line, []byte{'\\'}) {
 		// "Move \" should be treated as a stay in place
 		m := []*Move_t{
-			{UnitId: unitId,
+			a.newMove(Move_t{UnitId: unitId,
 				LineNo: lineNo, StepNo: 1, Line: []byte{},
-				Still: true, Result: results.Succeeded, Report: &Report_t{TurnId: tid, UnitId: unitId}},
+				Still: true, Result: results.Succeeded, Report: a.newReport(tid, unitId)}),
 		}
 		m[0].Debug.FleetMoves = debugFleetMoves
 		return m, nil
 	}
 
-	for _, move := range splitMoves(fid, tid, unitId, lineNo, line) {
+	for _, move := range splitMoves(a, fid, tid, unitId, lineNo, line) {
 		if debugSteps {
 			log.Printf("%s: %s: %d: step %d: %q\n", fid, unitId, lineNo, move.StepNo, move.Line)
 		}
@@ -645,11 +832,11 @@ func parseMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte
 				log.Printf("%s: %s: %d: step %d: dirt %q\n", fid, unitId, lineNo, move.StepNo, slug(thisHex, 44))
 			}
 
-			mt, err := parseMove(fid, tid, unitId, move.LineNo, move.StepNo, thisHex, isScout, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves, experimentalUnitSplit)
+			mt, err := parseMove(a, fid, tid, unitId, move.LineNo, move.StepNo, thisHex, move.Report, isScout, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves, experimentalUnitSplit)
 			if err != nil {
 				return nil, err
 			}
-			move.Advance, move.Still, move.Result, move.Report = mt.Advance, mt.Still, mt.Result, mt.Report
+			move.Advance, move.Still, move.Result = mt.Advance, mt.Still, mt.Result
 		}
 
 		// if the inner ring is present, parse it. this ring contains observations of the surrounding
@@ -669,16 +856,12 @@ func parseMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte
 					log.Printf("%s: %s: %d: step %d: deck %d: obs %q\n", fid, unitId, lineNo, move.StepNo, no+1, obs)
 					return nil, err
 				} else if deckObservation, ok := va.(NearHorizon_t); !ok {
-					log.Printf("%s: %s: %d: step %d: deck %q\n", fid, unitId, lineNo, move.StepNo, slug(innerRing, 44))
-					log.Printf("%s: %s: %d: step %d: deck %d: obs %q\n", fid, unitId, lineNo, move.StepNo, no+1, obs)
-					log.Printf("error: want NearHorizon_t, got %T\n", va)
-					log.Printf("please report this error\n")
-					panic(fmt.Sprintf("unexpected type %T", va))
+					return nil, unexpectedType(fid, unitId, lineNo, move.StepNo, obs, "NearHorizon_t", va)
 				} else {
-					move.Report.MergeBorders(&Border_t{
+					move.Report.MergeBorders(a.newBorder(Border_t{
 						Direction: deckObservation.Point,
 						Terrain:   deckObservation.Terrain,
-					})
+					}))
 				}
 			}
 		}
@@ -701,10 +884,7 @@ func parseMovementLine(fid, tid string, unitId UnitId_t, lineNo int, line []byte
 					log.Printf("%s: %s: %d: step %d: crow %d: %q\n", fid, unitId, lineNo, move.StepNo, crowNo, orStep)
 					return nil, err
 				} else if fh, ok := va.(FarHorizon_t); !ok {
-					log.Printf("%s: %s: %d: step %d: crow %d: %q\n", fid, unitId, lineNo, move.StepNo, crowNo, orStep)
-					log.Printf("error: want FarHorizon_t, got %T", va)
-					log.Printf("please report this error")
-					panic(fmt.Errorf("unexpected type %T", va))
+					return nil, unexpectedType(fid, unitId, lineNo, move.StepNo, orStep, "FarHorizon_t", va)
 				} else {
 					move.Report.mergeFarHorizons(fh)
 				}
@@ -768,8 +948,10 @@ func errslug(text []byte, width int) string {
 	return strings.ReplaceAll(fmt.Sprintf("%q", slug), "\\\\", "\\")
 }
 
-// parseMove parses a single step of a move, returning the results or an error
-func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte, isScout bool, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves bool, experimentalUnitSplit bool) (*Move_t, error) {
+// parseMove parses a single step of a move, returning the results or an error.
+// Observations are added to the report, which should belong to the step.
+// The move is returned by value because the caller only copies fields from it.
+func parseMove(a *arena_t, fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte, report *Report_t, isScout bool, acceptLoneDash, debugSteps, debugNodes, debugFleetMoves bool, experimentalUnitSplit bool) (Move_t, error) {
 
 	//debugSteps, debugNodes = true, true
 	line = bytes.TrimSpace(bytes.TrimRight(line, ","))
@@ -777,7 +959,7 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 		log.Printf("%s: %s: %d: step %d: %q\n", fid, unitId, lineNo, stepNo, line)
 	}
 
-	m := &Move_t{UnitId: unitId, LineNo: lineNo, StepNo: stepNo, Line: line, Report: &Report_t{TurnId: tid, UnitId: unitId}}
+	m := &Move_t{UnitId: unitId, LineNo: lineNo, StepNo: stepNo, Line: line, Report: report}
 	m.Debug.FleetMoves = debugFleetMoves
 
 	// each move should find at most one settlement
@@ -787,7 +969,7 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 	steps, err := nodesToSteps(root)
 	if err != nil {
 		log.Printf("parser: %s: %s: %d: step %d: %q\n", fid, unitId, lineNo, stepNo, line)
-		return nil, err
+		return Move_t{}, err
 	}
 
 	// parse and report on each step of this move separately.
@@ -814,14 +996,14 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 					}
 					log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
 					log.Printf("error: found lone dash on line; it must be removed\n")
-					return nil, fmt.Errorf("error parsing step")
+					return Move_t{}, fmt.Errorf("error parsing step")
 				}
 				if subStep[1] == '(' {
 					// probably a fleet movement result?
 				} else {
 					log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
 					log.Printf("error: found dash prefix on result; it must be removed\n")
-					return nil, fmt.Errorf("error parsing step")
+					return Move_t{}, fmt.Errorf("error parsing step")
 				}
 			}
 			// hack - an unrecognized step might be a settlement name
@@ -836,25 +1018,26 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 			if err != nil {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
 				log.Printf("error: %v\n", err)
-				return nil, fmt.Errorf("error parsing step")
+				return Move_t{}, fmt.Errorf("error parsing step")
 			}
 		}
 		switch v := obj.(type) {
 		case *BlockedByEdge_t:
 			if m.Result != results.Unknown { // only allowed at the beginning of the step
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("blocked by must start sub-step")
+				return Move_t{}, fmt.Errorf("blocked by must start sub-step")
 			}
 			m.Advance = v.Direction
 			m.Result = results.Failed
-			m.Report.MergeBorders(&Border_t{
+			m.Reason = results.Blocked
+			m.Report.MergeBorders(a.newBorder(Border_t{
 				Direction: v.Direction,
 				Edge:      v.Edge,
-			})
+			}))
 		case DirectionTerrain_t:
 			if m.Result != results.Unknown { // only allowed at the beginning of the step
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("multiple direction-terrain forbidden")
+				return Move_t{}, fmt.Errorf("multiple direction-terrain forbidden")
 			}
 			m.Advance = v.Direction
 			m.Result = results.Succeeded
@@ -862,32 +1045,36 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 		case []*Edge_t:
 			if m.Result == results.Unknown {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("edges forbidden at beginning of step")
+				return Move_t{}, fmt.Errorf("edges forbidden at beginning of step")
 			}
 			for _, edge := range v {
-				m.Report.MergeBorders(&Border_t{
+				m.Report.MergeBorders(a.newBorder(Border_t{
 					Direction: edge.Direction,
 					Edge:      edge.Edge,
-				})
+				}))
 			}
 		case *Exhausted_t:
 			if m.Result != results.Unknown { // only allowed at the beginning of the step
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("exhaustion must start step")
+				return Move_t{}, fmt.Errorf("exhaustion must start step")
 			}
 			m.Advance = v.Direction
 			m.Result = results.Failed
+			m.Reason = results.ExhaustedMovementPoints
 			// fleet movements can end up exhausted in an unknown direction and with no terrain.
 			// if we were smart enough to look back at the wind direction, we could use that,
 			// but we're not, and we still wouldn't know what to do with the terrain.
 			if v.Direction == direction.Unknown && v.Terrain == terrain.Blank {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q: fleet exhausted?\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
 			} else {
-				m.Report.MergeBorders(&Border_t{
+				m.Report.MergeBorders(a.newBorder(Border_t{
 					Direction: v.Direction,
 					Terrain:   v.Terrain,
-				})
+				}))
 			}
+		case DidNotReturn_t:
+			// the unit is lost in the hex it was in when the step started
+			m.Result, m.Advance = results.Vanished, direction.Unknown
 		case FoundItem_t: // ignore
 			// log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
 		case FoundNothing_t:
@@ -899,13 +1086,13 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 		case FoundUnit_t:
 			if m.Result == results.Unknown {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return m, fmt.Errorf("units forbidden at beginning of step")
+				return *m, fmt.Errorf("units forbidden at beginning of step")
 			}
 			m.Report.MergeEncounters(&Encounter_t{TurnId: tid, UnitId: v.Id})
 		case []FoundUnit_t:
 			if m.Result == results.Unknown {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("units forbidden at beginning of step")
+				return Move_t{}, fmt.Errorf("units forbidden at beginning of step")
 			}
 			for _, unit := range v {
 				m.Report.MergeEncounters(&Encounter_t{TurnId: tid, UnitId: unit.Id})
@@ -916,13 +1103,13 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 		case []*Neighbor_t:
 			if m.Result == results.Unknown {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("neighbors forbidden at beginning of step")
+				return Move_t{}, fmt.Errorf("neighbors forbidden at beginning of step")
 			}
 			for _, neighbor := range v {
-				m.Report.MergeBorders(&Border_t{
+				m.Report.MergeBorders(a.newBorder(Border_t{
 					Direction: neighbor.Direction,
 					Terrain:   neighbor.Terrain,
-				})
+				}))
 			}
 		case *Patrolled_t:
 			if m.Result == results.Unknown { // this is very likely the first item in a "Patrolled and found" step
@@ -934,59 +1121,55 @@ func parseMove(fid, tid string, unitId UnitId_t, lineNo, stepNo int, line []byte
 		case *ProhibitedFrom_t:
 			if m.Result != results.Unknown { // only allowed at the beginning of the step
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("prohibition must start step")
+				return Move_t{}, fmt.Errorf("prohibition must start step")
 			}
 			m.Advance = v.Direction
 			m.Result = results.Failed
-			m.Report.MergeBorders(&Border_t{
+			m.Reason = results.Prohibited
+			m.Report.MergeBorders(a.newBorder(Border_t{
 				Direction: v.Direction,
 				Terrain:   v.Terrain,
-			})
+			}))
 		case resources.Resource_e:
 			if m.Result == results.Unknown {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("resources forbidden at beginning of step")
+				return Move_t{}, fmt.Errorf("resources forbidden at beginning of step")
 			}
 			m.Report.MergeResources(v)
 		case *Settlement_t:
 			if m.Result == results.Unknown {
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("settlement forbidden at beginning of step")
+				return Move_t{}, fmt.Errorf("settlement forbidden at beginning of step")
+			}
+			if v.TurnId == "" {
+				v.TurnId = tid
 			}
 			m.Report.MergeSettlements(v)
 		case terrain.Terrain_e:
 			if m.Result != results.Unknown { // valid only at the beginning of the step for status line
 				log.Printf("%s: %s: %d: step %d: sub %d: %q\n", fid, unitId, lineNo, stepNo, subStepNo, subStep)
-				return nil, fmt.Errorf("terrain must start status")
+				return Move_t{}, fmt.Errorf("terrain must start status")
 			}
 			m.Result, m.Still = results.Succeeded, true
 			m.Report.Terrain = v
 		default:
-			log.Printf("error: unexpected input while parsing movement\n")
-			log.Printf("error: turn %q\n", fid)
-			log.Printf("error: unit %q\n", unitId)
-			log.Printf("error: input: line %d\n", lineNo)
-			log.Printf("error: input: text %s\n", errslug(line, 58))
-			log.Printf("error: move: step %d: sub-step %d\n", stepNo, subStepNo)
-			log.Printf("error: found type %T\n", v)
-			log.Printf("please report this error\n")
-			panic(fmt.Sprintf("unexpected %T", v))
+			return Move_t{}, unexpectedType(fid, unitId, lineNo, stepNo, subStep, "step result", v)
 		}
 	}
 
-	return m, nil
+	return *m, nil
 }
 
 // splitMoves splits the line into individual moves. moves are separated by backslashes.
 // leading and trailing spaces and any trailing commas are from each move.
-func splitMoves(fid, tid string, unitId UnitId_t, lineNo int, line []byte) (moves []*Move_t) {
+func splitMoves(a *arena_t, fid, tid string, unitId UnitId_t, lineNo int, line []byte) (moves []*Move_t) {
 	line = bytes.TrimSpace(bytes.TrimRight(line, " \t\\,"))
 	if len(line) == 0 {
 		return nil
 	}
 	for n, text := range bytes.Split(line, []byte{'\\'}) {
 		text = bytes.TrimSpace(bytes.TrimRight(text, ", \t"))
-		moves = append(moves, &Move_t{UnitId: unitId, LineNo: lineNo, StepNo: n + 1, Line: bdup(text), Report: &Report_t{TurnId: tid, UnitId: unitId}})
+		moves = append(moves, a.newMove(Move_t{UnitId: unitId, LineNo: lineNo, StepNo: n + 1, Line: bdup(text), Report: a.newReport(tid, unitId)}))
 	}
 	return moves
 }
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+)
+
+// isSectionHeader returns true if the line starts a unit section.
+func isSectionHeader(line []byte) bool {
+	return rxCourierSection.Match(line) ||
+		rxElementSection.Match(line) ||
+		rxFleetSection.Match(line) ||
+		rxGarrisonSection.Match(line) ||
+		rxTribeSection.Match(line)
+}
+
+// splitSections returns the index of the first line of every unit section.
+func splitSections(lines [][]byte) (starts []int) {
+	for n, line := range lines {
+		if isSectionHeader(line) {
+			starts = append(starts, n)
+		}
+	}
+	return starts
+}
+
+// section_t is the result of parsing a single unit section.
+type section_t struct {
+	firstLine int // index of the section header
+	lines     [][]byte
+	t         *Turn_t // units, special names, and errors found in the section
+	stopped   bool
+	err       error
+}
+
+// parseSectionsConcurrently parses the unit sections with a pool of workers
+// and merges the results into the parser's turn in the order of the report.
+//
+// The lines up to the end of the first unit section are parsed first, so that
+// every worker knows the turn id and report format. Merging stops at the first
+// section that stopped early or failed, just as sequential parsing would. The
+// errors from all failed sections are returned, in report order.
+//
+// Log messages from the workers may be interleaved.
+func parseSectionsConcurrently(p *sectionParser_t, lines [][]byte, workers int) error {
+	starts := splitSections(lines)
+	if len(starts) < 3 {
+		// not worth the overhead
+		_, err := p.parseLines(1, lines)
+		return err
+	}
+
+	if stopped, err := p.parseLines(1, lines[:starts[1]]); err != nil || stopped {
+		return err
+	}
+	t := p.t
+	if p.cfg.Ignore.Scouts && !p.cfg.Ignore.Logged.Scouts {
+		log.Printf("%s: ignoring scouts\n", p.fid)
+		p.cfg.Ignore.Logged.Scouts = true
+	}
+
+	var sections []*section_t
+	for n := 1; n < len(starts); n++ {
+		end := len(lines)
+		if n+1 < len(starts) {
+			end = starts[n+1]
+		}
+		sections = append(sections, &section_t{firstLine: starts[n], lines: lines[starts[n]:end]})
+	}
+
+	work := make(chan *section_t)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < workers && w < len(sections); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			a := &arena_t{} // arenas are not safe for concurrent use
+			for section := range work {
+				sp := *p
+				sp.a = a
+				sp.t = &Turn_t{
+					Id:        t.Id,
+					Year:      t.Year,
+					Month:     t.Month,
+					Season:    t.Season,
+					Weather:   t.Weather,
+					Format:    t.Format,
+					UnitMoves: map[UnitId_t]*Moves_t{},
+				}
+				section.t = sp.t
+				section.stopped, section.err = sp.parseLines(section.firstLine+1, section.lines)
+			}
+		}()
+	}
+	for _, section := range sections {
+		work <- section
+	}
+	close(work)
+	wg.Wait()
+
+	var errs []error
+	merging := true
+	for _, section := range sections {
+		if section.err != nil {
+			errs = append(errs, section.err)
+		}
+		if !merging {
+			continue
+		}
+		if err := mergeSection(p.fid, t, section); err != nil {
+			errs = append(errs, err)
+			merging = false
+		} else if section.err != nil || section.stopped {
+			merging = false
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// mergeSection adds the units, special names, and errors from the section to the turn.
+func mergeSection(fid string, t *Turn_t, section *section_t) error {
+	if t.Id == "" && section.t.Id != "" {
+		t.Id, t.Year, t.Month = section.t.Id, section.t.Year, section.t.Month
+		t.Season, t.Weather = section.t.Season, section.t.Weather
+	} else if section.t.Id != "" && section.t.Id != t.Id {
+		log.Printf("%s: %d: current turn: %s", fid, section.firstLine+1, t.Id)
+		log.Printf("%s: %d:    unit turn: %s", fid, section.firstLine+1, section.t.Id)
+		return fmt.Errorf("turn mismatch in report")
+	}
+
+	// sort the unit ids so that duplicates are reported the same way every time
+	var unitIds []UnitId_t
+	for unitId := range section.t.UnitMoves {
+		unitIds = append(unitIds, unitId)
+	}
+	sort.Slice(unitIds, func(i, j int) bool {
+		return unitIds[i] < unitIds[j]
+	})
+	for _, unitId := range unitIds {
+		if _, ok := t.UnitMoves[unitId]; ok {
+			log.Printf("%s: %s: %d: location %q\n", fid, unitId, section.firstLine+1, slug(section.lines[0], 15))
+			return fmt.Errorf("duplicate unit in turn")
+		}
+		t.UnitMoves[unitId] = section.t.UnitMoves[unitId]
+	}
+
+	for id, special := range section.t.SpecialNames {
+		if t.SpecialNames == nil {
+			t.SpecialNames = make(map[string]*Special_t)
+		}
+		t.SpecialNames[id] = special
+	}
+	t.Errors = append(t.Errors, section.t.Errors...)
+	return nil
+}
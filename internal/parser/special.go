@@ -0,0 +1,150 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"strings"
+)
+
+// Directive_e is an instruction for drawing a special hex.
+//
+// Directives follow the name on a ">>>>" line, each one starting with "!":
+//
+//	>>>>id>name>!icon Settlement Ruins>!note taken in 0902-04>!capital>!hide
+//
+// The name may be left out, in which case the id is used.
+// The "!at GRID" directive isn't one of these; it sets the location of the special hex.
+type Directive_e int
+
+const (
+	UnknownDirective Directive_e = iota
+	IconDirective                // draw the hex with this Worldographer feature
+	NoteDirective                // pin the text to the hex as a note
+	CapitalDirective             // draw the hex as a capital
+	HideDirective                // don't draw the label
+)
+
+var (
+	// DirectiveToString is a helper map for marshalling the enum
+	DirectiveToString = map[Directive_e]string{
+		UnknownDirective: "?",
+		IconDirective:    "icon",
+		NoteDirective:    "note",
+		CapitalDirective: "capital",
+		HideDirective:    "hide",
+	}
+	// StringToDirective is a helper map for unmarshalling the enum
+	StringToDirective = map[string]Directive_e{
+		"icon":    IconDirective,
+		"note":    NoteDirective,
+		"capital": CapitalDirective,
+		"hide":    HideDirective,
+	}
+)
+
+func (e Directive_e) String() string {
+	if str, ok := DirectiveToString[e]; ok {
+		return str
+	}
+	return fmt.Sprintf("Directive_e(%d)", int(e))
+}
+
+// Directive_t is a directive from a ">>>>" line.
+// Value is the argument for icons and notes and empty for the others.
+type Directive_t struct {
+	Kind  Directive_e
+	Value string
+}
+
+func (d *Directive_t) String() string {
+	if d.Value == "" {
+		return "!" + d.Kind.String()
+	}
+	return "!" + d.Kind.String() + " " + d.Value
+}
+
+// ParseSpecialHex returns the special hex from a ">>>>id>name>!directive" line.
+// It returns an error for an unknown directive, a missing or unexpected argument,
+// or an invalid location. The special hex is still returned, without directives
+// or location, so that the name can be used.
+func ParseSpecialHex(turnId string, line []byte) (*Special_t, error) {
+	id, name := ParseSpecialHexLine(line)
+	special := &Special_t{TurnId: turnId, Id: string(id), Name: string(name)}
+	directives, location, err := parseSpecialHexDirectives(line)
+	if err != nil {
+		return special, err
+	}
+	special.Directives, special.Location = directives, location
+	return special, nil
+}
+
+// parseSpecialHexDirectives returns the directives and the location from a ">>>>" line.
+func parseSpecialHexDirectives(line []byte) (directives []*Directive_t, location coords.Map, err error) {
+	for _, field := range specialHexFields(line)[1:] {
+		text, ok := bytes.CutPrefix(field, []byte{'!'})
+		if !ok {
+			continue
+		}
+		keyword, value, _ := bytes.Cut(text, []byte{' '})
+		if strings.ToLower(string(keyword)) == "at" {
+			if location, err = coords.HexToMap(strings.ToUpper(strings.TrimSpace(string(value)))); err != nil {
+				return nil, coords.Map{}, fmt.Errorf("%q: %w", field, err)
+			}
+			continue
+		}
+		kind, ok := StringToDirective[string(bytes.ToLower(keyword))]
+		if !ok {
+			return nil, coords.Map{}, fmt.Errorf("unknown directive %q", field)
+		}
+		d := &Directive_t{Kind: kind, Value: string(bytes.TrimSpace(value))}
+		switch kind {
+		case IconDirective, NoteDirective:
+			if d.Value == "" {
+				return nil, coords.Map{}, fmt.Errorf("%q: missing argument", field)
+			}
+		default:
+			if d.Value != "" {
+				return nil, coords.Map{}, fmt.Errorf("%q: unexpected argument", field)
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, location, nil
+}
+
+// SpecialKey is the key for a special hex in a turn's SpecialNames.
+// A special hex with a location is keyed by the id and the grid coordinates,
+// so that places with the same name in different hexes don't collide.
+func SpecialKey(id string, location coords.Map) string {
+	if location.IsZero() {
+		return id
+	}
+	return id + "@" + location.GridString()
+}
+
+// LookupSpecial returns the special hex for a settlement seen in a hex.
+// A special hex for that hex is preferred to one that matches the name anywhere.
+func LookupSpecial(names map[string]*Special_t, name string, at coords.Map) (*Special_t, bool) {
+	if len(names) == 0 {
+		return nil, false
+	}
+	id := strings.ToLower(name)
+	if special, ok := names[SpecialKey(id, at)]; ok && !at.IsZero() {
+		return special, true
+	}
+	special, ok := names[id]
+	return special, ok
+}
+
+// specialHexFields splits a ">>>>" line on ">", trimming the spaces around each field.
+// The first field is the id.
+func specialHexFields(line []byte) [][]byte {
+	fields := bytes.Split(bytes.TrimPrefix(line, []byte{'>', '>', '>', '>'}), []byte{'>'})
+	for n, field := range fields {
+		fields[n] = bytes.TrimSpace(field)
+	}
+	return fields
+}
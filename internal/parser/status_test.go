@@ -0,0 +1,33 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"testing"
+)
+
+func TestStatusText(t *testing.T) {
+	turn := parse(t, "0902-02", "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n"+
+		"Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n"+
+		"Tribe Movement: Move N-PR\\\n"+
+		"0138 Status: GRASSY HILLS, Village Bravo, O NW, 0138, 0138e1\n"+
+		"Element 0138e1, , Current Hex = QQ 1008, (Previous Hex = QQ 1009)\n"+
+		"Current Turn 902-02 (#26), Winter, FINE\n"+
+		"Tribe Movement: Move N-PR\\\n")
+	for _, tc := range []struct {
+		id     int
+		unitId parser.UnitId_t
+		want   string
+	}{
+		{1, "0138", "GRASSY HILLS, Village Bravo, O NW, 0138, 0138e1"},
+		{2, "0138e1", ""},
+	} {
+		moves, ok := turn.UnitMoves[tc.unitId]
+		if !ok {
+			t.Errorf("%d: %s: want moves, got none", tc.id, tc.unitId)
+		} else if moves.Status != tc.want {
+			t.Errorf("%d: %s: status: want %q, got %q", tc.id, tc.unitId, tc.want, moves.Status)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/playbymail/ottomap/internal/resources"
 	"github.com/playbymail/ottomap/internal/results"
 	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/winds"
 	"sort"
 	"strings"
 )
@@ -24,6 +25,14 @@ type Turn_t struct {
 	Year  int
 	Month int
 
+	// Format is the report format forced by the caller.
+	// When nil, the format is selected from the turn id.
+	Format *Format_t
+
+	// Season and Weather are taken from the "Current Turn" line.
+	Season  string
+	Weather string
+
 	// UnitMoves holds the units that moved in this turn
 	UnitMoves   map[UnitId_t]*Moves_t
 	SortedMoves []*Moves_t
@@ -33,6 +42,10 @@ type Turn_t struct {
 	// They are added to the map when parsing and are forced to lower case.
 	SpecialNames map[string]*Special_t
 
+	// Errors holds the internal errors found while parsing.
+	// The parser skips the rest of a unit's section after one of these.
+	Errors []*InternalError_t
+
 	Next, Prev *Turn_t
 }
 
@@ -41,7 +54,15 @@ func (t *Turn_t) FromMayBeObscured() bool {
 }
 
 func (t *Turn_t) ToMayBeObscured() bool {
-	return t.Id <= LastTurnCurrentLocationObscured
+	return t.ReportFormat().ObscuredCurrentHex
+}
+
+// ReportFormat returns the format of the reports for the turn.
+func (t *Turn_t) ReportFormat() *Format_t {
+	if t.Format != nil {
+		return t.Format
+	}
+	return FormatForTurn(t.Id)
 }
 
 // TopoSortMoves sorts the moves in the turn in a way that guarantees that units that depend on other units will be sorted last.
@@ -99,6 +120,17 @@ type Moves_t struct {
 	// Scouts are optional and move at the end of the turn
 	Scouts []*Scout_t
 
+	// Events are the units this unit created or disbanded this turn
+	Events []*UnitEvent_t
+
+	// Owners are the owners of settlements in the unit's current hex
+	Owners []*SettlementOwner_t
+
+	// Status is the text from the unit's status line, without the "Status:" prefix.
+	Status string
+	// Inventory is set if the status line reports people, animals, or goods.
+	Inventory *Inventory_t
+
 	// FromHex is the hex the unit starts the move in.
 	// This could be "N/A" if the unit was created this turn.
 	// In that case, we will populate it when we know where the unit started.
@@ -111,6 +143,8 @@ type Moves_t struct {
 
 	// Location is the tile the unit ends the move in
 	Location coords.Map
+	// StartLocation is the tile the unit starts the turn in. It is set by the walk.
+	StartLocation coords.Map
 }
 
 // Move_t represents a single move by a unit.
@@ -125,11 +159,26 @@ type Move_t struct {
 	GoesTo  string                // hex teleporting to
 	Still   bool                  // true if the unit is not moving (garrison) or a status entry
 
+	// Wind is set only for fleet movement.
+	Wind Wind_t
+
 	// Result should be failed, succeeded, or vanished
 	Result results.Result_e
+	// Reason is set when the move fails. It is Blocked, ExhaustedMovementPoints,
+	// or Prohibited when the report tells us why, otherwise it is Unknown.
+	Reason results.Result_e
 
 	Report *Report_t // all observations made by the unit at the end of this move
 
+	// MP is the estimated movement point accounting for an advance. It is set
+	// after the walk, and only if the cost of every step so far could be derived.
+	MP struct {
+		Known     bool
+		Cost      int    // movement points needed for the step
+		Remaining int    // movement points left after the step, or before it if the step failed
+		Warning   string // set if the result in the report disagrees with the estimate
+	}
+
 	LineNo int
 	StepNo int
 	Line   []byte
@@ -297,6 +346,38 @@ type FarHorizon_t struct {
 	Terrain terrain.Terrain_e
 }
 
+// Inventory_t is the count of people, animals, and goods reported on a status line.
+type Inventory_t struct {
+	People  int
+	Animals map[items.Item_e]int
+	Goods   map[items.Item_e]int
+}
+
+// String returns the inventory as "120 People, 30 Horses, 10 Wagons".
+// Animals and goods are sorted by name.
+func (i *Inventory_t) String() string {
+	if i == nil {
+		return ""
+	}
+	var list []string
+	if i.People != 0 {
+		list = append(list, fmt.Sprintf("%d People", i.People))
+	}
+	for _, m := range []map[items.Item_e]int{i.Animals, i.Goods} {
+		var names []string
+		for item, qty := range m {
+			names = append(names, fmt.Sprintf("%d %s", qty, item))
+		}
+		sort.Slice(names, func(a, b int) bool {
+			_, na, _ := strings.Cut(names[a], " ")
+			_, nb, _ := strings.Cut(names[b], " ")
+			return na < nb
+		})
+		list = append(list, names...)
+	}
+	return strings.Join(list, ", ")
+}
+
 // FoundItem_t represents items discovered by Scouts as they pass through a hex.
 type FoundItem_t struct {
 	Quantity int
@@ -371,8 +452,10 @@ type Scout_t struct {
 
 // Settlement_t is a settlement that the unit sees in the current hex.
 type Settlement_t struct {
-	TurnId string // turn the settlement was observed
-	Name   string
+	TurnId  string // turn the settlement was observed
+	Name    string
+	Owner   UnitId_t       // clan that controls the settlement, empty if not known
+	History []*Ownership_t // changes of owner, oldest first
 }
 
 func (s *Settlement_t) String() string {
@@ -383,18 +466,65 @@ func (s *Settlement_t) String() string {
 }
 
 type Special_t struct {
-	TurnId string // turn the special hex was observed
-	Id     string // id of the special hex, full name converted to lower case
-	Name   string // short name of the special hex (id if name is empty)
+	TurnId     string         // turn the special hex was observed
+	Id         string         // id of the special hex, full name converted to lower case
+	Name       string         // short name of the special hex (id if name is empty)
+	Location   coords.Map     // hex the name applies to, zero if it applies to every hex with the name
+	Directives []*Directive_t // how to draw the hex, from the ">>>>" line
+}
+
+// Key returns the key for the special hex in a turn's SpecialNames.
+func (s *Special_t) Key() string {
+	return SpecialKey(s.Id, s.Location)
+}
+
+// Has returns true if the special hex has the directive.
+func (s *Special_t) Has(kind Directive_e) bool {
+	for _, d := range s.Directives {
+		if d.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Values returns the values of the directives of the kind, in order.
+func (s *Special_t) Values(kind Directive_e) []string {
+	var values []string
+	for _, d := range s.Directives {
+		if d.Kind == kind {
+			values = append(values, d.Value)
+		}
+	}
+	return values
+}
+
+// Wind_t is the wind reported at the start of a fleet movement line.
+type Wind_t struct {
+	Strength winds.Strength_e
+	From     direction.Direction_e
+}
+
+func (w Wind_t) String() string {
+	if w.Strength == winds.Unknown {
+		return ""
+	}
+	return fmt.Sprintf("%s %s", w.Strength, w.From)
 }
 
 type UnitId_t string
 
-func (u UnitId_t) InClan(clan UnitId_t) bool {
+// Clan returns the id of the clan that the unit belongs to.
+// The clan of tribe 1138 and of element 1138e1 is 0138.
+func (u UnitId_t) Clan() UnitId_t {
 	if len(u) != 4 {
-		return u.Parent().Parent() == clan
+		return u.Parent().Parent()
 	}
-	return u.Parent() == clan
+	return u.Parent()
+}
+
+func (u UnitId_t) InClan(clan UnitId_t) bool {
+	return u.Clan() == clan
 }
 
 func (u UnitId_t) IsFleet() bool {
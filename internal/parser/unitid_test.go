@@ -0,0 +1,30 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"testing"
+)
+
+func TestUnitIdClan(t *testing.T) {
+	for _, tc := range []struct {
+		id   int
+		unit parser.UnitId_t
+		want parser.UnitId_t
+	}{
+		{1, "0138", "0138"},
+		{2, "1138", "0138"},
+		{3, "0138e1", "0138"},
+		{4, "1590e1", "0590"},
+		{5, "2590c3", "0590"},
+		{6, "1590f9", "0590"},
+	} {
+		if got := tc.unit.Clan(); got != tc.want {
+			t.Errorf("%d: %s: clan: want %q, got %q", tc.id, tc.unit, tc.want, got)
+		}
+		if !tc.unit.InClan(tc.want) {
+			t.Errorf("%d: %s: in clan %s: want true, got false", tc.id, tc.unit, tc.want)
+		}
+	}
+}
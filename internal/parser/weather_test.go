@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package parser_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"testing"
+)
+
+func TestSeasonWeatherWinds(t *testing.T) {
+	turn := parse(t, "0902-02", "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n"+
+		"Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n"+
+		"Tribe Movement: Move N-PR\\\n"+
+		"Fleet 0138f1, , Current Hex = QQ 0808, (Previous Hex = QQ 0810)\n"+
+		"Current Turn 902-02 (#26), Winter, FINE\n"+
+		"CALM NE Fleet Movement: Move NE-O,-(NE O,  SE O)(Sight Land - N/NE, Sight Water - NW/NW)\\NE-O,-(N O)(Sight Water - N/N)\\\n")
+	if turn.Season != "Winter" || turn.Weather != "FINE" {
+		t.Errorf("season: want Winter FINE, got %q %q", turn.Season, turn.Weather)
+	}
+
+	for _, tc := range []struct {
+		id     int
+		unitId parser.UnitId_t
+		want   string
+	}{
+		{1, "0138", ""},
+		{2, "0138f1", "CALM NE"},
+	} {
+		moves, ok := turn.UnitMoves[tc.unitId]
+		if !ok || len(moves.Moves) == 0 {
+			t.Errorf("%d: %s: want moves, got none", tc.id, tc.unitId)
+			continue
+		}
+		for _, move := range moves.Moves {
+			if got := move.Wind.String(); got != tc.want {
+				t.Errorf("%d: %s: step %d: wind: want %q, got %q", tc.id, tc.unitId, move.StepNo, tc.want, got)
+			}
+		}
+	}
+}
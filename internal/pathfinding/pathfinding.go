@@ -0,0 +1,276 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package pathfinding implements movement cost calculations over the merged tile map.
+package pathfinding
+
+import (
+	"container/heap"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+)
+
+const (
+	// DefaultMovementPoints is the number of movement points we assume
+	// a land unit has when the caller doesn't tell us otherwise.
+	DefaultMovementPoints = 30
+
+	// riverCrossingCost is added when crossing a river without a ford.
+	riverCrossingCost = 3
+)
+
+// landCosts are the movement points needed for a land unit to enter a hex.
+// These are taken from the player's guide where possible. Terrain that isn't
+// in this table (water, blank, and unknown terrain) can't be entered.
+var landCosts = map[terrain.Terrain_e]int{
+	terrain.Alps:                 12,
+	terrain.AridHills:            5,
+	terrain.AridTundra:           3,
+	terrain.BrushFlat:            4,
+	terrain.BrushHills:           5,
+	terrain.ConiferHills:         6,
+	terrain.Deciduous:            5,
+	terrain.DeciduousHills:       6,
+	terrain.Desert:               4,
+	terrain.GrassyHills:          5,
+	terrain.GrassyHillsPlateau:   5,
+	terrain.HighSnowyMountains:   12,
+	terrain.Jungle:               6,
+	terrain.JungleHills:          7,
+	terrain.LowAridMountains:     10,
+	terrain.LowConiferMountains:  10,
+	terrain.LowJungleMountains:   10,
+	terrain.LowSnowyMountains:    10,
+	terrain.LowVolcanicMountains: 10,
+	terrain.PolarIce:             8,
+	terrain.Prairie:              3,
+	terrain.PrairiePlateau:       3,
+	terrain.RockyHills:           5,
+	terrain.SnowyHills:           6,
+	terrain.Swamp:                8,
+	terrain.Tundra:               3,
+}
+
+// MovementCost returns the movement points a land unit spends to enter
+// a hex with the given terrain. It returns false if the hex can't be entered.
+func MovementCost(t terrain.Terrain_e) (int, bool) {
+	cost, ok := landCosts[t]
+	return cost, ok
+}
+
+// StepCost returns the cost for a land unit to move from one tile into the
+// neighboring tile in the given direction. Edges are checked on both sides of
+// the border since the reports may have recorded them on either tile.
+// It returns false if the move isn't allowed.
+func StepCost(from, to *tiles.Tile_t, d direction.Direction_e) (int, bool) {
+	if from == nil || to == nil {
+		return 0, false
+	}
+	cost, ok := MovementCost(to.Terrain)
+	if !ok {
+		return 0, false
+	}
+	hasEdge := func(e edges.Edge_e) bool {
+		for _, l := range from.Edges[d] {
+			if l == e {
+				return true
+			}
+		}
+		for _, l := range to.Edges[d.Opposite()] {
+			if l == e {
+				return true
+			}
+		}
+		return false
+	}
+	if hasEdge(edges.StoneRoad) {
+		// roads cut the cost in half, rounded up
+		return (cost + 1) / 2, true
+	}
+	if to.Terrain.IsAnyMountain() && hasEdge(edges.Pass) {
+		cost = cost / 2
+	}
+	if hasEdge(edges.River) && !hasEdge(edges.Ford) {
+		cost += riverCrossingCost
+	}
+	return cost, true
+}
+
+// Reachable returns all the tiles that a land unit starting at the origin
+// could reach by spending no more than the given movement points.
+// The value in the map is the cheapest cost to reach the tile.
+// The origin is always included with a cost of zero.
+//
+// Only tiles in the world map are considered; we never path through
+// hexes that we haven't seen.
+func Reachable(worldMap *tiles.Map_t, origin coords.Map, movementPoints int) map[coords.Map]int {
+	reached := map[coords.Map]int{}
+	if worldMap == nil || worldMap.Tiles[origin] == nil {
+		return reached
+	}
+
+	pq := &queue{{location: origin, cost: 0}}
+	reached[origin] = 0
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(*queueItem)
+		if cost, ok := reached[item.location]; ok && cost < item.cost {
+			// we've already found a cheaper path to this tile
+			continue
+		}
+		from := worldMap.Tiles[item.location]
+		for _, d := range direction.Directions {
+			neighbor := worldMap.World.Add(item.location, d)
+			stepCost, ok := StepCost(from, worldMap.Tiles[neighbor], d)
+			if !ok {
+				continue
+			}
+			cost := item.cost + stepCost
+			if cost > movementPoints {
+				continue
+			} else if prior, ok := reached[neighbor]; ok && prior <= cost {
+				continue
+			}
+			reached[neighbor] = cost
+			heap.Push(pq, &queueItem{location: neighbor, cost: cost})
+		}
+	}
+
+	return reached
+}
+
+// Account estimates the movement points spent and left for each advance by the
+// land units in the turns, starting each unit with the budget, and records them
+// on the moves. A step that the report says failed for lack of movement points
+// while the estimate says there were enough left, or a step that succeeded
+// after the estimate ran out, gets a warning. The warnings are also returned.
+//
+// Fleets, scouts, and units that follow or go to another hex are skipped.
+// Accounting for a unit stops at the first step that can't be costed,
+// for example because the hex isn't on the map.
+func Account(turns []*parser.Turn_t, worldMap *tiles.Map_t, budget int) (warnings []string) {
+	for _, turn := range turns {
+		for _, moves := range turn.SortedMoves {
+			if moves.UnitId.IsFleet() || moves.Follows != "" || moves.GoesTo != "" || moves.StartLocation.IsZero() {
+				continue
+			}
+			from, remaining := moves.StartLocation, budget
+			for stepNo, move := range moves.Moves {
+				if move.Advance == direction.Unknown {
+					continue
+				}
+				to := worldMap.World.Add(from, move.Advance)
+				cost, ok := StepCost(worldMap.Tiles[from], worldMap.Tiles[to], move.Advance)
+				if !ok {
+					break
+				}
+				move.MP.Known, move.MP.Cost = true, cost
+				switch move.Result {
+				case results.Succeeded:
+					remaining -= cost
+					if remaining < 0 {
+						move.MP.Warning = fmt.Sprintf("step %d %s succeeded, but the estimate is %d MP over budget", stepNo+1, move.Advance, -remaining)
+					}
+					from = to
+				case results.Failed:
+					if move.Reason == results.ExhaustedMovementPoints && remaining >= cost {
+						move.MP.Warning = fmt.Sprintf("step %d %s: not enough MP, but the estimate has %d left for a cost of %d", stepNo+1, move.Advance, remaining, cost)
+					}
+				}
+				move.MP.Remaining = remaining
+				if move.MP.Warning != "" {
+					warnings = append(warnings, fmt.Sprintf("%s: %-6s: %s", turn.Id, moves.UnitId, move.MP.Warning))
+				}
+				if move.Result != results.Succeeded {
+					break
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// Route_t is the cheapest way to reach a tile from one of several origins.
+type Route_t struct {
+	Origin coords.Map // origin the route starts at
+	Cost   int        // movement points spent along the route
+}
+
+// Nearest returns the cheapest route from any of the origins to every tile
+// that a land unit could reach from them, with no limit on movement points.
+// Origins that aren't on the world map are ignored. Ties go to the origin
+// that was listed first.
+func Nearest(worldMap *tiles.Map_t, origins []coords.Map) map[coords.Map]Route_t {
+	reached := map[coords.Map]Route_t{}
+	if worldMap == nil {
+		return reached
+	}
+
+	pq := &queue{}
+	for _, origin := range origins {
+		if _, ok := reached[origin]; ok || worldMap.Tiles[origin] == nil {
+			continue
+		}
+		reached[origin] = Route_t{Origin: origin}
+		heap.Push(pq, &queueItem{location: origin, origin: origin, cost: 0, order: pq.Len()})
+	}
+	for pq.Len() > 0 {
+		item := heap.Pop(pq).(*queueItem)
+		if route, ok := reached[item.location]; ok && (route.Cost < item.cost || route.Origin != item.origin) {
+			// we've already found a cheaper path to this tile
+			continue
+		}
+		from := worldMap.Tiles[item.location]
+		for _, d := range direction.Directions {
+			neighbor := worldMap.World.Add(item.location, d)
+			stepCost, ok := StepCost(from, worldMap.Tiles[neighbor], d)
+			if !ok {
+				continue
+			}
+			cost := item.cost + stepCost
+			if prior, ok := reached[neighbor]; ok && prior.Cost <= cost {
+				continue
+			}
+			reached[neighbor] = Route_t{Origin: item.origin, Cost: cost}
+			heap.Push(pq, &queueItem{location: neighbor, origin: item.origin, cost: cost, order: item.order})
+		}
+	}
+
+	return reached
+}
+
+type queueItem struct {
+	location coords.Map
+	origin   coords.Map // used by Nearest to track where the path started
+	cost     int
+	order    int // position of the origin in the list, used to break ties
+}
+
+// queue implements heap.Interface and holds the tiles we haven't expanded yet.
+type queue []*queueItem
+
+func (q queue) Len() int { return len(q) }
+func (q queue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].order < q[j].order
+}
+func (q queue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *queue) Push(x any) {
+	*q = append(*q, x.(*queueItem))
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package pathfinding_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestReachable(t *testing.T) {
+	// build a small map: origin is prairie, north is prairie, north-north is ocean,
+	// south is swamp behind a river.
+	origin := coords.Map{Column: 10, Row: 10}
+	worldMap := tiles.NewMap()
+	worldMap.FetchTile("", origin).Terrain = terrain.Prairie
+	worldMap.FetchTile("", origin.Add(direction.North)).Terrain = terrain.Prairie
+	worldMap.FetchTile("", origin.Move(direction.North, direction.North)).Terrain = terrain.Ocean
+	worldMap.FetchTile("", origin.Add(direction.South)).Terrain = terrain.Swamp
+	worldMap.Tiles[origin].Edges[direction.South] = []edges.Edge_e{edges.River}
+
+	tests := []struct {
+		id       int
+		mp       int
+		location coords.Map
+		want     int
+		ok       bool
+	}{
+		{1, 0, origin, 0, true},
+		{2, 2, origin.Add(direction.North), 0, false},
+		{3, 3, origin.Add(direction.North), 3, true},
+		{4, 30, origin.Move(direction.North, direction.North), 0, false},
+		{5, 10, origin.Add(direction.South), 0, false},
+		{6, 11, origin.Add(direction.South), 11, true},
+		{7, 30, origin.Add(direction.NorthEast), 0, false},
+	}
+	for _, tt := range tests {
+		reached := pathfinding.Reachable(worldMap, origin, tt.mp)
+		got, ok := reached[tt.location]
+		if ok != tt.ok {
+			t.Errorf("%d: %s: reachable: want %v, got %v", tt.id, tt.location.GridString(), tt.ok, ok)
+		} else if got != tt.want {
+			t.Errorf("%d: %s: cost: want %d, got %d", tt.id, tt.location.GridString(), tt.want, got)
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	// a row of prairie running south from the origin, with a second origin at the far end.
+	origin := coords.Map{Column: 10, Row: 10}
+	far := origin.Move(direction.South, direction.South, direction.South, direction.South)
+	worldMap := tiles.NewMap()
+	for _, location := range []coords.Map{origin, origin.Add(direction.South), origin.Move(direction.South, direction.South), origin.Move(direction.South, direction.South, direction.South), far} {
+		worldMap.FetchTile("", location).Terrain = terrain.Prairie
+	}
+	worldMap.FetchTile("", origin.Add(direction.North)).Terrain = terrain.Ocean
+
+	tests := []struct {
+		id       int
+		location coords.Map
+		want     pathfinding.Route_t
+		ok       bool
+	}{
+		{1, origin, pathfinding.Route_t{Origin: origin, Cost: 0}, true},
+		{2, origin.Add(direction.South), pathfinding.Route_t{Origin: origin, Cost: 3}, true},
+		{3, origin.Move(direction.South, direction.South), pathfinding.Route_t{Origin: origin, Cost: 6}, true},
+		{4, origin.Move(direction.South, direction.South, direction.South), pathfinding.Route_t{Origin: far, Cost: 3}, true},
+		{5, origin.Add(direction.North), pathfinding.Route_t{}, false},
+	}
+	routes := pathfinding.Nearest(worldMap, []coords.Map{origin, far, origin.Add(direction.NorthEast)})
+	for _, tt := range tests {
+		got, ok := routes[tt.location]
+		if ok != tt.ok {
+			t.Errorf("%d: %s: reachable: want %v, got %v", tt.id, tt.location.GridString(), tt.ok, ok)
+		} else if got != tt.want {
+			t.Errorf("%d: %s: route: want %+v, got %+v", tt.id, tt.location.GridString(), tt.want, got)
+		}
+	}
+}
+
+func TestAccount(t *testing.T) {
+	// prairie all the way north, so each step costs 3
+	origin := coords.Map{Column: 10, Row: 10}
+	worldMap := tiles.NewMap()
+	for n, location := 0, origin; n < 4; n, location = n+1, location.Add(direction.North) {
+		worldMap.FetchTile("", location).Terrain = terrain.Prairie
+	}
+	north := func(result, reason results.Result_e) *parser.Move_t {
+		return &parser.Move_t{Advance: direction.North, Result: result, Reason: reason}
+	}
+	tests := []struct {
+		id       int
+		budget   int
+		moves    []*parser.Move_t
+		warnings int
+		last     int // estimated remaining after the last move
+	}{
+		{1, 30, []*parser.Move_t{north(results.Succeeded, 0), north(results.Succeeded, 0)}, 0, 24},
+		{2, 30, []*parser.Move_t{north(results.Succeeded, 0), north(results.Failed, results.ExhaustedMovementPoints)}, 1, 27},
+		{3, 4, []*parser.Move_t{north(results.Succeeded, 0), north(results.Failed, results.ExhaustedMovementPoints)}, 0, 1},
+		{4, 5, []*parser.Move_t{north(results.Succeeded, 0), north(results.Succeeded, 0)}, 1, -1},
+	}
+	for _, tt := range tests {
+		turn := &parser.Turn_t{Id: "0902-02", SortedMoves: []*parser.Moves_t{{UnitId: "0138", StartLocation: origin, Moves: tt.moves}}}
+		warnings := pathfinding.Account([]*parser.Turn_t{turn}, worldMap, tt.budget)
+		if len(warnings) != tt.warnings {
+			t.Errorf("%d: warnings: want %d, got %d: %v", tt.id, tt.warnings, len(warnings), warnings)
+		}
+		last := tt.moves[len(tt.moves)-1]
+		if !last.MP.Known {
+			t.Errorf("%d: want known, got unknown", tt.id)
+		} else if last.MP.Remaining != tt.last {
+			t.Errorf("%d: remaining: want %d, got %d", tt.id, tt.last, last.MP.Remaining)
+		}
+	}
+}
+
+func TestReachableAcrossSeam(t *testing.T) {
+	// the world is two grids wide, so the last column is next to the first
+	origin := coords.Map{Column: 59, Row: 10}
+	worldMap := tiles.NewMap()
+	worldMap.World = coords.World{Columns: 2}
+	worldMap.FetchTile("", origin).Terrain = terrain.Prairie
+	worldMap.FetchTile("", origin.Add(direction.NorthEast)).Terrain = terrain.Prairie
+	worldMap.FetchTile("", origin.Move(direction.NorthEast, direction.SouthEast)).Terrain = terrain.Prairie
+
+	reached := pathfinding.Reachable(worldMap, origin, 6)
+	for _, tc := range []struct {
+		id       int
+		location coords.Map
+		want     int
+	}{
+		{1, coords.Map{Column: 0, Row: 10}, 3},
+		{2, coords.Map{Column: 1, Row: 10}, 6},
+	} {
+		if got, ok := reached[tc.location]; !ok {
+			t.Errorf("%d: %s: not reachable", tc.id, tc.location)
+		} else if got != tc.want {
+			t.Errorf("%d: %s: cost: want %d, got %d", tc.id, tc.location, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package plugins lets users add their own features, labels, and notes to the
+// map, for example to mark the hexes that match a house rule, without changing
+// the map writer.
+//
+// A plugin is a Decorator. It is given every hex on the merged map and returns
+// annotations, which are drawn the same way as the annotations file.
+//
+// Go plugins are compiled in and register themselves by name, usually from an
+// init function in a file added to the main package:
+//
+//	func init() {
+//		plugins.Register("swamps", plugins.DecoratorFunc(markSwamps))
+//	}
+//
+// Any other program can be used as an exec plugin by naming it "exec:PATH".
+// The program is sent the hexes as a JSON array on stdin and must write
+// annotations in the annotations file format to stdout.
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Hex_t is what a plugin is told about a hex on the map.
+type Hex_t struct {
+	Hex         string   `json:"hex"`                   // grid coordinates, for example "AB 1203"
+	Terrain     string   `json:"terrain"`               // terrain code, for example "PR"
+	FirstSeen   string   `json:"firstSeen,omitempty"`   // turn the hex was first reported
+	Visited     string   `json:"visited,omitempty"`     // turn a unit last visited the hex
+	Scouted     string   `json:"scouted,omitempty"`     // turn a scout last reported the hex
+	Settlements []string `json:"settlements,omitempty"` // names of settlements and special hexes
+	Resources   []string `json:"resources,omitempty"`
+	Units       []string `json:"units,omitempty"` // units that were seen in the hex
+}
+
+// Decorator adds annotations to the map.
+type Decorator interface {
+	Decorate(ctx context.Context, hexes []*Hex_t) ([]*annotations.Hex_t, error)
+}
+
+// DecoratorFunc lets a function be used as a Decorator.
+type DecoratorFunc func(ctx context.Context, hexes []*Hex_t) ([]*annotations.Hex_t, error)
+
+func (f DecoratorFunc) Decorate(ctx context.Context, hexes []*Hex_t) ([]*annotations.Hex_t, error) {
+	return f(ctx, hexes)
+}
+
+var (
+	mu       sync.Mutex
+	registry = map[string]Decorator{}
+)
+
+// Register makes a plugin available by name.
+// It panics if the name is already registered or starts with "exec:".
+func Register(name string, d Decorator) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d == nil {
+		panic(fmt.Sprintf("plugins: %q: nil decorator", name))
+	} else if strings.HasPrefix(name, "exec:") {
+		panic(fmt.Sprintf("plugins: %q: reserved name", name))
+	} else if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("plugins: %q: registered twice", name))
+	}
+	registry[name] = d
+}
+
+// Names returns the names of the registered plugins, sorted.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the plugin for the name. Names starting with "exec:" are exec plugins.
+func Lookup(name string) (Decorator, error) {
+	if path, ok := strings.CutPrefix(name, "exec:"); ok {
+		if path == "" {
+			return nil, fmt.Errorf("plugins: %q: missing path", name)
+		}
+		return Exec(path), nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if d, ok := registry[name]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("plugins: unknown plugin %q", name)
+}
+
+// Exec returns a plugin that runs a program.
+// The program's stderr is passed through so that it can log.
+func Exec(path string) Decorator {
+	return DecoratorFunc(func(ctx context.Context, hexes []*Hex_t) ([]*annotations.Hex_t, error) {
+		input, err := json.Marshal(hexes)
+		if err != nil {
+			return nil, err
+		}
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, path)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(input), &output, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		list, err := annotations.Parse(output.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%s: output: %w", path, err)
+		}
+		return list, nil
+	})
+}
+
+// Hexes returns the hexes on the map that have terrain, sorted by grid coordinates.
+func Hexes(worldMap *tiles.Map_t) []*Hex_t {
+	var list []*Hex_t
+	for _, t := range worldMap.Tiles {
+		if t.Terrain == terrain.Blank {
+			continue
+		}
+		hex := &Hex_t{
+			Hex:       t.Location.GridString(),
+			Terrain:   t.Terrain.String(),
+			FirstSeen: t.FirstSeen,
+			Visited:   t.Visited,
+			Scouted:   t.Scouted,
+		}
+		for _, s := range t.Settlements {
+			hex.Settlements = append(hex.Settlements, s.Name)
+		}
+		for _, s := range t.Special {
+			hex.Settlements = append(hex.Settlements, s.Name)
+		}
+		for _, r := range t.Resources {
+			hex.Resources = append(hex.Resources, r.String())
+		}
+		for _, e := range t.Encounters {
+			hex.Units = append(hex.Units, string(e.UnitId))
+		}
+		list = append(list, hex)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Hex < list[j].Hex
+	})
+	return list
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package plugins_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/plugins"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	plugins.Register("test-lookup", plugins.DecoratorFunc(func(ctx context.Context, hexes []*plugins.Hex_t) ([]*annotations.Hex_t, error) {
+		return nil, nil
+	}))
+	for _, tc := range []struct {
+		id      int
+		name    string
+		wantErr bool
+	}{
+		{1, "test-lookup", false},
+		{2, "exec:./plugin.sh", false},
+		{3, "exec:", true},
+		{4, "no-such-plugin", true},
+	} {
+		if _, err := plugins.Lookup(tc.name); (err != nil) != tc.wantErr {
+			t.Errorf("%d: %q: want error %v, got %v", tc.id, tc.name, tc.wantErr, err)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	// the plugin marks every prairie hex
+	script := filepath.Join(t.TempDir(), "plugin.sh")
+	body := "#!/bin/sh\ngrep -q '\"terrain\":\"PR\"' && echo '{\"AB 1203\": [{\"label\": \"Prairie\"}]}'\n"
+	if err := os.WriteFile(script, []byte(body), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	worldMap := tiles.NewMap()
+	location, err := coords.HexToMap("AB 1203")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tile := worldMap.FetchTile("0138", location)
+	tile.Terrain = terrain.Prairie
+	tile.Encounters = append(tile.Encounters, &parser.Encounter_t{TurnId: "0901-04", UnitId: "0138e1"})
+	hexes := plugins.Hexes(worldMap)
+	if len(hexes) != 1 || hexes[0].Hex != "AB 1203" || hexes[0].Terrain != "PR" || len(hexes[0].Units) != 1 {
+		t.Fatalf("hexes: got %+v", hexes)
+	}
+
+	d, err := plugins.Lookup("exec:" + script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := d.Decorate(context.Background(), hexes)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(list) != 1 || list[0].Location != location || list[0].Annotations[0].Label != "Prairie" {
+		t.Errorf("decorate: got %+v", list)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package progress reports how far along a long render or parse is.
+//
+// A run is a series of phases, for example "parse", "walk", and "map".
+// Each phase has a total, which is zero when the size isn't known up front.
+// All the methods are safe to call on a nil reporter, which reports nothing.
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Format_e is how the progress is written.
+type Format_e int
+
+const (
+	Bar  Format_e = iota // a single line that is redrawn, for a terminal
+	JSON                 // one JSON object per line, for other programs
+)
+
+// Formats are the names accepted by New.
+var Formats = map[string]Format_e{"bar": Bar, "json": JSON}
+
+// Interval is the least time between updates within a phase.
+// The start and the end of a phase are always written.
+const Interval = 250 * time.Millisecond
+
+// Reporter_t writes the progress of each phase.
+type Reporter_t struct {
+	sync.Mutex
+	w       io.Writer
+	format  Format_e
+	now     func() time.Time
+	phase   string
+	total   int
+	done    int
+	started time.Time
+	written time.Time // when the last update was written
+}
+
+// Event_t is a single update in the JSON stream.
+type Event_t struct {
+	Phase     string  `json:"phase"`
+	Done      int     `json:"done"`
+	Total     int     `json:"total"`             // zero if the size isn't known
+	Percent   float64 `json:"percent,omitempty"` // set only when the total is known
+	ElapsedMs int64   `json:"elapsedMs"`
+	EtaMs     int64   `json:"etaMs,omitempty"` // estimated time left, set only when it can be estimated
+	Finished  bool    `json:"finished,omitempty"`
+}
+
+// New returns a reporter for the format name. An empty name returns nil,
+// which turns off progress reporting.
+func New(w io.Writer, name string) (*Reporter_t, error) {
+	if name == "" {
+		return nil, nil
+	}
+	format, ok := Formats[name]
+	if !ok {
+		return nil, fmt.Errorf("progress: unknown format %q: want bar or json", name)
+	}
+	return &Reporter_t{w: w, format: format, now: time.Now}, nil
+}
+
+// WithClock replaces the clock, for tests.
+func (r *Reporter_t) WithClock(now func() time.Time) *Reporter_t {
+	if r != nil {
+		r.now = now
+	}
+	return r
+}
+
+// Start begins a new phase. Total is the number of steps, or zero if it isn't known.
+func (r *Reporter_t) Start(phase string, total int) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.phase, r.total, r.done = phase, total, 0
+	r.started = r.now()
+	r.write(false)
+}
+
+// Add records that n more steps of the current phase are done.
+func (r *Reporter_t) Add(n int) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	r.done += n
+	if r.now().Sub(r.written) < Interval {
+		return
+	}
+	r.write(false)
+}
+
+// Done ends the current phase.
+func (r *Reporter_t) Done() {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	if r.total != 0 {
+		r.done = r.total
+	}
+	r.write(true)
+}
+
+// write must be called with the lock held.
+func (r *Reporter_t) write(finished bool) {
+	now := r.now()
+	r.written = now
+	e := Event_t{Phase: r.phase, Done: r.done, Total: r.total, ElapsedMs: now.Sub(r.started).Milliseconds(), Finished: finished}
+	var eta time.Duration
+	if r.total != 0 {
+		e.Percent = float64(r.done) * 100 / float64(r.total)
+		if r.done != 0 && !finished {
+			elapsed := now.Sub(r.started)
+			eta = time.Duration(float64(elapsed) * float64(r.total-r.done) / float64(r.done))
+			e.EtaMs = eta.Milliseconds()
+		}
+	}
+
+	switch r.format {
+	case JSON:
+		data, _ := json.Marshal(e)
+		_, _ = fmt.Fprintf(r.w, "%s\n", data)
+	case Bar:
+		const width = 30
+		var line string
+		if r.total == 0 {
+			line = fmt.Sprintf("%-8s %d", r.phase, r.done)
+		} else {
+			filled := r.done * width / r.total
+			line = fmt.Sprintf("%-8s [%s%s] %3.0f%% %d/%d", r.phase, strings.Repeat("#", filled), strings.Repeat(".", width-filled), e.Percent, r.done, r.total)
+			if e.EtaMs != 0 {
+				line += fmt.Sprintf(" ETA %v", eta.Round(time.Second))
+			}
+		}
+		if finished {
+			line += fmt.Sprintf(" done in %v", now.Sub(r.started).Round(time.Millisecond))
+			_, _ = fmt.Fprintf(r.w, "\r%-80s\n", line)
+		} else {
+			_, _ = fmt.Fprintf(r.w, "\r%-80s", line)
+		}
+	}
+}
+
+// Writer_t reads the JSON stream written by a reporter and calls a function
+// for each update. Lines that aren't updates are ignored.
+type Writer_t struct {
+	fn      func(Event_t)
+	partial []byte // the start of a line that hasn't been finished
+}
+
+// NewWriter returns a writer that calls fn for each update in the stream.
+func NewWriter(fn func(Event_t)) *Writer_t {
+	return &Writer_t{fn: fn}
+}
+
+func (w *Writer_t) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		n := bytes.IndexByte(w.partial, '\n')
+		if n == -1 {
+			break
+		}
+		var e Event_t
+		if err := json.Unmarshal(w.partial[:n], &e); err == nil && e.Phase != "" {
+			w.fn(e)
+		}
+		w.partial = w.partial[n+1:]
+	}
+	return len(p), nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package progress_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playbymail/ottomap/internal/progress"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r, err := progress.New(&buf, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.WithClock(func() time.Time { return now })
+
+	r.Start("parse", 4)
+	for _, step := range []time.Duration{time.Second, 10 * time.Millisecond, time.Second} {
+		now = now.Add(step)
+		r.Add(1)
+	}
+	r.Done()
+
+	var got []progress.Event_t
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e progress.Event_t
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("%q: %v", line, err)
+		}
+		got = append(got, e)
+	}
+	// the second step is within the interval, so it isn't written
+	want := []progress.Event_t{
+		{Phase: "parse", Done: 0, Total: 4},
+		{Phase: "parse", Done: 1, Total: 4, Percent: 25, ElapsedMs: 1000, EtaMs: 3000},
+		{Phase: "parse", Done: 3, Total: 4, Percent: 75, ElapsedMs: 2010, EtaMs: 670},
+		{Phase: "parse", Done: 4, Total: 4, Percent: 100, ElapsedMs: 2010, Finished: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events: want %d, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		format string
+		isNil  bool
+		isErr  bool
+	}{
+		{format: "", isNil: true},
+		{format: "bar"},
+		{format: "json"},
+		{format: "xml", isNil: true, isErr: true},
+	} {
+		r, err := progress.New(&bytes.Buffer{}, tc.format)
+		if (err != nil) != tc.isErr {
+			t.Errorf("%q: want error %v, got %v", tc.format, tc.isErr, err)
+		}
+		if (r == nil) != tc.isNil {
+			t.Errorf("%q: want nil %v, got %v", tc.format, tc.isNil, r == nil)
+		}
+	}
+
+	// a nil reporter must be safe to use
+	var r *progress.Reporter_t
+	r.Start("map", 10)
+	r.Add(1)
+	r.Done()
+}
+
+func TestWriter(t *testing.T) {
+	var got []progress.Event_t
+	w := progress.NewWriter(func(e progress.Event_t) {
+		got = append(got, e)
+	})
+	// updates can be split across writes, and other lines are ignored
+	for _, chunk := range []string{
+		`{"phase":"parse","done":1,"tot`,
+		`al":2,"percent":50,"elapsedMs":3}` + "\nnot json\n",
+		`{"phase":"parse","done":2,"total":2,"percent":100,"elapsedMs":4,"finished":true}` + "\n",
+	} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []progress.Event_t{
+		{Phase: "parse", Done: 1, Total: 2, Percent: 50, ElapsedMs: 3},
+		{Phase: "parse", Done: 2, Total: 2, Percent: 100, ElapsedMs: 4, Finished: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("events: want %d, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package render
+
+import (
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"io"
+	"math"
+)
+
+// Azgaar renders the map as the "Full JSON" export of Azgaar's Fantasy Map Generator.
+// Each hex is a cell with a height and a biome, and each settlement is a burg.
+type Azgaar struct{}
+
+// azgaarHexHeight is the size of a hex in the exported coordinates.
+const azgaarHexHeight = 10
+
+// azgaarBiomes are the default biomes of the generator, by id.
+var azgaarBiomes = []struct {
+	name, color string
+}{
+	{"Marine", "#466eab"},
+	{"Hot desert", "#fbe79f"},
+	{"Cold desert", "#b5b887"},
+	{"Savanna", "#d2d082"},
+	{"Grassland", "#c8d68f"},
+	{"Tropical seasonal forest", "#b6d95d"},
+	{"Temperate deciduous forest", "#29bc56"},
+	{"Tropical rainforest", "#7dcb35"},
+	{"Temperate rainforest", "#409c43"},
+	{"Taiga", "#4b6b32"},
+	{"Tundra", "#96784b"},
+	{"Glacier", "#d5e7eb"},
+	{"Wetland", "#0b9131"},
+}
+
+func (Azgaar) Extension() string {
+	return ".json"
+}
+
+func (Azgaar) Render(w io.Writer, m *Map_t) error {
+	type cell struct {
+		I     int        `json:"i"`
+		P     [2]float64 `json:"p"`
+		H     int        `json:"h"` // 0 to 100, land is 20 and up
+		Biome int        `json:"biome"`
+		Burg  int        `json:"burg"`
+	}
+	type burg struct {
+		I    int     `json:"i,omitempty"`
+		Cell int     `json:"cell,omitempty"`
+		X    float64 `json:"x,omitempty"`
+		Y    float64 `json:"y,omitempty"`
+		Name string  `json:"name,omitempty"`
+	}
+	width, height := size(m.Columns, m.Rows, azgaarHexHeight)
+	var doc struct {
+		Info struct {
+			Version     string `json:"version"`
+			Description string `json:"description"`
+			MapName     string `json:"mapName"`
+			Width       int    `json:"width"`
+			Height      int    `json:"height"`
+		} `json:"info"`
+		Pack struct {
+			Cells []cell `json:"cells"`
+			Burgs []burg `json:"burgs"`
+		} `json:"pack"`
+		BiomesData struct {
+			I     []int    `json:"i"`
+			Name  []string `json:"name"`
+			Color []string `json:"color"`
+		} `json:"biomesData"`
+	}
+	doc.Info.Version = "1.99"
+	doc.Info.Description = "Azgaar's Fantasy Map Generator"
+	doc.Info.MapName = m.Name
+	doc.Info.Width, doc.Info.Height = int(math.Ceil(width)), int(math.Ceil(height))
+	doc.Pack.Cells = []cell{}
+	doc.Pack.Burgs = []burg{{}} // the generator doesn't use burg 0
+	for i, hex := range m.Hexes {
+		c := center(hex.Column, hex.Row, azgaarHexHeight)
+		doc.Pack.Cells = append(doc.Pack.Cells, cell{
+			I:     i,
+			P:     [2]float64{c.X, c.Y},
+			H:     azgaarHeight(hex.Terrain),
+			Biome: azgaarBiome(hex.Terrain),
+		})
+		for _, name := range hex.Settlements {
+			if doc.Pack.Cells[i].Burg == 0 {
+				doc.Pack.Cells[i].Burg = len(doc.Pack.Burgs)
+			}
+			doc.Pack.Burgs = append(doc.Pack.Burgs, burg{I: len(doc.Pack.Burgs), Cell: i, X: c.X, Y: c.Y, Name: name})
+		}
+	}
+	for i, biome := range azgaarBiomes {
+		doc.BiomesData.I = append(doc.BiomesData.I, i)
+		doc.BiomesData.Name = append(doc.BiomesData.Name, biome.name)
+		doc.BiomesData.Color = append(doc.BiomesData.Color, biome.color)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// azgaarHeight returns the height of the terrain on the generator's 0 to 100 scale.
+func azgaarHeight(t terrain.Terrain_e) int {
+	switch t.Height() {
+	case terrain.DeepWater:
+		return 5
+	case terrain.ShallowWater:
+		return 15
+	case terrain.Wetland:
+		return 21
+	case terrain.Flat:
+		return 25
+	case terrain.Plateau:
+		return 35
+	case terrain.Hills:
+		return 45
+	case terrain.Mountains:
+		return 65
+	case terrain.HighMountains:
+		return 85
+	case terrain.Ice:
+		return 70
+	}
+	if t == terrain.UnknownWater {
+		return 10
+	}
+	return 20
+}
+
+// azgaarBiome returns the id of the default biome that is closest to the terrain.
+func azgaarBiome(t terrain.Terrain_e) int {
+	switch t {
+	case terrain.Ocean, terrain.Lake, terrain.UnknownWater:
+		return 0
+	case terrain.Desert:
+		return 1
+	case terrain.AridHills, terrain.AridTundra, terrain.LowAridMountains, terrain.LowVolcanicMountains:
+		return 2
+	case terrain.BrushFlat, terrain.BrushHills:
+		return 3
+	case terrain.Deciduous, terrain.DeciduousHills:
+		return 6
+	case terrain.Jungle, terrain.JungleHills, terrain.LowJungleMountains:
+		return 7
+	case terrain.ConiferHills, terrain.LowConiferMountains, terrain.UnknownMountain:
+		return 9
+	case terrain.Tundra, terrain.SnowyHills, terrain.LowSnowyMountains:
+		return 10
+	case terrain.Alps, terrain.HighSnowyMountains, terrain.PolarIce:
+		return 11
+	case terrain.Swamp, terrain.UnknownJungleSwamp:
+		return 12
+	}
+	return 4 // grassland
+}
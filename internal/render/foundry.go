@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package render
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strings"
+)
+
+// Foundry renders the map as a FoundryVTT scene with a hex grid.
+// The terrain is drawn as a filled polygon under each hex of the grid and
+// the settlements are text drawings, so the scene doesn't need a background image.
+type Foundry struct{}
+
+// foundryHexHeight is the size of the grid in pixels, from flat side to flat side.
+const foundryHexHeight = 100
+
+// foundryHexOddQ is the grid type for flat-topped hexes with the odd columns shifted down.
+const foundryHexOddQ = 4
+
+func (Foundry) Extension() string {
+	return ".json"
+}
+
+func (Foundry) Render(w io.Writer, m *Map_t) error {
+	type shape struct {
+		Type   string    `json:"type"` // "p" for a polygon, "r" for a rectangle
+		Width  float64   `json:"width"`
+		Height float64   `json:"height"`
+		Points []float64 `json:"points"` // x, y pairs relative to the drawing
+	}
+	type drawing struct {
+		X           float64 `json:"x"`
+		Y           float64 `json:"y"`
+		Shape       shape   `json:"shape"`
+		FillType    int     `json:"fillType"` // 1 is a solid fill
+		FillColor   string  `json:"fillColor,omitempty"`
+		FillAlpha   float64 `json:"fillAlpha"`
+		StrokeWidth int     `json:"strokeWidth"`
+		Text        string  `json:"text,omitempty"`
+		FontSize    int     `json:"fontSize,omitempty"`
+		TextColor   string  `json:"textColor,omitempty"`
+		Locked      bool    `json:"locked"`
+	}
+	width, height := size(m.Columns, m.Rows, foundryHexHeight)
+	var scene struct {
+		Name            string    `json:"name"`
+		Width           int       `json:"width"`
+		Height          int       `json:"height"`
+		Padding         float64   `json:"padding"`
+		BackgroundColor string    `json:"backgroundColor"`
+		Grid            any       `json:"grid"`
+		Drawings        []drawing `json:"drawings"`
+	}
+	scene.Name = m.Name
+	scene.Width, scene.Height = int(math.Ceil(width)), int(math.Ceil(height))
+	scene.BackgroundColor = "#999999"
+	scene.Grid = struct {
+		Type     int     `json:"type"`
+		Size     int     `json:"size"`
+		Color    string  `json:"color"`
+		Alpha    float64 `json:"alpha"`
+		Distance float64 `json:"distance"`
+		Units    string  `json:"units"`
+	}{Type: foundryHexOddQ, Size: foundryHexHeight, Color: "#000000", Alpha: 0.2, Distance: 1, Units: "hex"}
+	scene.Drawings = []drawing{}
+
+	radius := foundryHexHeight / math.Sqrt(3)
+	for _, hex := range m.Hexes {
+		points := corners(hex.Column, hex.Row, foundryHexHeight)
+		origin := Point{X: points[0].X, Y: points[1].Y}
+		d := drawing{
+			X:         origin.X,
+			Y:         origin.Y,
+			Shape:     shape{Type: "p", Width: 2 * radius, Height: foundryHexHeight},
+			FillType:  1,
+			FillColor: Color(hex.Terrain),
+			FillAlpha: 1,
+			Locked:    true,
+		}
+		for _, p := range append(points[:], points[0]) {
+			d.Shape.Points = append(d.Shape.Points, p.X-origin.X, p.Y-origin.Y)
+		}
+		scene.Drawings = append(scene.Drawings, d)
+	}
+	for _, hex := range m.Hexes {
+		if len(hex.Settlements) == 0 {
+			continue
+		}
+		c := center(hex.Column, hex.Row, foundryHexHeight)
+		scene.Drawings = append(scene.Drawings, drawing{
+			X:         c.X - radius,
+			Y:         c.Y,
+			Shape:     shape{Type: "r", Width: 2 * radius, Height: foundryHexHeight / 2},
+			Text:      strings.Join(hex.Settlements, "\n"),
+			FontSize:  16,
+			TextColor: "#000000",
+			Locked:    true,
+		})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(scene)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package render
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// HexKit renders the map as a Hex Kit tile map.
+// Each tile names an image in the HexKitTileset, "ottomap/PR.png" for prairie,
+// so the player needs a tileset with one image for each terrain code.
+type HexKit struct{}
+
+// HexKitTileset is the name of the tileset that the tiles come from.
+const HexKitTileset = "ottomap"
+
+func (HexKit) Extension() string {
+	return ".map"
+}
+
+func (HexKit) Render(w io.Writer, m *Map_t) error {
+	type tile struct {
+		Tile     string `json:"tile"` // empty for holes in the map
+		Source   string `json:"source"`
+		Flipped  bool   `json:"flipped"`
+		Rotation int    `json:"rotation"`
+	}
+	var doc struct {
+		Version     string `json:"version"`
+		Name        string `json:"name"`
+		Orientation string `json:"orientation"`
+		Width       int    `json:"width"`
+		Height      int    `json:"height"`
+		Tiles       []tile `json:"tiles"` // row by row, from the upper left
+	}
+	doc.Version = "1.2.0"
+	doc.Name = m.Name
+	doc.Orientation = "flat"
+	doc.Width, doc.Height = m.Columns, m.Rows
+	doc.Tiles = make([]tile, m.Columns*m.Rows)
+	for _, hex := range m.Hexes {
+		doc.Tiles[hex.Row*m.Columns+hex.Column] = tile{
+			Tile:   HexKitTileset + "/" + hex.Terrain.String() + ".png",
+			Source: HexKitTileset,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
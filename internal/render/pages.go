@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+)
+
+// Page_t is a page of a printed map, in the columns and rows of the map's hexes.
+type Page_t struct {
+	Name          string // "page-2-3" is the second row of pages, third column
+	Column, Row   int    // upper left hex of the page
+	Columns, Rows int    // size of the page in hexes
+}
+
+// Contains returns true if the hex at the column and row is on the page.
+func (p *Page_t) Contains(column, row int) bool {
+	return p.Column <= column && column < p.Column+p.Columns && p.Row <= row && row < p.Row+p.Rows
+}
+
+// Pages slices the map into pages of columns by rows hexes. Neighboring pages
+// share overlap hexes so that the printed pages can be lined up on a wall.
+// Pages always start on an even column so that each page has the same hex
+// layout as the map; when that isn't possible the overlap grows by a column.
+// Pages without any hexes are left out.
+func (m *Map_t) Pages(columns, rows, overlap int) ([]*Page_t, error) {
+	if columns < 2 || rows < 1 {
+		return nil, fmt.Errorf("pages: must be at least 2 columns and 1 row")
+	} else if overlap < 0 || overlap >= columns-1 || overlap >= rows {
+		return nil, fmt.Errorf("pages: overlap must be less than the size of the page")
+	}
+	stepColumns, stepRows := columns-overlap, rows-overlap
+	if stepColumns%2 != 0 {
+		stepColumns--
+	}
+
+	var pages []*Page_t
+	for pageRow, row := 1, 0; ; pageRow, row = pageRow+1, row+stepRows {
+		for pageColumn, column := 1, 0; ; pageColumn, column = pageColumn+1, column+stepColumns {
+			page := &Page_t{
+				Name:    fmt.Sprintf("page-%d-%d", pageRow, pageColumn),
+				Column:  column,
+				Row:     row,
+				Columns: columns,
+				Rows:    rows,
+			}
+			for _, hex := range m.Hexes {
+				if page.Contains(hex.Column, hex.Row) {
+					pages = append(pages, page)
+					break
+				}
+			}
+			if column+columns >= m.Columns {
+				break
+			}
+		}
+		if row+rows >= m.Rows {
+			break
+		}
+	}
+	return pages, nil
+}
+
+// PageIndex writes an SVG sheet of the whole map with the outline and name of
+// each page drawn over it, to show where the printed pages go.
+func PageIndex(w io.Writer, m *Map_t, pages []*Page_t) error {
+	const height = 12.0
+	width, depth := size(m.Columns, m.Rows, height)
+	b := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.1f %.1f\">\n", width, depth, width, depth)
+	_, _ = fmt.Fprintf(b, "<title>%s</title>\n", html.EscapeString(m.Name))
+	for _, hex := range m.Hexes {
+		_, _ = fmt.Fprintf(b, "<polygon fill=\"%s\" points=\"", Color(hex.Terrain))
+		for n, p := range corners(hex.Column, hex.Row, height) {
+			if n != 0 {
+				_, _ = fmt.Fprint(b, " ")
+			}
+			_, _ = fmt.Fprintf(b, "%.1f,%.1f", p.X, p.Y)
+		}
+		_, _ = fmt.Fprintf(b, "\"/>\n")
+	}
+	for _, page := range pages {
+		// the box runs from the left corner of the first column to the right corner
+		// of the last, and from the top of the first row to the bottom of the last
+		// row of the shifted columns
+		upperLeft := corners(page.Column, page.Row, height)[0]
+		lowerRight := corners(page.Column+page.Columns-1, page.Row+page.Rows-1, height)[3]
+		top := center(page.Column, page.Row, height).Y - height/2
+		bottom := center(page.Column+1, page.Row+page.Rows-1, height).Y + height/2
+		_, _ = fmt.Fprintf(b, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"none\" stroke=\"#c00000\" stroke-width=\"2\"/>\n",
+			upperLeft.X, top, lowerRight.X-upperLeft.X, bottom-top)
+		_, _ = fmt.Fprintf(b, "<text x=\"%.1f\" y=\"%.1f\" font-family=\"sans-serif\" font-size=\"%.0f\" fill=\"#c00000\">%s</text>\n",
+			upperLeft.X+4, top+2*height, 1.5*height, html.EscapeString(page.Name))
+	}
+	_, _ = fmt.Fprintf(b, "</svg>\n")
+	return b.Flush()
+}
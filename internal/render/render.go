@@ -0,0 +1,193 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package render draws the merged map for mapping tools other than Worldographer.
+//
+// The map is converted once to a Map_t and each format is a Renderer that
+// draws it, so adding a format doesn't touch the parse, walk, or merge code.
+package render
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Renderer writes the map in the format of another tool.
+type Renderer interface {
+	// Extension is the file extension for the format, including the dot.
+	Extension() string
+	// Render writes the map.
+	Render(w io.Writer, m *Map_t) error
+}
+
+// Renderers are the formats that the map can be rendered in, by name.
+var Renderers = map[string]Renderer{
+	"azgaar":  Azgaar{},
+	"foundry": Foundry{},
+	"hexkit":  HexKit{},
+	"text":    Text{},
+}
+
+// Lookup returns the renderer for the format.
+func Lookup(name string) (Renderer, error) {
+	if r, ok := Renderers[name]; ok {
+		return r, nil
+	}
+	return nil, fmt.Errorf("render: unknown format %q: want one of %s", name, strings.Join(Formats(), ", "))
+}
+
+// Formats returns the names of the renderers, sorted.
+func Formats() []string {
+	var names []string
+	for name := range Renderers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Map_t is the merged map, shifted so that the upper left hex is near 0, 0.
+// The hexes are flat-topped with the odd columns shifted down half a hex,
+// the same as the Tribenet map.
+type Map_t struct {
+	Name    string
+	Columns int      // number of columns in the map
+	Rows    int      // number of rows in the map
+	Hexes   []*Hex_t // sorted by column and then row
+
+	offset coords.Map                  // subtracted from the stitched location of each tile
+	stitch func(coords.Map) coords.Map // moves tiles across the seam of a world that wraps
+}
+
+// Hex_t is a hex on the map that has terrain.
+type Hex_t struct {
+	Column, Row int        // position on the map, from 0
+	Location    coords.Map // coordinates from the turn report
+	Terrain     terrain.Terrain_e
+	Settlements []string // names of the settlements and special hexes
+}
+
+// NewMap converts the merged tiles. Tiles without terrain are skipped.
+func NewMap(name string, worldMap *tiles.Map_t) *Map_t {
+	upperLeft, _ := worldMap.Bounds()
+	m := &Map_t{
+		Name: name,
+		// keep the column parity so that the odd columns are still the ones shifted down
+		offset: coords.Map{Column: upperLeft.Column - upperLeft.Column%2, Row: upperLeft.Row},
+		stitch: worldMap.Stitch(),
+	}
+	for _, tile := range worldMap.Tiles {
+		if tile.Terrain == terrain.Blank {
+			continue
+		}
+		column, row := m.Locate(tile.Location)
+		hex := &Hex_t{
+			Column:   column,
+			Row:      row,
+			Location: tile.Location,
+			Terrain:  tile.Terrain,
+		}
+		for _, s := range tile.Settlements {
+			hex.Settlements = append(hex.Settlements, s.Name)
+		}
+		for _, s := range tile.Special {
+			if !s.Has(parser.HideDirective) {
+				hex.Settlements = append(hex.Settlements, s.Name)
+			}
+		}
+		m.Columns, m.Rows = max(m.Columns, hex.Column+1), max(m.Rows, hex.Row+1)
+		m.Hexes = append(m.Hexes, hex)
+	}
+	sort.Slice(m.Hexes, func(i, j int) bool {
+		if m.Hexes[i].Column != m.Hexes[j].Column {
+			return m.Hexes[i].Column < m.Hexes[j].Column
+		}
+		return m.Hexes[i].Row < m.Hexes[j].Row
+	})
+	return m
+}
+
+// Locate returns the column and row on the map of the tile at the location.
+func (m *Map_t) Locate(location coords.Map) (column, row int) {
+	at := m.stitch(location)
+	return at.Column - m.offset.Column, at.Row - m.offset.Row
+}
+
+// Point is a position in pixels, from the upper left corner of the map.
+type Point struct {
+	X, Y float64
+}
+
+// center returns the center of the hex when the hexes are height pixels from flat side to flat side.
+func center(column, row int, height float64) Point {
+	radius := height / math.Sqrt(3)
+	p := Point{X: radius + float64(column)*1.5*radius, Y: height/2 + float64(row)*height}
+	if column%2 == 1 {
+		p.Y += height / 2
+	}
+	return p
+}
+
+// corners returns the corners of the hex, clockwise from the left corner.
+func corners(column, row int, height float64) [6]Point {
+	c, radius := center(column, row, height), height/math.Sqrt(3)
+	return [6]Point{
+		{c.X - radius, c.Y},
+		{c.X - radius/2, c.Y - height/2},
+		{c.X + radius/2, c.Y - height/2},
+		{c.X + radius, c.Y},
+		{c.X + radius/2, c.Y + height/2},
+		{c.X - radius/2, c.Y + height/2},
+	}
+}
+
+// size returns the width and height in pixels of a map with the given number of columns and rows.
+func size(columns, rows int, height float64) (float64, float64) {
+	radius := height / math.Sqrt(3)
+	return radius/2 + float64(columns)*1.5*radius, float64(rows)*height + height/2
+}
+
+// Color returns the color for the terrain as "#rrggbb".
+func Color(t terrain.Terrain_e) string {
+	switch t {
+	case terrain.Desert:
+		return "#e8d18a"
+	case terrain.Jungle, terrain.JungleHills, terrain.LowJungleMountains:
+		return "#3f8f3a"
+	case terrain.Deciduous, terrain.DeciduousHills:
+		return "#5fa854"
+	case terrain.ConiferHills, terrain.LowConiferMountains:
+		return "#47704a"
+	case terrain.AridHills, terrain.AridTundra, terrain.LowAridMountains:
+		return "#b8a27a"
+	case terrain.Tundra, terrain.SnowyHills, terrain.LowSnowyMountains, terrain.HighSnowyMountains:
+		return "#d8dcd6"
+	case terrain.LowVolcanicMountains:
+		return "#7a5a50"
+	case terrain.UnknownLand, terrain.UnknownMountain, terrain.UnknownJungleSwamp, terrain.UnknownWater:
+		return "#a0a0a0"
+	}
+	switch t.Height() {
+	case terrain.DeepWater:
+		return "#3f6fae"
+	case terrain.ShallowWater:
+		return "#6fa3d6"
+	case terrain.Wetland:
+		return "#6f8f5a"
+	case terrain.Flat, terrain.Plateau:
+		return "#c8d68f"
+	case terrain.Hills:
+		return "#a8b46a"
+	case terrain.Mountains, terrain.HighMountains:
+		return "#8c7f70"
+	case terrain.Ice:
+		return "#eef4f7"
+	}
+	return "#a0a0a0"
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package render_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/render"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+)
+
+func TestRenderers(t *testing.T) {
+	worldMap := tiles.NewMap()
+	for _, tile := range []*tiles.Tile_t{
+		{Location: coords.Map{Column: 13, Row: 20}, Terrain: terrain.Prairie, Settlements: []*parser.Settlement_t{{Name: "Bree"}}},
+		{Location: coords.Map{Column: 14, Row: 20}, Terrain: terrain.Ocean},
+		{Location: coords.Map{Column: 14, Row: 22}, Terrain: terrain.Blank},
+	} {
+		worldMap.Tiles[tile.Location] = tile
+	}
+
+	m := render.NewMap("test", worldMap)
+	// column 13 is odd, so the map starts at column 12 to keep it shifted down
+	if m.Columns != 3 || m.Rows != 1 || len(m.Hexes) != 2 {
+		t.Fatalf("map: want 3 columns, 1 row, 2 hexes, got %d, %d, %d", m.Columns, m.Rows, len(m.Hexes))
+	} else if hex := m.Hexes[0]; hex.Column != 1 || hex.Row != 0 || len(hex.Settlements) != 1 {
+		t.Errorf("hex: want 1, 0 with 1 settlement, got %d, %d with %d", hex.Column, hex.Row, len(hex.Settlements))
+	}
+
+	for _, tc := range []struct {
+		format string
+		check  func(doc map[string]any) bool
+	}{
+		{"azgaar", func(doc map[string]any) bool {
+			pack := doc["pack"].(map[string]any)
+			return len(pack["cells"].([]any)) == 2 && len(pack["burgs"].([]any)) == 2
+		}},
+		{"foundry", func(doc map[string]any) bool {
+			// one drawing for each hex and one for the settlement
+			return len(doc["drawings"].([]any)) == 3
+		}},
+		{"hexkit", func(doc map[string]any) bool {
+			tiles := doc["tiles"].([]any)
+			return len(tiles) == 3 && tiles[1].(map[string]any)["tile"] == "ottomap/PR.png" && tiles[0].(map[string]any)["tile"] == ""
+		}},
+	} {
+		r, err := render.Lookup(tc.format)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		var buf bytes.Buffer
+		if err := r.Render(&buf, m); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+			t.Fatalf("%s: %v", tc.format, err)
+		} else if !tc.check(doc) {
+			t.Errorf("%s: unexpected output\n%s", tc.format, buf.String())
+		}
+	}
+
+	if _, err := render.Lookup("svg"); err == nil {
+		t.Errorf("svg: want error, got nil")
+	}
+}
+
+func TestText(t *testing.T) {
+	worldMap := tiles.NewMap()
+	for _, tile := range []*tiles.Tile_t{
+		{Location: coords.Map{Column: 10, Row: 20}, Terrain: terrain.Prairie},
+		{Location: coords.Map{Column: 11, Row: 20}, Terrain: terrain.Ocean},
+		{Location: coords.Map{Column: 12, Row: 20}, Terrain: terrain.Swamp},
+		{Location: coords.Map{Column: 10, Row: 21}, Terrain: terrain.LowConiferMountains},
+		{Location: coords.Map{Column: 16, Row: 25}, Terrain: terrain.Desert},
+	} {
+		worldMap.Tiles[tile.Location] = tile
+	}
+	m := render.NewMap("test", worldMap)
+
+	center := coords.Map{Column: 11, Row: 20}
+	var buf bytes.Buffer
+	if err := (render.Text{Center: &center, Radius: 1}).Render(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	// the odd column is printed on the line between the rows of the even columns
+	want := "test: hexes within 1 of " + center.GridString() + "\n" +
+		"pr  sw\n" +
+		"  ~~\n" +
+		"MC\n" +
+		"\n" +
+		"  MC  Low Conifer Mountains\n" +
+		"  pr  Prairie\n" +
+		"  sw  Swamp\n" +
+		"  ~~  Ocean\n"
+	if got := buf.String(); got != want {
+		t.Errorf("text: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestPages(t *testing.T) {
+	// a 10 by 6 block of prairie
+	worldMap := tiles.NewMap()
+	for column := 20; column < 30; column++ {
+		for row := 40; row < 46; row++ {
+			location := coords.Map{Column: column, Row: row}
+			worldMap.Tiles[location] = &tiles.Tile_t{Location: location, Terrain: terrain.Prairie}
+		}
+	}
+	m := render.NewMap("test", worldMap)
+
+	for _, tc := range []struct {
+		id                     int
+		columns, rows, overlap int
+		want                   []string
+	}{
+		{1, 10, 6, 0, []string{"page-1-1"}},
+		{2, 6, 4, 2, []string{"page-1-1", "page-1-2", "page-2-1", "page-2-2"}},
+		{3, 4, 4, 1, []string{"page-1-1", "page-1-2", "page-1-3", "page-1-4", "page-2-1", "page-2-2", "page-2-3", "page-2-4"}},
+		// an odd step is shortened to keep the pages on even columns
+		{4, 7, 6, 0, []string{"page-1-1", "page-1-2"}},
+	} {
+		pages, err := m.Pages(tc.columns, tc.rows, tc.overlap)
+		if err != nil {
+			t.Fatalf("%d: %v", tc.id, err)
+		}
+		var names []string
+		for _, page := range pages {
+			names = append(names, page.Name)
+			if page.Column%2 != 0 {
+				t.Errorf("%d: %s: starts on odd column %d", tc.id, page.Name, page.Column)
+			}
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tc.want) {
+			t.Errorf("%d: want %v, got %v", tc.id, tc.want, names)
+		}
+		// every hex must be on at least one page
+		for _, hex := range m.Hexes {
+			found := false
+			for _, page := range pages {
+				found = found || page.Contains(hex.Column, hex.Row)
+			}
+			if !found {
+				t.Errorf("%d: hex %d, %d is not on a page", tc.id, hex.Column, hex.Row)
+			}
+		}
+	}
+
+	if _, err := m.Pages(6, 4, 4); err == nil {
+		t.Errorf("overlap: want error, got nil")
+	}
+
+	pages, _ := m.Pages(6, 4, 2)
+	var buf bytes.Buffer
+	if err := render.PageIndex(&buf, m, pages); err != nil {
+		t.Fatal(err)
+	} else if got := strings.Count(buf.String(), "<rect "); got != len(pages) {
+		t.Errorf("index: want %d pages, got %d", len(pages), got)
+	}
+	if column, row := m.Locate(coords.Map{Column: 21, Row: 42}); column != 1 || row != 2 {
+		t.Errorf("locate: want 1, 2, got %d, %d", column, row)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Text renders the map as text for a terminal, two characters for each hex.
+// The odd columns are printed on the lines between the even columns, so each
+// hex is next to the same hexes that it is on the map.
+type Text struct {
+	Center *coords.Map // if set, only print the hexes within Radius of this one
+	Radius int
+	Color  bool // if true, shade each hex with the terrain color using ANSI escapes
+}
+
+// TextSymbols are the two characters printed for each terrain. Flat land and
+// hills are lower case, mountains are upper case, and water starts with "~".
+var TextSymbols = map[terrain.Terrain_e]string{
+	terrain.Blank:                "  ",
+	terrain.Alps:                 "AL",
+	terrain.AridHills:            "ah",
+	terrain.AridTundra:           "at",
+	terrain.BrushFlat:            "bf",
+	terrain.BrushHills:           "bh",
+	terrain.ConiferHills:         "ch",
+	terrain.Deciduous:            "dd",
+	terrain.DeciduousHills:       "dh",
+	terrain.Desert:               "de",
+	terrain.GrassyHills:          "gh",
+	terrain.GrassyHillsPlateau:   "gp",
+	terrain.HighSnowyMountains:   "HS",
+	terrain.Jungle:               "jj",
+	terrain.JungleHills:          "jh",
+	terrain.Lake:                 "~l",
+	terrain.LowAridMountains:     "MA",
+	terrain.LowConiferMountains:  "MC",
+	terrain.LowJungleMountains:   "MJ",
+	terrain.LowSnowyMountains:    "MS",
+	terrain.LowVolcanicMountains: "MV",
+	terrain.Ocean:                "~~",
+	terrain.PolarIce:             "**",
+	terrain.Prairie:              "pr",
+	terrain.PrairiePlateau:       "pp",
+	terrain.RockyHills:           "rh",
+	terrain.SnowyHills:           "sh",
+	terrain.Swamp:                "sw",
+	terrain.Tundra:               "tu",
+	terrain.UnknownJungleSwamp:   "?s",
+	terrain.UnknownLand:          "?l",
+	terrain.UnknownMountain:      "?m",
+	terrain.UnknownWater:         "?w",
+}
+
+func (Text) Extension() string {
+	return ".txt"
+}
+
+func (t Text) Render(w io.Writer, m *Map_t) error {
+	hexes := map[[2]int]*Hex_t{}
+	first, last := [2]int{-1, -1}, [2]int{-1, -1}
+	for _, hex := range m.Hexes {
+		if t.Center != nil && t.Center.Distance(hex.Location) > t.Radius {
+			continue
+		}
+		hexes[[2]int{hex.Column, hex.Row}] = hex
+		if first[0] == -1 {
+			first, last = [2]int{hex.Column, hex.Row}, [2]int{hex.Column, hex.Row}
+		}
+		first = [2]int{min(first[0], hex.Column), min(first[1], hex.Row)}
+		last = [2]int{max(last[0], hex.Column), max(last[1], hex.Row)}
+	}
+
+	bw := bufio.NewWriter(w)
+	if t.Center != nil {
+		fmt.Fprintf(bw, "%s: hexes within %d of %s\n", m.Name, t.Radius, t.Center.GridString())
+	} else {
+		fmt.Fprintf(bw, "%s\n", m.Name)
+	}
+	if len(hexes) == 0 {
+		fmt.Fprintf(bw, "no hexes to show\n")
+		return bw.Flush()
+	}
+
+	// start on an even column so that the odd columns are still the ones shifted down
+	first[0] -= first[0] % 2
+	used := map[terrain.Terrain_e]bool{}
+	for row := first[1]; row <= last[1]; row++ {
+		for half := 0; half < 2; half++ {
+			var line strings.Builder
+			for column := first[0]; column <= last[0]; column++ {
+				hex, ok := hexes[[2]int{column, row}]
+				if column%2 != half || !ok {
+					line.WriteString("  ")
+					continue
+				}
+				used[hex.Terrain] = true
+				symbol := TextSymbols[hex.Terrain]
+				if symbol == "" {
+					symbol = "??"
+				}
+				if !t.Color {
+					line.WriteString(symbol)
+					continue
+				}
+				r, g, b := rgb(Color(hex.Terrain))
+				if t.Center != nil && hex.Location == *t.Center {
+					line.WriteString("\x1b[7m") // reverse video for the center hex
+				}
+				fmt.Fprintf(&line, "\x1b[30;48;2;%d;%d;%dm%s\x1b[0m", r, g, b, symbol)
+			}
+			fmt.Fprintf(bw, "%s\n", strings.TrimRight(line.String(), " "))
+		}
+	}
+
+	// the key lists the terrains that are on the map
+	var key []terrain.Terrain_e
+	for k := range used {
+		key = append(key, k)
+	}
+	sort.Slice(key, func(i, j int) bool {
+		return TextSymbols[key[i]] < TextSymbols[key[j]]
+	})
+	for _, k := range key {
+		fmt.Fprintf(bw, "  %s  %s\n", TextSymbols[k], terrain.EnumToName[k])
+	}
+	return bw.Flush()
+}
+
+// rgb returns the red, green, and blue values of a "#rrggbb" color.
+func rgb(color string) (r, g, b int64) {
+	r, _ = strconv.ParseInt(color[1:3], 16, 0)
+	g, _ = strconv.ParseInt(color[3:5], 16, 0)
+	b, _ = strconv.ParseInt(color[5:7], 16, 0)
+	return r, g, b
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package search finds settlements, special hexes, units, and notes in the merged map.
+package search
+
+import (
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"sort"
+	"strings"
+)
+
+// Kinds of things that can be found.
+const (
+	Settlement = "settlement"
+	Special    = "special"
+	Unit       = "unit"      // a unit that has moves in the reports
+	Encounter  = "encounter" // a unit that was only seen by other units
+	Note       = "note"      // a label or note from the annotations file
+)
+
+// Match_t is something whose name or text contains the query.
+type Match_t struct {
+	Kind      string
+	Name      string
+	Location  coords.Map
+	FirstSeen string // turn the thing was first reported, empty for notes on hexes that aren't on the map
+}
+
+// Find returns everything in the map, the turns, and the annotations that
+// contains the query, ignoring case. Units are reported at the last hex they
+// were in. The matches are sorted by name, kind, and location.
+// The turns must be sorted and walked so that the locations are set.
+func Find(query string, worldMap *tiles.Map_t, turns []*parser.Turn_t, notes []*annotations.Hex_t) []Match_t {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+	var matches []Match_t
+	found := func(kind, name string, location coords.Map, firstSeen string) {
+		if strings.Contains(strings.ToLower(name), query) {
+			matches = append(matches, Match_t{Kind: kind, Name: name, Location: location, FirstSeen: firstSeen})
+		}
+	}
+
+	type unit_t struct {
+		firstSeen string
+		location  coords.Map
+	}
+	units := map[parser.UnitId_t]*unit_t{}
+	for _, turn := range turns {
+		for _, moves := range turn.SortedMoves {
+			u, ok := units[moves.UnitId]
+			if !ok {
+				u = &unit_t{firstSeen: turn.Id}
+				units[moves.UnitId] = u
+			}
+			if !moves.Location.IsZero() {
+				u.location = moves.Location
+			}
+		}
+	}
+	for id, u := range units {
+		found(Unit, string(id), u.location, u.firstSeen)
+	}
+
+	// units that were only encountered are listed once for each hex they were seen in
+	for location, tile := range worldMap.Tiles {
+		for _, s := range tile.Settlements {
+			found(Settlement, s.Name, location, or(s.TurnId, tile.FirstSeen))
+		}
+		for _, s := range tile.Special {
+			found(Special, s.Name, location, or(s.TurnId, tile.FirstSeen))
+		}
+		encountered := map[parser.UnitId_t]string{}
+		for _, e := range tile.Encounters {
+			if _, ok := units[e.UnitId]; ok {
+				continue
+			} else if turnId, ok := encountered[e.UnitId]; !ok || e.TurnId < turnId {
+				encountered[e.UnitId] = e.TurnId
+			}
+		}
+		for id, turnId := range encountered {
+			found(Encounter, string(id), location, turnId)
+		}
+	}
+
+	for _, hex := range notes {
+		var firstSeen string
+		if tile, ok := worldMap.Tiles[hex.Location]; ok {
+			firstSeen = tile.FirstSeen
+		}
+		for _, a := range hex.Annotations {
+			if a.Label != "" && a.Note != "" {
+				found(Note, a.Label+": "+a.Note, hex.Location, firstSeen)
+			} else {
+				found(Note, a.Label+a.Note, hex.Location, firstSeen)
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		} else if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Location.GridString() < b.Location.GridString()
+	})
+	return matches
+}
+
+func or(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
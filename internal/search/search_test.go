@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package search_test
+
+import (
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/search"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	home, bree := coords.Map{Column: 10, Row: 10}, coords.Map{Column: 11, Row: 10}
+	worldMap := tiles.NewMap()
+	worldMap.FetchTile("0138", home).FirstSeen = "0902-01"
+	tile := worldMap.FetchTile("0138", bree)
+	tile.FirstSeen = "0902-02"
+	tile.Settlements = append(tile.Settlements, &parser.Settlement_t{TurnId: "0902-02", Name: "Bree"})
+	tile.Encounters = append(tile.Encounters,
+		&parser.Encounter_t{TurnId: "0902-03", UnitId: "0138e1"},
+		&parser.Encounter_t{TurnId: "0902-03", UnitId: "1138"},
+		&parser.Encounter_t{TurnId: "0902-02", UnitId: "1138"})
+	turns := []*parser.Turn_t{
+		{Id: "0902-01", SortedMoves: []*parser.Moves_t{{UnitId: "0138", Location: home}}},
+		{Id: "0902-02", SortedMoves: []*parser.Moves_t{{UnitId: "0138", Location: bree}, {UnitId: "0138e1", Location: bree}}},
+	}
+	notes := []*annotations.Hex_t{{Location: home, Annotations: []*annotations.Annotation_t{{Label: "Camp", Note: "near Bree"}}}}
+
+	for _, tc := range []struct {
+		query string
+		want  []search.Match_t
+	}{
+		{"BREE", []search.Match_t{
+			{Kind: search.Settlement, Name: "Bree", Location: bree, FirstSeen: "0902-02"},
+			{Kind: search.Note, Name: "Camp: near Bree", Location: home, FirstSeen: "0902-01"},
+		}},
+		// our own units aren't listed as encounters
+		{"138", []search.Match_t{
+			{Kind: search.Unit, Name: "0138", Location: bree, FirstSeen: "0902-01"},
+			{Kind: search.Unit, Name: "0138e1", Location: bree, FirstSeen: "0902-02"},
+			{Kind: search.Encounter, Name: "1138", Location: bree, FirstSeen: "0902-02"},
+		}},
+		{"  ", nil},
+		{"rivendell", nil},
+	} {
+		got := search.Find(tc.query, worldMap, turns, notes)
+		if len(got) != len(tc.want) {
+			t.Errorf("%q: want %d matches, got %d: %+v", tc.query, len(tc.want), len(got), got)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%q: %d: want %+v, got %+v", tc.query, i, tc.want[i], got[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package stats counts what has been found on the merged map,
+// for example to track how much of a grid has been explored.
+package stats
+
+import (
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"sort"
+)
+
+// hexesPerGrid is the number of hexes in a grid, which is 30 columns by 21 rows.
+const hexesPerGrid = 30 * 21
+
+// Stats_t is the summary of the merged map.
+type Stats_t struct {
+	Hexes       int       `json:"hexes"`
+	Scouted     int       `json:"scouted"`  // hexes that a unit scouted
+	Visited     int       `json:"visited"`  // hexes that a unit was in but didn't scout
+	Inferred    int       `json:"inferred"` // hexes that were only seen from a neighboring hex
+	Settlements int       `json:"settlements"`
+	Resources   int       `json:"resources"`
+	Encounters  int       `json:"encounters"`
+	Grids       []Count_t `json:"grids"`   // percent is of the hexes in the grid
+	Terrain     []Count_t `json:"terrain"` // percent is of the hexes on the map
+	Turns       []Turn_t  `json:"turns"`
+}
+
+// Count_t is the number of hexes with a property.
+type Count_t struct {
+	Name    string  `json:"name"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// Turn_t is the exploration in a single turn.
+type Turn_t struct {
+	Turn  string `json:"turn"`
+	New   int    `json:"new"`   // hexes first reported in the turn
+	Total int    `json:"total"` // hexes reported in this turn or earlier
+}
+
+// Compute returns the summary of the map. Grids and terrain are sorted by name and turns by id.
+func Compute(worldMap *tiles.Map_t) *Stats_t {
+	s := &Stats_t{Grids: []Count_t{}, Terrain: []Count_t{}, Turns: []Turn_t{}}
+	grids, terrains, turns := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, tile := range worldMap.Tiles {
+		s.Hexes++
+		if tile.Scouted != "" {
+			s.Scouted++
+		} else if tile.Visited != "" {
+			s.Visited++
+		} else {
+			s.Inferred++
+		}
+		s.Settlements += len(tile.Settlements)
+		s.Resources += len(tile.Resources)
+		s.Encounters += len(tile.Encounters)
+		grids[tile.Location.GridId()]++
+		if tile.Terrain == terrain.Blank {
+			terrains["Unknown"]++
+		} else if name, ok := terrain.EnumToName[tile.Terrain]; ok {
+			terrains[name]++
+		} else {
+			terrains[tile.Terrain.String()]++
+		}
+		if tile.FirstSeen != "" {
+			turns[tile.FirstSeen]++
+		}
+	}
+
+	for name, count := range grids {
+		s.Grids = append(s.Grids, Count_t{Name: name, Count: count, Percent: Percent(count, hexesPerGrid)})
+	}
+	sort.Slice(s.Grids, func(i, j int) bool {
+		return s.Grids[i].Name < s.Grids[j].Name
+	})
+	for name, count := range terrains {
+		s.Terrain = append(s.Terrain, Count_t{Name: name, Count: count, Percent: Percent(count, s.Hexes)})
+	}
+	sort.Slice(s.Terrain, func(i, j int) bool {
+		return s.Terrain[i].Name < s.Terrain[j].Name
+	})
+	for turn, count := range turns {
+		s.Turns = append(s.Turns, Turn_t{Turn: turn, New: count})
+	}
+	sort.Slice(s.Turns, func(i, j int) bool {
+		return s.Turns[i].Turn < s.Turns[j].Turn
+	})
+	total := 0
+	for n := range s.Turns {
+		total += s.Turns[n].New
+		s.Turns[n].Total = total
+	}
+
+	return s
+}
+
+// Percent returns n as a percentage of total, rounded to one decimal place.
+func Percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(int(1000*float64(n)/float64(total)+0.5)) / 10
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package stats_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/stats"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"reflect"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	worldMap := tiles.NewMap()
+	tile := worldMap.FetchTile("0138", coords.Map{Column: 10, Row: 10})
+	tile.Terrain, tile.Visited, tile.Scouted = terrain.Prairie, "0902-02", "0902-02"
+	tile.MarkSeen("0902-02", "0138")
+	tile.Settlements = append(tile.Settlements, &parser.Settlement_t{TurnId: "0902-02", Name: "Alpha"})
+	tile = worldMap.FetchTile("0138", coords.Map{Column: 11, Row: 10})
+	tile.Terrain, tile.Visited = terrain.Prairie, "0902-03"
+	tile.MarkSeen("0902-03", "0138")
+	tile.Resources = append(tile.Resources, resources.Coal)
+	tile = worldMap.FetchTile("0138", coords.Map{Column: 40, Row: 10})
+	tile.Terrain = terrain.Lake
+	tile.MarkSeen("0902-03", "0138")
+	tile.Encounters = append(tile.Encounters, &parser.Encounter_t{TurnId: "0902-03", UnitId: "1590e1"})
+
+	got := stats.Compute(worldMap)
+	want := &stats.Stats_t{
+		Hexes: 3, Scouted: 1, Visited: 1, Inferred: 1,
+		Settlements: 1, Resources: 1, Encounters: 1,
+		Grids: []stats.Count_t{{Name: "AA", Count: 2, Percent: 0.3}, {Name: "AB", Count: 1, Percent: 0.2}},
+		Terrain: []stats.Count_t{
+			{Name: terrain.EnumToName[terrain.Lake], Count: 1, Percent: 33.3},
+			{Name: terrain.EnumToName[terrain.Prairie], Count: 2, Percent: 66.7},
+		},
+		Turns: []stats.Turn_t{{Turn: "0902-02", New: 1, Total: 1}, {Turn: "0902-03", New: 2, Total: 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
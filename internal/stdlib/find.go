@@ -16,7 +16,7 @@ import (
 type File_t struct {
 	Path     string    // full path to file
 	Name     string    // file name
-	Kind     string    // docx, txt
+	Kind     string    // text, word, odt, or rtf
 	Year     int       // year from file name
 	Month    int       // month from file name
 	Unit     string    // unit name from file name
@@ -25,10 +25,10 @@ type File_t struct {
 }
 
 var (
-	rxTurnReportFile = regexp.MustCompile(`^(\d{4})-(\d{2})\.(\d{4}([cefg]\d)?)\.report\.(docx|txt)$`)
+	rxTurnReportFile = regexp.MustCompile(`^(\d{4})-(\d{2})\.(\d{4}([cefg]\d)?)\.report\.(docx|odt|rtf|txt)$`)
 )
 
-// FindAllInputs returns a list of all DOCX and TXT files in the requested path.
+// FindAllInputs returns a list of all DOCX, ODT, RTF, and TXT files in the requested path.
 // The list is sorted by timestamp and then name.
 func FindAllInputs(path string) ([]*File_t, error) {
 	// search for report files in the requested path
@@ -120,9 +120,12 @@ func FindInput(path string, name string) (*File_t, error) {
 		item.Year, _ = strconv.Atoi(matches[1])
 		item.Month, _ = strconv.Atoi(matches[2])
 		item.Unit = matches[3]
-		if matches[4] == "txt" {
+		switch matches[5] {
+		case "odt", "rtf":
+			item.Kind = matches[5]
+		case "txt":
 			item.Kind = "text"
-		} else {
+		default:
 			item.Kind = "word"
 		}
 	}
@@ -0,0 +1,58 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package stdlib_test
+
+import (
+	"github.com/playbymail/ottomap/internal/stdlib"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindInput(t *testing.T) {
+	path := t.TempDir()
+	for _, tc := range []struct {
+		id    int
+		name  string
+		kind  string
+		year  int
+		month int
+		unit  string
+		err   bool
+	}{
+		{1, "0902-02.0138.report.txt", "text", 902, 2, "0138", false},
+		{2, "0902-02.0138e1.report.txt", "text", 902, 2, "0138e1", false},
+		{3, "0902-03.0138.report.docx", "word", 902, 3, "0138", false},
+		{4, "0902-03.0138f2.report.odt", "odt", 902, 3, "0138f2", false},
+		{5, "0902-04.0138.report.rtf", "rtf", 902, 4, "0138", false},
+		{6, "0902-04.0138.report.pdf", "", 0, 0, "", true},
+	} {
+		if err := os.WriteFile(filepath.Join(path, tc.name), []byte(tc.name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := stdlib.FindInput(path, tc.name)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d: %q: want error, got %+v", tc.id, tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %q: error %v", tc.id, tc.name, err)
+			continue
+		}
+		if got.Kind != tc.kind {
+			t.Errorf("%d: %q: kind: want %q, got %q", tc.id, tc.name, tc.kind, got.Kind)
+		}
+		if got.Year != tc.year || got.Month != tc.month || got.Unit != tc.unit {
+			t.Errorf("%d: %q: want %04d-%02d.%s, got %04d-%02d.%s", tc.id, tc.name, tc.year, tc.month, tc.unit, got.Year, got.Month, got.Unit)
+		}
+		if got.Hash == "" {
+			t.Errorf("%d: %q: hash: want hash, got empty", tc.id, tc.name)
+		}
+	}
+
+	if _, err := stdlib.FindInput(path, "0902-05.0138.report.txt"); err == nil {
+		t.Errorf("missing: want error, got nil")
+	}
+}
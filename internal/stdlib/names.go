@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package stdlib
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	rxLooseReportName = regexp.MustCompile(`(?i)(?:^|[^0-9])(\d{3,4})-(\d{2})[._ -]+(\d{4}(?:[cefg]\d)?)(?:[^0-9]|$)`)
+	rxReportUnit      = regexp.MustCompile(`^(?:Tribe|Courier|Element|Garrison|Fleet) (\d{4}(?:[cefg]\d)?),`)
+	rxReportTurn      = regexp.MustCompile(`Current Turn (\d{3,4})-(\d{2}) `)
+)
+
+// ReportName returns the name of the report file in the YYYY-MM.UNIT.report.(docx|odt|rtf|txt) format.
+// The turn and unit are taken from the file name when it contains them (for example,
+// "902-02 0138.txt"), otherwise from the first unit and turn headers in the text of the report.
+// The extension of the name is kept.
+func ReportName(name string, text []byte) (string, error) {
+	var kind string
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".docx":
+		kind = "docx"
+	case ".odt":
+		kind = "odt"
+	case ".rtf":
+		kind = "rtf"
+	case ".txt":
+		kind = "txt"
+	default:
+		return "", fmt.Errorf("%s: not a docx, odt, rtf, or txt file", name)
+	}
+
+	var year, month int
+	var unit string
+	if matches := rxLooseReportName.FindStringSubmatch(filepath.Base(name)); matches != nil {
+		year, _ = strconv.Atoi(matches[1])
+		month, _ = strconv.Atoi(matches[2])
+		unit = strings.ToLower(matches[3])
+	} else {
+		for _, line := range bytes.Split(text, []byte{'\n'}) {
+			line = bytes.TrimSpace(line)
+			if unit == "" {
+				if matches := rxReportUnit.FindSubmatch(line); matches != nil {
+					unit = strings.ToLower(string(matches[1]))
+				}
+			} else if matches := rxReportTurn.FindSubmatch(line); matches != nil {
+				year, _ = strconv.Atoi(string(matches[1]))
+				month, _ = strconv.Atoi(string(matches[2]))
+				break
+			}
+		}
+		if unit == "" || year == 0 {
+			return "", fmt.Errorf("%s: unable to find unit and turn in report", name)
+		}
+	}
+	if year < 899 || year > 9999 || month < 1 || month > 12 {
+		return "", fmt.Errorf("%s: invalid turn %d-%02d", name, year, month)
+	}
+
+	normalized := fmt.Sprintf("%04d-%02d.%s.report.%s", year, month, unit, kind)
+	if rxTurnReportFile.FindStringSubmatch(normalized) == nil {
+		return "", fmt.Errorf("%s: invalid report name %q", name, normalized)
+	}
+	return normalized, nil
+}
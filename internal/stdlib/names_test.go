@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package stdlib_test
+
+import (
+	"github.com/playbymail/ottomap/internal/stdlib"
+	"testing"
+)
+
+func TestReportName(t *testing.T) {
+	report := []byte("Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\nCurrent Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n")
+	for _, tc := range []struct {
+		id   int
+		name string
+		text []byte
+		want string
+		err  bool
+	}{
+		{1, "0902-02.0138.report.txt", nil, "0902-02.0138.report.txt", false},
+		{2, "902-02 0138e1.TXT", nil, "0902-02.0138e1.report.txt", false},
+		{3, "Clan 0138 - 0902-02.0138.docx", nil, "0902-02.0138.report.docx", false},
+		{4, "turn-report.txt", report, "0902-02.0138.report.txt", false},
+		{5, "attachment.docx", report, "0902-02.0138.report.docx", false},
+		{6, "turn-report.txt", []byte("hello"), "", true},
+		{7, "0902-02.0138.report.pdf", nil, "", true},
+		{8, "0902-13.0138.txt", nil, "", true},
+	} {
+		got, err := stdlib.ReportName(tc.name, tc.text)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%d: %q: want error, got %q", tc.id, tc.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: %q: error %v", tc.id, tc.name, err)
+		} else if got != tc.want {
+			t.Errorf("%d: %q: got %q, want %q", tc.id, tc.name, got, tc.want)
+		}
+	}
+}
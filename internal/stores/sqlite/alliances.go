@@ -0,0 +1,104 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// CreateAlliance creates a new alliance.
+// Returns the id of the new alliance.
+func (s *Store) CreateAlliance(name string) (int, error) {
+	if name == "" || strings.TrimSpace(name) != name {
+		return 0, ErrInvalidAlliance
+	}
+	id, err := s.q.CreateAlliance(s.ctx, name)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "constraint failed: UNIQUE constraint failed: alliances.name (") {
+			return 0, ErrDuplicateAlliance
+		}
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// JoinAlliance adds the clan to the alliance, or updates whether the clan
+// shares its reports with the other members.
+func (s *Store) JoinAlliance(name string, clan int, shares bool) error {
+	if !(0 < clan && clan < 1000) {
+		return ErrInvalidClanId
+	}
+	alliance, err := s.q.GetAllianceByName(s.ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidAlliance
+		}
+		return err
+	}
+	var flag int64
+	if shares {
+		flag = 1
+	}
+	return s.q.UpsertAllianceMember(s.ctx, UpsertAllianceMemberParams{
+		AllianceID: alliance.ID,
+		Clan:       int64(clan),
+		Shares:     flag,
+	})
+}
+
+// LeaveAlliance removes the clan from the alliance.
+// Returns nil if the clan was not a member.
+func (s *Store) LeaveAlliance(name string, clan int) error {
+	if !(0 < clan && clan < 1000) {
+		return ErrInvalidClanId
+	}
+	return s.q.DeleteAllianceMember(s.ctx, DeleteAllianceMemberParams{
+		Name: name,
+		Clan: int64(clan),
+	})
+}
+
+// SetAllianceSharing sets whether the clan shares its reports with the alliance.
+// Returns ErrNotAllianceMember if the clan has not joined the alliance.
+func (s *Store) SetAllianceSharing(name string, clan int, shares bool) error {
+	if !(0 < clan && clan < 1000) {
+		return ErrInvalidClanId
+	}
+	var flag int64
+	if shares {
+		flag = 1
+	}
+	n, err := s.q.UpdateAllianceSharing(s.ctx, UpdateAllianceSharingParams{
+		Shares: flag,
+		Clan:   int64(clan),
+		Name:   name,
+	})
+	if err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotAllianceMember
+	}
+	return nil
+}
+
+// GetSharingClans returns the other clans that share their reports with the clan.
+// If no clans share with the clan, an empty list is returned.
+func (s *Store) GetSharingClans(clan int) ([]int, error) {
+	if !(0 < clan && clan < 1000) {
+		return nil, ErrInvalidClanId
+	}
+	rows, err := s.q.GetSharingClans(s.ctx, int64(clan))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []int
+	for _, row := range rows {
+		list = append(list, int(row))
+	}
+	return list, nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite_test
+
+import (
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+)
+
+func TestAlliances(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	store := openStore(t)
+	for _, name := range []string{"north", "south"} {
+		if _, err := store.CreateAlliance(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.CreateAlliance("north"); err != sqlite.ErrDuplicateAlliance {
+		t.Errorf("create: want %v, got %v", sqlite.ErrDuplicateAlliance, err)
+	}
+	if err := store.JoinAlliance("west", 138, true); err != sqlite.ErrInvalidAlliance {
+		t.Errorf("join: want %v, got %v", sqlite.ErrInvalidAlliance, err)
+	}
+	if err := store.SetAllianceSharing("south", 138, true); err != sqlite.ErrNotAllianceMember {
+		t.Errorf("sharing: want %v, got %v", sqlite.ErrNotAllianceMember, err)
+	}
+
+	// 138 is in both alliances; 249 shares with north, 250 doesn't share, 251 shares with south
+	for _, m := range []struct {
+		alliance string
+		clan     int
+		shares   bool
+	}{
+		{"north", 138, true},
+		{"north", 249, true},
+		{"north", 250, false},
+		{"south", 138, false},
+		{"south", 249, true},
+		{"south", 251, true},
+	} {
+		if err := store.JoinAlliance(m.alliance, m.clan, m.shares); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		id     int
+		change func() error
+		clan   int
+		want   []int
+	}{
+		{1, nil, 138, []int{249, 251}},
+		{2, nil, 250, []int{138, 249}},
+		{3, func() error { return store.SetAllianceSharing("north", 250, true) }, 138, []int{249, 250, 251}},
+		{4, func() error { return store.LeaveAlliance("south", 251) }, 138, []int{249, 250}},
+		{5, func() error { return store.LeaveAlliance("north", 138) }, 138, []int{249}},
+		{6, nil, 999, nil},
+	} {
+		if tc.change != nil {
+			if err := tc.change(); err != nil {
+				t.Fatalf("%d: %v", tc.id, err)
+			}
+		}
+		got, err := store.GetSharingClans(tc.clan)
+		if err != nil {
+			t.Errorf("%d: %v", tc.id, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%d: clan %d: want %v, got %v", tc.id, tc.clan, tc.want, got)
+		}
+	}
+}
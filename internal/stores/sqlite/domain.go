@@ -2,6 +2,35 @@
 
 package sqlite
 
+import "time"
+
+// Job_t is a parse or render request that runs in the background.
+type Job_t struct {
+	ID          int
+	Kind        string // JobParse or JobRender
+	Status      string // JobQueued, JobRunning, JobDone, or JobFailed
+	Input       []byte // set only when the job is claimed
+	Error       string // error from the last attempt
+	Phase       string // phase of the running job
+	Percent     float64
+	Attempts    int
+	MaxAttempts int
+	Created     time.Time
+	Updated     time.Time
+}
+
+const (
+	JobParse  = "parse"
+	JobRender = "render"
+)
+
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
 type Report_t struct {
 	ID    int
 	Clan  int
@@ -11,3 +40,9 @@ type Report_t struct {
 	Hash  string
 	Lines string
 }
+
+type User_t struct {
+	ID     int
+	Handle string
+	Clan   int
+}
@@ -11,16 +11,25 @@ func (e Error) Error() string {
 const (
 	ErrCreateSchema        = Error("create schema")
 	ErrDatabaseExists      = Error("database exists")
+	ErrDuplicateAlliance   = Error("duplicate alliance")
+	ErrDuplicateHandle     = Error("duplicate handle")
 	ErrDuplicateHash       = Error("duplicate hash")
 	ErrDuplicateReportName = Error("duplicate report name")
 	ErrForeignKeysDisabled = Error("foreign keys disabled")
+	ErrInvalidAlliance     = Error("invalid alliance")
 	ErrInvalidClanId       = Error("invalid clan id")
+	ErrInvalidCredentials  = Error("invalid credentials")
+	ErrInvalidHandle       = Error("invalid handle")
 	ErrInvalidHash         = Error("invalid hash")
+	ErrInvalidJob          = Error("invalid job")
 	ErrInvalidPath         = Error("invalid path")
+	ErrInvalidSecret       = Error("invalid secret")
 	ErrInvalidMonth        = Error("invalid month")
 	ErrInvalidUnit         = Error("invalid unit")
 	ErrInvalidYear         = Error("invalid year")
+	ErrNotAllianceMember   = Error("not an alliance member")
 	ErrNotDirectory        = Error("not a directory")
 	ErrNotFound            = Error("not found")
 	ErrPragmaReturnedNil   = Error("pragma returned nil")
+	ErrSchemaTooNew        = Error("schema is newer than this release")
 )
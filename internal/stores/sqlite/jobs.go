@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateJob adds a job to the queue.
+// Returns the id of the new job.
+func (s *Store) CreateJob(kind string, input []byte, maxAttempts int) (int, error) {
+	if !(kind == JobParse || kind == JobRender) {
+		return 0, ErrInvalidJob
+	} else if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	id, err := s.q.CreateJob(s.ctx, CreateJobParams{
+		Kind:        kind,
+		Input:       string(input),
+		MaxAttempts: int64(maxAttempts),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// ClaimJob marks the oldest job that is ready to run as running and returns it.
+// Returns nil if there are no jobs ready to run.
+func (s *Store) ClaimJob(now time.Time) (*Job_t, error) {
+	row, err := s.q.ClaimJob(s.ctx, now.Unix())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &Job_t{
+		ID:          int(row.ID),
+		Kind:        row.Kind,
+		Status:      JobRunning,
+		Input:       []byte(row.Input),
+		Attempts:    int(row.Attempts),
+		MaxAttempts: int(row.MaxAttempts),
+	}, nil
+}
+
+// FailJob records the error from a job. If the job has attempts left,
+// it is queued to run again after retryAt. Otherwise, it is marked as failed.
+// Returns the new status of the job.
+func (s *Store) FailJob(job *Job_t, msg string, retryAt time.Time) (string, error) {
+	status := JobFailed
+	if job.Attempts < job.MaxAttempts {
+		status = JobQueued
+	}
+	return status, s.q.FailJob(s.ctx, FailJobParams{
+		Status:   status,
+		Error:    msg,
+		RunAfter: retryAt.Unix(),
+		ID:       int64(job.ID),
+	})
+}
+
+// FinishJob records the result of a job that succeeded.
+func (s *Store) FinishJob(id int, result []byte) error {
+	return s.q.FinishJob(s.ctx, FinishJobParams{Result: result, ID: int64(id)})
+}
+
+// GetJob returns the status of a job.
+// Returns ErrNotFound if there is no such job.
+func (s *Store) GetJob(id int) (*Job_t, error) {
+	row, err := s.q.GetJob(s.ctx, int64(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &Job_t{
+		ID:          int(row.ID),
+		Kind:        row.Kind,
+		Status:      row.Status,
+		Error:       row.Error,
+		Phase:       row.Phase,
+		Percent:     row.Percent,
+		Attempts:    int(row.Attempts),
+		MaxAttempts: int(row.MaxAttempts),
+		Created:     time.Unix(row.Created, 0).UTC(),
+		Updated:     time.Unix(row.Updated, 0).UTC(),
+	}, nil
+}
+
+// GetJobResult returns the status and result of a job.
+// The result is nil until the job is done.
+// Returns ErrNotFound if there is no such job.
+func (s *Store) GetJobResult(id int) (string, []byte, error) {
+	row, err := s.q.GetJobResult(s.ctx, int64(id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, ErrNotFound
+		}
+		return "", nil, err
+	}
+	return row.Status, row.Result, nil
+}
+
+// RequeueRunningJobs puts jobs that were running when the server stopped back on the queue.
+// Returns the number of jobs requeued.
+func (s *Store) RequeueRunningJobs() (int, error) {
+	n, err := s.q.RequeueRunningJobs(s.ctx)
+	return int(n), err
+}
+
+// UpdateJobProgress records the phase and percent complete of a running job.
+func (s *Store) UpdateJobProgress(id int, phase string, percent float64) error {
+	return s.q.UpdateJobProgress(s.ctx, UpdateJobProgressParams{Phase: phase, Percent: percent, ID: int64(id)})
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite_test
+
+import (
+	"context"
+	"io"
+	"log"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+)
+
+func TestJobQueue(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	if err := sqlite.Create(path, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.Open(path, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	now := time.Now()
+	if job, err := store.ClaimJob(now); err != nil || job != nil {
+		t.Fatalf("empty queue: want nil, nil: got %v, %v", job, err)
+	}
+	first, err := store.CreateJob(sqlite.JobRender, []byte(`{"clanId":"0138"}`), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := store.CreateJob(sqlite.JobParse, []byte(`{}`), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateJob("draw", nil, 1); err != sqlite.ErrInvalidJob {
+		t.Errorf("kind: want %v, got %v", sqlite.ErrInvalidJob, err)
+	}
+
+	// jobs are claimed oldest first, and a failed job waits until it is ready to run again
+	job, err := store.ClaimJob(now)
+	if err != nil || job == nil || job.ID != first || job.Attempts != 1 || string(job.Input) != `{"clanId":"0138"}` {
+		t.Fatalf("claim: want job %d attempt 1, got %+v, %v", first, job, err)
+	}
+	if err := store.UpdateJobProgress(first, "map", 50); err != nil {
+		t.Fatal(err)
+	} else if got, err := store.GetJob(first); err != nil || got.Status != sqlite.JobRunning || got.Phase != "map" || got.Percent != 50 {
+		t.Errorf("progress: got %+v, %v", got, err)
+	}
+	if status, err := store.FailJob(job, "timed out", now.Add(time.Minute)); err != nil || status != sqlite.JobQueued {
+		t.Errorf("fail: want %q, got %q, %v", sqlite.JobQueued, status, err)
+	}
+	if job, err := store.ClaimJob(now); err != nil || job == nil || job.ID != second {
+		t.Fatalf("claim: want job %d, got %+v, %v", second, job, err)
+	}
+	if job, err := store.ClaimJob(now); err != nil || job != nil {
+		t.Fatalf("claim before retry: want nil, got %+v, %v", job, err)
+	}
+
+	// the last attempt fails the job for good
+	job, err = store.ClaimJob(now.Add(time.Minute))
+	if err != nil || job == nil || job.ID != first || job.Attempts != 2 {
+		t.Fatalf("retry: want job %d attempt 2, got %+v, %v", first, job, err)
+	}
+	if status, err := store.FailJob(job, "timed out again", now); err != nil || status != sqlite.JobFailed {
+		t.Errorf("fail: want %q, got %q, %v", sqlite.JobFailed, status, err)
+	} else if got, err := store.GetJob(first); err != nil || got.Status != sqlite.JobFailed || got.Error != "timed out again" {
+		t.Errorf("failed: got %+v, %v", got, err)
+	}
+
+	// the running job is requeued after a restart, and its result is kept when it is done
+	if n, err := store.RequeueRunningJobs(); err != nil || n != 1 {
+		t.Errorf("requeue: want 1, got %d, %v", n, err)
+	}
+	if job, err := store.ClaimJob(now); err != nil || job == nil || job.ID != second {
+		t.Fatalf("claim: want job %d, got %+v, %v", second, job, err)
+	}
+	if err := store.FinishJob(second, []byte("turn")); err != nil {
+		t.Fatal(err)
+	} else if status, result, err := store.GetJobResult(second); err != nil || status != sqlite.JobDone || string(result) != "turn" {
+		t.Errorf("result: got %q, %q, %v", status, result, err)
+	}
+	if _, err := store.GetJob(999); err != sqlite.ErrNotFound {
+		t.Errorf("missing: want %v, got %v", sqlite.ErrNotFound, err)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// migration_t upgrades the schema by one version.
+type migration_t struct {
+	name string
+	up   func(tx *sql.Tx) error
+}
+
+// migrations upgrade a database created by an older release to the current schema.
+// The schema version is kept in "PRAGMA user_version," which is the number of migrations
+// that have been applied. Databases created before versioning start at 0.
+//
+// New tables and columns must be added to schema.sql too, since Create doesn't run migrations.
+// Never change or remove a migration once it is released; add a new one instead.
+var migrations = []migration_t{
+	{"users and alliances", execStatements(`
+CREATE TABLE IF NOT EXISTS users
+(
+    id            INTEGER PRIMARY KEY,
+    handle        TEXT    NOT NULL UNIQUE,
+    clan          INTEGER NOT NULL CHECK (clan BETWEEN 1 AND 999),
+    hashed_secret TEXT    NOT NULL,
+    created       INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+)`, `
+CREATE TABLE IF NOT EXISTS alliances
+(
+    id   INTEGER PRIMARY KEY,
+    name TEXT    NOT NULL UNIQUE
+)`, `
+CREATE TABLE IF NOT EXISTS alliance_members
+(
+    alliance_id INTEGER NOT NULL REFERENCES alliances (id) ON DELETE CASCADE,
+    clan        INTEGER NOT NULL CHECK (clan BETWEEN 1 AND 999),
+    shares      INTEGER NOT NULL DEFAULT 0 CHECK (shares in (0, 1)),
+    PRIMARY KEY (alliance_id, clan)
+)`)},
+	{"movement points", func(tx *sql.Tx) error {
+		for _, column := range []string{"mp_cost", "mp_remaining"} {
+			if ok, err := hasColumn(tx, "moves", column); err != nil {
+				return err
+			} else if ok {
+				continue
+			} else if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE moves ADD COLUMN %s INTEGER", column)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"jobs", execStatements(`
+CREATE TABLE IF NOT EXISTS jobs
+(
+    id           INTEGER PRIMARY KEY,
+    kind         TEXT    NOT NULL CHECK (kind in ('parse', 'render')),
+    status       TEXT    NOT NULL DEFAULT 'queued'
+        CHECK (status in ('queued', 'running', 'done', 'failed')),
+    input        TEXT    NOT NULL,
+    result       BLOB,
+    error        TEXT    NOT NULL DEFAULT '',
+    phase        TEXT    NOT NULL DEFAULT '',
+    percent      REAL    NOT NULL DEFAULT 0,
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 1 CHECK (max_attempts > 0),
+    run_after    INTEGER NOT NULL DEFAULT 0,
+    created      INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),
+    updated      INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))
+)`)},
+}
+
+// SchemaVersion is the version of the schema that this release uses.
+var SchemaVersion = len(migrations)
+
+// migrate applies the migrations that the database is missing, all in one transaction.
+// Returns ErrSchemaTooNew if the database was written by a newer release.
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	} else if version > SchemaVersion {
+		return ErrSchemaTooNew
+	} else if version == SchemaVersion {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	for n := version; n < SchemaVersion; n++ {
+		log.Printf("db: migrate: %d: %s\n", n+1, migrations[n].name)
+		if err := migrations[n].up(tx); err != nil {
+			return fmt.Errorf("migrate: %d: %s: %w", n+1, migrations[n].name, err)
+		}
+	}
+	if err := setSchemaVersion(tx, SchemaVersion); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// execStatements returns a migration that runs the statements in order.
+func execStatements(statements ...string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// hasColumn returns true if the table has the column.
+func hasColumn(tx *sql.Tx, table, column string) (bool, error) {
+	var found int
+	err := tx.QueryRow("SELECT 1 FROM pragma_table_info(?) WHERE name = ?", table, column).Scan(&found)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// setSchemaVersion records the version of the schema.
+// The pragma doesn't accept parameters, so the version is formatted into the statement.
+func setSchemaVersion(tx interface {
+	Exec(string, ...any) (sql.Result, error)
+}, version int) error {
+	_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	return err
+}
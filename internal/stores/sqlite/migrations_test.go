@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+)
+
+// TestMigrate opens a database created by the release before the schema was versioned.
+func TestMigrate(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	ddl, err := os.ReadFile(filepath.Join("testdata", "schema-v0.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "v0.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(string(ddl)); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	store, err := sqlite.Open(path, context.Background())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if _, err := store.CreateUser("alice", 138, "correct horse"); err != nil {
+		t.Errorf("users: %v", err)
+	}
+	if _, err := store.CreateAlliance("north"); err != nil {
+		t.Errorf("alliances: %v", err)
+	} else if err := store.JoinAlliance("north", 138, true); err != nil {
+		t.Errorf("alliance members: %v", err)
+	}
+	if _, err := store.CreateJob(sqlite.JobParse, []byte(`{}`), 1); err != nil {
+		t.Errorf("jobs: %v", err)
+	} else if job, err := store.ClaimJob(time.Now()); err != nil || job == nil {
+		t.Errorf("jobs: claim: want job, nil: got %v, %v", job, err)
+	}
+	_ = store.Close()
+
+	// opening again must not re-run the migrations
+	store, err = sqlite.Open(path, context.Background())
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	_ = store.Close()
+
+	db, err = sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version, columns int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	} else if version != sqlite.SchemaVersion {
+		t.Errorf("version: want %d, got %d", sqlite.SchemaVersion, version)
+	}
+	if err := db.QueryRow("SELECT count(*) FROM pragma_table_info('moves') WHERE name IN ('mp_cost', 'mp_remaining')").Scan(&columns); err != nil {
+		t.Fatal(err)
+	} else if columns != 2 {
+		t.Errorf("moves: want 2 movement point columns, got %d", columns)
+	}
+}
+
+// TestMigrateCreated checks that a new database is current and that a newer one is rejected.
+func TestMigrateCreated(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	path := filepath.Join(t.TempDir(), "new.db")
+	if err := sqlite.Create(path, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.Open(path, context.Background())
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = store.Close()
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		t.Fatal(err)
+	} else if version != sqlite.SchemaVersion {
+		t.Errorf("version: want %d, got %d", sqlite.SchemaVersion, version)
+	}
+	if _, err := db.Exec("PRAGMA user_version = 999"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	if _, err := sqlite.Open(path, context.Background()); !errors.Is(err, sqlite.ErrSchemaTooNew) {
+		t.Errorf("newer schema: want %v, got %v", sqlite.ErrSchemaTooNew, err)
+	}
+}
@@ -4,6 +4,99 @@
 
 package sqlite
 
+import (
+	"database/sql"
+)
+
+type Alliance struct {
+	ID   int64
+	Name string
+}
+
+type AllianceMember struct {
+	AllianceID int64
+	Clan       int64
+	Shares     int64
+}
+
+type BorderCode struct {
+	Code       string
+	Descr      string
+	WxxFeature string
+}
+
+type Clan struct {
+	ID int64
+}
+
+type ItemCode struct {
+	Code  string
+	Descr string
+}
+
+type Job struct {
+	ID          int64
+	Kind        string
+	Status      string
+	Input       string
+	Result      []byte
+	Error       string
+	Phase       string
+	Percent     float64
+	Attempts    int64
+	MaxAttempts int64
+	RunAfter    int64
+	Created     int64
+	Updated     int64
+}
+
+type Move struct {
+	ID            int64
+	TurnID        int64
+	UnitID        string
+	StepNo        int64
+	StartingTile  int64
+	Action        string
+	EndingTile    int64
+	TerrainCd     string
+	FailureReason sql.NullString
+	MpCost        sql.NullInt64
+	MpRemaining   sql.NullInt64
+}
+
+type MoveBorderDetail struct {
+	MoveID   int64
+	BorderCd string
+	Edge     string
+}
+
+type MovePassageDetail struct {
+	MoveID    int64
+	PassageCd string
+	Edge      string
+}
+
+type MoveResourceDetail struct {
+	MoveID     int64
+	ResourceCd string
+}
+
+type MoveSettlementDetail struct {
+	MoveID int64
+	Name   string
+}
+
+type MoveTransientDetail struct {
+	MoveID int64
+	UnitID string
+}
+
+type PassageCode struct {
+	Code       string
+	Descr      string
+	WxxFeature string
+}
+
 type Report struct {
 	ID      int64
 	Clan    int64
@@ -14,3 +107,95 @@ type Report struct {
 	Lines   string
 	Created int64
 }
+
+type ResourceCode struct {
+	Code       string
+	Descr      string
+	WxxFeature string
+}
+
+type TerrainCode struct {
+	Code       string
+	LongCode   string
+	Descr      string
+	WxxTerrain string
+}
+
+type Tile struct {
+	ID            int64
+	Grid          string
+	Row           int64
+	Col           int64
+	North         sql.NullInt64
+	NorthEast     sql.NullInt64
+	NorthWest     sql.NullInt64
+	South         sql.NullInt64
+	SouthEast     sql.NullInt64
+	SouthWest     sql.NullInt64
+	LastVisitedOn sql.NullInt64
+	LastScoutedOn sql.NullInt64
+}
+
+type TileBorderDetail struct {
+	TileID    int64
+	Effdt     int64
+	Enddt     int64
+	BorderCd  string
+	Direction string
+}
+
+type TilePassageDetail struct {
+	TileID    int64
+	Effdt     int64
+	Enddt     int64
+	PassageCd string
+	Direction string
+}
+
+type TileResourceDetail struct {
+	TileID     int64
+	Effdt      int64
+	Enddt      int64
+	ResourceCd string
+}
+
+type TileSettlementDetail struct {
+	TileID int64
+	Effdt  int64
+	Enddt  int64
+	Name   string
+}
+
+type TileTerrainDetail struct {
+	TileID    int64
+	Effdt     int64
+	Enddt     int64
+	TerrainCd string
+}
+
+type TileTransientDetail struct {
+	TileID int64
+	Effdt  int64
+	Enddt  int64
+	UnitID string
+}
+
+type Turn struct {
+	ID    int64
+	Year  sql.NullInt64
+	Month sql.NullInt64
+}
+
+type Unit struct {
+	ID      string
+	ClanID  int64
+	IsScout int64
+}
+
+type User struct {
+	ID           int64
+	Handle       string
+	Clan         int64
+	HashedSecret string
+	Created      int64
+}
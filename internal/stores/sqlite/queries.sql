@@ -1,5 +1,40 @@
 --  Copyright (c) 2024 Michael D Henderson. All rights reserved.
 
+-- --------------------------------------------------------------------------
+-- ClaimJob marks the oldest queued job that is ready to run as running
+-- and returns it. Returns no rows if there are no jobs ready to run.
+--
+-- name: ClaimJob :one
+UPDATE jobs
+SET status   = 'running',
+    attempts = attempts + 1,
+    phase    = '',
+    percent  = 0,
+    updated  = strftime('%s', 'now')
+WHERE id = (SELECT queued.id
+            FROM jobs AS queued
+            WHERE queued.status = 'queued'
+              AND queued.run_after <= :now
+            ORDER BY queued.id
+            LIMIT 1)
+RETURNING id, kind, input, attempts, max_attempts;
+
+-- --------------------------------------------------------------------------
+-- CreateAlliance creates a new alliance.
+--
+-- name: CreateAlliance :one
+INSERT INTO alliances (name)
+VALUES (:name)
+RETURNING id;
+
+-- --------------------------------------------------------------------------
+-- CreateJob adds a job to the queue.
+--
+-- name: CreateJob :one
+INSERT INTO jobs (kind, input, max_attempts)
+VALUES (:kind, :input, :max_attempts)
+RETURNING id;
+
 -- --------------------------------------------------------------------------
 -- CreateNewReport creates a new report.
 --
@@ -8,6 +43,23 @@ INSERT INTO reports (clan, year, month, unit, hash, lines)
 VALUES (:clan, :year, :month, :unit, :hash, :lines)
 RETURNING id;
 
+-- --------------------------------------------------------------------------
+-- CreateUser creates a new user.
+--
+-- name: CreateUser :one
+INSERT INTO users (handle, clan, hashed_secret)
+VALUES (:handle, :clan, :hashed_secret)
+RETURNING id;
+
+-- --------------------------------------------------------------------------
+-- DeleteAllianceMember removes a clan from an alliance.
+--
+-- name: DeleteAllianceMember :exec
+DELETE
+FROM alliance_members
+WHERE alliance_id = (SELECT id FROM alliances WHERE name = :name)
+  AND clan = :clan;
+
 -- --------------------------------------------------------------------------
 -- DeleteReportByHash deletes a report by its hash value.
 --
@@ -28,6 +80,55 @@ WHERE clan = :clan
   AND month = :month
   AND unit = :unit;
 
+-- --------------------------------------------------------------------------
+-- FailJob records the error from a job. The status is queued if the job
+-- will be retried after run_after, or failed if it won't.
+--
+-- name: FailJob :exec
+UPDATE jobs
+SET status    = :status,
+    error     = :error,
+    run_after = :run_after,
+    updated   = strftime('%s', 'now')
+WHERE id = :id;
+
+-- --------------------------------------------------------------------------
+-- FinishJob records the result of a job that succeeded.
+--
+-- name: FinishJob :exec
+UPDATE jobs
+SET status  = 'done',
+    result  = :result,
+    error   = '',
+    phase   = '',
+    percent = 100,
+    updated = strftime('%s', 'now')
+WHERE id = :id;
+
+-- --------------------------------------------------------------------------
+-- GetAllianceByName returns an alliance by its name.
+--
+-- name: GetAllianceByName :one
+SELECT id, name
+FROM alliances
+WHERE name = :name;
+
+-- --------------------------------------------------------------------------
+-- GetJob returns the status of a job.
+--
+-- name: GetJob :one
+SELECT id, kind, status, error, phase, percent, attempts, max_attempts, created, updated
+FROM jobs
+WHERE id = :id;
+
+-- --------------------------------------------------------------------------
+-- GetJobResult returns the result of a job. The result is null until the job is done.
+--
+-- name: GetJobResult :one
+SELECT status, result
+FROM jobs
+WHERE id = :id;
+
 -- --------------------------------------------------------------------------
 -- GetReportByHash returns a report by its hash value.
 --
@@ -45,4 +146,76 @@ SELECT id, clan, year, month, unit, hash
 FROM reports
 WHERE clan = :clan
   AND year = :year
-  AND month = :month;
\ No newline at end of file
+  AND month = :month;
+
+-- --------------------------------------------------------------------------
+-- GetReportsByTurnRange returns the reports, including their contents, for
+-- the turns from the first to the last turn. Turns are given as year * 100 + month.
+--
+-- name: GetReportsByTurnRange :many
+SELECT id, clan, year, month, unit, hash, lines
+FROM reports
+WHERE clan = :clan
+  AND year * 100 + month >= CAST(:first_turn AS INTEGER)
+  AND year * 100 + month <= CAST(:last_turn AS INTEGER)
+ORDER BY year, month, unit;
+
+-- --------------------------------------------------------------------------
+-- GetSharingClans returns the other clans that share their reports with the
+-- clan through any alliance that the clan is a member of.
+--
+-- name: GetSharingClans :many
+SELECT DISTINCT other.clan
+FROM alliance_members mine
+         JOIN alliance_members other ON other.alliance_id = mine.alliance_id
+WHERE mine.clan = :clan
+  AND other.clan != :clan
+  AND other.shares = 1
+ORDER BY other.clan;
+
+-- --------------------------------------------------------------------------
+-- GetUserByHandle returns a user by their handle.
+--
+-- name: GetUserByHandle :one
+SELECT id, handle, clan, hashed_secret
+FROM users
+WHERE handle = :handle;
+
+-- --------------------------------------------------------------------------
+-- RequeueRunningJobs puts jobs that were running when the server stopped
+-- back on the queue. Returns the number of jobs requeued.
+--
+-- name: RequeueRunningJobs :execrows
+UPDATE jobs
+SET status  = 'queued',
+    updated = strftime('%s', 'now')
+WHERE status = 'running';
+
+-- --------------------------------------------------------------------------
+-- UpdateAllianceSharing sets whether a member of the alliance shares its reports.
+-- Returns the number of rows updated, which is zero if the clan is not a member.
+--
+-- name: UpdateAllianceSharing :execrows
+UPDATE alliance_members
+SET shares = :shares
+WHERE clan = :clan
+  AND alliance_id = (SELECT id FROM alliances WHERE name = :name);
+
+-- --------------------------------------------------------------------------
+-- UpdateJobProgress records the phase and percent complete of a running job.
+--
+-- name: UpdateJobProgress :exec
+UPDATE jobs
+SET phase   = :phase,
+    percent = :percent,
+    updated = strftime('%s', 'now')
+WHERE id = :id
+  AND status = 'running';
+
+-- --------------------------------------------------------------------------
+-- UpsertAllianceMember adds a clan to an alliance or updates its sharing.
+--
+-- name: UpsertAllianceMember :exec
+INSERT INTO alliance_members (alliance_id, clan, shares)
+VALUES (:alliance_id, :clan, :shares)
+ON CONFLICT (alliance_id, clan) DO UPDATE SET shares = excluded.shares;
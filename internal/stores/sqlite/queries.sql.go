@@ -9,8 +9,86 @@ import (
 	"context"
 )
 
-const createNewReport = `-- name: CreateNewReport :one
+const claimJob = `-- name: ClaimJob :one
+
+UPDATE jobs
+SET status   = 'running',
+    attempts = attempts + 1,
+    phase    = '',
+    percent  = 0,
+    updated  = strftime('%s', 'now')
+WHERE id = (SELECT queued.id
+            FROM jobs AS queued
+            WHERE queued.status = 'queued'
+              AND queued.run_after <= ?1
+            ORDER BY queued.id
+            LIMIT 1)
+RETURNING id, kind, input, attempts, max_attempts
+`
+
+type ClaimJobRow struct {
+	ID          int64
+	Kind        string
+	Input       string
+	Attempts    int64
+	MaxAttempts int64
+}
+
+//	Copyright (c) 2024 Michael D Henderson. All rights reserved.
+//
+// --------------------------------------------------------------------------
+// ClaimJob marks the oldest queued job that is ready to run as running
+// and returns it. Returns no rows if there are no jobs ready to run.
+func (q *Queries) ClaimJob(ctx context.Context, now int64) (ClaimJobRow, error) {
+	row := q.db.QueryRowContext(ctx, claimJob, now)
+	var i ClaimJobRow
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Input,
+		&i.Attempts,
+		&i.MaxAttempts,
+	)
+	return i, err
+}
+
+const createAlliance = `-- name: CreateAlliance :one
+INSERT INTO alliances (name)
+VALUES (?1)
+RETURNING id
+`
+
+// --------------------------------------------------------------------------
+// CreateAlliance creates a new alliance.
+func (q *Queries) CreateAlliance(ctx context.Context, name string) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createAlliance, name)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createJob = `-- name: CreateJob :one
+INSERT INTO jobs (kind, input, max_attempts)
+VALUES (?1, ?2, ?3)
+RETURNING id
+`
 
+type CreateJobParams struct {
+	Kind        string
+	Input       string
+	MaxAttempts int64
+}
+
+// --------------------------------------------------------------------------
+// CreateJob adds a job to the queue.
+func (q *Queries) CreateJob(ctx context.Context, arg CreateJobParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createJob, arg.Kind, arg.Input, arg.MaxAttempts)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const createNewReport = `-- name: CreateNewReport :one
 INSERT INTO reports (clan, year, month, unit, hash, lines)
 VALUES (?1, ?2, ?3, ?4, ?5, ?6)
 RETURNING id
@@ -25,8 +103,6 @@ type CreateNewReportParams struct {
 	Lines string
 }
 
-//	Copyright (c) 2024 Michael D Henderson. All rights reserved.
-//
 // --------------------------------------------------------------------------
 // CreateNewReport creates a new report.
 func (q *Queries) CreateNewReport(ctx context.Context, arg CreateNewReportParams) (int64, error) {
@@ -43,6 +119,46 @@ func (q *Queries) CreateNewReport(ctx context.Context, arg CreateNewReportParams
 	return id, err
 }
 
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (handle, clan, hashed_secret)
+VALUES (?1, ?2, ?3)
+RETURNING id
+`
+
+type CreateUserParams struct {
+	Handle       string
+	Clan         int64
+	HashedSecret string
+}
+
+// --------------------------------------------------------------------------
+// CreateUser creates a new user.
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, createUser, arg.Handle, arg.Clan, arg.HashedSecret)
+	var id int64
+	err := row.Scan(&id)
+	return id, err
+}
+
+const deleteAllianceMember = `-- name: DeleteAllianceMember :exec
+DELETE
+FROM alliance_members
+WHERE alliance_id = (SELECT id FROM alliances WHERE name = ?1)
+  AND clan = ?2
+`
+
+type DeleteAllianceMemberParams struct {
+	Name string
+	Clan int64
+}
+
+// --------------------------------------------------------------------------
+// DeleteAllianceMember removes a clan from an alliance.
+func (q *Queries) DeleteAllianceMember(ctx context.Context, arg DeleteAllianceMemberParams) error {
+	_, err := q.db.ExecContext(ctx, deleteAllianceMember, arg.Name, arg.Clan)
+	return err
+}
+
 const deleteReportByHash = `-- name: DeleteReportByHash :exec
 DELETE
 FROM reports
@@ -90,6 +206,132 @@ func (q *Queries) DeleteReportByName(ctx context.Context, arg DeleteReportByName
 	return err
 }
 
+const failJob = `-- name: FailJob :exec
+UPDATE jobs
+SET status    = ?1,
+    error     = ?2,
+    run_after = ?3,
+    updated   = strftime('%s', 'now')
+WHERE id = ?4
+`
+
+type FailJobParams struct {
+	Status   string
+	Error    string
+	RunAfter int64
+	ID       int64
+}
+
+// --------------------------------------------------------------------------
+// FailJob records the error from a job. The status is queued if the job
+// will be retried after run_after, or failed if it won't.
+func (q *Queries) FailJob(ctx context.Context, arg FailJobParams) error {
+	_, err := q.db.ExecContext(ctx, failJob,
+		arg.Status,
+		arg.Error,
+		arg.RunAfter,
+		arg.ID,
+	)
+	return err
+}
+
+const finishJob = `-- name: FinishJob :exec
+UPDATE jobs
+SET status  = 'done',
+    result  = ?1,
+    error   = '',
+    phase   = '',
+    percent = 100,
+    updated = strftime('%s', 'now')
+WHERE id = ?2
+`
+
+type FinishJobParams struct {
+	Result []byte
+	ID     int64
+}
+
+// --------------------------------------------------------------------------
+// FinishJob records the result of a job that succeeded.
+func (q *Queries) FinishJob(ctx context.Context, arg FinishJobParams) error {
+	_, err := q.db.ExecContext(ctx, finishJob, arg.Result, arg.ID)
+	return err
+}
+
+const getAllianceByName = `-- name: GetAllianceByName :one
+SELECT id, name
+FROM alliances
+WHERE name = ?1
+`
+
+// --------------------------------------------------------------------------
+// GetAllianceByName returns an alliance by its name.
+func (q *Queries) GetAllianceByName(ctx context.Context, name string) (Alliance, error) {
+	row := q.db.QueryRowContext(ctx, getAllianceByName, name)
+	var i Alliance
+	err := row.Scan(&i.ID, &i.Name)
+	return i, err
+}
+
+const getJob = `-- name: GetJob :one
+SELECT id, kind, status, error, phase, percent, attempts, max_attempts, created, updated
+FROM jobs
+WHERE id = ?1
+`
+
+type GetJobRow struct {
+	ID          int64
+	Kind        string
+	Status      string
+	Error       string
+	Phase       string
+	Percent     float64
+	Attempts    int64
+	MaxAttempts int64
+	Created     int64
+	Updated     int64
+}
+
+// --------------------------------------------------------------------------
+// GetJob returns the status of a job.
+func (q *Queries) GetJob(ctx context.Context, id int64) (GetJobRow, error) {
+	row := q.db.QueryRowContext(ctx, getJob, id)
+	var i GetJobRow
+	err := row.Scan(
+		&i.ID,
+		&i.Kind,
+		&i.Status,
+		&i.Error,
+		&i.Phase,
+		&i.Percent,
+		&i.Attempts,
+		&i.MaxAttempts,
+		&i.Created,
+		&i.Updated,
+	)
+	return i, err
+}
+
+const getJobResult = `-- name: GetJobResult :one
+SELECT status, result
+FROM jobs
+WHERE id = ?1
+`
+
+type GetJobResultRow struct {
+	Status string
+	Result []byte
+}
+
+// --------------------------------------------------------------------------
+// GetJobResult returns the result of a job. The result is null until the job is done.
+func (q *Queries) GetJobResult(ctx context.Context, id int64) (GetJobResultRow, error) {
+	row := q.db.QueryRowContext(ctx, getJobResult, id)
+	var i GetJobResultRow
+	err := row.Scan(&i.Status, &i.Result)
+	return i, err
+}
+
 const getReportByHash = `-- name: GetReportByHash :one
 SELECT id, clan, year, month, unit
 FROM reports
@@ -179,3 +421,208 @@ func (q *Queries) GetReportsByTurn(ctx context.Context, arg GetReportsByTurnPara
 	}
 	return items, nil
 }
+
+const getReportsByTurnRange = `-- name: GetReportsByTurnRange :many
+SELECT id, clan, year, month, unit, hash, lines
+FROM reports
+WHERE clan = ?1
+  AND year * 100 + month >= CAST(?2 AS INTEGER)
+  AND year * 100 + month <= CAST(?3 AS INTEGER)
+ORDER BY year, month, unit
+`
+
+type GetReportsByTurnRangeParams struct {
+	Clan      int64
+	FirstTurn int64
+	LastTurn  int64
+}
+
+type GetReportsByTurnRangeRow struct {
+	ID    int64
+	Clan  int64
+	Year  int64
+	Month int64
+	Unit  string
+	Hash  string
+	Lines string
+}
+
+// --------------------------------------------------------------------------
+// GetReportsByTurnRange returns the reports, including their contents, for
+// the turns from the first to the last turn. Turns are given as year * 100 + month.
+func (q *Queries) GetReportsByTurnRange(ctx context.Context, arg GetReportsByTurnRangeParams) ([]GetReportsByTurnRangeRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReportsByTurnRange, arg.Clan, arg.FirstTurn, arg.LastTurn)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReportsByTurnRangeRow
+	for rows.Next() {
+		var i GetReportsByTurnRangeRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Clan,
+			&i.Year,
+			&i.Month,
+			&i.Unit,
+			&i.Hash,
+			&i.Lines,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSharingClans = `-- name: GetSharingClans :many
+SELECT DISTINCT other.clan
+FROM alliance_members mine
+         JOIN alliance_members other ON other.alliance_id = mine.alliance_id
+WHERE mine.clan = ?1
+  AND other.clan != ?1
+  AND other.shares = 1
+ORDER BY other.clan
+`
+
+// --------------------------------------------------------------------------
+// GetSharingClans returns the other clans that share their reports with the
+// clan through any alliance that the clan is a member of.
+func (q *Queries) GetSharingClans(ctx context.Context, clan int64) ([]int64, error) {
+	rows, err := q.db.QueryContext(ctx, getSharingClans, clan)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int64
+	for rows.Next() {
+		var clan int64
+		if err := rows.Scan(&clan); err != nil {
+			return nil, err
+		}
+		items = append(items, clan)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, handle, clan, hashed_secret
+FROM users
+WHERE handle = ?1
+`
+
+type GetUserByHandleRow struct {
+	ID           int64
+	Handle       string
+	Clan         int64
+	HashedSecret string
+}
+
+// --------------------------------------------------------------------------
+// GetUserByHandle returns a user by their handle.
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (GetUserByHandleRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i GetUserByHandleRow
+	err := row.Scan(
+		&i.ID,
+		&i.Handle,
+		&i.Clan,
+		&i.HashedSecret,
+	)
+	return i, err
+}
+
+const requeueRunningJobs = `-- name: RequeueRunningJobs :execrows
+UPDATE jobs
+SET status  = 'queued',
+    updated = strftime('%s', 'now')
+WHERE status = 'running'
+`
+
+// --------------------------------------------------------------------------
+// RequeueRunningJobs puts jobs that were running when the server stopped
+// back on the queue. Returns the number of jobs requeued.
+func (q *Queries) RequeueRunningJobs(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueRunningJobs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateAllianceSharing = `-- name: UpdateAllianceSharing :execrows
+UPDATE alliance_members
+SET shares = ?1
+WHERE clan = ?2
+  AND alliance_id = (SELECT id FROM alliances WHERE name = ?3)
+`
+
+type UpdateAllianceSharingParams struct {
+	Shares int64
+	Clan   int64
+	Name   string
+}
+
+// --------------------------------------------------------------------------
+// UpdateAllianceSharing sets whether a member of the alliance shares its reports.
+// Returns the number of rows updated, which is zero if the clan is not a member.
+func (q *Queries) UpdateAllianceSharing(ctx context.Context, arg UpdateAllianceSharingParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateAllianceSharing, arg.Shares, arg.Clan, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const updateJobProgress = `-- name: UpdateJobProgress :exec
+UPDATE jobs
+SET phase   = ?1,
+    percent = ?2,
+    updated = strftime('%s', 'now')
+WHERE id = ?3
+  AND status = 'running'
+`
+
+type UpdateJobProgressParams struct {
+	Phase   string
+	Percent float64
+	ID      int64
+}
+
+// --------------------------------------------------------------------------
+// UpdateJobProgress records the phase and percent complete of a running job.
+func (q *Queries) UpdateJobProgress(ctx context.Context, arg UpdateJobProgressParams) error {
+	_, err := q.db.ExecContext(ctx, updateJobProgress, arg.Phase, arg.Percent, arg.ID)
+	return err
+}
+
+const upsertAllianceMember = `-- name: UpsertAllianceMember :exec
+INSERT INTO alliance_members (alliance_id, clan, shares)
+VALUES (?1, ?2, ?3)
+ON CONFLICT (alliance_id, clan) DO UPDATE SET shares = excluded.shares
+`
+
+type UpsertAllianceMemberParams struct {
+	AllianceID int64
+	Clan       int64
+	Shares     int64
+}
+
+// --------------------------------------------------------------------------
+// UpsertAllianceMember adds a clan to an alliance or updates its sharing.
+func (q *Queries) UpsertAllianceMember(ctx context.Context, arg UpsertAllianceMemberParams) error {
+	_, err := q.db.ExecContext(ctx, upsertAllianceMember, arg.AllianceID, arg.Clan, arg.Shares)
+	return err
+}
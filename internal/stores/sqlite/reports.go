@@ -140,3 +140,38 @@ func (s *Store) GetReportsByTurn(clan, year, month int) ([]*Report_t, error) {
 	}
 	return list, nil
 }
+
+// GetReportsByTurnRange returns the reports, with their contents, for the
+// requested clan from the first turn to the last turn (inclusive).
+// The reports are sorted by year, month, and unit.
+// If no reports are found, an empty list is returned.
+func (s *Store) GetReportsByTurnRange(clan, firstYear, firstMonth, lastYear, lastMonth int) ([]*Report_t, error) {
+	if !(0 < clan && clan <= 1000) {
+		return nil, ErrInvalidClanId
+	}
+	rows, err := s.q.GetReportsByTurnRange(s.ctx, GetReportsByTurnRangeParams{
+		Clan:      int64(clan),
+		FirstTurn: int64(firstYear*100 + firstMonth),
+		LastTurn:  int64(lastYear*100 + lastMonth),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// no reports found is not an error
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list []*Report_t
+	for _, row := range rows {
+		list = append(list, &Report_t{
+			ID:    int(row.ID),
+			Clan:  int(row.Clan),
+			Year:  int(row.Year),
+			Month: int(row.Month),
+			Unit:  row.Unit,
+			Hash:  row.Hash,
+			Lines: row.Lines,
+		})
+	}
+	return list, nil
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite_test
+
+import (
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"io"
+	"log"
+	"slices"
+	"testing"
+)
+
+func TestReports(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	store := openStore(t)
+	for _, r := range []struct {
+		clan        int
+		year, month int
+		unit, hash  string
+	}{
+		{138, 902, 1, "0138", "a1"},
+		{138, 902, 2, "0138", "a2"},
+		{138, 902, 2, "0138e1", "a3"},
+		{138, 903, 1, "0138", "a4"},
+		{249, 902, 2, "0249", "b1"},
+	} {
+		if _, err := store.CreateNewReport(r.clan, r.year, r.month, r.unit, r.hash, []byte(r.unit+" "+r.hash)); err != nil {
+			t.Fatalf("%04d-%02d.%s: create: %v", r.year, r.month, r.unit, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		id          int
+		clan        int
+		year, month int
+		unit, hash  string
+		err         error
+	}{
+		{1, 138, 902, 3, "0138", "a1", sqlite.ErrDuplicateHash},
+		{2, 138, 902, 1, "0138", "a9", sqlite.ErrDuplicateReportName},
+		{3, 0, 902, 3, "0138", "a9", sqlite.ErrInvalidClanId},
+		{4, 138, 898, 3, "0138", "a9", sqlite.ErrInvalidYear},
+		{5, 138, 902, 13, "0138", "a9", sqlite.ErrInvalidMonth},
+		{6, 138, 902, 3, "", "a9", sqlite.ErrInvalidUnit},
+		{7, 138, 902, 3, "0138", "", sqlite.ErrInvalidHash},
+	} {
+		if _, err := store.CreateNewReport(tc.clan, tc.year, tc.month, tc.unit, tc.hash, nil); err != tc.err {
+			t.Errorf("%d: create: want %v, got %v", tc.id, tc.err, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		id          int
+		clan        int
+		year, month int
+		want        []string
+	}{
+		{1, 138, 902, 2, []string{"a2", "a3"}},
+		{2, 249, 902, 2, []string{"b1"}},
+		{3, 138, 902, 3, nil},
+	} {
+		reports, err := store.GetReportsByTurn(tc.clan, tc.year, tc.month)
+		if err != nil {
+			t.Errorf("%d: turn: %v", tc.id, err)
+			continue
+		}
+		if got := hashes(reports); !slices.Equal(got, tc.want) {
+			t.Errorf("%d: turn: want %v, got %v", tc.id, tc.want, got)
+		}
+	}
+
+	for _, tc := range []struct {
+		id          int
+		clan        int
+		first, last [2]int
+		want        []string
+	}{
+		{1, 138, [2]int{899, 12}, [2]int{903, 1}, []string{"a1", "a2", "a3", "a4"}},
+		{2, 138, [2]int{902, 2}, [2]int{902, 2}, []string{"a2", "a3"}},
+		{3, 138, [2]int{899, 12}, [2]int{902, 1}, []string{"a1"}},
+		{4, 138, [2]int{903, 2}, [2]int{904, 1}, nil},
+		{5, 249, [2]int{899, 12}, [2]int{903, 1}, []string{"b1"}},
+	} {
+		reports, err := store.GetReportsByTurnRange(tc.clan, tc.first[0], tc.first[1], tc.last[0], tc.last[1])
+		if err != nil {
+			t.Errorf("%d: range: %v", tc.id, err)
+			continue
+		}
+		if got := hashes(reports); !slices.Equal(got, tc.want) {
+			t.Errorf("%d: range: want %v, got %v", tc.id, tc.want, got)
+		}
+		for _, r := range reports {
+			if want := r.Unit + " " + r.Hash; r.Lines != want {
+				t.Errorf("%d: range: %s: lines: want %q, got %q", tc.id, r.Hash, want, r.Lines)
+			}
+		}
+	}
+
+	if _, err := store.GetReportsByTurnRange(0, 899, 12, 903, 1); err != sqlite.ErrInvalidClanId {
+		t.Errorf("range: clan 0: want %v, got %v", sqlite.ErrInvalidClanId, err)
+	}
+}
+
+// hashes returns the hashes of the reports, in order.
+func hashes(reports []*sqlite.Report_t) []string {
+	var list []string
+	for _, r := range reports {
+		list = append(list, r.Hash)
+	}
+	return list
+}
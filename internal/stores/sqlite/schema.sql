@@ -37,6 +37,65 @@ CREATE TABLE reports
 --     FOREIGN KEY (report_id) REFERENCES reports (id) ON DELETE CASCADE
 -- );
 
+-- --------------------------------------------------------------------------
+-- Users
+--
+-- Users sign in to the web server to upload reports and fetch maps.
+-- Each user belongs to a single clan and can only see that clan's reports.
+-- The secret is never stored; we keep a bcrypt hash of it, which includes the salt.
+CREATE TABLE users
+(
+    id            INTEGER PRIMARY KEY,                              -- unique identifier for each user
+    handle        TEXT    NOT NULL UNIQUE,                          -- name the user signs in with
+    clan          INTEGER NOT NULL CHECK (clan BETWEEN 1 AND 999),  -- clan that the user belongs to
+    hashed_secret TEXT    NOT NULL,                                 -- bcrypt hash of the secret
+    created       INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))  -- Creation timestamp as Unix epoch
+);
+
+-- --------------------------------------------------------------------------
+-- Alliances
+--
+-- Clans can form alliances to share their maps. A member clan's reports are
+-- only used for the alliance map when the clan has opted in to sharing.
+CREATE TABLE alliances
+(
+    id   INTEGER PRIMARY KEY, -- unique identifier for each alliance
+    name TEXT    NOT NULL UNIQUE
+);
+
+CREATE TABLE alliance_members
+(
+    alliance_id INTEGER NOT NULL REFERENCES alliances (id) ON DELETE CASCADE,
+    clan        INTEGER NOT NULL CHECK (clan BETWEEN 1 AND 999),
+    shares      INTEGER NOT NULL DEFAULT 0 CHECK (shares in (0, 1)), -- true if the clan shares its reports
+    PRIMARY KEY (alliance_id, clan)
+);
+
+-- --------------------------------------------------------------------------
+-- Jobs
+--
+-- Jobs are parse and render requests that the server's workers run in the
+-- background. The input is the JSON encoded request. The result is the parsed
+-- turn or the map, and is set only when the job is done. A job that fails is
+-- queued again, to run after run_after, until it has used all its attempts.
+CREATE TABLE jobs
+(
+    id           INTEGER PRIMARY KEY,                                       -- unique identifier for each job
+    kind         TEXT    NOT NULL CHECK (kind in ('parse', 'render')),
+    status       TEXT    NOT NULL DEFAULT 'queued'
+        CHECK (status in ('queued', 'running', 'done', 'failed')),
+    input        TEXT    NOT NULL,                                          -- JSON encoded request
+    result       BLOB,                                                      -- set only when the job is done
+    error        TEXT    NOT NULL DEFAULT '',                               -- error from the last attempt
+    phase        TEXT    NOT NULL DEFAULT '',                               -- phase of the running job
+    percent      REAL    NOT NULL DEFAULT 0,                                -- percent complete of the phase
+    attempts     INTEGER NOT NULL DEFAULT 0,                                -- number of times the job has started
+    max_attempts INTEGER NOT NULL DEFAULT 1 CHECK (max_attempts > 0),
+    run_after    INTEGER NOT NULL DEFAULT 0,                                -- earliest time to run, as Unix epoch
+    created      INTEGER NOT NULL DEFAULT (strftime('%s', 'now')),          -- Creation timestamp as Unix epoch
+    updated      INTEGER NOT NULL DEFAULT (strftime('%s', 'now'))           -- Update timestamp as Unix epoch
+);
+
 -- --------------------------------------------------------------------------
 -- Turns
 --
@@ -363,6 +422,8 @@ CREATE TABLE moves
     ending_tile    INTEGER NOT NULL REFERENCES tiles (id),
     terrain_cd     TEXT    NOT NULL REFERENCES terrain_codes (code),
     failure_reason TEXT,                -- set only if the move failed
+    mp_cost        INTEGER,             -- estimated movement points for the step, null if not known
+    mp_remaining   INTEGER,             -- estimated movement points left after the step, null if not known
     CONSTRAINT action_valid CHECK (action in ('STILL', 'SCOUT', 'N', 'NE', 'SE', 'S', 'SW', 'NW')),
     UNIQUE (turn_id, unit_id, step_no)
 );
@@ -58,6 +58,12 @@ func Create(path string, ctx context.Context) error {
 		return errors.Join(ErrCreateSchema, err)
 	}
 
+	// the schema is current, so there is nothing for Open to migrate
+	if err := setSchemaVersion(db, SchemaVersion); err != nil {
+		log.Printf("db: create: %v\n", err)
+		return errors.Join(ErrCreateSchema, err)
+	}
+
 	log.Printf("db: create: created %s\n", path)
 
 	// initialize the database with default data
@@ -80,7 +86,9 @@ func Open(path string, ctx context.Context) (*Store, error) {
 		return nil, ErrInvalidPath
 	}
 
-	db, err := sql.Open("sqlite", path)
+	// the job workers and the request handlers write at the same time,
+	// so wait for the lock instead of failing with "database is locked."
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
 	if err != nil {
 		log.Printf("db: open: %s: %v\n", path, err)
 		return nil, err
@@ -98,6 +106,13 @@ func Open(path string, ctx context.Context) (*Store, error) {
 		return nil, ErrPragmaReturnedNil
 	}
 
+	// upgrade databases created by older releases
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		log.Printf("db: open: %s: %v\n", path, err)
+		return nil, err
+	}
+
 	// return the store.
 	return &Store{path: path, db: db, ctx: ctx, q: New(db)}, nil
 }
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+// CreateUser creates a new user for the clan.
+// Secrets must be 8 to 72 bytes long, since bcrypt ignores anything after 72 bytes.
+// Returns the id of the new user.
+func (s *Store) CreateUser(handle string, clan int, secret string) (int, error) {
+	if handle == "" || strings.TrimSpace(handle) != handle || strings.ContainsRune(handle, ':') {
+		return 0, ErrInvalidHandle
+	} else if !(0 < clan && clan < 1000) {
+		return 0, ErrInvalidClanId
+	} else if len(secret) < 8 || len(secret) > 72 {
+		return 0, ErrInvalidSecret
+	}
+	hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return 0, err
+	}
+	id, err := s.q.CreateUser(s.ctx, CreateUserParams{
+		Handle:       handle,
+		Clan:         int64(clan),
+		HashedSecret: string(hashedSecret),
+	})
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "constraint failed: UNIQUE constraint failed: users.handle (") {
+			return 0, ErrDuplicateHandle
+		}
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// AuthenticateUser returns the user if the handle and secret match.
+// Returns ErrInvalidCredentials if the user doesn't exist or the secret is wrong.
+func (s *Store) AuthenticateUser(handle, secret string) (*User_t, error) {
+	row, err := s.q.GetUserByHandle(s.ctx, handle)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+	// bcrypt compares the hashes in constant time
+	if err := bcrypt.CompareHashAndPassword([]byte(row.HashedSecret), []byte(secret)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &User_t{
+		ID:     int(row.ID),
+		Handle: row.Handle,
+		Clan:   int(row.Clan),
+	}, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package sqlite_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openStore creates a new store in a temporary directory.
+func openStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ottomap.db")
+	if err := sqlite.Create(path, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.Open(path, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func TestUsers(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	store := openStore(t)
+	id, err := store.CreateUser("alice", 138, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		id     int
+		handle string
+		clan   int
+		secret string
+		err    error
+	}{
+		{1, "alice", 249, "battery staple", sqlite.ErrDuplicateHandle},
+		{2, "", 138, "battery staple", sqlite.ErrInvalidHandle},
+		{3, "bob:1", 138, "battery staple", sqlite.ErrInvalidHandle},
+		{4, "bob", 1000, "battery staple", sqlite.ErrInvalidClanId},
+		{5, "bob", 138, "short", sqlite.ErrInvalidSecret},
+		{6, "bob", 138, strings.Repeat("x", 73), sqlite.ErrInvalidSecret},
+	} {
+		if _, err := store.CreateUser(tc.handle, tc.clan, tc.secret); err != tc.err {
+			t.Errorf("%d: create: want %v, got %v", tc.id, tc.err, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		id     int
+		handle string
+		secret string
+		err    error
+	}{
+		{1, "alice", "correct horse", nil},
+		{2, "alice", "correct horse ", sqlite.ErrInvalidCredentials},
+		{3, "alice", "", sqlite.ErrInvalidCredentials},
+		{4, "carol", "correct horse", sqlite.ErrInvalidCredentials},
+	} {
+		user, err := store.AuthenticateUser(tc.handle, tc.secret)
+		if err != tc.err {
+			t.Errorf("%d: authenticate: want %v, got %v", tc.id, tc.err, err)
+		} else if err == nil && (user.ID != id || user.Handle != "alice" || user.Clan != 138) {
+			t.Errorf("%d: authenticate: want user %d alice 138, got %+v", tc.id, id, user)
+		}
+	}
+}
@@ -68,6 +68,10 @@ func (e Terrain_e) IsSwamp() bool {
 	return e == Swamp
 }
 
+func (e Terrain_e) IsWater() bool {
+	return e == Lake || e == Ocean || e == UnknownWater
+}
+
 // MarshalJSON implements the json.Marshaler interface.
 func (e Terrain_e) MarshalJSON() ([]byte, error) {
 	return json.Marshal(EnumToString[e])
@@ -137,6 +141,42 @@ var (
 		UnknownMountain:      "UM",
 		UnknownWater:         "UW",
 	}
+	// EnumToName is the name of the terrain for people, for example in a map legend
+	EnumToName = map[Terrain_e]string{
+		Blank:                "",
+		Alps:                 "Alps",
+		AridHills:            "Arid Hills",
+		AridTundra:           "Arid Tundra",
+		BrushFlat:            "Brush Flat",
+		BrushHills:           "Brush Hills",
+		ConiferHills:         "Conifer Hills",
+		Deciduous:            "Deciduous",
+		DeciduousHills:       "Deciduous Hills",
+		Desert:               "Desert",
+		GrassyHills:          "Grassy Hills",
+		GrassyHillsPlateau:   "Grassy Hills Plateau",
+		HighSnowyMountains:   "High Snowy Mountains",
+		Jungle:               "Jungle",
+		JungleHills:          "Jungle Hills",
+		Lake:                 "Lake",
+		LowAridMountains:     "Low Arid Mountains",
+		LowConiferMountains:  "Low Conifer Mountains",
+		LowJungleMountains:   "Low Jungle Mountains",
+		LowSnowyMountains:    "Low Snowy Mountains",
+		LowVolcanicMountains: "Low Volcanic Mountains",
+		Ocean:                "Ocean",
+		PolarIce:             "Polar Ice",
+		Prairie:              "Prairie",
+		PrairiePlateau:       "Prairie Plateau",
+		RockyHills:           "Rocky Hills",
+		SnowyHills:           "Snowy Hills",
+		Swamp:                "Swamp",
+		Tundra:               "Tundra",
+		UnknownJungleSwamp:   "Unknown Jungle or Swamp",
+		UnknownLand:          "Unknown Land",
+		UnknownMountain:      "Unknown Mountain",
+		UnknownWater:         "Unknown Water",
+	}
 	// StringToEnum is a helper map for unmarshalling the enum
 	StringToEnum = map[string]Terrain_e{
 		"":     Blank,
@@ -213,3 +253,94 @@ var (
 		UnknownWater:         "Water Reefs",
 	}
 )
+
+// Height_e is an enum for the height category of a terrain.
+type Height_e int
+
+const (
+	UnknownHeight Height_e = iota
+	DeepWater
+	ShallowWater
+	Wetland
+	Flat
+	Hills
+	Plateau
+	Mountains
+	HighMountains
+	Ice
+)
+
+// Height returns the height category of the terrain.
+func (e Terrain_e) Height() Height_e {
+	switch e {
+	case Ocean:
+		return DeepWater
+	case Lake:
+		return ShallowWater
+	case Swamp, UnknownJungleSwamp:
+		return Wetland
+	case AridTundra, BrushFlat, Deciduous, Desert, Jungle, Prairie, Tundra:
+		return Flat
+	case AridHills, BrushHills, ConiferHills, DeciduousHills, GrassyHills, JungleHills, RockyHills, SnowyHills:
+		return Hills
+	case GrassyHillsPlateau, PrairiePlateau:
+		return Plateau
+	case LowAridMountains, LowConiferMountains, LowJungleMountains, LowSnowyMountains, LowVolcanicMountains, UnknownMountain:
+		return Mountains
+	case Alps, HighSnowyMountains:
+		return HighMountains
+	case PolarIce:
+		return Ice
+	}
+	return UnknownHeight
+}
+
+// String implements the fmt.Stringer interface.
+func (e Height_e) String() string {
+	if str, ok := HeightToString[e]; ok {
+		return str
+	}
+	return fmt.Sprintf("Height(%d)", int(e))
+}
+
+var (
+	// HeightToString is a helper map for the names of the height categories
+	HeightToString = map[Height_e]string{
+		UnknownHeight: "unknown",
+		DeepWater:     "deep-water",
+		ShallowWater:  "shallow-water",
+		Wetland:       "wetland",
+		Flat:          "flat",
+		Hills:         "hills",
+		Plateau:       "plateau",
+		Mountains:     "mountains",
+		HighMountains: "high-mountains",
+		Ice:           "ice",
+	}
+	// StringToHeight is a helper map for parsing the names of the height categories
+	StringToHeight = map[string]Height_e{
+		"unknown":        UnknownHeight,
+		"deep-water":     DeepWater,
+		"shallow-water":  ShallowWater,
+		"wetland":        Wetland,
+		"flat":           Flat,
+		"hills":          Hills,
+		"plateau":        Plateau,
+		"mountains":      Mountains,
+		"high-mountains": HighMountains,
+		"ice":            Ice,
+	}
+	// DefaultElevations is the elevation, in meters, for each height category.
+	DefaultElevations = map[Height_e]int{
+		UnknownHeight: 0,
+		DeepWater:     -1_000,
+		ShallowWater:  -10,
+		Wetland:       5,
+		Flat:          100,
+		Hills:         400,
+		Plateau:       900,
+		Mountains:     2_000,
+		HighMountains: 4_000,
+		Ice:           1_500,
+	}
+)
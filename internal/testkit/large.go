@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package testkit
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// LargeReport returns a synthetic report for turn 0902-02 with a section for
+// every unit the clan may have, each with long movement and scout lines.
+// It is meant for benchmarks; the moves don't need to make sense on a map.
+func LargeReport(clanId string) []byte {
+	dirs := []string{"N", "NE", "SE", "S", "SW", "NW"}
+	terrains := []string{"PR", "GH", "CH", "D", "SW", "BF", "RH", "LCM"}
+	step := func(n int) string {
+		return fmt.Sprintf("%s-%s", dirs[n%len(dirs)], terrains[n%len(terrains)])
+	}
+
+	units := []string{clanId}
+	for _, kind := range []string{"c", "e", "f", "g"} {
+		for n := 1; n <= 9; n++ {
+			units = append(units, fmt.Sprintf("%s%s%d", clanId, kind, n))
+		}
+	}
+
+	b := &bytes.Buffer{}
+	for u, unitId := range units {
+		kind := "Tribe"
+		if len(unitId) == 6 {
+			kind = map[byte]string{'c': "Courier", 'e': "Element", 'f': "Fleet", 'g': "Garrison"}[unitId[4]]
+		}
+		_, _ = fmt.Fprintf(b, "%s %s, , Current Hex = QQ %02d%02d, (Previous Hex = QQ %02d%02d)\n", kind, unitId, 10+u%20, 10+u%11, 10+u%20, 11+u%11)
+		_, _ = fmt.Fprintf(b, "Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n")
+
+		if kind == "Fleet" {
+			_, _ = fmt.Fprintf(b, "CALM NE Fleet Movement: Move ")
+			for n := 0; n < 12; n++ {
+				_, _ = fmt.Fprintf(b, "%s-O,-(%s O, %s O)(Sight Land - N/NE, Sight Water - NW/NW)\\", dirs[n%len(dirs)], dirs[(n+1)%len(dirs)], dirs[(n+2)%len(dirs)])
+			}
+			_, _ = fmt.Fprintf(b, "\n")
+		} else {
+			_, _ = fmt.Fprintf(b, "Tribe Movement: Move ")
+			for n := 0; n < 24; n++ {
+				if n%5 == 4 {
+					_, _ = fmt.Fprintf(b, "%s, River %s\\", step(n+u), dirs[(n+3)%len(dirs)])
+				} else {
+					_, _ = fmt.Fprintf(b, "%s\\", step(n+u))
+				}
+			}
+			_, _ = fmt.Fprintf(b, "\n")
+		}
+
+		if kind == "Tribe" || kind == "Element" {
+			for scout := 1; scout <= 8; scout++ {
+				_, _ = fmt.Fprintf(b, "Scout %d:Scout ", scout)
+				for n := 0; n < 6; n++ {
+					if n == 5 {
+						_, _ = fmt.Fprintf(b, "%s, Nothing of interest found\\", step(n+scout))
+					} else {
+						_, _ = fmt.Fprintf(b, "%s\\", step(n+scout))
+					}
+				}
+				_, _ = fmt.Fprintf(b, "\n")
+			}
+		}
+
+		_, _ = fmt.Fprintf(b, "%s Status: PRAIRIE, River S, %s\n\n", unitId, unitId)
+	}
+	return b.Bytes()
+}
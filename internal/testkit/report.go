@@ -0,0 +1,471 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package testkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/compass"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"github.com/playbymail/ottomap/internal/winds"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	rxTurnId = regexp.MustCompile(`^\d{4}-\d{2}$`)
+	rxUnitId = regexp.MustCompile(`^\d{4}([cefg][1-9])?$`)
+	rxClanId = regexp.MustCompile(`^0\d{3}$`)
+)
+
+// Report returns the text of a turn report for the scenario, along with the
+// document that parsing the text should give back.
+//
+// The scenario is a tniif document, usually written by hand or taken from
+// "parse file". Units are written in the order given. Line and step numbers
+// in the scenario are ignored and assigned as the text is written. Each unit
+// gets a movement line, a follows line, and a goes-to line from its moves,
+// then its scouts, then a status line from the move with the "Status Line"
+// result, then its events and settlement owners. The unit's follows, goes-to, and status are set
+// from those lines.
+//
+// Observations that the report can't express, or that the parser ignores,
+// are errors rather than being dropped.
+func Report(scenario *tniif.Document_t) ([]byte, *tniif.Document_t, error) {
+	var year, month int
+	if !rxTurnId.MatchString(scenario.Turn) {
+		return nil, nil, fmt.Errorf("turn %q: want yyyy-mm", scenario.Turn)
+	} else if _, err := fmt.Sscanf(scenario.Turn, "%04d-%02d", &year, &month); err != nil || year < 899 || month < 1 || month > 12 {
+		return nil, nil, fmt.Errorf("turn %q: want yyyy-mm", scenario.Turn)
+	} else if len(scenario.Specials) != 0 {
+		return nil, nil, fmt.Errorf("specials are not supported")
+	} else if len(scenario.Errors) != 0 {
+		return nil, nil, fmt.Errorf("errors are not supported")
+	}
+
+	// the expected document starts as a copy of the scenario
+	want := &tniif.Document_t{}
+	if data, err := json.Marshal(scenario); err != nil {
+		return nil, nil, err
+	} else if err = json.Unmarshal(data, want); err != nil {
+		return nil, nil, err
+	}
+	if want.Units == nil {
+		want.Units = []*tniif.Unit_t{}
+	}
+
+	b := &bytes.Buffer{}
+	lineNo := 0
+	writeln := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(b, format+"\n", args...)
+		lineNo++
+	}
+	seen := map[string]bool{}
+	for _, unit := range want.Units {
+		if !rxUnitId.MatchString(unit.Id) {
+			return nil, nil, fmt.Errorf("unit %q: invalid id", unit.Id)
+		} else if seen[unit.Id] {
+			return nil, nil, fmt.Errorf("unit %s: duplicate unit", unit.Id)
+		}
+		seen[unit.Id] = true
+		kind := "Tribe"
+		if len(unit.Id) == 6 {
+			kind = map[byte]string{'c': "Courier", 'e': "Element", 'f': "Fleet", 'g': "Garrison"}[unit.Id[4]]
+		}
+		if unit.PreviousHex == "" {
+			unit.PreviousHex = "N/A"
+		}
+		if unit.CurrentHex == "" {
+			unit.CurrentHex = "N/A"
+		}
+
+		var movement []*tniif.Move_t
+		var follows, goesTo, status *tniif.Move_t
+		for _, m := range unit.Moves {
+			// the legacy parser doesn't keep the reason a step failed
+			m.Reason = ""
+			switch {
+			case m.Follows != "":
+				if follows != nil {
+					return nil, nil, fmt.Errorf("unit %s: multiple follows", unit.Id)
+				}
+				follows = m
+			case m.GoesTo != "":
+				if goesTo != nil {
+					return nil, nil, fmt.Errorf("unit %s: multiple goes to", unit.Id)
+				}
+				goesTo = m
+			case m.Result == "Status Line":
+				if status != nil {
+					return nil, nil, fmt.Errorf("unit %s: multiple status lines", unit.Id)
+				}
+				status = m
+			default:
+				movement = append(movement, m)
+			}
+		}
+
+		writeln("%s %s, , Current Hex = %s, (Previous Hex = %s)", kind, unit.Id, unit.CurrentHex, unit.PreviousHex)
+		writeln("Current Turn %d-%02d (#%d), Winter, FINE", year, month, (year-900)*12+month)
+		if len(movement) != 0 {
+			wind := movement[0].Wind
+			prefix, lk := "Tribe Movement:", movementLine
+			if wind != "" {
+				if err := validWind(wind); err != nil {
+					return nil, nil, fmt.Errorf("unit %s: %w", unit.Id, err)
+				}
+				prefix, lk = wind+" Fleet Movement:", fleetLine
+			}
+			for _, m := range movement {
+				if m.Wind != wind {
+					return nil, nil, fmt.Errorf("unit %s: moves must all have the same wind", unit.Id)
+				}
+			}
+			text, err := stepsText(lineNo+1, movement, lk)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unit %s: %w", unit.Id, err)
+			}
+			writeln("%s Move %s", prefix, text)
+		}
+		unit.Follows, unit.GoesTo, unit.Status = "", "", ""
+		if follows != nil {
+			if !rxUnitId.MatchString(follows.Follows) {
+				return nil, nil, fmt.Errorf("unit %s: follows %q: invalid id", unit.Id, follows.Follows)
+			}
+			writeln("Tribe Follows %s", follows.Follows)
+			*follows = tniif.Move_t{Line: lineNo, Step: 1, Follows: follows.Follows}
+			unit.Follows = follows.Follows
+		}
+		if goesTo != nil {
+			writeln("Tribe Goes to %s", goesTo.GoesTo)
+			*goesTo = tniif.Move_t{Line: lineNo, Step: 1, GoesTo: goesTo.GoesTo}
+			unit.GoesTo = goesTo.GoesTo
+		}
+		scouts := map[int]bool{}
+		for _, scout := range unit.Scouts {
+			if scout.No < 1 || scout.No > 8 || scouts[scout.No] {
+				return nil, nil, fmt.Errorf("unit %s: scout %d: want a unique number from 1 to 8", unit.Id, scout.No)
+			} else if len(scout.Moves) == 0 {
+				return nil, nil, fmt.Errorf("unit %s: scout %d: no moves", unit.Id, scout.No)
+			}
+			scouts[scout.No] = true
+			scout.Line = lineNo + 1
+			text, err := stepsText(scout.Line, scout.Moves, scoutLine)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unit %s: scout %d: %w", unit.Id, scout.No, err)
+			}
+			writeln("Scout %d:Scout %s", scout.No, text)
+		}
+		if status != nil {
+			status.Line, status.Step = lineNo+1, 1
+			text, err := stepText(status, statusLine)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unit %s: status: %w", unit.Id, err)
+			}
+			writeln("%s Status: %s", unit.Id, text)
+			unit.Status = text
+		}
+		for _, event := range unit.Events {
+			verb := map[string]string{"created": "Created", "disbanded": "Disbanded"}[event.Kind]
+			if verb == "" {
+				return nil, nil, fmt.Errorf("unit %s: event %q: want created or disbanded", unit.Id, event.Kind)
+			} else if !rxUnitId.MatchString(event.Unit) || len(event.Unit) != 6 {
+				return nil, nil, fmt.Errorf("unit %s: event %s: %q: want a courier, element, fleet, or garrison", unit.Id, event.Kind, event.Unit)
+			}
+			kind := map[byte]string{'c': "Courier", 'e': "Element", 'f': "Fleet", 'g': "Garrison"}[event.Unit[4]]
+			writeln("%s %s %s", verb, kind, event.Unit)
+			event.Line = lineNo
+		}
+		for _, owner := range unit.Owners {
+			if !rxClanId.MatchString(owner.Clan) {
+				return nil, nil, fmt.Errorf("unit %s: owner %q: %q: want a clan", unit.Id, owner.Settlement, owner.Clan)
+			} else if owner.Settlement == "" || owner.Settlement != strings.TrimSpace(owner.Settlement) {
+				return nil, nil, fmt.Errorf("unit %s: owner %q: want a settlement name", unit.Id, owner.Settlement)
+			}
+			verb := "Held"
+			if owner.Captured {
+				verb = "Captured"
+			}
+			writeln("Settlement %s %s by %s", owner.Settlement, verb, owner.Clan)
+			owner.Line = lineNo
+		}
+		writeln("")
+	}
+
+	sort.Slice(want.Units, func(i, j int) bool {
+		return want.Units[i].Id < want.Units[j].Id
+	})
+	return b.Bytes(), want, nil
+}
+
+type lineKind_e int
+
+const (
+	movementLine lineKind_e = iota
+	fleetLine
+	scoutLine
+	statusLine
+)
+
+// stepsText numbers the moves and returns the text of the steps on the line.
+func stepsText(lineNo int, moves []*tniif.Move_t, lk lineKind_e) (string, error) {
+	// a unit that stays in place has a line with only a backslash
+	if lk != scoutLine && len(moves) == 1 && moves[0].Still && moves[0].Advance == "" && moves[0].Result == "Succeeded" && isEmpty(moves[0].Report) {
+		moves[0].Line, moves[0].Step, moves[0].Report = lineNo, 1, nil
+		return "\\", nil
+	}
+	var steps []string
+	for n, m := range moves {
+		m.Line, m.Step = lineNo, n+1
+		text, err := stepText(m, lk)
+		if err != nil {
+			return "", err
+		}
+		steps = append(steps, text)
+	}
+	return strings.Join(steps, "\\") + "\\", nil
+}
+
+// stepText returns the text of a single step: the result of the attempt
+// to move, followed by the observations.
+func stepText(m *tniif.Move_t, lk lineKind_e) (string, error) {
+	r := m.Report
+	if r == nil {
+		r = &tniif.Report_t{}
+	}
+	borders := append([]*tniif.Border_t{}, r.Borders...)
+
+	var fields []string
+	switch {
+	case lk == statusLine:
+		if !m.Still || m.Advance != "" {
+			return "", fmt.Errorf("step %d: status must be still", m.Step)
+		}
+		name, err := terrainName(r.Terrain)
+		if err != nil {
+			return "", fmt.Errorf("step %d: %w", m.Step, err)
+		}
+		fields = append(fields, name)
+	case m.Result == "Vanished":
+		if m.Advance != "" || m.Still || !isEmpty(r) {
+			return "", fmt.Errorf("step %d: vanished units don't move or observe", m.Step)
+		}
+		return "Group did not return", nil
+	case m.Result == "Succeeded" && m.Still:
+		if lk != scoutLine || m.Step != 1 || m.Advance != "" {
+			return "", fmt.Errorf("step %d: only the first step of a scout can be still", m.Step)
+		} else if r.Terrain != "" {
+			return "", fmt.Errorf("step %d: still scouts don't report terrain", m.Step)
+		}
+		fields = append(fields, "Still")
+	case m.Result == "Succeeded":
+		if _, ok := direction.StringToEnum[m.Advance]; !ok || m.Advance == "?" {
+			return "", fmt.Errorf("step %d: advance %q: invalid direction", m.Step, m.Advance)
+		} else if !stepTerrainCodes[r.Terrain] {
+			return "", fmt.Errorf("step %d: terrain %q: want a terrain code", m.Step, r.Terrain)
+		}
+		fields = append(fields, m.Advance+"-"+r.Terrain)
+	case m.Result == "Failed":
+		if _, ok := direction.StringToEnum[m.Advance]; !ok || m.Advance == "?" {
+			return "", fmt.Errorf("step %d: advance %q: invalid direction", m.Step, m.Advance)
+		} else if r.Terrain != "" {
+			return "", fmt.Errorf("step %d: failed steps don't report terrain", m.Step)
+		}
+		if m.Still {
+			fields = append(fields, fmt.Sprintf("No River Adjacent to Hex to %s of HEX", m.Advance))
+			break
+		}
+		// the border in the direction of the move says why it failed
+		var why string
+		for n, border := range borders {
+			if border.Direction != m.Advance {
+				continue
+			}
+			switch {
+			case border.Edge == "River" && border.Terrain == "":
+				why = fmt.Sprintf("No Ford on River to %s of HEX", m.Advance)
+			case border.Edge != "":
+				continue
+			case border.Terrain == "L" || border.Terrain == "O":
+				why = fmt.Sprintf("Can't Move on %s to %s of HEX", terrain.EnumToName[terrain.StringToEnum[border.Terrain]], m.Advance)
+			case border.Terrain == "UM":
+				why = fmt.Sprintf("No Pass into Mountain to %s of HEX", m.Advance)
+			case border.Terrain == "UJS":
+				why = fmt.Sprintf("Cannot Move Wagons into Swamp/Jungle Hill to %s of HEX", m.Advance)
+			default:
+				name, err := terrainName(border.Terrain)
+				if err != nil {
+					return "", fmt.Errorf("step %d: %w", m.Step, err)
+				}
+				why = fmt.Sprintf("Not enough M.P's to move to %s into %s", m.Advance, name)
+			}
+			borders = append(borders[:n], borders[n+1:]...)
+			break
+		}
+		if why == "" {
+			return "", fmt.Errorf("step %d: failed without a border to the %s", m.Step, m.Advance)
+		}
+		fields = append(fields, why)
+	default:
+		return "", fmt.Errorf("step %d: result %q: not supported", m.Step, m.Result)
+	}
+
+	if len(r.Items) != 0 {
+		return "", fmt.Errorf("step %d: items are ignored by the parser", m.Step)
+	} else if len(r.Settlements) > 1 {
+		return "", fmt.Errorf("step %d: a step finds at most one settlement", m.Step)
+	} else if len(r.FarHorizons) != 0 && lk != fleetLine {
+		return "", fmt.Errorf("step %d: only fleets see far horizons", m.Step)
+	}
+	for _, name := range r.Settlements {
+		if ch, _ := utf8.DecodeRuneInString(name); !(unicode.IsUpper(ch) || ch == '_') || strings.ContainsAny(name, ",\\()") {
+			return "", fmt.Errorf("step %d: settlement %q: want a capitalized name", m.Step, name)
+		}
+		fields = append(fields, name)
+	}
+
+	// edges and neighbors are grouped by kind, for example "River N NE"
+	var edgeKinds, neighborKinds []string
+	edgeDirs, neighborDirs := map[string][]string{}, map[string][]string{}
+	var innerRing []string
+	for _, border := range borders {
+		if _, ok := direction.StringToEnum[border.Direction]; !ok || border.Direction == "?" {
+			return "", fmt.Errorf("step %d: border %q: invalid direction", m.Step, border.Direction)
+		}
+		switch {
+		case border.Edge != "" && border.Terrain != "":
+			return "", fmt.Errorf("step %d: border %s: want an edge or a terrain, not both", m.Step, border.Direction)
+		case border.Edge != "":
+			if e, ok := edges.StringToEnum[border.Edge]; !ok || e == edges.None {
+				return "", fmt.Errorf("step %d: edge %q: invalid edge", m.Step, border.Edge)
+			}
+			if edgeDirs[border.Edge] == nil {
+				edgeKinds = append(edgeKinds, border.Edge)
+			}
+			edgeDirs[border.Edge] = append(edgeDirs[border.Edge], border.Direction)
+		case lk == fleetLine && stepTerrainCodes[border.Terrain]:
+			innerRing = append(innerRing, border.Direction+" "+border.Terrain)
+		case obviousTerrainCodes[border.Terrain]:
+			if neighborDirs[border.Terrain] == nil {
+				neighborKinds = append(neighborKinds, border.Terrain)
+			}
+			neighborDirs[border.Terrain] = append(neighborDirs[border.Terrain], border.Direction)
+		default:
+			return "", fmt.Errorf("step %d: border %s: terrain %q can't be reported", m.Step, border.Direction, border.Terrain)
+		}
+	}
+	for _, kind := range edgeKinds {
+		fields = append(fields, kind+" "+strings.Join(edgeDirs[kind], " "))
+	}
+	for _, kind := range neighborKinds {
+		fields = append(fields, kind+" "+strings.Join(neighborDirs[kind], " "))
+	}
+	for _, resource := range r.Resources {
+		if rs, ok := resources.StringToEnum[resource]; !ok || rs == resources.None {
+			return "", fmt.Errorf("step %d: resource %q: invalid resource", m.Step, resource)
+		}
+		fields = append(fields, "Find "+resource)
+	}
+	for _, unitId := range r.Encounters {
+		if !rxUnitId.MatchString(unitId) {
+			return "", fmt.Errorf("step %d: encounter %q: invalid id", m.Step, unitId)
+		}
+	}
+	if len(r.Encounters) != 0 {
+		fields = append(fields, strings.Join(r.Encounters, " "))
+	}
+	if lk == statusLine {
+		return strings.Join(fields, ", "), nil
+	}
+	text := strings.Join(fields, ", ")
+
+	var outerRing []string
+	for _, fh := range r.FarHorizons {
+		point, ok := compassPoints[fh.Point]
+		if !ok {
+			return "", fmt.Errorf("step %d: far horizon %q: invalid point", m.Step, fh.Point)
+		}
+		switch fh.Terrain {
+		case "UL":
+			outerRing = append(outerRing, "Sight Land - "+point)
+		case "UW":
+			outerRing = append(outerRing, "Sight Water - "+point)
+		default:
+			return "", fmt.Errorf("step %d: far horizon %s: want UL or UW, got %q", m.Step, fh.Point, fh.Terrain)
+		}
+	}
+	if len(innerRing) != 0 || len(outerRing) != 0 {
+		text += ",-(" + strings.Join(innerRing, ", ") + ")(" + strings.Join(outerRing, ", ") + ")"
+	}
+	return text, nil
+}
+
+// isEmpty returns true if the report has no observations.
+func isEmpty(r *tniif.Report_t) bool {
+	return r == nil || (r.Terrain == "" && len(r.Borders) == 0 && len(r.Encounters) == 0 && len(r.Items) == 0 &&
+		len(r.Resources) == 0 && len(r.Settlements) == 0 && len(r.FarHorizons) == 0)
+}
+
+// validWind returns an error if the wind isn't a strength and a direction, for example "CALM NE".
+func validWind(wind string) error {
+	fields := strings.Fields(wind)
+	if len(fields) != 2 {
+		return fmt.Errorf("wind %q: want strength and direction", wind)
+	} else if ws, ok := winds.StringToEnum[fields[0]]; !ok || ws == winds.Unknown {
+		return fmt.Errorf("wind %q: invalid strength", wind)
+	} else if d, ok := direction.StringToEnum[fields[1]]; !ok || d == direction.Unknown {
+		return fmt.Errorf("wind %q: invalid direction", wind)
+	}
+	return nil
+}
+
+// terrainName returns the name of the terrain as it is written in status lines.
+func terrainName(code string) (string, error) {
+	t, ok := terrain.StringToEnum[code]
+	if !ok || !stepTerrainCodes[code] {
+		return "", fmt.Errorf("terrain %q: want a terrain code", code)
+	}
+	switch t {
+	case terrain.AridHills:
+		return "ARID", nil
+	case terrain.PrairiePlateau:
+		return "PLATEAU PRAIRIE", nil
+	}
+	return strings.ToUpper(terrain.EnumToName[t]), nil
+}
+
+var (
+	// stepTerrainCodes are the codes that can follow a direction, for example "N-PR".
+	stepTerrainCodes = map[string]bool{
+		"ALPS": true, "AH": true, "AR": true, "BF": true, "BH": true, "CH": true, "D": true, "DE": true, "DH": true,
+		"GH": true, "GHP": true, "HSM": true, "JG": true, "JH": true, "L": true, "LAM": true, "LCM": true, "LJM": true,
+		"LSM": true, "LVM": true, "O": true, "PI": true, "PPR": true, "PR": true, "RH": true, "SH": true, "SW": true, "TU": true,
+	}
+	// obviousTerrainCodes are the neighboring terrains that a unit reports, for example "O N NE".
+	obviousTerrainCodes = map[string]bool{
+		"ALPS": true, "HSM": true, "LCM": true, "LJM": true, "LSM": true, "LVM": true, "L": true, "O": true,
+	}
+	// compassPoints is the text of each point in a fleet's crow's nest observations.
+	compassPoints = map[string]string{
+		compass.North.String():          "N/N",
+		compass.NorthNorthEast.String(): "N/NE",
+		compass.NorthEast.String():      "NE/NE",
+		compass.East.String():           "NE/SE",
+		compass.SouthEast.String():      "SE/SE",
+		compass.SouthSouthEast.String(): "S/SE",
+		compass.South.String():          "S/S",
+		compass.SouthSouthWest.String(): "S/SW",
+		compass.SouthWest.String():      "SW/SW",
+		compass.West.String():           "SW/NW",
+		compass.NorthWest.String():      "NW/NW",
+		compass.NorthNorthWest.String(): "N/NW",
+	}
+)
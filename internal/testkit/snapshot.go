@@ -0,0 +1,129 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package testkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"sort"
+)
+
+// Snapshot_t is a stable summary of the parsed turns and the merged map.
+// Everything is sorted so that two runs over the same reports produce the same JSON.
+type Snapshot_t struct {
+	Turns []*TurnSnapshot_t `json:"turns"`
+	Tiles []*TileSnapshot_t `json:"tiles"`
+}
+
+type TurnSnapshot_t struct {
+	Id    string            `json:"id"`
+	Units []*UnitSnapshot_t `json:"units"`
+}
+
+type UnitSnapshot_t struct {
+	Id      string   `json:"id"`
+	FromHex string   `json:"fromHex"`
+	ToHex   string   `json:"toHex"`
+	Follows string   `json:"follows,omitempty"`
+	GoesTo  string   `json:"goesTo,omitempty"`
+	Steps   []string `json:"steps,omitempty"`  // location after each move
+	Scouts  []string `json:"scouts,omitempty"` // final location of each scout
+}
+
+type TileSnapshot_t struct {
+	Hex         string              `json:"hex"`
+	Terrain     string              `json:"terrain"`
+	FirstSeen   string              `json:"firstSeen,omitempty"`
+	Visited     string              `json:"visited,omitempty"`
+	Scouted     string              `json:"scouted,omitempty"`
+	Edges       map[string][]string `json:"edges,omitempty"`
+	Resources   []string            `json:"resources,omitempty"`
+	Settlements []string            `json:"settlements,omitempty"`
+	Encounters  []string            `json:"encounters,omitempty"`
+	Special     []string            `json:"special,omitempty"`
+}
+
+// Snapshot returns the snapshot of the turns and the map as indented JSON.
+func Snapshot(turns []*parser.Turn_t, worldMap *tiles.Map_t) ([]byte, error) {
+	s := &Snapshot_t{}
+	for _, turn := range turns {
+		ts := &TurnSnapshot_t{Id: turn.Id}
+		for _, moves := range turn.SortedMoves {
+			us := &UnitSnapshot_t{
+				Id:      string(moves.UnitId),
+				FromHex: moves.FromHex,
+				ToHex:   moves.ToHex,
+				Follows: string(moves.Follows),
+				GoesTo:  moves.GoesTo,
+			}
+			for _, move := range moves.Moves {
+				us.Steps = append(us.Steps, move.Location.GridString())
+			}
+			for _, scout := range moves.Scouts {
+				if len(scout.Moves) != 0 {
+					us.Scouts = append(us.Scouts, scout.Moves[len(scout.Moves)-1].Location.GridString())
+				}
+			}
+			ts.Units = append(ts.Units, us)
+		}
+		sort.Slice(ts.Units, func(i, j int) bool {
+			return ts.Units[i].Id < ts.Units[j].Id
+		})
+		s.Turns = append(s.Turns, ts)
+	}
+	sort.Slice(s.Turns, func(i, j int) bool {
+		return s.Turns[i].Id < s.Turns[j].Id
+	})
+
+	for _, tile := range worldMap.Tiles {
+		t := &TileSnapshot_t{
+			Hex:       tile.Location.GridString(),
+			Terrain:   tile.Terrain.String(),
+			FirstSeen: tile.FirstSeen,
+			Visited:   tile.Visited,
+			Scouted:   tile.Scouted,
+		}
+		for d, list := range tile.Edges {
+			for _, edge := range list {
+				if t.Edges == nil {
+					t.Edges = map[string][]string{}
+				}
+				t.Edges[direction.Direction_e(d).String()] = append(t.Edges[direction.Direction_e(d).String()], edge.String())
+			}
+		}
+		for _, r := range tile.Resources {
+			t.Resources = append(t.Resources, r.String())
+		}
+		for _, settlement := range tile.Settlements {
+			t.Settlements = append(t.Settlements, settlement.Name)
+		}
+		for _, encounter := range tile.Encounters {
+			t.Encounters = append(t.Encounters, fmt.Sprintf("%s %s", encounter.TurnId, encounter.UnitId))
+		}
+		for _, special := range tile.Special {
+			t.Special = append(t.Special, special.Name)
+		}
+		for _, list := range [][]string{t.Resources, t.Settlements, t.Encounters, t.Special} {
+			sort.Strings(list)
+		}
+		s.Tiles = append(s.Tiles, t)
+	}
+	sort.Slice(s.Tiles, func(i, j int) bool {
+		return s.Tiles[i].Hex < s.Tiles[j].Hex
+	})
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// DecodeWXX returns the XML from a Worldographer file, which is gzipped UTF-16.
+func DecodeWXX(data []byte) ([]byte, error) {
+	return wxx.Decode(data)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package testkit replays a corpus of synthetic turn reports and compares
+// the results to golden files. It is used by the selftest command to catch
+// parser and renderer regressions.
+package testkit
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed corpus
+var corpus embed.FS
+
+// Golden file names in each case folder.
+const (
+	GoldenJSON = "golden.json" // snapshot of the turns and tiles
+	GoldenWXX  = "golden.xml"  // decoded Worldographer map
+)
+
+var rxReportName = regexp.MustCompile(`^\d{4}-\d{2}\.(\d{4})([cefg]\d)?\.report\.txt$`)
+
+// Case_t is a single replay from the corpus.
+// Each case is a folder containing the turn reports for one clan and the golden files.
+type Case_t struct {
+	Name    string
+	ClanId  string
+	Reports map[string][]byte // report file name to contents
+	JSON    []byte            // golden snapshot, nil if missing
+	WXX     []byte            // golden map, nil if missing
+}
+
+// Cases returns the cases in the bundled corpus, sorted by name.
+func Cases() ([]*Case_t, error) {
+	entries, err := fs.ReadDir(corpus, "corpus")
+	if err != nil {
+		return nil, err
+	}
+	var list []*Case_t
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		c := &Case_t{Name: entry.Name(), Reports: map[string][]byte{}}
+		files, err := fs.ReadDir(corpus, path.Join("corpus", c.Name))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := fs.ReadFile(corpus, path.Join("corpus", c.Name, file.Name()))
+			if err != nil {
+				return nil, err
+			}
+			switch name := file.Name(); {
+			case name == GoldenJSON:
+				c.JSON = data
+			case name == GoldenWXX:
+				c.WXX = data
+			case rxReportName.MatchString(name):
+				clanId := rxReportName.FindStringSubmatch(name)[1]
+				if c.ClanId == "" {
+					c.ClanId = clanId
+				} else if c.ClanId != clanId {
+					return nil, fmt.Errorf("%s: %s: expected clan %s", c.Name, name, c.ClanId)
+				}
+				c.Reports[name] = data
+			}
+		}
+		if len(c.Reports) == 0 {
+			return nil, fmt.Errorf("%s: no reports", c.Name)
+		}
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Diff returns the differences between the golden and actual output as
+// "-" (golden) and "+" (actual) lines, each prefixed with its line number.
+// At most limit lines are returned; the last line reports how many were dropped.
+func Diff(want, got []byte, limit int) []string {
+	a, b := strings.Split(string(want), "\n"), strings.Split(string(got), "\n")
+
+	// trim the common prefix and suffix so the table stays small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// longest common subsequence of the remaining lines
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []string
+	dropped := 0
+	emit := func(op byte, line int, text string) {
+		if len(lines) < limit {
+			lines = append(lines, fmt.Sprintf("%c%6d: %s", op, prefix+line+1, text))
+		} else {
+			dropped++
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			emit('-', i, a[i])
+			i++
+		default:
+			emit('+', j, b[j])
+			j++
+		}
+	}
+	if dropped != 0 {
+		lines = append(lines, fmt.Sprintf("... %d more lines", dropped))
+	}
+	return lines
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package testkit_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCases(t *testing.T) {
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatalf("cases: %v", err)
+	}
+	for _, c := range cases {
+		if c.ClanId == "" || len(c.Reports) == 0 {
+			t.Errorf("%s: missing clan or reports", c.Name)
+		}
+		if c.JSON == nil || c.WXX == nil {
+			t.Errorf("%s: missing golden files", c.Name)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		id        int
+		want, got string
+		limit     int
+		expect    []string
+	}{
+		{1, "a\nb\nc", "a\nb\nc", 10, nil},
+		{2, "a\nb\nc", "a\nx\nc", 10, []string{"-     2: b", "+     2: x"}},
+		{3, "a\nc", "a\nb\nc", 10, []string{"+     2: b"}},
+		{4, "a\nb\nc\nd", "a\nd", 1, []string{"-     2: b", "... 1 more lines"}},
+	} {
+		got := testkit.Diff([]byte(tc.want), []byte(tc.got), tc.limit)
+		if !reflect.DeepEqual(got, tc.expect) {
+			t.Errorf("%d: got %q, want %q", tc.id, got, tc.expect)
+		}
+	}
+}
+
+// scenario has a step of every kind that the report generator writes.
+const scenario = `{
+  "source": "0902-02.0138",
+  "turn": "0902-02",
+  "units": [
+    {"id": "0138", "previousHex": "QQ 1011", "currentHex": "QQ 1010",
+     "moves": [
+       {"advance": "N", "result": "Succeeded", "report": {"terrain": "PR",
+         "borders": [{"direction": "NE", "edge": "River"}, {"direction": "SE", "edge": "River"}, {"direction": "S", "terrain": "O"}, {"direction": "SW", "edge": "Ford"}],
+         "encounters": ["0138e1", "0200"], "resources": ["Iron Ore"], "settlements": ["Rivertown"]}},
+       {"advance": "NE", "result": "Succeeded", "report": {"terrain": "GH"}},
+       {"advance": "N", "result": "Failed", "reason": "Prohibited", "report": {"borders": [{"direction": "N", "terrain": "L"}]}},
+       {"still": true, "result": "Status Line", "report": {"terrain": "PPR", "settlements": ["Rivertown"], "borders": [{"direction": "NW", "terrain": "O"}], "encounters": ["0138", "0138e1"]}}
+     ],
+     "scouts": [
+       {"no": 1, "moves": [
+         {"advance": "N", "result": "Succeeded", "report": {"terrain": "PR"}},
+         {"advance": "NE", "result": "Failed", "report": {"borders": [{"direction": "NE", "edge": "River"}]}}]},
+       {"no": 2, "moves": [{"still": true, "result": "Succeeded", "report": {"borders": [{"direction": "S", "terrain": "LCM"}]}}]},
+       {"no": 3, "moves": [
+         {"advance": "S", "result": "Succeeded", "report": {"terrain": "CH"}},
+         {"advance": "SE", "result": "Failed", "report": {"borders": [{"direction": "SE", "terrain": "AH"}]}}]},
+       {"no": 4, "moves": [{"advance": "SE", "result": "Failed", "report": {"borders": [{"direction": "SE", "terrain": "UM"}]}}]},
+       {"no": 5, "moves": [{"advance": "SE", "still": true, "result": "Failed"}]},
+       {"no": 6, "moves": [{"advance": "N", "result": "Succeeded", "report": {"terrain": "D"}}, {"result": "Vanished"}]}
+     ],
+     "events": [{"kind": "created", "unit": "0138e2"}, {"kind": "disbanded", "unit": "0138g1"}],
+     "owners": [{"settlement": "Rivertown", "clan": "0250"}, {"settlement": "Old Mill", "clan": "0138", "captured": true}]},
+    {"id": "0138f1", "currentHex": "QQ 0909",
+     "moves": [
+       {"advance": "N", "wind": "CALM NE", "result": "Succeeded", "report": {"terrain": "O",
+         "borders": [{"direction": "NE", "terrain": "O"}, {"direction": "SE", "terrain": "PR"}],
+         "farHorizons": [{"point": "NorthNorthEast", "terrain": "UL"}, {"point": "SouthSouthWest", "terrain": "UW"}]}},
+       {"advance": "N", "wind": "CALM NE", "result": "Succeeded", "report": {"terrain": "O"}},
+       {"still": true, "result": "Status Line", "report": {"terrain": "O", "encounters": ["0138f1"]}}
+     ]},
+    {"id": "0138e1", "previousHex": "QQ 1010", "currentHex": "QQ 1010",
+     "moves": [{"follows": "0138"}, {"still": true, "result": "Status Line", "report": {"terrain": "PR"}}]},
+    {"id": "0138c1", "previousHex": "QQ 1010", "currentHex": "QQ 1410",
+     "moves": [{"goesTo": "QQ 1410"}, {"still": true, "result": "Succeeded"}]}
+  ]
+}`
+
+// parsing a generated report must give back the scenario.
+func TestReport(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	doc := &tniif.Document_t{}
+	if err := json.Unmarshal([]byte(scenario), doc); err != nil {
+		t.Fatal(err)
+	}
+	text, want, err := testkit.Report(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	turn, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", text, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		t.Fatalf("parse: %v\n%s", err, text)
+	}
+	if diffs := tniif.Diff(want, tniif.FromTurn("0902-02.0138", turn)); len(diffs) != 0 {
+		t.Errorf("round trip:\n%s\n%s", strings.Join(diffs, "\n"), text)
+	}
+
+	for _, tc := range []struct {
+		id   int
+		edit func(*tniif.Document_t)
+	}{
+		{1, func(d *tniif.Document_t) { d.Turn = "902-2" }},
+		{2, func(d *tniif.Document_t) { d.Units[0].Moves[1].Report.Items = []string{"Adze"} }},
+		{3, func(d *tniif.Document_t) { d.Units[0].Moves[1].Report.Terrain = "UL" }},
+		{4, func(d *tniif.Document_t) {
+			d.Units[0].Moves[0].Report.Settlements = append(d.Units[0].Moves[0].Report.Settlements, "Elsewhere")
+		}},
+		{5, func(d *tniif.Document_t) {
+			d.Units[0].Moves[1].Report.Borders = []*tniif.Border_t{{Direction: "N", Terrain: "PR"}}
+		}},
+		{6, func(d *tniif.Document_t) { d.Units[1].Moves[1].Wind = "MILD N" }},
+		{7, func(d *tniif.Document_t) { d.Units[0].Scouts[1].No = 1 }},
+		{8, func(d *tniif.Document_t) { d.Units[0].Events[0].Unit = "0139" }},
+		{9, func(d *tniif.Document_t) { d.Units[0].Owners[0].Clan = "1250" }},
+	} {
+		doc := &tniif.Document_t{}
+		if err := json.Unmarshal([]byte(scenario), doc); err != nil {
+			t.Fatal(err)
+		}
+		tc.edit(doc)
+		if _, _, err := testkit.Report(doc); err == nil {
+			t.Errorf("%d: want error, got nil", tc.id)
+		}
+	}
+}
@@ -4,6 +4,8 @@ package tiles
 
 import (
 	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
 	"github.com/playbymail/ottomap/internal/parser"
 	"log"
 	"sort"
@@ -14,6 +16,9 @@ import (
 type Map_t struct {
 	// key is the grid location of the tile
 	Tiles map[coords.Map]*Tile_t
+	// World is the size of the big map when it wraps around at the edges.
+	// Locations are wrapped onto the map when tiles are fetched.
+	World coords.World
 }
 
 // NewMap creates a new map.
@@ -23,37 +28,60 @@ func NewMap() *Map_t {
 	}
 }
 
+// Bounds returns the corners of the map.
+// When the world wraps, the corners are of the stitched map.
 func (m *Map_t) Bounds() (upperLeft, lowerRight coords.Map) {
 	if m.Length() == 0 {
 		return coords.Map{}, coords.Map{}
 	}
 
+	stitch := m.Stitch()
 	for _, tile := range m.Tiles {
 		if (tile.Visited != "" || tile.Scouted != "") && strings.Contains(tile.Location.GridString(), "-") {
 			log.Printf("tile: %s: visited %q: scouted %q\n", tile.Location.GridString(), tile.Visited, tile.Scouted)
 		}
+		location := stitch(tile.Location)
 		if upperLeft.Column == 0 {
 			// assume that we're on the first tile
-			upperLeft.Column, upperLeft.Row = tile.Location.Column, tile.Location.Row
-			lowerRight.Column, lowerRight.Row = tile.Location.Column, tile.Location.Row
+			upperLeft.Column, upperLeft.Row = location.Column, location.Row
+			lowerRight.Column, lowerRight.Row = location.Column, location.Row
 		}
-		if tile.Location.Column < upperLeft.Column {
-			upperLeft.Column = tile.Location.Column
+		if location.Column < upperLeft.Column {
+			upperLeft.Column = location.Column
 		}
-		if tile.Location.Row < upperLeft.Row {
-			upperLeft.Row = tile.Location.Row
+		if location.Row < upperLeft.Row {
+			upperLeft.Row = location.Row
 		}
-		if lowerRight.Column < tile.Location.Column {
-			lowerRight.Column = tile.Location.Column
+		if lowerRight.Column < location.Column {
+			lowerRight.Column = location.Column
 		}
-		if lowerRight.Row < tile.Location.Row {
-			lowerRight.Row = tile.Location.Row
+		if lowerRight.Row < location.Row {
+			lowerRight.Row = location.Row
 		}
 	}
 
 	return upperLeft, lowerRight
 }
 
+// Stitch returns a function that gives the location a tile is drawn at.
+// When the world wraps, tiles on the far side of the seam are moved next to
+// the others, so that a map that straddles the seam isn't as wide as the world.
+func (m *Map_t) Stitch() func(coords.Map) coords.Map {
+	if m.World.IsZero() {
+		return func(location coords.Map) coords.Map {
+			return location
+		}
+	}
+	var locations []coords.Map
+	for location := range m.Tiles {
+		locations = append(locations, location)
+	}
+	seam := m.World.Seam(locations)
+	return func(location coords.Map) coords.Map {
+		return m.World.Unwrap(seam, location)
+	}
+}
+
 func (m *Map_t) Dump() {
 	var sortedTiles []*Tile_t
 	for _, tile := range m.Tiles {
@@ -77,6 +105,7 @@ func (m *Map_t) Length() int {
 // FetchTile returns the tile at the given location.
 // If the tile does not exist, it is created.
 func (m *Map_t) FetchTile(unitId parser.UnitId_t, location coords.Map) *Tile_t {
+	location = m.World.Wrap(location)
 	if tile, ok := m.Tiles[location]; ok {
 		return tile
 	}
@@ -97,9 +126,27 @@ func (m *Map_t) FetchTile(unitId parser.UnitId_t, location coords.Map) *Tile_t {
 	return tile
 }
 
+// Grids returns a map for each grid that has tiles, keyed by grid id, for example "AB".
+// The maps share the tiles with this one.
+func (m *Map_t) Grids() map[string]*Map_t {
+	grids := map[string]*Map_t{}
+	for location, tile := range m.Tiles {
+		id := location.GridId()
+		grid, ok := grids[id]
+		if !ok {
+			grid = NewMap()
+			grid.World = m.World
+			grids[id] = grid
+		}
+		grid.Tiles[location] = tile
+	}
+	return grids
+}
+
 // Solo returns a map of tiles that are sourced by the given elements.
 func (m *Map_t) Solo(elements ...string) *Map_t {
 	solo := NewMap()
+	solo.World = m.World
 	for _, tile := range m.Tiles {
 		for _, element := range elements {
 			if tile.SourcedBy[element] {
@@ -111,3 +158,47 @@ func (m *Map_t) Solo(elements ...string) *Map_t {
 	}
 	return solo
 }
+
+// MirrorEdges copies the road, pass, and canal edges of each tile onto the
+// neighboring tile, so that an edge reported from one side is drawn from both.
+// Neighbors that aren't on the map are not created.
+// Returns the number of edges that were added.
+func (m *Map_t) MirrorEdges() (added int) {
+	for _, tile := range m.Tiles {
+		for _, d := range direction.Directions {
+			neighbor, ok := m.Tiles[m.World.Add(tile.Location, d)]
+			if !ok {
+				continue
+			}
+			for _, e := range tile.Edges[d] {
+				switch e {
+				case edges.Canal, edges.Pass, edges.StoneRoad:
+					if !neighbor.HasEdge(d.Opposite(), e) {
+						neighbor.MergeEdge(d.Opposite(), e)
+						added++
+					}
+				}
+			}
+		}
+	}
+	return added
+}
+
+// DropEncounters removes the encounters with the unit from the turn and
+// earlier turns, so that a disbanded unit isn't drawn where it was last seen.
+// Encounters from later turns are kept, since the id may be reused.
+// Returns the number of encounters that were removed.
+func (m *Map_t) DropEncounters(unitId parser.UnitId_t, turnId string) (dropped int) {
+	for _, tile := range m.Tiles {
+		kept := tile.Encounters[:0]
+		for _, e := range tile.Encounters {
+			if e.UnitId == unitId && e.TurnId <= turnId {
+				dropped++
+				continue
+			}
+			kept = append(kept, e)
+		}
+		tile.Encounters = kept
+	}
+	return dropped
+}
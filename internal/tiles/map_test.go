@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tiles_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestMirrorEdges(t *testing.T) {
+	center := coords.Map{Column: 3, Row: 3}
+	north, south := center.Add(direction.North), center.Add(direction.South)
+	m := tiles.NewMap()
+	for _, at := range []coords.Map{center, north, south} {
+		m.FetchTile("0138", at)
+	}
+	m.Tiles[center].MergeEdge(direction.North, edges.StoneRoad)
+	m.Tiles[center].MergeEdge(direction.South, edges.River)
+	m.Tiles[center].MergeEdge(direction.SouthEast, edges.Pass)
+
+	// the road is copied north, rivers are not copied, and there is no tile to the south-east
+	if got := m.MirrorEdges(); got != 1 {
+		t.Errorf("added: want 1, got %d", got)
+	}
+	for _, tc := range []struct {
+		id   int
+		at   coords.Map
+		d    direction.Direction_e
+		edge edges.Edge_e
+		want bool
+	}{
+		{1, north, direction.South, edges.StoneRoad, true},
+		{2, south, direction.North, edges.River, false},
+		{3, center, direction.North, edges.StoneRoad, true},
+		{4, center, direction.North, edges.Pass, false},
+	} {
+		if got := m.Tiles[tc.at].HasEdge(tc.d, tc.edge); got != tc.want {
+			t.Errorf("%d: %s: %s: want %v, got %v", tc.id, tc.at.GridString(), tc.d, tc.want, got)
+		}
+	}
+	if _, ok := m.Tiles[center.Add(direction.SouthEast)]; ok {
+		t.Errorf("south-east: want no tile, got one")
+	}
+
+	// the edges are already mirrored
+	if got := m.MirrorEdges(); got != 0 {
+		t.Errorf("again: want 0, got %d", got)
+	}
+}
+
+func TestGrids(t *testing.T) {
+	m := tiles.NewMap()
+	for _, at := range []coords.Map{{Column: 5, Row: 5}, {Column: 29, Row: 20}, {Column: 30, Row: 5}, {Column: 5, Row: 21}} {
+		m.FetchTile("0138", at)
+	}
+	grids := m.Grids()
+	for _, tc := range []struct {
+		id    int
+		grid  string
+		tiles int
+	}{
+		{1, "AA", 2},
+		{2, "AB", 1},
+		{3, "BA", 1},
+		{4, "BB", 0},
+	} {
+		grid, ok := grids[tc.grid]
+		if tc.tiles == 0 {
+			if ok {
+				t.Errorf("%d: %s: want no map, got %d tiles", tc.id, tc.grid, grid.Length())
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%d: %s: want map, got none", tc.id, tc.grid)
+			continue
+		}
+		if grid.Length() != tc.tiles {
+			t.Errorf("%d: %s: tiles: want %d, got %d", tc.id, tc.grid, tc.tiles, grid.Length())
+		}
+		// the grid shares the tiles with the map
+		for at, tile := range grid.Tiles {
+			if m.Tiles[at] != tile {
+				t.Errorf("%d: %s: %s: want shared tile", tc.id, tc.grid, at.GridString())
+			}
+		}
+	}
+}
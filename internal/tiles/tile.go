@@ -23,6 +23,11 @@ type Tile_t struct {
 	Visited string // set to the turn the tile was last visited
 	Scouted string // set to the turn the tile was last scouted
 
+	FirstSeen    string          // set to the turn the tile was first reported
+	LastSeen     string          // set to the turn the tile was last reported
+	Changed      string          // set to the turn that last added terrain, edges, resources, or settlements
+	DiscoveredBy parser.UnitId_t // unit that first reported the tile
+
 	// permanent items in this tile
 	Terrain terrain.Terrain_e
 	Edges   [direction.NumDirections][]edges.Edge_e
@@ -32,6 +37,7 @@ type Tile_t struct {
 	Resources   []resources.Resource_e
 	Settlements []*parser.Settlement_t
 	Special     []*parser.Special_t
+	Lost        []*Lost_t // units and scouts that vanished in this tile
 
 	// map of elements that are responsible for this tile.
 	// does not work for fleets!
@@ -64,18 +70,27 @@ func (t *Tile_t) MergeReports(turnId string, report *parser.Report_t, worldMap *
 	if scouting {
 		t.Scouted = turnId
 	}
+	t.MarkSeen(turnId, report.UnitId)
+
+	// encounters are left out because other units come and go every turn
+	terrainWas, discoveriesWas := t.Terrain, t.discoveries()
+	defer func() {
+		if t.Terrain != terrainWas || t.discoveries() != discoveriesWas {
+			t.MarkChanged(turnId)
+		}
+	}()
 
 	// merge the reports from this move into the tile
 	t.MergeTerrain(report.Terrain, warnOnTerrainChange)
 	for _, border := range report.Borders {
-		t.MergeBorder(report.UnitId, border, worldMap, warnOnTerrainChange)
+		t.MergeBorder(turnId, report.UnitId, border, worldMap, warnOnTerrainChange)
 		t.MergeEdge(border.Direction, border.Edge)
 	}
 	for _, encounter := range report.Encounters {
 		t.MergeEncounter(encounter)
 	}
 	for _, fh := range report.FarHorizons {
-		t.MergeFarHorizon(report.UnitId, fh, worldMap, warnOnTerrainChange)
+		t.MergeFarHorizon(turnId, report.UnitId, fh, worldMap, warnOnTerrainChange)
 	}
 	for _, item := range report.Items {
 		t.MergeItem(item)
@@ -91,13 +106,43 @@ func (t *Tile_t) MergeReports(turnId string, report *parser.Report_t, worldMap *
 }
 
 // MergeBorder merges a new border into the tile.
-func (t *Tile_t) MergeBorder(unitId parser.UnitId_t, border *parser.Border_t, worldMap *Map_t, warnOnTerrainChange bool) {
+func (t *Tile_t) MergeBorder(turnId string, unitId parser.UnitId_t, border *parser.Border_t, worldMap *Map_t, warnOnTerrainChange bool) {
 	if border.Terrain == terrain.Blank {
 		return
 	}
 	// create neighbor with terrain
 	neighbor := worldMap.FetchTile(unitId, t.Location.Add(border.Direction))
+	neighbor.MarkSeen(turnId, unitId)
+	terrainWas := neighbor.Terrain
 	neighbor.MergeTerrain(border.Terrain, warnOnTerrainChange)
+	if neighbor.Terrain != terrainWas {
+		neighbor.MarkChanged(turnId)
+	}
+}
+
+// Lost_t is a unit or scouting party that vanished in a tile.
+type Lost_t struct {
+	TurnId  string
+	UnitId  parser.UnitId_t
+	ScoutNo int // set only for scouting parties
+}
+
+// String returns the unit id, with the scout number for scouting parties.
+func (l *Lost_t) String() string {
+	if l.ScoutNo != 0 {
+		return fmt.Sprintf("%s scout %d", l.UnitId, l.ScoutNo)
+	}
+	return string(l.UnitId)
+}
+
+// MergeLost records a unit that vanished in the tile.
+func (t *Tile_t) MergeLost(l *Lost_t) {
+	for _, x := range t.Lost {
+		if x.TurnId == l.TurnId && x.UnitId == l.UnitId && x.ScoutNo == l.ScoutNo {
+			return
+		}
+	}
+	t.Lost = append(t.Lost, l)
 }
 
 // MergeEdge merges a new edge into the tile.
@@ -113,10 +158,26 @@ func (t *Tile_t) MergeEdge(d direction.Direction_e, e edges.Edge_e) {
 	t.Edges[d] = append(t.Edges[d], e)
 }
 
+// HasEdge returns true if the tile has the edge in the given direction.
+func (t *Tile_t) HasEdge(d direction.Direction_e, e edges.Edge_e) bool {
+	for _, l := range t.Edges[d] {
+		if l == e {
+			return true
+		}
+	}
+	return false
+}
+
 // MergeEncounter merges a new encounter into the tile.
+// Encounters are merged in turn order, so the search for a duplicate starts
+// with the latest and stops at the first encounter from an earlier turn;
+// tiles that are visited every turn for years would otherwise be rescanned
+// from the start for every report.
 func (t *Tile_t) MergeEncounter(e *parser.Encounter_t) {
-	for _, l := range t.Encounters {
-		if l.TurnId == e.TurnId && l.UnitId == e.UnitId {
+	for i := len(t.Encounters) - 1; i >= 0; i-- {
+		if l := t.Encounters[i]; l.TurnId < e.TurnId {
+			break
+		} else if l.TurnId == e.TurnId && l.UnitId == e.UnitId {
 			return
 		}
 	}
@@ -124,7 +185,7 @@ func (t *Tile_t) MergeEncounter(e *parser.Encounter_t) {
 }
 
 // MergeFarHorizon merges the far horizon from two tiles.
-func (t *Tile_t) MergeFarHorizon(unitId parser.UnitId_t, fh *parser.FarHorizon_t, worldMap *Map_t, warnOnTerrainChange bool) {
+func (t *Tile_t) MergeFarHorizon(turnId string, unitId parser.UnitId_t, fh *parser.FarHorizon_t, worldMap *Map_t, warnOnTerrainChange bool) {
 	if fh == nil {
 		return
 	}
@@ -158,7 +219,12 @@ func (t *Tile_t) MergeFarHorizon(unitId parser.UnitId_t, fh *parser.FarHorizon_t
 	default:
 		panic(fmt.Sprintf("assert(point != %d)", fh.Point))
 	}
+	neighbor.MarkSeen(turnId, unitId)
+	terrainWas := neighbor.Terrain
 	neighbor.MergeTerrain(fh.Terrain, warnOnTerrainChange)
+	if neighbor.Terrain != terrainWas {
+		neighbor.MarkChanged(turnId)
+	}
 }
 
 // MergeItem merges a new item into the tile.
@@ -188,7 +254,7 @@ func (t *Tile_t) MergeSettlement(s *parser.Settlement_t, specialNames map[string
 	//if specialNames != nil {
 	//	log.Printf("merge: settlement: special names: %d\n", len(specialNames))
 	//}
-	if special, ok := specialNames[strings.ToLower(s.Name)]; ok {
+	if special, ok := parser.LookupSpecial(specialNames, s.Name, t.Location); ok {
 		//log.Printf("merge: settlement %q: special %q\n", special.Id, special.Name)
 		foundId := false
 		for _, ss := range t.Special { // loop to prevent adding duplicates
@@ -204,7 +270,7 @@ func (t *Tile_t) MergeSettlement(s *parser.Settlement_t, specialNames map[string
 		}
 	}
 	for _, l := range t.Settlements {
-		if strings.ToLower(l.Name) == strings.ToLower(s.Name) {
+		if strings.EqualFold(l.Name, s.Name) {
 			return
 		}
 	}
@@ -214,6 +280,28 @@ func (t *Tile_t) MergeSettlement(s *parser.Settlement_t, specialNames map[string
 	t.Settlements = append(t.Settlements, s)
 }
 
+// MergeOwner records the owner of a settlement in the tile, adding the settlement if it isn't there.
+// The history only grows when the owner changes. It returns true if the owner changed.
+func (t *Tile_t) MergeOwner(turnId string, o *parser.SettlementOwner_t) bool {
+	var s *parser.Settlement_t
+	for _, l := range t.Settlements {
+		if strings.EqualFold(l.Name, o.Name) {
+			s = l
+			break
+		}
+	}
+	if s == nil {
+		s = &parser.Settlement_t{TurnId: turnId, Name: o.Name}
+		t.Settlements = append(t.Settlements, s)
+	}
+	if s.Owner == o.Clan {
+		return false
+	}
+	s.Owner = o.Clan
+	s.History = append(s.History, &parser.Ownership_t{TurnId: turnId, Clan: o.Clan, Captured: o.Captured})
+	return true
+}
+
 // MergeTerrain if it is not blank and is different
 func (t *Tile_t) MergeTerrain(n terrain.Terrain_e, warnOnTerrainChange bool) {
 	// ignore the new terrain if it is blank or the same as the existing terrain
@@ -263,6 +351,36 @@ func (t *Tile_t) MergeTerrain(n terrain.Terrain_e, warnOnTerrainChange bool) {
 	t.Terrain = n
 }
 
+// MarkSeen updates the first and last seen turns for the tile.
+// The first unit to report the tile is credited with discovering it.
+func (t *Tile_t) MarkSeen(turnId string, unitId parser.UnitId_t) {
+	if t.FirstSeen == "" {
+		t.MarkChanged(turnId)
+	}
+	if t.FirstSeen == "" || turnId < t.FirstSeen {
+		t.FirstSeen, t.DiscoveredBy = turnId, unitId
+	}
+	if t.LastSeen < turnId {
+		t.LastSeen = turnId
+	}
+}
+
+// MarkChanged records that the turn added something new to the tile.
+func (t *Tile_t) MarkChanged(turnId string) {
+	if t.Changed < turnId {
+		t.Changed = turnId
+	}
+}
+
+// discoveries returns the number of edges, resources, and settlements in the tile,
+// which only grows, so a merge that adds any of them changes the count.
+func (t *Tile_t) discoveries() (n int) {
+	for _, e := range t.Edges {
+		n += len(e)
+	}
+	return n + len(t.Resources) + len(t.Settlements) + len(t.Special)
+}
+
 // Source adds an element to the source list for the tile.
 func (t *Tile_t) Source(elements ...string) {
 	if t.SourcedBy == nil {
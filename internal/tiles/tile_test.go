@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tiles_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"testing"
+)
+
+func TestChanged(t *testing.T) {
+	at := coords.Map{Column: 3, Row: 3}
+	north := at.Add(direction.North)
+	m := tiles.NewMap()
+	tile := m.FetchTile("0138", at)
+	for _, tc := range []struct {
+		id     int
+		turnId string
+		report *parser.Report_t
+		want   string // turn the tile last changed
+		north  string // turn the neighbor to the north last changed
+	}{
+		{1, "0901-01", &parser.Report_t{UnitId: "0138", Terrain: terrain.Prairie}, "0901-01", ""},
+		// the same report adds nothing new
+		{2, "0901-02", &parser.Report_t{UnitId: "0138", Terrain: terrain.Prairie}, "0901-01", ""},
+		// encounters don't count as changes
+		{3, "0901-03", &parser.Report_t{UnitId: "0138", Terrain: terrain.Prairie, Encounters: []*parser.Encounter_t{{TurnId: "0901-03", UnitId: "0249"}}}, "0901-01", ""},
+		{4, "0901-04", &parser.Report_t{UnitId: "0138", Terrain: terrain.Prairie, Resources: []resources.Resource_e{resources.Coal}}, "0901-04", ""},
+		// the border reports the terrain of the neighbor, which is new
+		{5, "0901-05", &parser.Report_t{UnitId: "0138", Terrain: terrain.Prairie, Borders: []*parser.Border_t{{Direction: direction.North, Terrain: terrain.Lake}}}, "0901-04", "0901-05"},
+		{6, "0901-06", &parser.Report_t{UnitId: "0138", Terrain: terrain.Prairie, Borders: []*parser.Border_t{{Direction: direction.North, Terrain: terrain.Lake}}}, "0901-04", "0901-05"},
+	} {
+		if err := tile.MergeReports(tc.turnId, tc.report, m, nil, false, false, false); err != nil {
+			t.Fatalf("%d: merge: %v", tc.id, err)
+		}
+		if tile.Changed != tc.want {
+			t.Errorf("%d: changed: want %q, got %q", tc.id, tc.want, tile.Changed)
+		}
+		var got string
+		if neighbor, ok := m.Tiles[north]; ok {
+			got = neighbor.Changed
+		}
+		if got != tc.north {
+			t.Errorf("%d: north: changed: want %q, got %q", tc.id, tc.north, got)
+		}
+	}
+
+	// an older turn never replaces a newer one
+	tile.MarkChanged("0901-02")
+	if tile.Changed != "0901-04" {
+		t.Errorf("older: changed: want %q, got %q", "0901-04", tile.Changed)
+	}
+}
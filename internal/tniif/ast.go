@@ -0,0 +1,125 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/ast"
+	"github.com/playbymail/ottomap/internal/parser"
+	"log"
+	"sort"
+	"strings"
+)
+
+// FromAST converts the output of the new pipeline to a document.
+//
+// The section headers and turn lines were parsed by the pipeline. The steps on
+// the movement, scout, and status lines are still parsed with the line parsers
+// from the legacy parser, so both pipelines report steps the same way.
+func FromAST(fid, tid string, report *ast.Report_t) (*Document_t, error) {
+	doc := &Document_t{Source: fid, Units: []*Unit_t{}}
+	specials := map[string]*Special_t{}
+	seen := map[string]bool{}
+	for _, u := range report.Units {
+		if seen[u.Id] {
+			return doc, fmt.Errorf("%s: %s: %d: duplicate unit in turn", fid, u.Id, u.Line)
+		}
+		seen[u.Id] = true
+		if doc.Turn != "" && !parser.FormatForTurn(doc.Turn).ObscuredCurrentHex && strings.HasPrefix(u.CurrentHex, "##") {
+			return doc, fmt.Errorf("%s: %s: %d: current location is obscured", fid, u.Id, u.Line)
+		}
+		if u.Turn != nil {
+			turnId := fmt.Sprintf("%04d-%02d", u.Turn.Year, u.Turn.Month)
+			if doc.Turn == "" {
+				doc.Turn = turnId
+			} else if doc.Turn != turnId {
+				return doc, fmt.Errorf("%s: %s: %d: turn mismatch in report: want %s, got %s", fid, u.Id, u.Turn.Line, doc.Turn, turnId)
+			}
+		}
+
+		unit := &Unit_t{Id: u.Id, PreviousHex: u.PreviousHex, CurrentHex: u.CurrentHex}
+		doc.Units = append(doc.Units, unit)
+		unitId := parser.UnitId_t(u.Id)
+		for _, line := range u.Lines {
+			var moves []*parser.Move_t
+			var err error
+			switch line.Type {
+			case ast.FLEET_MOVEMENT:
+				moves, err = parser.ParseFleetMovementLine(fid, tid, unitId, line.No, line.Text, false, false, false, false, false)
+			case ast.SCOUT_MOVEMENT:
+				var scout *parser.Scout_t
+				if scout, err = parser.ParseScoutMovementLine(fid, tid, unitId, line.No, line.Text, false, false, false, false, false); err == nil {
+					unit.Scouts = append(unit.Scouts, fromScout(scout))
+				}
+			case ast.SPECIAL_HEX:
+				// same as the legacy parser: invalid directives are ignored
+				special, _ := parser.ParseSpecialHex(tid, line.Text)
+				s := fromSpecial(special)
+				specials[s.key()] = s
+			case ast.STATUS:
+				prefix := []byte(fmt.Sprintf("%s Status: ", u.Id))
+				text, _ := bytes.CutPrefix(line.Text, prefix)
+				unit.Status = string(bytes.TrimSpace(text))
+				// the grammar doesn't know about inventory, so we strip it before parsing the status line
+				status, _ := parser.SplitStatusInventory(text)
+				moves, err = parser.ParseStatusLine(fid, tid, unitId, line.No, append(prefix, status...), false, false, false, false)
+			case ast.TRIBE_FOLLOWS:
+				var move *parser.Move_t
+				if move, err = parser.ParseTribeFollowsLine(fid, tid, unitId, line.No, line.Text, false); err == nil {
+					if unit.Follows != "" {
+						return doc, fmt.Errorf("%s: %s: %d: multiple follows", fid, u.Id, line.No)
+					}
+					unit.Follows, moves = string(move.Follows), []*parser.Move_t{move}
+				}
+			case ast.TRIBE_GOES_TO:
+				var move *parser.Move_t
+				if move, err = parser.ParseTribeGoesToLine(fid, tid, unitId, line.No, line.Text, false); err == nil {
+					if unit.GoesTo != "" {
+						return doc, fmt.Errorf("%s: %s: %d: multiple goes to", fid, u.Id, line.No)
+					}
+					unit.GoesTo, moves = move.GoesTo, []*parser.Move_t{move}
+				}
+			case ast.TRIBE_MOVEMENT:
+				moves, err = parser.ParseTribeMovementLine(fid, tid, unitId, line.No, line.Text, false, false, false, false)
+			case ast.UNIT_EVENT:
+				// same as the legacy parser: invalid events are ignored
+				if event, err := parser.ParseUnitEventLine(fid, unitId, line.No, line.Text); err != nil {
+					log.Printf("warn: %v: ignoring the line\n", err)
+				} else {
+					unit.Events = append(unit.Events, fromEvent(event))
+				}
+			case ast.SETTLEMENT_OWNER:
+				var owner *parser.SettlementOwner_t
+				if owner, err = parser.ParseSettlementOwnerLine(fid, unitId, line.No, line.Text); err == nil {
+					unit.Owners = append(unit.Owners, fromOwner(owner))
+				}
+			}
+			if err != nil {
+				var ie *parser.InternalError_t
+				if errors.As(err, &ie) {
+					// same as the legacy parser: skip the rest of the section
+					log.Printf("warn: %v\n", ie)
+					doc.Errors = append(doc.Errors, ie.Error())
+					break
+				}
+				return doc, err
+			}
+			for _, move := range moves {
+				unit.Moves = append(unit.Moves, fromMove(move))
+			}
+		}
+	}
+
+	sort.Slice(doc.Units, func(i, j int) bool {
+		return doc.Units[i].Id < doc.Units[j].Id
+	})
+	for _, special := range specials {
+		doc.Specials = append(doc.Specials, special)
+	}
+	sort.Slice(doc.Specials, func(i, j int) bool {
+		return doc.Specials[i].key() < doc.Specials[j].key()
+	})
+	return doc, nil
+}
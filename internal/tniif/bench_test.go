@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/playbymail/ottomap/internal/cst"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"io"
+	"log"
+	"runtime"
+	"testing"
+)
+
+// benchmark runs fn over the large synthetic report, reporting bytes and lines per second.
+func benchmark(b *testing.B, fn func(data []byte) error) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	data := testkit.LargeReport("0138")
+	lines := bytes.Count(data, []byte{'\n'})
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := fn(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(lines*b.N)/b.Elapsed().Seconds(), "lines/s")
+}
+
+func BenchmarkParseInput(b *testing.B) {
+	benchmark(b, func(data []byte) error {
+		_, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+		return err
+	})
+}
+
+func BenchmarkParseInputConcurrent(b *testing.B) {
+	benchmark(b, func(data []byte) error {
+		_, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", data, false, false, false, false, false, false, false, false, parser.ParseConfig{Workers: runtime.GOMAXPROCS(0)})
+		return err
+	})
+}
+
+func BenchmarkLegacyDocument(b *testing.B) {
+	benchmark(b, func(data []byte) error {
+		turn, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+		if err == nil {
+			_ = tniif.FromTurn("0902-02.0138", turn)
+		}
+		return err
+	})
+}
+
+func BenchmarkCST(b *testing.B) {
+	benchmark(b, func(data []byte) error {
+		_, err := cst.Parse(data).ToAST()
+		return err
+	})
+}
+
+func BenchmarkNewDocument(b *testing.B) {
+	benchmark(b, func(data []byte) error {
+		report, err := cst.Parse(data).ToAST()
+		if err == nil {
+			_, err = tniif.FromAST("0902-02.0138", "0902-02", report)
+		}
+		return err
+	})
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff compares two documents and returns the differences, one per line.
+// Lines start with "+" for something only in b, "-" for something only in a,
+// and "~" for a value that changed.
+//
+// The comparison ignores the order of units, specials, moves, and observations,
+// so documents that differ only in formatting or ordering have no differences.
+// Moves are matched by line and step number, and scouts by scout number.
+func Diff(a, b *Document_t) (diffs []string) {
+	if a.Turn != b.Turn {
+		diffs = append(diffs, fmt.Sprintf("~ turn: %q -> %q", a.Turn, b.Turn))
+	}
+
+	aUnits, bUnits := map[string]*Unit_t{}, map[string]*Unit_t{}
+	for _, u := range a.Units {
+		aUnits[u.Id] = u
+	}
+	for _, u := range b.Units {
+		bUnits[u.Id] = u
+	}
+	for _, id := range sortedKeys(aUnits, bUnits) {
+		au, bu := aUnits[id], bUnits[id]
+		if au == nil {
+			diffs = append(diffs, fmt.Sprintf("+ unit %s", id))
+		} else if bu == nil {
+			diffs = append(diffs, fmt.Sprintf("- unit %s", id))
+		} else {
+			diffs = append(diffs, diffUnit(au, bu)...)
+		}
+	}
+
+	aSpecials, bSpecials := map[string]*Special_t{}, map[string]*Special_t{}
+	for _, s := range a.Specials {
+		aSpecials[s.key()] = s
+	}
+	for _, s := range b.Specials {
+		bSpecials[s.key()] = s
+	}
+	for _, id := range sortedKeys(aSpecials, bSpecials) {
+		as, bs := aSpecials[id], bSpecials[id]
+		if as == nil {
+			diffs = append(diffs, fmt.Sprintf("+ special %q: %q", id, bs.Name))
+		} else if bs == nil {
+			diffs = append(diffs, fmt.Sprintf("- special %q: %q", id, as.Name))
+		} else if as.Name != bs.Name {
+			diffs = append(diffs, fmt.Sprintf("~ special %q: %q -> %q", id, as.Name, bs.Name))
+		} else if !sameJSON(as.Directives, bs.Directives) {
+			diffs = append(diffs, fmt.Sprintf("~ special %q: directives %s -> %s", id, directiveList(as.Directives), directiveList(bs.Directives)))
+		}
+	}
+
+	return append(diffs, diffSets("error", a.Errors, b.Errors)...)
+}
+
+func diffUnit(a, b *Unit_t) (diffs []string) {
+	pfx := fmt.Sprintf("unit %s", a.Id)
+	for _, f := range []struct{ name, a, b string }{
+		{"previousHex", a.PreviousHex, b.PreviousHex},
+		{"currentHex", a.CurrentHex, b.CurrentHex},
+		{"follows", a.Follows, b.Follows},
+		{"goesTo", a.GoesTo, b.GoesTo},
+		{"status", a.Status, b.Status},
+	} {
+		if f.a != f.b {
+			diffs = append(diffs, fmt.Sprintf("~ %s: %s: %q -> %q", pfx, f.name, f.a, f.b))
+		}
+	}
+	diffs = append(diffs, diffMoves(pfx, a.Moves, b.Moves)...)
+	diffs = append(diffs, diffSets(pfx+": event", events(a.Events), events(b.Events))...)
+	diffs = append(diffs, diffSets(pfx+": owner", owners(a.Owners), owners(b.Owners))...)
+
+	aScouts, bScouts := map[string]*Scout_t{}, map[string]*Scout_t{}
+	for _, s := range a.Scouts {
+		aScouts[fmt.Sprintf("%d", s.No)] = s
+	}
+	for _, s := range b.Scouts {
+		bScouts[fmt.Sprintf("%d", s.No)] = s
+	}
+	for _, no := range sortedKeys(aScouts, bScouts) {
+		as, bs := aScouts[no], bScouts[no]
+		if as == nil {
+			diffs = append(diffs, fmt.Sprintf("+ %s: scout %s", pfx, no))
+		} else if bs == nil {
+			diffs = append(diffs, fmt.Sprintf("- %s: scout %s", pfx, no))
+		} else {
+			diffs = append(diffs, diffMoves(fmt.Sprintf("%s: scout %s", pfx, no), as.Moves, bs.Moves)...)
+		}
+	}
+	return diffs
+}
+
+func diffMoves(pfx string, a, b []*Move_t) (diffs []string) {
+	key := func(m *Move_t) string {
+		return fmt.Sprintf("%05d.%03d", m.Line, m.Step)
+	}
+	aMoves, bMoves := map[string]*Move_t{}, map[string]*Move_t{}
+	for _, m := range a {
+		aMoves[key(m)] = m
+	}
+	for _, m := range b {
+		bMoves[key(m)] = m
+	}
+	for _, k := range sortedKeys(aMoves, bMoves) {
+		am, bm := aMoves[k], bMoves[k]
+		if am == nil {
+			diffs = append(diffs, fmt.Sprintf("+ %s: step %d.%d", pfx, bm.Line, bm.Step))
+			continue
+		} else if bm == nil {
+			diffs = append(diffs, fmt.Sprintf("- %s: step %d.%d", pfx, am.Line, am.Step))
+			continue
+		}
+		step := fmt.Sprintf("%s: step %d.%d", pfx, am.Line, am.Step)
+		for _, f := range []struct{ name, a, b string }{
+			{"advance", am.Advance, bm.Advance},
+			{"follows", am.Follows, bm.Follows},
+			{"goesTo", am.GoesTo, bm.GoesTo},
+			{"still", fmt.Sprint(am.Still), fmt.Sprint(bm.Still)},
+			{"wind", am.Wind, bm.Wind},
+			{"result", am.Result, bm.Result},
+			{"reason", am.Reason, bm.Reason},
+		} {
+			if f.a != f.b {
+				diffs = append(diffs, fmt.Sprintf("~ %s: %s: %q -> %q", step, f.name, f.a, f.b))
+			}
+		}
+		diffs = append(diffs, diffSets(step+": observed", observations(am.Report), observations(bm.Report))...)
+	}
+	return diffs
+}
+
+// observations returns the observations in the report as strings.
+func observations(r *Report_t) (obs []string) {
+	if r == nil {
+		return nil
+	}
+	if r.Terrain != "" {
+		obs = append(obs, "terrain "+r.Terrain)
+	}
+	for _, b := range r.Borders {
+		obs = append(obs, fmt.Sprintf("border %s %s %s", b.Direction, b.Edge, b.Terrain))
+	}
+	for _, e := range r.Encounters {
+		obs = append(obs, "encounter "+e)
+	}
+	for _, i := range r.Items {
+		obs = append(obs, "item "+i)
+	}
+	for _, rs := range r.Resources {
+		obs = append(obs, "resource "+rs)
+	}
+	for _, s := range r.Settlements {
+		obs = append(obs, "settlement "+s)
+	}
+	for _, fh := range r.FarHorizons {
+		obs = append(obs, fmt.Sprintf("far horizon %s %s", fh.Point, fh.Terrain))
+	}
+	return obs
+}
+
+// events returns the events as strings, for example "created 0138e2".
+func events(list []*Event_t) (events []string) {
+	for _, e := range list {
+		events = append(events, e.Kind+" "+e.Unit)
+	}
+	return events
+}
+
+// owners returns the owners as strings, for example "Rivertown captured by 0138".
+func owners(list []*Owner_t) (owners []string) {
+	for _, o := range list {
+		verb := "held"
+		if o.Captured {
+			verb = "captured"
+		}
+		owners = append(owners, fmt.Sprintf("%s %s by %s", o.Settlement, verb, o.Clan))
+	}
+	return owners
+}
+
+// diffSets compares two lists of strings, ignoring order.
+// Duplicates are counted, so a value listed twice in a and once in b is a difference.
+func diffSets(pfx string, a, b []string) (diffs []string) {
+	count := map[string]int{}
+	for _, s := range a {
+		count[s]--
+	}
+	for _, s := range b {
+		count[s]++
+	}
+	var keys []string
+	for s, n := range count {
+		if n != 0 {
+			keys = append(keys, s)
+		}
+	}
+	sort.Strings(keys)
+	for _, s := range keys {
+		for n := count[s]; n < 0; n++ {
+			diffs = append(diffs, fmt.Sprintf("- %s: %s", pfx, s))
+		}
+		for n := count[s]; n > 0; n-- {
+			diffs = append(diffs, fmt.Sprintf("+ %s: %s", pfx, s))
+		}
+	}
+	return diffs
+}
+
+// sortedKeys returns the keys from both maps, sorted.
+func sortedKeys[T any](a, b map[string]T) (keys []string) {
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// directiveList returns the directives as they would be written on a ">>>>" line.
+func directiveList(list []*Directive_t) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	var fields []string
+	for _, d := range list {
+		fields = append(fields, strings.TrimSpace("!"+d.Kind+" "+d.Value))
+	}
+	return strings.Join(fields, ">")
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif_test
+
+import (
+	"github.com/playbymail/ottomap/internal/tniif"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	doc := func(edit func(*tniif.Document_t)) *tniif.Document_t {
+		d := &tniif.Document_t{Source: "0902-02.0138", Turn: "0902-02"}
+		for _, id := range []string{"0138", "0138e1"} {
+			d.Units = append(d.Units, &tniif.Unit_t{
+				Id:         id,
+				CurrentHex: "AA 0101",
+				Moves: []*tniif.Move_t{{Line: 3, Step: 1, Advance: "N", Result: "Succeeded", Report: &tniif.Report_t{
+					Terrain:    "Prairie",
+					Encounters: []string{"0249", "0301"},
+				}}},
+			})
+		}
+		if edit != nil {
+			edit(d)
+		}
+		return d
+	}
+
+	for _, tc := range []struct {
+		id   int
+		edit func(*tniif.Document_t)
+		want []string
+	}{
+		{id: 1},
+		{id: 2, edit: func(d *tniif.Document_t) {
+			// ordering is ignored
+			d.Units[0], d.Units[1] = d.Units[1], d.Units[0]
+			d.Units[0].Moves[0].Report.Encounters = []string{"0301", "0249"}
+		}},
+		{id: 3, edit: func(d *tniif.Document_t) { d.Units = d.Units[:1] }, want: []string{"- unit 0138e1"}},
+		{id: 4, edit: func(d *tniif.Document_t) { d.Units[0].CurrentHex = "AA 0102" }, want: []string{`~ unit 0138: currentHex: "AA 0101" -> "AA 0102"`}},
+		{id: 5, edit: func(d *tniif.Document_t) { d.Units[1].Moves[0].Result = "Failed" }, want: []string{`~ unit 0138e1: step 3.1: result: "Succeeded" -> "Failed"`}},
+		{id: 6, edit: func(d *tniif.Document_t) { d.Units[1].Moves[0].Report.Encounters = []string{"0249"} }, want: []string{"- unit 0138e1: step 3.1: observed: encounter 0301"}},
+		{id: 7, edit: func(d *tniif.Document_t) {
+			d.Units[0].Moves = append(d.Units[0].Moves, &tniif.Move_t{Line: 3, Step: 2, Still: true})
+		}, want: []string{"+ unit 0138: step 3.2"}},
+		{id: 8, edit: func(d *tniif.Document_t) { d.Specials = []*tniif.Special_t{{Id: "a", Name: "Alpha"}} }, want: []string{`+ special "a": "Alpha"`}},
+	} {
+		got := tniif.Diff(doc(nil), doc(tc.edit))
+		if len(got) != len(tc.want) {
+			t.Errorf("%d: want %q, got %q", tc.id, tc.want, got)
+			continue
+		}
+		for n := range got {
+			if got[n] != tc.want[n] {
+				t.Errorf("%d: %d: want %q, got %q", tc.id, n, tc.want[n], got[n])
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif
+
+import (
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"sort"
+)
+
+// FromTurn converts the output of the legacy parser to a document.
+func FromTurn(fid string, t *parser.Turn_t) *Document_t {
+	doc := &Document_t{Source: fid, Turn: t.Id, Units: []*Unit_t{}}
+	for _, moves := range t.UnitMoves {
+		unit := &Unit_t{
+			Id:          string(moves.UnitId),
+			PreviousHex: moves.FromHex,
+			CurrentHex:  moves.ToHex,
+			Follows:     string(moves.Follows),
+			GoesTo:      moves.GoesTo,
+			Status:      moves.Status,
+		}
+		for _, move := range moves.Moves {
+			unit.Moves = append(unit.Moves, fromMove(move))
+		}
+		for _, scout := range moves.Scouts {
+			unit.Scouts = append(unit.Scouts, fromScout(scout))
+		}
+		for _, event := range moves.Events {
+			unit.Events = append(unit.Events, fromEvent(event))
+		}
+		for _, owner := range moves.Owners {
+			unit.Owners = append(unit.Owners, fromOwner(owner))
+		}
+		doc.Units = append(doc.Units, unit)
+	}
+	sort.Slice(doc.Units, func(i, j int) bool {
+		return doc.Units[i].Id < doc.Units[j].Id
+	})
+	for _, special := range t.SpecialNames {
+		doc.Specials = append(doc.Specials, fromSpecial(special))
+	}
+	sort.Slice(doc.Specials, func(i, j int) bool {
+		return doc.Specials[i].key() < doc.Specials[j].key()
+	})
+	for _, err := range t.Errors {
+		doc.Errors = append(doc.Errors, err.Error())
+	}
+	return doc
+}
+
+func fromSpecial(s *parser.Special_t) *Special_t {
+	special := &Special_t{Id: s.Id, Name: s.Name, Directives: fromDirectives(s.Directives)}
+	if !s.Location.IsZero() {
+		special.Location = s.Location.GridString()
+	}
+	return special
+}
+
+func fromDirectives(list []*parser.Directive_t) []*Directive_t {
+	var directives []*Directive_t
+	for _, d := range list {
+		directives = append(directives, &Directive_t{Kind: d.Kind.String(), Value: d.Value})
+	}
+	return directives
+}
+
+func fromScout(s *parser.Scout_t) *Scout_t {
+	scout := &Scout_t{No: s.No, Line: s.LineNo}
+	for _, move := range s.Moves {
+		scout.Moves = append(scout.Moves, fromMove(move))
+	}
+	return scout
+}
+
+func fromEvent(e *parser.UnitEvent_t) *Event_t {
+	return &Event_t{Line: e.LineNo, Kind: e.Kind.String(), Unit: string(e.UnitId)}
+}
+
+func fromOwner(o *parser.SettlementOwner_t) *Owner_t {
+	return &Owner_t{Line: o.LineNo, Settlement: o.Name, Clan: string(o.Clan), Captured: o.Captured}
+}
+
+func fromMove(m *parser.Move_t) *Move_t {
+	move := &Move_t{
+		Line:    m.LineNo,
+		Step:    m.StepNo,
+		Follows: string(m.Follows),
+		GoesTo:  m.GoesTo,
+		Still:   m.Still,
+		Wind:    m.Wind.String(),
+		Report:  fromReport(m.Report),
+	}
+	if m.Advance != direction.Unknown {
+		move.Advance = m.Advance.String()
+	}
+	if m.Result != results.Unknown {
+		move.Result = m.Result.String()
+	}
+	if m.Reason != results.Unknown {
+		move.Reason = m.Reason.String()
+	}
+	return move
+}
+
+// fromReport returns nil if the report has no observations.
+func fromReport(r *parser.Report_t) *Report_t {
+	if r == nil {
+		return nil
+	}
+	report := &Report_t{}
+	empty := true
+	if r.Terrain != terrain.Blank {
+		report.Terrain, empty = r.Terrain.String(), false
+	}
+	for _, b := range r.Borders {
+		border := &Border_t{Direction: b.Direction.String()}
+		if b.Edge != edges.None {
+			border.Edge = b.Edge.String()
+		}
+		if b.Terrain != terrain.Blank {
+			border.Terrain = b.Terrain.String()
+		}
+		report.Borders, empty = append(report.Borders, border), false
+	}
+	for _, e := range r.Encounters {
+		report.Encounters, empty = append(report.Encounters, string(e.UnitId)), false
+	}
+	for _, i := range r.Items {
+		report.Items, empty = append(report.Items, i.String()), false
+	}
+	for _, rs := range r.Resources {
+		report.Resources, empty = append(report.Resources, rs.String()), false
+	}
+	for _, s := range r.Settlements {
+		report.Settlements, empty = append(report.Settlements, s.Name), false
+	}
+	for _, fh := range r.FarHorizons {
+		report.FarHorizons, empty = append(report.FarHorizons, &FarHorizon_t{Point: fh.Point.String(), Terrain: fh.Terrain.String()}), false
+	}
+	if empty {
+		return nil
+	}
+	return report
+}
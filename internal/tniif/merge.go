@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Merge combines documents for the same turn, for example the reports of
+// allied clans, into a single document.
+//
+// A unit that is in more than one document is kept once, but only if every
+// copy is identical. A special hex must have the same name in every document.
+// The source of the merged document is the sorted list of sources, separated
+// by commas. Errors are prefixed with the source of their document.
+func Merge(docs ...*Document_t) (*Document_t, error) {
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("no documents to merge")
+	}
+	merged := &Document_t{Turn: docs[0].Turn, Units: []*Unit_t{}}
+
+	var sources []string
+	units := map[string]*Unit_t{}
+	unitSource := map[string]string{}
+	specials := map[string]*Special_t{}
+	specialSource := map[string]string{}
+	for _, doc := range docs {
+		if doc.Turn != merged.Turn {
+			return nil, fmt.Errorf("%s: turn mismatch: want %q, got %q", doc.Source, merged.Turn, doc.Turn)
+		}
+		sources = append(sources, doc.Source)
+		for _, unit := range doc.Units {
+			if other, ok := units[unit.Id]; !ok {
+				units[unit.Id], unitSource[unit.Id] = unit, doc.Source
+				merged.Units = append(merged.Units, unit)
+			} else if !sameJSON(unit, other) {
+				return nil, fmt.Errorf("%s: unit %s: does not match %s", doc.Source, unit.Id, unitSource[unit.Id])
+			}
+		}
+		for _, special := range doc.Specials {
+			key := special.key()
+			if other, ok := specials[key]; !ok {
+				specials[key], specialSource[key] = special, doc.Source
+				merged.Specials = append(merged.Specials, special)
+			} else if other.Name != special.Name {
+				return nil, fmt.Errorf("%s: special hex %q: name %q does not match %q from %s", doc.Source, key, special.Name, other.Name, specialSource[key])
+			} else if !sameJSON(other.Directives, special.Directives) {
+				return nil, fmt.Errorf("%s: special hex %q: directives do not match %s", doc.Source, key, specialSource[key])
+			}
+		}
+		for _, err := range doc.Errors {
+			merged.Errors = append(merged.Errors, fmt.Sprintf("%s: %s", doc.Source, err))
+		}
+	}
+
+	sort.Strings(sources)
+	merged.Source = strings.Join(sources, ",")
+	sort.Slice(merged.Units, func(i, j int) bool {
+		return merged.Units[i].Id < merged.Units[j].Id
+	})
+	sort.Slice(merged.Specials, func(i, j int) bool {
+		return merged.Specials[i].key() < merged.Specials[j].key()
+	})
+	return merged, nil
+}
+
+// sameJSON returns true if both values have the same JSON encoding.
+func sameJSON(a, b any) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif_test
+
+import (
+	"github.com/playbymail/ottomap/internal/tniif"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	doc := func(source, turn string, units []string, specials ...string) *tniif.Document_t {
+		d := &tniif.Document_t{Source: source, Turn: turn}
+		for _, id := range units {
+			d.Units = append(d.Units, &tniif.Unit_t{Id: id, CurrentHex: "AA 0101"})
+		}
+		for n := 0; n+1 < len(specials); n += 2 {
+			d.Specials = append(d.Specials, &tniif.Special_t{Id: specials[n], Name: specials[n+1]})
+		}
+		return d
+	}
+
+	for _, tc := range []struct {
+		id      int
+		docs    []*tniif.Document_t
+		source  string
+		units   []string
+		wantErr bool
+	}{
+		{id: 1, docs: []*tniif.Document_t{doc("0902-02.0138", "0902-02", []string{"0138", "0138e1"})}, source: "0902-02.0138", units: []string{"0138", "0138e1"}},
+		{id: 2, docs: []*tniif.Document_t{doc("0902-02.0249", "0902-02", []string{"0249"}), doc("0902-02.0138", "0902-02", []string{"0138"})}, source: "0902-02.0138,0902-02.0249", units: []string{"0138", "0249"}},
+		{id: 3, docs: []*tniif.Document_t{doc("0902-02.0138", "0902-02", []string{"0138"}), doc("0902-02.0138", "0902-02", []string{"0138"})}, source: "0902-02.0138,0902-02.0138", units: []string{"0138"}},
+		{id: 4, docs: []*tniif.Document_t{doc("0902-02.0138", "0902-02", []string{"0138"}), doc("0902-03.0249", "0902-03", []string{"0249"})}, wantErr: true},
+		{id: 5, docs: []*tniif.Document_t{doc("0902-02.0138", "0902-02", nil, "a", "Alpha"), doc("0902-02.0249", "0902-02", nil, "a", "Beta")}, wantErr: true},
+		{id: 6, docs: []*tniif.Document_t{doc("0902-02.0138", "0902-02", nil, "a", "Alpha"), doc("0902-02.0249", "0902-02", nil, "a", "Alpha")}, source: "0902-02.0138,0902-02.0249"},
+		{id: 7, docs: nil, wantErr: true},
+	} {
+		merged, err := tniif.Merge(tc.docs...)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("%d: want error, got nil", tc.id)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%d: want nil, got %v", tc.id, err)
+			continue
+		}
+		if merged.Source != tc.source {
+			t.Errorf("%d: source: want %q, got %q", tc.id, tc.source, merged.Source)
+		}
+		if len(merged.Units) != len(tc.units) {
+			t.Errorf("%d: units: want %d, got %d", tc.id, len(tc.units), len(merged.Units))
+			continue
+		}
+		for n, unit := range merged.Units {
+			if unit.Id != tc.units[n] {
+				t.Errorf("%d: unit %d: want %q, got %q", tc.id, n, tc.units[n], unit.Id)
+			}
+		}
+	}
+
+	// a unit in two documents must be identical in both
+	a, b := doc("0902-02.0138", "0902-02", []string{"0138"}), doc("0902-02.0249", "0902-02", []string{"0138"})
+	b.Units[0].CurrentHex = "AA 0102"
+	if _, err := tniif.Merge(a, b); err == nil {
+		t.Errorf("conflicting unit: want error, got nil")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package tniif defines the TribeNet interchange format, a JSON document
+// for a parsed turn report that doesn't depend on the parser's internals.
+//
+// Both parsers produce these documents: the legacy Pigeon parser (FromTurn)
+// and the lexer, CST, and AST pipeline (FromAST). Since the documents are
+// plain data, the outputs of the two parsers can be compared directly.
+package tniif
+
+// Document_t is a parsed turn report.
+type Document_t struct {
+	Source   string       `json:"source"` // id of the report file, for example "0902-02.0138"
+	Turn     string       `json:"turn"`   // yyyy-mm
+	Units    []*Unit_t    `json:"units"`  // sorted by unit id
+	Specials []*Special_t `json:"specials,omitempty"`
+	Errors   []string     `json:"errors,omitempty"` // internal parser errors, see parser.InternalError_t
+}
+
+// Unit_t is the section of the report for a single unit.
+type Unit_t struct {
+	Id          string     `json:"id"`
+	PreviousHex string     `json:"previousHex,omitempty"`
+	CurrentHex  string     `json:"currentHex"`
+	Follows     string     `json:"follows,omitempty"`
+	GoesTo      string     `json:"goesTo,omitempty"`
+	Moves       []*Move_t  `json:"moves,omitempty"`
+	Scouts      []*Scout_t `json:"scouts,omitempty"`
+	Status      string     `json:"status,omitempty"` // text of the status line
+	Events      []*Event_t `json:"events,omitempty"` // units created or disbanded by this unit
+	Owners      []*Owner_t `json:"owners,omitempty"` // owners of settlements in the current hex
+}
+
+// Owner_t is the clan that controls a settlement, from a "Settlement ... Held by" or "Captured by" line.
+type Owner_t struct {
+	Line       int    `json:"line"`
+	Settlement string `json:"settlement"`
+	Clan       string `json:"clan"`
+	Captured   bool   `json:"captured,omitempty"`
+}
+
+// Event_t is a unit created or disbanded this turn, from a "Created" or "Disbanded" line.
+type Event_t struct {
+	Line int    `json:"line"`
+	Kind string `json:"kind"` // "created" or "disbanded"
+	Unit string `json:"unit"`
+}
+
+// Scout_t is the results of a single scout line.
+type Scout_t struct {
+	No    int       `json:"no"`
+	Line  int       `json:"line"`
+	Moves []*Move_t `json:"moves,omitempty"`
+}
+
+// Move_t is a single step from a movement, scout, or status line.
+type Move_t struct {
+	Line    int       `json:"line"`
+	Step    int       `json:"step"`
+	Advance string    `json:"advance,omitempty"` // direction, set only if the unit tried to advance
+	Follows string    `json:"follows,omitempty"`
+	GoesTo  string    `json:"goesTo,omitempty"`
+	Still   bool      `json:"still,omitempty"`
+	Wind    string    `json:"wind,omitempty"` // fleet movement only, for example "CALM NE"
+	Result  string    `json:"result,omitempty"`
+	Reason  string    `json:"reason,omitempty"`
+	Report  *Report_t `json:"report,omitempty"`
+}
+
+// Report_t is the observations made at the end of a step.
+type Report_t struct {
+	Terrain     string          `json:"terrain,omitempty"`
+	Borders     []*Border_t     `json:"borders,omitempty"`
+	Encounters  []string        `json:"encounters,omitempty"`
+	Items       []string        `json:"items,omitempty"`
+	Resources   []string        `json:"resources,omitempty"`
+	Settlements []string        `json:"settlements,omitempty"`
+	FarHorizons []*FarHorizon_t `json:"farHorizons,omitempty"`
+}
+
+// Border_t is an observation of an edge or a neighboring hex.
+type Border_t struct {
+	Direction string `json:"direction"`
+	Edge      string `json:"edge,omitempty"`
+	Terrain   string `json:"terrain,omitempty"`
+}
+
+// FarHorizon_t is an observation of a hex two hexes away.
+type FarHorizon_t struct {
+	Point   string `json:"point"`
+	Terrain string `json:"terrain"`
+}
+
+// Special_t is a name for a special hex, from a ">>>>" line.
+type Special_t struct {
+	Id         string         `json:"id"`
+	Name       string         `json:"name"`
+	Location   string         `json:"location,omitempty"` // hex the name applies to, from "!at"
+	Directives []*Directive_t `json:"directives,omitempty"`
+}
+
+// key returns the id, and the location if there is one, so that
+// specials with the same name in different hexes are kept apart.
+func (s *Special_t) key() string {
+	if s.Location == "" {
+		return s.Id
+	}
+	return s.Id + "@" + s.Location
+}
+
+// Directive_t is an instruction for drawing a special hex, from a ">>>>" line.
+type Directive_t struct {
+	Kind  string `json:"kind"`            // "icon", "note", "capital", or "hide"
+	Value string `json:"value,omitempty"` // feature type for icons, text for notes
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/cst"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+// TestPipelines checks that the legacy parser and the new pipeline produce the same document.
+func TestPipelines(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		for name, data := range tc.Reports {
+			fid, tid := strings.TrimSuffix(name, ".report.txt"), name[:7]
+			data, _ = extract.Normalize(data)
+			data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+
+			turn, err := parser.ParseInput(context.Background(), fid, tid, data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+			if err != nil {
+				t.Fatalf("%s: %s: legacy: %v", tc.Name, fid, err)
+			}
+			want, _ := json.MarshalIndent(tniif.FromTurn(fid, turn), "", "  ")
+
+			report, err := cst.Parse(data).ToAST()
+			if err != nil {
+				t.Fatalf("%s: %s: ast: %v", tc.Name, fid, err)
+			}
+			doc, err := tniif.FromAST(fid, tid, report)
+			if err != nil {
+				t.Fatalf("%s: %s: new: %v", tc.Name, fid, err)
+			}
+			got, _ := json.MarshalIndent(doc, "", "  ")
+
+			for _, line := range testkit.Diff(want, got, 20) {
+				t.Errorf("%s: %s: %s", tc.Name, fid, line)
+			}
+		}
+	}
+}
+
+// TestToTurn checks that converting a document back to a turn doesn't lose anything the document has.
+func TestToTurn(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		for name, data := range tc.Reports {
+			fid, tid := strings.TrimSuffix(name, ".report.txt"), name[:7]
+			data, _ = extract.Normalize(data)
+			data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+
+			report, err := cst.Parse(data).ToAST()
+			if err != nil {
+				t.Fatalf("%s: %s: ast: %v", tc.Name, fid, err)
+			}
+			doc, err := tniif.FromAST(fid, tid, report)
+			if err != nil {
+				t.Fatalf("%s: %s: new: %v", tc.Name, fid, err)
+			}
+			want, _ := json.MarshalIndent(doc, "", "  ")
+
+			turn, err := tniif.ToTurn(doc)
+			if err != nil {
+				t.Fatalf("%s: %s: to turn: %v", tc.Name, fid, err)
+			} else if turn.Id != tid {
+				t.Errorf("%s: %s: turn: want %q, got %q", tc.Name, fid, tid, turn.Id)
+			}
+			got, _ := json.MarshalIndent(tniif.FromTurn(fid, turn), "", "  ")
+
+			for _, line := range testkit.Diff(want, got, 20) {
+				t.Errorf("%s: %s: %s", tc.Name, fid, line)
+			}
+		}
+	}
+
+	if _, err := tniif.ToTurn(&tniif.Document_t{Source: "bad", Turn: "0901-04", Units: []*tniif.Unit_t{{Id: "0138", Moves: []*tniif.Move_t{{Advance: "NNE"}}}}}); err == nil {
+		t.Errorf("bad: want error for unknown direction, got nil")
+	}
+}
+
+// TestConcurrentParse checks that parsing unit sections concurrently produces the same document as parsing sequentially.
+func TestConcurrentParse(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	inputs := map[string][]byte{"0902-02.0138": testkit.LargeReport("0138")}
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		for name, data := range tc.Reports {
+			data, _ = extract.Normalize(data)
+			inputs[strings.TrimSuffix(name, ".report.txt")] = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+		}
+	}
+
+	for fid, data := range inputs {
+		tid := fid[:7]
+		turn, err := parser.ParseInput(context.Background(), fid, tid, data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+		if err != nil {
+			t.Fatalf("%s: sequential: %v", fid, err)
+		}
+		want, _ := json.MarshalIndent(tniif.FromTurn(fid, turn), "", "  ")
+		for _, workers := range []int{2, 8} {
+			turn, err := parser.ParseInput(context.Background(), fid, tid, data, false, false, false, false, false, false, false, false, parser.ParseConfig{Workers: workers})
+			if err != nil {
+				t.Fatalf("%s: %d workers: %v", fid, workers, err)
+			}
+			got, _ := json.MarshalIndent(tniif.FromTurn(fid, turn), "", "  ")
+			for _, line := range testkit.Diff(want, got, 20) {
+				t.Errorf("%s: %d workers: %s", fid, workers, line)
+			}
+		}
+	}
+
+	// a unit that appears twice is an error, even when the sections are parsed by different workers
+	data := testkit.LargeReport("0138")
+	data = append(data, data[:bytes.Index(data, []byte("\n\n"))+2]...)
+	if _, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", data, false, false, false, false, false, false, false, false, parser.ParseConfig{Workers: 4}); err == nil {
+		t.Errorf("duplicate unit: want error, got nil")
+	}
+}
+
+// TestCancelledParse checks that the parser stops with the context's error.
+func TestCancelledParse(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	data := testkit.LargeReport("0138")
+	for _, workers := range []int{0, 4} {
+		_, err := parser.ParseInput(ctx, "0902-02.0138", "0902-02", data, false, false, false, false, false, false, false, false, parser.ParseConfig{Workers: workers})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("%d workers: want %v, got %v", workers, context.Canceled, err)
+		}
+	}
+}
+
+// TestSpecialDirectives checks that both parsers read the directives on a ">>>>" line.
+func TestSpecialDirectives(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	data := []byte(`Tribe 0138, , Current Hex = QQ 1107, (Previous Hex = QQ 1008)
+Current Turn 902-03 (#27), Spring, FINE	Next Turn 902-04 (#28), 28/11/2023
+Tribe Movement: Move NE-PR\
+0138 Status: PRAIRIE, 0138
+>>>>Bree>Bree>!icon Settlement Ruins>!note burned in 0902-04>!hide
+>>>>Rivendell>!capital>!bogus
+`)
+	want := []*tniif.Special_t{
+		{Id: "bree", Name: "Bree", Directives: []*tniif.Directive_t{{Kind: "icon", Value: "Settlement Ruins"}, {Kind: "note", Value: "burned in 0902-04"}, {Kind: "hide"}}},
+		{Id: "rivendell", Name: "Rivendell"}, // the unknown directive drops the others
+	}
+
+	turn, err := parser.ParseInput(context.Background(), "0902-03.0138", "0902-03", data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		t.Fatalf("legacy: %v", err)
+	}
+	report, err := cst.Parse(data).ToAST()
+	if err != nil {
+		t.Fatalf("ast: %v", err)
+	}
+	doc, err := tniif.FromAST("0902-03.0138", "0902-03", report)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	wantJSON, _ := json.Marshal(want)
+	for name, got := range map[string]*tniif.Document_t{"legacy": tniif.FromTurn("0902-03.0138", turn), "new": doc} {
+		if gotJSON, _ := json.Marshal(got.Specials); !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("%s: specials: want %s, got %s", name, wantJSON, gotJSON)
+		}
+	}
+}
+
+func TestSpecialLocations(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	data := []byte(`Tribe 0138, , Current Hex = QQ 1107, (Previous Hex = QQ 1008)
+Current Turn 902-03 (#27), Spring, FINE	Next Turn 902-04 (#28), 28/11/2023
+Tribe Movement: Move NE-PR\
+0138 Status: PRAIRIE, 0138
+>>>>Bree>Bree>!capital
+>>>>Bree>Old Bree>!at qq 1107>!icon Settlement Ruins
+>>>>Bree>New Bree>!at QQ 1208
+>>>>Shire>Shire>!at the ford
+`)
+	want := []*tniif.Special_t{
+		{Id: "bree", Name: "Bree", Directives: []*tniif.Directive_t{{Kind: "capital"}}},
+		{Id: "bree", Name: "Old Bree", Location: "QQ 1107", Directives: []*tniif.Directive_t{{Kind: "icon", Value: "Settlement Ruins"}}},
+		{Id: "bree", Name: "New Bree", Location: "QQ 1208"},
+		{Id: "shire", Name: "Shire"}, // the invalid location drops the directives
+	}
+
+	turn, err := parser.ParseInput(context.Background(), "0902-03.0138", "0902-03", data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		t.Fatalf("legacy: %v", err)
+	}
+	report, err := cst.Parse(data).ToAST()
+	if err != nil {
+		t.Fatalf("ast: %v", err)
+	}
+	doc, err := tniif.FromAST("0902-03.0138", "0902-03", report)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	wantJSON, _ := json.Marshal(want)
+	for name, got := range map[string]*tniif.Document_t{"legacy": tniif.FromTurn("0902-03.0138", turn), "new": doc} {
+		if gotJSON, _ := json.Marshal(got.Specials); !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("%s: specials: want %s, got %s", name, wantJSON, gotJSON)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		at   string
+		want string
+	}{
+		{"Bree", "QQ 1107", "Old Bree"},
+		{"bree", "QQ 1208", "New Bree"},
+		{"Bree", "QQ 0101", "Bree"},
+	} {
+		at, _ := coords.HexToMap(tc.at)
+		if got, ok := parser.LookupSpecial(turn.SpecialNames, tc.name, at); !ok || got.Name != tc.want {
+			t.Errorf("lookup %q at %s: want %q, got %+v", tc.name, tc.at, tc.want, got)
+		}
+	}
+}
+
+// TestSettlementOwnerErrors checks that both pipelines fail on a malformed settlement owner directive.
+func TestSettlementOwnerErrors(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	for _, tc := range []struct {
+		id   int
+		line string
+	}{
+		{1, "Settlement Rivertown Held by 1250"},
+		{2, "Settlement Rivertown"},
+	} {
+		data := []byte("Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n" +
+			"Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n" +
+			"Tribe Movement: Move N-PR\\\n" +
+			tc.line + "\n")
+		if _, err := parser.ParseInput(context.Background(), "0902-02.0138", "0902-02", data, false, false, false, false, false, false, false, false, parser.ParseConfig{}); err == nil {
+			t.Errorf("%d: legacy: want error", tc.id)
+		}
+		report, err := cst.Parse(data).ToAST()
+		if err != nil {
+			t.Fatalf("%d: ast: %v", tc.id, err)
+		}
+		if _, err := tniif.FromAST("0902-02.0138", "0902-02", report); err == nil {
+			t.Errorf("%d: new: want error", tc.id)
+		}
+	}
+}
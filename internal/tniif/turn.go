@@ -0,0 +1,241 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tniif
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/compass"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/items"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/winds"
+	"strconv"
+	"strings"
+)
+
+// ToTurn converts a document back to the legacy parser's turn, so that the
+// output of either parser can be walked and rendered by the legacy pipeline.
+//
+// The document doesn't keep the season, weather, or the text of the lines,
+// so those are left empty. The internal errors are kept only as text in the
+// document, so they aren't copied to the turn. Every move gets a report, even one without
+// observations, the same as the legacy parser.
+func ToTurn(doc *Document_t) (*parser.Turn_t, error) {
+	var year, month int
+	if _, err := fmt.Sscanf(doc.Turn, "%04d-%02d", &year, &month); err != nil {
+		return nil, fmt.Errorf("%s: turn %q: %w", doc.Source, doc.Turn, err)
+	}
+	t := &parser.Turn_t{
+		Id:           doc.Turn,
+		Year:         year,
+		Month:        month,
+		UnitMoves:    map[parser.UnitId_t]*parser.Moves_t{},
+		SpecialNames: map[string]*parser.Special_t{},
+	}
+	for _, u := range doc.Units {
+		unitId := parser.UnitId_t(u.Id)
+		if _, ok := t.UnitMoves[unitId]; ok {
+			return nil, fmt.Errorf("%s: %s: duplicate unit", doc.Source, u.Id)
+		}
+		moves := &parser.Moves_t{
+			TurnId:  t.Id,
+			UnitId:  unitId,
+			Follows: parser.UnitId_t(u.Follows),
+			GoesTo:  u.GoesTo,
+			Status:  u.Status,
+			FromHex: u.PreviousHex,
+			ToHex:   u.CurrentHex,
+		}
+		if u.Status != "" {
+			_, moves.Inventory = parser.SplitStatusInventory([]byte(u.Status))
+		}
+		for _, m := range u.Moves {
+			move, err := toMove(t.Id, unitId, m)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %d: %w", doc.Source, u.Id, m.Line, err)
+			}
+			moves.Moves = append(moves.Moves, move)
+		}
+		for _, s := range u.Scouts {
+			scout := &parser.Scout_t{No: s.No, TurnId: t.Id, LineNo: s.Line}
+			for _, m := range s.Moves {
+				move, err := toMove(t.Id, unitId, m)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s: %d: %w", doc.Source, u.Id, m.Line, err)
+				}
+				move.Report.ScoutedTurnId = t.Id
+				scout.Moves = append(scout.Moves, move)
+			}
+			moves.Scouts = append(moves.Scouts, scout)
+		}
+		for _, e := range u.Events {
+			kind, ok := parser.StringToUnitEvent[e.Kind]
+			if !ok {
+				return nil, fmt.Errorf("%s: %s: %d: unknown event %q", doc.Source, u.Id, e.Line, e.Kind)
+			}
+			moves.Events = append(moves.Events, &parser.UnitEvent_t{Kind: kind, UnitId: parser.UnitId_t(e.Unit), LineNo: e.Line})
+		}
+		for _, o := range u.Owners {
+			moves.Owners = append(moves.Owners, &parser.SettlementOwner_t{Name: o.Settlement, Clan: parser.UnitId_t(o.Clan), Captured: o.Captured, LineNo: o.Line})
+		}
+		t.UnitMoves[unitId] = moves
+	}
+	for _, s := range doc.Specials {
+		special, err := toSpecial(t.Id, s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: special %q: %w", doc.Source, s.Id, err)
+		}
+		t.SpecialNames[special.Key()] = special
+	}
+	return t, nil
+}
+
+func toSpecial(tid string, s *Special_t) (*parser.Special_t, error) {
+	special := &parser.Special_t{TurnId: tid, Id: s.Id, Name: s.Name}
+	if s.Location != "" {
+		location, err := coords.HexToMap(s.Location)
+		if err != nil {
+			return nil, err
+		}
+		special.Location = location
+	}
+	for _, d := range s.Directives {
+		kind, ok := parser.StringToDirective[d.Kind]
+		if !ok {
+			return nil, fmt.Errorf("unknown directive %q", d.Kind)
+		}
+		special.Directives = append(special.Directives, &parser.Directive_t{Kind: kind, Value: d.Value})
+	}
+	return special, nil
+}
+
+func toMove(tid string, unitId parser.UnitId_t, m *Move_t) (*parser.Move_t, error) {
+	move := &parser.Move_t{
+		UnitId:  unitId,
+		Follows: parser.UnitId_t(m.Follows),
+		GoesTo:  m.GoesTo,
+		Still:   m.Still,
+		LineNo:  m.Line,
+		StepNo:  m.Step,
+		TurnId:  tid,
+	}
+	var ok bool
+	if m.Advance != "" {
+		if move.Advance, ok = direction.StringToEnum[m.Advance]; !ok {
+			return nil, fmt.Errorf("advance: unknown direction %q", m.Advance)
+		}
+	}
+	if m.Wind != "" {
+		strength, from, _ := strings.Cut(m.Wind, " ")
+		if move.Wind.Strength, ok = winds.StringToEnum[strength]; !ok {
+			return nil, fmt.Errorf("wind: unknown strength %q", strength)
+		}
+		if move.Wind.From, ok = direction.StringToEnum[from]; !ok {
+			return nil, fmt.Errorf("wind: unknown direction %q", from)
+		}
+	}
+	if m.Result != "" {
+		if move.Result, ok = results.StringToEnum[m.Result]; !ok {
+			return nil, fmt.Errorf("unknown result %q", m.Result)
+		}
+	}
+	if m.Reason != "" {
+		if move.Reason, ok = results.StringToEnum[m.Reason]; !ok {
+			return nil, fmt.Errorf("unknown reason %q", m.Reason)
+		}
+	}
+	report, err := toReport(tid, unitId, m.Report)
+	if err != nil {
+		return nil, err
+	}
+	move.Report = report
+	return move, nil
+}
+
+// toReport returns an empty report if there are no observations.
+func toReport(tid string, unitId parser.UnitId_t, r *Report_t) (*parser.Report_t, error) {
+	report := &parser.Report_t{TurnId: tid, UnitId: unitId}
+	if r == nil {
+		return report, nil
+	}
+	var ok bool
+	if r.Terrain != "" {
+		if report.Terrain, ok = terrain.StringToEnum[r.Terrain]; !ok {
+			return nil, fmt.Errorf("unknown terrain %q", r.Terrain)
+		}
+	}
+	for _, b := range r.Borders {
+		border := &parser.Border_t{}
+		if border.Direction, ok = direction.StringToEnum[b.Direction]; !ok {
+			return nil, fmt.Errorf("border: unknown direction %q", b.Direction)
+		}
+		if b.Edge != "" {
+			if border.Edge, ok = edges.StringToEnum[b.Edge]; !ok {
+				return nil, fmt.Errorf("border: unknown edge %q", b.Edge)
+			}
+		}
+		if b.Terrain != "" {
+			if border.Terrain, ok = terrain.StringToEnum[b.Terrain]; !ok {
+				return nil, fmt.Errorf("border: unknown terrain %q", b.Terrain)
+			}
+		}
+		report.Borders = append(report.Borders, border)
+	}
+	for _, e := range r.Encounters {
+		report.Encounters = append(report.Encounters, &parser.Encounter_t{TurnId: tid, UnitId: parser.UnitId_t(e)})
+	}
+	for _, i := range r.Items {
+		item, err := toItem(i)
+		if err != nil {
+			return nil, err
+		}
+		report.Items = append(report.Items, item)
+	}
+	for _, rs := range r.Resources {
+		resource, ok := resources.StringToEnum[rs]
+		if !ok {
+			return nil, fmt.Errorf("unknown resource %q", rs)
+		}
+		report.Resources = append(report.Resources, resource)
+	}
+	for _, s := range r.Settlements {
+		report.Settlements = append(report.Settlements, &parser.Settlement_t{TurnId: tid, Name: s})
+	}
+	for _, fh := range r.FarHorizons {
+		farHorizon := &parser.FarHorizon_t{}
+		if farHorizon.Point, ok = compass.StringToEnum[fh.Point]; !ok {
+			return nil, fmt.Errorf("far horizon: unknown point %q", fh.Point)
+		}
+		if farHorizon.Terrain, ok = terrain.StringToEnum[fh.Terrain]; !ok {
+			return nil, fmt.Errorf("far horizon: unknown terrain %q", fh.Terrain)
+		}
+		report.FarHorizons = append(report.FarHorizons, farHorizon)
+	}
+	return report, nil
+}
+
+// toItem parses the "found(quantity-item)" text from FoundItem_t.String.
+func toItem(s string) (*parser.FoundItem_t, error) {
+	text, ok := strings.CutPrefix(s, "found(")
+	if ok {
+		text, ok = strings.CutSuffix(text, ")")
+	}
+	qty, name, found := strings.Cut(text, "-")
+	if !ok || !found {
+		return nil, fmt.Errorf("item %q: want found(quantity-item)", s)
+	}
+	quantity, err := strconv.Atoi(qty)
+	if err != nil {
+		return nil, fmt.Errorf("item %q: %w", s, err)
+	}
+	item, ok := items.StringToEnum[name]
+	if !ok {
+		return nil, fmt.Errorf("item %q: unknown item", s)
+	}
+	return &parser.FoundItem_t{Quantity: quantity, Item: item}, nil
+}
@@ -0,0 +1,341 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package transform
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/turns"
+	"strconv"
+	"strings"
+)
+
+// Expr_t is a compiled expression that selects tiles.
+type Expr_t interface {
+	// Match returns true if the expression is true for the tile.
+	// The turn id is the last turn on the map, for ages.
+	Match(t *tiles.Tile_t, turnId string) bool
+}
+
+// Compile parses an expression. The grammar is:
+//
+//	expr  = and { "or" and }
+//	and   = unary { "and" unary }
+//	unary = "not" unary | "(" expr ")" | test
+//	test  = list ( "==" | "!=" ) value
+//	      | "age" ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) number
+//	      | flag
+//	list  = "terrain" | "edge" | "resource" | "settlement" | "unit"
+//	flag  = "visited" | "scouted" | "settled" | "occupied" | "true"
+//
+// A list test is true if any item matches, for example "edge == River" is
+// true if the hex has a river on any side. Values with spaces must be quoted.
+// Keywords and values are not case sensitive.
+func Compile(src string) (Expr_t, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &compiler_t{tokens: tokens}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	} else if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return e, nil
+}
+
+type token_t struct {
+	text   string
+	quoted bool
+}
+
+func lex(src string) ([]token_t, error) {
+	var tokens []token_t
+	for i := 0; i < len(src); {
+		switch ch := src[i]; {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+		case ch == '(' || ch == ')':
+			tokens, i = append(tokens, token_t{text: src[i : i+1]}), i+1
+		case ch == '=' || ch == '!' || ch == '<' || ch == '>':
+			if i+1 < len(src) && src[i+1] == '=' {
+				tokens, i = append(tokens, token_t{text: src[i : i+2]}), i+2
+			} else if ch == '<' || ch == '>' {
+				tokens, i = append(tokens, token_t{text: src[i : i+1]}), i+1
+			} else {
+				return nil, fmt.Errorf("unexpected %q", src[i:i+1])
+			}
+		case ch == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end == -1 {
+				return nil, fmt.Errorf("missing closing quote")
+			}
+			tokens, i = append(tokens, token_t{text: src[i+1 : i+1+end], quoted: true}), i+end+2
+		case isWordChar(ch):
+			start := i
+			for i < len(src) && isWordChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token_t{text: src[start:i]})
+		default:
+			return nil, fmt.Errorf("unexpected %q", src[i:i+1])
+		}
+	}
+	return tokens, nil
+}
+
+func isWordChar(ch byte) bool {
+	return ch == '_' || ch == '-' || ('0' <= ch && ch <= '9') || ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z')
+}
+
+type compiler_t struct {
+	tokens []token_t
+	pos    int
+}
+
+// accept consumes the next token if it is the keyword.
+func (p *compiler_t) accept(keyword string) bool {
+	if p.pos < len(p.tokens) && !p.tokens[p.pos].quoted && strings.EqualFold(p.tokens[p.pos].text, keyword) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *compiler_t) next() (token_t, error) {
+	if p.pos >= len(p.tokens) {
+		return token_t{}, fmt.Errorf("unexpected end of expression")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *compiler_t) or() (Expr_t, error) {
+	e, err := p.and()
+	for err == nil && p.accept("or") {
+		var rhs Expr_t
+		if rhs, err = p.and(); err == nil {
+			e = orExpr{e, rhs}
+		}
+	}
+	return e, err
+}
+
+func (p *compiler_t) and() (Expr_t, error) {
+	e, err := p.unary()
+	for err == nil && p.accept("and") {
+		var rhs Expr_t
+		if rhs, err = p.unary(); err == nil {
+			e = andExpr{e, rhs}
+		}
+	}
+	return e, err
+}
+
+func (p *compiler_t) unary() (Expr_t, error) {
+	if p.accept("not") {
+		e, err := p.unary()
+		return notExpr{e}, err
+	} else if p.accept("(") {
+		e, err := p.or()
+		if err == nil && !p.accept(")") {
+			err = fmt.Errorf("missing closing parenthesis")
+		}
+		return e, err
+	}
+	return p.test()
+}
+
+func (p *compiler_t) test() (Expr_t, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	} else if tok.quoted {
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+	field := strings.ToLower(tok.text)
+	switch field {
+	case "true", "visited", "scouted", "settled", "occupied":
+		return flagExpr(field), nil
+	case "age":
+		op, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch op.text {
+		case "==", "!=", "<", "<=", ">", ">=":
+		default:
+			return nil, fmt.Errorf("age: unexpected %q", op.text)
+		}
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(value.text)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("age: %q: want a number of turns", value.text)
+		}
+		return ageExpr{op: op.text, turns: n}, nil
+	case "terrain", "edge", "resource", "settlement", "unit":
+		op, err := p.next()
+		if err != nil {
+			return nil, err
+		} else if op.text != "==" && op.text != "!=" {
+			return nil, fmt.Errorf("%s: unexpected %q", field, op.text)
+		}
+		value, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		canonicalValue, err := canonical(field, value.text)
+		if err != nil {
+			return nil, err
+		}
+		return listExpr{field: field, value: canonicalValue, negate: op.text == "!="}, nil
+	}
+	return nil, fmt.Errorf("unknown field %q", tok.text)
+}
+
+// canonical returns the value as it is stored in the tile, or an error if
+// the value can never match.
+func canonical(field, value string) (string, error) {
+	var names []string
+	switch field {
+	case "terrain":
+		for _, name := range terrain.EnumToString {
+			names = append(names, name)
+		}
+	case "edge":
+		for _, name := range edges.EnumToString {
+			names = append(names, name)
+		}
+	case "resource":
+		for _, name := range resources.EnumToString {
+			names = append(names, name)
+		}
+	default:
+		return value, nil
+	}
+	for _, name := range names {
+		if name != "" && strings.EqualFold(name, value) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("%s: unknown value %q", field, value)
+}
+
+type orExpr struct{ lhs, rhs Expr_t }
+
+func (e orExpr) Match(t *tiles.Tile_t, turnId string) bool {
+	return e.lhs.Match(t, turnId) || e.rhs.Match(t, turnId)
+}
+
+type andExpr struct{ lhs, rhs Expr_t }
+
+func (e andExpr) Match(t *tiles.Tile_t, turnId string) bool {
+	return e.lhs.Match(t, turnId) && e.rhs.Match(t, turnId)
+}
+
+type notExpr struct{ e Expr_t }
+
+func (e notExpr) Match(t *tiles.Tile_t, turnId string) bool {
+	return !e.e.Match(t, turnId)
+}
+
+type flagExpr string
+
+func (e flagExpr) Match(t *tiles.Tile_t, turnId string) bool {
+	switch e {
+	case "true":
+		return true
+	case "visited":
+		return t.Visited != ""
+	case "scouted":
+		return t.Scouted != ""
+	case "settled":
+		return len(t.Settlements) != 0 || len(t.Special) != 0
+	case "occupied":
+		return len(t.Encounters) != 0
+	}
+	return false
+}
+
+// ageExpr compares the number of turns since the tile was last reported.
+type ageExpr struct {
+	op    string
+	turns int
+}
+
+func (e ageExpr) Match(t *tiles.Tile_t, turnId string) bool {
+	age := Age(t.LastSeen, turnId)
+	switch e.op {
+	case "==":
+		return age == e.turns
+	case "!=":
+		return age != e.turns
+	case "<":
+		return age < e.turns
+	case "<=":
+		return age <= e.turns
+	case ">":
+		return age > e.turns
+	case ">=":
+		return age >= e.turns
+	}
+	return false
+}
+
+type listExpr struct {
+	field  string
+	value  string
+	negate bool
+}
+
+func (e listExpr) Match(t *tiles.Tile_t, turnId string) bool {
+	found := false
+	switch e.field {
+	case "terrain":
+		found = t.Terrain.String() == e.value
+	case "edge":
+		for _, list := range t.Edges {
+			for _, edge := range list {
+				found = found || edge.String() == e.value
+			}
+		}
+	case "resource":
+		for _, r := range t.Resources {
+			found = found || r.String() == e.value
+		}
+	case "settlement":
+		for _, s := range t.Settlements {
+			found = found || strings.EqualFold(s.Name, e.value)
+		}
+		for _, s := range t.Special {
+			found = found || strings.EqualFold(s.Name, e.value)
+		}
+	case "unit":
+		for _, u := range t.Encounters {
+			found = found || strings.EqualFold(string(u.UnitId), e.value)
+		}
+	}
+	return found != e.negate
+}
+
+// Age returns the number of turns from one turn to a later one.
+// It returns 0 if either turn id is invalid.
+func Age(from, to string) int {
+	fy, fm, err := turns.ParseTurnId(from)
+	if err != nil {
+		return 0
+	}
+	ty, tm, err := turns.ParseTurnId(to)
+	if err != nil {
+		return 0
+	}
+	return (ty*12 + tm) - (fy*12 + fm)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package transform applies the player's rules to the merged map before it is drawn.
+//
+// A rule selects hexes with an expression, for example
+//
+//	terrain == SW and edge == River
+//
+// and then annotates them, drops old encounters from them, or both.
+// See Compile for the expression language.
+package transform
+
+import (
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"sort"
+)
+
+// Rule_t is a compiled rule.
+type Rule_t struct {
+	Name string // for the log, usually the expression
+	When Expr_t
+	// Annotation is added to every hex the rule selects, if set.
+	Annotation *annotations.Annotation_t
+	// DropEncountersOlderThan removes encounters from more than this many
+	// turns before the last turn from the selected hexes. Zero keeps them all.
+	DropEncountersOlderThan int
+}
+
+// Result_t counts what a rule did.
+type Result_t struct {
+	Rule       *Rule_t
+	Matched    int // hexes the rule selected
+	Encounters int // encounters that were dropped
+}
+
+// Apply runs the rules in order on every tile. A rule sees the changes made
+// by the rules before it. The turn id is the last turn on the map.
+// It returns the annotations to draw, sorted by location, and what each rule did.
+func Apply(worldMap *tiles.Map_t, rules []*Rule_t, turnId string) ([]*annotations.Hex_t, []Result_t) {
+	notes := map[*tiles.Tile_t]*annotations.Hex_t{}
+	var results []Result_t
+	for _, rule := range rules {
+		result := Result_t{Rule: rule}
+		for _, t := range worldMap.Tiles {
+			if !rule.When.Match(t, turnId) {
+				continue
+			}
+			result.Matched++
+			if rule.DropEncountersOlderThan != 0 {
+				kept := t.Encounters[:0]
+				for _, e := range t.Encounters {
+					if Age(e.TurnId, turnId) > rule.DropEncountersOlderThan {
+						result.Encounters++
+						continue
+					}
+					kept = append(kept, e)
+				}
+				t.Encounters = kept
+			}
+			if rule.Annotation != nil {
+				hex, ok := notes[t]
+				if !ok {
+					hex = &annotations.Hex_t{Location: t.Location}
+					notes[t] = hex
+				}
+				hex.Annotations = append(hex.Annotations, rule.Annotation)
+			}
+		}
+		results = append(results, result)
+	}
+	var list []*annotations.Hex_t
+	for _, hex := range notes {
+		list = append(list, hex)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Location.GridString() < list[j].Location.GridString()
+	})
+	return list, results
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package transform_test
+
+import (
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/transform"
+	"testing"
+)
+
+func TestCompile(t *testing.T) {
+	swamp := &tiles.Tile_t{Terrain: terrain.Swamp, LastSeen: "0901-02", Visited: "0901-02"}
+	swamp.Edges[direction.North] = []edges.Edge_e{edges.River}
+	swamp.Settlements = []*parser.Settlement_t{{Name: "Bree Town"}}
+	for _, tc := range []struct {
+		id   int
+		expr string
+		want bool
+		err  string
+	}{
+		{id: 1, expr: "terrain == SW", want: true},
+		{id: 2, expr: "terrain == sw and edge == river", want: true},
+		{id: 3, expr: "terrain == SW and not edge == River", want: false},
+		{id: 4, expr: "terrain != SW or (visited and settlement == \"bree town\")", want: true},
+		{id: 5, expr: "age > 3", want: true},
+		{id: 6, expr: "age <= 3 or scouted", want: false},
+		{id: 7, expr: "edge == \"Stone Road\"", want: false},
+		{id: 8, expr: "occupied", want: false},
+		{id: 9, expr: "terrain == XX", err: `terrain: unknown value "XX"`},
+		{id: 10, expr: "terrain = SW", err: `unexpected "="`},
+		{id: 11, expr: "(true", err: "missing closing parenthesis"},
+		{id: 12, expr: "height == 3", err: `unknown field "height"`},
+		{id: 13, expr: "true true", err: `unexpected "true"`},
+		{id: 14, expr: "", err: "unexpected end of expression"},
+	} {
+		e, err := transform.Compile(tc.expr)
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%d: %q: want error %q, got %v", tc.id, tc.expr, tc.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%d: %q: %v", tc.id, tc.expr, err)
+			continue
+		}
+		if got := e.Match(swamp, "0901-08"); got != tc.want {
+			t.Errorf("%d: %q: want %v, got %v", tc.id, tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	worldMap := tiles.NewMap()
+	location, _ := coords.HexToMap("AB 1203")
+	tile := worldMap.FetchTile("0138", location)
+	tile.Terrain = terrain.Swamp
+	tile.Encounters = []*parser.Encounter_t{{TurnId: "0901-02", UnitId: "0249"}, {TurnId: "0901-07", UnitId: "0249e1"}}
+	other, _ := coords.HexToMap("AB 1204")
+	worldMap.FetchTile("0138", other).Terrain = terrain.Prairie
+
+	fishing, _ := transform.Compile("terrain == SW")
+	stale, _ := transform.Compile("true")
+	list, results := transform.Apply(worldMap, []*transform.Rule_t{
+		{Name: "fishing", When: fishing, Annotation: &annotations.Annotation_t{Note: "good fishing"}},
+		{Name: "stale", When: stale, DropEncountersOlderThan: 3},
+	}, "0901-08")
+	if len(list) != 1 || list[0].Location != location || list[0].Annotations[0].Note != "good fishing" {
+		t.Errorf("annotations: got %+v", list)
+	}
+	if results[0].Matched != 1 || results[1].Matched != 2 || results[1].Encounters != 1 {
+		t.Errorf("results: got %+v", results)
+	}
+	if len(tile.Encounters) != 1 || tile.Encounters[0].UnitId != "0249e1" {
+		t.Errorf("encounters: got %+v", tile.Encounters)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tui
+
+import (
+	"bufio"
+	"unicode/utf8"
+)
+
+// Key_e is a key that the browser knows about.
+type Key_e int
+
+const (
+	KeyUnknown Key_e = iota
+	KeyRune          // a printable character
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyEnter
+	KeyEscape
+	KeyBackspace
+	KeyInterrupt // control-C
+)
+
+// Key_t is a key press. Rune is set only for KeyRune.
+type Key_t struct {
+	Key  Key_e
+	Rune rune
+}
+
+// ReadKey reads one key press from a terminal in raw mode.
+// The arrow keys are the ANSI escape sequences "ESC [ A" through "ESC [ D".
+// An escape that isn't followed by anything already in the buffer is the escape key.
+func ReadKey(r *bufio.Reader) (Key_t, error) {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return Key_t{}, err
+	}
+	switch ch {
+	case 0x03:
+		return Key_t{Key: KeyInterrupt}, nil
+	case '\r', '\n':
+		return Key_t{Key: KeyEnter}, nil
+	case 0x08, 0x7f:
+		return Key_t{Key: KeyBackspace}, nil
+	case 0x1b:
+		if r.Buffered() == 0 {
+			return Key_t{Key: KeyEscape}, nil
+		}
+		if next, _ := r.Peek(1); next[0] != '[' && next[0] != 'O' {
+			return Key_t{Key: KeyEscape}, nil
+		}
+		_, _ = r.ReadByte()
+		// skip the parameters of the sequence and use the final byte
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return Key_t{}, err
+			}
+			if b < 0x40 || b > 0x7e {
+				continue
+			}
+			switch b {
+			case 'A':
+				return Key_t{Key: KeyUp}, nil
+			case 'B':
+				return Key_t{Key: KeyDown}, nil
+			case 'C':
+				return Key_t{Key: KeyRight}, nil
+			case 'D':
+				return Key_t{Key: KeyLeft}, nil
+			}
+			return Key_t{Key: KeyUnknown}, nil
+		}
+	}
+	if ch == utf8.RuneError || ch < ' ' {
+		return Key_t{Key: KeyUnknown}, nil
+	}
+	return Key_t{Key: KeyRune, Rune: ch}, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"golang.org/x/term"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Run browses the map until the user quits.
+// The terminal is switched to raw mode and the alternate screen, and both are
+// restored when Run returns or the process is interrupted or terminated.
+func Run(m *Model_t, color bool) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("tui: stdin is not a terminal")
+	}
+	saved, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("tui: %w", err)
+	}
+	_, _ = fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	restore := func() {
+		_, _ = fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+		_ = term.Restore(fd, saved)
+	}
+	defer restore()
+
+	// raw mode passes Ctrl-C through as a key, but a signal from outside would
+	// otherwise leave the terminal in raw mode after the process exits.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer func() {
+		signal.Stop(signals)
+		close(signals)
+	}()
+	go func() {
+		if _, ok := <-signals; ok {
+			restore()
+			os.Exit(1)
+		}
+	}()
+
+	r := bufio.NewReader(os.Stdin)
+	for !m.Quitting {
+		// the size is checked every time so that resizing the window works
+		width, height := size()
+		if err := m.View(os.Stdout, width, height, color); err != nil {
+			return err
+		}
+		key, err := ReadKey(r)
+		if err != nil {
+			return err
+		}
+		m.Update(key)
+	}
+	return nil
+}
+
+// size returns the width and height of the terminal, or 80 by 24 if the terminal can't tell.
+func size() (width, height int) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width == 0 || height == 0 {
+		return 80, 24
+	}
+	return width, height
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package tui is a terminal browser for the merged map.
+//
+// The Model_t holds all the state and is updated one key at a time, so the
+// browser can be tested without a terminal. Run puts the terminal in raw mode
+// and redraws the screen after every key.
+package tui
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/search"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"sort"
+	"strings"
+)
+
+// Mode_e is what the keys are being used for.
+type Mode_e int
+
+const (
+	Browse Mode_e = iota // keys move the cursor
+	Search               // keys are typed into the search text
+	Goto                 // keys are typed into the grid coordinates
+	Help                 // any key closes the help
+)
+
+// Observation_t is a report that a unit or scout made about a hex.
+type Observation_t struct {
+	TurnId string
+	UnitId parser.UnitId_t
+	Scout  int  // scout number, zero if the unit made the report
+	Ended  bool // true if the unit ended the turn in the hex
+	Report *parser.Report_t
+}
+
+// Model_t is the state of the browser.
+type Model_t struct {
+	Name     string
+	Tiles    *tiles.Map_t
+	Turns    []*parser.Turn_t
+	Notes    []*annotations.Hex_t // searched along with the map
+	History  map[coords.Map][]*Observation_t
+	Units    map[coords.Map][]parser.UnitId_t // units that ended the last turn they were reported in each hex
+	Cursor   coords.Map
+	Mode     Mode_e
+	Input    string           // search text or grid coordinates being typed
+	Matches  []search.Match_t // results of the last search
+	Match    int              // index of the current match
+	Message  string           // shown on the status line until the next key
+	Scroll   int              // first line of the details that is shown
+	Quitting bool
+}
+
+// New returns a browser for the map with the cursor on the first hex.
+// The turns must be sorted and walked so that the locations are set.
+func New(name string, worldMap *tiles.Map_t, turns []*parser.Turn_t) *Model_t {
+	m := &Model_t{
+		Name:    name,
+		Tiles:   worldMap,
+		Turns:   turns,
+		History: Observations(turns),
+		Units:   map[coords.Map][]parser.UnitId_t{},
+	}
+	latest := map[parser.UnitId_t]coords.Map{}
+	for _, turn := range turns {
+		for _, moves := range turn.SortedMoves {
+			if !moves.Location.IsZero() {
+				latest[moves.UnitId] = moves.Location
+			}
+		}
+	}
+	for id, location := range latest {
+		m.Units[location] = append(m.Units[location], id)
+	}
+	for _, units := range m.Units {
+		sort.Slice(units, func(i, j int) bool { return units[i] < units[j] })
+	}
+
+	// start on the last location of the first unit, or the upper left tile
+	var ids []parser.UnitId_t
+	for id := range latest {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if len(ids) != 0 {
+		m.Cursor = latest[ids[0]]
+	} else {
+		m.Cursor, _ = worldMap.Bounds()
+	}
+	return m
+}
+
+// Observations returns every report made in the turns, grouped by hex and in turn order.
+func Observations(turns []*parser.Turn_t) map[coords.Map][]*Observation_t {
+	history := map[coords.Map][]*Observation_t{}
+	// the walk sets the location of the move, not the report
+	add := func(location coords.Map, o *Observation_t) {
+		if o.Report == nil || location.IsZero() {
+			return
+		}
+		history[location] = append(history[location], o)
+	}
+	for _, turn := range turns {
+		for _, moves := range turn.SortedMoves {
+			for i, move := range moves.Moves {
+				add(move.Location, &Observation_t{TurnId: turn.Id, UnitId: moves.UnitId, Ended: i == len(moves.Moves)-1, Report: move.Report})
+			}
+			for _, scout := range moves.Scouts {
+				for _, move := range scout.Moves {
+					add(move.Location, &Observation_t{TurnId: turn.Id, UnitId: moves.UnitId, Scout: scout.No, Report: move.Report})
+				}
+			}
+		}
+	}
+	return history
+}
+
+// Update applies a key to the model.
+func (m *Model_t) Update(key Key_t) {
+	m.Message = ""
+	if key.Key == KeyInterrupt {
+		m.Quitting = true
+		return
+	}
+	switch m.Mode {
+	case Help:
+		m.Mode = Browse
+	case Search, Goto:
+		m.edit(key)
+	default:
+		m.browse(key)
+	}
+}
+
+// browse handles the keys that move the cursor.
+func (m *Model_t) browse(key Key_t) {
+	if key.Key == KeyRune {
+		// vi keys move the cursor too
+		switch key.Rune {
+		case 'h':
+			key = Key_t{Key: KeyLeft}
+		case 'j':
+			key = Key_t{Key: KeyDown}
+		case 'k':
+			key = Key_t{Key: KeyUp}
+		case 'l':
+			key = Key_t{Key: KeyRight}
+		}
+	}
+	switch key.Key {
+	case KeyUp:
+		m.move(coords.Map{Column: m.Cursor.Column, Row: m.Cursor.Row - 1})
+	case KeyDown:
+		m.move(coords.Map{Column: m.Cursor.Column, Row: m.Cursor.Row + 1})
+	case KeyLeft:
+		m.move(coords.Map{Column: m.Cursor.Column - 1, Row: m.Cursor.Row})
+	case KeyRight:
+		m.move(coords.Map{Column: m.Cursor.Column + 1, Row: m.Cursor.Row})
+	case KeyEscape:
+		m.Matches, m.Match = nil, 0
+	case KeyRune:
+		switch key.Rune {
+		case 'q':
+			m.Quitting = true
+		case '?':
+			m.Mode = Help
+		case '/':
+			m.Mode, m.Input = Search, ""
+		case 'g':
+			m.Mode, m.Input = Goto, ""
+		case 'n':
+			m.next(1)
+		case 'N':
+			m.next(-1)
+		case '[':
+			m.Scroll = max(0, m.Scroll-1)
+		case ']':
+			m.Scroll++
+		}
+	}
+}
+
+// edit handles the keys that type into the search text or grid coordinates.
+func (m *Model_t) edit(key Key_t) {
+	switch key.Key {
+	case KeyEscape:
+		m.Mode, m.Input = Browse, ""
+	case KeyBackspace:
+		if n := len([]rune(m.Input)); n != 0 {
+			m.Input = string([]rune(m.Input)[:n-1])
+		}
+	case KeyRune:
+		m.Input += string(key.Rune)
+	case KeyEnter:
+		mode, input := m.Mode, strings.TrimSpace(m.Input)
+		m.Mode, m.Input = Browse, ""
+		if mode == Goto {
+			m.jump(input)
+		} else {
+			m.find(input)
+		}
+	}
+}
+
+// move puts the cursor on the hex and resets the details.
+func (m *Model_t) move(location coords.Map) {
+	if location.Column < 0 || location.Row < 0 {
+		return
+	}
+	m.Cursor, m.Scroll = location, 0
+}
+
+// jump moves the cursor to grid coordinates like "AB 1203".
+func (m *Model_t) jump(hex string) {
+	if hex == "" {
+		return
+	}
+	location, err := coords.HexToMap(strings.ToUpper(hex))
+	if err != nil {
+		m.Message = fmt.Sprintf("goto: %v", err)
+		return
+	}
+	m.move(location)
+}
+
+// find searches the settlements, special hexes, units, and notes and moves
+// the cursor to the first match.
+func (m *Model_t) find(text string) {
+	m.Matches, m.Match = nil, 0
+	if text == "" {
+		return
+	}
+	m.Matches = search.Find(text, m.Tiles, m.Turns, m.Notes)
+	if len(m.Matches) == 0 {
+		m.Message = fmt.Sprintf("search: %q not found", text)
+		return
+	}
+	m.move(m.Matches[0].Location)
+}
+
+// next moves the cursor to the next (or previous) match, wrapping around.
+func (m *Model_t) next(step int) {
+	if len(m.Matches) == 0 {
+		m.Message = "search: no matches"
+		return
+	}
+	m.Match = (m.Match + step + len(m.Matches)) % len(m.Matches)
+	m.move(m.Matches[m.Match].Location)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tui_test
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/tui"
+	"strings"
+	"testing"
+)
+
+func TestModel(t *testing.T) {
+	home, bree := coords.Map{Column: 10, Row: 10}, coords.Map{Column: 11, Row: 10}
+	worldMap := tiles.NewMap()
+	worldMap.FetchTile("0138", home).Terrain = terrain.Prairie
+	tile := worldMap.FetchTile("0138", bree)
+	tile.Terrain = terrain.GrassyHills
+	tile.Settlements = append(tile.Settlements, &parser.Settlement_t{Name: "Bree"})
+	turns := []*parser.Turn_t{
+		{Id: "0902-02", SortedMoves: []*parser.Moves_t{
+			{UnitId: "0138", Location: home, Moves: []*parser.Move_t{
+				{Location: bree, Report: &parser.Report_t{Terrain: terrain.GrassyHills}},
+				{Location: home, Report: &parser.Report_t{Terrain: terrain.Prairie}},
+			}, Scouts: []*parser.Scout_t{
+				{No: 1, Moves: []*parser.Move_t{{Location: bree, Report: &parser.Report_t{Terrain: terrain.GrassyHills}}}},
+			}},
+		}},
+	}
+
+	m := tui.New("test", worldMap, turns)
+	if m.Cursor != home {
+		t.Fatalf("cursor: want %s, got %s", home.GridString(), m.Cursor.GridString())
+	}
+
+	// type the keys as a terminal would send them
+	keys := bufio.NewReader(strings.NewReader("/bree\r"))
+	for {
+		key, err := tui.ReadKey(keys)
+		if err != nil {
+			break
+		}
+		m.Update(key)
+	}
+	if m.Cursor != bree || len(m.Matches) != 1 {
+		t.Fatalf("search: want cursor on %s with 1 match, got %s with %d", bree.GridString(), m.Cursor.GridString(), len(m.Matches))
+	}
+	details := strings.Join(m.Details(), "\n")
+	for _, want := range []string{"settlement   Bree", "observations (2)", "0138     passed", "0138s1   scouted"} {
+		if !strings.Contains(details, want) {
+			t.Errorf("details: missing %q in\n%s", want, details)
+		}
+	}
+
+	key, err := tui.ReadKey(bufio.NewReader(strings.NewReader("\x1b[D")))
+	if err != nil || key.Key != tui.KeyLeft {
+		t.Fatalf("read: want left arrow, got %+v, %v", key, err)
+	}
+	m.Update(key)
+	if m.Cursor != home {
+		t.Errorf("left: want %s, got %s", home.GridString(), m.Cursor.GridString())
+	}
+
+	m.Update(tui.Key_t{Key: tui.KeyRune, Rune: 'g'})
+	for _, r := range bree.GridString() {
+		m.Update(tui.Key_t{Key: tui.KeyRune, Rune: r})
+	}
+	m.Update(tui.Key_t{Key: tui.KeyEnter})
+	if m.Cursor != bree {
+		t.Errorf("goto: want %s, got %s", bree.GridString(), m.Cursor.GridString())
+	}
+
+	var buf bytes.Buffer
+	if err := m.View(&buf, 80, 24, false); err != nil {
+		t.Fatal(err)
+	} else if got := strings.Count(buf.String(), "\r\n"); got != 23 {
+		t.Errorf("view: want 23 lines, got %d", got)
+	}
+
+	m.Update(tui.Key_t{Key: tui.KeyRune, Rune: 'q'})
+	if !m.Quitting {
+		t.Errorf("quit: want quitting")
+	}
+}
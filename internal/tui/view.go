@@ -0,0 +1,216 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/render"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// detailsWidth is the width of the panel to the right of the map.
+const detailsWidth = 44
+
+// HelpText is shown in place of the details by the "?" key.
+var HelpText = []string{
+	"arrows, h j k l   move the cursor",
+	"/                 search settlements, units, and notes",
+	"n, N              next or previous match",
+	"esc               clear the matches",
+	"g                 go to grid coordinates",
+	"[, ]              scroll the details",
+	"?                 show this help",
+	"q, ctrl-c         quit",
+	"",
+	"press any key to close the help",
+}
+
+// View draws the whole screen: the title, the map around the cursor with the
+// details of the cursor hex to the right, and the status line at the bottom.
+// Lines end with "\r\n" because the terminal is in raw mode.
+// If color is set, the hexes are shaded with the terrain colors.
+func (m *Model_t) View(w io.Writer, width, height int, color bool) error {
+	width, height = max(width, detailsWidth+8), max(height, 6)
+	mapWidth, mapHeight := width-detailsWidth-2, height-2
+
+	var details []string
+	if m.Mode == Help {
+		details = HelpText
+	} else {
+		details = m.Details()
+		m.Scroll = min(m.Scroll, max(0, len(details)-mapHeight))
+		details = details[m.Scroll:]
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\x1b[H%s\x1b[K\r\n", clip(fmt.Sprintf("%s   %s   ? for help", m.Name, m.Cursor.GridString()), width))
+	for i, line := range m.mapLines(mapWidth/2, mapHeight, color) {
+		detail := ""
+		if i < len(details) {
+			detail = clip(details[i], detailsWidth)
+		}
+		fmt.Fprintf(bw, "%s  %s\x1b[K\r\n", line, detail)
+	}
+	fmt.Fprintf(bw, "%s\x1b[K", clip(m.status(), width-1))
+	return bw.Flush()
+}
+
+// mapLines returns the map centered on the cursor, padded to columns*2 characters.
+// There are two lines for each row; the odd columns are printed on the second one.
+func (m *Model_t) mapLines(columns, lines int, color bool) []string {
+	firstColumn := m.Cursor.Column - columns/2
+	firstColumn -= firstColumn & 1 // keep the odd columns shifted down
+	firstRow := m.Cursor.Row - lines/4
+
+	var out []string
+	for i := 0; i < lines; i++ {
+		row, half := firstRow+i/2, i%2
+		var line strings.Builder
+		for column := firstColumn; column < firstColumn+columns; column++ {
+			if column&1 != half || column < 0 || row < 0 {
+				line.WriteString("  ")
+				continue
+			}
+			var symbol string
+			var t terrain.Terrain_e
+			if tile, ok := m.Tiles.Tiles[coords.Map{Column: column, Row: row}]; ok {
+				t = tile.Terrain
+				if symbol = render.TextSymbols[t]; symbol == "" {
+					symbol = "??"
+				}
+			} else {
+				symbol = "  "
+			}
+			isCursor := m.Cursor.Column == column && m.Cursor.Row == row
+			if isCursor && symbol == "  " {
+				symbol = "[]"
+			}
+			switch {
+			case color && t != terrain.Blank:
+				if isCursor {
+					line.WriteString("\x1b[7m")
+				}
+				r, g, b := rgb(render.Color(t))
+				fmt.Fprintf(&line, "\x1b[30;48;2;%d;%d;%dm%s\x1b[0m", r, g, b, symbol)
+			case isCursor:
+				fmt.Fprintf(&line, "\x1b[7m%s\x1b[0m", symbol)
+			default:
+				line.WriteString(symbol)
+			}
+		}
+		out = append(out, line.String())
+	}
+	return out
+}
+
+// Details returns the lines describing the hex under the cursor.
+func (m *Model_t) Details() []string {
+	c := m.Cursor
+	lines := []string{c.GridString()}
+	tile, ok := m.Tiles.Tiles[c]
+	if !ok {
+		return append(lines, "not on the map")
+	}
+	add := func(label string, values ...string) {
+		var list []string
+		for _, v := range values {
+			if v != "" {
+				list = append(list, v)
+			}
+		}
+		if len(list) != 0 {
+			lines = append(lines, fmt.Sprintf("%-12s %s", label, strings.Join(list, ", ")))
+		}
+	}
+	add("terrain", terrain.EnumToName[tile.Terrain])
+	add("first seen", tile.FirstSeen)
+	add("last seen", tile.LastSeen)
+	add("changed", tile.Changed)
+	add("visited", tile.Visited)
+	add("scouted", tile.Scouted)
+	add("found by", string(tile.DiscoveredBy))
+	for _, s := range tile.Settlements {
+		add("settlement", s.Name)
+	}
+	for _, s := range tile.Special {
+		add("special", s.Name)
+	}
+	for _, r := range tile.Resources {
+		add("resource", r.String())
+	}
+	for _, d := range direction.Directions {
+		for _, e := range tile.Edges[d] {
+			add("edge", d.String()+" "+e.String())
+		}
+	}
+	var units []string
+	for _, id := range m.Units[c] {
+		units = append(units, string(id))
+	}
+	add("units", units...)
+	for _, e := range tile.Encounters {
+		add("encounter", fmt.Sprintf("%s %s", e.TurnId, e.UnitId))
+	}
+
+	history := m.History[c]
+	if len(history) == 0 {
+		return lines
+	}
+	lines = append(lines, "", fmt.Sprintf("observations (%d)", len(history)))
+	for _, o := range history {
+		who, what := string(o.UnitId), "passed"
+		if o.Scout != 0 {
+			who, what = fmt.Sprintf("%ss%d", o.UnitId, o.Scout), "scouted"
+		} else if o.Ended {
+			what = "ended"
+		}
+		line := fmt.Sprintf("%s %-8s %-7s %s", o.TurnId, who, what, o.Report.Terrain)
+		for _, s := range o.Report.Settlements {
+			line += " " + s.Name
+		}
+		if n := len(o.Report.Encounters); n != 0 {
+			line += fmt.Sprintf(" +%d units", n)
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return lines
+}
+
+// status returns the bottom line of the screen.
+func (m *Model_t) status() string {
+	switch m.Mode {
+	case Search:
+		return "/" + m.Input
+	case Goto:
+		return "goto: " + m.Input
+	}
+	if m.Message != "" {
+		return m.Message
+	} else if len(m.Matches) != 0 {
+		match := m.Matches[m.Match]
+		return fmt.Sprintf("match %d of %d: %s %s in %s (n, N for more)", m.Match+1, len(m.Matches), match.Kind, match.Name, match.Location.GridString())
+	}
+	return "arrows move, / search, g goto, q quit"
+}
+
+// clip truncates the text to the number of characters.
+func clip(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
+
+// rgb returns the red, green, and blue values of a "#rrggbb" color.
+func rgb(color string) (r, g, b int64) {
+	r, _ = strconv.ParseInt(color[1:3], 16, 0)
+	g, _ = strconv.ParseInt(color[3:5], 16, 0)
+	b, _ = strconv.ParseInt(color[5:7], 16, 0)
+	return r, g, b
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/turns"
+	"io"
+	"log"
+	"testing"
+)
+
+// largeInput returns turns for an alliance of units that wander over the same
+// hexes for years, so that tiles are merged with many reports. It returns the
+// number of events (steps, borders, encounters, and settlements) in the turns.
+func largeInput(years, units, steps int) ([]*parser.Turn_t, int) {
+	loop := []direction.Direction_e{direction.North, direction.NorthEast, direction.SouthEast, direction.South, direction.SouthWest, direction.NorthWest}
+	kinds := []terrain.Terrain_e{terrain.Prairie, terrain.GrassyHills, terrain.ConiferHills, terrain.Desert, terrain.Swamp, terrain.BrushFlat}
+
+	var input []*parser.Turn_t
+	var events int
+	for year := 901; year < 901+years; year++ {
+		for month := 1; month <= 12; month++ {
+			turnId := fmt.Sprintf("%04d-%02d", year, month)
+			turn := &parser.Turn_t{Id: turnId, Year: year, Month: month, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+			for u := 0; u < units; u++ {
+				unitId := parser.UnitId_t(fmt.Sprintf("%04d", 100+u))
+				moves := &parser.Moves_t{TurnId: turnId, UnitId: unitId, FromHex: "N/A"}
+				if len(input) == 0 {
+					moves.FromHex = fmt.Sprintf("QQ %02d%02d", 5+u%20, 5+u%10)
+				}
+				for n := 0; n < steps; n++ {
+					report := &parser.Report_t{UnitId: unitId, TurnId: turnId, Terrain: kinds[(u+n)%len(kinds)]}
+					for d, dir := range loop {
+						border := &parser.Border_t{Direction: dir, Terrain: kinds[(u+n+d)%len(kinds)]}
+						if d == n%len(loop) {
+							border.Edge = edges.River
+						}
+						report.Borders = append(report.Borders, border)
+					}
+					for e := 1; e <= 3; e++ {
+						report.Encounters = append(report.Encounters, &parser.Encounter_t{TurnId: turnId, UnitId: parser.UnitId_t(fmt.Sprintf("%04d", 100+(u+e)%units))})
+					}
+					if n%10 == 0 {
+						report.Settlements = append(report.Settlements, &parser.Settlement_t{TurnId: turnId, Name: fmt.Sprintf("Village %d", u)})
+					}
+					moves.Moves = append(moves.Moves, &parser.Move_t{UnitId: unitId, Advance: loop[(u+n)%len(loop)], Result: results.Succeeded, Report: report})
+					events += 1 + len(report.Borders) + len(report.Encounters) + len(report.Settlements)
+				}
+				turn.UnitMoves[unitId] = moves
+				turn.SortedMoves = append(turn.SortedMoves, moves)
+			}
+			input = append(input, turn)
+		}
+	}
+	return input, events
+}
+
+func BenchmarkWalk(b *testing.B) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	for _, tc := range []struct {
+		name                string
+		years, units, steps int
+	}{
+		{"1y-10u", 1, 10, 12},
+		{"4y-40u", 4, 40, 24},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			_, events := largeInput(tc.years, tc.units, tc.steps)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for n := 0; n < b.N; n++ {
+				// the walk updates the locations in the input, so each pass needs fresh turns
+				b.StopTimer()
+				input, _ := largeInput(tc.years, tc.units, tc.steps)
+				b.StartTimer()
+				if _, err := turns.Walk(context.Background(), input, nil, coords.World{}, "", false, false, false, false, false); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(events*b.N)/b.Elapsed().Seconds(), "events/s")
+		})
+	}
+}
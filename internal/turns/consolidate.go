@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/parser"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Consolidate merges the documents for each turn into a single turn, keeping
+// one copy of units that are in more than one document and calling out the
+// conflicts. The turns are returned sorted by year and month and linked,
+// along with the special hex names from all the documents.
+//
+// It returns an error if a unit is in more than one document for its own clan,
+// since there's no way to tell which one is right.
+func Consolidate(allTurns map[string][]*Document_t) ([]*parser.Turn_t, map[string]*parser.Special_t, error) {
+	var consolidatedTurns []*parser.Turn_t
+	consolidatedSpecialNames := map[string]*parser.Special_t{}
+	foundDuplicates := 0
+	for _, docs := range allTurns {
+		if len(docs) == 0 {
+			// we shouldn't have any empty turns, but be safe
+			continue
+		}
+		first := docs[0].Turn
+		// create a new turn to hold the consolidated unit moves for the turn
+		turn := &parser.Turn_t{
+			Id:        fmt.Sprintf("%04d-%02d", first.Year, first.Month),
+			Year:      first.Year,
+			Month:     first.Month,
+			Format:    first.Format,
+			UnitMoves: map[parser.UnitId_t]*parser.Moves_t{},
+		}
+		consolidatedTurns = append(consolidatedTurns, turn)
+
+		// copy the unit moves into this new turn, keeping one copy of units
+		// that are in more than one report and calling out the conflicts
+		unitMoves, duplicates := ResolveDuplicates(docs)
+		for _, dup := range duplicates {
+			if dup.Ambiguous {
+				foundDuplicates++
+				log.Printf("error: %s: %-6s: duplicate unit in %s and %s\n", turn.Id, dup.UnitId, dup.Kept, strings.Join(dup.Dropped, ", "))
+			} else if dup.Conflict {
+				var endings []string
+				for _, id := range append([]string{dup.Kept}, dup.Dropped...) {
+					endings = append(endings, fmt.Sprintf("%s ends in %q", id, dup.Ending[id]))
+				}
+				log.Printf("warn: %s: %-6s: reports disagree: %s: using %s\n", turn.Id, dup.UnitId, strings.Join(endings, ", "), dup.Kept)
+			}
+		}
+		for id, moves := range unitMoves {
+			turn.UnitMoves[id] = moves
+			turn.SortedMoves = append(turn.SortedMoves, moves)
+		}
+		for _, doc := range docs {
+			unitTurn := doc.Turn
+			if turn.Season == "" {
+				turn.Season, turn.Weather = unitTurn.Season, unitTurn.Weather
+			}
+			if unitTurn.SpecialNames != nil {
+				// consolidate any the special hexes
+				for id, special := range unitTurn.SpecialNames {
+					consolidatedSpecialNames[id] = special
+				}
+			}
+		}
+	}
+	if foundDuplicates != 0 {
+		return nil, nil, fmt.Errorf("%d duplicate units", foundDuplicates)
+	}
+	if len(consolidatedSpecialNames) > 0 {
+		log.Printf("consolidated %d special hex names\n", len(consolidatedSpecialNames))
+	}
+	sort.Slice(consolidatedTurns, func(i, j int) bool {
+		a, b := consolidatedTurns[i], consolidatedTurns[j]
+		if a.Year < b.Year {
+			return true
+		} else if a.Year == b.Year {
+			return a.Month < b.Month
+		}
+		return false
+	})
+	for _, turn := range consolidatedTurns {
+		log.Printf("%s: %8d units\n", turn.Id, len(turn.UnitMoves))
+		sort.Slice(turn.SortedMoves, func(i, j int) bool {
+			return turn.SortedMoves[i].UnitId < turn.SortedMoves[j].UnitId
+		})
+	}
+
+	// link prev and next turns
+	for n, turn := range consolidatedTurns {
+		if n > 0 {
+			turn.Prev = consolidatedTurns[n-1]
+		}
+		if n+1 < len(consolidatedTurns) {
+			turn.Next = consolidatedTurns[n+1]
+		}
+	}
+
+	return consolidatedTurns, consolidatedSpecialNames, nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/parser"
+	"strings"
+)
+
+// Break_t is a place where the reports don't line up from one turn to the next.
+type Break_t struct {
+	TurnId  string
+	UnitId  parser.UnitId_t // empty if the whole turn is missing
+	Missing []string        // turns with no report, for gaps
+	Ended   string          // hex the unit ended the prior turn in, for moves
+	Started string          // hex the unit started this turn in, for moves
+}
+
+func (b Break_t) String() string {
+	if b.UnitId == "" {
+		return fmt.Sprintf("%s: no reports for %s", b.TurnId, strings.Join(b.Missing, ", "))
+	} else if len(b.Missing) != 0 {
+		return fmt.Sprintf("%s: %-6s: no report for %s", b.TurnId, b.UnitId, strings.Join(b.Missing, ", "))
+	}
+	return fmt.Sprintf("%s: %-6s: started in %q but ended the prior turn in %q", b.TurnId, b.UnitId, b.Started, b.Ended)
+}
+
+// CheckContinuity checks that each unit starts a turn in the hex that it ended
+// the prior turn in. The input must be sorted by turn and linked.
+//
+// It reports:
+//   - turns that are missing between the ones that were loaded,
+//   - units that are missing from a turn and are reported again later,
+//   - units that start a turn somewhere other than where they ended the prior one.
+//
+// The ending hex of a unit that follows another unit or goes to a hex may not be
+// the one on its own report, so the leader's ending hex and the goes-to hex are
+// accepted too. Obscured hexes only have to match the digits and unknown hexes
+// always match. Units aren't compared across missing turns.
+func CheckContinuity(input []*parser.Turn_t) []Break_t {
+	var breaks []Break_t
+	for n, turn := range input {
+		if n == 0 {
+			continue
+		}
+		prev := input[n-1]
+		if missing := missingTurns(prev.Id, turn.Id); len(missing) != 0 {
+			breaks = append(breaks, Break_t{TurnId: turn.Id, Missing: missing})
+			continue
+		}
+		for _, moves := range turn.SortedMoves {
+			prior, ok := prev.UnitMoves[moves.UnitId]
+			if !ok {
+				// find the last loaded turn that has the unit
+				var missing []string
+				for i := n - 1; i >= 0 && prior == nil; i-- {
+					if prior = input[i].UnitMoves[moves.UnitId]; prior == nil {
+						missing = append([]string{input[i].Id}, missing...)
+					}
+				}
+				if prior != nil {
+					breaks = append(breaks, Break_t{TurnId: turn.Id, UnitId: moves.UnitId, Missing: missing})
+				}
+				continue
+			}
+			if !endedIn(prior, prev, moves.FromHex) {
+				breaks = append(breaks, Break_t{TurnId: turn.Id, UnitId: moves.UnitId, Ended: prior.ToHex, Started: moves.FromHex})
+			}
+		}
+	}
+	return breaks
+}
+
+// endedIn returns true if the unit could have ended the turn in the hex.
+func endedIn(moves *parser.Moves_t, turn *parser.Turn_t, hex string) bool {
+	if sameHex(moves.ToHex, hex) {
+		return true
+	} else if moves.GoesTo != "" && sameHex(moves.GoesTo, hex) {
+		return true
+	} else if leader, ok := turn.UnitMoves[moves.Follows]; ok && moves.Follows != "" {
+		return sameHex(leader.ToHex, hex)
+	}
+	return false
+}
+
+// sameHex returns true if the hexes could be the same.
+// Unknown hexes match anything and obscured hexes only compare the digits.
+func sameHex(a, b string) bool {
+	if a == "" || b == "" || a == "N/A" || b == "N/A" {
+		return true
+	} else if isObscured(a) || isObscured(b) {
+		return len(a) == 7 && len(b) == 7 && a[2:] == b[2:]
+	}
+	return a == b
+}
+
+// missingTurns returns the turns between from and to, not including either.
+func missingTurns(from, to string) []string {
+	fy, fm, err := ParseTurnId(from)
+	if err != nil {
+		return nil
+	}
+	ty, tm, err := ParseTurnId(to)
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	// t counts months from the first month of year 0, starting with the one after from
+	for t := fy*12 + fm; t < ty*12+tm-1; t++ {
+		missing = append(missing, fmt.Sprintf("%04d-%02d", t/12, t%12+1))
+	}
+	return missing
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestCheckContinuity(t *testing.T) {
+	newTurn := func(id string, moves ...*parser.Moves_t) *parser.Turn_t {
+		turn := &parser.Turn_t{Id: id, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}, SortedMoves: moves}
+		for _, m := range moves {
+			turn.UnitMoves[m.UnitId] = m
+		}
+		return turn
+	}
+	input := []*parser.Turn_t{
+		newTurn("0901-11",
+			&parser.Moves_t{UnitId: "0138", FromHex: "AB 1203", ToHex: "AB 1204"},
+			&parser.Moves_t{UnitId: "0138e1", FromHex: "AB 1203", ToHex: "AB 1203", Follows: "0138"},
+			&parser.Moves_t{UnitId: "0138e2", FromHex: "AB 1203", ToHex: "AB 1205"},
+			&parser.Moves_t{UnitId: "0138e3", FromHex: "AB 1203", ToHex: "AB 1203"},
+		),
+		newTurn("0901-12",
+			&parser.Moves_t{UnitId: "0138", FromHex: "## 1204", ToHex: "AB 1204"},   // obscured digits match
+			&parser.Moves_t{UnitId: "0138e1", FromHex: "AB 1204", ToHex: "AB 1204"}, // ended with its leader
+			&parser.Moves_t{UnitId: "0138e2", FromHex: "AB 1206", ToHex: "AB 1206"}, // moved between turns
+		),
+		newTurn("0902-01",
+			&parser.Moves_t{UnitId: "0138", FromHex: "AB 1204", ToHex: "AB 1204"},
+			&parser.Moves_t{UnitId: "0138e3", FromHex: "AB 1203", ToHex: "AB 1203"}, // missing from 0901-12
+		),
+		newTurn("0902-04", // 0902-02 and 0902-03 are missing
+			&parser.Moves_t{UnitId: "0138", FromHex: "AB 0101", ToHex: "AB 0101"},
+		),
+	}
+
+	want := []string{
+		`0901-12: 0138e2: started in "AB 1206" but ended the prior turn in "AB 1205"`,
+		`0902-01: 0138e3: no report for 0901-12`,
+		`0902-04: no reports for 0902-02, 0902-03`,
+	}
+	got := turns.CheckContinuity(input)
+	if len(got) != len(want) {
+		t.Fatalf("breaks: want %d, got %d: %v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Errorf("break %d: want %q, got %q", i, want[i], got[i].String())
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"sort"
+)
+
+// Document_t is a parsed turn report and the clan that it belongs to.
+// A turn can have several documents, for example the clan's own report and
+// the reports that allies shared.
+type Document_t struct {
+	Id     string          // id of the report file
+	ClanId parser.UnitId_t // clan that the report belongs to
+	Turn   *parser.Turn_t
+}
+
+// Duplicate_t is a unit that is in more than one document for the same turn.
+type Duplicate_t struct {
+	UnitId  parser.UnitId_t
+	Kept    string   // id of the document whose moves are used
+	Dropped []string // ids of the other documents
+
+	// Conflict is set if the documents disagree on where the unit ended the turn.
+	// Ending holds the ending hex from each document, keyed by document id.
+	Conflict bool
+	Ending   map[string]string
+
+	// Ambiguous is set if more than one of the documents belongs to the unit's
+	// clan, so there's no way to tell which one is right.
+	Ambiguous bool
+}
+
+// ResolveDuplicates returns the moves to use for every unit in the documents,
+// which must all be for the same turn. When a unit is in more than one document,
+// the moves from the document that belongs to the unit's clan are used.
+// If no document belongs to the clan, the moves from the first document are used.
+// The duplicates are returned sorted by unit id so that the caller can warn about them.
+func ResolveDuplicates(docs []*Document_t) (map[parser.UnitId_t]*parser.Moves_t, []*Duplicate_t) {
+	// collect the documents that have each unit, in the order they were loaded
+	found := map[parser.UnitId_t][]*Document_t{}
+	for _, doc := range docs {
+		for id := range doc.Turn.UnitMoves {
+			found[id] = append(found[id], doc)
+		}
+	}
+
+	moves := map[parser.UnitId_t]*parser.Moves_t{}
+	var duplicates []*Duplicate_t
+	for id, list := range found {
+		kept, owners := list[0], 0
+		for _, doc := range list {
+			if id.InClan(doc.ClanId) {
+				if owners == 0 {
+					kept = doc
+				}
+				owners++
+			}
+		}
+		moves[id] = kept.Turn.UnitMoves[id]
+		if len(list) == 1 {
+			continue
+		}
+
+		dup := &Duplicate_t{UnitId: id, Kept: kept.Id, Ending: map[string]string{}, Ambiguous: owners > 1}
+		for _, doc := range list {
+			ending := doc.Turn.UnitMoves[id].ToHex
+			dup.Ending[doc.Id] = ending
+			if ending != moves[id].ToHex {
+				dup.Conflict = true
+			}
+			if doc != kept {
+				dup.Dropped = append(dup.Dropped, doc.Id)
+			}
+		}
+		duplicates = append(duplicates, dup)
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].UnitId < duplicates[j].UnitId
+	})
+	return moves, duplicates
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestResolveDuplicates(t *testing.T) {
+	doc := func(id string, clanId parser.UnitId_t, endings map[parser.UnitId_t]string) *turns.Document_t {
+		d := &turns.Document_t{Id: id, ClanId: clanId, Turn: &parser.Turn_t{UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}}
+		for unitId, toHex := range endings {
+			d.Turn.UnitMoves[unitId] = &parser.Moves_t{UnitId: unitId, ToHex: toHex}
+		}
+		return d
+	}
+	// 0138's own report and an ally's report that both have 0138e1
+	own := doc("0902-02.0138", "0138", map[parser.UnitId_t]string{"0138": "AB 1203", "0138e1": "AB 1204"})
+	ally := doc("0902-02.0250", "0250", map[parser.UnitId_t]string{"0250": "AB 1203", "0138e1": "AB 1205", "0999": "AB 0101"})
+	other := doc("0902-02.0375", "0375", map[parser.UnitId_t]string{"0999": "AB 0101"})
+
+	for _, tc := range []struct {
+		id        string
+		docs      []*turns.Document_t
+		unitId    parser.UnitId_t
+		wantHex   string
+		wantKept  string
+		conflict  bool
+		ambiguous bool
+	}{
+		{"owner first", []*turns.Document_t{own, ally}, "0138e1", "AB 1204", "0902-02.0138", true, false},
+		{"owner last", []*turns.Document_t{ally, own}, "0138e1", "AB 1204", "0902-02.0138", true, false},
+		{"no owner", []*turns.Document_t{other, ally}, "0999", "AB 0101", "0902-02.0375", false, false},
+		{"two owners", []*turns.Document_t{own, own}, "0138", "AB 1203", "0902-02.0138", false, true},
+	} {
+		moves, duplicates := turns.ResolveDuplicates(tc.docs)
+		if got := moves[tc.unitId].ToHex; got != tc.wantHex {
+			t.Errorf("%s: hex: want %q, got %q", tc.id, tc.wantHex, got)
+		}
+		var dup *turns.Duplicate_t
+		for _, d := range duplicates {
+			if d.UnitId == tc.unitId {
+				dup = d
+			}
+		}
+		if dup == nil {
+			t.Errorf("%s: want duplicate %s, got none", tc.id, tc.unitId)
+			continue
+		}
+		if dup.Kept != tc.wantKept {
+			t.Errorf("%s: kept: want %q, got %q", tc.id, tc.wantKept, dup.Kept)
+		}
+		if dup.Conflict != tc.conflict {
+			t.Errorf("%s: conflict: want %v, got %v", tc.id, tc.conflict, dup.Conflict)
+		}
+		if dup.Ambiguous != tc.ambiguous {
+			t.Errorf("%s: ambiguous: want %v, got %v", tc.id, tc.ambiguous, dup.Ambiguous)
+		}
+	}
+
+	// units in only one document are not duplicates
+	moves, duplicates := turns.ResolveDuplicates([]*turns.Document_t{own, ally})
+	if len(moves) != 4 || len(duplicates) != 1 {
+		t.Errorf("moves: want 4 units and 1 duplicate, got %d and %d", len(moves), len(duplicates))
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/parser"
+	"strings"
+)
+
+// ResolveFollows orders the moves in the turn so that every unit that follows
+// another unit is walked after its leader. It handles chains of followers
+// (A follows B follows C) and returns an error if the follows form a cycle.
+//
+// Leaders that aren't in the turn (for example, a unit from another clan)
+// are left alone; the walk will use the follower's reported ending hex.
+func ResolveFollows(turn *parser.Turn_t) error {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[parser.UnitId_t]int{}
+	var sorted []*parser.Moves_t
+
+	var visit func(moves *parser.Moves_t, path []string) error
+	visit = func(moves *parser.Moves_t, path []string) error {
+		switch state[moves.UnitId] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%s: follows cycle: %s -> %s", turn.Id, strings.Join(path, " -> "), moves.UnitId)
+		}
+		state[moves.UnitId] = visiting
+		if leader, ok := turn.UnitMoves[moves.Follows]; ok && moves.Follows != "" {
+			if err := visit(leader, append(path, string(moves.UnitId))); err != nil {
+				return err
+			}
+		}
+		state[moves.UnitId] = visited
+		sorted = append(sorted, moves)
+		return nil
+	}
+
+	// visiting in the current order keeps the goes-to and normal moves first
+	for _, moves := range turn.SortedMoves {
+		if err := visit(moves, nil); err != nil {
+			return err
+		}
+	}
+	turn.SortedMoves = sorted
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestResolveFollows(t *testing.T) {
+	newTurn := func(follows map[parser.UnitId_t]parser.UnitId_t, ids ...parser.UnitId_t) *parser.Turn_t {
+		turn := &parser.Turn_t{Id: "0902-02", UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+		for _, id := range ids {
+			moves := &parser.Moves_t{UnitId: id, Follows: follows[id]}
+			turn.UnitMoves[id] = moves
+			turn.SortedMoves = append(turn.SortedMoves, moves)
+		}
+		turn.TopoSortMoves()
+		return turn
+	}
+
+	// 0138e1 follows 0138e2 follows 0138; 0138e3 follows a unit outside the clan
+	turn := newTurn(map[parser.UnitId_t]parser.UnitId_t{"0138e1": "0138e2", "0138e2": "0138", "0138e3": "1590"}, "0138e1", "0138e2", "0138e3", "0138")
+	if err := turns.ResolveFollows(turn); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	order := map[parser.UnitId_t]int{}
+	for n, moves := range turn.SortedMoves {
+		order[moves.UnitId] = n
+	}
+	if len(order) != 4 {
+		t.Errorf("resolve: want 4 units, got %d", len(order))
+	}
+	if !(order["0138"] < order["0138e2"] && order["0138e2"] < order["0138e1"]) {
+		t.Errorf("resolve: leaders must be walked first: got %v", order)
+	}
+
+	// 0138e1 and 0138e2 follow each other
+	turn = newTurn(map[parser.UnitId_t]parser.UnitId_t{"0138e1": "0138e2", "0138e2": "0138e1"}, "0138", "0138e1", "0138e2")
+	if err := turns.ResolveFollows(turn); err == nil {
+		t.Errorf("cycle: want error, got nil")
+	}
+}
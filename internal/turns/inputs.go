@@ -16,6 +16,12 @@ var (
 	rxTurnReportFile = regexp.MustCompile(`^(\d{3,4})-(\d{2})\.(0\d{3})\.report\.txt$`)
 )
 
+// IsReportFileName returns true if CollectInputs reads the file.
+// Documents and reports for elements must be converted to a clan's text report first.
+func IsReportFileName(name string) bool {
+	return rxTurnReportFile.MatchString(name)
+}
+
 // CollectInputs returns a slice containing all the turn reports in the path
 // if solo is true, then only the turn reports for the soloClan are returned.
 func CollectInputs(path string, maxYear, maxMonth int, solo bool, soloClan string) (inputs []*TurnReportFile_t, err error) {
@@ -34,7 +40,7 @@ func CollectInputs(path string, maxYear, maxMonth int, solo bool, soloClan strin
 
 	entries, err := os.ReadDir(path)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	for _, entry := range entries {
 		if !entry.IsDir() {
@@ -52,7 +58,7 @@ func CollectInputs(path string, maxYear, maxMonth int, solo bool, soloClan strin
 				continue
 			}
 			if year < 899 || year > 9999 || month < 1 || month > 12 {
-				log.Printf("warn: %q: invalid turn year or month\n")
+				log.Printf("warn: %q: invalid turn year or month\n", fileName)
 				continue
 			}
 			pastCutoff := false
@@ -86,6 +92,7 @@ func CollectInputs(path string, maxYear, maxMonth int, solo bool, soloClan strin
 type TurnReportFile_t struct {
 	Id   string // the id of the report file, taken from the file name.
 	Path string // the path to the report file
+	Data []byte // the contents of the report, set only if it was not loaded from a file
 	Turn struct {
 		Id     string // the id of the turn, taken from the file name.
 		Year   int    // the year of the turn
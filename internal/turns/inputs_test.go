@@ -0,0 +1,27 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestIsReportFileName(t *testing.T) {
+	for _, tc := range []struct {
+		id   int
+		name string
+		want bool
+	}{
+		{1, "0902-03.0138.report.txt", true},
+		{2, "902-03.0138.report.txt", true},
+		{3, "0902-03.0138e1.report.txt", false},
+		{4, "0902-03.0138.report.docx", false},
+		{5, "0902-03.1138.report.txt", false},
+		{6, "0902-03.0138.scrubbed.txt", false},
+	} {
+		if got := turns.IsReportFileName(tc.name); got != tc.want {
+			t.Errorf("%d: %q: got %v, want %v", tc.id, tc.name, got, tc.want)
+		}
+	}
+}
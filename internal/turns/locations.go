@@ -0,0 +1,215 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/parser"
+	"log"
+	"strings"
+)
+
+// LocationOptions_t controls how DeriveLocations treats hexes it can't resolve.
+type LocationOptions_t struct {
+	// Strict returns an error if any "N/A" or obscured hex is left after all
+	// the passes. Otherwise, they are returned as warnings and left for the walk.
+	Strict bool
+	// OriginGrid, if set, is the grid substituted for obscured hexes that
+	// couldn't be resolved, for example "RR".
+	OriginGrid string
+}
+
+// Derivation sources, from strongest to weakest.
+const (
+	FromReport     = "report"                     // the report gave the hex
+	FromLink       = "next turn's previous hex"   // the next turn's report disagreed or filled in the grid
+	FromEvidence   = "co-located units and moves" // ResolveObscured found a single grid that fits
+	FromOriginGrid = "origin grid"                // the origin grid was substituted
+	FromNothing    = "unresolved"                 // the hex is still obscured or unknown
+)
+
+// Derivation_t explains where a unit's ending hex for a turn came from.
+type Derivation_t struct {
+	TurnId   string
+	UnitId   parser.UnitId_t
+	Reported string // current hex from the report
+	Hex      string // current hex after the passes
+	Source   string // one of the From constants
+}
+
+func (d Derivation_t) String() string {
+	if d.Reported == d.Hex {
+		return fmt.Sprintf("%s: %-6s: %s: %s", d.TurnId, d.UnitId, d.Hex, d.Source)
+	}
+	return fmt.Sprintf("%s: %-6s: %s: %s (reported %q)", d.TurnId, d.UnitId, d.Hex, d.Source, d.Reported)
+}
+
+// Locations_t is the result of DeriveLocations.
+type Locations_t struct {
+	Derivations []Derivation_t // for every unit in every turn, in turn and unit order
+	Breaks      []Break_t      // units that didn't start a turn where they ended the prior one
+	Unresolved  []Unresolved_t // "N/A" and obscured hexes that are left
+}
+
+// DeriveLocations fills in the previous and current hex for every unit in every
+// turn, before the walk. The input must be sorted by turn and linked.
+//
+// The passes are:
+//   - fill in "N/A" previous hexes (ResolveUnknown),
+//   - check that the links between turns line up (CheckContinuity),
+//   - copy each turn's current hex to the next turn's previous hex, and
+//     real previous hexes back to the prior turn's current hex,
+//   - resolve the rest of the obscured hexes from the evidence (ResolveObscured),
+//   - substitute the origin grid for whatever is still obscured, if it is set.
+//
+// It returns an error only in strict mode, when hexes are left unresolved.
+func DeriveLocations(input []*parser.Turn_t, opts LocationOptions_t) (*Locations_t, error) {
+	result := &Locations_t{}
+
+	// remember the reported hexes so that we can explain how they changed
+	type key_t struct {
+		turnId string
+		unitId parser.UnitId_t
+	}
+	reported, linked := map[key_t]string{}, map[key_t]string{}
+	for _, turn := range input {
+		for _, moves := range turn.SortedMoves {
+			reported[key_t{turn.Id, moves.UnitId}] = moves.ToHex
+		}
+	}
+
+	// fill in N/A values in locations from earlier turns, parent units, and the unit's own moves
+	resolvedUnknown, unresolvedUnknown := ResolveUnknown(input)
+	log.Printf("resolved %7d 'N/A' locations\n", resolvedUnknown)
+	for _, u := range unresolvedUnknown {
+		log.Printf("warn: %s: %-6s: location %q: unable to reconstruct starting hex\n", u.TurnId, u.UnitId, u.Hex)
+	}
+
+	// check that each unit starts the turn where it ended the prior one,
+	// before a bad link puts the unit's tiles in the wrong place
+	result.Breaks = CheckContinuity(input)
+	for _, b := range result.Breaks {
+		log.Printf("warn: continuity: %s\n", b)
+	}
+
+	// sanity check on the current and prior locations.
+	changedLinks, staticLinks := 0, 0
+	for _, turn := range input {
+		if turn.Next == nil { // nothing to update
+			continue
+		}
+		for _, unitMoves := range turn.UnitMoves {
+			nextUnitMoves := turn.Next.UnitMoves[unitMoves.UnitId]
+			if nextUnitMoves == nil {
+				continue
+			}
+			if unitMoves.ToHex[2:] != nextUnitMoves.FromHex[2:] {
+				// CheckContinuity has already reported the unit
+				changedLinks++
+			} else {
+				staticLinks++
+			}
+			nextUnitMoves.FromHex = unitMoves.ToHex
+		}
+	}
+	log.Printf("links: %d same, %d changed\n", staticLinks, changedLinks)
+	if changedLinks != 0 {
+		// this can happen when an element is destroyed and another created with the same name
+		// during a single turn.
+		log.Printf("warning: the previous and current hexes don't align in some reports\n")
+		log.Printf("warning: if you didn't destroy a unit and create another with the\n")
+		log.Printf("warning: same name in a single turn, then there may be a bug here.\n")
+	}
+
+	// proactively patch some of the obscured locations.
+	// turn reports initially gave obscured locations for from and to hexes.
+	// around 0902-02, the current location stopped being obscured,
+	// but the previous location is still obscured.
+	// NB: links between the locations must be validated before patching them!
+	updatedCurrentLinks, updatedPreviousLinks := 0, 0
+	for _, turn := range input {
+		for _, unitMoves := range turn.UnitMoves {
+			var prevTurnMoves *parser.Moves_t
+			if turn.Prev != nil {
+				prevTurnMoves = turn.Prev.UnitMoves[unitMoves.UnitId]
+			}
+			var nextTurnMoves *parser.Moves_t
+			if turn.Next != nil {
+				nextTurnMoves = turn.Next.UnitMoves[unitMoves.UnitId]
+			}
+
+			// link prior.ToHex and this.FromHex if this.FromHex is not obscured
+			if !strings.HasPrefix(unitMoves.FromHex, "##") && prevTurnMoves != nil {
+				if prevTurnMoves.ToHex != unitMoves.FromHex {
+					updatedPreviousLinks++
+					prevTurnMoves.ToHex = unitMoves.FromHex
+				}
+			}
+
+			// link this.ToHex and next.FromHex if this.ToHex is not obscured
+			if !strings.HasPrefix(unitMoves.ToHex, "##") && nextTurnMoves != nil {
+				if unitMoves.ToHex != nextTurnMoves.FromHex {
+					updatedCurrentLinks++
+					nextTurnMoves.FromHex = unitMoves.ToHex
+				}
+			}
+		}
+	}
+	log.Printf("updated %8d obscured 'Previous Hex' locations\n", updatedPreviousLinks)
+	log.Printf("updated %8d obscured 'Current Hex'  locations\n", updatedCurrentLinks)
+	for _, turn := range input {
+		for _, moves := range turn.SortedMoves {
+			linked[key_t{turn.Id, moves.UnitId}] = moves.ToHex
+		}
+	}
+
+	// use the links, co-located units, and movement to find the rest of the obscured locations
+	resolvedObscured, unresolvedObscured := ResolveObscured(input)
+	log.Printf("resolved %7d obscured locations\n", resolvedObscured)
+	for _, u := range unresolvedObscured {
+		log.Printf("warn: obscured: %s\n", u)
+	}
+
+	// substitute the origin grid for the obscured hexes that are left
+	substituted := map[key_t]bool{}
+	if opts.OriginGrid != "" {
+		for _, turn := range input {
+			for _, moves := range turn.SortedMoves {
+				if isObscured(moves.FromHex) {
+					moves.FromHex = opts.OriginGrid + moves.FromHex[2:]
+				}
+				if isObscured(moves.ToHex) {
+					moves.ToHex = opts.OriginGrid + moves.ToHex[2:]
+					substituted[key_t{turn.Id, moves.UnitId}] = true
+				}
+			}
+		}
+		unresolvedObscured = nil
+	}
+
+	for _, turn := range input {
+		for _, moves := range turn.SortedMoves {
+			k := key_t{turn.Id, moves.UnitId}
+			d := Derivation_t{TurnId: turn.Id, UnitId: moves.UnitId, Reported: reported[k], Hex: moves.ToHex}
+			switch {
+			case substituted[k]:
+				d.Source = FromOriginGrid
+			case isObscured(d.Hex) || d.Hex == "N/A" || d.Hex == "":
+				d.Source = FromNothing
+			case linked[k] != d.Hex:
+				d.Source = FromEvidence
+			case reported[k] != d.Hex:
+				d.Source = FromLink
+			default:
+				d.Source = FromReport
+			}
+			result.Derivations = append(result.Derivations, d)
+		}
+	}
+
+	result.Unresolved = append(unresolvedUnknown, unresolvedObscured...)
+	if opts.Strict && len(result.Unresolved) != 0 {
+		return result, fmt.Errorf("locations: %d hexes could not be resolved, starting with %s", len(result.Unresolved), result.Unresolved[0])
+	}
+	return result, nil
+}
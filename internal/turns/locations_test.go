@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/turns"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestDeriveLocations(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	newInput := func() []*parser.Turn_t {
+		newTurn := func(id string, moves ...*parser.Moves_t) *parser.Turn_t {
+			turn := &parser.Turn_t{Id: id, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+			for _, m := range moves {
+				m.TurnId = id
+				turn.UnitMoves[m.UnitId] = m
+				turn.SortedMoves = append(turn.SortedMoves, m)
+			}
+			return turn
+		}
+		input := []*parser.Turn_t{
+			newTurn("0901-12",
+				&parser.Moves_t{UnitId: "0138", FromHex: "QQ 1010", ToHex: "## 1008", Moves: []*parser.Move_t{{}, {}}},
+				&parser.Moves_t{UnitId: "0200", FromHex: "## 0101", ToHex: "## 0101"},
+			),
+			newTurn("0902-01",
+				&parser.Moves_t{UnitId: "0138", FromHex: "QQ 1008", ToHex: "QQ 1009"},
+			),
+		}
+		input[0].Next, input[1].Prev = input[1], input[0]
+		return input
+	}
+
+	for _, tc := range []struct {
+		id     int
+		opts   turns.LocationOptions_t
+		want   map[string]string // source by turn and unit
+		hex    string            // current hex of the stranger after the passes
+		strict bool              // true if an error is expected
+	}{
+		{id: 1, opts: turns.LocationOptions_t{},
+			want: map[string]string{"0901-12 0138": turns.FromEvidence, "0901-12 0200": turns.FromNothing, "0902-01 0138": turns.FromReport},
+			hex:  "## 0101"},
+		{id: 2, opts: turns.LocationOptions_t{OriginGrid: "RR"},
+			want: map[string]string{"0901-12 0138": turns.FromEvidence, "0901-12 0200": turns.FromOriginGrid, "0902-01 0138": turns.FromReport},
+			hex:  "RR 0101"},
+		{id: 3, opts: turns.LocationOptions_t{Strict: true}, strict: true},
+		{id: 4, opts: turns.LocationOptions_t{Strict: true, OriginGrid: "RR"},
+			want: map[string]string{"0901-12 0200": turns.FromOriginGrid},
+			hex:  "RR 0101"},
+	} {
+		input := newInput()
+		got, err := turns.DeriveLocations(input, tc.opts)
+		if tc.strict {
+			if err == nil {
+				t.Errorf("%d: want error, got nil", tc.id)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%d: want nil, got %v", tc.id, err)
+			continue
+		}
+		for _, d := range got.Derivations {
+			if want, ok := tc.want[d.TurnId+" "+string(d.UnitId)]; ok && d.Source != want {
+				t.Errorf("%d: %s: %s: source: want %q, got %q", tc.id, d.TurnId, d.UnitId, want, d.Source)
+			}
+		}
+		if hex := input[0].UnitMoves["0200"].ToHex; hex != tc.hex {
+			t.Errorf("%d: 0200: hex: want %q, got %q", tc.id, tc.hex, hex)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"sort"
+	"strings"
+)
+
+// Unresolved_t is an obscured hex that ResolveObscured could not disambiguate.
+type Unresolved_t struct {
+	TurnId     string
+	UnitId     parser.UnitId_t
+	Field      string   // "Previous Hex" or "Current Hex"
+	Hex        string   // the obscured hex, e.g. "## 1108"
+	Candidates []string // grids that fit the evidence, empty if there was none
+}
+
+func (u Unresolved_t) String() string {
+	if len(u.Candidates) == 0 {
+		return fmt.Sprintf("%s: %-6s: %s %q: no evidence", u.TurnId, u.UnitId, u.Field, u.Hex)
+	}
+	return fmt.Sprintf("%s: %-6s: %s %q: ambiguous: %s", u.TurnId, u.UnitId, u.Field, u.Hex, strings.Join(u.Candidates, ", "))
+}
+
+// ResolveObscured replaces obscured ("##") previous and current hexes with
+// the real grid. The input must be sorted by turn.
+//
+// It uses, in order of strength:
+//   - the link between one turn's current hex and the next turn's previous hex,
+//   - the hex of the unit's parent in the same turn,
+//   - other units known to be in a hex with the same digits that turn,
+//   - movement continuity: the unit can't end a turn farther from where
+//     it started than the number of steps it took.
+//
+// The rules are applied until nothing changes. It returns the number of
+// hexes that were updated and the hexes that could not be disambiguated.
+func ResolveObscured(input []*parser.Turn_t) (int, []Unresolved_t) {
+	resolved := 0
+	for changed := true; changed; {
+		changed = false
+		for n, turn := range input {
+			var prev, next *parser.Turn_t
+			if n > 0 {
+				prev = input[n-1]
+			}
+			if n+1 < len(input) {
+				next = input[n+1]
+			}
+			for _, moves := range turn.SortedMoves {
+				if isObscured(moves.FromHex) {
+					if hex, ok := resolvePrevious(moves, turn, prev); ok {
+						moves.FromHex, changed = hex, true
+						resolved++
+					}
+				}
+				if isObscured(moves.ToHex) {
+					if hex, ok := resolveCurrent(moves, turn, next); ok {
+						moves.ToHex, changed = hex, true
+						resolved++
+					}
+				}
+			}
+		}
+	}
+
+	var unresolved []Unresolved_t
+	for n, turn := range input {
+		var prev, next *parser.Turn_t
+		if n > 0 {
+			prev = input[n-1]
+		}
+		if n+1 < len(input) {
+			next = input[n+1]
+		}
+		for _, moves := range turn.SortedMoves {
+			if isObscured(moves.FromHex) {
+				unresolved = append(unresolved, Unresolved_t{
+					TurnId:     turn.Id,
+					UnitId:     moves.UnitId,
+					Field:      "Previous Hex",
+					Hex:        moves.FromHex,
+					Candidates: previousCandidates(moves, turn, prev),
+				})
+			}
+			if isObscured(moves.ToHex) {
+				unresolved = append(unresolved, Unresolved_t{
+					TurnId:     turn.Id,
+					UnitId:     moves.UnitId,
+					Field:      "Current Hex",
+					Hex:        moves.ToHex,
+					Candidates: currentCandidates(moves, turn, next),
+				})
+			}
+		}
+	}
+
+	return resolved, unresolved
+}
+
+func isObscured(hex string) bool {
+	return strings.HasPrefix(hex, "##")
+}
+
+// resolvePrevious tries to find the grid for the unit's obscured previous hex.
+func resolvePrevious(moves *parser.Moves_t, turn, prev *parser.Turn_t) (string, bool) {
+	// the current hex from the prior turn is the strongest link
+	if prev != nil {
+		if prior, ok := prev.UnitMoves[moves.UnitId]; ok && sameDigits(prior.ToHex, moves.FromHex) {
+			return prior.ToHex, true
+		}
+	}
+	// new units start in their parent's hex, usually before the parent moves
+	if parent, ok := turn.UnitMoves[moves.UnitId.Parent()]; ok && parent.UnitId != moves.UnitId {
+		if sameDigits(parent.FromHex, moves.FromHex) {
+			return parent.FromHex, true
+		} else if sameDigits(parent.ToHex, moves.FromHex) {
+			return parent.ToHex, true
+		}
+	}
+	if candidates := previousCandidates(moves, turn, prev); len(candidates) == 1 {
+		return candidates[0] + moves.FromHex[2:], true
+	}
+	return moves.FromHex, false
+}
+
+// resolveCurrent tries to find the grid for the unit's obscured current hex.
+func resolveCurrent(moves *parser.Moves_t, turn, next *parser.Turn_t) (string, bool) {
+	// the previous hex from the next turn is the strongest link
+	if next != nil {
+		if later, ok := next.UnitMoves[moves.UnitId]; ok && sameDigits(later.FromHex, moves.ToHex) {
+			return later.FromHex, true
+		}
+	}
+	// followers end the turn with their leader
+	if moves.Follows != "" {
+		if leader, ok := turn.UnitMoves[moves.Follows]; ok && sameDigits(leader.ToHex, moves.ToHex) {
+			return leader.ToHex, true
+		}
+	}
+	if candidates := currentCandidates(moves, turn, next); len(candidates) == 1 {
+		return candidates[0] + moves.ToHex[2:], true
+	}
+	return moves.ToHex, false
+}
+
+// previousCandidates returns the grids that could hold the unit's previous hex.
+func previousCandidates(moves *parser.Moves_t, turn, prev *parser.Turn_t) []string {
+	var colocated []string
+	for _, other := range turn.SortedMoves {
+		if other != moves && sameDigits(other.FromHex, moves.FromHex) {
+			colocated = append(colocated, other.FromHex[:2])
+		}
+	}
+	if prev != nil {
+		for _, other := range prev.SortedMoves {
+			if sameDigits(other.ToHex, moves.FromHex) {
+				colocated = append(colocated, other.ToHex[:2])
+			}
+		}
+	}
+	var reachable []string
+	if moves.GoesTo == "" && moves.Follows == "" && !isObscured(moves.ToHex) {
+		reachable = gridsWithin(moves.ToHex, moves.FromHex[2:], len(moves.Moves))
+	}
+	return combineCandidates(colocated, reachable)
+}
+
+// currentCandidates returns the grids that could hold the unit's current hex.
+func currentCandidates(moves *parser.Moves_t, turn, next *parser.Turn_t) []string {
+	var colocated []string
+	for _, other := range turn.SortedMoves {
+		if other != moves && sameDigits(other.ToHex, moves.ToHex) {
+			colocated = append(colocated, other.ToHex[:2])
+		}
+	}
+	if next != nil {
+		for _, other := range next.SortedMoves {
+			if sameDigits(other.FromHex, moves.ToHex) {
+				colocated = append(colocated, other.FromHex[:2])
+			}
+		}
+	}
+	var reachable []string
+	if moves.GoesTo == "" && moves.Follows == "" && !isObscured(moves.FromHex) {
+		reachable = gridsWithin(moves.FromHex, moves.ToHex[2:], len(moves.Moves))
+	}
+	return combineCandidates(colocated, reachable)
+}
+
+// sameDigits returns true if known is a real hex with the same digits as the obscured hex.
+func sameDigits(known, obscured string) bool {
+	if len(known) != 7 || len(obscured) != 7 || isObscured(known) || known == "N/A" {
+		return false
+	}
+	return known[2:] == obscured[2:]
+}
+
+// gridsWithin returns the grids, from the known hex's grid and its neighbors,
+// where the digits are no more than maxSteps hexes from the known hex.
+func gridsWithin(known, digits string, maxSteps int) []string {
+	from, err := coords.HexToMap(known)
+	if err != nil {
+		return nil
+	}
+	var grids []string
+	for dr := -1; dr <= 1; dr++ {
+		for dc := -1; dc <= 1; dc++ {
+			row, col := known[0]+byte(dr), known[1]+byte(dc)
+			if row < 'A' || row > 'Z' || col < 'A' || col > 'Z' {
+				continue
+			}
+			grid := string([]byte{row, col})
+			to, err := coords.HexToMap(grid + digits)
+			if err != nil {
+				continue
+			}
+			if from.Distance(to) <= maxSteps {
+				grids = append(grids, grid)
+			}
+		}
+	}
+	return grids
+}
+
+// combineCandidates intersects the two sets of grids. If only one set
+// has any evidence, it is used as is. The result is sorted and unique.
+func combineCandidates(a, b []string) []string {
+	set := map[string]bool{}
+	if len(a) == 0 || len(b) == 0 {
+		for _, grid := range a {
+			set[grid] = true
+		}
+		for _, grid := range b {
+			set[grid] = true
+		}
+	} else {
+		inB := map[string]bool{}
+		for _, grid := range b {
+			inB[grid] = true
+		}
+		for _, grid := range a {
+			if inB[grid] {
+				set[grid] = true
+			}
+		}
+	}
+	var grids []string
+	for grid := range set {
+		grids = append(grids, grid)
+	}
+	sort.Strings(grids)
+	return grids
+}
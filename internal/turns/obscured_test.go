@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestResolveObscured(t *testing.T) {
+	newTurn := func(id string, moves ...*parser.Moves_t) *parser.Turn_t {
+		turn := &parser.Turn_t{Id: id, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+		for _, m := range moves {
+			m.TurnId = id
+			turn.UnitMoves[m.UnitId] = m
+			turn.SortedMoves = append(turn.SortedMoves, m)
+		}
+		return turn
+	}
+	steps := func(n int) []*parser.Move_t {
+		var moves []*parser.Move_t
+		for i := 0; i < n; i++ {
+			moves = append(moves, &parser.Move_t{})
+		}
+		return moves
+	}
+
+	tribe1 := &parser.Moves_t{UnitId: "0138", FromHex: "## 1010", ToHex: "## 1008", Moves: steps(2)}
+	courier := &parser.Moves_t{UnitId: "0138c1", FromHex: "## 1008", ToHex: "## 1510", Moves: steps(1)}
+	stranger := &parser.Moves_t{UnitId: "0200", FromHex: "## 0101", ToHex: "## 0101"}
+	tribe2 := &parser.Moves_t{UnitId: "0138", FromHex: "## 1008", ToHex: "QQ 1008"}
+	input := []*parser.Turn_t{
+		newTurn("0901-12", tribe1, courier, stranger),
+		newTurn("0902-01", tribe2),
+	}
+
+	resolved, unresolved := turns.ResolveObscured(input)
+	for _, tc := range []struct {
+		id   int
+		got  string
+		want string
+	}{
+		{1, tribe2.FromHex, "QQ 1008"}, // co-located with its own current hex
+		{2, tribe1.ToHex, "QQ 1008"},   // linked to the next turn
+		{3, tribe1.FromHex, "QQ 1010"}, // only grid within two steps
+		{4, courier.FromHex, "QQ 1008"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%d: got %q, want %q", tc.id, tc.got, tc.want)
+		}
+	}
+	if resolved != 4 {
+		t.Errorf("resolved: got %d, want 4", resolved)
+	}
+
+	// the courier can't reach 1510 in one step, and nothing is known about 0200
+	want := map[string]bool{"0138c1 Current Hex": true, "0200 Previous Hex": true, "0200 Current Hex": true}
+	if len(unresolved) != len(want) {
+		t.Errorf("unresolved: got %d, want %d: %v", len(unresolved), len(want), unresolved)
+	}
+	for _, u := range unresolved {
+		if key := string(u.UnitId) + " " + u.Field; !want[key] {
+			t.Errorf("unresolved: unexpected %s", u)
+		}
+	}
+}
@@ -58,6 +58,10 @@ func Step(turnId string, move *parser.Move_t, location, leader coords.Map, world
 		if to, err = stepGoto(turnId, move, from, move.GoesTo, worldMap, scouting, debug); err != nil {
 			return location, err
 		}
+	} else if move.Result == results.Vanished {
+		if to, err = stepVanished(turnId, move, from, worldMap, scouting, debug); err != nil {
+			return location, err
+		}
 	} else if move.Result == results.Failed {
 		if to, err = stepFailed(turnId, move, from, worldMap, scouting, debug); err != nil {
 			return location, err
@@ -99,6 +103,12 @@ func stepFailed(turnId string, move *parser.Move_t, from *tiles.Tile_t, worldMap
 	return from, nil
 }
 
+// stepVanished processes a single step from a unit's move.
+// It returns the last known location of the unit, which is where it was when it vanished.
+func stepVanished(turnId string, move *parser.Move_t, from *tiles.Tile_t, worldMap *tiles.Map_t, scouting, debug bool) (*tiles.Tile_t, error) {
+	return from, nil
+}
+
 // stepFollows processes a single step from a unit's move.
 // It returns the final location of the unit.
 func stepFollows(turnId string, move *parser.Move_t, from *tiles.Tile_t, leader coords.Map, worldMap *tiles.Map_t, scouting, debug bool) (*tiles.Tile_t, error) {
@@ -0,0 +1,86 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+// a failed step must still paint the terrain that blocked it into the neighboring tile.
+func TestStepFailedBorders(t *testing.T) {
+	for _, tc := range []struct {
+		id      int
+		reason  results.Result_e
+		advance direction.Direction_e
+		terrain terrain.Terrain_e
+	}{
+		{id: 1, reason: results.Prohibited, advance: direction.North, terrain: terrain.Ocean},
+		{id: 2, reason: results.Prohibited, advance: direction.SouthEast, terrain: terrain.UnknownMountain},
+		{id: 3, reason: results.ExhaustedMovementPoints, advance: direction.NorthWest, terrain: terrain.Lake},
+	} {
+		location, err := coords.HexToMap("QQ 1208")
+		if err != nil {
+			t.Fatalf("%d: location: %v", tc.id, err)
+		}
+		worldMap := tiles.NewMap()
+		move := &parser.Move_t{
+			UnitId:  "0138",
+			Advance: tc.advance,
+			Result:  results.Failed,
+			Reason:  tc.reason,
+			Report: &parser.Report_t{
+				UnitId:  "0138",
+				Borders: []*parser.Border_t{{Direction: tc.advance, Terrain: tc.terrain}},
+			},
+		}
+		got, err := turns.Step("0902-02", move, location, coords.Map{}, worldMap, nil, false, false, false, false)
+		if err != nil {
+			t.Fatalf("%d: step: %v", tc.id, err)
+		}
+		if got != location {
+			t.Errorf("%d: location: want %s, got %s", tc.id, location.GridString(), got.GridString())
+		}
+		neighbor, ok := worldMap.Tiles[location.Add(tc.advance)]
+		if !ok {
+			t.Errorf("%d: neighbor: want tile, got none", tc.id)
+			continue
+		}
+		if neighbor.Terrain != tc.terrain {
+			t.Errorf("%d: terrain: want %q, got %q", tc.id, tc.terrain, neighbor.Terrain)
+		}
+		// the neighbor is inferred from the border, never visited or scouted
+		if neighbor.Visited != "" || neighbor.Scouted != "" {
+			t.Errorf("%d: neighbor: want inferred, got visited %q scouted %q", tc.id, neighbor.Visited, neighbor.Scouted)
+		}
+		if neighbor.FirstSeen != "0902-02" {
+			t.Errorf("%d: first seen: want %q, got %q", tc.id, "0902-02", neighbor.FirstSeen)
+		}
+	}
+}
+
+// a unit that vanishes is last seen in the hex it started the step in.
+func TestStepVanished(t *testing.T) {
+	location, err := coords.HexToMap("QQ 1108")
+	if err != nil {
+		t.Fatalf("location: %v", err)
+	}
+	worldMap := tiles.NewMap()
+	move := &parser.Move_t{UnitId: "0138", Result: results.Vanished, Report: &parser.Report_t{UnitId: "0138"}}
+	got, err := turns.Step("0902-02", move, location, coords.Map{}, worldMap, nil, true, false, false, false)
+	if err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	if got != location {
+		t.Errorf("location: want %s, got %s", location.GridString(), got.GridString())
+	}
+	if len(worldMap.Tiles) != 1 {
+		t.Errorf("tiles: want 1, got %d", len(worldMap.Tiles))
+	}
+}
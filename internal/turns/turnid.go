@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseTurnId returns the year and month from a turn id in yyyy-mm format.
+// The year may be given with three digits, as it is in the turn reports.
+func ParseTurnId(id string) (year, month int, err error) {
+	yyyy, mm, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q: must be yyyy-mm format", id)
+	} else if year, err = strconv.Atoi(yyyy); err != nil {
+		return 0, 0, fmt.Errorf("%q: must be yyyy-mm format", id)
+	} else if month, err = strconv.Atoi(mm); err != nil {
+		return 0, 0, fmt.Errorf("%q: must be yyyy-mm format", id)
+	} else if year < 899 || year > 9999 {
+		return 0, 0, fmt.Errorf("%q: invalid year %d", id, year)
+	} else if month < 1 || month > 12 {
+		return 0, 0, fmt.Errorf("%q: invalid month %d", id, month)
+	}
+	return year, month, nil
+}
+
+// CompareTurnId returns -1 if a is before b, 0 if they are the same turn,
+// and +1 if a is after b. Invalid turn ids sort before valid ones.
+func CompareTurnId(a, b string) int {
+	ay, am, aerr := ParseTurnId(a)
+	by, bm, berr := ParseTurnId(b)
+	if aerr != nil || berr != nil {
+		if aerr != nil && berr != nil {
+			return 0
+		} else if aerr != nil {
+			return -1
+		}
+		return 1
+	}
+	if ta, tb := ay*12+am, by*12+bm; ta < tb {
+		return -1
+	} else if ta > tb {
+		return 1
+	}
+	return 0
+}
+
+// InTurnRange returns true if the turn is between from and to, inclusive.
+// An empty from or to leaves that end of the range open.
+func InTurnRange(id, from, to string) bool {
+	if from != "" && CompareTurnId(id, from) < 0 {
+		return false
+	} else if to != "" && CompareTurnId(id, to) > 0 {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestCompareTurnId(t *testing.T) {
+	for _, tc := range []struct {
+		id   int
+		a, b string
+		want int
+	}{
+		{1, "0902-02", "0902-03", -1},
+		{2, "0902-03", "0902-03", 0},
+		{3, "902-03", "0902-03", 0},
+		{4, "0903-01", "0902-12", 1},
+		{5, "bogus", "0902-12", -1},
+	} {
+		if got := turns.CompareTurnId(tc.a, tc.b); got != tc.want {
+			t.Errorf("%d: compare(%q, %q): got %d, want %d", tc.id, tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestInTurnRange(t *testing.T) {
+	// the map as the player knew it in 0903-06 only uses reports from 0902-06 through 0903-06
+	for _, tc := range []struct {
+		id       int
+		turnId   string
+		from, to string
+		want     bool
+	}{
+		{1, "0902-05", "0902-06", "0903-06", false},
+		{2, "0902-06", "0902-06", "0903-06", true},
+		{3, "0903-06", "0902-06", "0903-06", true},
+		{4, "0903-07", "0902-06", "0903-06", false},
+		{5, "0899-12", "", "0903-06", true},
+		{6, "1234-12", "0902-06", "", true},
+	} {
+		if got := turns.InTurnRange(tc.turnId, tc.from, tc.to); got != tc.want {
+			t.Errorf("%d: range(%q, %q, %q): got %v, want %v", tc.id, tc.turnId, tc.from, tc.to, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+)
+
+// ResolveUnknown fills in previous hexes that the report gave as "N/A".
+// The input must be sorted by turn.
+//
+// It walks forward, using the unit's current hex from the prior turn or
+// its parent's starting hex, and backward, retracing the unit's successful
+// steps from its current hex. The passes are repeated until nothing changes.
+// It returns the number of hexes that were updated and the units whose
+// previous hex could not be reconstructed.
+func ResolveUnknown(input []*parser.Turn_t) (int, []Unresolved_t) {
+	resolved := 0
+	for changed := true; changed; {
+		changed = false
+		for n, turn := range input {
+			var prev *parser.Turn_t
+			if n > 0 {
+				prev = input[n-1]
+			}
+			for _, moves := range turn.SortedMoves {
+				if moves.FromHex != "N/A" {
+					continue
+				}
+				if hex, ok := resolveUnknown(moves, turn, prev); ok {
+					moves.FromHex, changed = hex, true
+					resolved++
+				}
+			}
+		}
+	}
+
+	var unresolved []Unresolved_t
+	for _, turn := range input {
+		for _, moves := range turn.SortedMoves {
+			if moves.FromHex == "N/A" {
+				unresolved = append(unresolved, Unresolved_t{
+					TurnId: turn.Id,
+					UnitId: moves.UnitId,
+					Field:  "Previous Hex",
+					Hex:    moves.FromHex,
+				})
+			}
+		}
+	}
+
+	return resolved, unresolved
+}
+
+func resolveUnknown(moves *parser.Moves_t, turn, prev *parser.Turn_t) (string, bool) {
+	// forward from where the unit ended the prior turn
+	if prev != nil {
+		if prior, ok := prev.UnitMoves[moves.UnitId]; ok && isKnown(prior.ToHex) {
+			return prior.ToHex, true
+		}
+	}
+	// forward from where the parent started this turn
+	if parent, ok := turn.UnitMoves[moves.UnitId.Parent()]; ok && parent.UnitId != moves.UnitId && isKnown(parent.FromHex) {
+		return parent.FromHex, true
+	}
+	// backward from where the unit ended this turn
+	return retrace(moves)
+}
+
+// retrace undoes the unit's successful steps, starting from its current hex.
+// It fails if the unit followed another unit, jumped, or vanished,
+// since we can't know where it came from.
+func retrace(moves *parser.Moves_t) (string, bool) {
+	if moves.Follows != "" || moves.GoesTo != "" || !isKnown(moves.ToHex) {
+		return "", false
+	}
+	location, err := coords.HexToMap(moves.ToHex)
+	if err != nil {
+		return "", false
+	}
+	for i := len(moves.Moves) - 1; i >= 0; i-- {
+		move := moves.Moves[i]
+		if move.Follows != "" || move.GoesTo != "" || move.Result == results.Vanished {
+			return "", false
+		} else if move.Result == results.Succeeded && move.Advance != direction.Unknown {
+			location = location.Add(move.Advance.Opposite())
+		}
+	}
+	return location.ToHex(), true
+}
+
+func isKnown(hex string) bool {
+	return len(hex) == 7 && !isObscured(hex)
+}
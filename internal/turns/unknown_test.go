@@ -0,0 +1,58 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/turns"
+	"testing"
+)
+
+func TestResolveUnknown(t *testing.T) {
+	newTurn := func(id string, moves ...*parser.Moves_t) *parser.Turn_t {
+		turn := &parser.Turn_t{Id: id, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+		for _, m := range moves {
+			m.TurnId = id
+			turn.UnitMoves[m.UnitId] = m
+			turn.SortedMoves = append(turn.SortedMoves, m)
+		}
+		return turn
+	}
+
+	// the tribe moved N then failed to move NE, so it started one hex south of QQ 1008
+	tribe1 := &parser.Moves_t{UnitId: "0138", FromHex: "N/A", ToHex: "QQ 1008", Moves: []*parser.Move_t{
+		{Advance: direction.North, Result: results.Succeeded},
+		{Advance: direction.NorthEast, Result: results.Failed},
+	}}
+	tribe2 := &parser.Moves_t{UnitId: "0138", FromHex: "N/A", ToHex: "QQ 1108"}
+	element := &parser.Moves_t{UnitId: "0138e1", FromHex: "N/A", ToHex: "QQ 1209"}
+	follower := &parser.Moves_t{UnitId: "0200e1", FromHex: "N/A", ToHex: "QQ 1108", Follows: "0199"}
+	input := []*parser.Turn_t{
+		newTurn("0901-12", tribe1),
+		newTurn("0902-01", tribe2, element, follower),
+	}
+
+	resolved, unresolved := turns.ResolveUnknown(input)
+	for _, tc := range []struct {
+		id   int
+		got  string
+		want string
+	}{
+		{1, tribe1.FromHex, "QQ 1009"},  // retraced from the current hex
+		{2, tribe2.FromHex, "QQ 1008"},  // from the prior turn
+		{3, element.FromHex, "QQ 1008"}, // from the parent
+		{4, follower.FromHex, "N/A"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%d: got %q, want %q", tc.id, tc.got, tc.want)
+		}
+	}
+	if resolved != 3 {
+		t.Errorf("resolved: got %d, want 3", resolved)
+	}
+	if len(unresolved) != 1 || unresolved[0].UnitId != "0200e1" {
+		t.Errorf("unresolved: want 0200e1, got %v", unresolved)
+	}
+}
@@ -3,15 +3,21 @@
 package turns
 
 import (
+	"context"
+	"fmt"
 	"github.com/playbymail/ottomap/internal/coords"
 	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
 	"github.com/playbymail/ottomap/internal/tiles"
 	"log"
 	"strings"
 	"time"
 )
 
-func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, originGrid string, quitOnInvalidGrid, warnOnInvalidGrid, warnOnNewSettlement, warnOnTerrainChange, debug bool) (*tiles.Map_t, error) {
+// Walk steps every unit through every turn and returns the tiles they reported.
+// Moves off the edge of a world that wraps come back on the other side.
+// It stops with the context's error if the context is cancelled between units.
+func Walk(ctx context.Context, input []*parser.Turn_t, specialNames map[string]*parser.Special_t, world coords.World, originGrid string, quitOnInvalidGrid, warnOnInvalidGrid, warnOnNewSettlement, warnOnTerrainChange, debug bool) (*tiles.Map_t, error) {
 	started := time.Now()
 	log.Printf("walk: input: %8d turns\n", len(input))
 
@@ -19,6 +25,7 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 	lastSeen := map[parser.UnitId_t]coords.Map{}
 
 	worldMap := tiles.NewMap()
+	worldMap.World = world
 	for _, turn := range input {
 		// sanity check, these should always be the same value
 		for _, moves := range turn.SortedMoves {
@@ -32,7 +39,7 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 
 		// leap of faith, update the location of all units that have a valid FromHex
 		for _, unit := range turn.SortedMoves {
-			if !strings.HasPrefix(unit.FromHex, "##") {
+			if !strings.HasPrefix(unit.FromHex, "##") && unit.FromHex != "N/A" {
 				if location, err := coords.HexToMap(unit.FromHex); err != nil {
 					log.Printf("walk: %s: %s: %q: %v\n", turn.Id, unit.UnitId, unit.FromHex, err)
 					panic(err)
@@ -67,10 +74,16 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 		}
 
 		turn.TopoSortMoves()
+		if err := ResolveFollows(turn); err != nil {
+			return nil, err
+		}
 
 		// walk the moves for all the units in this turn
 		for _, moves := range turn.SortedMoves {
 			unit := moves.UnitId
+			if err := ctx.Err(); err != nil {
+				return nil, fmt.Errorf("walk: %s: %s: %w", turn.Id, unit, err)
+			}
 			//log.Printf("walk: turn %s unit %-8s goto %-8s follows %-8s %-8s    %s\n", turn.Id, unit, moves.GoesTo, moves.Follows, moves.FromHex, moves.Location.GridString())
 			// if we're missing the location, can we derive it from the previous turn?
 			if moves.Location.IsZero() {
@@ -79,13 +92,22 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 
 			var leader coords.Map // set only if this is a follows move
 			if moves.Follows != "" {
-				if location, ok := lastSeen[moves.Follows]; !ok {
-					panic("!!!")
-				} else {
+				if _, ok := turn.UnitMoves[moves.Follows]; ok {
+					// ResolveFollows guarantees that the leader has already been walked
+					leader = lastSeen[moves.Follows]
+				} else if location, err := coords.HexToMap(moves.ToHex); err == nil {
+					// the leader isn't in our reports this turn, so trust the follower's ending hex
+					log.Printf("walk: %s: %-6s: follows %q: leader not found: using %s\n", turn.Id, unit, moves.Follows, moves.ToHex)
 					leader = location
+				} else if location, ok := lastSeen[moves.Follows]; ok {
+					leader = location
+				} else {
+					log.Printf("walk: %s: %-6s: follows %q: leader not found: ending hex %q\n", turn.Id, unit, moves.Follows, moves.ToHex)
+					return nil, fmt.Errorf("%s: %s: follows %s: leader not found", turn.Id, unit, moves.Follows)
 				}
 			}
 
+			moves.StartLocation = moves.Location
 			current := moves.Location
 
 			// step through all the moves this unit makes this turn, tracking the location of the unit after each step
@@ -97,6 +119,9 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 				if move.Debug.FleetMoves {
 					log.Printf("%s: %-6s: %d: step %d: result %q: to %q\n", turn.Id, unit, move.LineNo, move.StepNo, move.Result, location)
 				}
+				if move.Result == results.Vanished {
+					worldMap.Tiles[location].MergeLost(&tiles.Lost_t{TurnId: turn.Id, UnitId: unit})
+				}
 				current = location
 			}
 			if strings.Contains(current.GridString(), "-") {
@@ -105,6 +130,10 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 
 			moves.Location, lastSeen[unit] = current, current
 
+			// a unit that moved without reporting, for example by following another unit,
+			// may not be in the encounters for the hex it ended in, so put it there.
+			worldMap.Tiles[current].MergeEncounter(&parser.Encounter_t{TurnId: turn.Id, UnitId: unit})
+
 			// the unit's final location has been updated, so we can now send out the scouting parties
 			for _, scout := range moves.Scouts {
 				// each scout will start in the unit's current location
@@ -116,10 +145,36 @@ func Walk(input []*parser.Turn_t, specialNames map[string]*parser.Special_t, ori
 						panic(err)
 					}
 					//log.Printf("%s: %-6s: %d: step %d: result %q: to %q\n", turn.Id, unit, move.LineNo, move.StepNo, move.Result, location)
+					if move.Result == results.Vanished {
+						worldMap.Tiles[location].MergeLost(&tiles.Lost_t{TurnId: turn.Id, UnitId: unit, ScoutNo: scout.No})
+					}
 					current = location
 				}
 			}
 		}
+
+		// units disbanded this turn are no longer anywhere on the map
+		for _, moves := range turn.SortedMoves {
+			for _, event := range moves.Events {
+				if event.Kind != parser.UnitDisbanded {
+					continue
+				}
+				dropped := worldMap.DropEncounters(event.UnitId, turn.Id)
+				delete(lastSeen, event.UnitId)
+				if debug {
+					log.Printf("walk: %s: %-6s: disbanded %s: dropped %d encounters\n", turn.Id, moves.UnitId, event.UnitId, dropped)
+				}
+			}
+		}
+
+		// owners are reported for settlements in the hex the unit ended the turn in
+		for _, moves := range turn.SortedMoves {
+			for _, owner := range moves.Owners {
+				if worldMap.Tiles[moves.Location].MergeOwner(turn.Id, owner) && debug {
+					log.Printf("walk: %s: %-6s: %s: owner %s\n", turn.Id, moves.UnitId, owner.Name, owner.Clan)
+				}
+			}
+		}
 	}
 
 	log.Printf("walk: %8d nodes: elapsed %v\n", len(input), time.Since(started))
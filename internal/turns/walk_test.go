@@ -0,0 +1,167 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package turns_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/results"
+	"github.com/playbymail/ottomap/internal/turns"
+	"io"
+	"log"
+	"testing"
+)
+
+// a unit that follows another without reporting anything must still be placed in the hex it ended in.
+func TestWalkPlacesSilentUnits(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	turn := &parser.Turn_t{Id: "0902-02", UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+	for _, moves := range []*parser.Moves_t{
+		{TurnId: "0902-02", UnitId: "0138", FromHex: "QQ 1208", ToHex: "QQ 1308", Moves: []*parser.Move_t{
+			{UnitId: "0138", Advance: direction.SouthEast, Result: results.Succeeded, Report: &parser.Report_t{UnitId: "0138"}},
+		}},
+		{TurnId: "0902-02", UnitId: "0138e1", FromHex: "QQ 1208", ToHex: "QQ 1308", Follows: "0138", Moves: []*parser.Move_t{
+			{UnitId: "0138e1", Follows: "0138", Report: &parser.Report_t{UnitId: "0138e1"}},
+		}},
+	} {
+		turn.UnitMoves[moves.UnitId] = moves
+		turn.SortedMoves = append(turn.SortedMoves, moves)
+	}
+
+	start, err := coords.HexToMap("QQ 1208")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := start.Add(direction.SouthEast)
+
+	worldMap, err := turns.Walk(context.Background(), []*parser.Turn_t{turn}, nil, coords.World{}, "", false, false, false, false, false)
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	for _, id := range []parser.UnitId_t{"0138", "0138e1"} {
+		ended := turn.UnitMoves[id].Location
+		if ended != want {
+			t.Errorf("%s: location: want %q, got %q", id, want.GridString(), ended.GridString())
+		}
+		found := false
+		for _, e := range worldMap.Tiles[ended].Encounters {
+			found = found || (e.UnitId == id && e.TurnId == turn.Id)
+		}
+		if !found {
+			t.Errorf("%s: want encounter in %s, got none", id, ended.GridString())
+		}
+	}
+}
+
+// a disbanded unit must not be left in the hexes it was seen in.
+func TestWalkDropsDisbandedUnits(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	var input []*parser.Turn_t
+	for _, tc := range []struct {
+		turnId string
+		units  []parser.UnitId_t
+		events []*parser.UnitEvent_t
+	}{
+		{"0902-01", []parser.UnitId_t{"0138", "0138e1"}, nil},
+		{"0902-02", []parser.UnitId_t{"0138"}, []*parser.UnitEvent_t{{Kind: parser.UnitDisbanded, UnitId: "0138e1"}}},
+	} {
+		turn := &parser.Turn_t{Id: tc.turnId, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+		for _, id := range tc.units {
+			moves := &parser.Moves_t{TurnId: tc.turnId, UnitId: id, FromHex: "QQ 1208", ToHex: "QQ 1208", Moves: []*parser.Move_t{
+				{UnitId: id, Still: true, Result: results.Succeeded, Report: &parser.Report_t{UnitId: id}},
+			}}
+			if id == "0138" {
+				moves.Events = tc.events
+			}
+			turn.UnitMoves[id] = moves
+			turn.SortedMoves = append(turn.SortedMoves, moves)
+		}
+		input = append(input, turn)
+	}
+
+	worldMap, err := turns.Walk(context.Background(), input, nil, coords.World{}, "", false, false, false, false, false)
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	var kept int
+	for _, tile := range worldMap.Tiles {
+		for _, e := range tile.Encounters {
+			if e.UnitId == "0138e1" {
+				t.Errorf("%s: %s: want disbanded unit dropped", tile.Location.GridString(), e.TurnId)
+			} else if e.UnitId == "0138" {
+				kept++
+			}
+		}
+	}
+	if kept != 2 {
+		t.Errorf("0138: want 2 encounters, got %d", kept)
+	}
+}
+
+// the owner of a settlement is tracked across turns, with a history entry for each change.
+func TestWalkTracksSettlementOwners(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	var input []*parser.Turn_t
+	for _, tc := range []struct {
+		turnId string
+		owner  *parser.SettlementOwner_t
+	}{
+		{"0902-01", &parser.SettlementOwner_t{Name: "Rivertown", Clan: "0250"}},
+		{"0902-02", &parser.SettlementOwner_t{Name: "Rivertown", Clan: "0250"}},
+		{"0902-03", &parser.SettlementOwner_t{Name: "RIVERTOWN", Clan: "0138", Captured: true}},
+	} {
+		turn := &parser.Turn_t{Id: tc.turnId, UnitMoves: map[parser.UnitId_t]*parser.Moves_t{}}
+		moves := &parser.Moves_t{TurnId: tc.turnId, UnitId: "0138", FromHex: "QQ 1208", ToHex: "QQ 1208", Moves: []*parser.Move_t{
+			{UnitId: "0138", Still: true, Result: results.Succeeded, Report: &parser.Report_t{UnitId: "0138", Settlements: []*parser.Settlement_t{{TurnId: tc.turnId, Name: "Rivertown"}}}},
+		}, Owners: []*parser.SettlementOwner_t{tc.owner}}
+		turn.UnitMoves[moves.UnitId] = moves
+		turn.SortedMoves = append(turn.SortedMoves, moves)
+		input = append(input, turn)
+	}
+
+	worldMap, err := turns.Walk(context.Background(), input, nil, coords.World{}, "", false, false, false, false, false)
+	if err != nil {
+		t.Fatalf("walk: %v", err)
+	}
+	location, err := coords.HexToMap("QQ 1208")
+	if err != nil {
+		t.Fatal(err)
+	}
+	settlements := worldMap.Tiles[location].Settlements
+	if len(settlements) != 1 {
+		t.Fatalf("settlements: want 1, got %d", len(settlements))
+	}
+	s := settlements[0]
+	if s.Owner != "0138" {
+		t.Errorf("owner: want %q, got %q", "0138", s.Owner)
+	}
+	for _, tc := range []struct {
+		id       int
+		turnId   string
+		clan     parser.UnitId_t
+		captured bool
+	}{
+		{1, "0902-01", "0250", false},
+		{2, "0902-03", "0138", true},
+	} {
+		if tc.id > len(s.History) {
+			t.Errorf("%d: history: missing", tc.id)
+			continue
+		}
+		h := s.History[tc.id-1]
+		if h.TurnId != tc.turnId || h.Clan != tc.clan || h.Captured != tc.captured {
+			t.Errorf("%d: history: want %s %s %v, got %s %s %v", tc.id, tc.turnId, tc.clan, tc.captured, h.TurnId, h.Clan, h.Captured)
+		}
+	}
+	if len(s.History) != 2 {
+		t.Errorf("history: want 2 changes, got %d", len(s.History))
+	}
+}
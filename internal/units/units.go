@@ -50,6 +50,7 @@ var (
 	// EnumToString is a helper map for marshalling the enum
 	EnumToString = map[Type_e]string{
 		Unknown:  "Unknown",
+		Clan:     "Clan",
 		Tribe:    "Tribe",
 		Courier:  "Courier",
 		Element:  "Element",
@@ -59,6 +60,7 @@ var (
 	// StringToEnum is a helper map for unmarshalling the enum
 	StringToEnum = map[string]Type_e{
 		"Unknown":  Unknown,
+		"Clan":     Clan,
 		"Tribe":    Tribe,
 		"Courier":  Courier,
 		"Element":  Element,
@@ -66,3 +68,26 @@ var (
 		"Garrison": Garrison,
 	}
 )
+
+// TypeOf returns the type of unit from the unit id. For example, "0138" is a clan,
+// "1138" is a tribe, and "0138f1" is a fleet. It returns Unknown for anything else.
+func TypeOf(id string) Type_e {
+	switch {
+	case len(id) == 4 && id[0] == '0':
+		return Clan
+	case len(id) == 4:
+		return Tribe
+	case len(id) == 6:
+		switch id[4] {
+		case 'c':
+			return Courier
+		case 'e':
+			return Element
+		case 'f':
+			return Fleet
+		case 'g':
+			return Garrison
+		}
+	}
+	return Unknown
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package units_test
+
+import (
+	"github.com/playbymail/ottomap/internal/units"
+	"testing"
+)
+
+func TestTypeOf(t *testing.T) {
+	for _, tc := range []struct {
+		id   string
+		want units.Type_e
+	}{
+		{"0138", units.Clan},
+		{"2138", units.Tribe},
+		{"0138c1", units.Courier},
+		{"2138e3", units.Element},
+		{"0138f2", units.Fleet},
+		{"1138g1", units.Garrison},
+		{"0138x1", units.Unknown},
+		{"", units.Unknown},
+	} {
+		if got := units.TypeOf(tc.id); got != tc.want {
+			t.Errorf("%q: want %s, got %s", tc.id, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package viewer draws the merged map as an SVG document for viewing in a browser.
+package viewer
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"html"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Unit_t is a unit to mark on the map.
+type Unit_t struct {
+	Id       string
+	Location coords.Map
+}
+
+// Path_t is the route that a unit took during a turn.
+type Path_t struct {
+	UnitId string
+	TurnId string
+	Steps  []coords.Map
+}
+
+// Layers are the ids of the SVG groups, in drawing order.
+var Layers = []string{"terrain", "settlements", "paths", "units"}
+
+const (
+	// radius is the distance from the center of a hex to a corner.
+	radius = 20.0
+)
+
+// center returns the center of the hex in the odd-q layout (odd columns are shifted down).
+func center(m coords.Map) (float64, float64) {
+	x := float64(m.Column) * 1.5 * radius
+	y := float64(m.Row) * math.Sqrt(3) * radius
+	if m.Column%2 == 1 {
+		y += math.Sqrt(3) / 2 * radius
+	}
+	return x, y
+}
+
+// SVG writes the map as an SVG document with one group per layer.
+func SVG(w io.Writer, worldMap *tiles.Map_t, units []Unit_t, paths []Path_t) error {
+	bw := bufio.NewWriter(w)
+
+	// sort the tiles so that the output is stable
+	var list []*tiles.Tile_t
+	for _, tile := range worldMap.Tiles {
+		list = append(list, tile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i].Location, list[j].Location
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Row < b.Row
+	})
+
+	minX, minY, maxX, maxY := 0.0, 0.0, 0.0, 0.0
+	for n, tile := range list {
+		x, y := center(tile.Location)
+		if n == 0 || x < minX {
+			minX = x
+		}
+		if n == 0 || y < minY {
+			minY = y
+		}
+		if n == 0 || x > maxX {
+			maxX = x
+		}
+		if n == 0 || y > maxY {
+			maxY = y
+		}
+	}
+	minX, minY, maxX, maxY = minX-2*radius, minY-2*radius, maxX+2*radius, maxY+2*radius
+
+	_, _ = fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" id="map" viewBox="%.1f %.1f %.1f %.1f">`+"\n", minX, minY, maxX-minX, maxY-minY)
+
+	_, _ = fmt.Fprintf(bw, `<g id="terrain">`+"\n")
+	for _, tile := range list {
+		x, y := center(tile.Location)
+		var corners []string
+		for i := 0; i < 6; i++ {
+			angle := math.Pi / 3 * float64(i)
+			corners = append(corners, fmt.Sprintf("%.1f,%.1f", x+radius*math.Cos(angle), y+radius*math.Sin(angle)))
+		}
+		_, _ = fmt.Fprintf(bw, `<polygon points="%s" fill="%s" stroke="#555" stroke-width="0.5"><title>%s %s</title></polygon>`+"\n",
+			strings.Join(corners, " "), terrainColor(tile.Terrain), tile.Location.GridString(), html.EscapeString(tile.Terrain.String()))
+	}
+	_, _ = fmt.Fprintf(bw, "</g>\n")
+
+	_, _ = fmt.Fprintf(bw, `<g id="settlements">`+"\n")
+	for _, tile := range list {
+		for _, settlement := range tile.Settlements {
+			if settlement.Name == "" {
+				continue
+			}
+			x, y := center(tile.Location)
+			_, _ = fmt.Fprintf(bw, `<circle cx="%.1f" cy="%.1f" r="4" fill="#c00"/><text x="%.1f" y="%.1f" font-size="7" text-anchor="middle">%s</text>`+"\n",
+				x, y, x, y+radius*0.7, html.EscapeString(settlement.Name))
+		}
+	}
+	_, _ = fmt.Fprintf(bw, "</g>\n")
+
+	_, _ = fmt.Fprintf(bw, `<g id="paths" fill="none" stroke="#00c" stroke-width="1.5" stroke-opacity="0.7">`+"\n")
+	for _, path := range paths {
+		if len(path.Steps) < 2 {
+			continue
+		}
+		var points []string
+		for _, step := range path.Steps {
+			x, y := center(step)
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		_, _ = fmt.Fprintf(bw, `<polyline points="%s"><title>%s %s</title></polyline>`+"\n", strings.Join(points, " "), html.EscapeString(path.UnitId), path.TurnId)
+	}
+	_, _ = fmt.Fprintf(bw, "</g>\n")
+
+	_, _ = fmt.Fprintf(bw, `<g id="units">`+"\n")
+	for _, unit := range units {
+		x, y := center(unit.Location)
+		_, _ = fmt.Fprintf(bw, `<rect x="%.1f" y="%.1f" width="8" height="8" fill="#fc0" stroke="#000" stroke-width="0.5"><title>%s %s</title></rect>`+"\n",
+			x-4, y-radius*0.6, html.EscapeString(unit.Id), unit.Location.GridString())
+	}
+	_, _ = fmt.Fprintf(bw, "</g>\n")
+
+	_, _ = fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+// Page writes an HTML page that embeds the map with pan, zoom, and a toggle for each layer.
+func Page(w io.Writer, title string, worldMap *tiles.Map_t, units []Unit_t, paths []Path_t) error {
+	_, _ = fmt.Fprintf(w, "<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\"><title>%s</title>\n", html.EscapeString(title))
+	_, _ = fmt.Fprintf(w, "<style>body{margin:0;font-family:sans-serif}#layers{position:fixed;top:8px;left:8px;background:#fff;padding:4px 8px;border:1px solid #999}svg{width:100vw;height:100vh;cursor:grab}</style>\n")
+	_, _ = fmt.Fprintf(w, "</head><body>\n<div id=\"layers\">\n")
+	for _, layer := range Layers {
+		_, _ = fmt.Fprintf(w, `<label><input type="checkbox" checked onchange="document.getElementById('%s').style.display=this.checked?'':'none'"> %s</label>`+"\n", layer, layer)
+	}
+	_, _ = fmt.Fprintf(w, "</div>\n")
+	if err := SVG(w, worldMap, units, paths); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "<script>%s</script>\n</body></html>\n", panZoomScript)
+	return err
+}
+
+// panZoomScript pans the map by dragging and zooms with the mouse wheel by updating the view box.
+const panZoomScript = `
+const svg = document.getElementById('map');
+let vb = svg.viewBox.baseVal, drag = null;
+svg.addEventListener('wheel', e => {
+  e.preventDefault();
+  const k = e.deltaY > 0 ? 1.1 : 1/1.1, r = svg.getBoundingClientRect();
+  const mx = vb.x + (e.clientX - r.left) / r.width * vb.width, my = vb.y + (e.clientY - r.top) / r.height * vb.height;
+  vb.x = mx - (mx - vb.x) * k; vb.y = my - (my - vb.y) * k; vb.width *= k; vb.height *= k;
+});
+svg.addEventListener('mousedown', e => { drag = {x: e.clientX, y: e.clientY}; });
+window.addEventListener('mouseup', () => { drag = null; });
+window.addEventListener('mousemove', e => {
+  if (!drag) return;
+  const r = svg.getBoundingClientRect();
+  vb.x -= (e.clientX - drag.x) / r.width * vb.width; vb.y -= (e.clientY - drag.y) / r.height * vb.height;
+  drag = {x: e.clientX, y: e.clientY};
+});
+`
+
+// terrainColor returns the fill color for the terrain.
+func terrainColor(t terrain.Terrain_e) string {
+	switch {
+	case t == terrain.Lake || t == terrain.Ocean || t == terrain.UnknownWater:
+		return "#6ab0e0"
+	case t.IsAnyMountain() || t == terrain.UnknownMountain:
+		return "#9b8b7a"
+	case t.IsJungle() || t.IsSwamp() || t == terrain.UnknownJungleSwamp:
+		return "#3f7f3f"
+	}
+	switch t {
+	case terrain.Blank:
+		return "#ddd"
+	case terrain.AridHills, terrain.AridTundra, terrain.Desert:
+		return "#e8d49a"
+	case terrain.BrushFlat, terrain.BrushHills:
+		return "#b5c27a"
+	case terrain.ConiferHills, terrain.Deciduous, terrain.DeciduousHills:
+		return "#5f9f4f"
+	case terrain.GrassyHills, terrain.GrassyHillsPlateau, terrain.Prairie, terrain.PrairiePlateau:
+		return "#a8d080"
+	case terrain.PolarIce, terrain.SnowyHills, terrain.Tundra:
+		return "#eef"
+	case terrain.RockyHills:
+		return "#b0a090"
+	}
+	return "#ccc"
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package viewer_test
+
+import (
+	"bytes"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/viewer"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	worldMap := tiles.NewMap()
+	from, to := coords.Map{Column: 10, Row: 10}, coords.Map{Column: 11, Row: 10}
+	worldMap.FetchTile("0138", from).Terrain = terrain.Prairie
+	tile := worldMap.FetchTile("0138", to)
+	tile.Terrain = terrain.GrassyHills
+	tile.Settlements = append(tile.Settlements, &parser.Settlement_t{Name: "Bravo & Co"})
+
+	units := []viewer.Unit_t{{Id: "0138", Location: to}}
+	paths := []viewer.Path_t{{UnitId: "0138", TurnId: "0902-02", Steps: []coords.Map{from, to}}}
+
+	var buf bytes.Buffer
+	if err := viewer.SVG(&buf, worldMap, units, paths); err != nil {
+		t.Fatalf("svg: %v", err)
+	}
+	svg := buf.String()
+	for _, layer := range viewer.Layers {
+		if !strings.Contains(svg, `<g id="`+layer+`"`) {
+			t.Errorf("svg: missing layer %q", layer)
+		}
+	}
+	for _, tc := range []struct {
+		what string
+		want int
+	}{
+		{"<polygon ", 2},
+		{"<polyline ", 1},
+		{"<rect ", 1},
+		{"Bravo &amp; Co", 1},
+	} {
+		if got := strings.Count(svg, tc.what); got != tc.want {
+			t.Errorf("svg: %q: got %d, want %d", tc.what, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package watch polls a folder for changed files.
+// Polling is used instead of file system notifications because it works the
+// same on every platform and on network and synced folders, where events are
+// often missed. The cost is a delay of up to one interval.
+package watch
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Poll calls rebuild once at the start and then every time a file in the path is added, changed, or removed.
+// The path is checked every interval until done is closed; a nil done polls forever.
+// Rebuild is passed the number of files that changed since the last call.
+func Poll(path string, interval time.Duration, done <-chan struct{}, rebuild func(changes int)) {
+	var prior map[string]string
+	for {
+		current, err := Snapshot(path)
+		if err != nil {
+			log.Printf("warn: watch: %v\n", err)
+		} else if changes := CountChanges(prior, current); prior == nil || changes != 0 {
+			rebuild(changes)
+			prior = current
+		}
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Snapshot returns the size and modification time of every file in the path.
+func Snapshot(path string) (map[string]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// the file was removed while we were reading the folder
+			continue
+		}
+		snapshot[filepath.Join(path, entry.Name())] = fmt.Sprintf("%d %s", info.Size(), info.ModTime().Format(time.RFC3339Nano))
+	}
+	return snapshot, nil
+}
+
+// CountChanges returns the number of files that were added, changed, or removed.
+func CountChanges(prior, current map[string]string) int {
+	changes := 0
+	for name, stamp := range current {
+		if prior[name] != stamp {
+			changes++
+		}
+	}
+	for name := range prior {
+		if _, ok := current[name]; !ok {
+			changes++
+		}
+	}
+	return changes
+}
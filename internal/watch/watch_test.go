@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package watch_test
+
+import (
+	"github.com/playbymail/ottomap/internal/watch"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPoll(t *testing.T) {
+	path := t.TempDir()
+	report := filepath.Join(path, "0902-03.0138.report.txt")
+	if err := os.WriteFile(report, []byte("Tribe 0138\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rebuilds := make(chan int, 10)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		watch.Poll(path, 10*time.Millisecond, done, func(changes int) { rebuilds <- changes })
+		close(stopped)
+	}()
+	defer func() {
+		close(done)
+		<-stopped
+	}()
+
+	wait := func(id int, want int) {
+		select {
+		case got := <-rebuilds:
+			if got != want {
+				t.Errorf("%d: changes: want %d, got %d", id, want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%d: want rebuild, got none", id)
+		}
+	}
+
+	// the first check always renders
+	wait(1, 1)
+
+	// nothing changed, so there is no render
+	select {
+	case got := <-rebuilds:
+		t.Fatalf("2: want no rebuild, got one with %d changes", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// a changed report triggers a render
+	if err := os.WriteFile(report, []byte("Tribe 0138, , Current Hex = QQ 1010\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(3, 1)
+
+	// so do an added and a removed report
+	if err := os.WriteFile(filepath.Join(path, "0902-04.0138.report.txt"), []byte("Tribe 0138\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(4, 1)
+	if err := os.Remove(report); err != nil {
+		t.Fatal(err)
+	}
+	wait(5, 1)
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestChanges(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	hex := func(column int, changed bool) *wxx.Hex {
+		at := coords.Map{Column: column, Row: 1}
+		h := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+		h.Features.IsChanged = changed
+		return h
+	}
+	for _, tc := range []struct {
+		id    int
+		hexes []*wxx.Hex
+		want  int
+	}{
+		{1, []*wxx.Hex{hex(1, false), hex(2, false)}, 0},
+		{2, []*wxx.Hex{hex(1, true), hex(2, false), hex(3, true)}, 2},
+	} {
+		doc := document(t, tc.hexes, nil, coords.Map{Column: 1, Row: 1}, coords.Map{Column: 3, Row: 1}, wxx.RenderConfig{})
+		rings := 0
+		for _, shape := range doc.Shapes {
+			if shape.MapLayer != "Tribenet Changes" {
+				continue
+			}
+			rings++
+			// six corners and back to the first
+			if len(shape.Points) != 7 {
+				t.Errorf("%d: ring: want 7 points, got %d", tc.id, len(shape.Points))
+			} else if first, last := shape.Points[0], shape.Points[6]; first.X != last.X || first.Y != last.Y {
+				t.Errorf("%d: ring: want closed, got open", tc.id)
+			}
+		}
+		if rings != tc.want {
+			t.Errorf("%d: rings: want %d, got %d", tc.id, tc.want, rings)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/terrain"
+)
+
+// coastlines traces the sides between water and land tiles and joins them into
+// smoothed lines. Sides next to blank tiles are skipped since we don't know what
+// is there. A coast that goes all the way around an island or lake is returned
+// as a closed line that starts and ends at the same point.
+func coastlines(allTiles [][]*Tile, layout HexLayout) (lines [][]Point) {
+	var vertices []string
+	points := map[string]Point{}
+	links := map[string][]string{}
+	for _, row := range allTiles {
+		for _, t := range row {
+			if t == nil || !t.Terrain.IsWater() {
+				continue
+			}
+			hex := layout.points(t.RenderAt.Column, t.RenderAt.Row)
+			for _, d := range direction.Directions {
+				n := tileAt(allTiles, t.RenderAt.Add(d))
+				if n == nil || n.Terrain == terrain.Blank || n.Terrain.IsWater() {
+					continue
+				}
+				// each side is found only once, from the water tile
+				from, to := edgeEnds(d, hex)
+				a, b := vertexKey(from), vertexKey(to)
+				for _, v := range []Point{from, to} {
+					if _, ok := points[vertexKey(v)]; !ok {
+						points[vertexKey(v)] = v
+						vertices = append(vertices, vertexKey(v))
+					}
+				}
+				links[a] = append(links[a], b)
+				links[b] = append(links[b], a)
+			}
+		}
+	}
+
+	for _, path := range joinLinks(vertices, links) {
+		var line []Point
+		for _, v := range path {
+			line = append(line, points[v])
+		}
+		lines = append(lines, smooth(smooth(line)))
+	}
+	return lines
+}
+
+// smooth rounds the corners of a line by cutting each one off a quarter of the
+// way along the segments on either side. The ends of an open line are kept.
+func smooth(line []Point) []Point {
+	if len(line) < 3 {
+		return line
+	}
+	closed := vertexKey(line[0]) == vertexKey(line[len(line)-1])
+	cut := func(a, b Point) (Point, Point) {
+		return Point{X: 0.75*a.X + 0.25*b.X, Y: 0.75*a.Y + 0.25*b.Y}, Point{X: 0.25*a.X + 0.75*b.X, Y: 0.25*a.Y + 0.75*b.Y}
+	}
+	var smoothed []Point
+	for n := 0; n+1 < len(line); n++ {
+		q, r := cut(line[n], line[n+1])
+		if !closed && n == 0 {
+			q = line[0]
+		}
+		if !closed && n+2 == len(line) {
+			r = line[n+1]
+		}
+		smoothed = append(smoothed, q, r)
+	}
+	if closed {
+		smoothed = append(smoothed, smoothed[0])
+	}
+	return smoothed
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestCoastlines(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	lake := coords.Map{Column: 4, Row: 4}
+	hex := func(at coords.Map, kind terrain.Terrain_e) *wxx.Hex {
+		return &wxx.Hex{Location: at, RenderAt: at, Terrain: kind, WasVisited: true}
+	}
+	// ring returns the lake with the terrain on every side
+	ring := func(kind terrain.Terrain_e) []*wxx.Hex {
+		hexes := []*wxx.Hex{hex(lake, terrain.Lake)}
+		for _, d := range direction.Directions {
+			hexes = append(hexes, hex(lake.Add(d), kind))
+		}
+		return hexes
+	}
+	for _, tc := range []struct {
+		id     int
+		show   bool
+		hexes  []*wxx.Hex
+		lines  int
+		closed bool
+	}{
+		{1, false, ring(terrain.Prairie), 0, false},
+		{2, true, ring(terrain.Prairie), 1, true},
+		{3, true, ring(terrain.Ocean), 0, false},
+		{4, true, []*wxx.Hex{hex(lake, terrain.Lake), hex(lake.Add(direction.North), terrain.Prairie)}, 1, false},
+	} {
+		cfg := wxx.RenderConfig{}
+		cfg.Show.Coastlines = tc.show
+		doc := document(t, tc.hexes, nil, coords.Map{Column: 1, Row: 1}, coords.Map{Column: 7, Row: 7}, cfg)
+		if hasLayer(doc, "Coastlines") != tc.show {
+			t.Errorf("%d: layer: want %v, got %v", tc.id, tc.show, !tc.show)
+		}
+		var lines []*wxx.ShapeElement
+		for _, shape := range doc.Shapes {
+			if shape.MapLayer == "Coastlines" {
+				lines = append(lines, shape)
+			}
+		}
+		if len(lines) != tc.lines {
+			t.Errorf("%d: lines: want %d, got %d", tc.id, tc.lines, len(lines))
+			continue
+		}
+		for _, line := range lines {
+			first, last := line.Points[0], line.Points[len(line.Points)-1]
+			if closed := first.X == last.X && first.Y == last.Y; closed != tc.closed {
+				t.Errorf("%d: closed: want %v, got %v", tc.id, tc.closed, closed)
+			}
+		}
+	}
+}
@@ -4,6 +4,9 @@ package wxx
 
 import (
 	"fmt"
+	"github.com/playbymail/ottomap/internal/parser"
+	"math"
+	"sort"
 	"strconv"
 )
 
@@ -72,3 +75,77 @@ func hexToRGBA(hex string) (float64, float64, float64, float64, error) {
 
 	return rf, gf, bf, af, nil
 }
+
+// heatColor returns the color for the n-th of count steps, running through the hues
+// from blue for the first step to red for the last. A single step is red.
+func heatColor(n, count int) (float64, float64, float64) {
+	f := 1.0
+	if count > 1 {
+		f = float64(n) / float64(count-1)
+	}
+	// the hue is in sixths of the color wheel, 4 for blue down to 0 for red
+	hue := 4 * (1 - f)
+	x := 1 - math.Abs(math.Mod(hue, 2)-1)
+	switch {
+	case hue < 1:
+		return 1, x, 0
+	case hue < 2:
+		return x, 1, 0
+	case hue < 3:
+		return 0, 1, x
+	default:
+		return 0, x, 1
+	}
+}
+
+// ClanPalette is the colors given to the units of clans that don't have a color
+// in the configuration, in order of clan id. Red is left out because it marks
+// hexes with units from several clans.
+var ClanPalette = []string{"#1f77b4", "#2ca02c", "#9467bd", "#8c564b", "#e377c2", "#17becf", "#bcbd22", "#ff7f0e"}
+
+// clanColor is the marker color and legend label for a clan's units.
+type clanColor struct {
+	color string // Worldographer color, for example "1.0,0.0,0.0,1.0"
+	label string
+}
+
+// clanColors returns the marker colors for the units of the other clans.
+// Clans with a configured color are labeled as allies; the others are given
+// the next color from the palette.
+func clanColors(configured map[string]string, clans []parser.UnitId_t) (map[parser.UnitId_t]clanColor, error) {
+	sort.Slice(clans, func(i, j int) bool {
+		return clans[i] < clans[j]
+	})
+	colors, next := map[parser.UnitId_t]clanColor{}, 0
+	for _, clan := range clans {
+		if hex, ok := configured[string(clan)]; ok {
+			color, err := worldographerColor(hex)
+			if err != nil {
+				return nil, fmt.Errorf("clan %s: %w", clan, err)
+			}
+			colors[clan] = clanColor{color: color, label: fmt.Sprintf("Allied clan %s", clan)}
+			continue
+		}
+		color, err := worldographerColor(ClanPalette[next%len(ClanPalette)])
+		if err != nil {
+			return nil, fmt.Errorf("clan %s: %w", clan, err)
+		}
+		colors[clan] = clanColor{color: color, label: fmt.Sprintf("Clan %s", clan)}
+		next++
+	}
+	return colors, nil
+}
+
+// worldographerColor converts "#rrggbb" to the "r,g,b,a" form that Worldographer uses.
+func worldographerColor(hex string) (string, error) {
+	red, green, blue, err := hexToRGB(hex)
+	if err != nil {
+		return "", err
+	}
+	return rgba(red, green, blue), nil
+}
+
+// rgba returns the opaque color as a Worldographer color.
+func rgba(red, green, blue float64) string {
+	return fmt.Sprintf("%g,%g,%g,1.0", red, green, blue)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"sort"
+)
+
+// Diff returns the differences between two maps: tiles that were added,
+// removed, or changed terrain, and features and labels that were added,
+// removed, or moved. Items on the ignored layers are skipped.
+// Lines start with "+" for added, "-" for removed, and "~" for changed.
+func Diff(a, b *MapFile, ignoreLayers ...string) (diffs []string) {
+	ignored := map[string]bool{}
+	for _, layer := range ignoreLayers {
+		ignored[layer] = true
+	}
+
+	var hexes []coords.Map
+	for hex := range a.Terrain {
+		hexes = append(hexes, hex)
+	}
+	for hex := range b.Terrain {
+		if _, ok := a.Terrain[hex]; !ok {
+			hexes = append(hexes, hex)
+		}
+	}
+	sortHexes(hexes)
+	for _, hex := range hexes {
+		at, bt := a.Terrain[hex], b.Terrain[hex]
+		if at == "" {
+			diffs = append(diffs, fmt.Sprintf("+ %s: tile %q", hex.GridString(), bt))
+		} else if bt == "" {
+			diffs = append(diffs, fmt.Sprintf("- %s: tile %q", hex.GridString(), at))
+		} else if at != bt {
+			diffs = append(diffs, fmt.Sprintf("~ %s: terrain %q -> %q", hex.GridString(), at, bt))
+		}
+	}
+
+	diffs = append(diffs, diffItems("feature", a.Features, b.Features, ignored)...)
+	diffs = append(diffs, diffItems("label", a.Labels, b.Labels, ignored)...)
+	return diffs
+}
+
+// diffItems compares the hexes that each feature or label is in.
+// An item that is removed from one hex and added to another is reported as moved.
+func diffItems(kind string, a, b []MapItem, ignored map[string]bool) (diffs []string) {
+	type key_t struct {
+		layer, name string
+	}
+	count := func(items []MapItem) map[key_t]map[coords.Map]int {
+		counts := map[key_t]map[coords.Map]int{}
+		for _, item := range items {
+			if ignored[item.Layer] {
+				continue
+			}
+			k := key_t{layer: item.Layer, name: item.Name}
+			if counts[k] == nil {
+				counts[k] = map[coords.Map]int{}
+			}
+			counts[k][item.Hex]++
+		}
+		return counts
+	}
+	aCounts, bCounts := count(a), count(b)
+
+	var keys []key_t
+	for k := range aCounts {
+		keys = append(keys, k)
+	}
+	for k := range bCounts {
+		if _, ok := aCounts[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].layer != keys[j].layer {
+			return keys[i].layer < keys[j].layer
+		}
+		return keys[i].name < keys[j].name
+	})
+
+	for _, k := range keys {
+		var added, removed []coords.Map
+		for hex, n := range aCounts[k] {
+			for ; n > bCounts[k][hex]; n-- {
+				removed = append(removed, hex)
+			}
+		}
+		for hex, n := range bCounts[k] {
+			for ; n > aCounts[k][hex]; n-- {
+				added = append(added, hex)
+			}
+		}
+		sortHexes(added)
+		sortHexes(removed)
+		if len(added) == 1 && len(removed) == 1 {
+			diffs = append(diffs, fmt.Sprintf("~ %s %q (%s): moved %s -> %s", kind, k.name, k.layer, removed[0].GridString(), added[0].GridString()))
+			continue
+		}
+		for _, hex := range removed {
+			diffs = append(diffs, fmt.Sprintf("- %s: %s %q (%s)", hex.GridString(), kind, k.name, k.layer))
+		}
+		for _, hex := range added {
+			diffs = append(diffs, fmt.Sprintf("+ %s: %s %q (%s)", hex.GridString(), kind, k.name, k.layer))
+		}
+	}
+	return diffs
+}
+
+func sortHexes(hexes []coords.Map) {
+	sort.Slice(hexes, func(i, j int) bool {
+		if hexes[i].Column != hexes[j].Column {
+			return hexes[i].Column < hexes[j].Column
+		}
+		return hexes[i].Row < hexes[j].Row
+	})
+}
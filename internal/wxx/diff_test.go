@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/wxx"
+)
+
+func TestDiff(t *testing.T) {
+	hexA := coords.Map{Column: 10, Row: 7}
+	hexB := coords.Map{Column: 11, Row: 7}
+	hexC := coords.Map{Column: 12, Row: 8}
+	old := &wxx.MapFile{
+		Terrain: map[coords.Map]string{hexA: "Prairie", hexB: "Ocean"},
+		Features: []wxx.MapItem{
+			{Layer: "Units", Name: "Soldier", Hex: hexA},
+			{Layer: "Settlements", Name: "Village", Hex: hexB},
+			{Layer: "Legend", Name: "Box", Hex: hexA},
+		},
+		Labels: []wxx.MapItem{{Layer: "Notes", Name: "S", Hex: hexA}},
+	}
+	new := &wxx.MapFile{
+		Terrain: map[coords.Map]string{hexA: "Hills", hexB: "Ocean", hexC: "Swamp"},
+		Features: []wxx.MapItem{
+			{Layer: "Units", Name: "Soldier", Hex: hexC},
+			{Layer: "Legend", Name: "Box", Hex: hexC},
+		},
+		Labels: []wxx.MapItem{{Layer: "Notes", Name: "S", Hex: hexA}},
+	}
+	want := []string{
+		`~ ` + hexA.GridString() + `: terrain "Prairie" -> "Hills"`,
+		`+ ` + hexC.GridString() + `: tile "Swamp"`,
+		`- ` + hexB.GridString() + `: feature "Village" (Settlements)`,
+		`~ feature "Soldier" (Units): moved ` + hexA.GridString() + ` -> ` + hexC.GridString(),
+	}
+	if got := wxx.Diff(old, new, "Legend"); !reflect.DeepEqual(got, want) {
+		t.Errorf("diff: got\n%q\nwant\n%q", got, want)
+	}
+	if got := wxx.Diff(new, new); len(got) != 0 {
+		t.Errorf("diff: same map: got %q", got)
+	}
+}
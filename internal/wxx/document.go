@@ -0,0 +1,559 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Document is the XML of a Worldographer file.
+//
+// Encode builds the document and marshals it, so the escaping and nesting
+// are left to encoding/xml. The attributes that never change are filled in
+// by the constructors.
+type Document struct {
+	ViewLevel      string
+	HexWidth       float64
+	HexHeight      float64
+	HexOrientation string
+
+	// Offset is the shift from report coordinates to map coordinates, in a comment
+	// so that Read can put the tiles back. Worldographer ignores comments.
+	Offset xml.Comment
+
+	GridAndNumbering GridAndNumberingElement
+	TerrainMap       InnerXML
+	Layers           []LayerElement
+	Tiles            TilesElement
+	MapKey           MapKeyElement
+	Features         Features_t
+	Labels           Labels_t
+	Shapes           Shapes_t
+	Notes            Notes_t
+	Configuration    ConfigurationElement
+}
+
+// newDocument returns a document with the defaults for the hex layout.
+func newDocument(layout HexLayout) *Document {
+	return &Document{
+		ViewLevel:        World,
+		HexWidth:         layout.HexWidth,
+		HexHeight:        layout.HexHeight,
+		HexOrientation:   Columns,
+		GridAndNumbering: newGridAndNumbering(),
+		Tiles:            TilesElement{ViewLevel: World},
+		MapKey:           newMapKey(),
+	}
+}
+
+// MarshalXML writes the map with each section on its own line.
+func (d *Document) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	attr := func(name, value string) xml.Attr {
+		return xml.Attr{Name: xml.Name{Local: name}, Value: value}
+	}
+	start = xml.StartElement{Name: xml.Name{Local: "map"}, Attr: []xml.Attr{
+		attr("type", d.ViewLevel),
+		attr("version", "1.74"),
+		attr("lastViewLevel", d.ViewLevel),
+		attr("continentFactor", "0"),
+		attr("kingdomFactor", "0"),
+		attr("provinceFactor", "0"),
+		attr("worldToContinentHOffset", "0.0"),
+		attr("continentToKingdomHOffset", "0.0"),
+		attr("kingdomToProvinceHOffset", "0.0"),
+		attr("worldToContinentVOffset", "0.0"),
+		attr("continentToKingdomVOffset", "0.0"),
+		attr("kingdomToProvinceVOffset", "0.0"),
+		attr("hexWidth", strconv.FormatFloat(d.HexWidth, 'g', -1, 64)),
+		attr("hexHeight", strconv.FormatFloat(d.HexHeight, 'g', -1, 64)),
+		attr("hexOrientation", d.HexOrientation),
+		attr("mapProjection", "FLAT"),
+		attr("showNotes", "true"),
+		attr("showGMOnly", "true"),
+		attr("showGMOnlyGlow", "false"),
+		attr("showFeatureLabels", "true"),
+		attr("showGrid", "true"),
+		attr("showGridNumbers", "false"),
+		attr("showShadows", "true"),
+		attr("triangleSize", "12"),
+	}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	line := func(v any, name string) error {
+		if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		}
+		return e.EncodeToken(xml.CharData("\n"))
+	}
+	if err := e.EncodeToken(xml.CharData("\n")); err != nil {
+		return err
+	} else if err = e.EncodeToken(d.Offset); err != nil {
+		return err
+	} else if err = e.EncodeToken(xml.CharData("\n")); err != nil {
+		return err
+	} else if err = line(d.GridAndNumbering, "gridandnumbering"); err != nil {
+		return err
+	} else if err = line(d.TerrainMap, "terrainmap"); err != nil {
+		return err
+	}
+	for _, layer := range d.Layers {
+		if err := line(layer, "maplayer"); err != nil {
+			return err
+		}
+	}
+	for _, section := range []struct {
+		name string
+		v    any
+	}{
+		{"tiles", d.Tiles},
+		{"mapkey", d.MapKey},
+		{"features", d.Features},
+		{"labels", d.Labels},
+		{"shapes", d.Shapes},
+		{"notes", d.Notes},
+		{"informations", struct{}{}},
+		{"configuration", d.Configuration},
+	} {
+		if err := line(section.v, section.name); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// addLayer adds a map layer. Worldographer draws the layers from the bottom up.
+func (d *Document) addLayer(name string) {
+	d.Layers = append(d.Layers, LayerElement{Name: name, IsVisible: true})
+}
+
+// setViewLevel moves everything on the map to the view level.
+func (d *Document) setViewLevel(level string) {
+	d.ViewLevel = level
+	d.Tiles.ViewLevel, d.MapKey.ViewLevel = level, level
+	for _, f := range d.Features {
+		f.Location.ViewLevel = level
+		if f.Label != nil {
+			f.Label.Location.ViewLevel = level
+		}
+	}
+	for _, l := range d.Labels {
+		l.Location.ViewLevel = level
+	}
+	for _, s := range d.Shapes {
+		s.HighestViewLevel, s.CurrentShapeViewLevel = level, level
+	}
+	for _, n := range d.Notes {
+		n.ViewLevel = level
+	}
+}
+
+// InnerXML is text that is written as is. It is only used for text that
+// ottomap generates, like the tab-separated tile rows.
+type InnerXML struct {
+	Text string `xml:",innerxml"`
+}
+
+// GridAndNumberingElement is the style of the grid and the hex numbers.
+type GridAndNumberingElement struct {
+	Color0                      string `xml:"color0,attr"`
+	Color1                      string `xml:"color1,attr"`
+	Color2                      string `xml:"color2,attr"`
+	Color3                      string `xml:"color3,attr"`
+	Color4                      string `xml:"color4,attr"`
+	Width0                      string `xml:"width0,attr"`
+	Width1                      string `xml:"width1,attr"`
+	Width2                      string `xml:"width2,attr"`
+	Width3                      string `xml:"width3,attr"`
+	Width4                      string `xml:"width4,attr"`
+	GridOffsetContinentKingdomX string `xml:"gridOffsetContinentKingdomX,attr"`
+	GridOffsetContinentKingdomY string `xml:"gridOffsetContinentKingdomY,attr"`
+	GridOffsetWorldContinentX   string `xml:"gridOffsetWorldContinentX,attr"`
+	GridOffsetWorldContinentY   string `xml:"gridOffsetWorldContinentY,attr"`
+	GridOffsetWorldKingdomX     string `xml:"gridOffsetWorldKingdomX,attr"`
+	GridOffsetWorldKingdomY     string `xml:"gridOffsetWorldKingdomY,attr"`
+	GridSquare                  string `xml:"gridSquare,attr"`
+	GridSquareHeight            string `xml:"gridSquareHeight,attr"`
+	GridSquareWidth             string `xml:"gridSquareWidth,attr"`
+	GridOffsetX                 string `xml:"gridOffsetX,attr"`
+	GridOffsetY                 string `xml:"gridOffsetY,attr"`
+	NumberFont                  string `xml:"numberFont,attr"`
+	NumberColor                 string `xml:"numberColor,attr"`
+	NumberSize                  string `xml:"numberSize,attr"`
+	NumberStyle                 string `xml:"numberStyle,attr"`
+	NumberFirstCol              string `xml:"numberFirstCol,attr"`
+	NumberFirstRow              string `xml:"numberFirstRow,attr"`
+	NumberOrder                 string `xml:"numberOrder,attr"`
+	NumberPosition              string `xml:"numberPosition,attr"`
+	NumberPrePad                string `xml:"numberPrePad,attr"`
+	NumberSeparator             string `xml:"numberSeparator,attr"`
+}
+
+func newGridAndNumbering() GridAndNumberingElement {
+	return GridAndNumberingElement{
+		Color0: "0x00000040", Color1: "0x00000040", Color2: "0x00000040", Color3: "0x00000040", Color4: "0x00000040",
+		Width0: "1.0", Width1: "2.0", Width2: "3.0", Width3: "4.0", Width4: "1.0",
+		GridOffsetContinentKingdomX: "0.0", GridOffsetContinentKingdomY: "0.0",
+		GridOffsetWorldContinentX: "0.0", GridOffsetWorldContinentY: "0.0",
+		GridOffsetWorldKingdomX: "0.0", GridOffsetWorldKingdomY: "0.0",
+		GridSquare: "0", GridSquareHeight: "-1.0", GridSquareWidth: "-1.0",
+		GridOffsetX: "0.0", GridOffsetY: "0.0",
+		NumberFont: "Arial", NumberColor: "0x000000ff", NumberSize: "20", NumberStyle: "PLAIN",
+		NumberFirstCol: "0", NumberFirstRow: "0", NumberOrder: "COL_ROW", NumberPosition: "BOTTOM",
+		NumberPrePad: "DOUBLE_ZERO", NumberSeparator: ".",
+	}
+}
+
+// LayerElement is a map layer.
+type LayerElement struct {
+	Name      string `xml:"name,attr"`
+	IsVisible bool   `xml:"isVisible,attr"`
+}
+
+// TilesElement is the terrain of the map. Each row is a column of tiles, one
+// tab-separated line per tile.
+type TilesElement struct {
+	ViewLevel string
+	TilesWide int
+	TilesHigh int
+	Rows      []InnerXML
+}
+
+func (t TilesElement) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "viewLevel"}, Value: t.ViewLevel},
+		{Name: xml.Name{Local: "tilesWide"}, Value: strconv.Itoa(t.TilesWide)},
+		{Name: xml.Name{Local: "tilesHigh"}, Value: strconv.Itoa(t.TilesHigh)},
+	}
+	return encodeLines(e, start, "tilerow", t.Rows)
+}
+
+// MapKeyElement is the style of the map key. ottomap doesn't add entries to it.
+type MapKeyElement struct {
+	PositionX         string `xml:"positionx,attr"`
+	PositionY         string `xml:"positiony,attr"`
+	ViewLevel         string `xml:"viewlevel,attr"`
+	Height            string `xml:"height,attr"`
+	BackgroundColor   string `xml:"backgroundcolor,attr"`
+	BackgroundOpacity string `xml:"backgroundopacity,attr"`
+	TitleText         string `xml:"titleText,attr"`
+	TitleFontFace     string `xml:"titleFontFace,attr"`
+	TitleFontColor    string `xml:"titleFontColor,attr"`
+	TitleFontBold     bool   `xml:"titleFontBold,attr"`
+	TitleFontItalic   bool   `xml:"titleFontItalic,attr"`
+	TitleScale        string `xml:"titleScale,attr"`
+	ScaleText         string `xml:"scaleText,attr"`
+	ScaleFontFace     string `xml:"scaleFontFace,attr"`
+	ScaleFontColor    string `xml:"scaleFontColor,attr"`
+	ScaleFontBold     bool   `xml:"scaleFontBold,attr"`
+	ScaleFontItalic   bool   `xml:"scaleFontItalic,attr"`
+	ScaleScale        string `xml:"scaleScale,attr"`
+	EntryFontFace     string `xml:"entryFontFace,attr"`
+	EntryFontColor    string `xml:"entryFontColor,attr"`
+	EntryFontBold     bool   `xml:"entryFontBold,attr"`
+	EntryFontItalic   bool   `xml:"entryFontItalic,attr"`
+	EntryScale        string `xml:"entryScale,attr"`
+}
+
+func newMapKey() MapKeyElement {
+	return MapKeyElement{
+		PositionX: "0.0", PositionY: "0.0", ViewLevel: World, Height: "-1",
+		BackgroundColor: "0.9803921580314636,0.9215686321258545,0.843137264251709,1.0", BackgroundOpacity: "50",
+		TitleText: "Map Key", TitleFontFace: "Arial", TitleFontColor: "0.0,0.0,0.0,1.0", TitleFontBold: true, TitleScale: "80",
+		ScaleText: "1 Hex = ? units", ScaleFontFace: "Arial", ScaleFontColor: "0.0,0.0,0.0,1.0", ScaleFontBold: true, ScaleScale: "65",
+		EntryFontFace: "Arial", EntryFontColor: "0.0,0.0,0.0,1.0", EntryFontBold: true, EntryScale: "55",
+	}
+}
+
+// LocationElement places a feature or label on the map. Features don't have a scale.
+type LocationElement struct {
+	ViewLevel string  `xml:"viewLevel,attr"`
+	X         float64 `xml:"x,attr"`
+	Y         float64 `xml:"y,attr"`
+	Scale     string  `xml:"scale,attr,omitempty"`
+}
+
+// FeatureElement is an icon on the map, with an optional label.
+type FeatureElement struct {
+	Type              string          `xml:"type,attr"`
+	Rotate            string          `xml:"rotate,attr"`
+	UUID              string          `xml:"uuid,attr"`
+	MapLayer          string          `xml:"mapLayer,attr"`
+	IsFlipHorizontal  bool            `xml:"isFlipHorizontal,attr"`
+	IsFlipVertical    bool            `xml:"isFlipVertical,attr"`
+	Scale             string          `xml:"scale,attr"`
+	ScaleHt           string          `xml:"scaleHt,attr"`
+	Tags              string          `xml:"tags,attr"`
+	Color             string          `xml:"color,attr"`
+	RingColor         string          `xml:"ringcolor,attr"`
+	IsGMOnly          bool            `xml:"isGMOnly,attr"`
+	IsPlaceFreely     bool            `xml:"isPlaceFreely,attr"`
+	LabelPosition     string          `xml:"labelPosition,attr"`
+	LabelDistance     string          `xml:"labelDistance,attr"`
+	IsWorld           bool            `xml:"isWorld,attr"`
+	IsContinent       bool            `xml:"isContinent,attr"`
+	IsKingdom         bool            `xml:"isKingdom,attr"`
+	IsProvince        bool            `xml:"isProvince,attr"`
+	IsFillHexBottom   bool            `xml:"isFillHexBottom,attr"`
+	IsHideTerrainIcon bool            `xml:"isHideTerrainIcon,attr"`
+	Location          LocationElement `xml:"location"`
+	Label             *LabelElement   `xml:"label,omitempty"`
+}
+
+// newFeature returns a feature at 25% scale with the label under it.
+func newFeature(uuid, featureType, mapLayer string, at Point) *FeatureElement {
+	return &FeatureElement{
+		Type:          sanitize(featureType),
+		Rotate:        "0.0",
+		UUID:          uuid,
+		MapLayer:      mapLayer,
+		Scale:         "25.0",
+		ScaleHt:       "-1.0",
+		Color:         "null",
+		RingColor:     "null",
+		LabelPosition: "6:00",
+		LabelDistance: "0",
+		IsWorld:       true,
+		IsContinent:   true,
+		IsKingdom:     true,
+		IsProvince:    true,
+		Location:      LocationElement{ViewLevel: World, X: at.X, Y: at.Y},
+	}
+}
+
+// LabelElement is text on the map.
+type LabelElement struct {
+	MapLayer     string          `xml:"mapLayer,attr"`
+	Style        string          `xml:"style,attr"`
+	FontFace     string          `xml:"fontFace,attr"`
+	Color        string          `xml:"color,attr"`
+	OutlineColor string          `xml:"outlineColor,attr"`
+	OutlineSize  string          `xml:"outlineSize,attr"`
+	Rotate       string          `xml:"rotate,attr"`
+	IsBold       bool            `xml:"isBold,attr"`
+	IsItalic     bool            `xml:"isItalic,attr"`
+	IsWorld      bool            `xml:"isWorld,attr"`
+	IsContinent  bool            `xml:"isContinent,attr"`
+	IsKingdom    bool            `xml:"isKingdom,attr"`
+	IsProvince   bool            `xml:"isProvince,attr"`
+	IsGMOnly     bool            `xml:"isGMOnly,attr"`
+	Tags         string          `xml:"tags,attr"`
+	Location     LocationElement `xml:"location"`
+	Text         string          `xml:",chardata"`
+}
+
+// newLabel returns black text with a white outline. The scale is the font size.
+func newLabel(mapLayer string, at Point, scale, text string) *LabelElement {
+	return &LabelElement{
+		MapLayer:     mapLayer,
+		Style:        "null",
+		FontFace:     "null",
+		Color:        "0.0,0.0,0.0,1.0",
+		OutlineColor: "1.0,1.0,1.0,1.0",
+		OutlineSize:  "0.0",
+		Rotate:       "0.0",
+		IsWorld:      true,
+		IsContinent:  true,
+		IsKingdom:    true,
+		IsProvince:   true,
+		Location:     LocationElement{ViewLevel: World, X: at.X, Y: at.Y, Scale: scale},
+		Text:         sanitize(text),
+	}
+}
+
+// ShapeElement is a path or a polygon on the map.
+type ShapeElement struct {
+	Type                  string         `xml:"type,attr"`
+	IsCurve               bool           `xml:"isCurve,attr"`
+	IsGMOnly              bool           `xml:"isGMOnly,attr"`
+	IsSnapVertices        bool           `xml:"isSnapVertices,attr"`
+	IsMatchTileBorders    bool           `xml:"isMatchTileBorders,attr"`
+	Tags                  string         `xml:"tags,attr"`
+	CreationType          string         `xml:"creationType,attr"`
+	IsDropShadow          bool           `xml:"isDropShadow,attr"`
+	IsInnerShadow         bool           `xml:"isInnerShadow,attr"`
+	IsBoxBlur             bool           `xml:"isBoxBlur,attr"`
+	IsWorld               bool           `xml:"isWorld,attr"`
+	IsContinent           bool           `xml:"isContinent,attr"`
+	IsKingdom             bool           `xml:"isKingdom,attr"`
+	IsProvince            bool           `xml:"isProvince,attr"`
+	DsSpread              string         `xml:"dsSpread,attr"`
+	DsRadius              string         `xml:"dsRadius,attr"`
+	DsOffsetX             string         `xml:"dsOffsetX,attr"`
+	DsOffsetY             string         `xml:"dsOffsetY,attr"`
+	InsChoke              string         `xml:"insChoke,attr"`
+	InsRadius             string         `xml:"insRadius,attr"`
+	InsOffsetX            string         `xml:"insOffsetX,attr"`
+	InsOffsetY            string         `xml:"insOffsetY,attr"`
+	BbWidth               string         `xml:"bbWidth,attr"`
+	BbHeight              string         `xml:"bbHeight,attr"`
+	BbIterations          string         `xml:"bbIterations,attr"`
+	MapLayer              string         `xml:"mapLayer,attr"`
+	FillTexture           string         `xml:"fillTexture,attr"`
+	StrokeTexture         string         `xml:"strokeTexture,attr"`
+	StrokeType            string         `xml:"strokeType,attr"`
+	HighestViewLevel      string         `xml:"highestViewLevel,attr"`
+	CurrentShapeViewLevel string         `xml:"currentShapeViewLevel,attr"`
+	LineCap               string         `xml:"lineCap,attr"`
+	LineJoin              string         `xml:"lineJoin,attr"`
+	Opacity               float64        `xml:"opacity,attr"`
+	FillRule              string         `xml:"fillRule,attr"`
+	FillColor             string         `xml:"fillColor,attr,omitempty"`
+	StrokeColor           string         `xml:"strokeColor,attr"`
+	StrokeWidth           float64        `xml:"strokeWidth,attr"`
+	DsColor               string         `xml:"dsColor,attr"`
+	InsColor              string         `xml:"insColor,attr"`
+	Points                []PointElement `xml:"p"`
+}
+
+// PointElement is a vertex of a shape. The first vertex is a move.
+type PointElement struct {
+	Type string  `xml:"type,attr,omitempty"`
+	X    float64 `xml:"x,attr"`
+	Y    float64 `xml:"y,attr"`
+}
+
+// newPath returns an opaque line through the points.
+// Paths that snap to vertices follow the hex edges.
+func newPath(mapLayer, strokeColor string, strokeWidth float64, isSnapVertices bool, points ...Point) *ShapeElement {
+	s := &ShapeElement{
+		Type:                  "Path",
+		IsSnapVertices:        isSnapVertices,
+		CreationType:          "BASIC",
+		IsWorld:               true,
+		IsContinent:           true,
+		IsKingdom:             true,
+		IsProvince:            true,
+		DsSpread:              "0.2",
+		DsRadius:              "50.0",
+		DsOffsetX:             "0.0",
+		DsOffsetY:             "0.0",
+		InsChoke:              "0.2",
+		InsRadius:             "50.0",
+		InsOffsetX:            "0.0",
+		InsOffsetY:            "0.0",
+		BbWidth:               "10.0",
+		BbHeight:              "10.0",
+		BbIterations:          "3",
+		MapLayer:              mapLayer,
+		StrokeType:            "SIMPLE",
+		HighestViewLevel:      World,
+		CurrentShapeViewLevel: World,
+		LineCap:               "ROUND",
+		LineJoin:              "ROUND",
+		Opacity:               1.0,
+		FillRule:              "NON_ZERO",
+		StrokeColor:           strokeColor,
+		StrokeWidth:           strokeWidth,
+		DsColor:               "1.0,0.8941176533699036,0.7686274647712708,1.0",
+		InsColor:              "1.0,0.8941176533699036,0.7686274647712708,1.0",
+	}
+	for n, p := range points {
+		pt := PointElement{X: p.X, Y: p.Y}
+		if n == 0 {
+			pt.Type = "m"
+		}
+		s.Points = append(s.Points, pt)
+	}
+	return s
+}
+
+// newPolygon returns a translucent hex outline that matches the tile borders.
+func newPolygon(mapLayer, color string, opacity float64, points ...Point) *ShapeElement {
+	s := newPath(mapLayer, color, 0, true, points...)
+	s.Type, s.IsMatchTileBorders, s.Opacity, s.FillColor = "Polygon", true, opacity, color
+	return s
+}
+
+// NoteElement is a note pinned to a feature. The text is HTML in a CDATA section.
+type NoteElement struct {
+	Key       string  `xml:"key,attr"`
+	ViewLevel string  `xml:"viewLevel,attr"`
+	X         float64 `xml:"x,attr"`
+	Y         float64 `xml:"y,attr"`
+	Filename  string  `xml:"filename,attr"`
+	Parent    string  `xml:"parent,attr"`
+	Color     string  `xml:"color,attr"`
+	Title     string  `xml:"title,attr"`
+	Text      struct {
+		HTML string `xml:",cdata"`
+	} `xml:"notetext"`
+}
+
+// newNote returns a yellow note for the feature.
+// The lines are escaped, so they are shown as written.
+func newNote(note *FeatureNote, viewLevel string, policy NotePolicy) *NoteElement {
+	n := &NoteElement{
+		Key:       fmt.Sprintf("%s,%f,%f", viewLevel, note.Origin.X, note.Origin.Y),
+		ViewLevel: World,
+		X:         note.Origin.X,
+		Y:         note.Origin.Y,
+		Parent:    note.Id,
+		Color:     "1.0,1.0,0.0,1.0",
+		Title:     sanitize(note.Title),
+	}
+	var sb strings.Builder
+	sb.WriteString(`<html dir="ltr"><head></head><body contenteditable="true">`)
+	for _, line := range policy.Text(note.Lines) {
+		sb.WriteString(escape(line))
+		sb.WriteString(`<br/>`)
+	}
+	sb.WriteString(`</body></html>`)
+	n.Text.HTML = sb.String()
+	return n
+}
+
+// ConfigurationElement is the empty Worldographer configuration.
+type ConfigurationElement struct {
+	TerrainConfig struct{} `xml:"terrain-config"`
+	FeatureConfig struct{} `xml:"feature-config"`
+	TextureConfig struct{} `xml:"texture-config"`
+	TextConfig    struct{} `xml:"text-config"`
+	ShapeConfig   struct{} `xml:"shape-config"`
+}
+
+// Features_t, Labels_t, Shapes_t, and Notes_t are the sections of the map.
+// They are written one element per line so that the files can be compared with diff.
+type Features_t []*FeatureElement
+
+func (s Features_t) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeLines(e, start, "feature", s)
+}
+
+type Labels_t []*LabelElement
+
+func (s Labels_t) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeLines(e, start, "label", s)
+}
+
+type Shapes_t []*ShapeElement
+
+func (s Shapes_t) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeLines(e, start, "shape", s)
+}
+
+type Notes_t []*NoteElement
+
+func (s Notes_t) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return encodeLines(e, start, "note", s)
+}
+
+// encodeLines writes the elements with a newline after each one.
+func encodeLines[T any](e *xml.Encoder, start xml.StartElement, name string, elements []T) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	} else if err = e.EncodeToken(xml.CharData("\n")); err != nil {
+		return err
+	}
+	for _, element := range elements {
+		if err := e.EncodeElement(element, xml.StartElement{Name: xml.Name{Local: name}}); err != nil {
+			return err
+		} else if err = e.EncodeToken(xml.CharData("\n")); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	at, next := coords.Map{Column: 4, Row: 4}, coords.Map{Column: 5, Row: 4}
+	w, err := wxx.NewWXX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hex := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+	hex.Features.Settlements = []*parser.Settlement_t{{Name: "Alpha"}}
+	if err := w.MergeHex(hex); err != nil {
+		t.Fatal(err)
+	}
+	hex = &wxx.Hex{Location: next, RenderAt: next, Terrain: terrain.Prairie}
+	hex.Features.Special = []*parser.Special_t{{Id: "keep", Name: "Keep", Directives: []*parser.Directive_t{{Kind: parser.NoteDirective, Value: "walls"}}}}
+	if err := w.MergeHex(hex); err != nil {
+		t.Fatal(err)
+	}
+	w.AddAnnotation(wxx.Annotation{At: at, Label: "camp"})
+
+	doc, err := w.Document(context.Background(), "0901-07", at, next, wxx.RenderConfig{Deterministic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.ViewLevel != "WORLD" {
+		t.Errorf("viewLevel: want %q, got %q", "WORLD", doc.ViewLevel)
+	}
+	if len(doc.Tiles.Rows) != doc.Tiles.TilesWide {
+		t.Errorf("tiles: want %d rows, got %d", doc.Tiles.TilesWide, len(doc.Tiles.Rows))
+	}
+
+	labels := map[string]bool{}
+	for _, label := range doc.Labels {
+		labels[label.Text] = true
+	}
+	for _, want := range []string{"Alpha", "camp"} {
+		if !labels[want] {
+			t.Errorf("labels: want %q", want)
+		}
+	}
+	for _, feature := range doc.Features {
+		if feature.UUID == "" {
+			t.Errorf("feature %q: missing uuid", feature.Type)
+		}
+	}
+	if len(doc.Notes) != 1 {
+		t.Errorf("notes: want 1, got %d", len(doc.Notes))
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EncounterPolicy controls which encounters get unit markers.
+// The zero value draws only the encounters from the last turn.
+type EncounterPolicy struct {
+	Turns int  // draw the encounters from this many turns, ending with the last; 0 or 1 is the last turn only
+	All   bool // draw the encounters from every turn; Turns is ignored
+	Fade  bool // draw the markers for older encounters more transparent
+	Stamp bool // add the turn to the labels and notes of older encounters
+}
+
+// visible returns true if encounters that are age turns old are drawn.
+func (p EncounterPolicy) visible(age int) bool {
+	return age >= 0 && (p.All || age < max(p.Turns, 1))
+}
+
+// alpha returns the opacity of a marker for encounters that are age turns old.
+// The opacity drops evenly from 1.0 for the last turn to 0.25 for the oldest turn drawn.
+func (p EncounterPolicy) alpha(age, oldest int) float64 {
+	if !p.Fade || age <= 0 || oldest <= 0 {
+		return 1.0
+	}
+	return 1.0 - 0.75*float64(min(age, oldest))/float64(oldest)
+}
+
+// turnsBetween returns the number of turns from one "yyyy-mm" turn to a later one.
+// It returns -1 if either turn id is invalid.
+func turnsBetween(from, to string) int {
+	var fy, fm, ty, tm int
+	if _, err := fmt.Sscanf(from, "%d-%d", &fy, &fm); err != nil {
+		return -1
+	} else if _, err = fmt.Sscanf(to, "%d-%d", &ty, &tm); err != nil {
+		return -1
+	}
+	return (ty*12 + tm) - (fy*12 + fm)
+}
+
+// withAlpha replaces the opacity of an "r,g,b,a" color.
+// The default color, "null", is treated as white.
+func withAlpha(color string, alpha float64) string {
+	if alpha >= 1.0 {
+		return color
+	}
+	rgb := "1.0,1.0,1.0"
+	if i := strings.LastIndexByte(color, ','); color != "null" && color != "" && i != -1 {
+		rgb = color[:i]
+	}
+	return fmt.Sprintf("%s,%g", rgb, alpha)
+}
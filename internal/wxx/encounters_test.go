@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestEncounterPolicy(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	// 0249 was seen two turns before the last turn, 0250 four turns before
+	encounters := map[coords.Map]*parser.Encounter_t{
+		{Column: 4, Row: 4}: {TurnId: "0901-07", UnitId: "0138"},
+		{Column: 5, Row: 4}: {TurnId: "0901-05", UnitId: "0249"},
+		{Column: 6, Row: 4}: {TurnId: "0901-03", UnitId: "0250"},
+	}
+	render := func(policy wxx.EncounterPolicy) string {
+		w, err := wxx.NewWXX()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for at, e := range encounters {
+			hex := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+			hex.Features.Encounters = []*parser.Encounter_t{{TurnId: e.TurnId, UnitId: e.UnitId, Friendly: e.UnitId == "0138"}}
+			if err := w.MergeHex(hex); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf bytes.Buffer
+		if err := w.Encode(context.Background(), &buf, "0901-07", coords.Map{Column: 4, Row: 4}, coords.Map{Column: 6, Row: 4}, wxx.RenderConfig{Deterministic: true, Encounters: policy}); err != nil {
+			t.Fatal(err)
+		}
+		xml, err := wxx.Decode(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(xml)
+	}
+
+	for _, tc := range []struct {
+		id      int
+		policy  wxx.EncounterPolicy
+		want    []string
+		notWant []string
+	}{
+		{id: 1, want: []string{">0138<"}, notWant: []string{">0249", ">0250"}},
+		{id: 2, policy: wxx.EncounterPolicy{Turns: 3}, want: []string{">0138<", ">0249<"}, notWant: []string{">0250"}},
+		{id: 3, policy: wxx.EncounterPolicy{All: true, Stamp: true}, want: []string{">0138<", ">0249 (0901-05)<", ">0250 (0901-03)<"}},
+		{id: 4, policy: wxx.EncounterPolicy{All: true, Fade: true}, want: []string{",0.625\"", ",0.25\""}},
+	} {
+		xml := render(tc.policy)
+		for _, want := range tc.want {
+			if !strings.Contains(xml, want) {
+				t.Errorf("%d: want %q in map", tc.id, want)
+			}
+		}
+		for _, notWant := range tc.notWant {
+			if strings.Contains(xml, notWant) {
+				t.Errorf("%d: did not want %q in map", tc.id, notWant)
+			}
+		}
+	}
+}
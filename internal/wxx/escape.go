@@ -0,0 +1,32 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"html"
+	"strings"
+	"unicode/utf8"
+)
+
+// sanitize returns the text without the characters that XML doesn't allow.
+// It is used for every label, attribute value, and note line that comes from
+// a report or a config file; encoding/xml escapes the rest.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == utf8.RuneError:
+			return -1
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < 0x20, 0xfffe <= r && r <= 0xffff:
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// escape returns the sanitized text with the markup characters escaped, for the
+// HTML in notes. Escaping ">" also keeps the text from closing the CDATA section.
+func escape(s string) string {
+	return html.EscapeString(sanitize(s))
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestHostileNames(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	for _, tc := range []struct {
+		id   int
+		name string
+		want string
+	}{
+		{id: 1, name: "Smith & Sons", want: "Smith &amp; Sons"},
+		{id: 2, name: "<b>Fort</b>", want: "&lt;b&gt;Fort&lt;/b&gt;"},
+		{id: 3, name: `The "Keep"`, want: "The &#34;Keep&#34;"},
+		{id: 4, name: "Close]]>Early", want: "Close]]&gt;Early"},
+		{id: 5, name: "Bell\x07Tower", want: "BellTower"},
+	} {
+		at := coords.Map{Column: 4, Row: 4}
+		w, err := wxx.NewWXX()
+		if err != nil {
+			t.Fatal(err)
+		}
+		hex := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+		hex.Features.Settlements = []*parser.Settlement_t{{Name: tc.name}}
+		hex.Features.Lost = []wxx.NoteLine{{Kind: "lost", Message: tc.name}}
+		if err := w.MergeHex(hex); err != nil {
+			t.Fatal(err)
+		}
+		next := coords.Map{Column: 5, Row: 4}
+		hex = &wxx.Hex{Location: next, RenderAt: next, Terrain: terrain.Prairie, WasVisited: true}
+		hex.Features.Special = []*parser.Special_t{{Id: "keep", Name: tc.name, Directives: []*parser.Directive_t{{Kind: parser.NoteDirective, Value: tc.name}}}}
+		if err := w.MergeHex(hex); err != nil {
+			t.Fatal(err)
+		}
+		w.AddAnnotation(wxx.Annotation{At: at, Label: tc.name, Note: tc.name})
+
+		var buf bytes.Buffer
+		if err := w.Encode(context.Background(), &buf, "0901-07", at, next, wxx.RenderConfig{Deterministic: true}); err != nil {
+			t.Errorf("%d: encode: %v", tc.id, err)
+			continue
+		}
+		data, err := wxx.Decode(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the map must still be well-formed XML
+		d := xml.NewDecoder(bytes.NewReader(data))
+		d.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+		for {
+			if _, err = d.Token(); err != nil {
+				break
+			}
+		}
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("%d: xml: %v", tc.id, err)
+		}
+		if got := strings.Count(string(data), tc.want); got == 0 {
+			t.Errorf("%d: want %q in the map", tc.id, tc.want)
+		}
+	}
+}
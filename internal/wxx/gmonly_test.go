@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestGMOnly(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	hex := func(column int, visited, scouted, gmOnly bool, name string) *wxx.Hex {
+		at := coords.Map{Column: column, Row: 1}
+		return &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: visited, WasScouted: scouted, IsGMOnly: gmOnly,
+			Features: wxx.Features{Settlements: []*parser.Settlement_t{{TurnId: "0901-07", Name: name}}}}
+	}
+	hexes := []*wxx.Hex{
+		hex(1, true, false, false, "Visited"),
+		hex(2, false, false, true, "Unconfirmed"),
+		// a unit in the hex confirms it, so the flag is dropped
+		hex(3, false, true, true, "Scouted"),
+	}
+	doc := document(t, hexes, nil, coords.Map{Column: 1, Row: 1}, coords.Map{Column: 3, Row: 1}, wxx.RenderConfig{})
+
+	// the settlement icons have no label, so only the count of flagged icons is checked
+	settlements, flagged := 0, 0
+	for _, feature := range doc.Features {
+		if feature.MapLayer == "Tribenet Settlements" {
+			settlements++
+			if feature.IsGMOnly {
+				flagged++
+			}
+		}
+	}
+	if settlements != len(hexes) || flagged != 1 {
+		t.Errorf("settlements: want %d with 1 gm only, got %d with %d", len(hexes), settlements, flagged)
+	}
+
+	var gmOnly []string
+	for _, label := range doc.Labels {
+		if label.IsGMOnly {
+			gmOnly = append(gmOnly, label.MapLayer+" "+label.Text)
+		}
+	}
+	want := []string{"Tribenet Visited X", "Tribenet Settlements Unconfirmed"}
+	if len(gmOnly) != len(want) {
+		t.Fatalf("labels: want %q, got %q", want, gmOnly)
+	}
+	for i := range want {
+		if gmOnly[i] != want[i] {
+			t.Errorf("labels: %d: want %q, got %q", i, want[i], gmOnly[i])
+		}
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/resources"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the golden maps in testdata/golden")
+
+// TestGolden renders small canonical tile sets and compares the decoded maps
+// with the golden files. The comparison is on the structure of the XML, not the
+// bytes, so a failure lists the elements and attributes that changed.
+// Run "go test ./internal/wxx -run TestGolden -update" to accept the output.
+func TestGolden(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	at := func(column, row int) coords.Map {
+		return coords.Map{Column: column, Row: row}
+	}
+	hex := func(column, row int, t terrain.Terrain_e) *wxx.Hex {
+		return &wxx.Hex{Location: at(column, row), RenderAt: at(column, row), Terrain: t, WasVisited: true}
+	}
+
+	for _, tc := range []struct {
+		name  string
+		hexes func() []*wxx.Hex
+		extra func(w *wxx.WXX)
+	}{
+		{name: "terrain", hexes: func() (list []*wxx.Hex) {
+			for n := 1; n < terrain.NumberOfTerrainTypes; n++ {
+				list = append(list, hex(1+(n-1)%8, 1+(n-1)/8, terrain.Terrain_e(n)))
+			}
+			return list
+		}},
+		{name: "edges", hexes: func() []*wxx.Hex {
+			canal, ford, pass, river, road := hex(2, 2, terrain.Prairie), hex(4, 2, terrain.Prairie), hex(6, 2, terrain.RockyHills), hex(2, 4, terrain.Prairie), hex(4, 4, terrain.Prairie)
+			canal.Features.Edges.Canal = []direction.Direction_e{direction.North}
+			ford.Features.Edges.Ford = []direction.Direction_e{direction.NorthEast}
+			pass.Features.Edges.Pass = []direction.Direction_e{direction.SouthEast}
+			river.Features.Edges.River = []direction.Direction_e{direction.South, direction.SouthWest}
+			road.Features.Edges.StoneRoad = []direction.Direction_e{direction.NorthWest}
+			return []*wxx.Hex{canal, ford, pass, river, road, hex(6, 4, terrain.Lake)}
+		}},
+		{name: "settlements", hexes: func() []*wxx.Hex {
+			one, two := hex(2, 2, terrain.Prairie), hex(3, 2, terrain.GrassyHills)
+			one.Features.Settlements = []*parser.Settlement_t{{TurnId: "0901-07", Name: "Alpha"}}
+			two.Features.Settlements = []*parser.Settlement_t{{TurnId: "0901-06", Name: "Bravo & Sons"}}
+			two.Features.Resources = []resources.Resource_e{resources.IronOre}
+			return []*wxx.Hex{one, two}
+		}},
+		{name: "encounters", hexes: func() []*wxx.Hex {
+			one, two := hex(2, 2, terrain.Prairie), hex(3, 2, terrain.Prairie)
+			one.Features.Encounters = []*parser.Encounter_t{{TurnId: "0901-07", UnitId: "0138", Friendly: true}}
+			two.Features.Encounters = []*parser.Encounter_t{{TurnId: "0901-07", UnitId: "0249"}, {TurnId: "0901-07", UnitId: "0250e1"}}
+			return []*wxx.Hex{one, two}
+		}},
+		{name: "labels", hexes: func() []*wxx.Hex {
+			one, two := hex(2, 2, terrain.Prairie), hex(3, 2, terrain.Prairie)
+			one.Features.Label = &wxx.Label{Text: "Home"}
+			two.Features.Special = []*parser.Special_t{{Id: "keep", Name: "Keep", Directives: []*parser.Directive_t{{Kind: parser.NoteDirective, Value: "stone walls"}}}}
+			return []*wxx.Hex{one, two}
+		}, extra: func(w *wxx.WXX) {
+			w.AddAnnotation(wxx.Annotation{At: at(2, 2), Label: "camp"})
+		}},
+	} {
+		w, err := wxx.NewWXX()
+		if err != nil {
+			t.Fatal(err)
+		}
+		upperLeft, lowerRight := at(1, 1), at(1, 1)
+		for _, h := range tc.hexes() {
+			if err := w.MergeHex(h); err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			upperLeft.Column, upperLeft.Row = min(upperLeft.Column, h.RenderAt.Column), min(upperLeft.Row, h.RenderAt.Row)
+			lowerRight.Column, lowerRight.Row = max(lowerRight.Column, h.RenderAt.Column), max(lowerRight.Row, h.RenderAt.Row)
+		}
+		if tc.extra != nil {
+			tc.extra(w)
+		}
+		var buf bytes.Buffer
+		if err := w.Encode(context.Background(), &buf, "0901-07", upperLeft, lowerRight, wxx.RenderConfig{Deterministic: true}); err != nil {
+			t.Fatalf("%s: encode: %v", tc.name, err)
+		}
+		got, err := wxx.Decode(buf.Bytes())
+		if err != nil {
+			t.Fatalf("%s: decode: %v", tc.name, err)
+		}
+
+		path := filepath.Join("testdata", "golden", tc.name+".xml")
+		if *update {
+			if err := os.WriteFile(path, got, 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		wantOutline, err := outline(want)
+		if err != nil {
+			t.Fatalf("%s: golden: %v", tc.name, err)
+		}
+		gotOutline, err := outline(got)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if diffs := testkit.Diff(wantOutline, gotOutline, 40); len(diffs) != 0 {
+			t.Errorf("%s: map differs from %s\n%s", tc.name, path, strings.Join(diffs, "\n"))
+		}
+	}
+}
+
+// outline returns one line per element, attribute, and line of text in the map.
+// Attributes are sorted and numbers are normalized, so that only changes to the
+// structure or the values are reported.
+func outline(data []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	var b bytes.Buffer
+	var path []string
+	for {
+		token, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			path = append(path, token.Name.Local)
+			_, _ = fmt.Fprintf(&b, "%s\n", strings.Join(path, "/"))
+			attrs := token.Attr
+			sort.Slice(attrs, func(i, j int) bool {
+				return attrs[i].Name.Local < attrs[j].Name.Local
+			})
+			for _, attr := range attrs {
+				value := attr.Value
+				if f, err := strconv.ParseFloat(value, 64); err == nil {
+					value = strconv.FormatFloat(f, 'g', -1, 64)
+				}
+				_, _ = fmt.Fprintf(&b, "%s @%s=%q\n", strings.Join(path, "/"), attr.Name.Local, value)
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+		case xml.CharData:
+			for _, line := range strings.Split(string(token), "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					_, _ = fmt.Fprintf(&b, "%s %q\n", strings.Join(path, "/"), line)
+				}
+			}
+		case xml.Comment:
+			_, _ = fmt.Fprintf(&b, "%s <!--%s-->\n", strings.Join(path, "/"), strings.TrimSpace(string(token)))
+		}
+	}
+	return b.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+)
+
+// gridLabel is the "AA" to "ZZ" id of a grid, drawn in the grid's upper left corner.
+type gridLabel struct {
+	id string
+	at Point
+}
+
+// gridBoundaries traces the sides between hexes in different grids, along with a label
+// for each grid on the map. The offset is added to a render location to get the true
+// location. Only the hexes in the first tilesWide columns and tilesHigh rows are checked.
+func gridBoundaries(tilesWide, tilesHigh int, offset coords.Map, layout HexLayout) (lines [][]Point, labels []gridLabel) {
+	gridOf := func(c coords.Map) (row, column int) {
+		return (c.Row + offset.Row) / rowsPerGrid, (c.Column + offset.Column) / columnsPerGrid
+	}
+
+	var vertices []string
+	points := map[string]Point{}
+	links := map[string][]string{}
+	sides := map[[2]string]bool{}
+	for row := 0; row < tilesHigh; row++ {
+		for column := 0; column < tilesWide; column++ {
+			c := coords.Map{Column: column, Row: row}
+			gridRow, gridColumn := gridOf(c)
+			hex := layout.points(column, row)
+			if c.Row+offset.Row == gridRow*rowsPerGrid || row == 0 {
+				if c.Column+offset.Column == gridColumn*columnsPerGrid || column == 0 {
+					// upper left corner of the grid, or of the part of the grid that is on the map
+					labels = append(labels, gridLabel{
+						id: gridRowColumnToId(gridRow, gridColumn),
+						at: midpoint(hex[0], hex[2]),
+					})
+				}
+			}
+
+			for _, d := range direction.Directions {
+				n := c.Add(d)
+				if n.Column < 0 || n.Column >= tilesWide || n.Row < 0 || n.Row >= tilesHigh {
+					continue
+				}
+				if nRow, nColumn := gridOf(n); nRow == gridRow && nColumn == gridColumn {
+					continue
+				}
+				from, to := edgeEnds(d, hex)
+				a, b := vertexKey(from), vertexKey(to)
+				if b < a {
+					a, b = b, a
+				}
+				// each side is found from both hexes
+				if sides[[2]string{a, b}] {
+					continue
+				}
+				sides[[2]string{a, b}] = true
+				for _, v := range []Point{from, to} {
+					if _, ok := points[vertexKey(v)]; !ok {
+						points[vertexKey(v)] = v
+						vertices = append(vertices, vertexKey(v))
+					}
+				}
+				links[a] = append(links[a], b)
+				links[b] = append(links[b], a)
+			}
+		}
+	}
+
+	for _, path := range joinLinks(vertices, links) {
+		var line []Point
+		for _, v := range path {
+			line = append(line, points[v])
+		}
+		lines = append(lines, line)
+	}
+	return lines, labels
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"slices"
+	"testing"
+)
+
+func TestGridBoundaries(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	hex := func(column, row int) *wxx.Hex {
+		at := coords.Map{Column: column, Row: row}
+		return &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+	}
+	for _, tc := range []struct {
+		id     int
+		show   bool
+		from   coords.Map
+		to     coords.Map
+		lines  int
+		labels []string
+	}{
+		{1, false, coords.Map{Column: 25, Row: 5}, coords.Map{Column: 35, Row: 10}, 0, nil},
+		{2, true, coords.Map{Column: 5, Row: 5}, coords.Map{Column: 15, Row: 10}, 0, []string{"AA"}},
+		// the map crosses from grid AA into grid AB
+		{3, true, coords.Map{Column: 25, Row: 5}, coords.Map{Column: 35, Row: 10}, 1, []string{"AA", "AB"}},
+		// the map crosses from grid AA into grid BA
+		{4, true, coords.Map{Column: 5, Row: 15}, coords.Map{Column: 15, Row: 25}, 1, []string{"AA", "BA"}},
+	} {
+		cfg := wxx.RenderConfig{}
+		cfg.Show.Grid.Boundaries = tc.show
+		doc := document(t, []*wxx.Hex{hex(tc.from.Column, tc.from.Row), hex(tc.to.Column, tc.to.Row)}, nil, tc.from, tc.to, cfg)
+		if hasLayer(doc, "Tribenet Grids") != tc.show {
+			t.Errorf("%d: layer: want %v, got %v", tc.id, tc.show, !tc.show)
+		}
+		if got := shapesOn(doc, "Tribenet Grids"); got != tc.lines {
+			t.Errorf("%d: lines: want %d, got %d", tc.id, tc.lines, got)
+		}
+		got := labelsOn(doc, "Tribenet Grids")
+		slices.Sort(got)
+		if !slices.Equal(got, tc.labels) {
+			t.Errorf("%d: labels: want %q, got %q", tc.id, tc.labels, got)
+		}
+	}
+}
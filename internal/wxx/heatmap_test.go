@@ -0,0 +1,48 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"slices"
+	"testing"
+)
+
+func TestHeatmap(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	hex := func(column int, firstSeen string) *wxx.Hex {
+		at := coords.Map{Column: column, Row: 1}
+		h := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+		h.Features.FirstSeen = firstSeen
+		return h
+	}
+	for _, tc := range []struct {
+		id     int
+		hexes  []*wxx.Hex
+		colors []string // fill colors of the shaded hexes, from left to right
+	}{
+		{1, []*wxx.Hex{hex(1, ""), hex(2, "")}, nil},
+		// a single turn is red
+		{2, []*wxx.Hex{hex(1, "0901-01"), hex(2, "")}, []string{"1,0,0,1.0"}},
+		// the oldest turn is blue and the latest is red, no matter how far apart they are
+		{3, []*wxx.Hex{hex(1, "0901-01"), hex(2, "0903-12"), hex(3, "0901-01")}, []string{"0,0,1,1.0", "1,0,0,1.0", "0,0,1,1.0"}},
+		{4, []*wxx.Hex{hex(1, "0901-01"), hex(2, "0901-02"), hex(3, "0901-03")}, []string{"0,0,1,1.0", "0,1,0,1.0", "1,0,0,1.0"}},
+	} {
+		doc := document(t, tc.hexes, nil, coords.Map{Column: 1, Row: 1}, coords.Map{Column: 3, Row: 1}, wxx.RenderConfig{})
+		var got []string
+		for _, shape := range doc.Shapes {
+			if shape.MapLayer == "Tribenet Heatmap" {
+				got = append(got, shape.FillColor)
+			}
+		}
+		if !slices.Equal(got, tc.colors) {
+			t.Errorf("%d: colors: want %q, got %q", tc.id, tc.colors, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"testing"
+)
+
+// document merges the hexes into a new map, lets setup add anything else,
+// and returns the document for the map from upper left to lower right.
+func document(t *testing.T, hexes []*wxx.Hex, setup func(w *wxx.WXX), upperLeft, lowerRight coords.Map, cfg wxx.RenderConfig) *wxx.Document {
+	t.Helper()
+	w, err := wxx.NewWXX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hex := range hexes {
+		if err := w.MergeHex(hex); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if setup != nil {
+		setup(w)
+	}
+	cfg.Deterministic = true
+	doc, err := w.Document(context.Background(), "0901-07", upperLeft, lowerRight, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// shapesOn returns the number of shapes on the map layer.
+func shapesOn(doc *wxx.Document, layer string) (n int) {
+	for _, shape := range doc.Shapes {
+		if shape.MapLayer == layer {
+			n++
+		}
+	}
+	return n
+}
+
+// featuresOn returns the number of features on the map layer.
+func featuresOn(doc *wxx.Document, layer string) (n int) {
+	for _, feature := range doc.Features {
+		if feature.MapLayer == layer {
+			n++
+		}
+	}
+	return n
+}
+
+// labelsOn returns the text of the labels on the map layer.
+func labelsOn(doc *wxx.Document, layer string) (list []string) {
+	for _, label := range doc.Labels {
+		if label.MapLayer == layer {
+			list = append(list, label.Text)
+		}
+	}
+	return list
+}
+
+// hasLayer returns true if the map has the layer.
+func hasLayer(doc *wxx.Document, layer string) bool {
+	for _, l := range doc.Layers {
+		if l.Name == layer {
+			return true
+		}
+	}
+	return false
+}
@@ -60,6 +60,10 @@ func (w *WXX) MergeHex(hex *Hex) error {
 			log.Printf("grid: addTile: unknown terrain type %d %q", hex.Terrain, hex.Terrain.String())
 			panic(fmt.Sprintf("assert(hex.Terrain != %d)", hex.Terrain))
 		}
+		if w.elevations != nil {
+			t.Elevation = w.elevations[t.Terrain]
+		}
+		t.IsIcy = w.icy[t.Terrain]
 
 		w.tiles[hex.Location] = t
 	}
@@ -73,6 +77,7 @@ func (w *WXX) MergeHex(hex *Hex) error {
 
 	t.WasScouted = t.WasScouted || hex.WasScouted
 	t.WasVisited = t.WasVisited || hex.WasVisited
+	t.IsGMOnly = hex.IsGMOnly && !(t.WasScouted || t.WasVisited)
 	t.Features = hex.Features
 
 	return nil
@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"fmt"
+)
+
+// NoteLine is a line in the note for a feature.
+// Lines with the same kind, message, and turn are duplicates.
+type NoteLine struct {
+	Kind    string // what the line is about, for example "lost" or "warning"
+	Message string
+	TurnId  string // turn shown with the message, empty for lines without a turn
+}
+
+// String returns the message with the turn after it, "0138e1 vanished (0901-07)".
+func (l NoteLine) String() string {
+	if l.TurnId == "" {
+		return l.Message
+	}
+	return fmt.Sprintf("%s (%s)", l.Message, l.TurnId)
+}
+
+// NotePolicy controls how the lines of a note are written.
+// The zero value drops duplicate lines and keeps all the others.
+type NotePolicy struct {
+	TurnPrefix bool // start lines with their turn, "0901-07: 0138e1 vanished", instead of ending with it
+	Max        int  // if more than 0, keep this many lines and fold the rest into "and N more"
+}
+
+// Text returns the lines of the note. Duplicates are dropped, keeping the
+// first, so the lines stay in the order they were added.
+func (p NotePolicy) Text(lines []NoteLine) []string {
+	var text []string
+	seen := map[NoteLine]bool{}
+	for _, line := range lines {
+		if seen[line] {
+			continue
+		}
+		seen[line] = true
+		if p.TurnPrefix && line.TurnId != "" {
+			text = append(text, fmt.Sprintf("%s: %s", line.TurnId, line.Message))
+		} else {
+			text = append(text, line.String())
+		}
+	}
+	if p.Max > 0 && len(text) > p.Max {
+		text = append(text[:p.Max], fmt.Sprintf("and %d more", len(text)-p.Max))
+	}
+	return text
+}
+
+// noteLines returns the text as lines of the same kind without a turn.
+func noteLines(kind string, text ...string) []NoteLine {
+	lines := make([]NoteLine, 0, len(text))
+	for _, message := range text {
+		lines = append(lines, NoteLine{Kind: kind, Message: message})
+	}
+	return lines
+}
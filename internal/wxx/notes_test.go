@@ -0,0 +1,79 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestNotePolicy(t *testing.T) {
+	lost := func(message, turnId string) wxx.NoteLine {
+		return wxx.NoteLine{Kind: "lost", Message: message, TurnId: turnId}
+	}
+	lines := []wxx.NoteLine{
+		lost("0138e1 vanished", "0901-07"),
+		lost("0138e2 vanished", "0901-07"),
+		lost("0138e1 vanished", "0901-07"), // duplicate from an overlapping report
+		lost("0138e1 vanished", "0901-08"), // same message, later turn
+		{Kind: "warning", Message: "0138e1 vanished"},
+	}
+	for _, tc := range []struct {
+		id     int
+		policy wxx.NotePolicy
+		want   []string
+	}{
+		{1, wxx.NotePolicy{}, []string{"0138e1 vanished (0901-07)", "0138e2 vanished (0901-07)", "0138e1 vanished (0901-08)", "0138e1 vanished"}},
+		{2, wxx.NotePolicy{TurnPrefix: true}, []string{"0901-07: 0138e1 vanished", "0901-07: 0138e2 vanished", "0901-08: 0138e1 vanished", "0138e1 vanished"}},
+		{3, wxx.NotePolicy{Max: 2}, []string{"0138e1 vanished (0901-07)", "0138e2 vanished (0901-07)", "and 2 more"}},
+		{4, wxx.NotePolicy{Max: 4}, []string{"0138e1 vanished (0901-07)", "0138e2 vanished (0901-07)", "0138e1 vanished (0901-08)", "0138e1 vanished"}},
+	} {
+		got := tc.policy.Text(lines)
+		if strings.Join(got, "|") != strings.Join(tc.want, "|") {
+			t.Errorf("%d: want %q, got %q", tc.id, tc.want, got)
+		}
+	}
+}
+
+// the policy is applied to the notes written to the map.
+func TestNotePolicyInMap(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	at, next := coords.Map{Column: 4, Row: 4}, coords.Map{Column: 5, Row: 4}
+	w, err := wxx.NewWXX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, location := range []coords.Map{at, next} {
+		hex := &wxx.Hex{Location: location, RenderAt: location, Terrain: terrain.Prairie, WasVisited: true}
+		if location == at {
+			for _, unitId := range []string{"0138e1", "0138e2", "0138e1", "0138e3"} {
+				hex.Features.Lost = append(hex.Features.Lost, wxx.NoteLine{Kind: "lost", Message: unitId + " vanished", TurnId: "0901-07"})
+			}
+		}
+		if err := w.MergeHex(hex); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := w.Encode(context.Background(), &buf, "0901-07", at, next, wxx.RenderConfig{Deterministic: true, Notes: wxx.NotePolicy{TurnPrefix: true, Max: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := wxx.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"0901-07: 0138e1 vanished", "and 2 more"} {
+		if got := strings.Count(string(data), want); got != 1 {
+			t.Errorf("note: want one %q, got %d", want, got)
+		}
+	}
+}
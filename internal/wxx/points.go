@@ -27,6 +27,57 @@ func (p Point) Translate(t Point) Point {
 	}
 }
 
+// HexLayout is the size of the hexes in the Worldographer file.
+// The zero value is the layout of the Tribenet map.
+//
+// The hexes are always flat-topped columns, like the Tribenet map. A pointy-topped
+// (ROWS) map has no hex directly north or south of another, so it can't be drawn
+// with north at the top without changing the neighbors of the hexes.
+type HexLayout struct {
+	HexWidth  float64 // width of a hex at 100% zoom, defaults to DefaultHexWidth
+	HexHeight float64 // height of a hex at 100% zoom, defaults to DefaultHexHeight
+	ViewLevel string  // World (the default), Continent, Kingdom, or Province
+}
+
+// The hex orientation and view levels, spelled the way Worldographer spells them.
+const (
+	Columns   = "COLUMNS"
+	World     = "WORLD"
+	Continent = "CONTINENT"
+	Kingdom   = "KINGDOM"
+	Province  = "PROVINCE"
+)
+
+// DefaultHexWidth and DefaultHexHeight control the initial "zoom" on the map.
+const DefaultHexWidth, DefaultHexHeight = 46.18, 40.0
+
+// withDefaults returns the layout with the empty settings filled in.
+func (l HexLayout) withDefaults() (HexLayout, error) {
+	switch l.ViewLevel {
+	case "":
+		l.ViewLevel = World
+	case World, Continent, Kingdom, Province:
+	default:
+		return l, fmt.Errorf("wxx: unknown view level %q", l.ViewLevel)
+	}
+	if l.HexWidth < 0 || l.HexHeight < 0 {
+		return l, fmt.Errorf("wxx: hex size must not be negative")
+	}
+	if l.HexWidth == 0 {
+		l.HexWidth = DefaultHexWidth
+	}
+	if l.HexHeight == 0 {
+		l.HexHeight = DefaultHexHeight
+	}
+	return l, nil
+}
+
+// points returns the center point and vertices of the hexagon at the given column and row.
+// Tribenet hexes are flat-topped with the odd columns shifted down.
+func (l HexLayout) points(column, row int) [7]Point {
+	return coordsToPoints(column, row)
+}
+
 var (
 	// Define the offsets based on the flattened hexagon dimensions
 	flattenedHexOffsets = [6]Point{
@@ -86,6 +137,18 @@ func distance(p1, p2 Point) float64 {
 }
 
 func edgeCenter(edge direction.Direction_e, v [7]Point) Point {
+	from, to := edgeEnds(edge, v)
+	return midpoint(from, to)
+}
+
+// vertexKey returns a key for matching the vertices of neighboring hexes.
+// The vertices are computed separately for each hex, so the key is rounded.
+func vertexKey(p Point) string {
+	return fmt.Sprintf("%.1f,%.1f", p.X, p.Y)
+}
+
+// edgeEnds returns the vertices at the ends of the edge of the hex.
+func edgeEnds(edge direction.Direction_e, v [7]Point) (Point, Point) {
 	var from, to int
 	switch edge {
 	case direction.North:
@@ -103,7 +166,16 @@ func edgeCenter(edge direction.Direction_e, v [7]Point) Point {
 	default:
 		panic(fmt.Sprintf("assert(direction != %d)", edge))
 	}
-	return midpoint(v[from], v[to])
+	return v[from], v[to]
+}
+
+// bezier returns the point at t (0..1) on the quadratic curve from p1 to p2 with control point c.
+func bezier(p1, c, p2 Point, t float64) Point {
+	u := 1 - t
+	return Point{
+		X: u*u*p1.X + 2*u*t*c.X + t*t*p2.X,
+		Y: u*u*p1.Y + 2*u*t*c.Y + t*t*p2.Y,
+	}
 }
 
 func midpoint(p1, p2 Point) Point {
@@ -0,0 +1,183 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// MapFile is the part of a Worldographer file that we can compare:
+// the terrain of each tile and the features and labels on the map.
+type MapFile struct {
+	Terrain  map[coords.Map]string // terrain name for each tile that isn't blank
+	Features []MapItem
+	Labels   []MapItem
+}
+
+// MapItem is a feature or label, placed in the hex that contains it.
+type MapItem struct {
+	Layer string
+	Name  string // feature type, or the text of a label
+	Hex   coords.Map
+	At    Point
+}
+
+// rxOffset matches the comment that Create writes with the shift from
+// report coordinates to map coordinates.
+var rxOffset = regexp.MustCompile(`<!-- ottomap:offset column="(-?\d+)" row="(-?\d+)" -->`)
+
+// Decode returns the XML from a Worldographer file, which is gzipped UTF-16.
+func Decode(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(gz)
+	if err != nil {
+		return nil, err
+	}
+	bigEndian := true
+	if bytes.HasPrefix(raw, []byte{0xfe, 0xff}) {
+		raw = raw[2:]
+	} else if bytes.HasPrefix(raw, []byte{0xff, 0xfe}) {
+		raw, bigEndian = raw[2:], false
+	}
+	if len(raw)%2 != 0 {
+		return nil, fmt.Errorf("wxx: odd number of bytes in UTF-16 data")
+	}
+	units := make([]uint16, len(raw)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = binary.BigEndian.Uint16(raw[2*i:])
+		} else {
+			units[i] = binary.LittleEndian.Uint16(raw[2*i:])
+		}
+	}
+	return []byte(string(utf16.Decode(units))), nil
+}
+
+// Read decodes a Worldographer file. If the file was created by ottomap,
+// hexes are returned in report coordinates; otherwise they are map coordinates.
+func Read(data []byte) (*MapFile, error) {
+	text, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	type location struct {
+		X float64 `xml:"x,attr"`
+		Y float64 `xml:"y,attr"`
+	}
+	var doc struct {
+		Orientation string `xml:"hexOrientation,attr"`
+		TerrainMap  string `xml:"terrainmap"`
+		Tiles       struct {
+			Rows []string `xml:"tilerow"`
+		} `xml:"tiles"`
+		Features []struct {
+			Type     string   `xml:"type,attr"`
+			Layer    string   `xml:"mapLayer,attr"`
+			Location location `xml:"location"`
+		} `xml:"features>feature"`
+		Labels []struct {
+			Layer    string   `xml:"mapLayer,attr"`
+			Location location `xml:"location"`
+			Text     string   `xml:",chardata"`
+		} `xml:"labels>label"`
+	}
+	d := xml.NewDecoder(bytes.NewReader(text))
+	// the text has already been converted from the UTF-16 in the header
+	d.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	if err := d.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("wxx: %w", err)
+	}
+	if doc.Orientation != "" && doc.Orientation != Columns {
+		return nil, fmt.Errorf("wxx: %s orientation is not supported", doc.Orientation)
+	}
+
+	// the terrain map is a list of name and index pairs
+	terrains := map[int]string{}
+	fields := strings.Split(doc.TerrainMap, "\t")
+	for n := 0; n+1 < len(fields); n += 2 {
+		index, err := strconv.Atoi(fields[n+1])
+		if err != nil {
+			return nil, fmt.Errorf("wxx: terrainmap: %q: %w", fields[n+1], err)
+		}
+		terrains[index] = fields[n]
+	}
+
+	var offset coords.Map
+	if match := rxOffset.FindSubmatch(text); match != nil {
+		offset.Column, _ = strconv.Atoi(string(match[1]))
+		offset.Row, _ = strconv.Atoi(string(match[2]))
+	}
+
+	m := &MapFile{Terrain: map[coords.Map]string{}}
+	// each tile row is a column of tiles.
+	for column, tileRow := range doc.Tiles.Rows {
+		for row, line := range strings.Split(strings.Trim(tileRow, "\n"), "\n") {
+			index, err := strconv.Atoi(strings.SplitN(line, "\t", 2)[0])
+			if err != nil {
+				return nil, fmt.Errorf("wxx: tile %d, %d: %q: %w", column, row, line, err)
+			} else if index == 0 {
+				continue // blank tile
+			}
+			name, ok := terrains[index]
+			if !ok {
+				return nil, fmt.Errorf("wxx: tile %d, %d: unknown terrain %d", column, row, index)
+			}
+			m.Terrain[shift(coords.Map{Column: column, Row: row}, offset)] = name
+		}
+	}
+	hexOf := func(at Point) coords.Map {
+		return shift(pointToCoords(at), offset)
+	}
+	for _, f := range doc.Features {
+		at := Point{X: f.Location.X, Y: f.Location.Y}
+		m.Features = append(m.Features, MapItem{Layer: f.Layer, Name: f.Type, Hex: hexOf(at), At: at})
+	}
+	for _, l := range doc.Labels {
+		at := Point{X: l.Location.X, Y: l.Location.Y}
+		m.Labels = append(m.Labels, MapItem{Layer: l.Layer, Name: strings.TrimSpace(l.Text), Hex: hexOf(at), At: at})
+	}
+	return m, nil
+}
+
+// shift moves the hex by the offset that Create recorded.
+func shift(hex, offset coords.Map) coords.Map {
+	return coords.Map{Column: hex.Column + offset.Column, Row: hex.Row + offset.Row}
+}
+
+// pointToCoords returns the column and row of the hex that contains the point.
+// It is the inverse of coordsToPoints.
+func pointToCoords(p Point) coords.Map {
+	// start with a guess and then pick the nearest center around it
+	column := int(math.Round((p.X - 150) / 225))
+	row := int(math.Round((p.Y - 150) / 300))
+	best, bestDistance := coords.Map{}, math.Inf(1)
+	for c := column - 1; c <= column+1; c++ {
+		for r := row - 1; r <= row+1; r++ {
+			if c < 0 || r < 0 {
+				continue
+			}
+			center := coordsToPoints(c, r)[0]
+			if distance := math.Hypot(p.X-center.X, p.Y-center.Y); distance < bestDistance {
+				best, bestDistance = coords.Map{Column: c, Row: r}, distance
+			}
+		}
+	}
+	return best
+}
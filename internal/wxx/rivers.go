@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"sort"
+)
+
+// waterway is a river stitched together from the hex sides that share an end point.
+type waterway struct {
+	name   string
+	points []Point
+	banks  []coords.Map // true locations of the hexes that the river borders, sorted
+}
+
+// riverWaterways stitches river edges into waterways.
+// A side is shared by two hexes, so a river reported from both banks is only stitched once.
+//
+// Sides drawn with a ford gap are left out and the caller draws them as before.
+// The sides that are stitched are returned so that the caller doesn't draw them again.
+//
+// A waterway takes the name of the first bank, in grid order, that has a name.
+// Waterways without a name are numbered.
+func riverWaterways(allTiles [][]*Tile, layout HexLayout, fordsAsPills bool, names map[coords.Map]string) (waterways []*waterway, joined map[coords.Map]map[direction.Direction_e]bool) {
+	var vertices []string
+	points := map[string]Point{}
+	links := map[string][]string{}
+	banks := map[[2]string]map[coords.Map]bool{}
+	joined = map[coords.Map]map[direction.Direction_e]bool{}
+	for _, row := range allTiles {
+		for _, t := range row {
+			if t == nil {
+				continue
+			}
+			is := func(edges []direction.Direction_e, d direction.Direction_e) bool {
+				for _, dir := range edges {
+					if dir == d {
+						return true
+					}
+				}
+				return false
+			}
+			hex := layout.points(t.RenderAt.Column, t.RenderAt.Row)
+			for _, d := range direction.Directions {
+				isFord, isCanal := is(t.Features.Edges.Ford, d), is(t.Features.Edges.Canal, d)
+				// same test as the writer; the report says "ford" for "river" edges sometimes
+				if !(is(t.Features.Edges.River, d) || (isFord && !isCanal)) {
+					continue
+				} else if isFord && !fordsAsPills {
+					continue
+				}
+				if joined[t.RenderAt] == nil {
+					joined[t.RenderAt] = map[direction.Direction_e]bool{}
+				}
+				joined[t.RenderAt][d] = true
+
+				from, to := edgeEnds(d, hex)
+				a, b := vertexKey(from), vertexKey(to)
+				if b < a {
+					a, b = b, a
+				}
+				side := [2]string{a, b}
+				if banks[side] == nil {
+					banks[side] = map[coords.Map]bool{}
+					for _, v := range []Point{from, to} {
+						if _, ok := points[vertexKey(v)]; !ok {
+							points[vertexKey(v)] = v
+							vertices = append(vertices, vertexKey(v))
+						}
+					}
+					links[a] = append(links[a], b)
+					links[b] = append(links[b], a)
+				}
+				banks[side][t.Location] = true
+			}
+		}
+	}
+
+	unnamed := 0
+	for _, path := range joinLinks(vertices, links) {
+		ww := &waterway{}
+		onBank := map[coords.Map]bool{}
+		for n, v := range path {
+			ww.points = append(ww.points, points[v])
+			if n == 0 {
+				continue
+			}
+			side := [2]string{path[n-1], v}
+			if side[1] < side[0] {
+				side[0], side[1] = side[1], side[0]
+			}
+			for location := range banks[side] {
+				if !onBank[location] {
+					onBank[location] = true
+					ww.banks = append(ww.banks, location)
+				}
+			}
+		}
+		sort.Slice(ww.banks, func(i, j int) bool {
+			return ww.banks[i].GridString() < ww.banks[j].GridString()
+		})
+		for _, location := range ww.banks {
+			if name, ok := names[location]; ok {
+				ww.name = name
+				break
+			}
+		}
+		if ww.name == "" {
+			unnamed++
+			ww.name = fmt.Sprintf("River %d", unnamed)
+		}
+		waterways = append(waterways, ww)
+	}
+
+	return waterways, joined
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"slices"
+	"testing"
+)
+
+func TestJoinRivers(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	at := func(row int) coords.Map { return coords.Map{Column: 3, Row: row} }
+	hex := func(location coords.Map, rivers ...direction.Direction_e) *wxx.Hex {
+		h := &wxx.Hex{Location: location, RenderAt: location, Terrain: terrain.Prairie, WasVisited: true}
+		h.Features.Edges.River = rivers
+		return h
+	}
+	ford := func(location coords.Map, fords ...direction.Direction_e) *wxx.Hex {
+		h := hex(location)
+		h.Features.Edges.Ford = fords
+		return h
+	}
+	// a river runs down the east side of three hexes
+	east := []*wxx.Hex{
+		hex(at(2), direction.NorthEast, direction.SouthEast),
+		hex(at(3), direction.NorthEast, direction.SouthEast),
+		hex(at(4), direction.NorthEast, direction.SouthEast),
+	}
+	for _, tc := range []struct {
+		id     int
+		hexes  []*wxx.Hex
+		names  map[coords.Map]string
+		pills  bool
+		points []int    // number of points in each waterway
+		labels []string // name of each waterway
+	}{
+		{id: 1, hexes: []*wxx.Hex{hex(at(3), direction.SouthEast)}, points: []int{2}, labels: []string{"River 1"}},
+		// the side is reported from both banks and is only stitched once
+		{id: 2, hexes: []*wxx.Hex{hex(at(3), direction.SouthEast), hex(at(3).Add(direction.SouthEast), direction.NorthWest)}, points: []int{2}, labels: []string{"River 1"}},
+		{id: 3, hexes: east, points: []int{7}, labels: []string{"River 1"}},
+		// the first bank in grid order with a name names the waterway
+		{id: 4, hexes: east, names: map[coords.Map]string{at(4): "Blue", at(3): "Red"}, points: []int{7}, labels: []string{"Red"}},
+		// a name is only taken from a bank that reported the river
+		{id: 5, hexes: east, names: map[coords.Map]string{at(3).Add(direction.SouthEast): "Green"}, points: []int{7}, labels: []string{"River 1"}},
+		{id: 6, hexes: append(slices.Clone(east), hex(at(3).Add(direction.SouthEast), direction.NorthWest)), names: map[coords.Map]string{at(3).Add(direction.SouthEast): "Green"}, points: []int{7}, labels: []string{"Green"}},
+		{id: 7, hexes: []*wxx.Hex{hex(at(2), direction.SouthEast), hex(at(6), direction.SouthEast)}, points: []int{2, 2}, labels: []string{"River 1", "River 2"}},
+		// fords drawn with a gap aren't stitched; fords drawn as pills are, and the pill is drawn after the waterway
+		{id: 8, hexes: []*wxx.Hex{ford(at(3), direction.SouthEast)}, points: []int{2, 2}},
+		{id: 9, hexes: []*wxx.Hex{ford(at(3), direction.SouthEast)}, pills: true, points: []int{2, 2}, labels: []string{"River 1"}},
+	} {
+		w, err := wxx.NewWXX(wxx.WithRiverNames(tc.names))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, hex := range tc.hexes {
+			if err := w.MergeHex(hex); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cfg := wxx.RenderConfig{JoinRivers: true, FordsAsPills: tc.pills, Deterministic: true}
+		doc, err := w.Document(context.Background(), "0901-07", coords.Map{Column: 1, Row: 1}, coords.Map{Column: 7, Row: 9}, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var points []int
+		for _, shape := range doc.Shapes {
+			if shape.MapLayer == "Above Terrain" {
+				points = append(points, len(shape.Points))
+			}
+		}
+		if !slices.Equal(points, tc.points) {
+			t.Errorf("%d: waterways: want %v, got %v", tc.id, tc.points, points)
+		}
+		// each waterway is marked with a feature labeled with its name
+		var labels []string
+		for _, f := range doc.Features {
+			if f.MapLayer == "Labels" && f.Label != nil {
+				labels = append(labels, f.Label.Text)
+			}
+		}
+		if !slices.Equal(labels, tc.labels) {
+			t.Errorf("%d: labels: want %q, got %q", tc.id, tc.labels, labels)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+)
+
+// roadPaths joins stone road edges into paths that run from hex center to hex center.
+// An edge is joined only if the hexes on both sides report the road; the edges that
+// are joined are returned so that the caller doesn't draw them again as stubs.
+func roadPaths(allTiles [][]*Tile) (paths [][]coords.Map, joined map[coords.Map]map[direction.Direction_e]bool) {
+	hasRoad := func(t *Tile, d direction.Direction_e) bool {
+		for _, dir := range t.Features.Edges.StoneRoad {
+			if dir == d {
+				return true
+			}
+		}
+		return false
+	}
+
+	// collect the links in row and column order so that the paths are always the same
+	var hexes []coords.Map
+	links := map[coords.Map][]coords.Map{}
+	joined = map[coords.Map]map[direction.Direction_e]bool{}
+	for _, row := range allTiles {
+		for _, t := range row {
+			if t == nil {
+				continue
+			}
+			for _, d := range direction.Directions {
+				if !hasRoad(t, d) {
+					continue
+				}
+				n := tileAt(allTiles, t.RenderAt.Add(d))
+				if n == nil || !hasRoad(n, d.Opposite()) {
+					continue
+				}
+				if joined[t.RenderAt] == nil {
+					joined[t.RenderAt] = map[direction.Direction_e]bool{}
+					hexes = append(hexes, t.RenderAt)
+				}
+				joined[t.RenderAt][d] = true
+				links[t.RenderAt] = append(links[t.RenderAt], n.RenderAt)
+			}
+		}
+	}
+
+	return joinLinks(hexes, links), joined
+}
+
+// tileAt returns the tile at the render location, or nil if there isn't one.
+func tileAt(allTiles [][]*Tile, c coords.Map) *Tile {
+	if c.Row < 0 || c.Row >= len(allTiles) || c.Column < 0 || c.Column >= len(allTiles[c.Row]) {
+		return nil
+	}
+	return allTiles[c.Row][c.Column]
+}
+
+// joinLinks follows the links between nodes to build paths. Paths start and stop
+// at nodes that don't have exactly two links, so they end where a line ends or
+// branches. A loop is returned as a single path that starts and ends at the same node.
+// The nodes must be in a stable order for the paths to be the same on every run.
+func joinLinks[K comparable](nodes []K, links map[K][]K) (paths [][]K) {
+	used := map[[2]K]bool{}
+	walk := func(from, to K) []K {
+		path := []K{from}
+		for {
+			used[[2]K{from, to}], used[[2]K{to, from}] = true, true
+			path = append(path, to)
+			if len(links[to]) != 2 {
+				return path
+			}
+			var next K
+			found := false
+			for _, n := range links[to] {
+				if !used[[2]K{to, n}] {
+					next, found = n, true
+					break
+				}
+			}
+			if !found {
+				return path
+			}
+			from, to = to, next
+		}
+	}
+
+	// start at the ends and branches first, then pick up the loops
+	for _, loops := range []bool{false, true} {
+		for _, node := range nodes {
+			if (len(links[node]) == 2) != loops {
+				continue
+			}
+			for _, n := range links[node] {
+				if !used[[2]K{node, n}] {
+					paths = append(paths, walk(node, n))
+				}
+			}
+		}
+	}
+
+	return paths
+}
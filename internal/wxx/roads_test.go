@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"slices"
+	"testing"
+)
+
+func TestJoinRoads(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	// a road runs south through three hexes and the last hex has a stub to the south-east
+	hex := func(row int, roads ...direction.Direction_e) *wxx.Hex {
+		at := coords.Map{Column: 3, Row: row}
+		h := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+		h.Features.Edges.StoneRoad = roads
+		return h
+	}
+	hexes := []*wxx.Hex{
+		hex(2, direction.South),
+		hex(3, direction.North, direction.South),
+		hex(4, direction.North, direction.SouthEast),
+	}
+	for _, tc := range []struct {
+		id     int
+		join   bool
+		points []int // number of points in each road shape
+	}{
+		{1, false, []int{2, 2, 2, 2, 2}},
+		{2, true, []int{3, 2}},
+	} {
+		doc := document(t, hexes, nil, coords.Map{Column: 1, Row: 1}, coords.Map{Column: 5, Row: 5}, wxx.RenderConfig{JoinRoads: tc.join})
+		var got []int
+		for _, shape := range doc.Shapes {
+			if shape.MapLayer == "Above Terrain" {
+				got = append(got, len(shape.Points))
+			}
+		}
+		if !slices.Equal(got, tc.points) {
+			t.Errorf("%d: roads: want %v, got %v", tc.id, tc.points, got)
+		}
+	}
+}
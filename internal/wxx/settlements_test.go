@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"bytes"
+	"context"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestSettlementOwners(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	w, err := wxx.NewWXX()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := coords.Map{Column: 4, Row: 4}
+	hex := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+	hex.Features.Settlements = []*parser.Settlement_t{{TurnId: "0901-03", Name: "Rivertown", Owner: "0250", History: []*parser.Ownership_t{
+		{TurnId: "0901-03", Clan: "0138"},
+		{TurnId: "0901-05", Clan: "0250", Captured: true},
+	}}}
+	if err := w.MergeHex(hex); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := w.Encode(context.Background(), &buf, "0901-07", at, at, wxx.RenderConfig{Deterministic: true, ClanColors: map[string]string{"0250": "#ff0000"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := wxx.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	xml := string(data)
+	// the label is in the color of the clan that holds the settlement now
+	for _, want := range []string{
+		`color="1,0,0,1.0" outlineColor`,
+		"Rivertown held by 0138 (0901-03)",
+		"Rivertown captured by 0250 (0901-05)",
+	} {
+		if !strings.Contains(xml, want) {
+			t.Errorf("want %q in map", want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestStackUnits(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	at := coords.Map{Column: 2, Row: 2}
+	h := &wxx.Hex{Location: at, RenderAt: at, Terrain: terrain.Prairie, WasVisited: true}
+	for _, unitId := range []parser.UnitId_t{"0138", "0138e1", "0138e2"} {
+		h.Features.Encounters = append(h.Features.Encounters, &parser.Encounter_t{TurnId: "0901-07", UnitId: unitId, Friendly: true})
+	}
+	for _, unitId := range []parser.UnitId_t{"0249", "0249e1", "0249e2"} {
+		h.Features.Encounters = append(h.Features.Encounters, &parser.Encounter_t{TurnId: "0901-07", UnitId: unitId})
+	}
+	h.Features.Contacts = []wxx.Contact{{UnitId: "0350", Age: 2}, {UnitId: "0350e1", Age: 0}, {UnitId: "0351", Age: 4}}
+
+	for _, tc := range []struct {
+		id                         int
+		stack                      int
+		clan, encounters, contacts string
+	}{
+		{1, 0, "CLAN", "0249", "XXXX ~0"},
+		{2, 2, "x3", "x3", "x3 ~0"},
+		// the count is only used for more units than the threshold
+		{3, 3, "CLAN", "0249", "XXXX ~0"},
+	} {
+		doc := document(t, []*wxx.Hex{h}, nil, at, at, wxx.RenderConfig{StackUnits: tc.stack})
+		for _, layer := range []struct {
+			name string
+			want string
+		}{
+			{"Tribenet Clan Units", tc.clan},
+			{"Tribenet Encounters", tc.encounters},
+			{"Tribenet Contacts", tc.contacts},
+		} {
+			var got []string
+			for _, feature := range doc.Features {
+				if feature.MapLayer == layer.name && feature.Label != nil {
+					got = append(got, feature.Label.Text)
+				}
+			}
+			if len(got) != 1 || got[0] != layer.want {
+				t.Errorf("%d: %s: want [%s], got %q", tc.id, layer.name, layer.want, got)
+			}
+		}
+	}
+}
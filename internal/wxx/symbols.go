@@ -0,0 +1,43 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx
+
+import (
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/units"
+)
+
+// DefaultUnitSymbols is the Worldographer feature type for the unit markers, by type of unit.
+// RenderConfig.UnitSymbols overrides it.
+var DefaultUnitSymbols = map[units.Type_e]string{
+	units.Unknown:  "Military Ancient Soldier",
+	units.Clan:     "Military Ancient Soldier",
+	units.Tribe:    "Military Ancient Soldier",
+	units.Courier:  "Military Ancient Cavalry",
+	units.Element:  "Military Ancient Soldier",
+	units.Fleet:    "Military Sailing Ship",
+	units.Garrison: "Military Tower",
+}
+
+// unitSymbol returns the feature type for a marker that stands for the units.
+// Units of different types share the symbol for an unknown unit.
+func unitSymbol(symbols map[units.Type_e]string, ids ...parser.UnitId_t) string {
+	kind := units.Unknown
+	for n, id := range ids {
+		if t := units.TypeOf(string(id)); n == 0 {
+			kind = t
+		} else if t != kind {
+			kind = units.Unknown
+			break
+		}
+	}
+	if symbol, ok := symbols[kind]; ok && symbol != "" {
+		return symbol
+	}
+	return DefaultUnitSymbols[kind]
+}
+
+// isStacked returns true if a marker for this many units should show the count instead of a name.
+func isStacked(threshold, count int) bool {
+	return threshold > 0 && count > threshold
+}
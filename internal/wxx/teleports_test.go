@@ -0,0 +1,45 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package wxx_test
+
+import (
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"log"
+	"testing"
+)
+
+func TestTeleports(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	from, to := coords.Map{Column: 2, Row: 2}, coords.Map{Column: 6, Row: 5}
+	hexes := []*wxx.Hex{
+		{Location: from, RenderAt: from, Terrain: terrain.Prairie, WasVisited: true},
+		{Location: to, RenderAt: to, Terrain: terrain.Prairie, WasVisited: true},
+	}
+	for _, tc := range []struct {
+		id        int
+		teleports []wxx.Teleport
+		want      int
+	}{
+		{1, nil, 0},
+		// each jump is an arc of 12 dashes
+		{2, []wxx.Teleport{{UnitId: "0138e1", From: from, To: to}}, 12},
+		{3, []wxx.Teleport{{UnitId: "0138e1", From: from, To: to}, {UnitId: "0138e2", From: to, To: from}}, 24},
+	} {
+		doc := document(t, hexes, func(w *wxx.WXX) {
+			for _, teleport := range tc.teleports {
+				w.AddTeleport(teleport)
+			}
+		}, from, to, wxx.RenderConfig{})
+		if !hasLayer(doc, "Tribenet Teleports") {
+			t.Errorf("%d: want teleports layer", tc.id)
+		}
+		if got := shapesOn(doc, "Tribenet Teleports"); got != tc.want {
+			t.Errorf("%d: dashes: want %d, got %d", tc.id, tc.want, got)
+		}
+	}
+}
@@ -17,6 +17,7 @@ type Hex struct {
 	Terrain    terrain.Terrain_e
 	WasScouted bool
 	WasVisited bool
+	IsGMOnly   bool // true if the hex should be hidden from players
 	Features   Features
 }
 
@@ -76,12 +77,62 @@ type Features struct {
 	CoordsLabel  string
 	NumbersLabel string
 
-	IsOrigin    bool // true for the clan's origin hex
+	IsOrigin    bool   // true for the clan's origin hex
+	IsReachable bool   // true if the hex is in the reachability overlay
+	FirstSeen   string // turn the hex was first reported, set only for the exploration heatmap
+	IsChanged   bool   // true if the latest turn added something to the hex, set only for the changes layer
 	Label       *Label
-	Encounters  []*parser.Encounter_t // other units in this tile
+	Contacts    []Contact                  // last known positions of foreign units
+	Lost        []NoteLine                 // clan units and scouts that vanished in this tile
+	Encounters  []*parser.Encounter_t      // other units in this tile
+	Inventory   map[parser.UnitId_t]string // inventory of clan units in this tile
 	Resources   []resources.Resource_e
 	Settlements []*parser.Settlement_t // name of settlement
 	Special     []*parser.Special_t    // any special hex name
+	Warnings    []string               // data that was dropped because it could not be rendered
+}
+
+// Teleport is a "Goes to" jump by a unit.
+// The locations are render coordinates, not the coordinates from the turn report.
+type Teleport struct {
+	UnitId parser.UnitId_t
+	From   coords.Map
+	To     coords.Map
+}
+
+// UnitHistory is the path a unit took over several turns.
+// The locations are render coordinates, not the coordinates from the turn report.
+type UnitHistory struct {
+	UnitId parser.UnitId_t
+	Turns  []UnitTurn
+}
+
+// UnitTurn is the hexes a unit passed through in a single turn, starting with the hex it started in.
+type UnitTurn struct {
+	TurnId string
+	Path   []coords.Map
+}
+
+// Annotation is a player's note, label, or icon for a hex.
+type Annotation struct {
+	At    coords.Map // render location
+	Label string
+	Note  string
+	Icon  string // feature type; if empty, only the label is drawn
+	Color string // "#rrggbb" for the icon, optional
+}
+
+// Region is a named territory shaded on the map.
+type Region struct {
+	Name  string
+	Color string       // "#rrggbb"
+	Hexes []coords.Map // render locations
+}
+
+// Contact is the last known position of a foreign unit.
+type Contact struct {
+	UnitId parser.UnitId_t
+	Age    int // number of turns since the unit was last seen
 }
 
 type Resources struct {
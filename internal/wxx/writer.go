@@ -5,31 +5,70 @@ package wxx
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/binary"
+	"encoding/xml"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/playbymail/ottomap/internal/coords"
 	"github.com/playbymail/ottomap/internal/direction"
+	"github.com/playbymail/ottomap/internal/parser"
 	"github.com/playbymail/ottomap/internal/resources"
 	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/units"
+	"io"
 	"log"
 	"os"
+	"sort"
 	"strings"
 	"unicode/utf16"
 	"unicode/utf8"
 )
 
+// featureData is the color and width of a line on the map.
+type featureData struct {
+	R, G, B, Width float64
+}
+
+// color returns the color of the line for Worldographer.
+func (d featureData) color() string {
+	return rgba(d.R, d.G, d.B)
+}
+
 type RenderConfig struct {
-	FordsAsPills bool // if true, draw ford icons as pills
-	Show         struct {
+	FordsAsPills  bool                    // if true, draw ford icons as pills
+	JoinRivers    bool                    // if true, draw rivers as named waterways that run across hexes
+	JoinRoads     bool                    // if true, draw stone roads as paths from hex center to hex center
+	Deterministic bool                    // if true, feature ids are sequential so that the output can be compared between runs
+	Legend        *Legend                 // if set, draw a legend below the map
+	ClanColors    map[string]string       // "#rrggbb" for units by clan id; other clans get colors from ClanPalette
+	UnitSymbols   map[units.Type_e]string // feature type for unit markers by type of unit, overriding DefaultUnitSymbols
+	StackUnits    int                     // if more than this many units share a marker, label it with the count; 0 never does
+	Encounters    EncounterPolicy         // which turns of encounters get unit markers
+	Notes         NotePolicy              // how the lines of the notes are written
+	Hexes         HexLayout               // size and view level of the hexes
+	Show          struct {
 		Grid struct {
-			Centers bool
-			Coords  bool
-			Numbers bool
+			Boundaries bool // if true, outline each 30 by 21 grid and label it with its "AA" to "ZZ" id
+			Centers    bool
+			Coords     bool
+			Numbers    bool
 		}
+		Coastlines bool     // if true, trace smoothed coastlines between water and land
+		Weather    []string // if set, list the season and weather for each turn in the corner of the map
+		Warnings   bool     // if true, mark hexes with dropped data with a red "!"
 	}
 }
 
+// Legend is drawn below the map.
+type Legend struct {
+	Title    string
+	Terrain  bool     // show a swatch for each terrain on the map
+	Edges    bool     // show the styles of rivers, canals, fords, passes, and roads
+	Units    bool     // show the clan unit and encounter markers
+	Metadata []string // lines of text, for example the clan and the turns
+}
+
 type FeatureNotes struct {
 	Notes map[string]*FeatureNote
 }
@@ -37,13 +76,79 @@ type FeatureNotes struct {
 type FeatureNote struct {
 	Id     string // uuid of the feature
 	Title  string
-	Text   []string
+	Lines  []NoteLine
 	Origin Point // origin of the feature
 }
 
-func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Map, cfg RenderConfig) error {
+// Create writes the map to a Worldographer file.
+// It stops with the context's error, without writing the file, if the context is cancelled.
+func (w *WXX) Create(ctx context.Context, path string, turnId string, upperLeft, lowerRight coords.Map, cfg RenderConfig) error {
+	var buf bytes.Buffer
+	if err := w.Encode(ctx, &buf, turnId, upperLeft, lowerRight, cfg); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// Encode writes the map to out as a compressed Worldographer file.
+// Nothing is written if the context is cancelled.
+func (w *WXX) Encode(ctx context.Context, out io.Writer, turnId string, upperLeft, lowerRight coords.Map, cfg RenderConfig) error {
+	doc, err := w.Document(ctx, turnId, upperLeft, lowerRight, cfg)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<?xml version='1.0' encoding='utf-16'?>\n")
+	if err := xml.NewEncoder(&buf).Encode(doc); err != nil {
+		return fmt.Errorf("wxx: create: %w", err)
+	}
+	buf.WriteByte('\n')
+	src := buf.Bytes()
+
+	// convert the source from UTF-8 to UTF-16
+	var buf16 bytes.Buffer
+	buf16.Write([]byte{0xfe, 0xff}) // write the BOM
+	for len(src) > 0 {
+		// extract next rune from the source
+		r, w := utf8.DecodeRune(src)
+		if r == utf8.RuneError {
+			return fmt.Errorf("invalid utf8 data")
+		}
+		// consume that rune
+		src = src[w:]
+		// convert the rune to UTF-16 and write it to the results
+		for _, v := range utf16.Encode([]rune{r}) {
+			if err := binary.Write(&buf16, binary.BigEndian, v); err != nil {
+				return err
+			}
+		}
+	}
+
+	// convert the UTF-16 to a gzip stream
+	var bufGZ bytes.Buffer
+	gz := gzip.NewWriter(&bufGZ)
+	if _, err := gz.Write(buf16.Bytes()); err != nil {
+		return err
+	} else if err = gz.Close(); err != nil {
+		return err
+	}
+
+	// write the compressed data to the output
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("wxx: create: %w", err)
+	}
+	if _, err := out.Write(bufGZ.Bytes()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Document returns the Worldographer XML for the map.
+// It stops with the context's error if the context is cancelled.
+func (w *WXX) Document(ctx context.Context, turnId string, upperLeft, lowerRight coords.Map, cfg RenderConfig) (*Document, error) {
 	if len(w.tiles) == 0 {
-		return fmt.Errorf("wxx: create: no tiles")
+		return nil, fmt.Errorf("wxx: create: no tiles")
 	}
 	log.Printf("wxx: create: %d tiles\n", len(w.tiles))
 
@@ -57,10 +162,18 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 		Notes: make(map[string]*FeatureNote),
 	}
 
-	var err error
+	layout, err := cfg.Hexes.withDefaults()
+	if err != nil {
+		return nil, err
+	}
 
-	type featureData struct {
-		R, G, B, Width float64
+	newId := uuid.NewString
+	if cfg.Deterministic {
+		var n int
+		newId = func() string {
+			n++
+			return fmt.Sprintf("00000000-0000-4000-8000-%012d", n)
+		}
 	}
 
 	// const canalWidth, riverWidth = 0.0625, 0.0625
@@ -80,6 +193,40 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 		R: 0.7019608020782471, G: 0.7019608020782471, B: 0.7019608020782471, Width: 0.08,
 	}
 
+	coastlineData := featureData{
+		R: 0.2, G: 0.3, B: 0.5, Width: 0.05,
+	}
+
+	gridLineData := featureData{
+		R: 0.8, G: 0.0, B: 0.0, Width: 0.1,
+	}
+
+	unitHistoryData := featureData{
+		R: 0.9, G: 0.5, B: 0.0, Width: 0.06,
+	}
+
+	// hexes that changed in the latest turn are ringed in magenta
+	changesData := featureData{
+		R: 1.0, G: 0.0, B: 1.0, Width: 0.08,
+	}
+
+	teleportData := featureData{
+		R: 0.6, G: 0.2, B: 0.8, Width: 0.08,
+	}
+
+	// reachable hexes are shaded with a translucent green
+	reachableData := struct {
+		R, G, B, Opacity float64
+	}{
+		R: 0.0, G: 0.8, B: 0.2, Opacity: 0.35,
+	}
+
+	// the exploration heatmap is translucent so that the terrain shows through
+	const heatmapOpacity = 0.4
+
+	// territories are shaded lightly so that the terrain shows through
+	const territoryOpacity = 0.3
+
 	type niceLabel struct {
 		OffsetFromCenter Point
 		R, G, B          float64
@@ -139,6 +286,183 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 		allTiles[t.RenderAt.Row][t.RenderAt.Column] = t
 	}
 
+	// the heatmap colors are spread evenly over the turns that hexes were first reported in
+	heatmap := map[string]int{}
+	var heatmapTurns []string
+	for _, t := range w.tiles {
+		if t.Features.FirstSeen != "" {
+			if _, ok := heatmap[t.Features.FirstSeen]; !ok {
+				heatmap[t.Features.FirstSeen] = 0
+				heatmapTurns = append(heatmapTurns, t.Features.FirstSeen)
+			}
+		}
+	}
+	sort.Strings(heatmapTurns)
+	for n, turnId := range heatmapTurns {
+		heatmap[turnId] = n
+	}
+
+	// the layer for lost units is only added when a unit has vanished
+	hasLost := false
+	for _, t := range w.tiles {
+		if len(t.Features.Lost) != 0 {
+			hasLost = true
+			break
+		}
+	}
+
+	// the changes layer is only added when the latest turn changed a hex
+	hasChanges := false
+	for _, t := range w.tiles {
+		if t.Features.IsChanged {
+			hasChanges = true
+			break
+		}
+	}
+
+	// every tile is shifted by the same offset, so any tile gives us the true location.
+	var offset coords.Map
+	for _, t := range w.tiles {
+		offset = coords.Map{Column: t.Location.Column - t.RenderAt.Column, Row: t.Location.Row - t.RenderAt.Row}
+		break
+	}
+
+	// grid boundaries are traced before the legend is added so that they stay on the map.
+	var gridLines [][]Point
+	var gridLabels []gridLabel
+	if cfg.Show.Grid.Boundaries {
+		gridLines, gridLabels = gridBoundaries(tilesWide, tilesHigh, offset, layout)
+	}
+
+	// the legend is drawn below the map, down column 1. entries are on every other row
+	// so that the terrain swatches, which are real tiles, don't touch each other.
+	type legendLabel struct {
+		at   Point
+		text string
+		bold bool
+	}
+	type legendEdge struct {
+		from, to Point
+		data     featureData
+	}
+	type legendUnit struct {
+		at               Point
+		isFlipHorizontal bool
+		color            string
+	}
+	var legendLabels []legendLabel
+	var legendEdges []legendEdge
+	var legendUnits []legendUnit
+
+	// the units of other clans are colored by clan. only clans with units that are drawn get a color.
+	var ownClan parser.UnitId_t
+	var otherClans []parser.UnitId_t
+	foundClan := map[parser.UnitId_t]bool{}
+	oldestEncounter := 0 // age in turns of the oldest encounter that is drawn, for fading
+	for _, t := range w.tiles {
+		for _, e := range t.Features.Encounters {
+			age := turnsBetween(e.TurnId, turnId)
+			if !cfg.Encounters.visible(age) {
+				continue
+			}
+			oldestEncounter = max(oldestEncounter, age)
+			if clan := e.UnitId.Clan(); e.Friendly {
+				ownClan = clan
+			} else if !foundClan[clan] {
+				foundClan[clan] = true
+				otherClans = append(otherClans, clan)
+			}
+		}
+	}
+	// settlements held by other clans are labeled with the clan's color
+	for _, t := range w.tiles {
+		for _, s := range t.Features.Settlements {
+			if s != nil && s.Owner != "" && s.Owner != ownClan && !foundClan[s.Owner] {
+				foundClan[s.Owner] = true
+				otherClans = append(otherClans, s.Owner)
+			}
+		}
+	}
+	colorOf, err := clanColors(cfg.ClanColors, otherClans)
+	if err != nil {
+		return nil, fmt.Errorf("wxx: create: %w", err)
+	}
+	// our own units keep the default color unless the configuration sets one
+	friendlyColor := "null"
+	if hex, ok := cfg.ClanColors[string(ownClan)]; ok && ownClan != "" {
+		if friendlyColor, err = worldographerColor(hex); err != nil {
+			return nil, fmt.Errorf("wxx: create: clan %s: %w", ownClan, err)
+		}
+		if unitHistoryData.R, unitHistoryData.G, unitHistoryData.B, err = hexToRGB(hex); err != nil {
+			return nil, fmt.Errorf("wxx: create: clan %s: %w", ownClan, err)
+		}
+	}
+	if legend := cfg.Legend; legend != nil {
+		row := tilesHigh
+		next := func(text string) [7]Point {
+			points := layout.points(1, row)
+			legendLabels = append(legendLabels, legendLabel{at: points[0].Translate(Point{X: 170}), text: text})
+			row += 2
+			return points
+		}
+		title := legend.Title
+		if title == "" {
+			title = "Legend"
+		}
+		next(title)
+		legendLabels[len(legendLabels)-1].bold = true
+		if legend.Terrain {
+			present := map[terrain.Terrain_e]bool{}
+			for _, t := range w.tiles {
+				present[t.Terrain] = true
+			}
+			for n := 1; n < terrain.NumberOfTerrainTypes; n++ {
+				if t := terrain.Terrain_e(n); present[t] {
+					swatch := &Tile{RenderAt: coords.Map{Column: 1, Row: row}, Terrain: t, WasVisited: true}
+					next(fmt.Sprintf("%s  %s", t, terrain.EnumToName[t]))
+					for len(allTiles) <= swatch.RenderAt.Row {
+						allTiles = append(allTiles, make([]*Tile, tilesWide+1))
+					}
+					// the swatch is marked as visited so that it isn't labeled as unexplored
+					allTiles[swatch.RenderAt.Row][1] = swatch
+				}
+			}
+		}
+		if legend.Edges {
+			for _, e := range []struct {
+				name string
+				data featureData
+			}{
+				{"River", riverData}, {"Canal", canalData}, {"Ford", fordPillData}, {"Pass", mountainPassPillData}, {"Stone Road", stoneRoadPillData},
+			} {
+				center := next(e.name)[0]
+				legendEdges = append(legendEdges, legendEdge{from: center.Translate(Point{X: -100}), to: center.Translate(Point{X: 100}), data: e.data})
+			}
+		}
+		if legend.Units {
+			legendUnits = append(legendUnits, legendUnit{at: next("Clan unit")[0], isFlipHorizontal: false, color: friendlyColor})
+			for _, clan := range otherClans {
+				legendUnits = append(legendUnits, legendUnit{at: next(colorOf[clan].label)[0], isFlipHorizontal: true, color: colorOf[clan].color})
+			}
+			legendUnits = append(legendUnits, legendUnit{at: next("Several clans")[0], isFlipHorizontal: true, color: "1.0,0.0,0.0,1.0"})
+		}
+		for _, line := range legend.Metadata {
+			next(line)
+		}
+		tilesHigh = row + 2
+		for len(allTiles) <= tilesHigh {
+			allTiles = append(allTiles, make([]*Tile, tilesWide+1))
+		}
+	}
+
+	// rivers are stitched before writing so that the waterways can be listed with the features
+	var waterways []*waterway
+	var joinedRivers map[coords.Map]map[direction.Direction_e]bool
+	if cfg.JoinRivers {
+		waterways, joinedRivers = riverWaterways(allTiles, layout, cfg.FordsAsPills, w.riverNames)
+		log.Printf("map: stitched %d waterways\n", len(waterways))
+	}
+
 	// create the slice that maps our terrains to the Worldographer terrain names.
 	// todo: this is a hack and should be extracted into the terrain package.
 	var terrainSlice []string // the first row must be the Blank terrain
@@ -154,130 +478,163 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 	}
 	//log.Printf("terrains: %d: %v\n", len(terrainSlice), terrainSlice)
 
-	// start writing the XML
-	w.buffer = &bytes.Buffer{}
-
-	w.Println(`<?xml version='1.0' encoding='utf-16'?>`)
-
-	// hexWidth and hexHeight are used to control the initial "zoom" on the map.
-	const hexWidth, hexHeight = 46.18, 40.0
-
-	w.Println(`<map type="WORLD" version="1.74" lastViewLevel="WORLD" continentFactor="0" kingdomFactor="0" provinceFactor="0" worldToContinentHOffset="0.0" continentToKingdomHOffset="0.0" kingdomToProvinceHOffset="0.0" worldToContinentVOffset="0.0" continentToKingdomVOffset="0.0" kingdomToProvinceVOffset="0.0" `)
-	w.Println(`hexWidth="%g" hexHeight="%g" hexOrientation="COLUMNS" mapProjection="FLAT" showNotes="true" showGMOnly="true" showGMOnlyGlow="false" showFeatureLabels="true" showGrid="true" showGridNumbers="false" showShadows="true"  triangleSize="12">`, hexWidth, hexHeight)
+	doc := newDocument(layout)
+	// record the shift so that Read can put tiles back at their report coordinates.
+	doc.Offset = xml.Comment(fmt.Sprintf(` ottomap:offset column="%d" row="%d" `, offset.Column, offset.Row))
 
-	w.Println(`<gridandnumbering color0="0x00000040" color1="0x00000040" color2="0x00000040" color3="0x00000040" color4="0x00000040" width0="1.0" width1="2.0" width2="3.0" width3="4.0" width4="1.0" gridOffsetContinentKingdomX="0.0" gridOffsetContinentKingdomY="0.0" gridOffsetWorldContinentX="0.0" gridOffsetWorldContinentY="0.0" gridOffsetWorldKingdomX="0.0" gridOffsetWorldKingdomY="0.0" gridSquare="0" gridSquareHeight="-1.0" gridSquareWidth="-1.0" gridOffsetX="0.0" gridOffsetY="0.0" numberFont="Arial" numberColor="0x000000ff" numberSize="20" numberStyle="PLAIN" numberFirstCol="0" numberFirstRow="0" numberOrder="COL_ROW" numberPosition="BOTTOM" numberPrePad="DOUBLE_ZERO" numberSeparator="." />`)
-
-	w.Printf("<terrainmap>")
+	var terrainMap []string
 	for n, terrain := range terrainSlice {
-		if n == 0 {
-			w.Printf("%s\t%d", terrain, n)
-		} else {
-			w.Printf("\t%s\t%d", terrain, n)
-		}
+		terrainMap = append(terrainMap, fmt.Sprintf("%s\t%d", terrain, n))
 	}
-	w.Printf("</terrainmap>\n")
+	doc.TerrainMap.Text = strings.Join(terrainMap, "\t")
 
 	// order of these is important; worldographer renders them from the bottom up.
-	w.Println(`<maplayer name="Tribenet Resources" isVisible="true"/>`)
-	w.Println(`<maplayer name="Tribenet Settlements" isVisible="true"/>`)
-	w.Println(`<maplayer name="Tribenet Clan Units" isVisible="true"/>`)
-	w.Println(`<maplayer name="Tribenet Encounters" isVisible="true"/>`)
-	w.Println(`<maplayer name="Tribenet Visited" isVisible="true"/>`)
-	w.Println(`<maplayer name="Tribenet Coords" isVisible="true"/>`)
-	w.Println(`<maplayer name="Tribenet Origin" isVisible="true"/>`)
-	w.Println(`<maplayer name="Labels" isVisible="true"/>`)
-	w.Println(`<maplayer name="Grid" isVisible="true"/>`)
-	w.Println(`<maplayer name="Features" isVisible="true"/>`)
-	w.Println(`<maplayer name="Above Terrain" isVisible="true"/>`)
-	w.Println(`<maplayer name="Terrain Land" isVisible="true"/>`)
-	w.Println(`<maplayer name="Above Water" isVisible="true"/>`)
-	w.Println(`<maplayer name="Terrain Water" isVisible="true"/>`)
-	w.Println(`<maplayer name="Below All" isVisible="true"/>`)
+	doc.addLayer("Tribenet Warnings")
+	doc.addLayer("Tribenet Resources")
+	doc.addLayer("Tribenet Reachable")
+	if len(heatmapTurns) != 0 {
+		doc.addLayer("Tribenet Heatmap")
+	}
+	if hasChanges {
+		doc.addLayer("Tribenet Changes")
+	}
+	if len(w.regions) != 0 {
+		doc.addLayer("Territories")
+	}
+	if len(w.annotations) != 0 {
+		doc.addLayer("Annotations")
+	}
+	if cfg.Legend != nil {
+		doc.addLayer("Legend")
+	}
+	if cfg.Show.Grid.Boundaries {
+		doc.addLayer("Tribenet Grids")
+	}
+	doc.addLayer("Tribenet Settlements")
+	doc.addLayer("Tribenet Clan Units")
+	doc.addLayer("Tribenet Encounters")
+	doc.addLayer("Tribenet Contacts")
+	if hasLost {
+		doc.addLayer("Tribenet Lost")
+	}
+	doc.addLayer("Tribenet Teleports")
+	if len(w.histories) != 0 {
+		doc.addLayer("Tribenet Unit History")
+	}
+	doc.addLayer("Tribenet Visited")
+	doc.addLayer("Tribenet Coords")
+	doc.addLayer("Tribenet Origin")
+	doc.addLayer("Labels")
+	doc.addLayer("Grid")
+	doc.addLayer("Features")
+	if cfg.Show.Coastlines {
+		doc.addLayer("Coastlines")
+	}
+	doc.addLayer("Above Terrain")
+	doc.addLayer("Terrain Land")
+	doc.addLayer("Above Water")
+	doc.addLayer("Terrain Water")
+	doc.addLayer("Below All")
 
-	// width is the number of columns, height is the number of rows.
-	w.Println(`<tiles viewLevel="WORLD" tilesWide="%d" tilesHigh="%d">`, tilesWide, tilesHigh)
+	// each tile-row element is a column of tiles on the Worldographer map, so we have to
+	// generate all the rows for a single column before we move on to the next column.
+	wxxWide, wxxHigh := tilesWide, tilesHigh
+	tileAt := func(x, y int) *Tile { return allTiles[y][x] }
 
-	// generate the tile-row elements. this is confusing because we're using COLUMNS orientation.
-	// we have to generate all the columns for a single row before we move on to the next row.
-	for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
-		w.Printf("<tilerow>\n")
+	// width is the number of columns, height is the number of rows.
+	doc.Tiles.TilesWide, doc.Tiles.TilesHigh = wxxWide, wxxHigh
+	for x := 0; x < wxxWide; x++ {
+		var sb strings.Builder
+		sb.WriteByte('\n')
 
 		// generate all the tiles in this column, one tile per row
-		for gridRow := 0; gridRow < tilesHigh; gridRow++ {
-			t := allTiles[gridRow][gridColumn]
+		for y := 0; y < wxxHigh; y++ {
+			t := tileAt(x, y)
 			if t == nil {
 				// this will happen when there are holes in the map.
 				t = &Tile{}
 			}
 
 			// todo: this should be replaced with a call to terrainToTile() and then use the slot.
-			w.Printf("%d\t%d", int(t.Terrain), t.Elevation)
+			isIcy, isGMOnly := 0, 0
 			if t.IsIcy {
-				w.Printf("\t1")
-			} else {
-				w.Printf("\t0")
+				isIcy = 1
 			}
 			if t.IsGMOnly {
-				w.Printf("\t1")
-			} else {
-				w.Printf("\t0")
+				isGMOnly = 1
 			}
 			// todo: implement resources. for now, just set them to 0 Z.
-			w.Printf("\t%d\t%s\n", t.Resources.Animal, "Z")
+			_, _ = fmt.Fprintf(&sb, "%d\t%d\t%d\t%d\t%d\t%s\n", int(t.Terrain), t.Elevation, isIcy, isGMOnly, t.Resources.Animal, "Z")
 		}
 
-		w.Printf("</tilerow>\n")
+		doc.Tiles.Rows = append(doc.Tiles.Rows, InnerXML{Text: sb.String()})
 	}
 
-	w.Println(`</tiles>`)
-
-	w.Println(`<mapkey positionx="0.0" positiony="0.0" viewlevel="WORLD" height="-1" backgroundcolor="0.9803921580314636,0.9215686321258545,0.843137264251709,1.0" backgroundopacity="50" titleText="Map Key" titleFontFace="Arial"  titleFontColor="0.0,0.0,0.0,1.0" titleFontBold="true" titleFontItalic="false" titleScale="80" scaleText="1 Hex = ? units" scaleFontFace="Arial"  scaleFontColor="0.0,0.0,0.0,1.0" scaleFontBold="true" scaleFontItalic="false" scaleScale="65" entryFontFace="Arial"  entryFontColor="0.0,0.0,0.0,1.0" entryFontBold="true" entryFontItalic="false" entryScale="55"  >`)
-	w.Println(`</mapkey>`)
-
 	// add features
-	w.Println(`<features>`)
+	feature := func(f *FeatureElement) {
+		doc.Features = append(doc.Features, f)
+	}
+	label := func(l *LabelElement) {
+		doc.Labels = append(doc.Labels, l)
+	}
+	shape := func(s *ShapeElement) {
+		doc.Shapes = append(doc.Shapes, s)
+	}
 
 	for gridRow := 0; gridRow < tilesHigh; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("wxx: create: %w", err)
+		}
 		for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
 			t := allTiles[gridRow][gridColumn]
 			if t == nil {
 				continue
 			}
-			points := coordsToPoints(t.RenderAt.Column, t.RenderAt.Row)
+			points := layout.points(t.RenderAt.Column, t.RenderAt.Row)
 
 			if t.Features.IsOrigin {
 				origin := points[0]
-				w.Printf(`<feature type="Three Dots" rotate="0.0" uuid="%s" mapLayer="Tribenet Origin" isFlipHorizontal="false" isFlipVertical="false" scale="-1.0" scaleHt="-1.0" tags="" color="0.800000011920929,0.800000011920929,0.800000011920929,1.0" ringcolor="null" isGMOnly="false" isPlaceFreely="false" labelPosition="6:00" labelDistance="0" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isFillHexBottom="false" isHideTerrainIcon="false">`, uuid.NewString())
-				w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" />`, origin.X, origin.Y)
-				w.Printf(`<label  mapLayer="Tribenet Origin" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-				w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" scale="25.0" />`, origin.X, origin.Y)
-				w.Printf(`</label>`)
-				w.Printf("</feature>\n")
+				f := newFeature(newId(), "Three Dots", "Tribenet Origin", origin)
+				f.Scale, f.Color = "-1.0", "0.800000011920929,0.800000011920929,0.800000011920929,1.0"
+				f.Label = newLabel("Tribenet Origin", origin, "25.0", "")
+				feature(f)
 			}
 
 			if t.Terrain == terrain.PrairiePlateau {
 				origin := points[0]
-				w.Printf(`<feature type="Semi-Real Hill Jagged" rotate="0.0" uuid="%s" mapLayer="Features" isFlipHorizontal="false" isFlipVertical="false" scale="90.0" scaleHt="-1.0" tags="" color="0.800000011920929,0.800000011920929,0.800000011920929,1.0" ringcolor="null" isGMOnly="false" isPlaceFreely="false" labelPosition="6:00" labelDistance="0" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isFillHexBottom="false" isHideTerrainIcon="false">`, uuid.NewString())
-				w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" />`, origin.X, origin.Y)
-				w.Printf(`<label  mapLayer="Features" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-				w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" scale="25.0" />`, origin.X, origin.Y)
-				w.Printf(`</label>`)
-				w.Printf("</feature>\n")
+				f := newFeature(newId(), "Semi-Real Hill Jagged", "Features", origin)
+				f.Scale, f.Color, f.IsGMOnly = "90.0", "0.800000011920929,0.800000011920929,0.800000011920929,1.0", t.IsGMOnly
+				f.Label = newLabel("Features", origin, "25.0", "")
+				f.Label.IsGMOnly = t.IsGMOnly
+				feature(f)
 			}
 
 			// unit notes are used only if there are multiple units in the hex
 			var unitNotes [2]struct {
-				id                                string
-				name                              string
-				origin                            Point
-				units                             []string
-				mapLayer, isFlipHorizontal, color string
+				id               string
+				name             string
+				origin           Point
+				units            []NoteLine
+				mapLayer, color  string
+				isFlipHorizontal bool
+				hasInventory     bool
+				clans            map[parser.UnitId_t]bool
+				ids              []parser.UnitId_t
+				age              int    // age in turns of the freshest encounter
+				turnId           string // turn of the freshest encounter
 			}
+			drawn := map[parser.UnitId_t]bool{}
 			for _, e := range t.Features.Encounters {
-				// for now, only show encounters that are in the current turn.
-				if e.TurnId != turnId {
+				// only show the encounters that the policy allows, and each unit only once
+				age := turnsBetween(e.TurnId, turnId)
+				if !cfg.Encounters.visible(age) || drawn[e.UnitId] {
 					continue
 				}
+				drawn[e.UnitId] = true
+				unitLine := NoteLine{Kind: "unit", Message: string(e.UnitId)}
+				if cfg.Encounters.Stamp && age > 0 {
+					unitLine.TurnId = e.TurnId
+				}
 				// get the center of the hex we're in
 				center := points[0]
 
@@ -290,47 +647,86 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 				}
 				origin := midpoint(center, edgePoint)
 				//var mapLayer, isFlipHorizontal, color string
+				n := 1
 				if e.Friendly {
-					unitNotes[0].id = uuid.NewString()
+					n = 0
+				}
+				if unitNotes[n].id == "" || age < unitNotes[n].age {
+					unitNotes[n].age, unitNotes[n].turnId = age, e.TurnId
+				}
+				if e.Friendly {
+					unitNotes[0].ids = append(unitNotes[0].ids, e.UnitId)
+					unitNotes[0].id = newId()
 					unitNotes[0].name = string(e.UnitId)
 					unitNotes[0].origin = origin
-					unitNotes[0].units = append(unitNotes[0].units, string(e.UnitId))
-					unitNotes[0].mapLayer, unitNotes[0].isFlipHorizontal, unitNotes[0].color = "Tribenet Clan Units", "false", "null"
+					if inventory := t.Features.Inventory[e.UnitId]; inventory != "" && age == 0 {
+						unitNotes[0].units = append(unitNotes[0].units, NoteLine{Kind: "inventory", Message: fmt.Sprintf("%s: %s", e.UnitId, inventory)})
+						unitNotes[0].hasInventory = true
+					} else {
+						unitNotes[0].units = append(unitNotes[0].units, unitLine)
+					}
+					unitNotes[0].mapLayer, unitNotes[0].isFlipHorizontal, unitNotes[0].color = "Tribenet Clan Units", false, friendlyColor
 				} else {
-					unitNotes[1].id = uuid.NewString()
+					unitNotes[1].ids = append(unitNotes[1].ids, e.UnitId)
+					unitNotes[1].id = newId()
 					unitNotes[1].name = string(e.UnitId)
 					unitNotes[1].origin = origin
-					unitNotes[1].units = append(unitNotes[1].units, string(e.UnitId))
-					unitNotes[1].mapLayer, unitNotes[1].isFlipHorizontal, unitNotes[1].color = "Tribenet Encounters", "true", "1.0,0.0,0.0,1.0"
+					unitNotes[1].units = append(unitNotes[1].units, unitLine)
+					unitNotes[1].mapLayer, unitNotes[1].isFlipHorizontal = "Tribenet Encounters", true
+					if unitNotes[1].clans == nil {
+						unitNotes[1].clans = map[parser.UnitId_t]bool{}
+					}
+					unitNotes[1].clans[e.UnitId.Clan()] = true
 				}
 			}
 			if len(unitNotes[0].units) > 1 {
 				unitNotes[0].name = "CLAN"
 			}
-			if len(unitNotes[1].units) > 1 {
-				unitNotes[1].name = "XXXX"
+			// units from a single clan are drawn in the clan's color, units from several in red
+			if len(unitNotes[1].clans) == 1 {
+				for clan := range unitNotes[1].clans {
+					unitNotes[1].color = colorOf[clan].color
+					if len(unitNotes[1].units) > 1 {
+						unitNotes[1].name = string(clan)
+					}
+				}
+			} else if len(unitNotes[1].clans) > 1 {
+				unitNotes[1].color, unitNotes[1].name = "1.0,0.0,0.0,1.0", "XXXX"
+			}
+			// a stack of units is labeled with the count; the units are listed in the note
+			for n := range unitNotes {
+				if isStacked(cfg.StackUnits, len(unitNotes[n].units)) {
+					unitNotes[n].name = fmt.Sprintf("x%d", len(unitNotes[n].units))
+				}
+			}
+			// older encounters are stamped with their turn and faded
+			for n := range unitNotes {
+				if unitNotes[n].id == "" || unitNotes[n].age == 0 {
+					continue
+				}
+				if cfg.Encounters.Stamp {
+					unitNotes[n].name = fmt.Sprintf("%s (%s)", unitNotes[n].name, unitNotes[n].turnId)
+				}
+				unitNotes[n].color = withAlpha(unitNotes[n].color, cfg.Encounters.alpha(unitNotes[n].age, oldestEncounter))
 			}
 
 			for _, un := range unitNotes {
-				// for now, only show encounters that are in the current turn.
+				// skip the groups without encounters
 				if un.id == "" {
 					continue
 				}
 
-				w.Printf(`<feature type="Military Ancient Soldier" rotate="0.0" uuid="%s" mapLayer=%q isFlipHorizontal=%q isFlipVertical="false" scale="25.0" scaleHt="-1.0" tags="" color=%q ringcolor="null" isGMOnly="false" isPlaceFreely="false" labelPosition="12:00" labelDistance="-50" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isFillHexBottom="false" isHideTerrainIcon="false">`, un.id, un.mapLayer, un.isFlipHorizontal, un.color)
-				w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" />`, un.origin.X, un.origin.Y)
-				w.Printf(`<label  mapLayer=%q style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`, un.mapLayer)
-				w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="6.25" />`, un.origin.X, un.origin.Y)
-				w.Printf("%s", un.name)
-				w.Printf(`</label>`)
-				w.Println(`</feature>`)
+				f := newFeature(un.id, unitSymbol(cfg.UnitSymbols, un.ids...), un.mapLayer, un.origin)
+				f.IsFlipHorizontal, f.Color, f.LabelPosition, f.LabelDistance = un.isFlipHorizontal, un.color, "12:00", "-50"
+				f.Label = newLabel(un.mapLayer, un.origin, "6.25", un.name)
+				feature(f)
 			}
 			// do we need to add notes for units?
-			if len(unitNotes[0].units) > 1 {
+			if len(unitNotes[0].units) > 1 || unitNotes[0].hasInventory {
 				notes.Notes[unitNotes[0].id] = &FeatureNote{
 					Id:     unitNotes[0].id,
 					Title:  "Clan Units",
-					Text:   unitNotes[0].units,
+					Lines:  unitNotes[0].units,
 					Origin: unitNotes[0].origin,
 				}
 			}
@@ -338,29 +734,114 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 				notes.Notes[unitNotes[1].id] = &FeatureNote{
 					Id:     unitNotes[1].id,
 					Title:  "Non-Clan Units",
-					Text:   unitNotes[1].units,
+					Lines:  unitNotes[1].units,
 					Origin: unitNotes[1].origin,
 				}
 			}
 
+			// contacts are shifted to the south-west and colored by how stale they are.
+			if len(t.Features.Contacts) != 0 {
+				id := newId()
+				origin := midpoint(points[0], edgeCenter(direction.SouthWest, points))
+				freshest := t.Features.Contacts[0]
+				var units []NoteLine
+				var ids []parser.UnitId_t
+				for _, c := range t.Features.Contacts {
+					ids = append(ids, c.UnitId)
+					if c.Age < freshest.Age {
+						freshest = c
+					}
+					units = append(units, NoteLine{Kind: "contact", Message: fmt.Sprintf("%s (last seen %d turns ago)", c.UnitId, c.Age)})
+				}
+				name := fmt.Sprintf("%s ~%d", freshest.UnitId, freshest.Age)
+				if isStacked(cfg.StackUnits, len(t.Features.Contacts)) {
+					name = fmt.Sprintf("x%d ~%d", len(t.Features.Contacts), freshest.Age)
+				} else if len(t.Features.Contacts) > 1 {
+					name = fmt.Sprintf("XXXX ~%d", freshest.Age)
+				}
+				color := "1.0,0.0,0.0,1.0" // seen this turn
+				if freshest.Age > 2 {
+					color = "0.5,0.5,0.5,1.0"
+				} else if freshest.Age > 0 {
+					color = "1.0,0.6,0.0,1.0"
+				}
+				f := newFeature(id, unitSymbol(cfg.UnitSymbols, ids...), "Tribenet Contacts", origin)
+				f.IsFlipHorizontal, f.Color, f.LabelDistance = true, color, "-50"
+				f.Label = newLabel("Tribenet Contacts", origin, "6.25", name)
+				feature(f)
+				notes.Notes[id] = &FeatureNote{
+					Id:     id,
+					Title:  "Contacts",
+					Lines:  units,
+					Origin: origin,
+				}
+			}
+
+			// lost units are an upside-down black soldier in the south-east of the hex.
+			if len(t.Features.Lost) != 0 {
+				id := newId()
+				origin := midpoint(points[0], edgeCenter(direction.SouthEast, points))
+				name := "lost"
+				if len(t.Features.Lost) > 1 {
+					name = fmt.Sprintf("%d lost", len(t.Features.Lost))
+				}
+				f := newFeature(id, "Military Ancient Soldier", "Tribenet Lost", origin)
+				f.IsFlipVertical, f.Color, f.LabelDistance = true, "0.0,0.0,0.0,1.0", "-50"
+				f.Label = newLabel("Tribenet Lost", origin, "6.25", name)
+				f.Label.IsBold = true
+				feature(f)
+				notes.Notes[id] = &FeatureNote{
+					Id:     id,
+					Title:  "Lost Units",
+					Lines:  t.Features.Lost,
+					Origin: origin,
+				}
+			}
+
+			// warnings are a red "!" in the north-east of the hex, with a note listing what was dropped.
+			if cfg.Show.Warnings && len(t.Features.Warnings) != 0 {
+				id := newId()
+				origin := midpoint(points[0], edgeCenter(direction.NorthEast, points))
+				f := newFeature(id, "Symbol Point-of-Interest", "Tribenet Warnings", origin)
+				f.Color, f.LabelDistance = "1.0,0.0,0.0,1.0", "-50"
+				f.Label = newLabel("Tribenet Warnings", origin, "12.5", "!")
+				f.Label.Color, f.Label.IsBold = "1.0,0.0,0.0,1.0", true
+				feature(f)
+				notes.Notes[id] = &FeatureNote{
+					Id:     id,
+					Title:  "Warnings",
+					Lines:  noteLines("warning", t.Features.Warnings...),
+					Origin: origin,
+				}
+			}
+
 			for _, r := range t.Features.Resources {
 				if r != resources.None {
 					origin := points[0]
-					w.Printf(`<feature type="Resource Mines" rotate="0.0" uuid="%s" mapLayer="Tribenet Resources" isFlipHorizontal="false" isFlipVertical="false" scale="35.0" scaleHt="-1.0" tags="" color="null" ringcolor="null" isGMOnly="false" isPlaceFreely="false" labelPosition="6:00" labelDistance="0" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isFillHexBottom="false" isHideTerrainIcon="false">`, uuid.NewString())
-					w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" />`, origin.X, origin.Y)
-					w.Printf(`<label  mapLayer="Tribenet Resources" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-					w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="12.5" />`, origin.X, origin.Y)
-					w.Printf("%s", r.String())
-					w.Printf(`</label>`)
-					w.Println(`</feature>`)
+					f := newFeature(newId(), "Resource Mines", "Tribenet Resources", origin)
+					f.Scale, f.IsGMOnly = "35.0", t.IsGMOnly
+					f.Label = newLabel("Tribenet Resources", origin, "12.5", r.String())
+					f.Label.IsGMOnly = t.IsGMOnly
+					feature(f)
 				}
 			}
 
 			for _, s := range t.Features.Settlements {
 				if s != nil && s.Name != "" && !strings.HasPrefix(s.Name, "_") {
-					settlement := points[0]
-					w.Printf(`<feature type="Settlement City" rotate="0.0" uuid="%s" mapLayer="Tribenet Settlements" isFlipHorizontal="false" isFlipVertical="false" scale="35.0" scaleHt="-1.0" tags="" color="null" ringcolor="null" isGMOnly="false" isPlaceFreely="false" labelPosition="6:00" labelDistance="0" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isFillHexBottom="false" isHideTerrainIcon="false"><location viewLevel="WORLD" x="%f" y="%f" />`, uuid.NewString(), settlement.X, settlement.Y)
-					w.Println(`</feature>`)
+					id := newId()
+					f := newFeature(id, "Settlement City", "Tribenet Settlements", points[0])
+					f.Scale, f.IsGMOnly = "35.0", t.IsGMOnly
+					feature(f)
+					// the note lists the owners of all the settlements in the hex
+					var owners []NoteLine
+					for _, other := range t.Features.Settlements {
+						for _, h := range other.History {
+							owners = append(owners, NoteLine{Kind: "owner", Message: fmt.Sprintf("%s %s", strings.Trim(other.Name, "_"), h), TurnId: h.TurnId})
+						}
+					}
+					if len(owners) != 0 {
+						notes.Notes[id] = &FeatureNote{Id: id, Title: "Settlement Owners", Lines: owners, Origin: points[0]}
+					}
 					break
 				}
 			}
@@ -369,108 +850,236 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 			for _, s := range t.Features.Special {
 				//log.Printf("special: %q: %q", s.Id, s.Name)
 				center := points[0]
-				w.Printf(`<feature type="Symbol Point-of-Interest" rotate="0.0" uuid="%s" mapLayer="Tribenet Settlements" isFlipHorizontal="false" isFlipVertical="false" scale="-1.0" scaleHt="-1.0" tags="" color="null" ringcolor="null" isGMOnly="false" isPlaceFreely="false" labelPosition="6:00" labelDistance="0" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isFillHexBottom="false" isHideTerrainIcon="false">`, uuid.NewString())
-				w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" />`, center.X, center.Y)
-				w.Printf(`<label  mapLayer="Tribenet Settlements" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-				w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="12.5" />`, center.X, center.Y)
-				w.Printf("%s", s.Name)
-				w.Printf(`</label>`)
-				w.Println(`</feature>`)
+				// the directives from the ">>>>" line can change the icon, hide the label, and add a note
+				featureType, isCapital := "Symbol Point-of-Interest", s.Has(parser.CapitalDirective)
+				if isCapital {
+					featureType = "Settlement Capital"
+				}
+				if icons := s.Values(parser.IconDirective); len(icons) != 0 {
+					featureType = icons[len(icons)-1]
+				}
+				id := newId()
+				f := newFeature(id, featureType, "Tribenet Settlements", center)
+				f.Scale, f.IsGMOnly = "-1.0", t.IsGMOnly
+				if !s.Has(parser.HideDirective) {
+					f.Label = newLabel("Tribenet Settlements", center, "12.5", s.Name)
+					f.Label.IsBold, f.Label.IsGMOnly = isCapital, t.IsGMOnly
+				}
+				feature(f)
+				if text := s.Values(parser.NoteDirective); len(text) != 0 {
+					notes.Notes[id] = &FeatureNote{Id: id, Title: s.Name, Lines: noteLines("special", text...), Origin: center}
+				}
 				break // never render more than one special hex per tile
 			}
 		}
 	}
 
-	w.Println(`</features>`)
+	for _, u := range legendUnits {
+		f := newFeature(newId(), "Military Ancient Soldier", "Legend", u.at)
+		f.IsFlipHorizontal, f.Color, f.LabelPosition, f.LabelDistance = u.isFlipHorizontal, u.color, "12:00", "-50"
+		feature(f)
+	}
+
+	// annotation icons are stacked down from the south-east of the hex, with the label under the icon.
+	// annotations without an icon are drawn with the labels.
+	stacked := map[coords.Map]int{}
+	for _, a := range w.annotations {
+		if a.Icon == "" {
+			continue
+		}
+		points := layout.points(a.At.Column, a.At.Row)
+		origin := midpoint(points[0], edgeCenter(direction.SouthEast, points)).Translate(Point{Y: float64(stacked[a.At]) * 20})
+		stacked[a.At]++
+		color := "null"
+		if a.Color != "" {
+			red, green, blue, err := hexToRGB(a.Color)
+			if err != nil {
+				return nil, fmt.Errorf("annotation %q: %w", a.At.GridString(), err)
+			}
+			color = fmt.Sprintf("%g,%g,%g,1.0", red, green, blue)
+		}
+		id := newId()
+		f := newFeature(id, a.Icon, "Annotations", origin)
+		f.Color, f.LabelDistance = color, "-50"
+		f.Label = newLabel("Annotations", origin, "6.25", a.Label)
+		feature(f)
+		if a.Note != "" {
+			title := a.Label
+			if title == "" {
+				title = "Annotation"
+			}
+			notes.Notes[id] = &FeatureNote{
+				Id:     id,
+				Title:  title,
+				Lines:  noteLines("annotation", a.Note),
+				Origin: origin,
+			}
+		}
+	}
 
-	w.Printf("<labels>\n")
+	// each waterway is labeled at its middle, with a note listing the hexes on its banks.
+	for _, ww := range waterways {
+		id := newId()
+		origin := ww.points[len(ww.points)/2]
+		f := newFeature(id, "Symbol Point-of-Interest", "Labels", origin)
+		f.Scale, f.Color = "15.0", riverData.color()
+		f.Label = newLabel("Labels", origin, "12.5", ww.name)
+		f.Label.IsItalic = true
+		feature(f)
+		text := []string{fmt.Sprintf("%d sides", len(ww.points)-1)}
+		for _, bank := range ww.banks {
+			text = append(text, bank.GridString())
+		}
+		notes.Notes[id] = &FeatureNote{
+			Id:     id,
+			Title:  ww.name,
+			Lines:  noteLines("waterway", text...),
+			Origin: origin,
+		}
+	}
 
 	for gridRow := 0; gridRow < tilesHigh; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("wxx: create: %w", err)
+		}
 		for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
 			t := allTiles[gridRow][gridColumn]
 			if t == nil {
 				continue
 			}
-			points := coordsToPoints(t.RenderAt.Column, t.RenderAt.Row)
+			points := layout.points(t.RenderAt.Column, t.RenderAt.Row)
 
 			if cfg.Show.Grid.Centers {
-				w.Printf(`<label  mapLayer="Tribenet Coords" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-				w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="6.25" />`, points[0].X, points[0].Y)
-				w.Printf("0")
-				w.Printf("</label>\n")
+				label(newLabel("Tribenet Coords", points[0], "6.25", "0"))
 			}
 
 			if t.Terrain != terrain.Blank {
 				//if t.Terrain == terrain.Alps {
 				//	log.Printf("alps %s", t.Location.GridString())
 				//}
+				// the labels for hexes that haven't been visited are big and yellow
+				visited := func(nice niceLabel, scale, text string) {
+					l := newLabel("Tribenet Visited", points[0].Translate(nice.OffsetFromCenter), scale, text)
+					l.Color, l.IsGMOnly = fmt.Sprintf("%g,%g,%g,1.0", nice.R, nice.G, nice.B), t.IsGMOnly
+					label(l)
+				}
 				if !(t.WasVisited || t.WasScouted) {
-					//labelXY := points[0].Translate(Point{-1.851698, 91.814090})
-					//w.Printf(`<label  mapLayer="Tribenet Visited" style="null" fontFace="null" color="0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-					//w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" scale="90.0" />`, labelXY.X, labelXY.Y)
-					//w.Printf("X")
-					//w.Printf("</label>/n")
 					if t.Terrain == terrain.UnknownJungleSwamp || t.Terrain == terrain.UnknownMountain {
-						labelXY := points[0].Translate(unknownLabel.OffsetFromCenter)
-						w.Printf(`<label  mapLayer="Tribenet Visited" style="null" fontFace="null" color="%g,%g,%g,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`, notVisitedLabel.R, notVisitedLabel.G, notVisitedLabel.B)
-						w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" scale="50.0" />`, labelXY.X, labelXY.Y)
-						w.Printf("?")
-						w.Printf("</label>/n")
+						visited(unknownLabel, "50.0", "?")
 					} else {
-						labelXY := points[0].Translate(notVisitedLabel.OffsetFromCenter)
-						w.Printf(`<label  mapLayer="Tribenet Visited" style="null" fontFace="null" color="%g,%g,%g,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`, notVisitedLabel.R, notVisitedLabel.G, notVisitedLabel.B)
-						w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" scale="50.0" />`, labelXY.X, labelXY.Y)
-						w.Printf("X")
-						w.Printf("</label>/n")
+						visited(notVisitedLabel, "50.0", "X")
 					}
 				}
 				if t.WasScouted {
-					labelXY := points[0].Translate(scoutedLabel.OffsetFromCenter)
-					w.Printf(`<label  mapLayer="Tribenet Visited" style="null" fontFace="null" color="%g,%g,%g,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`, scoutedLabel.R, scoutedLabel.G, scoutedLabel.B)
-					w.Printf(`<location viewLevel="WORLD" x="%f" y="%f" scale="12.5" />`, labelXY.X, labelXY.Y)
-					w.Printf("S")
-					w.Printf("</label>/n")
+					visited(scoutedLabel, "12.5", "S")
 				}
 
 				if t.Features.CoordsLabel != "" {
-					labelXY := bottomLeftCenter(points).Translate(Point{-9, -2.5})
-					w.Printf(`<label  mapLayer="Tribenet Coords" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-					w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="6.25" />`, labelXY.X, labelXY.Y)
-					w.Printf("%s", t.Features.CoordsLabel)
-					w.Printf("</label>\n")
+					l := newLabel("Tribenet Coords", bottomLeftCenter(points).Translate(Point{-9, -2.5}), "6.25", t.Features.CoordsLabel)
+					l.IsGMOnly = t.IsGMOnly
+					label(l)
 				} else if t.Features.NumbersLabel != "" {
-					labelXY := bottomLeftCenter(points).Translate(Point{-15, -2.5})
-					w.Printf(`<label  mapLayer="Tribenet Coords" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-					w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="6.25" />`, labelXY.X, labelXY.Y)
-					w.Printf("%s", t.Features.NumbersLabel)
-					w.Printf("</label>\n")
+					l := newLabel("Tribenet Coords", bottomLeftCenter(points).Translate(Point{-15, -2.5}), "6.25", t.Features.NumbersLabel)
+					l.IsGMOnly = t.IsGMOnly
+					label(l)
 				}
 			}
 
 			if t.Features.Label != nil {
-				labelXY := points[0]
-				w.Printf(`<label  mapLayer="Labels" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-				w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="12.5" />`, labelXY.X, labelXY.Y)
-				w.Printf("%s", t.Features.Label.Text)
-				w.Printf("</label>\n")
+				label(newLabel("Labels", points[0], "12.5", t.Features.Label.Text))
 			}
 
 			for _, s := range t.Features.Settlements {
 				if s != nil && s.Name != "" {
-					label := strings.Trim(s.Name, "_")
-					labelXY := settlementLabelXY(label, points)
-					w.Printf(`<label  mapLayer="Tribenet Settlements" style="null" fontFace="null" color="0.0,0.0,0.0,1.0" outlineColor="1.0,1.0,1.0,1.0" outlineSize="0.0" rotate="0.0" isBold="false" isItalic="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" isGMOnly="false" tags="">`)
-					w.Printf(`<location viewLevel="WORLD" x="%g" y="%g" scale="12.5" />`, labelXY.X, labelXY.Y)
-					w.Printf("%s", label)
-					w.Printf("</label>\n")
+					text := strings.Trim(s.Name, "_")
+					l := newLabel("Tribenet Settlements", settlementLabelXY(text, points), "12.5", text)
+					l.IsGMOnly = t.IsGMOnly
+					if s.Owner == ownClan && s.Owner != "" {
+						if friendlyColor != "null" {
+							l.Color = friendlyColor
+						}
+					} else if owner, ok := colorOf[s.Owner]; ok {
+						l.Color = owner.color
+					}
+					label(l)
 				}
 			}
 		}
 	}
 
-	w.Printf("</labels>\n")
+	// the weather notes are stacked in the upper left corner of the map
+	if len(cfg.Show.Weather) != 0 {
+		origin := layout.points(0, 0)[0]
+		for n, line := range cfg.Show.Weather {
+			label(newLabel("Labels", origin.Translate(Point{Y: float64(n) * 40}), "12.5", line))
+		}
+	}
 
-	w.Println(`<shapes>`)
+	// the turn is labeled in the hex the unit stopped in, stacked if it stopped there more than once
+	for _, h := range w.histories {
+		stopped := map[coords.Map]int{}
+		for _, turn := range h.Turns {
+			if len(turn.Path) == 0 {
+				continue
+			}
+			end := turn.Path[len(turn.Path)-1]
+			points := layout.points(end.Column, end.Row)
+			labelXY := midpoint(points[0], edgeCenter(direction.North, points)).Translate(Point{Y: float64(stopped[end]) * 15})
+			stopped[end]++
+			label(newLabel("Tribenet Unit History", labelXY, "12.5", fmt.Sprintf("%s %s", h.UnitId, turn.TurnId)))
+		}
+	}
+
+	for _, l := range gridLabels {
+		grid := newLabel("Tribenet Grids", l.at, "50.0", l.id)
+		grid.IsBold = true
+		label(grid)
+	}
+
+	for _, l := range legendLabels {
+		legend := newLabel("Legend", l.at, "25.0", l.text)
+		legend.IsBold = l.bold
+		label(legend)
+	}
+
+	// annotations without an icon are stacked up from the south of the hex
+	for _, a := range w.annotations {
+		if a.Icon != "" {
+			continue
+		}
+		points := layout.points(a.At.Column, a.At.Row)
+		labelXY := midpoint(points[0], edgeCenter(direction.South, points)).Translate(Point{Y: float64(stacked[a.At]) * -15})
+		stacked[a.At]++
+		annotation := newLabel("Annotations", labelXY, "12.5", a.Label)
+		annotation.IsItalic = true
+		label(annotation)
+	}
+
+	// each territory is labeled in the hex closest to the middle of the region
+	for _, r := range w.regions {
+		var centers []Point
+		for _, hex := range r.Hexes {
+			if 0 <= hex.Column && hex.Column < tilesWide && 0 <= hex.Row && hex.Row < tilesHigh {
+				centers = append(centers, layout.points(hex.Column, hex.Row)[0])
+			}
+		}
+		if len(centers) == 0 {
+			continue
+		}
+		var middle Point
+		for _, p := range centers {
+			middle.X, middle.Y = middle.X+p.X/float64(len(centers)), middle.Y+p.Y/float64(len(centers))
+		}
+		labelXY := centers[0]
+		for _, p := range centers {
+			if distance(p, middle) < distance(labelXY, middle) {
+				labelXY = p
+			}
+		}
+		territory := newLabel("Territories", labelXY, "25.0", r.Name)
+		territory.IsBold = true
+		label(territory)
+	}
 
 	//	// unknown origins
 	//	w.Println(`
@@ -496,13 +1105,113 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 	//</shape>
 	//	`)
 
+	// shade the hexes in each territory with the region's color.
+	// hexes that are off the map are skipped.
+	for _, r := range w.regions {
+		red, green, blue, err := hexToRGB(r.Color)
+		if err != nil {
+			return nil, fmt.Errorf("region %q: %w", r.Name, err)
+		}
+		for _, hex := range r.Hexes {
+			if hex.Column < 0 || hex.Column >= tilesWide || hex.Row < 0 || hex.Row >= tilesHigh {
+				continue
+			}
+			points := layout.points(hex.Column, hex.Row)
+			shape(newPolygon("Territories", rgba(red, green, blue), territoryOpacity, points[1:]...))
+		}
+	}
+
+	// shade the hexes in the reachability overlay.
+	// the shape is the outline of the hex, filled with a translucent color.
 	for gridRow := 0; gridRow < tilesHigh; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("wxx: create: %w", err)
+		}
+		for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
+			t := allTiles[gridRow][gridColumn]
+			if t == nil || !t.Features.IsReachable {
+				continue
+			}
+			points := layout.points(t.RenderAt.Column, t.RenderAt.Row)
+			shape(newPolygon("Tribenet Reachable", rgba(reachableData.R, reachableData.G, reachableData.B), reachableData.Opacity, points[1:]...))
+		}
+	}
+
+	// shade the hexes in the exploration heatmap, from blue for the oldest turn to red for the latest.
+	for gridRow := 0; gridRow < tilesHigh; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("wxx: create: %w", err)
+		}
+		for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
+			t := allTiles[gridRow][gridColumn]
+			if t == nil || t.Features.FirstSeen == "" {
+				continue
+			}
+			red, green, blue := heatColor(heatmap[t.Features.FirstSeen], len(heatmapTurns))
+			points := layout.points(t.RenderAt.Column, t.RenderAt.Row)
+			shape(newPolygon("Tribenet Heatmap", rgba(red, green, blue), heatmapOpacity, points[1:]...))
+		}
+	}
+
+	// ring the hexes that the latest turn added something to
+	for gridRow := 0; gridRow < tilesHigh; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("wxx: create: %w", err)
+		}
+		for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
+			t := allTiles[gridRow][gridColumn]
+			if t == nil || !t.Features.IsChanged {
+				continue
+			}
+			points := layout.points(t.RenderAt.Column, t.RenderAt.Row)
+			// close the ring by returning to the first corner
+			shape(newPath("Tribenet Changes", changesData.color(), changesData.Width, false, append(points[1:], points[1])...))
+		}
+	}
+
+	for _, e := range legendEdges {
+		shape(newPath("Legend", e.data.color(), e.data.Width, false, e.from, e.to))
+	}
+
+	for _, line := range gridLines {
+		shape(newPath("Tribenet Grids", gridLineData.color(), gridLineData.Width, false, line...))
+	}
+
+	if cfg.Show.Coastlines {
+		for _, line := range coastlines(allTiles, layout) {
+			shape(newPath("Coastlines", coastlineData.color(), coastlineData.Width, false, line...))
+		}
+	}
+
+	for _, ww := range waterways {
+		shape(newPath("Above Terrain", riverData.color(), riverData.Width, true, ww.points...))
+	}
+
+	// roads that are reported from both sides of an edge are joined into paths.
+	// the rest are drawn as stubs with the other edges.
+	var joinedRoads map[coords.Map]map[direction.Direction_e]bool
+	if cfg.JoinRoads {
+		var roads [][]coords.Map
+		roads, joinedRoads = roadPaths(allTiles)
+		for _, road := range roads {
+			var centers []Point
+			for _, hex := range road {
+				centers = append(centers, layout.points(hex.Column, hex.Row)[0])
+			}
+			shape(newPath("Above Terrain", stoneRoadPillData.color(), stoneRoadPillData.Width, true, centers...))
+		}
+	}
+
+	for gridRow := 0; gridRow < tilesHigh; gridRow++ {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("wxx: create: %w", err)
+		}
 		for gridColumn := 0; gridColumn < tilesWide; gridColumn++ {
 			t := allTiles[gridRow][gridColumn]
 			if t == nil {
 				continue
 			}
-			points := coordsToPoints(t.RenderAt.Column, t.RenderAt.Row)
+			points := layout.points(t.RenderAt.Column, t.RenderAt.Row)
 
 			// create maps with all possible edges to help us draw edges that combine types
 			canalEdges := map[direction.Direction_e]bool{}
@@ -555,26 +1264,17 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 
 				// the test for river is odd because the report says "ford" for "river" edges sometimes.
 				// but it says "ford and canal" for canal edges that are also fords.
-				if riverEdges[dir] || (fordEdges[dir] && !canalEdges[dir]) {
+				if (riverEdges[dir] || (fordEdges[dir] && !canalEdges[dir])) && !joinedRivers[t.RenderAt][dir] {
 					if drawFordGap {
 						ford := edgeCenter(dir, points)
 						midpointFrom := midpoint(from, ford)
 						midpointTo := midpoint(to, ford)
 
-						w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, riverData.R, riverData.G, riverData.B, riverData.Width)
-						w.Printf(` <p type="m" x="%f" y="%f"/>`, from.X, from.Y)
-						w.Printf(` <p x="%f" y="%f"/>`, midpointFrom.X, midpointFrom.Y)
-						w.Println(`</shape>`)
+						shape(newPath("Above Terrain", riverData.color(), riverData.Width, true, from, midpointFrom))
 
-						w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, riverData.R, riverData.G, riverData.B, riverData.Width)
-						w.Printf(` <p type="m" x="%f" y="%f"/>`, midpointTo.X, midpointTo.Y)
-						w.Printf(` <p x="%f" y="%f"/>`, to.X, to.Y)
-						w.Println(`</shape>`)
+						shape(newPath("Above Terrain", riverData.color(), riverData.Width, true, midpointTo, to))
 					} else {
-						w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, riverData.R, riverData.G, riverData.B, riverData.Width)
-						w.Printf(` <p type="m" x="%f" y="%f"/>`, from.X, from.Y)
-						w.Printf(` <p x="%f" y="%f"/>`, to.X, to.Y)
-						w.Println(`</shape>`)
+						shape(newPath("Above Terrain", riverData.color(), riverData.Width, true, from, to))
 					}
 				}
 
@@ -584,33 +1284,23 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 						midpointFrom := midpoint(from, ford)
 						midpointTo := midpoint(to, ford)
 
-						w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, canalData.R, canalData.G, canalData.B, canalData.Width)
-						w.Printf(` <p type="m" x="%f" y="%f"/>`, from.X, from.Y)
-						w.Printf(` <p x="%f" y="%f"/>`, midpointFrom.X, midpointFrom.Y)
-						w.Println(`</shape>`)
+						shape(newPath("Above Terrain", canalData.color(), canalData.Width, true, from, midpointFrom))
 
-						w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, canalData.R, canalData.G, canalData.B, canalData.Width)
-						w.Printf(` <p type="m" x="%f" y="%f"/>`, midpointTo.X, midpointTo.Y)
-						w.Printf(` <p x="%f" y="%f"/>`, to.X, to.Y)
-						w.Println(`</shape>`)
+						shape(newPath("Above Terrain", canalData.color(), canalData.Width, true, midpointTo, to))
 					} else {
-						w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, canalData.R, canalData.G, canalData.B, canalData.Width)
-						w.Printf(` <p type="m" x="%f" y="%f"/>`, from.X, from.Y)
-						w.Printf(` <p x="%f" y="%f"/>`, to.X, to.Y)
-						w.Println(`</shape>`)
+						shape(newPath("Above Terrain", canalData.color(), canalData.Width, true, from, to))
 					}
 				}
 
-				if stoneRoadEdges[dir] {
+				if stoneRoadEdges[dir] && !joinedRoads[t.RenderAt][dir] {
 					// get the midpoint of the segment from the center to the edge
 					segmentEnd := edgeCenter(dir, points)
 					segmentStart := midpoint(midpoint(midpoint(center, segmentEnd), segmentEnd), segmentEnd)
 
 					//w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" fillColor="0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0" strokeColor="0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0" strokeWidth="0.05" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`)
-					w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" fillColor="0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, stoneRoadPillData.R, stoneRoadPillData.G, stoneRoadPillData.B, stoneRoadPillData.Width)
-					w.Printf(` <p type="m" x="%f" y="%f"/>`, segmentStart.X, segmentStart.Y)
-					w.Printf(` <p x="%f" y="%f"/>`, segmentEnd.X, segmentEnd.Y)
-					w.Println(`</shape>`)
+					pill := newPath("Above Terrain", stoneRoadPillData.color(), stoneRoadPillData.Width, true, segmentStart, segmentEnd)
+					pill.FillColor = "0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0"
+					shape(pill)
 				}
 
 				// fords as drawn as gaps or pills. gaps were handled in the river and canal code above,
@@ -620,10 +1310,9 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 					segmentEnd := edgeCenter(dir, points)
 					segmentStart := midpoint(midpoint(midpoint(center, segmentEnd), segmentEnd), segmentEnd)
 
-					w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" fillColor="0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, fordPillData.R, fordPillData.G, fordPillData.B, fordPillData.Width)
-					w.Printf(` <p type="m" x="%f" y="%f"/>`, segmentStart.X, segmentStart.Y)
-					w.Printf(` <p x="%f" y="%f"/>`, segmentEnd.X, segmentEnd.Y)
-					w.Println(`</shape>`)
+					pill := newPath("Above Terrain", fordPillData.color(), fordPillData.Width, true, segmentStart, segmentEnd)
+					pill.FillColor = "0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0"
+					shape(pill)
 				}
 
 				if passEdges[dir] {
@@ -632,85 +1321,63 @@ func (w *WXX) Create(path string, turnId string, upperLeft, lowerRight coords.Ma
 					segmentStart := midpoint(midpoint(midpoint(center, segmentEnd), segmentEnd), segmentEnd)
 
 					//w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" fillColor="%g,%g,%g,1.0" strokeColor="%g,%g,%g,1.0" strokeWidth="0.09" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, mountainPass.R, mountainPass.G, mountainPass.B, mountainPass.R, mountainPass.G, mountainPass.B)
-					w.Printf(`<shape  type="Path" isCurve="false" isGMOnly="false" isSnapVertices="true" isMatchTileBorders="false" tags="" creationType="BASIC" isDropShadow="false" isInnerShadow="false" isBoxBlur="false" isWorld="true" isContinent="true" isKingdom="true" isProvince="true" dsSpread="0.2" dsRadius="50.0" dsOffsetX="0.0" dsOffsetY="0.0" insChoke="0.2" insRadius="50.0" insOffsetX="0.0" insOffsetY="0.0" bbWidth="10.0" bbHeight="10.0" bbIterations="3" mapLayer="Above Terrain" fillTexture="" strokeTexture="" strokeType="SIMPLE" highestViewLevel="WORLD" currentShapeViewLevel="WORLD" lineCap="ROUND" lineJoin="ROUND" opacity="1.0" fillRule="NON_ZERO" fillColor="0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0" strokeColor="%f,%f,%f,1.0" strokeWidth="%f" dsColor="1.0,0.8941176533699036,0.7686274647712708,1.0" insColor="1.0,0.8941176533699036,0.7686274647712708,1.0">`, mountainPassPillData.R, mountainPassPillData.G, mountainPassPillData.B, mountainPassPillData.Width)
-					w.Printf(` <p type="m" x="%f" y="%f"/>`, segmentStart.X, segmentStart.Y)
-					w.Printf(` <p x="%f" y="%f"/>`, segmentEnd.X, segmentEnd.Y)
-					w.Println(`</shape>`)
-				}
-			}
-		}
-	}
-
-	w.Println(`</shapes>`)
-
-	w.Println(`<notes>`)
-	/*
-		<note key="WORLD,2343.75,3112.5" viewLevel="WORLD" x="2343.75" y="3112.5" filename="" parent="dde12f75-dcc9-4cb7-a96d-f18011601143" color="1.0,1.0,0.0,1.0" title="Units (Notes Title)">
-		<notetext><![CDATA[<html dir="ltr"><head></head><body contenteditable="true">Paragraph (Notes Paragraph)</body></html>]]></notetext></note>
-	*/
-	for _, note := range notes.Notes {
-		w.Printf(`<note key="WORLD,%f,%f" viewLevel="WORLD" x="%f" y="%f" filename="" parent=%q color="1.0,1.0,0.0,1.0" title=%q>`, note.Origin.X, note.Origin.Y, note.Origin.X, note.Origin.Y, note.Id, note.Title)
-		w.Printf(`<notetext><![CDATA[<html dir="ltr"><head></head><body contenteditable="true">`)
-		for _, line := range note.Text {
-			w.Printf(`%s<br/>`, line)
-		}
-		w.Println(`</body></html>]]></notetext></note>`)
-	}
-	w.Println(`</notes>`)
-	w.Println(`<informations>`)
-	w.Println(`</informations>`)
-	w.Println(`<configuration>`)
-	w.Println(`  <terrain-config>`)
-	w.Println(`  </terrain-config>`)
-	w.Println(`  <feature-config>`)
-	w.Println(`  </feature-config>`)
-	w.Println(`  <texture-config>`)
-	w.Println(`  </texture-config>`)
-	w.Println(`  <text-config>`)
-	w.Println(`  </text-config>`)
-	w.Println(`  <shape-config>`)
-	w.Println(`  </shape-config>`)
-	w.Println(`  </configuration>`)
-	w.Println(`</map>`)
-	w.Println(``)
-
-	//fmt.Printf("%s\n", w.buffer.String())
-
-	// convert the source from UTF-8 to UTF-16
-	var buf16 bytes.Buffer
-	buf16.Write([]byte{0xfe, 0xff}) // write the BOM
-	for src := w.buffer.Bytes(); len(src) > 0; {
-		// extract next rune from the source
-		r, w := utf8.DecodeRune(src)
-		if r == utf8.RuneError {
-			return fmt.Errorf("invalid utf8 data")
+					pill := newPath("Above Terrain", mountainPassPillData.color(), mountainPassPillData.Width, true, segmentStart, segmentEnd)
+					pill.FillColor = "0.7019608020782471,0.7019608020782471,0.7019608020782471,1.0"
+					shape(pill)
+				}
+			}
 		}
-		// consume that rune
-		src = src[w:]
-		// convert the rune to UTF-16 and write it to the results
-		for _, v := range utf16.Encode([]rune{r}) {
-			if err := binary.Write(&buf16, binary.BigEndian, v); err != nil {
-				return err
+	}
+
+	// unit histories are drawn as a single path through the hex centers
+	for _, h := range w.histories {
+		var path []coords.Map
+		for _, turn := range h.Turns {
+			for _, hex := range turn.Path {
+				if len(path) == 0 || path[len(path)-1] != hex {
+					path = append(path, hex)
+				}
 			}
 		}
+		if len(path) < 2 {
+			continue
+		}
+		var centers []Point
+		for _, hex := range path {
+			centers = append(centers, layout.points(hex.Column, hex.Row)[0])
+		}
+		shape(newPath("Tribenet Unit History", unitHistoryData.color(), unitHistoryData.Width, true, centers...))
 	}
-	w.buffer = nil
 
-	// convert the UTF-16 to a gzip stream
-	var bufGZ bytes.Buffer
-	gz := gzip.NewWriter(&bufGZ)
-	if _, err := gz.Write(buf16.Bytes()); err != nil {
-		return err
-	} else if err = gz.Close(); err != nil {
-		return err
+	// teleports are drawn as a dotted arc from the origin to the destination.
+	// the arc bends to the right of the line between the two hexes so that
+	// jumps in opposite directions don't overlap.
+	for _, t := range w.teleports {
+		from, to := layout.points(t.From.Column, t.From.Row)[0], layout.points(t.To.Column, t.To.Row)[0]
+		mid := midpoint(from, to)
+		control := Point{X: mid.X - (to.Y-from.Y)/4, Y: mid.Y + (to.X-from.X)/4}
+		const dots = 24
+		for n := 0; n < dots; n += 2 {
+			p1, p2 := bezier(from, control, to, float64(n)/dots), bezier(from, control, to, float64(n+1)/dots)
+			shape(newPath("Tribenet Teleports", teleportData.color(), teleportData.Width, true, p1, p2))
+		}
 	}
 
-	// write the compressed data to the output file
-	if err := os.WriteFile(path, bufGZ.Bytes(), 0644); err != nil {
-		return err
+	var noteKeys []string
+	for key := range notes.Notes {
+		noteKeys = append(noteKeys, key)
+	}
+	sort.Strings(noteKeys)
+	for _, key := range noteKeys {
+		doc.Notes = append(doc.Notes, newNote(notes.Notes[key], layout.ViewLevel, cfg.Notes))
 	}
 
-	return nil
+	// everything is placed at the WORLD view level; move it to the configured level.
+	if layout.ViewLevel != World {
+		doc.setViewLevel(layout.ViewLevel)
+	}
+
+	return doc, nil
 }
 
 // crs_to_pixel converts a column, row to the pixel at the center of the corresponding tile.
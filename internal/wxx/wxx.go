@@ -3,7 +3,6 @@
 package wxx
 
 import (
-	"bytes"
 	"fmt"
 	"github.com/playbymail/ottomap/internal/coords"
 	"github.com/playbymail/ottomap/internal/terrain"
@@ -11,10 +10,29 @@ import (
 )
 
 type WXX struct {
-	buffer *bytes.Buffer
-
 	tiles map[coords.Map]*Tile
 
+	// teleports are drawn as dotted arcs between the hexes
+	teleports []Teleport
+
+	// unit histories are drawn as paths with the turn labeled where the unit stopped
+	histories []UnitHistory
+
+	// regions are shaded and labeled on the territories layer
+	regions []Region
+
+	// annotations are the player's own notes, labels, and icons
+	annotations []Annotation
+
+	// elevations overrides the built-in elevation for each terrain, if set.
+	elevations map[terrain.Terrain_e]int
+
+	// icy is the set of terrains that are rendered as icy.
+	icy map[terrain.Terrain_e]bool
+
+	// riverNames names the waterways that border the hexes.
+	riverNames map[coords.Map]string
+
 	// terrainTileName maps our terrain type to the name of a Worldographer tile.
 	terrainTileName map[terrain.Terrain_e]string
 
@@ -108,6 +126,26 @@ func NewWXX(options ...Option) (*WXX, error) {
 	return w, nil
 }
 
+// AddTeleport adds a "Goes to" jump to the map.
+func (w *WXX) AddTeleport(t Teleport) {
+	w.teleports = append(w.teleports, t)
+}
+
+// AddUnitHistory adds the path a unit took to the map.
+func (w *WXX) AddUnitHistory(h UnitHistory) {
+	w.histories = append(w.histories, h)
+}
+
+// AddAnnotation adds a player's annotation to the map.
+func (w *WXX) AddAnnotation(a Annotation) {
+	w.annotations = append(w.annotations, a)
+}
+
+// AddRegion adds a named territory to the map.
+func (w *WXX) AddRegion(r Region) {
+	w.regions = append(w.regions, r)
+}
+
 // GetTile returns the tile at the given coordinates.
 func (w *WXX) GetTile(location coords.Map) *Tile {
 	t, ok := w.tiles[location]
@@ -118,3 +156,29 @@ func (w *WXX) GetTile(location coords.Map) *Tile {
 }
 
 type Option func(*WXX) error
+
+// WithElevations sets the elevation written for each terrain.
+// Terrains that aren't in the map get an elevation of 0.
+func WithElevations(elevations map[terrain.Terrain_e]int) Option {
+	return func(w *WXX) error {
+		w.elevations = elevations
+		return nil
+	}
+}
+
+// WithRiverNames sets the names of the waterways that border the hexes.
+// The keys are the locations from the turn reports, not the render locations.
+func WithRiverNames(names map[coords.Map]string) Option {
+	return func(w *WXX) error {
+		w.riverNames = names
+		return nil
+	}
+}
+
+// WithIcyTerrains sets the terrains that are rendered as icy.
+func WithIcyTerrains(icy map[terrain.Terrain_e]bool) Option {
+	return func(w *WXX) error {
+		w.icy = icy
+		return nil
+	}
+}
@@ -5,12 +5,19 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"github.com/mdhender/semver"
 	"github.com/playbymail/ottomap/cerrs"
+	"github.com/playbymail/ottomap/internal/config"
+	"github.com/playbymail/ottomap/internal/manifest"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/render"
 	"github.com/spf13/cobra"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 var (
@@ -19,6 +26,8 @@ var (
 
 func main() {
 	log.SetFlags(log.Lshortfile | log.Ltime)
+	argsRoot.warnings = manifest.NewCounter(os.Stderr)
+	log.SetOutput(argsRoot.warnings)
 
 	if err := Execute(); err != nil {
 		log.Fatal(err)
@@ -26,12 +35,48 @@ func main() {
 }
 
 func Execute() error {
+	// flags that weren't on the command line may be set from the environment.
+	// this runs after the flags are parsed and before cobra checks for required flags.
+	cobra.OnInitialize(func() {
+		cmd, _, err := cmdRoot.Find(os.Args[1:])
+		if err != nil {
+			return
+		}
+		if argsRoot.envVars, err = config.ApplyEnv(cmd.Flags(), os.LookupEnv); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	})
+
 	cmdRoot.PersistentFlags().BoolVar(&argsRoot.showVersion, "show-version", false, "show version")
 	cmdRoot.PersistentFlags().StringVar(&argsRoot.logFile.name, "log-file", "", "set log file")
 
+	cmdRoot.AddCommand(cmdAnonymize)
+	cmdAnonymize.Flags().Int64Var(&argsAnonymize.seed, "seed", 0, "seed for the mappings (default random)")
+	cmdAnonymize.Flags().StringVar(&argsAnonymize.output, "output", ".", "directory to write the anonymized files to")
+
 	cmdRoot.AddCommand(cmdDb)
 	cmdDb.PersistentFlags().StringVar(&argsDb.paths.store, "store", argsDb.paths.store, "path to the database file")
 
+	cmdDb.AddCommand(cmdDbAlliance)
+	cmdDbAlliance.AddCommand(cmdDbAllianceJoin)
+	cmdDbAllianceJoin.Flags().StringVar(&argsDb.alliance.clan, "clan", "", "clan joining the alliance")
+	if err := cmdDbAllianceJoin.MarkFlagRequired("clan"); err != nil {
+		log.Fatalf("clan: %v\n", err)
+	}
+	cmdDbAllianceJoin.Flags().StringVar(&argsDb.alliance.name, "name", "", "name of the alliance")
+	if err := cmdDbAllianceJoin.MarkFlagRequired("name"); err != nil {
+		log.Fatalf("name: %v\n", err)
+	}
+	cmdDbAllianceJoin.Flags().BoolVar(&argsDb.alliance.share, "share", false, "share the clan's reports with the alliance")
+	cmdDbAlliance.AddCommand(cmdDbAllianceLeave)
+	cmdDbAllianceLeave.Flags().StringVar(&argsDb.alliance.clan, "clan", "", "clan leaving the alliance")
+	if err := cmdDbAllianceLeave.MarkFlagRequired("clan"); err != nil {
+		log.Fatalf("clan: %v\n", err)
+	}
+	cmdDbAllianceLeave.Flags().StringVar(&argsDb.alliance.name, "name", "", "name of the alliance")
+	if err := cmdDbAllianceLeave.MarkFlagRequired("name"); err != nil {
+		log.Fatalf("name: %v\n", err)
+	}
 	cmdDb.AddCommand(cmdDbCreate)
 	cmdDbCreate.AddCommand(cmdDbCreateDatabase)
 	cmdDbCreateDatabase.Flags().BoolVar(&argsDb.create.force, "force", false, "force the creation if the database exists")
@@ -40,6 +85,32 @@ func Execute() error {
 		log.Fatalf("store: %v\n", err)
 	}
 
+	cmdDbCreate.AddCommand(cmdDbCreateAlliance)
+	cmdDbCreateAlliance.Flags().StringVar(&argsDb.alliance.name, "name", "", "name of the alliance")
+	if err := cmdDbCreateAlliance.MarkFlagRequired("name"); err != nil {
+		log.Fatalf("name: %v\n", err)
+	}
+	cmdDbCreate.AddCommand(cmdDbCreateUser)
+	cmdDbCreateUser.Flags().StringVar(&argsDb.create.user.clan, "clan", "", "clan the user belongs to")
+	if err := cmdDbCreateUser.MarkFlagRequired("clan"); err != nil {
+		log.Fatalf("clan: %v\n", err)
+	}
+	cmdDbCreateUser.Flags().StringVar(&argsDb.create.user.handle, "handle", "", "name the user signs in with")
+	if err := cmdDbCreateUser.MarkFlagRequired("handle"); err != nil {
+		log.Fatalf("handle: %v\n", err)
+	}
+	cmdDbCreateUser.Flags().StringVar(&argsDb.create.user.secret, "secret", "", "secret the user signs in with")
+	if err := cmdDbCreateUser.Flags().SetAnnotation("secret", config.EnvAnnotation, []string{"OTTOMAP_USER_SECRET"}); err != nil {
+		log.Fatalf("error: secret: %v\n", err)
+	}
+
+	cmdDb.AddCommand(cmdDbImport)
+	cmdDbImport.Flags().StringVar(&argsDb.load.clan, "clan", argsDb.load.clan, "clan that owns reports")
+	if err := cmdDbImport.MarkFlagRequired("clan"); err != nil {
+		log.Fatalf("clan: %v\n", err)
+	}
+	cmdDbImport.Flags().StringVar(&argsDb.load.path, "report-path", argsDb.load.path, "path to report files")
+
 	cmdDb.AddCommand(cmdDbLoad)
 	cmdDbLoad.AddCommand(cmdDbLoadFiles)
 	cmdDbLoadFiles.Flags().StringVar(&argsDb.load.clan, "clan", argsDb.load.clan, "clan that owns reports")
@@ -63,15 +134,61 @@ func Execute() error {
 	}
 	cmdDbLoadPath.Flags().StringVar(&argsDb.load.path, "report-path", argsDb.load.path, "path to report files")
 
+	cmdRoot.AddCommand(cmdBrowse)
+	addReportFlags(cmdBrowse)
+	cmdBrowse.Flags().StringVar(&argsBrowse.center, "center", "", "grid coordinates of the hex to start on, for example \"AB 1203\" (default is the first unit)")
+	cmdBrowse.Flags().StringVar(&argsBrowse.color, "color", "auto", "shade the hexes with the terrain colors (auto, always, never)")
 	cmdRoot.AddCommand(cmdDump)
 	cmdDump.Flags().BoolVar(&argsDump.defaultTileMap, "default-tile-map", false, "dump the default tile map")
 
+	cmdRoot.AddCommand(cmdExport)
+	cmdExport.AddCommand(cmdExportEncounters, cmdExportHistory, cmdExportMap, cmdExportSettlements, cmdExportTiles)
+	addExportFlags(cmdExportEncounters)
+	addExportFlags(cmdExportHistory)
+	cmdExportHistory.Flags().BoolVar(&argsExport.json, "json", false, "write JSON instead of CSV")
+	addReportFlags(cmdExportMap)
+	cmdExportMap.Flags().StringVarP(&argsExport.output, "output", "o", "", "file to write to (default is stdout)")
+	cmdExportMap.Flags().StringVar(&argsExport.format, "format", "", fmt.Sprintf("format to write (%s)", strings.Join(render.Formats(), ", ")))
+	if err := cmdExportMap.MarkFlagRequired("format"); err != nil {
+		log.Fatalf("error: format: %v\n", err)
+	}
+	addExportFlags(cmdExportSettlements)
+	addExportFlags(cmdExportTiles)
+
+	cmdRoot.AddCommand(cmdGen)
+	cmdGen.Flags().Int64Var(&argsGen.seed, "seed", 0, "seed for the map (default random)")
+	cmdGen.Flags().StringVar(&argsGen.size, "size", "60x42", "size of the map in hexes, COLUMNSxROWS")
+	cmdGen.Flags().StringVar(&argsGen.origin, "origin", "QQ 0101", "upper left hex of the map")
+	cmdGen.Flags().Float64Var(&argsGen.land, "land", 0.45, "fraction of the hexes that are land")
+	cmdGen.Flags().IntVar(&argsGen.rivers, "rivers", 20, "number of rivers to start")
+	cmdGen.Flags().IntVar(&argsGen.settlements, "settlements", 25, "number of settlements to place")
+	cmdGen.Flags().StringVar(&argsGen.clanId, "clan-id", "0138", "clan that reports the map")
+	cmdGen.Flags().StringVar(&argsGen.turnId, "turn", "0901-01", "turn the map is reported in (yyyy-mm format)")
+	cmdGen.Flags().StringVar(&argsGen.format, "format", "merged", "format to write (merged, tniif)")
+	cmdGen.Flags().StringVarP(&argsGen.output, "output", "o", "", "file to write to (default is stdout)")
+	cmdGen.AddCommand(cmdGenReport)
+	cmdGenReport.Flags().StringVarP(&argsGenReport.output, "output", "o", "", "report file to write to (default is stdout)")
+	cmdGenReport.Flags().StringVar(&argsGenReport.expect, "expect", "", "file to write the expected document to")
+
+	cmdRoot.AddCommand(cmdFind)
+	addReportFlags(cmdFind)
+	cmdFind.Flags().StringVar(&argsRender.paths.notes, "annotations", "", "path to the annotations file (default annotations.json in the data folder)")
+	cmdFind.Flags().StringVar(&argsRender.paths.store, "store", "", "load the reports from this database instead of the input folder")
 	cmdRoot.AddCommand(cmdList)
 	cmdList.AddCommand(cmdListClans)
 	cmdList.AddCommand(cmdListTurns)
 
 	cmdRoot.AddCommand(cmdParse)
 	cmdParse.AddCommand(cmdParseFile)
+	cmdParseFile.Flags().StringVar(&argsParseFiles.pipeline, "pipeline", "legacy", "parser pipeline (legacy, new, compare)")
+	cmdParseFile.Flags().StringVar(&argsParseFiles.output, "output", "", "file to write the document to (default stdout)")
+	cmdParseFile.Flags().IntVar(&argsParseFiles.maxDiff, "max-diff", 40, "maximum number of lines of differences to show")
+	cmdParse.AddCommand(cmdParseBench)
+	cmdParseBench.Flags().StringVar(&argsParseBench.pipeline, "pipeline", "both", "parser pipeline (legacy, new, both)")
+	cmdParseBench.Flags().IntVar(&argsParseBench.iterations, "iterations", 20, "number of times to parse each report")
+	cmdParseBench.Flags().StringVar(&argsParseBench.cpuProfile, "cpu-profile", "", "write a CPU profile to this file")
+	cmdParseBench.Flags().StringVar(&argsParseBench.memProfile, "mem-profile", "", "write an allocation profile to this file")
+	cmdParseBench.Flags().IntVar(&argsParseBench.workers, "workers", 0, "number of workers parsing unit sections in the legacy parser (0 or 1 is sequential)")
 	//cmdParseFile.Flags().StringVar(&argsParseFiles.clanId, "clan-id", "", "clan id")
 	//if err := cmdParseFile.MarkFlagRequired("clan-id"); err != nil {
 	//	log.Fatalf("error: clan-id: %v\n", err)
@@ -97,32 +214,152 @@ func Execute() error {
 	cmdRender.Flags().BoolVar(&argsRender.experimental.splitTrailingUnits, "x-split-units", false, "experimental: split trailing units")
 	cmdRender.Flags().BoolVar(&argsRender.mapper.Dump.BorderCounts, "dump-border-counts", false, "dump border counts")
 	cmdRender.Flags().BoolVar(&argsRender.render.FordsAsPills, "fords-as-pills", true, "render fords as pills")
+	cmdRender.Flags().BoolVar(&argsRender.render.JoinRivers, "join-rivers", false, "draw rivers as named waterways and list them in the map notes")
+	cmdRender.Flags().BoolVar(&argsRender.render.JoinRoads, "join-roads", false, "draw stone roads as continuous paths between hex centers")
+	cmdRender.Flags().BoolVar(&argsRender.mirrorEdges, "mirror-edges", false, "copy road, pass, and canal edges onto the neighboring hex")
 	cmdRender.Flags().BoolVar(&argsRender.parser.Ignore.Scouts, "ignore-scouts", false, "ignore scout reports")
+	cmdRender.Flags().IntVar(&argsRender.parser.Workers, "parse-workers", 0, "number of workers parsing unit sections (0 or 1 is sequential)")
+	cmdRender.Flags().IntVar(&argsRender.mapper.Workers, "map-workers", 0, "number of workers converting tiles to hexes (0 or 1 is sequential)")
+	cmdRender.Flags().StringSliceVar(&argsRender.plugins, "plugin", nil, "plugin to add annotations to the map, by name or exec:PATH (may be repeated)")
+	cmdRender.Flags().StringVar(&argsRender.pipeline, "pipeline", "legacy", "parser pipeline (legacy, new)")
+	cmdRender.Flags().StringVar(&argsRender.reportFormat, "report-format", "auto", "report format (auto, obscured, current)")
+	cmdRender.Flags().BoolVar(&argsRender.warnOnFleetDrift, "warn-on-fleet-drift", true, "warn when fleet movement doesn't match the winds")
 	cmdRender.Flags().BoolVar(&argsRender.warnOnInvalidGrid, "warn-on-invalid-grid", true, "warn on invalid grid id")
 	cmdRender.Flags().BoolVar(&argsRender.warnOnNewSettlement, "warn-on-new-settlement", true, "warn on new settlement")
 	cmdRender.Flags().BoolVar(&argsRender.warnOnTerrainChange, "warn-on-terrain-change", true, "warn when terrain changes")
+	cmdRender.Flags().BoolVar(&argsRender.render.Show.Grid.Boundaries, "show-grid-boundaries", false, "outline each grid and label it with its id (AA..ZZ)")
 	cmdRender.Flags().BoolVar(&argsRender.render.Show.Grid.Coords, "show-grid-coords", false, "show grid coordinates (XX CCRR)")
 	cmdRender.Flags().BoolVar(&argsRender.render.Show.Grid.Numbers, "show-grid-numbers", false, "show grid numbers (CCRR)")
+	cmdRender.Flags().StringVar(&argsRender.season, "season", "", "render icy terrain for this month (1-12) or season (winter, spring, summer, autumn) instead of the last turn's month")
+	cmdRender.Flags().BoolVar(&argsRender.mapper.Show.Heatmap, "show-heatmap", false, "shade hexes by the turn they were first reported, older hexes cooler and recent hexes warmer")
+	cmdRender.Flags().BoolVar(&argsRender.mapper.Render.GMOnly, "gm-only-unconfirmed", false, "mark hexes that were never visited or scouted as GM only")
+	cmdRender.Flags().BoolVar(&argsRender.render.Show.Coastlines, "show-coastlines", false, "draw smoothed coastlines between water and land hexes")
+	cmdRender.Flags().BoolVar(&argsRender.render.Show.Warnings, "show-warnings", false, "mark hexes with data that could not be rendered with a red \"!\"")
+	cmdRender.Flags().IntVar(&argsRender.render.StackUnits, "stack-units", 0, "label unit markers with a count when more than this many units share a hex (0 to always show names)")
 	cmdRender.Flags().BoolVar(&argsRender.saveWithTurnId, "save-with-turn-id", false, "add turn id to file name")
+	cmdRender.Flags().BoolVar(&argsRender.splitByGrid, "split-by-grid", false, "write a map for each grid (AA.wxx, AB.wxx, ...) and an index.json into a folder named after the map")
+	cmdRender.Flags().StringVar(&argsRender.print.pages, "print-pages", "", "slice the map into pages of COLUMNSxROWS hexes for printing, written with an index sheet into a folder named after the map")
+	cmdRender.Flags().IntVar(&argsRender.print.overlap, "print-overlap", 2, "hexes shared by neighboring pages with --print-pages")
+	cmdRender.Flags().StringVar(&argsRender.dumpMerged.path, "dump-merged", "", "write the merged tiles, with their sources and confidence, to this file")
+	cmdRender.Flags().StringVar(&argsRender.dumpMerged.format, "dump-merged-format", "", "format for --dump-merged (json, ndjson, csv; default from the file extension)")
+	cmdRender.Flags().StringVar(&argsRender.fromMerged, "from-merged", "", "render from a file written by --dump-merged instead of the turn reports")
 	cmdRender.Flags().BoolVar(&argsRoot.soloClan, "solo", false, "limit parsing to a single clan")
+	cmdRender.Flags().BoolVar(&argsRender.show.changes, "show-changes", false, "ring the hexes that the last turn discovered or added terrain, edges, resources, or settlements to")
+	cmdRender.Flags().BoolVar(&argsRender.show.contacts, "show-contacts", false, "show last known positions of foreign units")
 	cmdRender.Flags().BoolVar(&argsRender.show.origin, "show-origin", false, "show origin hex")
 	cmdRender.Flags().BoolVar(&argsRender.show.shiftMap, "shift-map", true, "shift map up and left")
+	cmdRender.Flags().BoolVar(&argsRender.show.teleports, "show-teleports", true, "draw \"Goes to\" jumps as dotted arcs")
+	cmdRender.Flags().BoolVar(&argsRender.show.weather, "show-weather", false, "show season and weather for each turn")
 	cmdRender.Flags().BoolVar(&argsRender.experimental.stripCR, "strip-cr", false, "experimental: enable conversion of DOS EOL")
 	cmdRender.Flags().BoolVar(&argsRender.experimental.cleanUpScoutStill, "x-clean-up-scout-still", false, "experimental: clean up 'scout still' entries")
 	cmdRender.Flags().BoolVar(&argsRender.experimental.newWaterTiles, "x-new-water-tiles", false, "experimental: use higher contrast water tiles")
+	cmdRender.Flags().BoolVar(&argsRender.render.Deterministic, "x-deterministic", false, "use sequential feature ids so that maps can be compared")
+	if err := cmdRender.Flags().MarkHidden("x-deterministic"); err != nil {
+		log.Fatalf("error: x-deterministic: %v\n", err)
+	}
+	cmdRender.Flags().StringVar(&argsRender.snapshot, "x-snapshot", "", "write a snapshot of the turns and tiles to this file")
+	if err := cmdRender.Flags().MarkHidden("x-snapshot"); err != nil {
+		log.Fatalf("error: x-snapshot: %v\n", err)
+	}
+	cmdRender.Flags().StringVar(&argsRender.paths.notes, "annotations", "", "path to the annotations file (default annotations.json in the data folder)")
+	cmdRender.Flags().StringVar(&argsRender.paths.config, "config", "", "path to the configuration file (default ottomap.json in the data folder)")
+	cmdRender.Flags().DurationVar(&argsRender.timeout, "timeout", 0, "stop the render if it takes longer than this (default no limit)")
+	cmdRender.Flags().StringVar(&argsRender.progressFormat, "progress", "", "report the progress of each phase as a bar or a json stream (bar, json)")
+	cmdRender.Flags().StringVar(&argsRender.profile, "profile", "", "configuration profile to apply (default \"default\" if the file has one)")
+	argsRender.layerFlags = cmdRender.Flags()
 	cmdRender.Flags().StringVar(&argsRender.clanId, "clan-id", "", "clan for output file names")
 	if err := cmdRender.MarkFlagRequired("clan-id"); err != nil {
 		log.Fatalf("error: clan-id: %v\n", err)
 	}
 	cmdRender.Flags().StringVar(&argsRender.paths.data, "data", "data", "path to root of data files")
+	cmdRender.Flags().StringVar(&argsRender.fromTurn.id, "from-turn", "", "first turn to map (yyyy-mm format)")
 	cmdRender.Flags().StringVar(&argsRender.maxTurn.id, "max-turn", "", "last turn to map (yyyy-mm format)")
+	cmdRender.Flags().StringVar(&argsRender.maxTurn.id, "to-turn", "", "last turn to map (yyyy-mm format), same as --max-turn")
 	cmdRender.Flags().StringVar(&argsRender.originGrid, "origin-grid", "", "grid id to substitute for ##")
+	cmdRender.Flags().BoolVar(&argsRender.explainLocations, "explain-locations", false, "log how the ending hex of each unit was derived")
+	cmdRender.Flags().BoolVar(&argsRender.locations.Strict, "strict-locations", false, "stop if any obscured or N/A hexes can't be resolved")
+	cmdRender.Flags().IntVar(&argsRender.show.reachable.movementPoints, "reachable-mp", pathfinding.DefaultMovementPoints, "movement points for the reachability overlay")
+	cmdRender.Flags().StringVar(&argsRender.show.reachable.unitId, "show-reachable", "", "shade hexes the unit can reach this turn")
+	cmdRender.Flags().StringVar(&argsRender.show.history, "show-unit-history", "", "draw the path the unit took over all the loaded turns")
 	cmdRender.Flags().StringVar(&argsRender.soloElement, "solo-element", "", "limit parsing to a single element of a clan")
+	// db render shares the render flags so that both commands accept the same options
+	cmdDb.AddCommand(cmdDbRender)
+	cmdDbRender.Flags().AddFlagSet(cmdRender.Flags())
+	cmdRoot.AddCommand(cmdSelftest)
+	cmdSelftest.Flags().IntVar(&argsSelftest.maxDiff, "max-diff", 40, "maximum number of diff lines to print for each file")
+	cmdSelftest.Flags().StringVar(&argsSelftest.update, "update", "", "write the results as the new golden files in this corpus folder")
+	cmdRoot.AddCommand(cmdServe)
+	cmdServe.Flags().StringVar(&argsServe.addr, "addr", "localhost:8080", "address to listen on")
+	cmdServe.Flags().Int64Var(&argsServe.maxUpload, "max-upload", 8<<20, "maximum size of a request, in bytes")
+	cmdServe.Flags().StringVar(&argsServe.secret, "secret", "", "shared secret that clients must send as a bearer token")
+	cmdServe.Flags().StringVar(&argsServe.store, "store", "", "database for the clan workspaces")
+	cmdServe.Flags().IntVar(&argsServe.attempts, "attempts", 3, "number of times a queued job is tried before it fails")
+	cmdServe.Flags().IntVar(&argsServe.workers, "workers", 2, "number of workers running queued jobs")
+	cmdServe.Flags().DurationVar(&argsServe.timeout, "timeout", 2*time.Minute, "maximum time allowed for a render or a parse")
+	cmdRoot.AddCommand(cmdView)
+	cmdView.Flags().AddFlagSet(cmdRender.Flags())
+	cmdView.Flags().StringVar(&argsView.addr, "addr", "localhost:8080", "address to listen on")
+	cmdView.Flags().StringVar(&argsRender.paths.store, "store", "", "load the reports from this database instead of the input folder")
+	cmdRoot.AddCommand(cmdIngest)
+	cmdIngest.Flags().StringVar(&argsIngest.data, "data", "data", "path to root of data files")
+	cmdIngest.Flags().BoolVar(&argsIngest.force, "force", false, "replace staged reports that have different contents")
+	cmdRoot.AddCommand(cmdWatch)
+	cmdWatch.Flags().AddFlagSet(cmdRender.Flags())
+	cmdWatch.Flags().DurationVar(&argsWatch.interval, "interval", 2*time.Second, "how often to check for changed reports")
+	cmdRender.AddCommand(cmdRenderPreview)
+	addReportFlags(cmdRenderPreview)
+	cmdRenderPreview.Flags().StringVar(&argsRenderPreview.center, "center", "", "grid coordinates of the hex to center on, for example \"AB 1203\" (default is the whole map)")
+	cmdRenderPreview.Flags().IntVar(&argsRenderPreview.radius, "radius", 8, "number of hexes to show around the center")
+	cmdRenderPreview.Flags().StringVar(&argsRenderPreview.color, "color", "auto", "shade the hexes with the terrain colors (auto, always, never)")
+	cmdRender.AddCommand(cmdRenderSummary)
+	addReportFlags(cmdRenderSummary)
+	cmdRenderSummary.Flags().BoolVar(&argsRenderSummary.save, "save", false, "save the summary to the output folder")
+	cmdRenderSummary.Flags().StringVar(&argsRenderSummary.format, "format", "markdown", "output format (markdown or html)")
+	cmdRenderSummary.Flags().StringVar(&argsRenderSummary.turnId, "turn", "", "turn to summarize (yyyy-mm format)")
+	if err := cmdRenderSummary.MarkFlagRequired("turn"); err != nil {
+		log.Fatalf("error: turn: %v\n", err)
+	}
+
+	cmdRoot.AddCommand(cmdReport)
+	cmdReport.AddCommand(cmdReportConsistency)
+	addReportFlags(cmdReportConsistency)
+	cmdReportConsistency.Flags().StringSliceVar(&argsReportConsistency.disable, "disable", nil, "consistency rules to skip")
+	cmdReport.AddCommand(cmdReportContacts)
+	addReportFlags(cmdReportContacts)
+	cmdReport.AddCommand(cmdReportDistances)
+	addReportFlags(cmdReportDistances)
+	cmdReport.AddCommand(cmdReportObscured)
+	addReportFlags(cmdReportObscured)
+	cmdReport.AddCommand(cmdReportOrders)
+	addReportFlags(cmdReportOrders)
+	cmdReport.AddCommand(cmdReportResources)
+	addReportFlags(cmdReportResources)
+	cmdReportResources.Flags().IntVar(&argsReportResources.movementPoints, "movement-points", pathfinding.DefaultMovementPoints, "movement points the expedition has each turn")
+	cmdReport.AddCommand(cmdReportRoster)
+	addReportFlags(cmdReportRoster)
+	cmdReport.AddCommand(cmdReportStats)
+	addReportFlags(cmdReportStats)
+	cmdReportStats.Flags().BoolVar(&argsReportStats.json, "json", false, "write the statistics as JSON")
+
+	cmdRoot.AddCommand(cmdConfig)
+	cmdConfig.AddCommand(cmdConfigCheck)
+	cmdConfigCheck.Flags().StringVar(&argsConfig.clanId, "clan-id", "", "clan whose section is applied")
+	cmdConfigCheck.Flags().StringVar(&argsConfig.data, "data", "data", "path to root of data files")
+	cmdConfigCheck.Flags().StringVar(&argsConfig.path, "config", "", "path to the configuration file (default ottomap.json in the data folder)")
+	cmdConfigCheck.Flags().StringVar(&argsConfig.profile, "profile", "", "configuration profile to apply (default \"default\" if the file has one)")
 
 	cmdRoot.AddCommand(cmdScrub)
 	cmdScrub.AddCommand(cmdScrubFile)
 	cmdScrub.AddCommand(cmdScrubFiles)
 
+	cmdRoot.AddCommand(cmdTniif)
+	cmdTniif.AddCommand(cmdTniifDiff)
+	cmdTniif.AddCommand(cmdTniifMerge)
+	cmdTniifMerge.Flags().StringVar(&argsTniifMerge.output, "output", "", "file to write the merged document to (default stdout)")
+	cmdRoot.AddCommand(cmdWxx)
+	cmdWxx.AddCommand(cmdWxxDiff)
+	cmdWxxDiff.Flags().StringSliceVar(&argsWxxDiff.ignoreLayers, "ignore-layer", []string{"Tribenet Coords", "Legend"}, "layers to skip when comparing features and labels")
+
 	cmdRoot.AddCommand(cmdVersion)
 
 	return cmdRoot.Execute()
@@ -134,7 +371,9 @@ var argsRoot struct {
 		fd   *os.File
 	}
 	showVersion bool
-	soloClan    bool // when set, only clans with this id are processed
+	soloClan    bool                // when set, only clans with this id are processed
+	envVars     []string            // environment variables that set flags
+	warnings    *manifest.Counter_t // counts the warnings written to the log
 }
 
 var cmdRoot = &cobra.Command{
@@ -148,12 +387,15 @@ var cmdRoot = &cobra.Command{
 			} else {
 				argsRoot.logFile.fd = fd
 			}
-			log.SetOutput(argsRoot.logFile.fd)
+			argsRoot.warnings.SetOutput(argsRoot.logFile.fd)
 			argsRoot.showVersion = true
 		}
 		if argsRoot.showVersion {
 			log.Printf("version: %s\n", version)
 		}
+		for _, name := range argsRoot.envVars {
+			log.Printf("env: %s\n", name)
+		}
 		return nil
 	},
 	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/render"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pageIndex_t is the index file written with the printed pages.
+type pageIndex_t struct {
+	Clan    string            `json:"clan"`
+	Turn    string            `json:"turn"`
+	Columns int               `json:"columns"` // hexes across each page
+	Rows    int               `json:"rows"`    // hexes down each page
+	Overlap int               `json:"overlap"` // hexes shared by neighboring pages
+	Pages   []*pageIndexEntry `json:"pages"`
+}
+
+type pageIndexEntry struct {
+	Page   string `json:"page"`   // page name, for example "page-1-2"
+	File   string `json:"file"`   // name of the map file in the folder
+	Column int    `json:"column"` // upper left hex of the page
+	Row    int    `json:"row"`
+	Tiles  int    `json:"tiles"` // number of tiles on the page
+}
+
+// parsePageSize parses a page size like "40x30" into columns and rows.
+func parsePageSize(s string) (columns, rows int, err error) {
+	c, r, ok := strings.Cut(strings.ToLower(s), "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q: want COLUMNSxROWS", s)
+	} else if columns, err = strconv.Atoi(c); err != nil || columns < 2 {
+		return 0, 0, fmt.Errorf("%q: columns must be a number greater than 1", s)
+	} else if rows, err = strconv.Atoi(r); err != nil || rows < 1 {
+		return 0, 0, fmt.Errorf("%q: rows must be a number greater than 0", s)
+	}
+	return columns, rows, nil
+}
+
+// renderPages slices the map into overlapping pages for printing. Each page is
+// written as a map into a folder named after the map, for example
+// "0138/page-1-2.wxx", with an index.json and an index.svg that shows where
+// the pages go.
+func renderPages(ctx context.Context, worldMap *tiles.Map_t, specialNames map[string]*parser.Special_t, mapName, turnId string) error {
+	columns, rows, err := parsePageSize(argsRender.print.pages)
+	if err != nil {
+		return err
+	}
+	m := render.NewMap(filepath.Base(mapName), worldMap)
+	pages, err := m.Pages(columns, rows, argsRender.print.overlap)
+	if err != nil {
+		return err
+	}
+
+	folder := strings.TrimSuffix(mapName, filepath.Ext(mapName))
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return err
+	}
+
+	index := &pageIndex_t{Clan: argsRender.clanId, Turn: turnId, Columns: columns, Rows: rows, Overlap: argsRender.print.overlap}
+	argsRender.progress.Start("write", len(pages))
+	for _, page := range pages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		onPage := func(location coords.Map) bool {
+			return page.Contains(m.Locate(location))
+		}
+		part := tiles.NewMap()
+		part.World = worldMap.World
+		for location, tile := range worldMap.Tiles {
+			if onPage(location) {
+				part.Tiles[location] = tile
+			}
+		}
+		name := page.Name + ".wxx"
+		if err := renderPart(ctx, part, specialNames, onPage, filepath.Join(folder, name), turnId); err != nil {
+			return err
+		}
+		argsRender.progress.Add(1)
+		index.Pages = append(index.Pages, &pageIndexEntry{Page: page.Name, File: name, Column: page.Column, Row: page.Row, Tiles: part.Length()})
+	}
+	argsRender.progress.Done()
+
+	sheetName := filepath.Join(folder, "index.svg")
+	fd, err := os.Create(sheetName)
+	if err != nil {
+		return err
+	} else if err = render.PageIndex(fd, m, pages); err != nil {
+		_ = fd.Close()
+		return err
+	} else if err = fd.Close(); err != nil {
+		return err
+	}
+	log.Printf("created  %s\n", sheetName)
+	if err := argsRender.manifest.AddOutput(sheetName); err != nil {
+		return err
+	}
+
+	log.Printf("print: %d pages of %dx%d hexes\n", len(index.Pages), columns, rows)
+	return writeIndex(filepath.Join(folder, "index.json"), index)
+}
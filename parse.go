@@ -3,9 +3,21 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"github.com/playbymail/ottomap/internal/cst"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/manifest"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
 	"github.com/spf13/cobra"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 )
 
 type parsedFileName_t struct {
@@ -28,8 +40,11 @@ func (i parsedFileName_t) Turn() string {
 }
 
 var argsParseFiles struct {
-	turnId string
-	clanId string
+	turnId   string
+	clanId   string
+	pipeline string // legacy, new, or compare
+	output   string // path to write the document to, stdout if empty
+	maxDiff  int    // maximum number of lines of differences to show
 }
 
 var cmdParse = &cobra.Command{
@@ -40,22 +55,143 @@ var cmdParse = &cobra.Command{
 
 var cmdParseFile = &cobra.Command{
 	Use:   "file",
-	Short: "parse a specific scrubbed file",
-	Long:  `Parse a specific scrubbed file.`,
+	Short: "parse a specific report file",
+	Long: `Parse a specific report or scrubbed file and write the TribeNet interchange format (tniif) document.
+The legacy pipeline uses the Pigeon parser.
+The new pipeline uses the lexer, concrete syntax tree, and abstract syntax tree.
+The compare pipeline runs both and shows the differences between their documents.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		switch argsParseFiles.pipeline {
+		case "legacy", "new", "compare":
+		default:
+			return fmt.Errorf("pipeline must be legacy, new, or compare")
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			log.Fatalf("error: expected file name to parse\n")
 		}
-		input, err := validateScrubbedFileName(args[0])
+		m := manifest.New("parse file", version.String(), cmd.Flags(), argsRoot.warnings)
+		fid, tid, data, err := readReportFile(args[0])
 		if err != nil {
 			log.Fatalf("error: %v\n", err)
 		}
 
-		log.Printf("scrubbed %q\n", input.Name())
-		log.Printf("created  %q\n", input.Id())
+		var doc *tniif.Document_t
+		switch argsParseFiles.pipeline {
+		case "legacy":
+			doc, err = parseLegacy(fid, tid, data, 0)
+		case "new":
+			doc, err = parseNew(fid, tid, data)
+		case "compare":
+			doc, err = parseCompare(fid, tid, data)
+		}
+		if err != nil {
+			log.Fatalf("error: %s: %v\n", fid, err)
+		}
+
+		if err := writeDocument(argsParseFiles.output, doc); err != nil {
+			log.Fatalf("error: %s: %v\n", fid, err)
+		}
+
+		// documents written to stdout don't get a manifest
+		if argsParseFiles.output != "" {
+			if err := m.AddInputFile(fid, args[0]); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			} else if err = m.AddOutput(argsParseFiles.output); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			} else if err = m.Write(manifest.Path(argsParseFiles.output)); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			}
+		}
 	},
 }
 
+// readReportFile returns the report id, turn id, and normalized text of a report or scrubbed file.
+func readReportFile(path string) (fid, tid string, data []byte, err error) {
+	name := filepath.Base(path)
+	if matches := rxUploadName.FindStringSubmatch(name); matches != nil {
+		fid, tid = strings.TrimSuffix(name, ".report.txt"), fmt.Sprintf("%s-%s", matches[1], matches[2])
+	} else if input, err := validateScrubbedFileName(name); err != nil {
+		return "", "", nil, fmt.Errorf("%s: %w", name, err)
+	} else {
+		fid, tid = input.Id(), input.Turn()
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return "", "", nil, err
+	}
+	if clean, fixes := extract.Normalize(data); len(fixes) != 0 {
+		log.Printf("warn: %q: %s\n", fid, strings.Join(fixes, ", "))
+		data = clean
+	}
+	data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+	data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
+	return fid, tid, data, nil
+}
+
+// parseLegacy parses the report with the Pigeon parser.
+// Unit sections are parsed concurrently when workers is more than one.
+func parseLegacy(fid, tid string, data []byte, workers int) (*tniif.Document_t, error) {
+	turn, err := parser.ParseInput(context.Background(), fid, tid, data, false, false, false, false, false, false, false, false, parser.ParseConfig{Workers: workers})
+	if err != nil {
+		return nil, err
+	}
+	return tniif.FromTurn(fid, turn), nil
+}
+
+// parseNew parses the report with the lexer, CST, and AST pipeline.
+func parseNew(fid, tid string, data []byte) (*tniif.Document_t, error) {
+	report, err := cst.Parse(data).ToAST()
+	if err != nil {
+		return nil, err
+	}
+	return tniif.FromAST(fid, tid, report)
+}
+
+// parseNewTurn parses the report with the new pipeline and converts the document
+// to a turn, so that it can be walked and rendered like the legacy parser's output.
+func parseNewTurn(fid, tid string, data []byte) (*parser.Turn_t, error) {
+	doc, err := parseNew(fid, tid, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Errors) != 0 {
+		log.Printf("warn: %q: skipped %d sections after internal errors: please report them\n", fid, len(doc.Errors))
+	}
+	return tniif.ToTurn(doc)
+}
+
+// parseCompare parses the report with both pipelines and logs the differences.
+// It returns the legacy document, or an error if the documents are different.
+func parseCompare(fid, tid string, data []byte) (*tniif.Document_t, error) {
+	legacy, err := parseLegacy(fid, tid, data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("legacy: %w", err)
+	}
+	next, err := parseNew(fid, tid, data)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+	want, err := json.MarshalIndent(legacy, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	got, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if diffs := testkit.Diff(want, got, argsParseFiles.maxDiff); len(diffs) != 0 {
+		for _, line := range diffs {
+			log.Printf("compare: %s: %s\n", fid, line)
+		}
+		return nil, fmt.Errorf("legacy and new pipelines differ")
+	}
+	log.Printf("compare: %s: legacy and new pipelines agree\n", fid)
+	return legacy, nil
+}
+
 func parseScrubbedFiles(files []*scrubbedFileName_t) error {
 	return nil
 }
@@ -0,0 +1,131 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+)
+
+var argsParseBench struct {
+	pipeline   string // legacy, new, or both
+	iterations int    // number of times to parse each input
+	cpuProfile string // path to write the CPU profile to, optional
+	memProfile string // path to write the allocation profile to, optional
+	workers    int    // number of workers for the legacy parser
+}
+
+var cmdParseBench = &cobra.Command{
+	Use:   "bench [report ...]",
+	Short: "measure parser throughput",
+	Long: `Parse reports repeatedly and report throughput (MB/s and lines/s) and allocations per line for each pipeline.
+When no reports are given, a large synthetic report with every unit of clan 0138 is used.
+Both pipelines produce a tniif document, so the times include building the document.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		switch argsParseBench.pipeline {
+		case "legacy", "new", "both":
+		default:
+			return fmt.Errorf("pipeline must be legacy, new, or both")
+		}
+		if argsParseBench.iterations < 1 {
+			return fmt.Errorf("iterations must be at least 1")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		type input_t struct {
+			fid, tid string
+			data     []byte
+		}
+		var inputs []input_t
+		var size, lines int
+		if len(args) == 0 {
+			inputs = append(inputs, input_t{fid: "0902-02.0138", tid: "0902-02", data: testkit.LargeReport("0138")})
+		}
+		for _, path := range args {
+			fid, tid, data, err := readReportFile(path)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			inputs = append(inputs, input_t{fid: fid, tid: tid, data: data})
+		}
+		for _, input := range inputs {
+			size, lines = size+len(input.data), lines+bytes.Count(input.data, []byte{'\n'})
+		}
+		log.Printf("bench: %d inputs: %d bytes: %d lines: %d iterations\n", len(inputs), size, lines, argsParseBench.iterations)
+
+		pipelines := []struct {
+			name  string
+			parse func(fid, tid string, data []byte) (*tniif.Document_t, error)
+		}{
+			{"legacy", func(fid, tid string, data []byte) (*tniif.Document_t, error) {
+				return parseLegacy(fid, tid, data, argsParseBench.workers)
+			}},
+			{"new", parseNew},
+		}
+
+		if argsParseBench.cpuProfile != "" {
+			fd, err := os.Create(argsParseBench.cpuProfile)
+			if err != nil {
+				log.Fatalf("error: cpu-profile: %v\n", err)
+			}
+			defer fd.Close()
+			if err := pprof.StartCPUProfile(fd); err != nil {
+				log.Fatalf("error: cpu-profile: %v\n", err)
+			}
+			defer pprof.StopCPUProfile()
+		}
+
+		for _, pipeline := range pipelines {
+			if argsParseBench.pipeline != "both" && argsParseBench.pipeline != pipeline.name {
+				continue
+			}
+			// the parsers log every oddity they find, which would swamp the timings
+			log.SetOutput(io.Discard)
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			started := time.Now()
+			for n := 0; n < argsParseBench.iterations; n++ {
+				for _, input := range inputs {
+					if _, err := pipeline.parse(input.fid, input.tid, input.data); err != nil {
+						log.SetOutput(os.Stderr)
+						log.Fatalf("error: %s: %s: %v\n", pipeline.name, input.fid, err)
+					}
+				}
+			}
+			elapsed := time.Since(started)
+			runtime.ReadMemStats(&after)
+			log.SetOutput(os.Stderr)
+
+			totalLines := float64(lines * argsParseBench.iterations)
+			log.Printf("bench: %-6s: %8.2f MB/s %10.0f lines/s %8.1f allocs/line %8.0f B/line %12v/run\n",
+				pipeline.name,
+				float64(size*argsParseBench.iterations)/1e6/elapsed.Seconds(),
+				totalLines/elapsed.Seconds(),
+				float64(after.Mallocs-before.Mallocs)/totalLines,
+				float64(after.TotalAlloc-before.TotalAlloc)/totalLines,
+				elapsed/time.Duration(argsParseBench.iterations))
+		}
+
+		if argsParseBench.memProfile != "" {
+			fd, err := os.Create(argsParseBench.memProfile)
+			if err != nil {
+				log.Fatalf("error: mem-profile: %v\n", err)
+			}
+			defer fd.Close()
+			if err := pprof.Lookup("allocs").WriteTo(fd, 0); err != nil {
+				log.Fatalf("error: mem-profile: %v\n", err)
+			}
+		}
+	},
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+// Package ottomap is the parse, merge, and render pipeline as a library,
+// so that other Go programs can build maps without running the ottomap command.
+//
+// Reports are parsed into TribeNet interchange format documents, which can be
+// saved as JSON, merged, and rendered as Worldographer maps:
+//
+//	doc, err := ottomap.ParseReport(ctx, "0902-02.0138", data)
+//	...
+//	err = ottomap.RenderWXX(ctx, w, []*ottomap.Document{doc}, ottomap.RenderOptions{ClanId: "0138"})
+//
+// The pipeline logs its progress with the standard logger.
+package ottomap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"github.com/playbymail/ottomap/internal/turns"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"io"
+	"regexp"
+)
+
+// Document is a parsed turn report.
+// Its contents are not part of the API. Save and load it with encoding/json,
+// which reads and writes the TribeNet interchange format.
+type Document struct {
+	doc *tniif.Document_t
+}
+
+// Source returns the id of the report, for example "0902-02.0138".
+// The source of a merged document lists the ids of the reports.
+func (d *Document) Source() string {
+	if d.doc == nil {
+		return ""
+	}
+	return d.doc.Source
+}
+
+// Turn returns the turn of the report, for example "0902-02".
+func (d *Document) Turn() string {
+	if d.doc == nil {
+		return ""
+	}
+	return d.doc.Turn
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d *Document) MarshalJSON() ([]byte, error) {
+	if d.doc == nil {
+		return nil, fmt.Errorf("ottomap: empty document")
+	}
+	return json.Marshal(d.doc)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Document) UnmarshalJSON(data []byte) error {
+	var doc tniif.Document_t
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	d.doc = &doc
+	return nil
+}
+
+// RenderOptions are the settings for RenderWXX.
+type RenderOptions struct {
+	// ClanId is the clan the map is for, for example "0138".
+	// Its units are drawn as friendly.
+	ClanId string
+	// OriginGrid is the grid used for obscured hexes that can't be
+	// worked out from the reports. It defaults to "RR".
+	OriginGrid string
+	// StrictLocations returns an error if any "N/A" hexes can't be worked out,
+	// instead of logging a warning.
+	StrictLocations bool
+	// Deterministic uses fixed ids for the features on the map,
+	// so that maps from the same reports are byte for byte the same.
+	Deterministic bool
+	// WorldColumns and WorldRows are the size of the big map in grids, for a
+	// world that wraps around at the edges. Zero means that axis doesn't wrap.
+	WorldColumns, WorldRows int
+}
+
+var rxReportId = regexp.MustCompile(`^(\d{4}-\d{2})\.(\d{4})$`)
+
+// ParseReport parses a turn report with the same parser as the render command.
+// The id is the name of the report without the extension, for example "0902-02.0138".
+func ParseReport(ctx context.Context, id string, data []byte) (*Document, error) {
+	matches := rxReportId.FindStringSubmatch(id)
+	if matches == nil {
+		return nil, fmt.Errorf("ottomap: %q: id must be YYYY-MM.CLAN", id)
+	}
+	tid := matches[1]
+	data, _ = extract.Normalize(data)
+	data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+	data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
+	turn, err := parser.ParseInput(ctx, id, tid, data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		return nil, fmt.Errorf("ottomap: %s: %w", id, err)
+	} else if turn.Id != tid {
+		return nil, fmt.Errorf("ottomap: %s: expected turn %q: got turn %q", id, tid, turn.Id)
+	}
+	return &Document{doc: tniif.FromTurn(id, turn)}, nil
+}
+
+// MergeDocuments combines documents for the same turn, for example the
+// reports of allied clans, into a single document.
+// A unit that is in more than one document must be the same in all of them.
+func MergeDocuments(docs ...*Document) (*Document, error) {
+	list, err := documents(docs)
+	if err != nil {
+		return nil, fmt.Errorf("ottomap: merge: %w", err)
+	}
+	doc, err := tniif.Merge(list...)
+	if err != nil {
+		return nil, fmt.Errorf("ottomap: merge: %w", err)
+	}
+	return &Document{doc: doc}, nil
+}
+
+// documents returns the interchange documents, or an error if any are empty.
+func documents(docs []*Document) ([]*tniif.Document_t, error) {
+	var list []*tniif.Document_t
+	for n, doc := range docs {
+		if doc == nil || doc.doc == nil {
+			return nil, fmt.Errorf("document %d is empty", n+1)
+		}
+		list = append(list, doc.doc)
+	}
+	return list, nil
+}
+
+// RenderWXX walks the units in the documents through every turn and writes
+// the map to w as a Worldographer file. The documents can be for any number
+// of turns and clans.
+//
+// Nothing is written if there's an error or the context is cancelled.
+func RenderWXX(ctx context.Context, w io.Writer, docs []*Document, opts RenderOptions) (err error) {
+	if len(docs) == 0 {
+		return fmt.Errorf("ottomap: render: no documents")
+	}
+	if opts.OriginGrid == "" {
+		opts.OriginGrid = "RR"
+	}
+	// the walk and the map panic on data they don't expect
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ottomap: render: %v", r)
+		}
+	}()
+
+	list, err := documents(docs)
+	if err != nil {
+		return fmt.Errorf("ottomap: render: %w", err)
+	}
+	allTurns := map[string][]*turns.Document_t{}
+	for _, doc := range list {
+		turn, err := tniif.ToTurn(doc)
+		if err != nil {
+			return fmt.Errorf("ottomap: render: %w", err)
+		}
+		// merged documents, with a list of sources, don't belong to a single clan
+		var clanId parser.UnitId_t
+		if matches := rxReportId.FindStringSubmatch(doc.Source); matches != nil {
+			clanId = parser.UnitId_t(matches[2]).Clan()
+		}
+		allTurns[turn.Id] = append(allTurns[turn.Id], &turns.Document_t{Id: doc.Source, ClanId: clanId, Turn: turn})
+	}
+	consolidatedTurns, specialNames, err := turns.Consolidate(allTurns)
+	if err != nil {
+		return fmt.Errorf("ottomap: render: %w", err)
+	}
+	_, err = turns.DeriveLocations(consolidatedTurns, turns.LocationOptions_t{Strict: opts.StrictLocations, OriginGrid: opts.OriginGrid})
+	if err != nil {
+		return fmt.Errorf("ottomap: render: %w", err)
+	}
+	worldMap, err := turns.Walk(ctx, consolidatedTurns, specialNames, coords.World{Columns: opts.WorldColumns, Rows: opts.WorldRows}, opts.OriginGrid, false, true, true, true, false)
+	if err != nil {
+		return fmt.Errorf("ottomap: render: %w", err)
+	} else if worldMap.Length() == 0 {
+		return fmt.Errorf("ottomap: render: no tiles to map")
+	}
+
+	// same defaults as the render command
+	var mapper actions.MapConfig
+	mapper.Render.ShiftMap = true
+	wxxMap, err := actions.MapWorld(ctx, worldMap, specialNames, parser.UnitId_t(opts.ClanId), mapper)
+	if err != nil {
+		return fmt.Errorf("ottomap: render: %w", err)
+	}
+	upperLeft, lowerRight := worldMap.Bounds()
+	turnId := consolidatedTurns[len(consolidatedTurns)-1].Id
+	if err := wxxMap.Encode(ctx, w, turnId, upperLeft, lowerRight, wxx.RenderConfig{FordsAsPills: true, Deterministic: opts.Deterministic}); err != nil {
+		return fmt.Errorf("ottomap: render: %w", err)
+	}
+	return nil
+}
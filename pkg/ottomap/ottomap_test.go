@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package ottomap_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/pkg/ottomap"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestRenderWXX checks that the corpus reports can be parsed and rendered
+// through the library, and that a deterministic map is the same every time.
+func TestRenderWXX(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		var names []string
+		for name := range tc.Reports {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var docs []*ottomap.Document
+		for _, name := range names {
+			doc, err := ottomap.ParseReport(context.Background(), strings.TrimSuffix(name, ".report.txt"), tc.Reports[name])
+			if err != nil {
+				t.Fatalf("%s: %s: %v", tc.Name, name, err)
+			}
+			docs = append(docs, doc)
+		}
+
+		var maps [2]bytes.Buffer
+		for n := range maps {
+			if err := ottomap.RenderWXX(context.Background(), &maps[n], docs, ottomap.RenderOptions{ClanId: tc.ClanId, Deterministic: true}); err != nil {
+				t.Fatalf("%s: render: %v", tc.Name, err)
+			}
+		}
+		if got := maps[0].Bytes(); len(got) < 2 || got[0] != 0x1f || got[1] != 0x8b {
+			t.Errorf("%s: render: want gzip stream, got %d bytes", tc.Name, len(got))
+		} else if !bytes.Equal(got, maps[1].Bytes()) {
+			t.Errorf("%s: render: maps differ between runs", tc.Name)
+		}
+	}
+}
+
+func TestParseReportId(t *testing.T) {
+	for _, id := range []string{"", "0902-02", "0902-02.0138.report.txt", "902-02.0138"} {
+		if _, err := ottomap.ParseReport(context.Background(), id, nil); err == nil {
+			t.Errorf("%q: want error, got nil", id)
+		}
+	}
+}
+
+// TestDocumentJSON checks that a document saved as JSON loads as the same document.
+func TestDocumentJSON(t *testing.T) {
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(io.Discard)
+
+	cases, err := testkit.Cases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range cases {
+		for name, report := range tc.Reports {
+			doc, err := ottomap.ParseReport(context.Background(), strings.TrimSuffix(name, ".report.txt"), report)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", tc.Name, name, err)
+			}
+			data, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("%s: %s: marshal: %v", tc.Name, name, err)
+			}
+			var loaded ottomap.Document
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatalf("%s: %s: unmarshal: %v", tc.Name, name, err)
+			}
+			if loaded.Source() != doc.Source() || loaded.Turn() != doc.Turn() {
+				t.Errorf("%s: %s: want %s %s, got %s %s", tc.Name, name, doc.Source(), doc.Turn(), loaded.Source(), loaded.Turn())
+			}
+			again, _ := json.Marshal(&loaded)
+			if !bytes.Equal(data, again) {
+				t.Errorf("%s: %s: json differs after loading", tc.Name, name)
+			}
+		}
+	}
+
+	if _, err := ottomap.MergeDocuments(&ottomap.Document{}); err == nil {
+		t.Errorf("merge: empty document: want error")
+	}
+}
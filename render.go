@@ -3,18 +3,33 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/config"
+	"github.com/playbymail/ottomap/internal/contacts"
+	"github.com/playbymail/ottomap/internal/coords"
 	"github.com/playbymail/ottomap/internal/edges"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/history"
+	"github.com/playbymail/ottomap/internal/manifest"
 	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/plugins"
+	"github.com/playbymail/ottomap/internal/progress"
 	"github.com/playbymail/ottomap/internal/results"
 	"github.com/playbymail/ottomap/internal/terrain"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/playbymail/ottomap/internal/transform"
 	"github.com/playbymail/ottomap/internal/turns"
 	"github.com/playbymail/ottomap/internal/wxx"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -27,20 +42,46 @@ var argsRender struct {
 		data   string // path to data folder
 		input  string // path to input folder
 		output string // path to output folder
+		store  string // path to the database, set only when rendering from the store
+		config string // path to the configuration file, defaults to ottomap.json in the data folder
+		notes  string // path to the annotations file, defaults to annotations.json in the data folder
 	}
+	config              *config.Config_t
+	profile             string               // profile to apply from the configuration file
+	manifest            *manifest.Manifest_t // inputs and outputs of the current run, if one is being recorded
+	progressFormat      string               // bar, json, or empty for no progress reports
+	progress            *progress.Reporter_t // reports the progress of each phase, nil when not reporting
+	timeout             time.Duration        // maximum time allowed for the render, zero for no limit
+	layerFlags          *pflag.FlagSet       // render flags that the layers in the configuration file can set
 	parser              parser.ParseConfig
 	mapper              actions.MapConfig
 	render              wxx.RenderConfig
+	wxxOptions          []wxx.Option // options for the map, mostly from the config file
+	season              string       // month or season to render the map at, empty for the month of the last turn
 	clanId              string
-	soloElement         string // when set, only this element is rendered
+	reportFormat        string   // name of the report format, or "auto" to select it from the turn
+	pipeline            string   // parser pipeline, legacy or new
+	plugins             []string // names of the plugins that decorate the map
+	decorators          []plugins.Decorator
+	transforms          []*transform.Rule_t // rules from the config file, applied to the merged map
+	soloElement         string              // when set, only this element is rendered
 	originGrid          string
+	locations           turns.LocationOptions_t // how the previous and current hexes are derived
+	explainLocations    bool                    // log how each unit's current hex was derived
 	acceptLoneDash      bool
+	mirrorEdges         bool // copy road, pass, and canal edges onto the neighboring tile
 	autoEOL             bool
 	quitOnInvalidGrid   bool
+	warnOnFleetDrift    bool
 	warnOnInvalidGrid   bool
 	warnOnNewSettlement bool
 	warnOnTerrainChange bool
-	maxTurn             struct { // maximum turn id to use
+	fromTurn            struct { // first turn id to use
+		id    string
+		year  int
+		month int
+	}
+	maxTurn struct { // maximum turn id to use
 		id    string
 		year  int
 		month int
@@ -65,10 +106,30 @@ var argsRender struct {
 		stripCR            bool
 	}
 	saveWithTurnId bool
+	splitByGrid    bool   // when set, write a map for each grid instead of one big map
+	snapshot       string // when set, a testkit snapshot of the turns and tiles is written to this file
 	show           struct {
-		origin   bool
-		shiftMap bool
+		changes   bool
+		contacts  bool
+		history   string // unit to draw the location history for
+		origin    bool
+		shiftMap  bool
+		teleports bool
+		weather   bool
+		reachable struct {
+			unitId         string // unit to compute the reachability overlay for
+			movementPoints int    // movement points available to the unit
+		}
+	}
+	print struct {
+		pages   string // when set, the size of the printed pages in hexes, "COLUMNSxROWS"
+		overlap int    // hexes shared by neighboring pages
 	}
+	dumpMerged struct {
+		path   string // when set, the merged tiles are written to this file
+		format string // json, ndjson, or csv; chosen from the extension when empty
+	}
+	fromMerged string // when set, the map is rendered from this merged tile file instead of the reports
 }
 
 var cmdRender = &cobra.Command{
@@ -95,6 +156,16 @@ var cmdRender = &cobra.Command{
 			return fmt.Errorf("path to data folder is required")
 		}
 
+		if argsRender.print.pages != "" {
+			if argsRender.splitByGrid {
+				return fmt.Errorf("print-pages and split-by-grid can't be used together")
+			} else if _, _, err := parsePageSize(argsRender.print.pages); err != nil {
+				return fmt.Errorf("print-pages: %w", err)
+			} else if argsRender.print.overlap < 0 {
+				return fmt.Errorf("print-overlap must not be negative")
+			}
+		}
+
 		// do the abs path check for data
 		if strings.TrimSpace(argsRender.paths.data) != argsRender.paths.data {
 			log.Fatalf("error: data: leading or trailing spaces are not allowed\n")
@@ -130,6 +201,111 @@ var cmdRender = &cobra.Command{
 			argsRender.paths.output = path
 		}
 
+		// the json stream goes to stdout so that it isn't mixed in with the log
+		progressOutput := os.Stderr
+		if argsRender.progressFormat == "json" {
+			progressOutput = os.Stdout
+		}
+		if reporter, err := progress.New(progressOutput, argsRender.progressFormat); err != nil {
+			return err
+		} else {
+			argsRender.progress = reporter
+			argsRender.mapper.Progress = reporter
+		}
+
+		if argsRender.paths.config == "" {
+			argsRender.paths.config = filepath.Join(argsRender.paths.data, "ottomap.json")
+		}
+		if cfg, err := config.Load(argsRender.paths.config, argsRender.profile, argsRender.clanId); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else {
+			argsRender.config = cfg
+		}
+		if err := applyConfigLayers(cmd, argsRender.config.Layers); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		}
+		if elevations, err := argsRender.config.Elevations.Map(); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else if elevations != nil {
+			argsRender.wxxOptions = append(argsRender.wxxOptions, wxx.WithElevations(elevations))
+		}
+		if argsRender.paths.notes == "" {
+			argsRender.paths.notes = filepath.Join(argsRender.paths.data, "annotations.json")
+		}
+		if hexes, err := annotations.Load(argsRender.paths.notes); err != nil {
+			log.Fatalf("error: annotations: %s: %v\n", argsRender.paths.notes, err)
+		} else if len(hexes) != 0 {
+			log.Printf("annotations: %s: %d hexes\n", argsRender.paths.notes, len(hexes))
+			argsRender.mapper.Show.Annotations = hexes
+		}
+		if rules, err := argsRender.config.TransformRules(); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else {
+			argsRender.transforms = rules
+		}
+		for _, name := range argsRender.plugins {
+			if d, err := plugins.Lookup(name); err != nil {
+				log.Fatalf("error: plugin: %v\n", err)
+			} else {
+				argsRender.decorators = append(argsRender.decorators, d)
+			}
+		}
+		defaultRegionColor, err := argsRender.config.Colors.RegionColor()
+		if err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		}
+		argsRender.render.Encounters = wxx.EncounterPolicy{
+			Turns: argsRender.config.Encounters.Turns,
+			All:   argsRender.config.Encounters.All,
+			Fade:  argsRender.config.Encounters.Fade,
+			Stamp: argsRender.config.Encounters.Stamp,
+		}
+		argsRender.render.Notes = wxx.NotePolicy{
+			TurnPrefix: argsRender.config.Notes.TurnPrefix,
+			Max:        argsRender.config.Notes.Max,
+		}
+		if argsRender.render.ClanColors, err = argsRender.config.Colors.ClanColors(); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		}
+		if argsRender.render.UnitSymbols, err = argsRender.config.Symbols.UnitSymbols(); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		}
+		for _, region := range argsRender.config.Regions {
+			hexes, err := region.Locations()
+			if err != nil {
+				log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+			}
+			color := region.Color
+			if color == "" {
+				color = defaultRegionColor
+			}
+			argsRender.mapper.Show.Regions = append(argsRender.mapper.Show.Regions, wxx.Region{Name: region.Name, Color: color, Hexes: hexes})
+		}
+		if riverNames, err := argsRender.config.RiverNames(); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else if len(riverNames) != 0 {
+			argsRender.wxxOptions = append(argsRender.wxxOptions, wxx.WithRiverNames(riverNames))
+		}
+		if argsRender.season != "" {
+			if _, err := seasonMonth(argsRender.season, ""); err != nil {
+				log.Fatalf("error: season: %v\n", err)
+			}
+		}
+
+		switch argsRender.pipeline {
+		case "legacy", "new":
+		default:
+			log.Fatalf("error: pipeline must be legacy or new\n")
+		}
+
+		if argsRender.reportFormat != "auto" {
+			if format, err := parser.LookupFormat(argsRender.reportFormat); err != nil {
+				log.Fatalf("error: report-format: %v\n", err)
+			} else {
+				argsRender.parser.Format = format
+			}
+		}
+
 		if len(argsRender.originGrid) == 0 {
 			// terminate on ## in location
 			argsRender.quitOnInvalidGrid = true
@@ -140,6 +316,7 @@ var cmdRender = &cobra.Command{
 		} else {
 			// don't quit when we replace ## with the location
 			argsRender.quitOnInvalidGrid = false
+			argsRender.locations.OriginGrid = argsRender.originGrid
 		}
 
 		if argsRender.maxTurn.id == "" {
@@ -170,9 +347,41 @@ var cmdRender = &cobra.Command{
 		}
 		argsRender.maxTurn.id = fmt.Sprintf("%04d-%02d", argsRender.maxTurn.year, argsRender.maxTurn.month)
 
+		if argsRender.fromTurn.id != "" {
+			if year, month, err := turns.ParseTurnId(argsRender.fromTurn.id); err != nil {
+				log.Fatalf("error: from-turn %v\n", err)
+			} else {
+				argsRender.fromTurn.year, argsRender.fromTurn.month = year, month
+				argsRender.fromTurn.id = fmt.Sprintf("%04d-%02d", year, month)
+			}
+			if turns.CompareTurnId(argsRender.fromTurn.id, argsRender.maxTurn.id) > 0 {
+				log.Fatalf("error: from-turn %q: must not be after %q\n", argsRender.fromTurn.id, argsRender.maxTurn.id)
+			}
+		}
+
+		if argsRender.fromMerged != "" {
+			// the merged tiles don't have the moves that these are drawn from
+			if argsRender.show.history != "" {
+				return fmt.Errorf("from-merged and show-unit-history can't be used together")
+			} else if argsRender.show.reachable.unitId != "" {
+				return fmt.Errorf("from-merged and show-reachable can't be used together")
+			} else if argsRender.show.contacts {
+				return fmt.Errorf("from-merged and show-contacts can't be used together")
+			}
+		}
+
+		if argsRender.dumpMerged.path != "" {
+			if _, err := export.FormatOf(argsRender.dumpMerged.format, argsRender.dumpMerged.path); err != nil {
+				log.Fatalf("error: dump-merged-format: %v\n", err)
+			}
+		} else if argsRender.dumpMerged.format != "" {
+			log.Fatalf("error: dump-merged-format: requires --dump-merged\n")
+		}
+
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		argsRender.manifest = manifest.New("render", version.String(), cmd.Flags(), argsRoot.warnings)
 		if argsRoot.showVersion {
 			log.Printf("ottomap version %s\n", version)
 		}
@@ -194,239 +403,23 @@ var cmdRender = &cobra.Command{
 		log.Printf("input:  %s\n", argsRender.paths.input)
 		log.Printf("output: %s\n", argsRender.paths.output)
 
-		inputs, err := turns.CollectInputs(argsRender.paths.input, argsRender.maxTurn.year, argsRender.maxTurn.month, argsRoot.soloClan, argsRender.clanId)
-		if err != nil {
-			log.Fatalf("error: inputs: %v\n", err)
-		}
-		log.Printf("inputs: found %d turn reports\n", len(inputs))
+		ctx, cancel := renderContext(cmd.Context())
+		defer cancel()
 
-		// allTurns holds the turn and move data and allows multiple clans to be loaded.
-		allTurns := map[string][]*parser.Turn_t{}
-		totalUnitMoves := 0
-		var turnId, maxTurnId string // will be set to the last/maximum turnId we process
-		for _, i := range inputs {
-			started := time.Now()
-			data, err := os.ReadFile(i.Path)
-			if err != nil {
-				log.Fatalf("error: read: %v\n", err)
-			} else if len(data) == 0 {
-				log.Printf("warn: %q: empty file\n", i.Path)
-				continue
-			}
-			if argsRender.autoEOL {
-				data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
-				data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
-			} else if argsRender.experimental.stripCR {
-				data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
-			}
-			if i.Turn.Year < 899 || i.Turn.Year > 9999 || i.Turn.Month < 1 || i.Turn.Month > 12 {
-				log.Printf("warn: %q: invalid turn year '%d'\n", i.Id, i.Turn.Year)
-				continue
-			} else if i.Turn.Month < 1 || i.Turn.Month > 12 {
-				log.Printf("warn: %q: invalid turn month '%d'\n", i.Id, i.Turn.Month)
-				continue
-			}
-			pastCutoff := false
-			if i.Turn.Year > argsRender.maxTurn.year {
-				pastCutoff = true
-			} else if i.Turn.Year == argsRender.maxTurn.year {
-				if i.Turn.Month > argsRender.maxTurn.month {
-					pastCutoff = true
-				}
-			}
-			if pastCutoff {
-				log.Printf("warn: %q: past cutoff %04d-%02d\n", i.Id, argsRender.maxTurn.year, argsRender.maxTurn.month)
-			}
-			turnId = fmt.Sprintf("%04d-%02d", i.Turn.Year, i.Turn.Month)
-			if turnId > maxTurnId {
-				maxTurnId = turnId
-			}
-			turn, err := parser.ParseInput(i.Id, turnId, data, argsRender.acceptLoneDash, argsRender.debug.parser, argsRender.debug.sections, argsRender.debug.steps, argsRender.debug.nodes, argsRender.debug.fleetMovement, argsRender.experimental.splitTrailingUnits, argsRender.experimental.cleanUpScoutStill, argsRender.parser)
-			if err != nil {
-				log.Fatal(err)
-			} else if turnId != fmt.Sprintf("%04d-%02d", turn.Year, turn.Month) {
-				if turn.Year == 0 && turn.Month == 0 {
-					log.Printf("error: unable to locate turn information in file\n")
-					log.Printf("error: this is usually caused by unexpected line endings in the file\n")
-					log.Printf("error: try running with --auto-eol\n")
-				}
-				log.Fatalf("error: expected turn %q: got turn %q\n", turnId, fmt.Sprintf("%04d-%02d", turn.Year, turn.Month))
-			}
-			//log.Printf("len(turn.SpecialNames) = %d\n", len(turn.SpecialNames))
-
-			allTurns[turnId] = append(allTurns[turnId], turn)
-			totalUnitMoves += len(turn.UnitMoves)
-			log.Printf("%q: parsed %6d units in %v\n", i.Id, len(turn.UnitMoves), time.Since(started))
-		}
-		log.Printf("parsed %d inputs in to %d turns and %d units in %v\n", len(inputs), len(allTurns), totalUnitMoves, time.Since(started))
-
-		// consolidate the turns, then sort by year and month
-		var consolidatedTurns []*parser.Turn_t
-		consolidatedSpecialNames := map[string]*parser.Special_t{}
-		foundDuplicates := false
-		for _, unitTurns := range allTurns {
-			if len(unitTurns) == 0 {
-				// we shouldn't have any empty turns, but be safe
-				continue
-			}
-			// create a new turn to hold the consolidated unit moves for the turn
-			turn := &parser.Turn_t{
-				Id:        fmt.Sprintf("%04d-%02d", unitTurns[0].Year, unitTurns[0].Month),
-				Year:      unitTurns[0].Year,
-				Month:     unitTurns[0].Month,
-				UnitMoves: map[parser.UnitId_t]*parser.Moves_t{},
-			}
-			consolidatedTurns = append(consolidatedTurns, turn)
-
-			// copy all the unit moves into this new turn, calling out duplicates
-			for _, unitTurn := range unitTurns {
-				for id, unitMoves := range unitTurn.UnitMoves {
-					if turn.UnitMoves[id] != nil {
-						foundDuplicates = true
-						log.Printf("error: %s: %-6s: duplicate unit\n", turn.Id, id)
-					}
-					turn.UnitMoves[id] = unitMoves
-					turn.SortedMoves = append(turn.SortedMoves, unitMoves)
-				}
-				if unitTurn.SpecialNames != nil {
-					// consolidate any the special hexes
-					for id, special := range unitTurn.SpecialNames {
-						consolidatedSpecialNames[id] = special
-					}
-				}
-			}
-		}
-		if foundDuplicates {
-			log.Fatalf("error: please fix the duplicate units and restart\n")
-		}
-		if len(consolidatedSpecialNames) > 0 {
-			log.Printf("consolidated %d special hex names\n", len(consolidatedSpecialNames))
-		}
-		sort.Slice(consolidatedTurns, func(i, j int) bool {
-			a, b := consolidatedTurns[i], consolidatedTurns[j]
-			if a.Year < b.Year {
-				return true
-			} else if a.Year == b.Year {
-				return a.Month < b.Month
-			}
-			return false
-		})
-		for _, turn := range consolidatedTurns {
-			log.Printf("%s: %8d units\n", turn.Id, len(turn.UnitMoves))
-			sort.Slice(turn.SortedMoves, func(i, j int) bool {
-				return turn.SortedMoves[i].UnitId < turn.SortedMoves[j].UnitId
-			})
-		}
-
-		// link prev and next turns
-		for n, turn := range consolidatedTurns {
-			if n > 0 {
-				turn.Prev = consolidatedTurns[n-1]
-			}
-			if n+1 < len(consolidatedTurns) {
-				turn.Next = consolidatedTurns[n+1]
-			}
-		}
-
-		// check for N/A values in locations and quit if we find any
-		naLocationCount := 0
-		for _, turn := range consolidatedTurns {
-			for _, unitMoves := range turn.UnitMoves {
-				if unitMoves.FromHex == "N/A" {
-					naLocationCount++
-					log.Printf("%s: %-6s: location %q: invalid location\n", unitMoves.TurnId, unitMoves.UnitId, unitMoves.FromHex)
-				}
-			}
-		}
-		if naLocationCount != 0 {
-			log.Fatalf("please update the invalid locations and restart\n")
+		var w *world_t
+		var err error
+		if argsRender.fromMerged != "" {
+			w, err = loadMergedWorld(argsRender.fromMerged)
+		} else {
+			w, err = loadWorld(ctx)
 		}
-
-		// sanity check on the current and prior locations.
-		changedLinks, staticLinks := 0, 0
-		for _, turn := range consolidatedTurns {
-			if turn.Next == nil { // nothing to update
-				continue
-			}
-			for _, unitMoves := range turn.UnitMoves {
-				nextUnitMoves := turn.Next.UnitMoves[unitMoves.UnitId]
-				if nextUnitMoves == nil {
-					continue
-				}
-				if unitMoves.ToHex[2:] != nextUnitMoves.FromHex[2:] {
-					changedLinks++
-					log.Printf("warning: %s: %-6s: from %q\n", turn.Id, unitMoves.UnitId, unitMoves.ToHex)
-					log.Printf("       : %s: %-6s: to   %q\n", turn.Next.Id, nextUnitMoves.UnitId, nextUnitMoves.FromHex)
-				} else {
-					staticLinks++
-				}
-				nextUnitMoves.FromHex = unitMoves.ToHex
-			}
-		}
-		log.Printf("links: %d same, %d changed\n", staticLinks, changedLinks)
-		if changedLinks != 0 {
-			// this can happen when an element is destroyed and another created with the same name
-			// during a single turn.
-			log.Printf("warning: the previous and current hexes don't align in some reports\n")
-			log.Printf("warning: if you didn't destroy a unit and create another with the\n")
-			log.Printf("warning: same name in a single turn, then there may be a bug here.\n")
-		}
-
-		// proactively patch some of the obscured locations.
-		// turn reports initially gave obscured locations for from and to hexes.
-		// around 0902-02, the current location stopped being obscured,
-		// but the previous location is still obscured.
-		// NB: links between the locations must be validated before patching them!
-		updatedCurrentLinks, updatedPreviousLinks := 0, 0
-		for _, turn := range consolidatedTurns {
-			for _, unitMoves := range turn.UnitMoves {
-				var prevTurnMoves *parser.Moves_t
-				if turn.Prev != nil {
-					prevTurnMoves = turn.Prev.UnitMoves[unitMoves.UnitId]
-				}
-				var nextTurnMoves *parser.Moves_t
-				if turn.Next != nil {
-					nextTurnMoves = turn.Next.UnitMoves[unitMoves.UnitId]
-				}
-				//if unitMoves.Id == "0138" {
-				//	log.Printf("this: %s: %-6s: this prior %q current %q\n", unitMoves.TurnId, unitMoves.Id, unitMoves.FromHex, unitMoves.ToHex)
-				//	if prevTurnMoves != nil {
-				//		log.Printf("      %s: %-6s: prev prior %q current %q\n", prevTurnMoves.TurnId, prevTurnMoves.Id, prevTurnMoves.FromHex, prevTurnMoves.ToHex)
-				//	}
-				//	if nextTurnMoves != nil {
-				//		log.Printf("      %s: %-6s: next prior %q current %q\n", nextTurnMoves.TurnId, nextTurnMoves.Id, nextTurnMoves.FromHex, nextTurnMoves.ToHex)
-				//	}
-				//}
-
-				// link prior.ToHex and this.FromHex if this.FromHex is not obscured
-				if !strings.HasPrefix(unitMoves.FromHex, "##") && prevTurnMoves != nil {
-					if prevTurnMoves.ToHex != unitMoves.FromHex {
-						updatedPreviousLinks++
-						prevTurnMoves.ToHex = unitMoves.FromHex
-					}
-				}
-
-				// link this.ToHex and next.FromHex if this.ToHex is not obscured
-				if !strings.HasPrefix(unitMoves.ToHex, "##") && nextTurnMoves != nil {
-					if unitMoves.ToHex != nextTurnMoves.FromHex {
-						updatedCurrentLinks++
-						nextTurnMoves.FromHex = unitMoves.ToHex
-					}
-				}
-			}
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
 		}
-		log.Printf("updated %8d obscured 'Previous Hex' locations\n", updatedPreviousLinks)
-		log.Printf("updated %8d obscured 'Current Hex'  locations\n", updatedCurrentLinks)
-
-		// dangerous but try to find the origin hex if asked
-		if argsRender.show.origin {
-			for _, turn := range consolidatedTurns {
-				for _, unit := range turn.SortedMoves {
-					argsRender.mapper.Origin = unit.Location
-					break
-				}
-			}
-			log.Printf("info: origin hex set to %q\n", argsRender.mapper.Origin)
+		consolidatedTurns, consolidatedSpecialNames, worldMap := w.turns, w.specialNames, w.tiles
+		turnId, maxTurnId := w.turnId, w.maxTurnId
+		if argsRender.mirrorEdges {
+			log.Printf("map: mirrored %d edges\n", worldMap.MirrorEdges())
 		}
 
 		// dangerous, shift the map
@@ -435,18 +428,6 @@ var cmdRender = &cobra.Command{
 			log.Printf("warn: will shift map up and left\n")
 		}
 
-		// walk the data
-		worldMap, err := turns.Walk(consolidatedTurns, consolidatedSpecialNames, argsRender.originGrid, argsRender.quitOnInvalidGrid, argsRender.warnOnInvalidGrid, argsRender.warnOnNewSettlement, argsRender.warnOnTerrainChange, argsRender.debug.maps)
-		if err != nil {
-			log.Fatalf("error: %v\n", err)
-		}
-		if argsRender.soloElement != "" {
-			log.Printf("info: rendering only %q\n", argsRender.soloElement)
-			solo := worldMap.Solo(argsRender.soloElement)
-			log.Printf("info: %s: world %d tiles: solo %d\n", argsRender.soloElement, len(worldMap.Tiles), len(solo.Tiles))
-			worldMap = solo
-		}
-
 		if argsRender.debug.dumpAllTurns {
 			log.Printf("hey, dumping it all\n")
 			for _, turn := range consolidatedTurns {
@@ -488,32 +469,299 @@ var cmdRender = &cobra.Command{
 				}
 			}
 		}
+		if argsRender.show.reachable.unitId != "" {
+			unitId := parser.UnitId_t(argsRender.show.reachable.unitId)
+			// find the unit's ending location by walking backwards from the last turn
+			var location coords.Map
+			for n := len(consolidatedTurns) - 1; n >= 0 && location.IsZero(); n-- {
+				if moves, ok := consolidatedTurns[n].UnitMoves[unitId]; ok {
+					location = moves.Location
+				}
+			}
+			if location.IsZero() {
+				log.Fatalf("error: show-reachable: %q: unit not found\n", unitId)
+			}
+			argsRender.mapper.Show.Reachable = pathfinding.Reachable(worldMap, location, argsRender.show.reachable.movementPoints)
+			log.Printf("info: %s: %s: %d hexes reachable with %d movement points\n", unitId, location.GridString(), len(argsRender.mapper.Show.Reachable), argsRender.show.reachable.movementPoints)
+		}
+		if argsRender.show.history != "" {
+			unitId := parser.UnitId_t(argsRender.show.history)
+			var found bool
+			for _, u := range history.Build(consolidatedTurns, worldMap) {
+				if u.Id != unitId {
+					continue
+				}
+				found = true
+				h := wxx.UnitHistory{UnitId: u.Id}
+				for _, turn := range u.Turns {
+					h.Turns = append(h.Turns, wxx.UnitTurn{TurnId: turn.Turn, Path: turn.Path})
+				}
+				argsRender.mapper.Show.UnitHistory = append(argsRender.mapper.Show.UnitHistory, h)
+			}
+			if !found {
+				log.Fatalf("error: show-unit-history: %q: unit not found\n", unitId)
+			}
+		}
+		if argsRender.show.teleports {
+			for _, turn := range consolidatedTurns {
+				for _, moves := range turn.SortedMoves {
+					if moves.GoesTo == "" || moves.StartLocation == moves.Location {
+						continue
+					}
+					argsRender.mapper.Show.Teleports = append(argsRender.mapper.Show.Teleports, wxx.Teleport{
+						UnitId: moves.UnitId,
+						From:   moves.StartLocation,
+						To:     moves.Location,
+					})
+				}
+			}
+		}
+		if argsRender.show.weather {
+			for _, turn := range consolidatedTurns {
+				if turn.Season == "" && turn.Weather == "" {
+					continue
+				}
+				argsRender.render.Show.Weather = append(argsRender.render.Show.Weather, fmt.Sprintf("%s: %s, %s", turn.Id, turn.Season, turn.Weather))
+			}
+		}
+
+		// add the latest inventory for each clan unit to the map notes
+		argsRender.mapper.Show.Inventory = map[parser.UnitId_t]string{}
+		for _, unit := range clanUnitLocations(w, parser.UnitId_t(argsRender.clanId)) {
+			if unit.moves.Inventory != nil {
+				argsRender.mapper.Show.Inventory[unit.id] = unit.moves.Inventory.String()
+			}
+		}
+		if argsRender.show.contacts {
+			intel, err := updateContacts(w)
+			if err != nil {
+				log.Fatalf("error: contacts: %v\n", err)
+			} else if err = argsRender.manifest.AddOutput(contactsPath()); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			}
+			argsRender.mapper.Show.Contacts = map[coords.Map][]wxx.Contact{}
+			histories := intel.Histories(maxTurnId)
+			for _, h := range histories {
+				last := h.LastSeen()
+				argsRender.mapper.Show.Contacts[last.Location] = append(argsRender.mapper.Show.Contacts[last.Location], wxx.Contact{
+					UnitId: h.UnitId,
+					Age:    contacts.Age(last.TurnId, maxTurnId),
+				})
+			}
+			log.Printf("info: contacts: %d foreign units on the map\n", len(histories))
+		}
+
 		upperLeft, lowerRight := worldMap.Bounds()
 
 		if argsRender.debug.dumpAllTiles {
 			worldMap.Dump()
 		}
 
-		// map the data
-		wxxMap, err := actions.MapWorld(worldMap, consolidatedSpecialNames, parser.UnitId_t(argsRender.clanId), argsRender.mapper)
-		if err != nil {
-			log.Fatalf("error: %v\n", err)
+		// seasonal rules depend on the month being rendered
+		if month, err := seasonMonth(argsRender.season, turnId); err != nil {
+			log.Fatalf("error: season: %v\n", err)
+		} else if icy, err := argsRender.config.Seasons.IcyTerrains(month); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else if icy != nil {
+			log.Printf("map: rendering month %d: %d icy terrains\n", month, len(icy))
+			argsRender.wxxOptions = append(argsRender.wxxOptions, wxx.WithIcyTerrains(icy))
+		}
+
+		if argsRender.show.changes {
+			argsRender.mapper.Show.Changes = maxTurnId
+		}
+
+		// apply the player's transforms before the plugins see the map
+		if len(argsRender.transforms) != 0 {
+			list, results := transform.Apply(worldMap, argsRender.transforms, maxTurnId)
+			for _, r := range results {
+				log.Printf("transform: %q: %d hexes, dropped %d encounters\n", r.Rule.Name, r.Matched, r.Encounters)
+			}
+			argsRender.mapper.Show.Annotations = append(argsRender.mapper.Show.Annotations, list...)
+		}
+
+		// let the plugins add their annotations to the player's
+		if len(argsRender.decorators) != 0 {
+			hexes := plugins.Hexes(worldMap)
+			for n, d := range argsRender.decorators {
+				list, err := d.Decorate(ctx, hexes)
+				if err != nil {
+					log.Fatalf("error: plugin: %s: %v\n", argsRender.plugins[n], err)
+				}
+				log.Printf("plugin: %s: %d hexes\n", argsRender.plugins[n], len(list))
+				argsRender.mapper.Show.Annotations = append(argsRender.mapper.Show.Annotations, list...)
+			}
 		}
-		log.Printf("map: %8d nodes: elapsed %v\n", worldMap.Length(), time.Since(started))
 
 		// now we can create the Worldographer map!
 		var mapName string
-		if argsRender.saveWithTurnId {
+		if name, err := argsRender.config.Output.MapName(argsRender.clanId, maxTurnId); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else if name != "" {
+			mapName = filepath.Join(argsRender.paths.output, name)
+		} else if argsRender.saveWithTurnId {
 			mapName = filepath.Join(argsRender.paths.output, fmt.Sprintf("%s.%s.wxx", maxTurnId, argsRender.clanId))
 		} else {
 			mapName = filepath.Join(argsRender.paths.output, fmt.Sprintf("%s.wxx", argsRender.clanId))
 		}
-		if err := wxxMap.Create(mapName, turnId, upperLeft, lowerRight, argsRender.render); err != nil {
-			log.Printf("creating %s\n", mapName)
-			log.Fatalf("error: %v\n", err)
+		if show, err := argsRender.config.Legend.Show(); err != nil {
+			log.Fatalf("error: config: %s: %v\n", argsRender.paths.config, err)
+		} else if show != nil {
+			argsRender.render.Legend = &wxx.Legend{
+				Title:   argsRender.config.Legend.Title,
+				Terrain: show["terrain"],
+				Edges:   show["edges"],
+				Units:   show["units"],
+			}
+			if show["metadata"] {
+				span := fmt.Sprintf("Turns to %s", maxTurnId)
+				if len(consolidatedTurns) != 0 {
+					span = fmt.Sprintf("Turns %s to %s", consolidatedTurns[0].Id, consolidatedTurns[len(consolidatedTurns)-1].Id)
+				}
+				argsRender.render.Legend.Metadata = []string{
+					fmt.Sprintf("Clan %s", argsRender.clanId),
+					span,
+					fmt.Sprintf("Created by ottomap %s", version),
+					fmt.Sprintf("Reports %s", strings.Join(w.sources, ", ")),
+				}
+			}
+		}
+		argsRender.render.Hexes = wxx.HexLayout{
+			HexWidth:  argsRender.config.Hexes.Width,
+			HexHeight: argsRender.config.Hexes.Height,
+			ViewLevel: strings.ToUpper(argsRender.config.Hexes.ViewLevel),
+		}
+		if argsRender.splitByGrid {
+			if err := renderByGrid(ctx, worldMap, consolidatedSpecialNames, mapName, turnId); err != nil {
+				log.Fatalf("error: split by grid: %v\n", err)
+			}
+		} else if argsRender.print.pages != "" {
+			if err := renderPages(ctx, worldMap, consolidatedSpecialNames, mapName, turnId); err != nil {
+				log.Fatalf("error: print pages: %v\n", err)
+			}
+		} else {
+			// map the data
+			wxxMap, err := actions.MapWorld(ctx, worldMap, consolidatedSpecialNames, parser.UnitId_t(argsRender.clanId), argsRender.mapper, argsRender.wxxOptions...)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			log.Printf("map: %8d nodes: elapsed %v\n", worldMap.Length(), time.Since(started))
+
+			argsRender.progress.Start("write", 0)
+			if err := wxxMap.Create(ctx, mapName, turnId, upperLeft, lowerRight, argsRender.render); err != nil {
+				log.Printf("creating %s\n", mapName)
+				log.Fatalf("error: %v\n", err)
+			}
+			argsRender.progress.Done()
+			log.Printf("created  %s\n", mapName)
+			if err := argsRender.manifest.AddOutput(mapName); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			}
+		}
+
+		if argsRender.snapshot != "" {
+			data, err := testkit.Snapshot(consolidatedTurns, worldMap)
+			if err != nil {
+				log.Fatalf("error: snapshot: %v\n", err)
+			} else if err = os.WriteFile(argsRender.snapshot, data, 0644); err != nil {
+				log.Fatalf("error: snapshot: %v\n", err)
+			}
+			log.Printf("created  %s\n", argsRender.snapshot)
+			if err := argsRender.manifest.AddOutput(argsRender.snapshot); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			}
 		}
-		log.Printf("created  %s\n", mapName)
+
+		if argsRender.dumpMerged.path != "" {
+			if err := dumpMerged(worldMap, argsRender.dumpMerged.path, argsRender.dumpMerged.format); err != nil {
+				log.Fatalf("error: dump-merged: %v\n", err)
+			}
+			log.Printf("created  %s\n", argsRender.dumpMerged.path)
+			if err := argsRender.manifest.AddOutput(argsRender.dumpMerged.path); err != nil {
+				log.Fatalf("error: manifest: %v\n", err)
+			}
+		}
+
+		notifyRender(consolidatedTurns, worldMap, maxTurnId, mapName)
+
+		manifestName := manifest.Path(mapName)
+		if err := argsRender.manifest.Write(manifestName); err != nil {
+			log.Fatalf("error: manifest: %v\n", err)
+		}
+		log.Printf("created  %s\n", manifestName)
 
 		log.Printf("elapsed: %v\n", time.Since(started))
 	},
 }
+
+// seasonMonth returns the month to render the map at. The season may be
+// a month number, a season name, or empty for the month of the turn.
+func seasonMonth(season, turnId string) (int, error) {
+	switch strings.ToLower(season) {
+	case "":
+		_, mm, ok := strings.Cut(turnId, "-")
+		if !ok {
+			return 0, fmt.Errorf("turn %q: must be yyyy-mm format", turnId)
+		}
+		return strconv.Atoi(mm)
+	case "winter":
+		return 1, nil
+	case "spring":
+		return 4, nil
+	case "summer":
+		return 7, nil
+	case "autumn", "fall":
+		return 10, nil
+	}
+	month, err := strconv.Atoi(season)
+	if err != nil || month < 1 || month > 12 {
+		return 0, fmt.Errorf("%q: must be a month (1-12) or winter, spring, summer, or autumn", season)
+	}
+	return month, nil
+}
+
+// applyConfigLayers sets the render --show-* flags from the layers in the configuration.
+// Flags set on the command line win. Commands that don't have the flag ignore the layer.
+func applyConfigLayers(cmd *cobra.Command, layers map[string]bool) error {
+	if err := checkConfigLayers(layers); err != nil {
+		return err
+	}
+	var names []string
+	for name := range layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if flag := cmd.Flags().Lookup("show-" + name); flag == nil || flag.Changed {
+			continue
+		} else if err := flag.Value.Set(strconv.FormatBool(layers[name])); err != nil {
+			return fmt.Errorf("layers: %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// checkConfigLayers returns an error if a layer isn't one of the boolean render --show-* flags.
+func checkConfigLayers(layers map[string]bool) error {
+	var errs []error
+	for name := range layers {
+		if flag := argsRender.layerFlags.Lookup("show-" + name); flag == nil || flag.Value.Type() != "bool" {
+			errs = append(errs, fmt.Errorf("layers: unknown layer %q", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// renderContext returns a context that is cancelled when the user interrupts
+// the render or when the --timeout expires.
+func renderContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt)
+	if argsRender.timeout <= 0 {
+		return ctx, stop
+	}
+	ctx, cancel := context.WithTimeout(ctx, argsRender.timeout)
+	return ctx, func() {
+		cancel()
+		stop()
+	}
+}
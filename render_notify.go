@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"github.com/playbymail/ottomap/internal/notify"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// notifyRender posts a summary of the latest turn to the webhook in the configuration.
+// Failures are logged as warnings since the map has already been created.
+func notifyRender(consolidatedTurns []*parser.Turn_t, worldMap *tiles.Map_t, maxTurnId, mapName string) {
+	if argsRender.config == nil || argsRender.config.Notify.Webhook == "" {
+		return
+	}
+	summary := notify.Summary_t{
+		ClanId:         argsRender.clanId,
+		TurnId:         maxTurnId,
+		NewSettlements: newSettlements(consolidatedTurns, maxTurnId),
+		MapFile:        filepath.Base(mapName),
+		MapURL:         argsRender.config.Notify.MapURL,
+	}
+	for _, tile := range worldMap.Tiles {
+		if tile.FirstSeen == maxTurnId {
+			summary.TilesAdded++
+		}
+	}
+	message, err := notify.Message(argsRender.config.Notify.Template, summary)
+	if err != nil {
+		log.Printf("warn: notify: template: %v\n", err)
+		return
+	}
+	if err := notify.Post(context.Background(), argsRender.config.Notify.Webhook, message); err != nil {
+		log.Printf("warn: notify: %v\n", err)
+		return
+	}
+	log.Printf("notify: posted summary for %s\n", maxTurnId)
+}
+
+// newSettlements returns the names of the settlements reported in the turn
+// that were not reported in any earlier turn.
+func newSettlements(consolidatedTurns []*parser.Turn_t, turnId string) []string {
+	seen, found := map[string]bool{}, map[string]string{}
+	for _, turn := range consolidatedTurns {
+		if turn.Id > turnId {
+			break
+		}
+		for _, unit := range turn.SortedMoves {
+			var moves []*parser.Move_t
+			moves = append(moves, unit.Moves...)
+			for _, scout := range unit.Scouts {
+				moves = append(moves, scout.Moves...)
+			}
+			for _, move := range moves {
+				if move.Report == nil {
+					continue
+				}
+				for _, settlement := range move.Report.Settlements {
+					key := strings.ToLower(settlement.Name)
+					if turn.Id != turnId {
+						seen[key] = true
+					} else if !seen[key] {
+						found[key] = settlement.Name
+					}
+				}
+			}
+		}
+	}
+	var list []string
+	for _, name := range found {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/render"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+var argsRenderPreview struct {
+	center string // grid coordinates of the hex to center on, whole map if empty
+	radius int    // number of hexes to show around the center
+	color  string // auto, always, or never
+}
+
+var cmdRenderPreview = &cobra.Command{
+	Use:   "preview",
+	Short: "print the map in the terminal",
+	Long: `Print the merged map as text, two characters for each hex, keyed by terrain.
+The odd columns are printed on the lines between the even columns.
+Colors are used when the output is a terminal and NO_COLOR isn't set.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if argsRenderPreview.center != "" {
+			if _, err := coords.HexToMap(argsRenderPreview.center); err != nil {
+				return fmt.Errorf("center: %w", err)
+			}
+		}
+		if argsRenderPreview.radius < 0 {
+			return fmt.Errorf("radius must not be negative")
+		}
+		switch argsRenderPreview.color {
+		case "auto", "always", "never":
+		default:
+			return fmt.Errorf("color must be auto, always, or never")
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		text := render.Text{Radius: argsRenderPreview.radius, Color: useColor(argsRenderPreview.color)}
+		if argsRenderPreview.center != "" {
+			center, _ := coords.HexToMap(argsRenderPreview.center)
+			text.Center = &center
+		}
+		m := render.NewMap(fmt.Sprintf("Clan %s %s", argsRender.clanId, w.maxTurnId), w.tiles)
+		if err := text.Render(os.Stdout, m); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+// useColor returns true if the output should use ANSI colors.
+// The setting is auto, always, or never.
+func useColor(setting string) bool {
+	switch setting {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
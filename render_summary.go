@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+var argsRenderSummary struct {
+	turnId string // turn to summarize (yyyy-mm format)
+	format string // markdown or html
+	save   bool   // if set, save the summary to the output folder
+}
+
+var cmdRenderSummary = &cobra.Command{
+	Use:   "summary",
+	Short: "create a digest of a single turn",
+	Long:  `Summarize the new hexes, settlements, encounters, and failed movements for a turn.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(argsRenderSummary.turnId) != 7 || argsRenderSummary.turnId[4] != '-' {
+			return fmt.Errorf("turn must be in yyyy-mm format")
+		}
+		switch argsRenderSummary.format {
+		case "html", "markdown":
+		default:
+			return fmt.Errorf("format must be html or markdown")
+		}
+		// the summary needs every turn up to the one being summarized
+		if argsRender.maxTurn.id == "" {
+			argsRender.maxTurn.id = argsRenderSummary.turnId
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		summary, err := actions.SummarizeTurn(w.turns, w.tiles, parser.UnitId_t(argsRender.clanId), argsRenderSummary.turnId)
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		var data []byte
+		var ext string
+		switch argsRenderSummary.format {
+		case "html":
+			data, ext = summary.HTML(), ".html"
+		case "markdown":
+			data, ext = summary.Markdown(), ".md"
+		}
+
+		if !argsRenderSummary.save {
+			_, _ = os.Stdout.Write(data)
+			return
+		}
+		path := filepath.Join(argsRender.paths.output, fmt.Sprintf("%s.%s.summary%s", argsRenderSummary.turnId, argsRender.clanId, ext))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		log.Printf("summary: created %s\n", path)
+	},
+}
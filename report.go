@@ -0,0 +1,536 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/compass"
+	"github.com/playbymail/ottomap/internal/consistency"
+	"github.com/playbymail/ottomap/internal/contacts"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/history"
+	"github.com/playbymail/ottomap/internal/orders"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/stats"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// report commands use the same settings as the render command to load the data.
+// they register only the flags that they need, bound to argsRender.
+var cmdReport = &cobra.Command{
+	Use:   "report",
+	Short: "create reports from turn report data",
+	Long:  `Load and parse turn reports and print reports from the merged data.`,
+}
+
+var argsReportConsistency struct {
+	disable []string // names of the rules to skip
+}
+
+var cmdReportConsistency = &cobra.Command{
+	Use:   "consistency",
+	Short: "print map data that breaks the consistency rules",
+	Long: `Check the merged map for data that can't be right: a hex reported as water on one turn and mountains
+on another, a river between two water hexes, or a ford without a river. These are usually typos in a report
+or a unit that was placed in the wrong hex. Use --disable to skip a rule.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		disabled := map[consistency.Rule_e]bool{}
+		for _, name := range argsReportConsistency.disable {
+			rule, ok := consistency.StringToRule[name]
+			if !ok {
+				log.Fatalf("error: disable: %q: unknown rule\n", name)
+			}
+			disabled[rule] = true
+		}
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		problems := consistency.Check(w.turns, w.tiles, disabled)
+		if len(problems) == 0 {
+			log.Printf("report: consistency: no problems found\n")
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Hex\tRule\tTurn\tLine\tUnit\tProblem\n")
+		for _, p := range problems {
+			turnId, line, unitId := p.TurnId, "", string(p.UnitId)
+			if turnId == "" {
+				turnId = "-"
+			}
+			if p.Line != 0 {
+				line = fmt.Sprintf("%d", p.Line)
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", p.Location.GridString(), p.Rule, turnId, line, unitId, p.Problem)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+var cmdReportContacts = &cobra.Command{
+	Use:     "contacts",
+	Short:   "print sightings of foreign units",
+	Long:    `Update the intelligence log with every foreign unit seen in the turn reports and print the movement history of each unit.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		intel, err := updateContacts(w)
+		if err != nil {
+			log.Fatalf("error: contacts: %v\n", err)
+		}
+		histories := intel.Histories(w.maxTurnId)
+		if len(histories) == 0 {
+			log.Printf("report: contacts: no foreign units found\n")
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Unit\tClan\tLast Seen\tHex\tAge\tHistory\n")
+		for _, h := range histories {
+			last := h.LastSeen()
+			var trail []string
+			for _, s := range h.Sightings {
+				trail = append(trail, fmt.Sprintf("%s %s (%s)", s.TurnId, s.Hex, s.SeenBy))
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", h.UnitId, h.Clan, last.TurnId, last.Hex, contacts.Age(last.TurnId, w.maxTurnId), strings.Join(trail, " -> "))
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+var cmdReportRoster = &cobra.Command{
+	Use:     "roster",
+	Short:   "print the clan's units and their status",
+	Long:    `Print each of the clan's units with its current hex, terrain, what it did on its last turn, and the text from its status line.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		units := clanUnitLocations(w, parser.UnitId_t(argsRender.clanId))
+		if len(units) == 0 {
+			log.Fatalf("error: clan %q: no units found\n", argsRender.clanId)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Unit\tTurn\tHex\tTerrain\tAction\tLast Result\tInventory\tStatus\n")
+		for _, unit := range units {
+			var terrainCode string
+			if tile, ok := w.tiles.Tiles[unit.location]; ok {
+				terrainCode = tile.Terrain.String()
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", unit.id, unit.turnId, unit.location.GridString(), terrainCode, history.Action(unit.moves), history.LastResult(unit.moves), unit.moves.Inventory, unit.moves.Status)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+var cmdReportDistances = &cobra.Command{
+	Use:     "distances",
+	Short:   "print distances from units to settlements",
+	Long:    `Print the distance and bearing from each of the clan's units to every known settlement and special hex.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		units := clanUnitLocations(w, parser.UnitId_t(argsRender.clanId))
+		if len(units) == 0 {
+			log.Fatalf("error: clan %q: no units found\n", argsRender.clanId)
+		}
+
+		// collect the known settlements and special hexes
+		type target_t struct {
+			name     string
+			location coords.Map
+		}
+		var targets []target_t
+		for _, tile := range w.tiles.Tiles {
+			for _, settlement := range tile.Settlements {
+				if settlement.Name == "" || strings.HasPrefix(settlement.Name, "_") {
+					continue
+				}
+				targets = append(targets, target_t{name: settlement.Name, location: tile.Location})
+			}
+			for _, special := range tile.Special {
+				targets = append(targets, target_t{name: special.Name, location: tile.Location})
+			}
+		}
+		if len(targets) == 0 {
+			log.Printf("report: distances: no settlements or special hexes found\n")
+			return
+		}
+		sort.Slice(targets, func(i, j int) bool {
+			if targets[i].name == targets[j].name {
+				return targets[i].location.GridString() < targets[j].location.GridString()
+			}
+			return targets[i].name < targets[j].name
+		})
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Unit\tHex")
+		for _, target := range targets {
+			_, _ = fmt.Fprintf(tw, "\t%s (%s)", target.name, target.location.GridString())
+		}
+		_, _ = fmt.Fprintf(tw, "\n")
+		for _, unit := range units {
+			_, _ = fmt.Fprintf(tw, "%s\t%s", unit.id, unit.location.GridString())
+			for _, target := range targets {
+				distance := unit.location.Distance(target.location)
+				if distance == 0 {
+					_, _ = fmt.Fprintf(tw, "\t%d", distance)
+					continue
+				}
+				bearing := compass.FromBearing(unit.location.Bearing(target.location))
+				_, _ = fmt.Fprintf(tw, "\t%d %s", distance, bearing)
+			}
+			_, _ = fmt.Fprintf(tw, "\n")
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+var cmdReportObscured = &cobra.Command{
+	Use:     "obscured",
+	Short:   "print obscured and unknown hexes that could not be resolved",
+	Long:    `Print the obscured ("##") and unknown ("N/A") previous and current hexes that could not be resolved from the links between turns, co-located units, or unit movement.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		if len(w.unresolved) == 0 {
+			log.Printf("report: obscured: all obscured hexes were resolved\n")
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Turn\tUnit\tField\tHex\tCandidates\n")
+		for _, u := range w.unresolved {
+			candidates := "none"
+			if len(u.Candidates) != 0 {
+				candidates = strings.Join(u.Candidates, ", ")
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", u.TurnId, u.UnitId, u.Field, u.Hex, candidates)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+var argsReportResources struct {
+	movementPoints int // movement points the expedition has each turn
+}
+
+var cmdReportOrders = &cobra.Command{
+	Use:   "orders",
+	Short: "compare submitted orders with the turn results",
+	Long: `Read the orders the clan submitted for each turn and flag the ones that the turn report doesn't match:
+orders that weren't executed, steps in an unexpected direction, and units that ran out of movement points early.
+
+Orders files are kept in the input folder next to the turn reports and are named YEAR-MONTH.CLAN.orders.txt,
+for the same turn as the report with the results. Each line is a unit id and an order:
+
+  0138 move NE N N
+  0138 scout 1 N N NE
+  0138e1 follow 0138
+  0138e2 goes to QQ 1410`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		type row_t struct {
+			turnId string
+			orders.Discrepancy_t
+		}
+		var rows []row_t
+		found := 0
+		for _, turn := range w.turns {
+			path := filepath.Join(argsRender.paths.input, fmt.Sprintf("%s.%s.orders.txt", turn.Id, argsRender.clanId))
+			data, err := os.ReadFile(path)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			found++
+			list, err := orders.Parse(data)
+			if err != nil {
+				log.Fatalf("error: %s: %v\n", path, err)
+			}
+			for _, d := range orders.Reconcile(list, turn) {
+				rows = append(rows, row_t{turnId: turn.Id, Discrepancy_t: d})
+			}
+		}
+		if found == 0 {
+			log.Printf("report: orders: no orders files found in %s\n", argsRender.paths.input)
+			return
+		} else if len(rows) == 0 {
+			log.Printf("report: orders: %d orders files: the results match the orders\n", found)
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Turn\tLine\tUnit\tProblem\n")
+		for _, row := range rows {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", row.turnId, row.Line, row.UnitId, row.Problem)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+var cmdReportResources = &cobra.Command{
+	Use:   "resources",
+	Short: "print known resources and how far they are from the clan",
+	Long: `Print every known resource hex with the nearest clan unit or settlement, the distance in hexes, the movement
+points a land unit would spend to get there, and the number of turns that would take.
+Settlements held by another clan are skipped; settlements without a reported owner are treated as friendly.
+Paths only go through hexes that are on the map; a resource that can't be reached over land is marked with a dash.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		} else if argsReportResources.movementPoints < 1 {
+			log.Fatalf("error: movement-points: must be at least 1\n")
+		}
+
+		// units are listed before settlements so that they win ties
+		var origins []coords.Map
+		names := map[coords.Map]string{}
+		addOrigin := func(location coords.Map, name string) {
+			if _, ok := names[location]; !ok {
+				origins = append(origins, location)
+				names[location] = name
+			}
+		}
+		for _, unit := range clanUnitLocations(w, parser.UnitId_t(argsRender.clanId)) {
+			addOrigin(unit.location, string(unit.id))
+		}
+		for _, tile := range sortedTiles(w) {
+			for _, settlement := range tile.Settlements {
+				if settlement.Owner != "" && settlement.Owner != parser.UnitId_t(argsRender.clanId) {
+					continue
+				} else if settlement.Name != "" && !strings.HasPrefix(settlement.Name, "_") {
+					addOrigin(tile.Location, settlement.Name)
+				}
+			}
+		}
+		if len(origins) == 0 {
+			log.Fatalf("error: clan %q: no units or settlements found\n", argsRender.clanId)
+		}
+		routes := pathfinding.Nearest(w.tiles, origins)
+
+		type row_t struct {
+			resource string
+			tile     *tiles.Tile_t
+			route    pathfinding.Route_t
+			ok       bool
+		}
+		var rows []row_t
+		for _, tile := range sortedTiles(w) {
+			route, ok := routes[tile.Location]
+			for _, r := range tile.Resources {
+				rows = append(rows, row_t{resource: r.String(), tile: tile, route: route, ok: ok})
+			}
+		}
+		if len(rows) == 0 {
+			log.Printf("report: resources: no resources found\n")
+			return
+		}
+		// closest first, unreachable last
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].ok != rows[j].ok {
+				return rows[i].ok
+			}
+			return rows[i].route.Cost < rows[j].route.Cost
+		})
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Resource\tHex\tTerrain\tNearest\tFrom\tHexes\tMP\tTurns\n")
+		for _, row := range rows {
+			if !row.ok {
+				_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t-\t-\t-\t-\t-\n", row.resource, row.tile.Location.GridString(), row.tile.Terrain)
+				continue
+			}
+			turns := (row.route.Cost + argsReportResources.movementPoints - 1) / argsReportResources.movementPoints
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\n", row.resource, row.tile.Location.GridString(), row.tile.Terrain, names[row.route.Origin], row.route.Origin.GridString(), row.route.Origin.Distance(row.tile.Location), row.route.Cost, turns)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+// sortedTiles returns the tiles on the map in grid order.
+func sortedTiles(w *world_t) []*tiles.Tile_t {
+	var list []*tiles.Tile_t
+	for _, tile := range w.tiles.Tiles {
+		list = append(list, tile)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Location.GridString() < list[j].Location.GridString()
+	})
+	return list
+}
+
+var argsReportStats struct {
+	json bool // if set, write the statistics as JSON
+}
+
+var cmdReportStats = &cobra.Command{
+	Use:     "stats",
+	Short:   "print statistics for the merged map",
+	Long:    `Print the hexes explored in each grid, the terrain on the map, the counts of settlements, resources, and encounters, how the hexes were seen, and the hexes discovered each turn.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error { return cmdRender.PreRunE(cmd, args) },
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+
+		s := stats.Compute(w.tiles)
+		if argsReportStats.json {
+			buf, err := json.MarshalIndent(s, "", "  ")
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			fmt.Println(string(buf))
+			return
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintf(tw, "Hexes\t%d\n", s.Hexes)
+		for _, row := range []struct {
+			name  string
+			count int
+		}{
+			{"Scouted", s.Scouted}, {"Visited", s.Visited}, {"Inferred", s.Inferred},
+		} {
+			_, _ = fmt.Fprintf(tw, "  %s\t%d\t%.1f%%\n", row.name, row.count, stats.Percent(row.count, s.Hexes))
+		}
+		_, _ = fmt.Fprintf(tw, "Settlements\t%d\n", s.Settlements)
+		_, _ = fmt.Fprintf(tw, "Resources\t%d\n", s.Resources)
+		_, _ = fmt.Fprintf(tw, "Encounters\t%d\n", s.Encounters)
+		_, _ = fmt.Fprintf(tw, "\nGrid\tHexes\tExplored\n")
+		for _, g := range s.Grids {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", g.Name, g.Count, g.Percent)
+		}
+		_, _ = fmt.Fprintf(tw, "\nTerrain\tHexes\tShare\n")
+		for _, t := range s.Terrain {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%.1f%%\n", t.Name, t.Count, t.Percent)
+		}
+		_, _ = fmt.Fprintf(tw, "\nTurn\tNew\tTotal\n")
+		for _, t := range s.Turns {
+			_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\n", t.Turn, t.New, t.Total)
+		}
+		if err := tw.Flush(); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+	},
+}
+
+// addReportFlags registers the flags that report commands need to load the data.
+func addReportFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&argsRender.autoEOL, "auto-eol", true, "automatically convert line endings")
+	cmd.Flags().StringVar(&argsRender.clanId, "clan-id", "", "clan that owns the reports")
+	if err := cmd.MarkFlagRequired("clan-id"); err != nil {
+		log.Fatalf("error: clan-id: %v\n", err)
+	}
+	cmd.Flags().StringVar(&argsRender.paths.data, "data", "data", "path to root of data files")
+	cmd.Flags().StringVar(&argsRender.fromTurn.id, "from-turn", "", "first turn to load (yyyy-mm format)")
+	cmd.Flags().StringVar(&argsRender.maxTurn.id, "max-turn", "", "last turn to load (yyyy-mm format)")
+	cmd.Flags().StringVar(&argsRender.profile, "profile", "", "configuration profile to apply (default \"default\" if the file has one)")
+}
+
+// updateContacts loads the clan's intelligence log from the output folder,
+// adds the sightings from the loaded turns, and saves it.
+func updateContacts(w *world_t) (*contacts.Log_t, error) {
+	path := contactsPath()
+	intel, err := contacts.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	added := intel.Collect(w.turns, parser.UnitId_t(argsRender.clanId))
+	if err := intel.Save(path); err != nil {
+		return nil, err
+	}
+	log.Printf("contacts: added %d sightings: %s\n", added, path)
+	return intel, nil
+}
+
+// unitLocation_t is a unit and the hex it ended its last reported turn in.
+type unitLocation_t struct {
+	id       parser.UnitId_t
+	turnId   string
+	location coords.Map
+	moves    *parser.Moves_t // moves from the unit's last reported turn
+}
+
+// clanUnitLocations returns the final known location for every unit in the clan,
+// sorted by unit id. Units that have been disbanded are left out.
+func clanUnitLocations(w *world_t, clan parser.UnitId_t) []unitLocation_t {
+	latest := map[parser.UnitId_t]unitLocation_t{}
+	for _, turn := range w.turns {
+		for id, moves := range turn.UnitMoves {
+			if !id.InClan(clan) || moves.Location.IsZero() {
+				continue
+			}
+			latest[id] = unitLocation_t{id: id, turnId: turn.Id, location: moves.Location, moves: moves}
+		}
+		for _, moves := range turn.UnitMoves {
+			for _, event := range moves.Events {
+				if event.Kind == parser.UnitDisbanded {
+					delete(latest, event.UnitId)
+				}
+			}
+		}
+	}
+	var units []unitLocation_t
+	for _, unit := range latest {
+		units = append(units, unit)
+	}
+	sort.Slice(units, func(i, j int) bool {
+		return units[i].id < units[j].id
+	})
+	return units
+}
+
+// contactsPath returns the path to the clan's intelligence log in the output folder.
+func contactsPath() string {
+	return filepath.Join(argsRender.paths.output, fmt.Sprintf("%s.contacts.json", argsRender.clanId))
+}
@@ -5,6 +5,7 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"github.com/playbymail/ottomap/internal/extract"
 	"github.com/playbymail/tndocx"
 	"github.com/spf13/cobra"
 	"log"
@@ -13,6 +14,7 @@ import (
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -61,7 +63,7 @@ var cmdScrubFile = &cobra.Command{
 			return
 		}
 
-		// file name must look like YEAR-MONTH.CLAN.report.(docx|txt)
+		// file name must look like YEAR-MONTH.CLAN.report.(docx|odt|rtf|txt)
 		fileName := args[0]
 		turnId, clanId, kind, err := validateFileName(fileName)
 		if err != nil {
@@ -98,7 +100,7 @@ var cmdScrubFiles = &cobra.Command{
 		}
 
 		for _, fileName := range args {
-			// file name must look like YEAR-MONTH.CLAN.report.(docx|txt)
+			// file name must look like YEAR-MONTH.CLAN.report.(docx|odt|rtf|txt)
 			turnId, clanId, kind, err := validateFileName(fileName)
 			if err != nil {
 				log.Fatalf("error: %v\n", err)
@@ -123,6 +125,21 @@ var cmdScrubFiles = &cobra.Command{
 }
 
 func scrubData(path, kind string, data []byte) ([]byte, error) {
+	// tndocx reads Word and text files, so convert other documents to text first
+	if kind == "odt" || kind == "rtf" {
+		text, err := extract.ToText(path, data)
+		if err != nil {
+			return nil, err
+		}
+		data = text
+	}
+	if kind != "word" {
+		var fixes []string
+		if data, fixes = extract.Normalize(data); len(fixes) != 0 {
+			log.Printf("scrub: %s: %s\n", path, strings.Join(fixes, ", "))
+		}
+	}
+
 	// parse the report text into sections
 	sections, err := tndocx.ParseSections(data)
 	if err != nil {
@@ -182,8 +199,8 @@ func scrubData(path, kind string, data []byte) ([]byte, error) {
 }
 
 func validateFileName(file string) (turnId, clanId, kind string, err error) {
-	// file name must look like YEAR-MONTH.CLAN.report.(docx|txt)
-	re := regexp.MustCompile(`^(\d{4})-(\d{2})\.([0-9]{4})\.report\.(docx|txt)$`)
+	// file name must look like YEAR-MONTH.CLAN.report.(docx|odt|rtf|txt)
+	re := regexp.MustCompile(`^(\d{4})-(\d{2})\.([0-9]{4})\.report\.(docx|odt|rtf|txt)$`)
 	if match := re.FindStringSubmatch(file); match == nil {
 		return turnId, clanId, kind, fmt.Errorf("file name does not match expected pattern")
 	} else {
@@ -210,9 +227,12 @@ func validateFileName(file string) (turnId, clanId, kind string, err error) {
 		} else {
 			clanId = fmt.Sprintf("%04d", n)
 		}
-		if match[4] == "txt" {
+		switch match[4] {
+		case "odt", "rtf":
+			kind = match[4]
+		case "txt":
 			kind = "text"
-		} else {
+		default:
 			kind = "word"
 		}
 	}
@@ -280,7 +300,7 @@ type reportFile_t struct {
 	year  int
 	month int
 	clan  int
-	kind  string // text, word, or scrubbed
+	kind  string // text, word, odt, rtf, or scrubbed
 }
 
 func (f reportFile_t) Clan() string {
@@ -305,7 +325,7 @@ func (f reportFile_t) clanIsValid() bool {
 	return 1 <= f.clan && f.clan <= 999
 }
 func (f reportFile_t) kindIsValid() bool {
-	return f.kind == "text" || f.kind == "word" || f.kind == "scrubbed"
+	return f.kind == "text" || f.kind == "word" || f.kind == "odt" || f.kind == "rtf" || f.kind == "scrubbed"
 }
 func (f reportFile_t) monthIsValid() bool {
 	return 1 <= f.month && f.month <= 12
@@ -316,7 +336,7 @@ func (f reportFile_t) yearIsValid() bool {
 
 // turn report files have names that match the pattern YEAR-MONTH.CLAN_ID.report.txt.
 func findReportFiles(path string) (items []*reportFile_t, err error) {
-	rxTurnReportFile := regexp.MustCompile(`^(\d{4})-(\d{2})\.(0\d{3})\.(report|scrubbed)\.(txt|docx)$`)
+	rxTurnReportFile := regexp.MustCompile(`^(\d{4})-(\d{2})\.(0\d{3})\.(report|scrubbed)\.(txt|docx|odt|rtf)$`)
 
 	// search for report files in the requested path
 	entries, err := os.ReadDir(path)
@@ -338,6 +358,10 @@ func findReportFiles(path string) (items []*reportFile_t, err error) {
 		switch matches[4] + "." + matches[5] {
 		case "report.docx":
 			item.kind = "word"
+		case "report.odt":
+			item.kind = "odt"
+		case "report.rtf":
+			item.kind = "rtf"
 		case "report.txt":
 			item.kind = "text"
 		case "scrubbed.txt":
@@ -369,7 +393,7 @@ func findReportFiles(path string) (items []*reportFile_t, err error) {
 
 type reportDependency_t struct {
 	text     *reportFile_t
-	word     *reportFile_t
+	word     *reportFile_t // docx, odt, or rtf
 	scrubbed *reportFile_t
 }
 
@@ -401,7 +425,7 @@ func findReportDependencies(path string) (map[string]*reportDependency_t, error)
 		switch f.Kind() {
 		case "text":
 			rd.text = f
-		case "word":
+		case "word", "odt", "rtf":
 			rd.word = f
 		case "scrubbed":
 			rd.scrubbed = f
@@ -0,0 +1,129 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/testkit"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+var argsSelftest struct {
+	maxDiff int    // maximum number of diff lines to print for each file
+	update  string // when set, golden files are written to this corpus folder
+}
+
+var cmdSelftest = &cobra.Command{
+	Use:   "selftest",
+	Short: "Replay the bundled report corpus and compare with the golden files",
+	Long: `Run every case in the bundled corpus through the render command and compare the snapshot
+of the parsed turns and merged tiles, and the decoded Worldographer map, with the golden files.
+Differences are printed as "-" (golden) and "+" (actual) lines. Exits with an error if any case fails.
+Use --update internal/testkit/corpus to accept the current output as the new golden files.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatalf("error: selftest: %v\n", err)
+		}
+		cases, err := testkit.Cases()
+		if err != nil {
+			log.Fatalf("error: selftest: %v\n", err)
+		}
+
+		failed := 0
+		for _, c := range cases {
+			started := time.Now()
+			snapshot, wxx, err := selftestCase(executable, c)
+			if err != nil {
+				log.Printf("selftest: %s: FAIL: %v\n", c.Name, err)
+				failed++
+				continue
+			}
+			if argsSelftest.update != "" {
+				folder := filepath.Join(argsSelftest.update, c.Name)
+				if err := os.WriteFile(filepath.Join(folder, testkit.GoldenJSON), snapshot, 0644); err != nil {
+					log.Fatalf("error: selftest: %v\n", err)
+				} else if err := os.WriteFile(filepath.Join(folder, testkit.GoldenWXX), wxx, 0644); err != nil {
+					log.Fatalf("error: selftest: %v\n", err)
+				}
+				log.Printf("selftest: %s: updated %s\n", c.Name, folder)
+				continue
+			}
+			ok := true
+			for _, file := range []struct {
+				name      string
+				want, got []byte
+			}{
+				{testkit.GoldenJSON, c.JSON, snapshot},
+				{testkit.GoldenWXX, c.WXX, wxx},
+			} {
+				if file.want == nil {
+					log.Printf("selftest: %s: %s: missing golden file\n", c.Name, file.name)
+					ok = false
+				} else if !bytes.Equal(file.want, file.got) {
+					log.Printf("selftest: %s: %s: output differs from golden file\n", c.Name, file.name)
+					for _, line := range testkit.Diff(file.want, file.got, argsSelftest.maxDiff) {
+						fmt.Printf("    %s\n", line)
+					}
+					ok = false
+				}
+			}
+			if !ok {
+				failed++
+				continue
+			}
+			log.Printf("selftest: %s: ok (%d reports in %v)\n", c.Name, len(c.Reports), time.Since(started))
+		}
+		if failed != 0 {
+			log.Fatalf("selftest: %d of %d cases failed\n", failed, len(cases))
+		}
+		log.Printf("selftest: %d cases passed\n", len(cases))
+	},
+}
+
+// selftestCase renders the case in a scratch data folder and returns the snapshot and decoded map.
+func selftestCase(executable string, c *testkit.Case_t) ([]byte, []byte, error) {
+	work, err := os.MkdirTemp("", "ottomap-selftest-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(work)
+	for _, path := range []string{filepath.Join(work, "input"), filepath.Join(work, "output")} {
+		if err := os.Mkdir(path, 0o755); err != nil {
+			return nil, nil, err
+		}
+	}
+	for name, data := range c.Reports {
+		if err := os.WriteFile(filepath.Join(work, "input", name), data, 0644); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	snapshotPath := filepath.Join(work, "output", "snapshot.json")
+	var logs bytes.Buffer
+	child := exec.Command(executable, "render", "--data", work, "--clan-id", c.ClanId, "--x-deterministic", "--x-snapshot", snapshotPath)
+	child.Stdout, child.Stderr = &logs, &logs
+	if err := child.Run(); err != nil {
+		return nil, nil, fmt.Errorf("render: %v\n%s", err, lastLines(logs.String(), 10))
+	}
+
+	snapshot, err := os.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(work, "output", c.ClanId+".wxx"))
+	if err != nil {
+		return nil, nil, err
+	}
+	wxx, err := testkit.DecodeWXX(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return snapshot, wxx, nil
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/progress"
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"github.com/spf13/cobra"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var argsServe struct {
+	addr      string        // address to listen on
+	secret    string        // shared secret that clients must send as a bearer token
+	store     string        // path to the database for the clan workspaces, optional
+	maxUpload int64         // maximum size of a request body, in bytes
+	timeout   time.Duration // maximum time allowed for a render or a parse
+	workers   int           // number of workers running queued jobs
+	attempts  int           // number of times a queued job is tried before it fails
+}
+
+var (
+	// rxUploadName matches the names of the report files that we accept.
+	// Word documents are converted to text when they are read.
+	rxUploadName = regexp.MustCompile(`^(\d{4})-(\d{2})\.(\d{4}([cefg]\d)?)\.report\.(txt|docx)$`)
+)
+
+var cmdServe = &cobra.Command{
+	Use:   "serve",
+	Short: "Run an HTTP server that parses reports and renders maps",
+	Long: `Run an HTTP server with two endpoints:
+  POST /parse  accepts a single turn report as the "report" form file and returns the parsed turn as JSON.
+  POST /render accepts one or more turn reports as "report" form files and the "clan-id" form value and returns the Worldographer map.
+Clients must send the shared secret as a bearer token.
+Reports are named yyyy-mm.unit.report.txt or yyyy-mm.unit.report.docx; Word documents are converted to text.
+When started with a database, the server also hosts a workspace for each clan:
+  POST /clan/reports accepts turn reports as "report" form files and stores them for the user's clan.
+  GET  /clan/turns   lists the turns and reports in the workspace.
+  GET  /clan/map     returns the Worldographer map, up to the optional "turn" query parameter.
+  POST /clan/sharing sets whether the user's clan shares its reports with the "alliance" (the "share" form value).
+  GET  /alliance/map returns the map combining the user's reports with the reports shared by allied clans.
+Workspace users sign in with HTTP basic auth; create them with "db create user".
+Create alliances with "db create alliance" and add clans with "db alliance join".
+The database also holds a queue of jobs that are run in the background:
+  POST /jobs/parse       queues a parse of a single "report" form file and returns the job.
+  POST /jobs/render      queues a render of the "report" form files for the "clan-id" form value and returns the job.
+  GET  /jobs/{id}        returns the status, phase, and percent complete of the job.
+  GET  /jobs/{id}/result returns the parsed turn or the map once the job is done.
+The job endpoints use the shared secret. Failed jobs are retried until they have used all their attempts.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if argsServe.secret == "" {
+			return fmt.Errorf("secret is required (use --secret or OTTOMAP_SECRET)")
+		} else if argsServe.maxUpload < 1024 {
+			return fmt.Errorf("max-upload must be at least 1024 bytes")
+		} else if argsServe.workers < 1 {
+			return fmt.Errorf("workers must be at least 1")
+		} else if argsServe.attempts < 1 {
+			return fmt.Errorf("attempts must be at least 1")
+		}
+		if argsServe.store != "" {
+			if path, err := filepath.Abs(argsServe.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else {
+				argsServe.store = path
+			}
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("POST /parse", serveAuth(serveParse))
+		mux.HandleFunc("POST /render", serveAuth(serveRender))
+		if argsServe.store != "" {
+			store, err := sqlite.Open(argsServe.store, context.Background())
+			if err != nil {
+				log.Fatalf("error: serve: %v\n", err)
+			}
+			defer store.Close()
+			mux.HandleFunc("POST /clan/reports", serveClanUpload(store))
+			mux.HandleFunc("GET /clan/turns", serveClanTurns(store))
+			mux.HandleFunc("GET /clan/map", serveClanMap(store))
+			mux.HandleFunc("POST /clan/sharing", serveClanSharing(store))
+			mux.HandleFunc("GET /alliance/map", serveAllianceMap(store))
+			log.Printf("serve: clan workspaces in %s\n", argsServe.store)
+			mux.HandleFunc("POST /jobs/parse", serveAuth(serveJobSubmit(store, sqlite.JobParse)))
+			mux.HandleFunc("POST /jobs/render", serveAuth(serveJobSubmit(store, sqlite.JobRender)))
+			mux.HandleFunc("GET /jobs/{id}", serveBearer(serveJobStatus(store)))
+			mux.HandleFunc("GET /jobs/{id}/result", serveBearer(serveJobResult(store)))
+			startJobWorkers(cmd.Context(), store, argsServe.workers)
+		}
+
+		log.Printf("serve: listening on %s\n", argsServe.addr)
+		srv := &http.Server{
+			Addr:              argsServe.addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		if err := srv.ListenAndServe(); err != nil {
+			log.Fatalf("error: serve: %v\n", err)
+		}
+	},
+}
+
+// serveBearer rejects requests that don't have the shared secret.
+func serveBearer(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(argsServe.secret)) != 1 {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// serveAuth rejects requests that don't have the shared secret and limits the size of the body.
+func serveAuth(next http.HandlerFunc) http.HandlerFunc {
+	return serveBearer(func(w http.ResponseWriter, r *http.Request) {
+		r.Body = http.MaxBytesReader(w, r.Body, argsServe.maxUpload)
+		if err := r.ParseMultipartForm(argsServe.maxUpload); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// serveParse parses a single report and returns the turn as JSON.
+func serveParse(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
+	uploads := r.MultipartForm.File["report"]
+	if len(uploads) != 1 {
+		http.Error(w, "expected exactly one report", http.StatusBadRequest)
+		return
+	}
+	name, data, err := readUpload(uploads[0])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	matches := rxUploadName.FindStringSubmatch(name)
+	turnId := fmt.Sprintf("%s-%s", matches[1], matches[2])
+
+	ctx, cancel := context.WithTimeout(r.Context(), argsServe.timeout)
+	defer cancel()
+	turn, err := parseUpload(ctx, name, turnId, data)
+	if err != nil {
+		log.Printf("serve: parse: %s: %v\n", name, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(turn); err != nil {
+		log.Printf("serve: parse: %s: %v\n", name, err)
+		return
+	}
+	log.Printf("serve: parse: %s: %d units in %v\n", name, len(turn.UnitMoves), time.Since(started))
+}
+
+// parseUpload runs the parser, turning any panic into an error so that a bad report doesn't stop the server.
+func parseUpload(ctx context.Context, name, turnId string, data []byte) (turn *parser.Turn_t, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			turn, err = nil, fmt.Errorf("%s: parser failed: %v", name, r)
+		}
+	}()
+	if clean, fixes := extract.Normalize(data); len(fixes) != 0 {
+		log.Printf("serve: parse: %s: %s\n", name, strings.Join(fixes, ", "))
+		data = clean
+	}
+	data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+	data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
+	turn, err = parser.ParseInput(ctx, strings.TrimSuffix(name, ".report.txt"), turnId, data, false, false, false, false, false, false, false, false, parser.ParseConfig{})
+	if err != nil {
+		return nil, err
+	} else if turnId != fmt.Sprintf("%04d-%02d", turn.Year, turn.Month) {
+		return nil, fmt.Errorf("%s: expected turn %q: got turn %q", name, turnId, fmt.Sprintf("%04d-%02d", turn.Year, turn.Month))
+	}
+	return turn, nil
+}
+
+// serveRender renders the uploaded reports and returns the Worldographer map.
+// The render runs in a child process in a scratch data folder.
+func serveRender(w http.ResponseWriter, r *http.Request) {
+	started := time.Now()
+	clanId := r.FormValue("clan-id")
+	if !(len(clanId) == 4 && clanId[0] == '0' && strings.Trim(clanId, "0123456789") == "") {
+		http.Error(w, "clan-id must be a 4 digit number starting with 0", http.StatusBadRequest)
+		return
+	}
+	uploads := r.MultipartForm.File["report"]
+	if len(uploads) == 0 {
+		http.Error(w, "expected at least one report", http.StatusBadRequest)
+		return
+	}
+
+	work, err := newScratch()
+	if err != nil {
+		log.Printf("serve: render: %v\n", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(work)
+	for _, upload := range uploads {
+		name, data, err := readUpload(upload)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		} else if err := os.WriteFile(filepath.Join(work, "input", name), data, 0o644); err != nil {
+			log.Printf("serve: render: %v\n", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	mapName := fmt.Sprintf("%s.wxx", clanId)
+	data, renderLog, err := runRender(r.Context(), work, clanId, "render")
+	if err != nil {
+		log.Printf("serve: render: %s: %v\n", clanId, err)
+		if renderLog != "" {
+			http.Error(w, fmt.Sprintf("render failed: %v\n%s", err, renderLog), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", mapName))
+	if _, err := w.Write(data); err != nil {
+		log.Printf("serve: render: %s: %v\n", clanId, err)
+		return
+	}
+	log.Printf("serve: render: %s: %d reports in %v\n", clanId, len(uploads), time.Since(started))
+}
+
+// newScratch creates a data folder, with input and output folders, for a render.
+// The caller must remove it when done.
+func newScratch() (string, error) {
+	work, err := os.MkdirTemp("", "ottomap-serve-")
+	if err != nil {
+		return "", err
+	}
+	for _, path := range []string{filepath.Join(work, "input"), filepath.Join(work, "output")} {
+		if err := os.Mkdir(path, 0o755); err != nil {
+			_ = os.RemoveAll(work)
+			return "", err
+		}
+	}
+	return work, nil
+}
+
+// runRender runs the render command in a child process using the scratch data folder
+// and returns the map. If the render fails, the last lines of its log are returned with the error.
+func runRender(ctx context.Context, work, clanId string, command ...string) ([]byte, string, error) {
+	return runRenderProgress(ctx, work, clanId, nil, command...)
+}
+
+// runRenderProgress is runRender for callers that want to follow the render.
+// When onProgress is set, the child reports its progress as a JSON stream
+// and onProgress is called for each update.
+func runRenderProgress(ctx context.Context, work, clanId string, onProgress func(progress.Event_t), command ...string) ([]byte, string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, argsServe.timeout)
+	defer cancel()
+	args := append(slices.Clone(command), "--data", work, "--clan-id", clanId)
+	if onProgress != nil {
+		args = append(args, "--progress", "json")
+	}
+	var logs bytes.Buffer
+	child := exec.CommandContext(ctx, executable, args...)
+	child.Stdout, child.Stderr = &logs, &logs
+	if onProgress != nil {
+		child.Stdout = progress.NewWriter(onProgress)
+	}
+	if err := child.Run(); err != nil {
+		return nil, lastLines(logs.String(), 10), err
+	}
+	data, err := os.ReadFile(filepath.Join(work, "output", fmt.Sprintf("%s.wxx", clanId)))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "", nil
+}
+
+// readUpload validates the name of an uploaded report and returns its text.
+// Word documents are converted to text and renamed to yyyy-mm.unit.report.txt.
+func readUpload(upload *multipart.FileHeader) (string, []byte, error) {
+	name := filepath.Base(upload.Filename)
+	if !rxUploadName.MatchString(name) {
+		return "", nil, fmt.Errorf("%q: report name must be yyyy-mm.unit.report.txt or yyyy-mm.unit.report.docx", name)
+	}
+	fd, err := upload.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %v", name, err)
+	}
+	defer fd.Close()
+	data, err := io.ReadAll(fd)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %v", name, err)
+	}
+	text, fixes, err := extract.Report(name, data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s: %v", name, err)
+	} else if len(fixes) != 0 {
+		log.Printf("serve: upload: %s: %s\n", name, strings.Join(fixes, ", "))
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".txt", text, nil
+}
+
+// lastLines returns the last n lines of the text.
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
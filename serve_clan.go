@@ -0,0 +1,299 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"github.com/playbymail/ottomap/internal/turns"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the clan workspace endpoints are enabled when the server is started with a store.
+// users sign in with HTTP basic auth and only see their own clan's reports.
+
+// serveClan signs the user in and limits the size of the body.
+func serveClan(store *sqlite.Store, next func(w http.ResponseWriter, r *http.Request, user *sqlite.User_t)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handle, secret, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="ottomap"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		user, err := store.AuthenticateUser(handle, secret)
+		if err != nil {
+			if !errors.Is(err, sqlite.ErrInvalidCredentials) {
+				log.Printf("serve: clan: %q: %v\n", handle, err)
+			}
+			w.Header().Set("WWW-Authenticate", `Basic realm="ottomap"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, argsServe.maxUpload)
+		next(w, r, user)
+	}
+}
+
+// serveClanUpload parses the uploaded reports and stores them in the clan's workspace.
+// A report replaces any report with the same name.
+func serveClanUpload(store *sqlite.Store) http.HandlerFunc {
+	return serveClan(store, func(w http.ResponseWriter, r *http.Request, user *sqlite.User_t) {
+		if err := r.ParseMultipartForm(argsServe.maxUpload); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, fmt.Sprintf("invalid form: %v", err), http.StatusBadRequest)
+			return
+		}
+		uploads := r.MultipartForm.File["report"]
+		if len(uploads) == 0 {
+			http.Error(w, "expected at least one report", http.StatusBadRequest)
+			return
+		}
+
+		type uploaded_t struct {
+			Name  string `json:"name"`
+			Id    int    `json:"id"`
+			Units int    `json:"units"`
+		}
+		var results []uploaded_t
+		ctx, cancel := context.WithTimeout(r.Context(), argsServe.timeout)
+		defer cancel()
+		for _, upload := range uploads {
+			name, data, err := readUpload(upload)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			matches := rxUploadName.FindStringSubmatch(name)
+			year, _ := strconv.Atoi(matches[1])
+			month, _ := strconv.Atoi(matches[2])
+			unit := matches[3]
+			if unit[:4] != fmt.Sprintf("%04d", user.Clan) {
+				http.Error(w, fmt.Sprintf("%s: unit %s is not in clan %04d", name, unit, user.Clan), http.StatusForbidden)
+				return
+			}
+			// parse the report first so that we never store a report that we can't render
+			turn, err := parseUpload(ctx, name, fmt.Sprintf("%04d-%02d", year, month), data)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+				return
+			}
+			sum := sha1.Sum(data)
+			hash := hex.EncodeToString(sum[:])
+			if err := store.DeleteReportByName(user.Clan, year, month, unit); err != nil {
+				log.Printf("serve: clan: %04d: %s: %v\n", user.Clan, name, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			} else if err := store.DeleteReportByHash(user.Clan, hash); err != nil {
+				log.Printf("serve: clan: %04d: %s: %v\n", user.Clan, name, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			id, err := store.CreateNewReport(user.Clan, year, month, unit, hash, data)
+			if err != nil {
+				log.Printf("serve: clan: %04d: %s: %v\n", user.Clan, name, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			log.Printf("serve: clan: %04d: %s: stored %d by %q\n", user.Clan, name, id, user.Handle)
+			results = append(results, uploaded_t{Name: name, Id: id, Units: len(turn.UnitMoves)})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(results)
+	})
+}
+
+// serveClanTurns lists the turns in the clan's workspace and the reports for each turn.
+func serveClanTurns(store *sqlite.Store) http.HandlerFunc {
+	return serveClan(store, func(w http.ResponseWriter, r *http.Request, user *sqlite.User_t) {
+		reports, err := store.GetReportsByTurnRange(user.Clan, 899, 12, 9999, 12)
+		if err != nil {
+			log.Printf("serve: clan: %04d: %v\n", user.Clan, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		type turn_t struct {
+			Turn    string   `json:"turn"`
+			Reports []string `json:"reports"`
+		}
+		list := []*turn_t{}
+		for _, report := range reports {
+			turnId := fmt.Sprintf("%04d-%02d", report.Year, report.Month)
+			if len(list) == 0 || list[len(list)-1].Turn != turnId {
+				list = append(list, &turn_t{Turn: turnId})
+			}
+			list[len(list)-1].Reports = append(list[len(list)-1].Reports, fmt.Sprintf("%s.%s.report.txt", turnId, report.Unit))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	})
+}
+
+// serveClanMap renders the map from the clan's workspace, optionally up to the "turn" query parameter.
+func serveClanMap(store *sqlite.Store) http.HandlerFunc {
+	return serveClan(store, func(w http.ResponseWriter, r *http.Request, user *sqlite.User_t) {
+		started := time.Now()
+		clanId := fmt.Sprintf("%04d", user.Clan)
+		command := []string{"db", "render", "--store", argsServe.store}
+		if turnId := r.URL.Query().Get("turn"); turnId != "" {
+			if _, _, err := turns.ParseTurnId(turnId); err != nil {
+				http.Error(w, fmt.Sprintf("turn %v", err), http.StatusBadRequest)
+				return
+			}
+			command = append(command, "--to-turn", turnId)
+		}
+
+		work, err := newScratch()
+		if err != nil {
+			log.Printf("serve: clan: %s: %v\n", clanId, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(work)
+
+		data, renderLog, err := runRender(r.Context(), work, clanId, command...)
+		if err != nil {
+			log.Printf("serve: clan: %s: %v\n", clanId, err)
+			if renderLog != "" {
+				http.Error(w, fmt.Sprintf("render failed: %v\n%s", err, renderLog), http.StatusUnprocessableEntity)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", clanId+".wxx"))
+		if _, err := w.Write(data); err != nil {
+			log.Printf("serve: clan: %s: %v\n", clanId, err)
+			return
+		}
+		log.Printf("serve: clan: %s: map for %q in %v\n", clanId, user.Handle, time.Since(started))
+	})
+}
+
+// serveClanSharing sets whether the user's clan shares its reports with an alliance.
+// The clan must already be a member of the alliance.
+func serveClanSharing(store *sqlite.Store) http.HandlerFunc {
+	return serveClan(store, func(w http.ResponseWriter, r *http.Request, user *sqlite.User_t) {
+		alliance := r.FormValue("alliance")
+		if alliance == "" {
+			http.Error(w, "alliance is required", http.StatusBadRequest)
+			return
+		}
+		shares, err := strconv.ParseBool(r.FormValue("share"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("share: %q: expected true or false", r.FormValue("share")), http.StatusBadRequest)
+			return
+		}
+		if err := store.SetAllianceSharing(alliance, user.Clan, shares); err != nil {
+			if errors.Is(err, sqlite.ErrNotAllianceMember) {
+				http.Error(w, fmt.Sprintf("clan %04d is not a member of %q", user.Clan, alliance), http.StatusForbidden)
+				return
+			}
+			log.Printf("serve: clan: %04d: sharing: %v\n", user.Clan, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("serve: clan: %04d: %q: sharing %v by %q\n", user.Clan, alliance, shares, user.Handle)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// serveAllianceMap renders the map from the clan's reports and the reports of every clan
+// that shares with it through an alliance, optionally up to the "turn" query parameter.
+// Clans that have not opted in to sharing are never included.
+func serveAllianceMap(store *sqlite.Store) http.HandlerFunc {
+	return serveClan(store, func(w http.ResponseWriter, r *http.Request, user *sqlite.User_t) {
+		started := time.Now()
+		clanId := fmt.Sprintf("%04d", user.Clan)
+		lastYear, lastMonth := 9999, 12
+		command := []string{"render"}
+		if turnId := r.URL.Query().Get("turn"); turnId != "" {
+			year, month, err := turns.ParseTurnId(turnId)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("turn %v", err), http.StatusBadRequest)
+				return
+			}
+			lastYear, lastMonth = year, month
+			command = append(command, "--to-turn", turnId)
+		}
+
+		sharing, err := store.GetSharingClans(user.Clan)
+		if err != nil {
+			log.Printf("serve: alliance: %s: %v\n", clanId, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		work, err := newScratch()
+		if err != nil {
+			log.Printf("serve: alliance: %s: %v\n", clanId, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		defer os.RemoveAll(work)
+
+		// the render merges every report in the input folder, so we only copy
+		// the reports that the user is allowed to see.
+		files := 0
+		for _, clan := range append([]int{user.Clan}, sharing...) {
+			reports, err := store.GetReportsByTurnRange(clan, 899, 12, lastYear, lastMonth)
+			if err != nil {
+				log.Printf("serve: alliance: %s: %04d: %v\n", clanId, clan, err)
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			for _, report := range reports {
+				if strings.HasPrefix(report.Lines, "// ") {
+					// reports loaded with "db load" are scrubbed and can't be parsed
+					continue
+				}
+				name := fmt.Sprintf("%04d-%02d.%s.report.txt", report.Year, report.Month, report.Unit)
+				if err := os.WriteFile(filepath.Join(work, "input", name), []byte(report.Lines), 0o644); err != nil {
+					log.Printf("serve: alliance: %s: %s: %v\n", clanId, name, err)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+					return
+				}
+				files++
+			}
+		}
+		if files == 0 {
+			http.Error(w, "no reports to render", http.StatusNotFound)
+			return
+		}
+
+		data, renderLog, err := runRender(r.Context(), work, clanId, command...)
+		if err != nil {
+			log.Printf("serve: alliance: %s: %v\n", clanId, err)
+			if renderLog != "" {
+				http.Error(w, fmt.Sprintf("render failed: %v\n%s", err, renderLog), http.StatusUnprocessableEntity)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", clanId+".alliance.wxx"))
+		if _, err := w.Write(data); err != nil {
+			log.Printf("serve: alliance: %s: %v\n", clanId, err)
+			return
+		}
+		log.Printf("serve: alliance: %s: map of %d clans for %q in %v\n", clanId, len(sharing)+1, user.Handle, time.Since(started))
+	})
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// clanStore creates a store with a user for clan 0138.
+func clanStore(t *testing.T) *sqlite.Store {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ottomap.db")
+	if err := sqlite.Create(path, context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store, err := sqlite.Open(path, context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	if _, err := store.CreateUser("alice", 138, "correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestServeClanAuth(t *testing.T) {
+	store := clanStore(t)
+	for _, tc := range []struct {
+		id             int
+		handle, secret string
+		want           int
+	}{
+		{1, "", "", http.StatusUnauthorized},
+		{2, "alice", "battery staple", http.StatusUnauthorized},
+		{3, "bob", "correct horse", http.StatusUnauthorized},
+		{4, "alice", "correct horse", http.StatusOK},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/clan/turns", nil)
+		if tc.handle != "" {
+			r.SetBasicAuth(tc.handle, tc.secret)
+		}
+		w := httptest.NewRecorder()
+		serveClanTurns(store)(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%d: status: want %d, got %d", tc.id, tc.want, w.Code)
+		} else if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%d: www-authenticate: want challenge, got none", tc.id)
+		}
+	}
+}
+
+func TestServeClanUpload(t *testing.T) {
+	store := clanStore(t)
+	for _, tc := range []struct {
+		id    int
+		files []string
+		want  int
+	}{
+		{1, []string{"0902-02.0138.report.txt", serveReport}, http.StatusOK},
+		{2, []string{"0902-02.0249.report.txt", serveReport}, http.StatusForbidden},
+		{3, []string{"0902-03.0138.report.txt", serveReport}, http.StatusUnprocessableEntity},
+		{4, []string{"0902-02.0138.txt", serveReport}, http.StatusBadRequest},
+		{5, nil, http.StatusBadRequest},
+		// uploading the same report again replaces it
+		{6, []string{"0902-02.0138.report.txt", serveReport}, http.StatusOK},
+		// and so does the same report as a Word document
+		{7, []string{"0902-02.0138.report.docx", serveDocx(t, serveReport)}, http.StatusOK},
+	} {
+		body, contentType := serveForm(t, nil, tc.files...)
+		r := httptest.NewRequest(http.MethodPost, "/clan/reports", body)
+		r.Header.Set("Content-Type", contentType)
+		r.SetBasicAuth("alice", "correct horse")
+		w := httptest.NewRecorder()
+		serveClanUpload(store)(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%d: status: want %d, got %d: %s", tc.id, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/clan/turns", nil)
+	r.SetBasicAuth("alice", "correct horse")
+	w := httptest.NewRecorder()
+	serveClanTurns(store)(w, r)
+	var list []struct {
+		Turn    string   `json:"turn"`
+		Reports []string `json:"reports"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("turns: json: %v", err)
+	}
+	if len(list) != 1 || list[0].Turn != "0902-02" || len(list[0].Reports) != 1 || list[0].Reports[0] != "0902-02.0138.report.txt" {
+		t.Errorf("turns: want [0902-02 [0902-02.0138.report.txt]], got %+v", list)
+	}
+}
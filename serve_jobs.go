@@ -0,0 +1,280 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/progress"
+	"github.com/playbymail/ottomap/internal/stores/sqlite"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the job endpoints are enabled when the server is started with a store.
+// uploads are queued and run by a pool of workers, so the requests return
+// right away and the client polls the job for its status and result.
+
+const (
+	jobPollInterval = time.Second      // how long an idle worker waits before checking the queue again
+	jobRetryDelay   = 10 * time.Second // how long a failed job waits for each attempt it has used
+)
+
+// jobInput_t is the request that is stored with a job.
+type jobInput_t struct {
+	ClanId  string        `json:"clanId,omitempty"` // set only for render jobs
+	Reports []jobReport_t `json:"reports"`
+}
+
+type jobReport_t struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// jobStatus_t is the status of a job that is returned to the client.
+type jobStatus_t struct {
+	Id          int       `json:"id"`
+	Kind        string    `json:"kind"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error,omitempty"`
+	Phase       string    `json:"phase,omitempty"`
+	Percent     float64   `json:"percent"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"maxAttempts"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// serveJobSubmit queues a parse or render of the uploaded reports and returns the job.
+func serveJobSubmit(store *sqlite.Store, kind string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var input jobInput_t
+		uploads := r.MultipartForm.File["report"]
+		switch kind {
+		case sqlite.JobParse:
+			if len(uploads) != 1 {
+				http.Error(w, "expected exactly one report", http.StatusBadRequest)
+				return
+			}
+		case sqlite.JobRender:
+			input.ClanId = r.FormValue("clan-id")
+			if !(len(input.ClanId) == 4 && input.ClanId[0] == '0' && strings.Trim(input.ClanId, "0123456789") == "") {
+				http.Error(w, "clan-id must be a 4 digit number starting with 0", http.StatusBadRequest)
+				return
+			} else if len(uploads) == 0 {
+				http.Error(w, "expected at least one report", http.StatusBadRequest)
+				return
+			}
+		}
+		for _, upload := range uploads {
+			name, data, err := readUpload(upload)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			input.Reports = append(input.Reports, jobReport_t{Name: name, Data: data})
+		}
+		data, err := json.Marshal(input)
+		if err != nil {
+			log.Printf("serve: jobs: %s: %v\n", kind, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		id, err := store.CreateJob(kind, data, argsServe.attempts)
+		if err != nil {
+			log.Printf("serve: jobs: %s: %v\n", kind, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("serve: jobs: %d: queued %s of %d reports\n", id, kind, len(input.Reports))
+		w.Header().Set("Location", fmt.Sprintf("/jobs/%d", id))
+		writeJobStatus(w, store, id, http.StatusAccepted)
+	}
+}
+
+// serveJobStatus returns the status of the job.
+func serveJobStatus(store *sqlite.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "job id must be a number", http.StatusBadRequest)
+			return
+		}
+		writeJobStatus(w, store, id, http.StatusOK)
+	}
+}
+
+// serveJobResult returns the parsed turn or the map from a job that is done.
+func serveJobResult(store *sqlite.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, "job id must be a number", http.StatusBadRequest)
+			return
+		}
+		job, err := store.GetJob(id)
+		if err != nil {
+			if errors.Is(err, sqlite.ErrNotFound) {
+				http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+				return
+			}
+			log.Printf("serve: jobs: %d: %v\n", id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		} else if job.Status != sqlite.JobDone {
+			http.Error(w, fmt.Sprintf("job %d is %s", id, job.Status), http.StatusConflict)
+			return
+		}
+		_, data, err := store.GetJobResult(id)
+		if err != nil {
+			log.Printf("serve: jobs: %d: %v\n", id, err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		switch job.Kind {
+		case sqlite.JobParse:
+			w.Header().Set("Content-Type", "application/json")
+		case sqlite.JobRender:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"job-%d.wxx\"", id))
+		}
+		if _, err := w.Write(data); err != nil {
+			log.Printf("serve: jobs: %d: %v\n", id, err)
+		}
+	}
+}
+
+// writeJobStatus writes the status of the job as JSON.
+func writeJobStatus(w http.ResponseWriter, store *sqlite.Store, id int, code int) {
+	job, err := store.GetJob(id)
+	if err != nil {
+		if errors.Is(err, sqlite.ErrNotFound) {
+			http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		log.Printf("serve: jobs: %d: %v\n", id, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(jobStatus_t{
+		Id:          job.ID,
+		Kind:        job.Kind,
+		Status:      job.Status,
+		Error:       job.Error,
+		Phase:       job.Phase,
+		Percent:     job.Percent,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		Created:     job.Created,
+		Updated:     job.Updated,
+	}); err != nil {
+		log.Printf("serve: jobs: %d: %v\n", id, err)
+	}
+}
+
+// startJobWorkers puts jobs that were interrupted back on the queue and
+// starts the workers. The workers stop when the context is cancelled.
+func startJobWorkers(ctx context.Context, store *sqlite.Store, workers int) {
+	if n, err := store.RequeueRunningJobs(); err != nil {
+		log.Printf("serve: jobs: requeue: %v\n", err)
+	} else if n != 0 {
+		log.Printf("serve: jobs: requeued %d interrupted jobs\n", n)
+	}
+	for n := 1; n <= workers; n++ {
+		go jobWorker(ctx, store, n)
+	}
+	log.Printf("serve: jobs: started %d workers\n", workers)
+}
+
+// jobWorker runs jobs from the queue until the context is cancelled.
+func jobWorker(ctx context.Context, store *sqlite.Store, worker int) {
+	for ctx.Err() == nil {
+		job, err := store.ClaimJob(time.Now())
+		if err != nil {
+			log.Printf("serve: jobs: worker %d: %v\n", worker, err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(jobPollInterval):
+			}
+			continue
+		}
+
+		started := time.Now()
+		result, err := runJob(ctx, store, job)
+		if err == nil {
+			err = store.FinishJob(job.ID, result)
+		}
+		if err == nil {
+			log.Printf("serve: jobs: %d: %s: done in %v\n", job.ID, job.Kind, time.Since(started))
+			continue
+		}
+		retryAt := time.Now().Add(time.Duration(job.Attempts) * jobRetryDelay)
+		if status, ferr := store.FailJob(job, err.Error(), retryAt); ferr != nil {
+			log.Printf("serve: jobs: %d: %v\n", job.ID, ferr)
+		} else if status == sqlite.JobQueued {
+			log.Printf("serve: jobs: %d: %s: attempt %d of %d: %v: retrying at %s\n", job.ID, job.Kind, job.Attempts, job.MaxAttempts, err, retryAt.Format(time.TimeOnly))
+		} else {
+			log.Printf("serve: jobs: %d: %s: failed after %d attempts: %v\n", job.ID, job.Kind, job.Attempts, err)
+		}
+	}
+}
+
+// runJob runs a parse or render job and returns the parsed turn or the map.
+func runJob(ctx context.Context, store *sqlite.Store, job *sqlite.Job_t) ([]byte, error) {
+	var input jobInput_t
+	if err := json.Unmarshal(job.Input, &input); err != nil {
+		return nil, fmt.Errorf("input: %w", err)
+	}
+
+	switch job.Kind {
+	case sqlite.JobParse:
+		if len(input.Reports) != 1 {
+			return nil, fmt.Errorf("expected exactly one report")
+		}
+		report := input.Reports[0]
+		matches := rxUploadName.FindStringSubmatch(report.Name)
+		if matches == nil {
+			return nil, fmt.Errorf("%q: report name must be yyyy-mm.unit.report.txt", report.Name)
+		}
+		ctx, cancel := context.WithTimeout(ctx, argsServe.timeout)
+		defer cancel()
+		_ = store.UpdateJobProgress(job.ID, "parse", 0)
+		turn, err := parseUpload(ctx, report.Name, fmt.Sprintf("%s-%s", matches[1], matches[2]), report.Data)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(turn)
+	case sqlite.JobRender:
+		work, err := newScratch()
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(work)
+		for _, report := range input.Reports {
+			if err := os.WriteFile(filepath.Join(work, "input", report.Name), report.Data, 0o644); err != nil {
+				return nil, err
+			}
+		}
+		data, renderLog, err := runRenderProgress(ctx, work, input.ClanId, func(e progress.Event_t) {
+			if err := store.UpdateJobProgress(job.ID, e.Phase, e.Percent); err != nil {
+				log.Printf("serve: jobs: %d: progress: %v\n", job.ID, err)
+			}
+		}, "render")
+		if err != nil && renderLog != "" {
+			return nil, fmt.Errorf("render failed: %w\n%s", err, renderLog)
+		}
+		return data, err
+	}
+	return nil, fmt.Errorf("%q: unknown job kind", job.Kind)
+}
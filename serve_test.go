@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveReport is a report that the parser accepts, for turn 0902-02.
+const serveReport = "Tribe 0138, , Current Hex = QQ 1008, (Previous Hex = QQ 1010)\n" +
+	"Current Turn 902-02 (#26), Winter, FINE\tNext Turn 902-03 (#27), 28/10/2023\n" +
+	"Tribe Movement: Move N-PR\\\n" +
+	"0138 Status: PRAIRIE, 0138\n"
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	argsServe.secret = "shared-secret"
+	argsServe.maxUpload = 64 * 1024
+	argsServe.timeout = 10 * time.Second
+	os.Exit(m.Run())
+}
+
+// serveForm returns a multipart body with the values and with the files as "report" form files.
+// The files alternate between names and contents.
+func serveForm(t *testing.T, values map[string]string, files ...string) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range values {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for n := 0; n+1 < len(files); n += 2 {
+		fw, err := mw.CreateFormFile("report", files[n])
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err = fw.Write([]byte(files[n+1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, mw.FormDataContentType()
+}
+
+// serveDocx returns a Word document with a paragraph for each line of the text.
+func serveDocx(t *testing.T, text string) string {
+	t.Helper()
+	var body strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		line = strings.ReplaceAll(line, "\t", "</w:t><w:tab/><w:t>")
+		body.WriteString(`<w:p><w:r><w:t xml:space="preserve">` + line + "</w:t></w:r></w:p>")
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = w.Write([]byte(`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body.String() + "</w:body></w:document>")); err != nil {
+		t.Fatal(err)
+	} else if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestServeAuth(t *testing.T) {
+	for _, tc := range []struct {
+		id     int
+		header string
+		size   int
+		want   int
+	}{
+		{1, "", 0, http.StatusUnauthorized},
+		{2, "Bearer wrong-secret", 0, http.StatusUnauthorized},
+		{3, "Basic shared-secret", 0, http.StatusUnauthorized},
+		{4, "Bearer shared-secret", 0, http.StatusNoContent},
+		{5, "Bearer shared-secret", 128 * 1024, http.StatusRequestEntityTooLarge},
+	} {
+		body, contentType := serveForm(t, nil, "0902-02.0138.report.txt", strings.Repeat("x", tc.size))
+		r := httptest.NewRequest(http.MethodPost, "/parse", body)
+		r.Header.Set("Content-Type", contentType)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		serveAuth(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%d: status: want %d, got %d", tc.id, tc.want, w.Code)
+		}
+	}
+}
+
+func TestServeParse(t *testing.T) {
+	for _, tc := range []struct {
+		id    int
+		files []string
+		want  int
+	}{
+		{1, []string{"0902-02.0138.report.txt", serveReport}, http.StatusOK},
+		{2, []string{"report.txt", serveReport}, http.StatusBadRequest},
+		{3, []string{"0902-02.0138.report.txt", serveReport, "0902-02.0138e1.report.txt", serveReport}, http.StatusBadRequest},
+		{4, nil, http.StatusBadRequest},
+		{5, []string{"0902-03.0138.report.txt", serveReport}, http.StatusUnprocessableEntity},
+		{6, []string{"0902-02.0138.report.docx", serveDocx(t, serveReport)}, http.StatusOK},
+		{7, []string{"0902-02.0138.report.docx", serveReport}, http.StatusBadRequest},
+	} {
+		body, contentType := serveForm(t, nil, tc.files...)
+		r := httptest.NewRequest(http.MethodPost, "/parse", body)
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Authorization", "Bearer "+argsServe.secret)
+		w := httptest.NewRecorder()
+		serveAuth(serveParse)(w, r)
+		if w.Code != tc.want {
+			t.Errorf("%d: status: want %d, got %d: %s", tc.id, tc.want, w.Code, w.Body.String())
+			continue
+		} else if w.Code != http.StatusOK {
+			continue
+		}
+		var turn struct {
+			Year, Month int
+			UnitMoves   map[string]any
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &turn); err != nil {
+			t.Errorf("%d: json: %v", tc.id, err)
+		} else if turn.Year != 902 || turn.Month != 2 || len(turn.UnitMoves) != 1 {
+			t.Errorf("%d: turn: want 902-02 with 1 unit, got %d-%02d with %d units", tc.id, turn.Year, turn.Month, len(turn.UnitMoves))
+		}
+	}
+}
+
+func TestServeRenderClanId(t *testing.T) {
+	// bad requests are rejected before the render starts
+	for _, tc := range []struct {
+		id     int
+		clanId string
+		files  []string
+	}{
+		{1, "", []string{"0902-02.0138.report.txt", serveReport}},
+		{2, "138", []string{"0902-02.0138.report.txt", serveReport}},
+		{3, "1138", []string{"0902-02.0138.report.txt", serveReport}},
+		{4, "0138", nil},
+	} {
+		body, contentType := serveForm(t, map[string]string{"clan-id": tc.clanId}, tc.files...)
+		r := httptest.NewRequest(http.MethodPost, "/render", body)
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Authorization", "Bearer "+argsServe.secret)
+		w := httptest.NewRecorder()
+		serveAuth(serveRender)(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%d: status: want %d, got %d", tc.id, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// gridIndex_t is the index file written with the per-grid maps.
+type gridIndex_t struct {
+	Clan  string            `json:"clan"`
+	Turn  string            `json:"turn"`
+	Grids []*gridIndexEntry `json:"grids"`
+}
+
+type gridIndexEntry struct {
+	Grid  string `json:"grid"`  // grid id, for example "AB"
+	File  string `json:"file"`  // name of the map file in the folder
+	Tiles int    `json:"tiles"` // number of tiles in the grid
+}
+
+// renderByGrid writes one map for each grid that has tiles into a folder named
+// after the map, for example "0138/AB.wxx", and an index.json that lists them.
+// Annotations and regions are clipped to each grid; teleports and unit history
+// are only drawn on the grid they stay inside.
+func renderByGrid(ctx context.Context, worldMap *tiles.Map_t, specialNames map[string]*parser.Special_t, mapName, turnId string) error {
+	folder := strings.TrimSuffix(mapName, filepath.Ext(mapName))
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return err
+	}
+
+	index := &gridIndex_t{Clan: argsRender.clanId, Turn: turnId}
+	grids := worldMap.Grids()
+	argsRender.progress.Start("write", len(grids))
+	for _, id := range slices.Sorted(maps.Keys(grids)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		grid, name := grids[id], id+".wxx"
+		inGrid := func(location coords.Map) bool {
+			return location.GridId() == id
+		}
+		if err := renderPart(ctx, grid, specialNames, inGrid, filepath.Join(folder, name), turnId); err != nil {
+			return err
+		}
+		argsRender.progress.Add(1)
+		index.Grids = append(index.Grids, &gridIndexEntry{Grid: id, File: name, Tiles: grid.Length()})
+	}
+	argsRender.progress.Done()
+
+	log.Printf("split: %d grids\n", len(index.Grids))
+	return writeIndex(filepath.Join(folder, "index.json"), index)
+}
+
+// renderPart maps the tiles, with the overlays clipped to the hexes that are
+// inside the part, and writes the map to the path.
+func renderPart(ctx context.Context, part *tiles.Map_t, specialNames map[string]*parser.Special_t, inside func(coords.Map) bool, path, turnId string) error {
+	wxxMap, err := actions.MapWorld(ctx, part, specialNames, parser.UnitId_t(argsRender.clanId), clipMapConfig(argsRender.mapper, inside), argsRender.wxxOptions...)
+	if err != nil {
+		return err
+	}
+	upperLeft, lowerRight := part.Bounds()
+	if err := wxxMap.Create(ctx, path, turnId, upperLeft, lowerRight, argsRender.render); err != nil {
+		return err
+	}
+	log.Printf("created  %s\n", path)
+	return argsRender.manifest.AddOutput(path)
+}
+
+// writeIndex writes the index of the parts as indented JSON.
+func writeIndex(path string, index any) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	} else if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	log.Printf("created  %s\n", path)
+	return argsRender.manifest.AddOutput(path)
+}
+
+// clipMapConfig returns a copy of the configuration with the overlays limited to the hexes inside the part.
+func clipMapConfig(cfg actions.MapConfig, inGrid func(coords.Map) bool) actions.MapConfig {
+	clipped := cfg
+	clipped.Show.Annotations = nil
+	for _, hex := range cfg.Show.Annotations {
+		if inGrid(hex.Location) {
+			clipped.Show.Annotations = append(clipped.Show.Annotations, hex)
+		}
+	}
+	clipped.Show.Regions = nil
+	for _, region := range cfg.Show.Regions {
+		r := wxx.Region{Name: region.Name, Color: region.Color}
+		for _, hex := range region.Hexes {
+			if inGrid(hex) {
+				r.Hexes = append(r.Hexes, hex)
+			}
+		}
+		if len(r.Hexes) != 0 {
+			clipped.Show.Regions = append(clipped.Show.Regions, r)
+		}
+	}
+	clipped.Show.Teleports = nil
+	for _, t := range cfg.Show.Teleports {
+		if inGrid(t.From) && inGrid(t.To) {
+			clipped.Show.Teleports = append(clipped.Show.Teleports, t)
+		}
+	}
+	clipped.Show.UnitHistory = nil
+	for _, h := range cfg.Show.UnitHistory {
+		inside := true
+		for _, turn := range h.Turns {
+			for _, hex := range turn.Path {
+				inside = inside && inGrid(hex)
+			}
+		}
+		if inside {
+			clipped.Show.UnitHistory = append(clipped.Show.UnitHistory, h)
+		}
+	}
+	return clipped
+}
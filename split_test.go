@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"github.com/playbymail/ottomap/actions"
+	"github.com/playbymail/ottomap/internal/annotations"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"testing"
+)
+
+func TestClipMapConfig(t *testing.T) {
+	inside, outside := coords.Map{Column: 5, Row: 5}, coords.Map{Column: 35, Row: 5}
+	inGrid := func(location coords.Map) bool {
+		return location.GridId() == "AA"
+	}
+
+	var cfg actions.MapConfig
+	cfg.Show.Annotations = []*annotations.Hex_t{{Location: inside}, {Location: outside}}
+	cfg.Show.Regions = []wxx.Region{
+		{Name: "split", Hexes: []coords.Map{inside, outside}},
+		{Name: "away", Hexes: []coords.Map{outside}},
+	}
+	cfg.Show.Teleports = []wxx.Teleport{
+		{UnitId: "0138e1", From: inside, To: inside},
+		{UnitId: "0138e2", From: inside, To: outside},
+	}
+	cfg.Show.UnitHistory = []wxx.UnitHistory{
+		{UnitId: "0138", Turns: []wxx.UnitTurn{{TurnId: "0901-07", Path: []coords.Map{inside, inside}}}},
+		{UnitId: "0138e1", Turns: []wxx.UnitTurn{{TurnId: "0901-07", Path: []coords.Map{inside}}, {TurnId: "0901-08", Path: []coords.Map{outside}}}},
+	}
+
+	clipped := clipMapConfig(cfg, inGrid)
+	if len(clipped.Show.Annotations) != 1 || clipped.Show.Annotations[0].Location != inside {
+		t.Errorf("annotations: want 1 inside, got %d", len(clipped.Show.Annotations))
+	}
+	if len(clipped.Show.Regions) != 1 || clipped.Show.Regions[0].Name != "split" || len(clipped.Show.Regions[0].Hexes) != 1 {
+		t.Errorf("regions: want split with 1 hex, got %+v", clipped.Show.Regions)
+	}
+	if len(clipped.Show.Teleports) != 1 || clipped.Show.Teleports[0].UnitId != "0138e1" {
+		t.Errorf("teleports: want 0138e1, got %+v", clipped.Show.Teleports)
+	}
+	if len(clipped.Show.UnitHistory) != 1 || clipped.Show.UnitHistory[0].UnitId != "0138" {
+		t.Errorf("history: want 0138, got %+v", clipped.Show.UnitHistory)
+	}
+	// the original configuration is not changed
+	if len(cfg.Show.Annotations) != 2 || len(cfg.Show.Regions) != 2 || len(cfg.Show.Regions[0].Hexes) != 2 {
+		t.Errorf("original: want unchanged, got %+v", cfg.Show)
+	}
+}
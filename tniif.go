@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/tniif"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+	"strings"
+)
+
+var argsTniifMerge struct {
+	output string // path to write the merged document to, stdout if empty
+}
+
+var cmdTniif = &cobra.Command{
+	Use:   "tniif",
+	Short: "work with TribeNet interchange format documents",
+	Long:  `Work with the tniif documents written by "parse file".`,
+}
+
+var cmdTniifMerge = &cobra.Command{
+	Use:   "merge document...",
+	Short: "merge documents for the same turn",
+	Long: `Merge tniif documents for the same turn, for example one from each allied clan, into a single document.
+Units that are in more than one document are kept once; the copies must be identical.
+Special hexes must have the same name in every document.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var docs []*tniif.Document_t
+		for _, path := range args {
+			doc, err := readDocument(path)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			docs = append(docs, doc)
+		}
+		merged, err := tniif.Merge(docs...)
+		if err != nil {
+			log.Fatalf("error: merge: %v\n", err)
+		}
+		if err := writeDocument(argsTniifMerge.output, merged); err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		log.Printf("tniif: merged %d documents: %d units\n", len(docs), len(merged.Units))
+	},
+}
+
+var cmdTniifDiff = &cobra.Command{
+	Use:   "diff old new",
+	Short: "compare two documents",
+	Long: `Compare two tniif documents and list the units, steps, and observations that were added, removed, or changed.
+Ordering and formatting are ignored. A report file is parsed with the legacy parser before comparing,
+so a saved document can be checked against a new parse of the same report.
+Exits with status 1 if there are differences.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var docs [2]*tniif.Document_t
+		for n, path := range args {
+			var err error
+			if strings.HasSuffix(path, ".json") {
+				docs[n], err = readDocument(path)
+			} else {
+				docs[n], err = parseDocument(path)
+			}
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+		}
+		diffs := tniif.Diff(docs[0], docs[1])
+		for _, line := range diffs {
+			fmt.Println(line)
+		}
+		if len(diffs) != 0 {
+			log.Printf("tniif: %d differences\n", len(diffs))
+			os.Exit(1)
+		}
+	},
+}
+
+// parseDocument parses a report file with the legacy parser.
+func parseDocument(path string) (*tniif.Document_t, error) {
+	fid, tid, data, err := readReportFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := parseLegacy(fid, tid, data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", fid, err)
+	}
+	return doc, nil
+}
+
+// readDocument loads a tniif document from a JSON file.
+func readDocument(path string) (*tniif.Document_t, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc tniif.Document_t
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &doc, nil
+}
+
+// writeDocument writes the document as indented JSON to the file, or to stdout if the path is empty.
+func writeDocument(path string, doc *tniif.Document_t) error {
+	buf, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	buf = append(buf, '\n')
+	if path == "" {
+		_, err = os.Stdout.Write(buf)
+		return err
+	}
+	return os.WriteFile(path, buf, 0o644)
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/coords"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/viewer"
+	"github.com/spf13/cobra"
+	"log"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+var argsView struct {
+	addr string // address to listen on
+}
+
+var cmdView = &cobra.Command{
+	Use:   "view",
+	Short: "View the map in a browser",
+	Long: `Load the turn reports, from the data folder or the database, and serve the merged map as a web page.
+The page has layers for terrain, settlements, unit paths, and units. Accepts the same flags as the render command.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if argsRender.paths.store != "" {
+			if path, err := filepath.Abs(argsRender.paths.store); err != nil {
+				return fmt.Errorf("database: %v\n", err)
+			} else {
+				argsRender.paths.store = path
+			}
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := loadWorld(cmd.Context())
+		if err != nil {
+			log.Fatalf("error: %v\n", err)
+		}
+		clan := parser.UnitId_t(argsRender.clanId)
+
+		var units []viewer.Unit_t
+		for _, unit := range clanUnitLocations(w, clan) {
+			units = append(units, viewer.Unit_t{Id: string(unit.id), Location: unit.location})
+		}
+		var paths []viewer.Path_t
+		for _, turn := range w.turns {
+			for _, moves := range turn.SortedMoves {
+				if !moves.UnitId.InClan(clan) || moves.StartLocation.IsZero() {
+					continue
+				}
+				path := viewer.Path_t{UnitId: string(moves.UnitId), TurnId: turn.Id, Steps: []coords.Map{moves.StartLocation}}
+				for _, move := range moves.Moves {
+					if !move.Location.IsZero() && move.Location != path.Steps[len(path.Steps)-1] {
+						path.Steps = append(path.Steps, move.Location)
+					}
+				}
+				paths = append(paths, path)
+			}
+		}
+
+		// the map doesn't change while we're running, so render it once
+		started := time.Now()
+		page, svg := &bytes.Buffer{}, &bytes.Buffer{}
+		title := fmt.Sprintf("%s %s", argsRender.clanId, w.maxTurnId)
+		if err := viewer.Page(page, title, w.tiles, units, paths); err != nil {
+			log.Fatalf("error: view: %v\n", err)
+		} else if err := viewer.SVG(svg, w.tiles, units, paths); err != nil {
+			log.Fatalf("error: view: %v\n", err)
+		}
+		log.Printf("view: %d tiles, %d units, %d paths in %v\n", len(w.tiles.Tiles), len(units), len(paths), time.Since(started))
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /{$}", func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = rw.Write(page.Bytes())
+		})
+		mux.HandleFunc("GET /map.svg", func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Content-Type", "image/svg+xml")
+			_, _ = rw.Write(svg.Bytes())
+		})
+		log.Printf("view: listening on http://%s/\n", argsView.addr)
+		srv := &http.Server{
+			Addr:              argsView.addr,
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		if err := srv.ListenAndServe(); err != nil {
+			log.Fatalf("error: view: %v\n", err)
+		}
+	},
+}
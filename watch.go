@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/watch"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"log"
+	"os"
+	"os/exec"
+	"time"
+)
+
+var argsWatch struct {
+	interval time.Duration // how often to check the input folder for changes
+}
+
+var cmdWatch = &cobra.Command{
+	Use:   "watch",
+	Short: "Re-render the map when report files change",
+	Long: `Watch the input folder and run the render command each time a report file is added, changed, or removed.
+The folder is polled every --interval, so a change can take that long to be noticed.
+Accepts the same flags as the render command.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if argsWatch.interval < 100*time.Millisecond {
+			return fmt.Errorf("interval must be at least 100ms")
+		}
+		return cmdRender.PreRunE(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatalf("error: watch: %v\n", err)
+		}
+
+		// the render is run in a child process so that a bad report doesn't stop the watch
+		renderArgs := append([]string{"render"}, renderFlags(cmd.Flags())...)
+
+		log.Printf("watch: %s: checking every %v\n", argsRender.paths.input, argsWatch.interval)
+		rebuild := 1
+		watch.Poll(argsRender.paths.input, argsWatch.interval, nil, func(changes int) {
+			log.Printf("watch: rebuild %d: %d files changed\n", rebuild, changes)
+			started := time.Now()
+			child := exec.Command(executable, renderArgs...)
+			child.Stdout, child.Stderr = os.Stdout, os.Stderr
+			if err := child.Run(); err != nil {
+				log.Printf("watch: rebuild %d: failed after %v: %v\n", rebuild, time.Since(started), err)
+			} else {
+				log.Printf("watch: rebuild %d: finished in %v\n", rebuild, time.Since(started))
+			}
+			rebuild++
+		})
+	},
+}
+
+// renderFlags returns the flags that were set on the command line, except for the interval,
+// so that they can be passed to the render command. Each element of a slice flag is passed
+// as a separate flag because String quotes and brackets the list.
+func renderFlags(flags *pflag.FlagSet) (args []string) {
+	flags.Visit(func(f *pflag.Flag) {
+		if f.Name == "interval" {
+			return
+		} else if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, value := range sv.GetSlice() {
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, value))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+	return args
+}
@@ -0,0 +1,25 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"github.com/spf13/pflag"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRenderFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("watch", pflag.ContinueOnError)
+	flags.Duration("interval", time.Second, "")
+	flags.Bool("show-grid-coords", false, "")
+	flags.StringSlice("clip", nil, "")
+	flags.String("clan-id", "", "")
+	if err := flags.Parse([]string{"--interval=5s", "--clip=AA,AB", "--clip", "BA", "--show-grid-coords", "--clan-id=0138"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"--clan-id=0138", "--clip=AA", "--clip=AB", "--clip=BA", "--show-grid-coords=true"}
+	if got := renderFlags(flags); !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
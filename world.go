@@ -0,0 +1,252 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/playbymail/ottomap/internal/export"
+	"github.com/playbymail/ottomap/internal/extract"
+	"github.com/playbymail/ottomap/internal/navigation"
+	"github.com/playbymail/ottomap/internal/parser"
+	"github.com/playbymail/ottomap/internal/pathfinding"
+	"github.com/playbymail/ottomap/internal/tiles"
+	"github.com/playbymail/ottomap/internal/turns"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// world_t holds the results of loading, parsing, and walking all the turn reports.
+type world_t struct {
+	turns        []*parser.Turn_t // consolidated turns, sorted by year and month
+	specialNames map[string]*parser.Special_t
+	tiles        *tiles.Map_t
+	turnId       string               // id of the last turn we processed
+	maxTurnId    string               // id of the maximum turn we processed
+	unresolved   []turns.Unresolved_t // obscured and N/A hexes that could not be resolved
+	sources      []string             // ids of the report files that were loaded
+}
+
+// loadWorld loads all the turn reports from the input path, consolidates them,
+// and walks the moves to build the map of tiles. It uses the settings from
+// argsRender, so the caller must have run the render pre-checks first.
+func loadWorld(ctx context.Context) (*world_t, error) {
+	started := time.Now()
+
+	var inputs []*turns.TurnReportFile_t
+	var err error
+	if argsRender.paths.store != "" {
+		inputs, err = collectStoreInputs(argsRender.paths.store, argsRender.clanId, argsRender.fromTurn.year, argsRender.fromTurn.month, argsRender.maxTurn.year, argsRender.maxTurn.month)
+	} else {
+		inputs, err = turns.CollectInputs(argsRender.paths.input, argsRender.maxTurn.year, argsRender.maxTurn.month, argsRoot.soloClan, argsRender.clanId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("inputs: %w", err)
+	}
+	log.Printf("inputs: found %d turn reports\n", len(inputs))
+	var sources []string
+	for _, i := range inputs {
+		sources = append(sources, i.Id)
+	}
+
+	// allTurns holds the turn and move data and allows multiple clans to be loaded.
+	allTurns := map[string][]*turns.Document_t{}
+	totalUnitMoves := 0
+	var turnId, maxTurnId string // will be set to the last/maximum turnId we process
+	argsRender.progress.Start("parse", len(inputs))
+	for _, i := range inputs {
+		argsRender.progress.Add(1)
+		started := time.Now()
+		data := i.Data
+		if data == nil {
+			data, err = os.ReadFile(i.Path)
+			if err != nil {
+				argsRender.progress.Done()
+				return nil, fmt.Errorf("read: %w", err)
+			}
+		}
+		if argsRender.manifest != nil {
+			argsRender.manifest.AddInput(i.Id, i.Path, data)
+		}
+		if len(data) == 0 {
+			log.Printf("warn: %q: empty file\n", i.Path)
+			continue
+		}
+		if clean, fixes := extract.Normalize(data); len(fixes) != 0 {
+			log.Printf("warn: %q: %s\n", i.Id, strings.Join(fixes, ", "))
+			data = clean
+		}
+		if argsRender.autoEOL {
+			data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+			data = bytes.ReplaceAll(data, []byte{'\r'}, []byte{'\n'})
+		} else if argsRender.experimental.stripCR {
+			data = bytes.ReplaceAll(data, []byte{'\r', '\n'}, []byte{'\n'})
+		}
+		if i.Turn.Year < 899 || i.Turn.Year > 9999 || i.Turn.Month < 1 || i.Turn.Month > 12 {
+			log.Printf("warn: %q: invalid turn year '%d'\n", i.Id, i.Turn.Year)
+			continue
+		} else if i.Turn.Month < 1 || i.Turn.Month > 12 {
+			log.Printf("warn: %q: invalid turn month '%d'\n", i.Id, i.Turn.Month)
+			continue
+		}
+		pastCutoff := false
+		if i.Turn.Year > argsRender.maxTurn.year {
+			pastCutoff = true
+		} else if i.Turn.Year == argsRender.maxTurn.year {
+			if i.Turn.Month > argsRender.maxTurn.month {
+				pastCutoff = true
+			}
+		}
+		if pastCutoff {
+			log.Printf("warn: %q: past cutoff %04d-%02d\n", i.Id, argsRender.maxTurn.year, argsRender.maxTurn.month)
+		}
+		if !turns.InTurnRange(i.Turn.Id, argsRender.fromTurn.id, "") {
+			log.Printf("warn: %q: before first turn %s\n", i.Id, argsRender.fromTurn.id)
+			continue
+		}
+		turnId = fmt.Sprintf("%04d-%02d", i.Turn.Year, i.Turn.Month)
+		if turnId > maxTurnId {
+			maxTurnId = turnId
+		}
+		var turn *parser.Turn_t
+		if argsRender.pipeline == "new" {
+			turn, err = parseNewTurn(i.Id, turnId, data)
+		} else {
+			turn, err = parser.ParseInput(ctx, i.Id, turnId, data, argsRender.acceptLoneDash, argsRender.debug.parser, argsRender.debug.sections, argsRender.debug.steps, argsRender.debug.nodes, argsRender.debug.fleetMovement, argsRender.experimental.splitTrailingUnits, argsRender.experimental.cleanUpScoutStill, argsRender.parser)
+		}
+		if err != nil {
+			argsRender.progress.Done()
+			return nil, fmt.Errorf("parse: %w", err)
+		} else if turnId != fmt.Sprintf("%04d-%02d", turn.Year, turn.Month) {
+			if turn.Year == 0 && turn.Month == 0 {
+				log.Printf("error: unable to locate turn information in file\n")
+				log.Printf("error: this is usually caused by unexpected line endings in the file\n")
+				log.Printf("error: try running with --auto-eol\n")
+			}
+			argsRender.progress.Done()
+			return nil, fmt.Errorf("%s: expected turn %q: got turn %q", i.Id, turnId, fmt.Sprintf("%04d-%02d", turn.Year, turn.Month))
+		}
+		if len(turn.Errors) != 0 {
+			log.Printf("warn: %q: skipped %d sections after internal errors: please report them\n", i.Id, len(turn.Errors))
+		}
+		//log.Printf("len(turn.SpecialNames) = %d\n", len(turn.SpecialNames))
+
+		allTurns[turnId] = append(allTurns[turnId], &turns.Document_t{Id: i.Id, ClanId: parser.UnitId_t(i.Turn.ClanId), Turn: turn})
+		totalUnitMoves += len(turn.UnitMoves)
+		log.Printf("%q: parsed %6d units in %v\n", i.Id, len(turn.UnitMoves), time.Since(started))
+	}
+	argsRender.progress.Done()
+	log.Printf("parsed %d inputs in to %d turns and %d units in %v\n", len(inputs), len(allTurns), totalUnitMoves, time.Since(started))
+
+	// consolidate the turns, then sort by year and month and link them
+	consolidatedTurns, consolidatedSpecialNames, err := turns.Consolidate(allTurns)
+	if err != nil {
+		return nil, fmt.Errorf("consolidate: %w: please fix the duplicate units and restart", err)
+	}
+
+	// fill in the previous and current hexes for every unit before walking
+	locations, err := turns.DeriveLocations(consolidatedTurns, argsRender.locations)
+	if err != nil {
+		return nil, err
+	}
+	if argsRender.explainLocations {
+		for _, d := range locations.Derivations {
+			log.Printf("locations: %s\n", d)
+		}
+	}
+
+	if argsRender.warnOnFleetDrift {
+		for _, warning := range navigation.DefaultRules().Validate(consolidatedTurns) {
+			log.Printf("warn: fleet: %s\n", warning)
+		}
+	}
+
+	// dangerous but try to find the origin hex if asked
+	if argsRender.show.origin {
+		for _, turn := range consolidatedTurns {
+			for _, unit := range turn.SortedMoves {
+				argsRender.mapper.Origin = unit.Location
+				break
+			}
+		}
+		log.Printf("info: origin hex set to %q\n", argsRender.mapper.Origin)
+	}
+
+	// walk the data. the phase is ended before checking for errors so that the bar is finished.
+	world, err := argsRender.config.World.Wrap()
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", argsRender.paths.config, err)
+	} else if !world.IsZero() {
+		log.Printf("walk: world wraps at %d grid columns and %d grid rows\n", world.Columns, world.Rows)
+	}
+	argsRender.progress.Start("walk", 0)
+	worldMap, err := turns.Walk(ctx, consolidatedTurns, consolidatedSpecialNames, world, argsRender.originGrid, argsRender.quitOnInvalidGrid, argsRender.warnOnInvalidGrid, argsRender.warnOnNewSettlement, argsRender.warnOnTerrainChange, argsRender.debug.maps)
+	argsRender.progress.Done()
+	if err != nil {
+		return nil, err
+	}
+	// estimate the movement points for each step and flag the ones that disagree with the report
+	for _, warning := range pathfinding.Account(consolidatedTurns, worldMap, pathfinding.DefaultMovementPoints) {
+		log.Printf("warn: mp: %s\n", warning)
+	}
+	if argsRender.soloElement != "" {
+		log.Printf("info: rendering only %q\n", argsRender.soloElement)
+		solo := worldMap.Solo(argsRender.soloElement)
+		log.Printf("info: %s: world %d tiles: solo %d\n", argsRender.soloElement, len(worldMap.Tiles), len(solo.Tiles))
+		worldMap = solo
+	}
+
+	return &world_t{
+		turns:        consolidatedTurns,
+		specialNames: consolidatedSpecialNames,
+		tiles:        worldMap,
+		turnId:       turnId,
+		maxTurnId:    maxTurnId,
+		unresolved:   locations.Unresolved,
+		sources:      sources,
+	}, nil
+}
+
+// loadMergedWorld loads the tiles from a file written by --dump-merged.
+// There are no turns, so the turn ids are taken from the tiles.
+func loadMergedWorld(path string) (*world_t, error) {
+	started := time.Now()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	argsRender.manifest.AddInput(filepath.Base(path), path, data)
+	list, err := export.ReadMerged(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	worldMap, err := export.MergedMap(list)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if worldMap.World, err = argsRender.config.World.Wrap(); err != nil {
+		return nil, fmt.Errorf("config: %s: %w", argsRender.paths.config, err)
+	}
+	var maxTurnId string
+	for _, tile := range worldMap.Tiles {
+		if tile.LastSeen > maxTurnId {
+			maxTurnId = tile.LastSeen
+		}
+	}
+	log.Printf("merged: %s: loaded %d tiles to turn %q in %v\n", path, worldMap.Length(), maxTurnId, time.Since(started))
+	if argsRender.soloElement != "" {
+		log.Printf("info: rendering only %q\n", argsRender.soloElement)
+		worldMap = worldMap.Solo(argsRender.soloElement)
+	}
+
+	return &world_t{
+		tiles:     worldMap,
+		turnId:    maxTurnId,
+		maxTurnId: maxTurnId,
+		sources:   []string{filepath.Base(path)},
+	}, nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Michael D Henderson. All rights reserved.
+
+package main
+
+import (
+	"fmt"
+	"github.com/playbymail/ottomap/internal/wxx"
+	"github.com/spf13/cobra"
+	"log"
+	"os"
+)
+
+var argsWxxDiff struct {
+	ignoreLayers []string // layers that are skipped when comparing features and labels
+}
+
+var cmdWxx = &cobra.Command{
+	Use:   "wxx",
+	Short: "work with Worldographer maps",
+	Long:  `Work with the Worldographer (.wxx) maps written by "render".`,
+}
+
+var cmdWxxDiff = &cobra.Command{
+	Use:   "diff old new",
+	Short: "compare two maps",
+	Long: `Compare two Worldographer maps, for example last turn's map and this turn's, and list
+the tiles that were added or changed terrain and the features and labels that were added, removed, or moved.
+Exits with status 1 if there are differences.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		var maps [2]*wxx.MapFile
+		for n, path := range args {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				log.Fatalf("error: %v\n", err)
+			}
+			maps[n], err = wxx.Read(data)
+			if err != nil {
+				log.Fatalf("error: %s: %v\n", path, err)
+			}
+		}
+		diffs := wxx.Diff(maps[0], maps[1], argsWxxDiff.ignoreLayers...)
+		for _, line := range diffs {
+			fmt.Println(line)
+		}
+		if len(diffs) != 0 {
+			log.Printf("wxx: %d differences\n", len(diffs))
+			os.Exit(1)
+		}
+	},
+}